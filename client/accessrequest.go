@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"idm/inner/accessrequest"
+	"net/http"
+	"net/url"
+)
+
+// CreateAccessRequest - POST /api/v1/access-requests
+func (c *Client) CreateAccessRequest(ctx context.Context, request accessrequest.CreateRequest) (accessrequest.Response, error) {
+	var rsl accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/access-requests", request), &rsl)
+	return rsl, err
+}
+
+// FindAllAccessRequests - GET /api/v1/access-requests
+func (c *Client) FindAllAccessRequests(ctx context.Context, request accessrequest.ListRequest) ([]accessrequest.Response, error) {
+	var rsl []accessrequest.Response
+	var req = newRequest(http.MethodGet, "/api/v1/access-requests", nil)
+	if request.Status != "" {
+		req.query = url.Values{"status": {request.Status}}
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
+
+// FindMyAccessRequests - GET /api/v1/access-requests/my
+func (c *Client) FindMyAccessRequests(ctx context.Context) ([]accessrequest.Response, error) {
+	var rsl []accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/access-requests/my", nil), &rsl)
+	return rsl, err
+}
+
+// FindAwaitingAccessRequests - GET /api/v1/access-requests/awaiting, ждущие решения вызывающего
+func (c *Client) FindAwaitingAccessRequests(ctx context.Context) ([]accessrequest.Response, error) {
+	var rsl []accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/access-requests/awaiting", nil), &rsl)
+	return rsl, err
+}
+
+// FindAccessRequestById - GET /api/v1/access-requests/:id
+func (c *Client) FindAccessRequestById(ctx context.Context, id int64) (accessrequest.Response, error) {
+	var rsl accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/access-requests/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// ApproveAccessRequest - POST /api/v1/access-requests/:id/approve
+func (c *Client) ApproveAccessRequest(ctx context.Context, id int64,
+	request accessrequest.DecisionRequest) (accessrequest.Response, error) {
+	var rsl accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/access-requests/%v/approve", id), request), &rsl)
+	return rsl, err
+}
+
+// RejectAccessRequest - POST /api/v1/access-requests/:id/reject
+func (c *Client) RejectAccessRequest(ctx context.Context, id int64,
+	request accessrequest.DecisionRequest) (accessrequest.Response, error) {
+	var rsl accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/access-requests/%v/reject", id), request), &rsl)
+	return rsl, err
+}
+
+// CancelAccessRequest - POST /api/v1/access-requests/:id/cancel
+func (c *Client) CancelAccessRequest(ctx context.Context, id int64) (accessrequest.Response, error) {
+	var rsl accessrequest.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/access-requests/%v/cancel", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindApprovalChain - GET /api/v1/roles/:id/approval-chain
+func (c *Client) FindApprovalChain(ctx context.Context, roleId int64) ([]accessrequest.Step, error) {
+	var rsl []accessrequest.Step
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/roles/%v/approval-chain", roleId), nil), &rsl)
+	return rsl, err
+}
+
+// SetApprovalChain - PUT /api/v1/roles/:id/approval-chain
+func (c *Client) SetApprovalChain(ctx context.Context, roleId int64, request accessrequest.ChainRequest) ([]accessrequest.Step, error) {
+	var rsl []accessrequest.Step
+	var err = c.call(ctx, newRequest(http.MethodPut, path("/api/v1/roles/%v/approval-chain", roleId), request), &rsl)
+	return rsl, err
+}
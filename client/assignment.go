@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"idm/inner/assignment"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Assign - POST /api/v1/assignments
+func (c *Client) Assign(ctx context.Context, request assignment.AssignRequest) (assignment.Response, error) {
+	var rsl assignment.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/assignments", request), &rsl)
+	return rsl, err
+}
+
+// Revoke - DELETE /api/v1/assignments
+func (c *Client) Revoke(ctx context.Context, request assignment.RevokeRequest) error {
+	return c.call(ctx, newRequest(http.MethodDelete, "/api/v1/assignments", request), nil)
+}
+
+// FindExpiring - GET /api/v1/assignments/expiring, назначения, истекающие в ближайшие days дней
+func (c *Client) FindExpiring(ctx context.Context, request assignment.ExpiringRequest) ([]assignment.Response, error) {
+	var rsl []assignment.Response
+	var req = newRequest(http.MethodGet, "/api/v1/assignments/expiring", nil)
+	if request.Days != 0 {
+		req.query = url.Values{"days": {strconv.Itoa(request.Days)}}
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
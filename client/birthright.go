@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"idm/inner/birthright"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AddBirthrightRule - POST /api/v1/birthright/rules, возвращает id правила
+func (c *Client) AddBirthrightRule(ctx context.Context, request birthright.CreateRequest) (int64, error) {
+	var id int64
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/birthright/rules", request), &id)
+	return id, err
+}
+
+// FindBirthrightRuleById - GET /api/v1/birthright/rules/:id
+func (c *Client) FindBirthrightRuleById(ctx context.Context, id int64) (birthright.Response, error) {
+	var rsl birthright.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/birthright/rules/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindAllBirthrightRules - GET /api/v1/birthright/rules
+func (c *Client) FindAllBirthrightRules(ctx context.Context) ([]birthright.Response, error) {
+	var rsl []birthright.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/birthright/rules", nil), &rsl)
+	return rsl, err
+}
+
+// DeleteBirthrightRuleById - DELETE /api/v1/birthright/rules/:id
+func (c *Client) DeleteBirthrightRuleById(ctx context.Context, id int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/birthright/rules/%v", id), nil), nil)
+}
+
+// BirthrightDiff - GET /api/v1/birthright/diff, изменения назначений без применения
+func (c *Client) BirthrightDiff(ctx context.Context, request birthright.DiffRequest) ([]birthright.Change, error) {
+	var rsl []birthright.Change
+	var err = c.call(ctx, birthrightRequest(http.MethodGet, "/api/v1/birthright/diff", request), &rsl)
+	return rsl, err
+}
+
+// BirthrightApply - POST /api/v1/birthright/apply
+func (c *Client) BirthrightApply(ctx context.Context, request birthright.DiffRequest) ([]birthright.Change, error) {
+	var rsl []birthright.Change
+	var err = c.call(ctx, birthrightRequest(http.MethodPost, "/api/v1/birthright/apply", request), &rsl)
+	return rsl, err
+}
+
+func birthrightRequest(method, path string, request birthright.DiffRequest) request {
+	var req = newRequest(method, path, nil)
+	if request.EmployeeId != 0 {
+		req.query = url.Values{"employee_id": {strconv.FormatInt(request.EmployeeId, 10)}}
+	}
+	return req
+}
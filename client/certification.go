@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"idm/inner/certification"
+	"net/http"
+	"net/url"
+)
+
+// CreateCertification - POST /api/v1/certifications
+func (c *Client) CreateCertification(ctx context.Context, request certification.CreateRequest) (certification.Response, error) {
+	var rsl certification.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/certifications", request), &rsl)
+	return rsl, err
+}
+
+// FindAllCertifications - GET /api/v1/certifications
+func (c *Client) FindAllCertifications(ctx context.Context) ([]certification.Response, error) {
+	var rsl []certification.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/certifications", nil), &rsl)
+	return rsl, err
+}
+
+// FindCertificationReviews - GET /api/v1/certifications/reviews, позиции, ждущие решения вызывающего
+func (c *Client) FindCertificationReviews(ctx context.Context) ([]certification.ItemResponse, error) {
+	var rsl []certification.ItemResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/certifications/reviews", nil), &rsl)
+	return rsl, err
+}
+
+// FindCertificationById - GET /api/v1/certifications/:id
+func (c *Client) FindCertificationById(ctx context.Context, id int64) (certification.Response, error) {
+	var rsl certification.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/certifications/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindCertificationItems - GET /api/v1/certifications/:id/items
+func (c *Client) FindCertificationItems(ctx context.Context, id int64) ([]certification.ItemResponse, error) {
+	var rsl []certification.ItemResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/certifications/%v/items", id), nil), &rsl)
+	return rsl, err
+}
+
+// CertificationReport - GET /api/v1/certifications/:id/report
+func (c *Client) CertificationReport(ctx context.Context, id int64) (certification.ReportResponse, error) {
+	var rsl certification.ReportResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/certifications/%v/report", id), nil), &rsl)
+	return rsl, err
+}
+
+// CertificationReportCsv - GET /api/v1/certifications/:id/report?format=csv, отчёт в CSV
+func (c *Client) CertificationReportCsv(ctx context.Context, id int64) ([]byte, error) {
+	var req = newRequest(http.MethodGet, path("/api/v1/certifications/%v/report", id), nil)
+	req.query = url.Values{"format": {"csv"}}
+	return c.send(ctx, req)
+}
+
+// DecideCertificationItem - POST /api/v1/certifications/:id/items/:itemId/decision
+func (c *Client) DecideCertificationItem(ctx context.Context, id, itemId int64,
+	request certification.DecisionRequest) (certification.ItemResponse, error) {
+	var rsl certification.ItemResponse
+	var err = c.call(ctx, newRequest(http.MethodPost,
+		path("/api/v1/certifications/%v/items/%v/decision", id, itemId), request), &rsl)
+	return rsl, err
+}
@@ -0,0 +1,239 @@
+// Package client - Go-клиент REST API IDM: типизированные методы эндпоинтов /api/v1 и /internal.
+// SCIM (/scim/v2) обслуживается стандартными SCIM-клиентами и сюда не входит
+//
+// Ошибки API возвращаются как *Error; повторы при 5xx и истечении Timeout настраиваются полями Client.
+// POST-запросы отправляются с Idempotency-Key, поэтому повтор не создаёт дубликатов
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/idempotency"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenSource - источник access-токена, который передаётся в заголовке Authorization
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken - заранее полученный токен
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// TokenFunc - функция как TokenSource, например, получение токена из Keycloak с кэшированием
+type TokenFunc func(ctx context.Context) (string, error)
+
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// Client - клиент API. Поля после NewClient можно менять до первого вызова
+type Client struct {
+	baseUrl string
+	tokens  TokenSource
+	// HttpClient - транспорт запросов, по умолчанию http.DefaultClient
+	HttpClient *http.Client
+	// Timeout - предельное время одной попытки, 0 - без ограничения
+	Timeout time.Duration
+	// MaxAttempts - число попыток запроса при ответе 5xx, 408 или истечении времени
+	MaxAttempts int
+	// RetryInterval - пауза перед второй попыткой, перед каждой следующей удваивается
+	RetryInterval time.Duration
+}
+
+// NewClient - baseUrl - адрес приложения без пути, например "https://idm.local:8080";
+// tokens - nil, если API вызывается без токена
+func NewClient(baseUrl string, tokens TokenSource) *Client {
+	return &Client{
+		baseUrl:       strings.TrimRight(baseUrl, "/"),
+		tokens:        tokens,
+		HttpClient:    http.DefaultClient,
+		Timeout:       30 * time.Second,
+		MaxAttempts:   3,
+		RetryInterval: 500 * time.Millisecond,
+	}
+}
+
+// idempotencyKeyCtx - ключ контекста с Idempotency-Key, заданным вызывающим
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey - POST-запрос с ctx отправляется с ключом key. Без него клиент генерирует ключ
+// на каждый вызов сам; свой ключ нужен, чтобы повтор после перезапуска процесса не создал дубль
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// request - запрос к API; body - JSON-значение, []byte или nil
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        any
+	contentType string
+	header      http.Header
+}
+
+func newRequest(method, path string, body any) request {
+	return request{method: method, path: path, body: body}
+}
+
+// call - выполняет запрос и декодирует data ответа-обёртки common.Response в out (nil - не нужно)
+func (c *Client) call(ctx context.Context, req request, out any) error {
+	var body, err = c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	var envelope = common.Response[json.RawMessage]{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("idm: decoding response of %s %s: %w", req.method, req.path, err)
+	}
+	if !envelope.Success {
+		return &Error{StatusCode: http.StatusOK, Message: envelope.Message}
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("idm: decoding data of %s %s: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// send - выполняет запрос с повторами и возвращает тело успешного (2xx) ответа
+func (c *Client) send(ctx context.Context, req request) ([]byte, error) {
+	var payload []byte
+	switch body := req.body.(type) {
+	case nil:
+	case []byte:
+		payload = body
+	default:
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("idm: encoding request of %s %s: %w", req.method, req.path, err)
+		}
+		if req.contentType == "" {
+			req.contentType = "application/json"
+		}
+	}
+	if req.method == http.MethodPost {
+		// один ключ на все попытки: повтор после обрыва связи получит сохранённый сервером ответ
+		var key, _ = ctx.Value(idempotencyKeyCtx{}).(string)
+		if key == "" {
+			key = rand.Text()
+		}
+		req.header = req.header.Clone()
+		if req.header == nil {
+			req.header = http.Header{}
+		}
+		req.header.Set(idempotency.Header, key)
+	}
+	var attempts = max(c.MaxAttempts, 1)
+	var interval = c.RetryInterval
+	for attempt := 1; ; attempt++ {
+		body, err := c.attempt(ctx, req, payload)
+		if err == nil || attempt == attempts || !retryable(ctx, err) {
+			return body, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req request, payload []byte) ([]byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	resp, err := c.do(ctx, req, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("idm: reading response of %s %s: %w", req.method, req.path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, decodeError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// do - один HTTP-запрос; тело ответа закрывает вызывающий
+func (c *Client) do(ctx context.Context, req request, payload []byte) (*http.Response, error) {
+	var target = c.baseUrl + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("idm: building request %s %s: %w", req.method, req.path, err)
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("idm: getting token: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	var httpClient = c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("idm: %s %s: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
+
+// retryable - стоит ли повторить запрос: ошибка сервера или истекло время попытки, но не всего вызова
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusRequestTimeout
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func path(format string, args ...any) string {
+	var escaped = make([]any, len(args))
+	for i, arg := range args {
+		escaped[i] = url.PathEscape(fmt.Sprint(arg))
+	}
+	return fmt.Sprintf(format, escaped...)
+}
@@ -0,0 +1,476 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/idempotency"
+	"idm/inner/role"
+	"idm/inner/web"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+var secret = []byte("secret")
+
+type MockEmployeeService struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeService) Add(ctx context.Context, e employee.Entity) (employee.Response, error) {
+	args := m.Called(ctx, e)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) DeleteByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) DeleteById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAll(ctx context.Context) ([]employee.Response, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAllWithLimitOffset(ctx context.Context, req employee.PageRequest) (employee.PageResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(employee.PageResponse), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAllWithLimitOffsetAsOf(ctx context.Context, req employee.PageRequest, asOf time.Time) (employee.PageResponse, error) {
+	args := m.Called(ctx, req, asOf)
+	return args.Get(0).(employee.PageResponse), args.Error(1)
+}
+
+func (m *MockEmployeeService) Move(ctx context.Context, id int64, request employee.MoveRequest) (employee.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (employee.Response, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindHistory(ctx context.Context, id int64) ([]employee.HistoryResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]employee.HistoryResponse), args.Error(1)
+}
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) Add(r role.Entity) (role.Response, error) {
+	args := m.Called(r)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindById(id int64) (role.Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindByIds(ids []int64) ([]role.Response, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) DeleteByIds(ctx context.Context, actor string, ids []int64) ([]role.Response, error) {
+	args := m.Called(ctx, actor, ids)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) DeleteById(ctx context.Context, actor string, id int64) (role.Response, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindAll() ([]role.Entity, error) {
+	args := m.Called()
+	return args.Get(0).([]role.Entity), args.Error(1)
+}
+
+func (m *MockRoleService) AddComposite(ctx context.Context, parentId int64, request role.CompositeRequest) error {
+	return m.Called(ctx, parentId, request).Error(0)
+}
+
+func (m *MockRoleService) DeleteComposite(ctx context.Context, parentId, childId int64) error {
+	return m.Called(ctx, parentId, childId).Error(0)
+}
+
+func (m *MockRoleService) FindComposites(ctx context.Context, parentId int64) ([]role.Response, error) {
+	args := m.Called(ctx, parentId)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]role.EffectiveRoleResponse), args.Error(1)
+}
+
+func (m *MockRoleService) Update(ctx context.Context, id int64, request role.Request) (role.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+// appTransport - запросы клиента обслуживает приложение Fiber через App.Test, без сети
+type appTransport struct {
+	app *fiber.App
+}
+
+func (t appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	var done = make(chan result, 1)
+	go func() {
+		resp, err := t.app.Test(req, -1)
+		done <- result{resp, err}
+	}()
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// newTestServer - приложение с хендлерами сотрудников и ролей; токен проверяется, как AuthMiddleware,
+// но подписан HS256
+func newTestServer(employees employee.Svc, roles role.Svc) *web.Server {
+	var logger = &common.Logger{Logger: zap.NewNop()}
+	var server = web.NewServer()
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		var claims = &web.IdmClaims{}
+		var token, err = jwt.ParseWithClaims(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "), claims,
+			func(*jwt.Token) (any, error) { return secret, nil })
+		if err != nil {
+			return common.ErrResponse(c, fiber.StatusUnauthorized, err.Error())
+		}
+		c.Locals(web.JwtKey, token)
+		return c.Next()
+	})
+	employee.NewHandler(server, employees, logger).RegisterRoutes()
+	role.NewHandler(server, roles, logger).RegisterRouters()
+	return server
+}
+
+func newTestClient(t *testing.T, app *fiber.App, roles ...string) *Client {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &web.IdmClaims{
+		RealmAccess:       web.RealmAccessClaims{Roles: roles},
+		PreferredUsername: "john",
+	}).SignedString(secret)
+	assert.NoError(t, err)
+	var client = NewClient("http://idm.local/", StaticToken(token))
+	client.HttpClient = &http.Client{Transport: appTransport{app: app}}
+	client.RetryInterval = time.Millisecond
+	return client
+}
+
+func TestEmployees(t *testing.T) {
+	t.Run("Should send token and decode response envelope", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		client := newTestClient(t, newTestServer(svc, new(MockRoleService)).App, web.IdmUser)
+		var createdAt = time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC)
+		svc.On("FindById", mock.Anything, int64(1)).
+			Return(employee.Response{Id: 1, Name: "John", Age: 30, CreatedAt: createdAt}, nil)
+
+		got, err := client.FindEmployeeById(context.Background(), 1)
+
+		a.NoError(err)
+		a.Equal(employee.Response{Id: 1, Name: "John", Age: 30, CreatedAt: createdAt}, got)
+	})
+
+	t.Run("Should decode failures into typed errors", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		server := newTestServer(svc, new(MockRoleService))
+		client := newTestClient(t, server.App, web.IdmUser)
+		svc.On("FindById", mock.Anything, int64(2)).Return(employee.Response{}, common.NotFoundError{Message: "employee not found"})
+
+		_, err := client.FindEmployeeById(context.Background(), 2)
+		a.True(errors.As(err, &common.NotFoundError{}))
+		var apiErr *Error
+		a.True(errors.As(err, &apiErr))
+		a.Equal(http.StatusNotFound, apiErr.StatusCode)
+		a.Equal("employee not found", apiErr.Message)
+
+		_, err = client.DeleteEmployeeById(context.Background(), 2)
+		a.True(errors.As(err, &common.ForbiddenError{}))
+
+		var anonymous = NewClient("http://idm.local", nil)
+		anonymous.HttpClient = &http.Client{Transport: appTransport{app: server.App}}
+		_, err = anonymous.FindEmployeeById(context.Background(), 2)
+		a.True(errors.As(err, &apiErr))
+		a.Equal(http.StatusUnauthorized, apiErr.StatusCode)
+		svc.AssertNotCalled(t, "DeleteById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should iterate over all pages", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		client := newTestClient(t, newTestServer(svc, new(MockRoleService)).App, web.IdmUser)
+		var page = employee.PageRequest{PageSize: 2, TextFilter: "ov"}
+		svc.On("FindAllWithLimitOffset", mock.Anything, page).
+			Return(employee.PageResponse{Result: []employee.Response{{Id: 1}, {Id: 2}}, Total: 3}, nil)
+		page.PageNumber = 1
+		svc.On("FindAllWithLimitOffset", mock.Anything, page).
+			Return(employee.PageResponse{Result: []employee.Response{{Id: 3}}, Total: 3}, nil)
+
+		var ids []int64
+		for e, err := range client.Employees(context.Background(), employee.PageRequest{PageSize: 2, TextFilter: "ov"}) {
+			a.NoError(err)
+			ids = append(ids, e.Id)
+		}
+
+		a.Equal([]int64{1, 2, 3}, ids)
+		svc.AssertNumberOfCalls(t, "FindAllWithLimitOffset", 2)
+	})
+
+	t.Run("Should stop iteration on error", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		client := newTestClient(t, newTestServer(svc, new(MockRoleService)).App, web.IdmUser)
+		svc.On("FindAllWithLimitOffset", mock.Anything, mock.Anything).
+			Return(employee.PageResponse{}, common.RequestValidationError{Message: "invalid page size"})
+
+		var errs []error
+		for _, err := range client.Employees(context.Background(), employee.PageRequest{PageSize: 500}) {
+			errs = append(errs, err)
+		}
+
+		a.Len(errs, 1)
+		a.True(errors.As(errs[0], &common.RequestValidationError{}))
+		svc.AssertNumberOfCalls(t, "FindAllWithLimitOffset", 1)
+	})
+}
+
+func TestRoles(t *testing.T) {
+	t.Run("Should create role and manage composites", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockRoleService)
+		client := newTestClient(t, newTestServer(new(MockEmployeeService), svc).App, web.IdmAdmin, web.IdmUser)
+		var days = int32(30)
+		svc.On("Add", mock.MatchedBy(func(r role.Entity) bool { return r.Name == "auditor" })).
+			Return(role.Response{Id: 4, Name: "auditor", MaxAssignmentDays: &days}, nil)
+		svc.On("AddComposite", mock.Anything, int64(4), role.CompositeRequest{ChildRoleId: 5}).Return(nil)
+		svc.On("FindEffectiveRoles", mock.Anything, int64(9)).
+			Return([]role.EffectiveRoleResponse{{Id: 5, Name: "viewer", Paths: [][]string{{"auditor", "viewer"}}}}, nil)
+
+		created, err := client.AddRole(context.Background(), role.Request{Name: "auditor", MaxAssignmentDays: &days})
+		a.NoError(err)
+		a.Equal(int64(4), created.Id)
+		a.Equal(&days, created.MaxAssignmentDays)
+		a.NoError(client.AddComposite(context.Background(), 4, role.CompositeRequest{ChildRoleId: 5}))
+		effective, err := client.FindEffectiveRoles(context.Background(), 9)
+		a.NoError(err)
+		a.Equal([][]string{{"auditor", "viewer"}}, effective[0].Paths)
+	})
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("Should retry server errors and give up after MaxAttempts", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int32
+		var app = fiber.New()
+		app.Get("/internal/info", func(c *fiber.Ctx) error {
+			if calls.Add(1) < 3 {
+				return common.ErrResponse(c, fiber.StatusServiceUnavailable, "database is not available")
+			}
+			return c.JSON(fiber.Map{"name": "idm", "version": "1.0"})
+		})
+		client := newTestClient(t, app)
+
+		got, err := client.Info(context.Background())
+		a.NoError(err)
+		a.Equal("idm", got.Name)
+		a.Equal(int32(3), calls.Load())
+
+		calls.Store(-10)
+		_, err = client.Info(context.Background())
+		var apiErr *Error
+		a.True(errors.As(err, &apiErr))
+		a.Equal(http.StatusServiceUnavailable, apiErr.StatusCode)
+		a.Equal("database is not available", apiErr.Message)
+		a.Equal(int32(-7), calls.Load())
+	})
+
+	t.Run("Should not retry client errors", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int32
+		var app = fiber.New()
+		app.Get("/internal/info", func(c *fiber.Ctx) error {
+			calls.Add(1)
+			return common.ErrResponse(c, fiber.StatusBadRequest, "bad request")
+		})
+		client := newTestClient(t, app)
+
+		_, err := client.Info(context.Background())
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.Equal(int32(1), calls.Load())
+	})
+
+	t.Run("Should retry attempt that timed out", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int32
+		var app = fiber.New()
+		app.Get("/internal/health", func(c *fiber.Ctx) error {
+			if calls.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return c.SendString("OK")
+		})
+		client := newTestClient(t, app)
+		client.Timeout = 50 * time.Millisecond
+
+		a.NoError(client.Health(context.Background()))
+		a.Equal(int32(2), calls.Load())
+	})
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("Should read events after Last-Event-ID", func(t *testing.T) {
+		a := assert.New(t)
+		var app = fiber.New()
+		var lastEventId string
+		app.Get("/api/v1/events/stream", func(c *fiber.Ctx) error {
+			lastEventId = c.Get("Last-Event-ID")
+			c.Set(fiber.HeaderContentType, "text/event-stream")
+			return c.SendString("retry: 3000\n\n: ping\n\n" +
+				"id: 8\nevent: EmployeeCreated\ndata: {\"id\":1,\"type\":\"EmployeeCreated\",\"aggregate_id\":5}\n\n" +
+				"id: 9\nevent: RoleDeleted\ndata: {\"id\":2,\"type\":\"RoleDeleted\",\"aggregate_id\":3}\n\n")
+		})
+		client := newTestClient(t, app)
+
+		var seqs []int64
+		var types []string
+		for e, err := range client.Events(context.Background(), "7") {
+			a.NoError(err)
+			seqs = append(seqs, e.Seq)
+			types = append(types, e.Message.Type)
+		}
+
+		a.Equal("7", lastEventId)
+		a.Equal([]int64{8, 9}, seqs)
+		a.Equal([]string{"EmployeeCreated", "RoleDeleted"}, types)
+	})
+}
+
+func TestClientCredentials(t *testing.T) {
+	t.Run("Should request token once and reuse it until expiry", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int32
+		var app = fiber.New()
+		app.Post("/realms/idm/protocol/openid-connect/token", func(c *fiber.Ctx) error {
+			calls.Add(1)
+			if c.FormValue("grant_type") != "client_credentials" || c.FormValue("client_secret") != "s3cret" {
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+			return c.JSON(fiber.Map{"access_token": "token-" + c.FormValue("client_id"), "expires_in": 300})
+		})
+		var source = &ClientCredentials{
+			TokenUrl:     KeycloakTokenUrl("http://keycloak.local/", "idm"),
+			ClientId:     "idmctl",
+			ClientSecret: "s3cret",
+			HttpClient:   &http.Client{Transport: appTransport{app: app}},
+		}
+
+		first, err := source.Token(context.Background())
+		a.NoError(err)
+		second, err := source.Token(context.Background())
+		a.NoError(err)
+
+		a.Equal("token-idmctl", first)
+		a.Equal(first, second)
+		a.Equal(int32(1), calls.Load())
+
+		source = &ClientCredentials{TokenUrl: source.TokenUrl, ClientId: "idmctl", ClientSecret: "wrong",
+			HttpClient: source.HttpClient}
+		_, err = source.Token(context.Background())
+		a.ErrorContains(err, "status 401")
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Run("Should send the same key on every attempt of a call", func(t *testing.T) {
+		a := assert.New(t)
+		var keys []string
+		var app = fiber.New()
+		app.Post("/api/v1/roles/add", func(c *fiber.Ctx) error {
+			keys = append(keys, strings.Clone(c.Get(idempotency.Header)))
+			if len(keys) == 1 {
+				return common.ErrResponse(c, fiber.StatusBadGateway, "upstream is not available")
+			}
+			return common.OkResponse(c, role.Response{Id: 1, Name: "ANALYST"})
+		})
+		client := newTestClient(t, app)
+
+		_, err := client.AddRole(context.Background(), role.Request{Name: "ANALYST"})
+		a.NoError(err)
+		_, err = client.AddRole(context.Background(), role.Request{Name: "ANALYST"})
+		a.NoError(err)
+
+		a.Len(keys, 3)
+		a.NotEmpty(keys[0])
+		a.Equal(keys[0], keys[1])
+		a.NotEqual(keys[1], keys[2])
+	})
+
+	t.Run("Should use key from context and not send it on GET", func(t *testing.T) {
+		a := assert.New(t)
+		var postKey, getKey string
+		var app = fiber.New()
+		app.Post("/api/v1/roles/add", func(c *fiber.Ctx) error {
+			postKey = strings.Clone(c.Get(idempotency.Header))
+			return common.OkResponse(c, role.Response{Id: 1})
+		})
+		app.Get("/api/v1/roles", func(c *fiber.Ctx) error {
+			getKey = strings.Clone(c.Get(idempotency.Header))
+			return common.OkResponse(c, []role.Entity{})
+		})
+		client := newTestClient(t, app)
+		var ctx = WithIdempotencyKey(context.Background(), "import-row-7")
+
+		_, err := client.AddRole(ctx, role.Request{Name: "ANALYST"})
+		a.NoError(err)
+		_, err = client.FindAllRoles(ctx)
+		a.NoError(err)
+
+		a.Equal("import-row-7", postKey)
+		a.Empty(getKey)
+	})
+}
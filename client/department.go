@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"idm/inner/department"
+	"net/http"
+)
+
+// AddDepartmentAdmin - POST /api/v1/departments/:id/admins
+func (c *Client) AddDepartmentAdmin(ctx context.Context, departmentId int64,
+	request department.AdminRequest) (department.AdminResponse, error) {
+	var rsl department.AdminResponse
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/departments/%v/admins", departmentId), request), &rsl)
+	return rsl, err
+}
+
+// FindDepartmentAdmins - GET /api/v1/departments/:id/admins
+func (c *Client) FindDepartmentAdmins(ctx context.Context, departmentId int64) ([]department.AdminResponse, error) {
+	var rsl []department.AdminResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/departments/%v/admins", departmentId), nil), &rsl)
+	return rsl, err
+}
+
+// DeleteDepartmentAdmin - DELETE /api/v1/departments/:id/admins/:employeeId
+func (c *Client) DeleteDepartmentAdmin(ctx context.Context, departmentId, employeeId int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete,
+		path("/api/v1/departments/%v/admins/%v", departmentId, employeeId), nil), nil)
+}
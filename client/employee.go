@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"idm/inner/employee"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CreateEmployee - POST /api/v1/employees, возвращает id сотрудника
+func (c *Client) CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error) {
+	var id int64
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/employees", request), &id)
+	return id, err
+}
+
+// AddEmployee - POST /api/v1/employees/add
+func (c *Client) AddEmployee(ctx context.Context, entity employee.Entity) (employee.Response, error) {
+	var rsl employee.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/employees/add", entity), &rsl)
+	return rsl, err
+}
+
+// FindEmployeeById - POST /api/v1/employees/:id
+func (c *Client) FindEmployeeById(ctx context.Context, id int64) (employee.Response, error) {
+	var rsl employee.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/employees/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindEmployeeByIdAsOf - состояние сотрудника на момент asOf
+func (c *Client) FindEmployeeByIdAsOf(ctx context.Context, id int64, asOf time.Time) (employee.Response, error) {
+	var rsl employee.Response
+	var req = newRequest(http.MethodPost, path("/api/v1/employees/%v", id), nil)
+	req.query = url.Values{"as_of": {asOf.Format(time.RFC3339)}}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
+
+// FindEmployeeHistory - GET /api/v1/employees/:id/history
+func (c *Client) FindEmployeeHistory(ctx context.Context, id int64) ([]employee.HistoryResponse, error) {
+	var rsl []employee.HistoryResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/employees/%v/history", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindEmployeesByIds - POST /api/v1/employees/ids
+func (c *Client) FindEmployeesByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	var rsl []employee.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/employees/ids", ids), &rsl)
+	return rsl, err
+}
+
+// MoveEmployee - POST /api/v1/employees/:id/move
+func (c *Client) MoveEmployee(ctx context.Context, id int64, request employee.MoveRequest) (employee.Response, error) {
+	var rsl employee.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/employees/%v/move", id), request), &rsl)
+	return rsl, err
+}
+
+// DeleteEmployeeById - DELETE /api/v1/employees/:id
+func (c *Client) DeleteEmployeeById(ctx context.Context, id int64) (employee.Response, error) {
+	var rsl employee.Response
+	var err = c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/employees/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// DeleteEmployeesByIds - DELETE /api/v1/employees/ids
+func (c *Client) DeleteEmployeesByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	var rsl []employee.Response
+	var err = c.call(ctx, newRequest(http.MethodDelete, "/api/v1/employees/ids", ids), &rsl)
+	return rsl, err
+}
+
+// FindAllEmployees - GET /api/v1/employees
+func (c *Client) FindAllEmployees(ctx context.Context) ([]employee.Response, error) {
+	var rsl []employee.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/employees", nil), &rsl)
+	return rsl, err
+}
+
+// FindEmployeesPage - GET /api/v1/employees/page, одна страница
+func (c *Client) FindEmployeesPage(ctx context.Context, request employee.PageRequest) (employee.PageResponse, error) {
+	var rsl employee.PageResponse
+	var req = newRequest(http.MethodGet, "/api/v1/employees/page", nil)
+	req.query = url.Values{
+		"page_number": {strconv.Itoa(request.PageNumber)},
+		"page_size":   {strconv.Itoa(request.PageSize)},
+	}
+	if request.TextFilter != "" {
+		req.query.Set("text_filter", request.TextFilter)
+	}
+	if request.AsOf != "" {
+		req.query.Set("as_of", request.AsOf)
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
+
+// Employees - все сотрудники под фильтром request, начиная со страницы request.PageNumber.
+// Страницы запрашиваются по мере перебора; на ошибке перебор останавливается после её выдачи
+func (c *Client) Employees(ctx context.Context, request employee.PageRequest) iter.Seq2[employee.Response, error] {
+	return func(yield func(employee.Response, error) bool) {
+		var next = request
+		if next.PageSize == 0 {
+			next.PageSize = 100
+		}
+		for {
+			page, err := c.FindEmployeesPage(ctx, next)
+			if err != nil {
+				yield(employee.Response{}, err)
+				return
+			}
+			for _, e := range page.Result {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			if len(page.Result) < next.PageSize || int64((next.PageNumber+1)*next.PageSize) >= page.Total {
+				return
+			}
+			next.PageNumber++
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"idm/inner/common"
+	"net/http"
+	"strings"
+)
+
+// Error - неуспешный ответ API. Через errors.As доступна и ошибка сервиса, соответствующая
+// статусу: 400 - common.RequestValidationError, 403 - common.ForbiddenError,
+// 404 - common.NotFoundError
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("idm: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return common.RequestValidationError{Message: e.Message}
+	case http.StatusForbidden:
+		return common.ForbiddenError{Message: e.Message}
+	case http.StatusNotFound:
+		return common.NotFoundError{Message: e.Message}
+	default:
+		return nil
+	}
+}
+
+// decodeError - сообщение из обёртки common.Response, а если тело не в JSON - текст ответа
+func decodeError(statusCode int, body []byte) error {
+	var envelope common.Response[json.RawMessage]
+	var message string
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		message = envelope.Message
+	} else {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &Error{StatusCode: statusCode, Message: message}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"idm/inner/stream"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Events - GET /api/v1/events/stream: события после lastEventId ("" - только новые), пока ctx
+// не отменён или сервер не закрыл поток. Повторов и ограничения Timeout нет: после ошибки
+// подключение продолжают с Seq последнего полученного события
+func (c *Client) Events(ctx context.Context, lastEventId string) iter.Seq2[stream.Event, error] {
+	return func(yield func(stream.Event, error) bool) {
+		var req = newRequest(http.MethodGet, "/api/v1/events/stream", nil)
+		if lastEventId != "" {
+			req.header = http.Header{"Last-Event-Id": {lastEventId}}
+		}
+		resp, err := c.do(ctx, req, nil)
+		if err != nil {
+			yield(stream.Event{}, err)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			yield(stream.Event{}, decodeError(resp.StatusCode, body))
+			return
+		}
+		var scanner = bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		var event stream.Event
+		var data strings.Builder
+		for scanner.Scan() {
+			var line = scanner.Text()
+			if line != "" {
+				field, value, _ := strings.Cut(line, ":")
+				value = strings.TrimPrefix(value, " ")
+				switch field {
+				case "id":
+					if event.Seq, err = strconv.ParseInt(value, 10, 64); err != nil {
+						yield(stream.Event{}, fmt.Errorf("idm: invalid event id %q", value))
+						return
+					}
+				case "data":
+					if data.Len() > 0 {
+						data.WriteByte('\n')
+					}
+					data.WriteString(value)
+				}
+				continue
+			}
+			if data.Len() == 0 {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data.String()), &event.Message); err != nil {
+				yield(stream.Event{}, fmt.Errorf("idm: decoding event %d: %w", event.Seq, err))
+				return
+			}
+			if !yield(event, nil) {
+				return
+			}
+			event = stream.Event{}
+			data.Reset()
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			yield(stream.Event{}, fmt.Errorf("idm: reading events: %w", err))
+		}
+	}
+}
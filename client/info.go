@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"idm/inner/info"
+	"net/http"
+)
+
+// Info - GET /internal/info, имя и версия приложения
+func (c *Client) Info(ctx context.Context) (info.InfoResponse, error) {
+	var rsl info.InfoResponse
+	body, err := c.send(ctx, newRequest(http.MethodGet, "/internal/info", nil))
+	if err != nil {
+		return rsl, err
+	}
+	if err := json.Unmarshal(body, &rsl); err != nil {
+		return rsl, fmt.Errorf("idm: decoding info: %w", err)
+	}
+	return rsl, nil
+}
+
+// Health - GET /internal/health; ошибка, если приложение не может обслуживать запросы
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.send(ctx, newRequest(http.MethodGet, "/internal/health", nil))
+	return err
+}
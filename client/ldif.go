@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"idm/inner/ldif"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ExportLdif - GET /api/v1/ldif/export, каталог в формате LDIF
+func (c *Client) ExportLdif(ctx context.Context) ([]byte, error) {
+	return c.send(ctx, newRequest(http.MethodGet, "/api/v1/ldif/export", nil))
+}
+
+// ImportLdif - POST /api/v1/ldif/import; data - содержимое LDIF-файла
+func (c *Client) ImportLdif(ctx context.Context, data []byte, request ldif.ImportRequest) (ldif.ImportResponse, error) {
+	var rsl ldif.ImportResponse
+	var req = newRequest(http.MethodPost, "/api/v1/ldif/import", data)
+	req.contentType = ldif.ContentType
+	req.query = url.Values{}
+	if request.DryRun {
+		req.query.Set("dry_run", "true")
+	}
+	if request.DefaultAge != 0 {
+		req.query.Set("default_age", strconv.Itoa(int(request.DefaultAge)))
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"idm/inner/me"
+	"net/http"
+)
+
+// FindMe - GET /api/v1/me, профиль владельца токена
+func (c *Client) FindMe(ctx context.Context) (me.Response, error) {
+	var rsl me.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/me", nil), &rsl)
+	return rsl, err
+}
+
+// UpdateMe - PATCH /api/v1/me
+func (c *Client) UpdateMe(ctx context.Context, request me.UpdateRequest) (me.Response, error) {
+	var rsl me.Response
+	var err = c.call(ctx, newRequest(http.MethodPatch, "/api/v1/me", request), &rsl)
+	return rsl, err
+}
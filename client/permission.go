@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"idm/inner/permission"
+	"net/http"
+)
+
+// AddPermission - POST /api/v1/permissions
+func (c *Client) AddPermission(ctx context.Context, request permission.Request) (permission.Response, error) {
+	var rsl permission.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/permissions", request), &rsl)
+	return rsl, err
+}
+
+// FindPermissionById - GET /api/v1/permissions/:id
+func (c *Client) FindPermissionById(ctx context.Context, id int64) (permission.Response, error) {
+	var rsl permission.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/permissions/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindAllPermissions - GET /api/v1/permissions
+func (c *Client) FindAllPermissions(ctx context.Context) ([]permission.Response, error) {
+	var rsl []permission.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/permissions", nil), &rsl)
+	return rsl, err
+}
+
+// UpdatePermission - PUT /api/v1/permissions/:id
+func (c *Client) UpdatePermission(ctx context.Context, id int64, request permission.Request) (permission.Response, error) {
+	var rsl permission.Response
+	var err = c.call(ctx, newRequest(http.MethodPut, path("/api/v1/permissions/%v", id), request), &rsl)
+	return rsl, err
+}
+
+// DeletePermissionById - DELETE /api/v1/permissions/:id
+func (c *Client) DeletePermissionById(ctx context.Context, id int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/permissions/%v", id), nil), nil)
+}
+
+// FindRolePermissions - GET /api/v1/roles/:id/permissions
+func (c *Client) FindRolePermissions(ctx context.Context, roleId int64) ([]permission.Response, error) {
+	var rsl []permission.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/roles/%v/permissions", roleId), nil), &rsl)
+	return rsl, err
+}
+
+// AddPermissionToRole - POST /api/v1/roles/:id/permissions
+func (c *Client) AddPermissionToRole(ctx context.Context, roleId int64, request permission.RoleRequest) error {
+	return c.call(ctx, newRequest(http.MethodPost, path("/api/v1/roles/%v/permissions", roleId), request), nil)
+}
+
+// RemovePermissionFromRole - DELETE /api/v1/roles/:id/permissions/:permissionId
+func (c *Client) RemovePermissionFromRole(ctx context.Context, roleId, permissionId int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete,
+		path("/api/v1/roles/%v/permissions/%v", roleId, permissionId), nil), nil)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"idm/inner/provisioning"
+	"net/http"
+	"net/url"
+)
+
+// FindProvisioningStatuses - GET /api/v1/provisioning/status
+func (c *Client) FindProvisioningStatuses(ctx context.Context,
+	request provisioning.StatusRequest) ([]provisioning.StatusResponse, error) {
+	var rsl []provisioning.StatusResponse
+	var req = newRequest(http.MethodGet, "/api/v1/provisioning/status", nil)
+	if request.State != "" {
+		req.query = url.Values{"state": {request.State}}
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
+
+// FindProvisioningStatus - GET /api/v1/provisioning/status/:employeeId
+func (c *Client) FindProvisioningStatus(ctx context.Context, employeeId int64) (provisioning.StatusResponse, error) {
+	var rsl provisioning.StatusResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/provisioning/status/%v", employeeId), nil), &rsl)
+	return rsl, err
+}
+
+// RetryProvisioning - POST /api/v1/provisioning/status/:employeeId/retry
+func (c *Client) RetryProvisioning(ctx context.Context, employeeId int64) error {
+	return c.call(ctx, newRequest(http.MethodPost, path("/api/v1/provisioning/status/%v/retry", employeeId), nil), nil)
+}
+
+// Reconcile - POST /internal/reconcile; apply - исправить найденные расхождения
+func (c *Client) Reconcile(ctx context.Context, request provisioning.ReconcileRequest) (provisioning.Report, error) {
+	var rsl provisioning.Report
+	var req = newRequest(http.MethodPost, "/internal/reconcile", nil)
+	if request.Apply {
+		req.query = url.Values{"apply": {"true"}}
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
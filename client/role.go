@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"idm/inner/role"
+	"net/http"
+)
+
+// AddRole - POST /api/v1/roles/add
+func (c *Client) AddRole(ctx context.Context, request role.Request) (role.Response, error) {
+	var rsl role.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/roles/add", request), &rsl)
+	return rsl, err
+}
+
+// FindRoleById - POST /api/v1/roles/:id
+func (c *Client) FindRoleById(ctx context.Context, id int64) (role.Response, error) {
+	var rsl role.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/roles/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindRolesByIds - POST /api/v1/roles/ids
+func (c *Client) FindRolesByIds(ctx context.Context, ids []int64) ([]role.Response, error) {
+	var rsl []role.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/roles/ids", ids), &rsl)
+	return rsl, err
+}
+
+// FindAllRoles - GET /api/v1/roles
+func (c *Client) FindAllRoles(ctx context.Context) ([]role.Entity, error) {
+	var rsl []role.Entity
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/roles", nil), &rsl)
+	return rsl, err
+}
+
+// UpdateRole - PUT /api/v1/roles/:id
+func (c *Client) UpdateRole(ctx context.Context, id int64, request role.Request) (role.Response, error) {
+	var rsl role.Response
+	var err = c.call(ctx, newRequest(http.MethodPut, path("/api/v1/roles/%v", id), request), &rsl)
+	return rsl, err
+}
+
+// DeleteRoleById - DELETE /api/v1/roles/:id
+func (c *Client) DeleteRoleById(ctx context.Context, id int64) (role.Response, error) {
+	var rsl role.Response
+	var err = c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/roles/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// DeleteRolesByIds - DELETE /api/v1/roles/ids
+func (c *Client) DeleteRolesByIds(ctx context.Context, ids []int64) ([]role.Response, error) {
+	var rsl []role.Response
+	var err = c.call(ctx, newRequest(http.MethodDelete, "/api/v1/roles/ids", ids), &rsl)
+	return rsl, err
+}
+
+// FindComposites - GET /api/v1/roles/:id/composites
+func (c *Client) FindComposites(ctx context.Context, parentId int64) ([]role.Response, error) {
+	var rsl []role.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/roles/%v/composites", parentId), nil), &rsl)
+	return rsl, err
+}
+
+// AddComposite - POST /api/v1/roles/:id/composites
+func (c *Client) AddComposite(ctx context.Context, parentId int64, request role.CompositeRequest) error {
+	return c.call(ctx, newRequest(http.MethodPost, path("/api/v1/roles/%v/composites", parentId), request), nil)
+}
+
+// DeleteComposite - DELETE /api/v1/roles/:id/composites/:childId
+func (c *Client) DeleteComposite(ctx context.Context, parentId, childId int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete,
+		path("/api/v1/roles/%v/composites/%v", parentId, childId), nil), nil)
+}
+
+// FindEffectiveRoles - GET /api/v1/employees/:id/effective-roles
+func (c *Client) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error) {
+	var rsl []role.EffectiveRoleResponse
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/employees/%v/effective-roles", employeeId), nil), &rsl)
+	return rsl, err
+}
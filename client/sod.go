@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"idm/inner/sod"
+	"net/http"
+)
+
+// AddSodRule - POST /api/v1/sod/rules, возвращает id правила
+func (c *Client) AddSodRule(ctx context.Context, request sod.CreateRequest) (int64, error) {
+	var id int64
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/sod/rules", request), &id)
+	return id, err
+}
+
+// FindSodRuleById - GET /api/v1/sod/rules/:id
+func (c *Client) FindSodRuleById(ctx context.Context, id int64) (sod.Response, error) {
+	var rsl sod.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/sod/rules/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// FindAllSodRules - GET /api/v1/sod/rules
+func (c *Client) FindAllSodRules(ctx context.Context) ([]sod.Response, error) {
+	var rsl []sod.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/sod/rules", nil), &rsl)
+	return rsl, err
+}
+
+// DeleteSodRuleById - DELETE /api/v1/sod/rules/:id
+func (c *Client) DeleteSodRuleById(ctx context.Context, id int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/sod/rules/%v", id), nil), nil)
+}
+
+// FindSodViolations - GET /api/v1/sod/violations
+func (c *Client) FindSodViolations(ctx context.Context) ([]sod.Violation, error) {
+	var rsl []sod.Violation
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/sod/violations", nil), &rsl)
+	return rsl, err
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenLeeway - токен обновляется заранее, чтобы не истёк во время запроса
+const tokenLeeway = 30 * time.Second
+
+// ClientCredentials - TokenSource, получающий токен сервисного клиента по OAuth 2.0
+// client credentials и кэширующий его до истечения
+type ClientCredentials struct {
+	// TokenUrl - token endpoint, для Keycloak "<адрес>/realms/<realm>/protocol/openid-connect/token"
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	// HttpClient - транспорт запросов токена, по умолчанию http.DefaultClient
+	HttpClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// KeycloakTokenUrl - token endpoint realm Keycloak по адресу Keycloak без пути
+func KeycloakTokenUrl(baseUrl, realm string) string {
+	return strings.TrimRight(baseUrl, "/") + "/realms/" + url.PathEscape(realm) + "/protocol/openid-connect/token"
+}
+
+func (s *ClientCredentials) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	var form = url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.ClientId},
+		"client_secret": {s.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var httpClient = s.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token endpoint: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("token endpoint: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("token endpoint: error decoding response: %w", err)
+	}
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenLeeway)
+	return s.token, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"idm/inner/webhook"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AddWebhook - POST /api/v1/webhooks
+func (c *Client) AddWebhook(ctx context.Context, request webhook.CreateRequest) (webhook.Response, error) {
+	var rsl webhook.Response
+	var err = c.call(ctx, newRequest(http.MethodPost, "/api/v1/webhooks", request), &rsl)
+	return rsl, err
+}
+
+// FindAllWebhooks - GET /api/v1/webhooks
+func (c *Client) FindAllWebhooks(ctx context.Context) ([]webhook.Response, error) {
+	var rsl []webhook.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, "/api/v1/webhooks", nil), &rsl)
+	return rsl, err
+}
+
+// FindWebhookById - GET /api/v1/webhooks/:id
+func (c *Client) FindWebhookById(ctx context.Context, id int64) (webhook.Response, error) {
+	var rsl webhook.Response
+	var err = c.call(ctx, newRequest(http.MethodGet, path("/api/v1/webhooks/%v", id), nil), &rsl)
+	return rsl, err
+}
+
+// UpdateWebhook - PUT /api/v1/webhooks/:id
+func (c *Client) UpdateWebhook(ctx context.Context, id int64, request webhook.UpdateRequest) (webhook.Response, error) {
+	var rsl webhook.Response
+	var err = c.call(ctx, newRequest(http.MethodPut, path("/api/v1/webhooks/%v", id), request), &rsl)
+	return rsl, err
+}
+
+// DeleteWebhookById - DELETE /api/v1/webhooks/:id
+func (c *Client) DeleteWebhookById(ctx context.Context, id int64) error {
+	return c.call(ctx, newRequest(http.MethodDelete, path("/api/v1/webhooks/%v", id), nil), nil)
+}
+
+// FindWebhookDeliveries - GET /api/v1/webhooks/:id/deliveries
+func (c *Client) FindWebhookDeliveries(ctx context.Context, id int64,
+	request webhook.DeliveriesRequest) ([]webhook.DeliveryResponse, error) {
+	var rsl []webhook.DeliveryResponse
+	var req = newRequest(http.MethodGet, path("/api/v1/webhooks/%v/deliveries", id), nil)
+	req.query = url.Values{}
+	if request.State != "" {
+		req.query.Set("state", request.State)
+	}
+	if request.Limit != 0 {
+		req.query.Set("limit", strconv.Itoa(request.Limit))
+	}
+	var err = c.call(ctx, req, &rsl)
+	return rsl, err
+}
+
+// RedeliverWebhook - POST /api/v1/webhooks/:id/deliveries/:deliveryId/redeliver
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryId int64) error {
+	return c.call(ctx, newRequest(http.MethodPost,
+		path("/api/v1/webhooks/%v/deliveries/%v/redeliver", id, deliveryId), nil), nil)
+}
+
+// TestWebhook - POST /api/v1/webhooks/:id/test, пробная доставка
+func (c *Client) TestWebhook(ctx context.Context, id int64) (webhook.DeliveryResponse, error) {
+	var rsl webhook.DeliveryResponse
+	var err = c.call(ctx, newRequest(http.MethodPost, path("/api/v1/webhooks/%v/test", id), nil), &rsl)
+	return rsl, err
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/ldif"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/go-playground/validator/v10"
+)
+
+// Статусы проверок check-config; к ненулевому коду выхода приводит только FAIL
+const (
+	checkOk   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// certificateWarnPeriod - за сколько до истечения сертификата проверка предупреждает
+const certificateWarnPeriod = 30 * 24 * time.Hour
+
+// checkResult - итог одной проверки
+type checkResult struct {
+	name   string
+	status string
+	detail string
+}
+
+// checkConfig - проверяет конфиг, сертификаты, подключение к БД и доступность JWKS
+// и печатает отчёт; код выхода 1, если хотя бы одна проверка не прошла
+func checkConfig([]string) int {
+	var cfg, err = common.LoadConfig(".env")
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var results = []checkResult{
+		checkValidation(err),
+		checkCertificate(cfg, time.Now()),
+	}
+	results = append(results, checkDatabase(ctx, cfg)...)
+	results = append(results,
+		checkJwks(ctx, http.DefaultClient, cfg.KeycloakJwkUrl),
+		checkDirectory(cfg),
+	)
+	if !printReport(os.Stdout, results) {
+		return 1
+	}
+	return 0
+}
+
+// printReport - отчёт по проверкам; false - хотя бы одна не прошла
+func printReport(w io.Writer, results []checkResult) bool {
+	var passed = true
+	var writer = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		passed = passed && r.status != checkFail
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", r.status, r.name, r.detail)
+	}
+	_ = writer.Flush()
+	if passed {
+		_, _ = fmt.Fprintln(w, "All checks passed")
+	} else {
+		_, _ = fmt.Fprintln(w, "Some checks failed")
+	}
+	return passed
+}
+
+// checkValidation - ошибки валидации конфига по полям
+func checkValidation(err error) checkResult {
+	var result = checkResult{name: "config", status: checkOk, detail: "valid"}
+	var validateErrs validator.ValidationErrors
+	if errors.As(err, &validateErrs) {
+		var problems = make([]string, len(validateErrs))
+		for i, fe := range validateErrs {
+			problems[i] = fe.Field() + ": " + strings.TrimSpace(fe.Tag()+" "+fe.Param())
+		}
+		result.status = checkFail
+		result.detail = strings.Join(problems, "; ")
+	} else if err != nil {
+		result.status = checkFail
+		result.detail = err.Error()
+	}
+	return result
+}
+
+// checkCertificate - пара сертификат-ключ загружается и сертификат действует
+func checkCertificate(cfg common.Config, now time.Time) checkResult {
+	var result = checkResult{name: "certificate"}
+	if cfg.SslSert == "" || cfg.SslKey == "" {
+		result.status, result.detail = checkSkip, "SSL_SERT or SSL_KEY is not set"
+		return result
+	}
+	var pair, err = tls.LoadX509KeyPair(cfg.SslSert, cfg.SslKey)
+	if err != nil {
+		result.status, result.detail = checkFail, err.Error()
+		return result
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		result.status, result.detail = checkFail, err.Error()
+		return result
+	}
+	var subject = leaf.Subject.CommonName
+	if len(leaf.DNSNames) > 0 {
+		subject = strings.Join(leaf.DNSNames, ",")
+	}
+	var expires = leaf.NotAfter.UTC().Format(time.RFC3339)
+	switch {
+	case now.Before(leaf.NotBefore):
+		result.status = checkFail
+		result.detail = fmt.Sprintf("%s: not valid before %s", subject, leaf.NotBefore.UTC().Format(time.RFC3339))
+	case now.After(leaf.NotAfter):
+		result.status, result.detail = checkFail, fmt.Sprintf("%s: expired at %s", subject, expires)
+	case leaf.NotAfter.Sub(now) < certificateWarnPeriod:
+		result.status, result.detail = checkWarn, fmt.Sprintf("%s: expires soon, at %s", subject, expires)
+	default:
+		result.status, result.detail = checkOk, fmt.Sprintf("%s: valid until %s", subject, expires)
+	}
+	return result
+}
+
+// checkDatabase - подключение к БД и версия схемы относительно встроенных миграций
+func checkDatabase(ctx context.Context, cfg common.Config) []checkResult {
+	var result = checkResult{name: "database"}
+	if cfg.DbDriverName == "" || cfg.Dsn == "" {
+		result.status, result.detail = checkSkip, "DB_DRIVER_NAME or DB_DSN is not set"
+		return []checkResult{result}
+	}
+	var db, err = database.Connect(cfg)
+	if err != nil {
+		result.status, result.detail = checkFail, err.Error()
+		return []checkResult{result}
+	}
+	defer func() { _ = db.Close() }()
+	var version string
+	if err = db.GetContext(ctx, &version, "SHOW server_version"); err != nil {
+		result.status, result.detail = checkFail, err.Error()
+		return []checkResult{result}
+	}
+	result.status, result.detail = checkOk, "connected, server version "+version
+	var schema = checkResult{name: "schema"}
+	checker, err := database.NewSchema(db)
+	if err == nil {
+		var schemaVersion database.SchemaVersion
+		schemaVersion, err = checker.Version(ctx)
+		schema = checkSchema(schemaVersion, cfg.SchemaCheck)
+	}
+	if err != nil {
+		schema.status, schema.detail = checkFail, err.Error()
+	}
+	return []checkResult{result, schema}
+}
+
+// checkSchema - ожидающие миграции не дают серверу стартовать только в режиме refuse
+func checkSchema(version database.SchemaVersion, mode string) checkResult {
+	var result = checkResult{name: "schema", status: checkOk}
+	result.detail = fmt.Sprintf("version %d, latest migration %d", version.Current, version.Latest)
+	if len(version.Pending) == 0 {
+		return result
+	}
+	result.detail += fmt.Sprintf(", %d pending", len(version.Pending))
+	switch mode {
+	case database.SchemaMigrate:
+		result.status, result.detail = checkWarn, result.detail+", will be applied on start"
+	case database.SchemaWarn:
+		result.status = checkWarn
+	default:
+		result.status, result.detail = checkFail, result.detail+", run \"idm migrate up\""
+	}
+	return result
+}
+
+// checkJwks - набор ключей Keycloak загружается и содержит хотя бы один ключ
+func checkJwks(ctx context.Context, httpClient *http.Client, url string) checkResult {
+	var result = checkResult{name: "jwks"}
+	if url == "" {
+		result.status, result.detail = checkSkip, "KEYCLOAK_JWK_URL is not set"
+		return result
+	}
+	var fail = func(err error) checkResult {
+		result.status, result.detail = checkFail, fmt.Sprintf("%s: %v", url, err)
+		return result
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fail(err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("status %d", resp.StatusCode))
+	}
+	var raw json.RawMessage
+	if err = json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fail(err)
+	}
+	jwks, err := keyfunc.NewJSON(raw)
+	if err != nil {
+		return fail(err)
+	}
+	if jwks.Len() == 0 {
+		return fail(errors.New("no usable keys"))
+	}
+	result.status, result.detail = checkOk, fmt.Sprintf("%d keys from %s", jwks.Len(), url)
+	return result
+}
+
+// checkDirectory - DN каталога LDIF и сервисной записи LDAP, с которыми сервер иначе не стартует
+func checkDirectory(cfg common.Config) checkResult {
+	var result = checkResult{name: "directory", status: checkOk, detail: "base DN " + cfg.LdifBaseDn}
+	if _, err := ldif.NewTree(cfg.LdifBaseDn); err != nil {
+		result.status, result.detail = checkFail, fmt.Sprintf("LDIF_BASE_DN: %v", err)
+		return result
+	}
+	if cfg.LdapAddr != "" {
+		if _, err := ldif.ParseDN(cfg.LdapBindDn); err != nil {
+			result.status, result.detail = checkFail, fmt.Sprintf("LDAP_BIND_DN: %v", err)
+		}
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/database"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate - самоподписанный сертификат localhost и ключ во временных файлах
+func writeCertificate(t *testing.T, notBefore, notAfter time.Time) common.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template = &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dir = t.TempDir()
+	var cfg = common.Config{SslSert: filepath.Join(dir, "cert.pem"), SslKey: filepath.Join(dir, "key.pem")}
+	_ = os.WriteFile(cfg.SslSert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(cfg.SslKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	return cfg
+}
+
+func TestCheckCertificate(t *testing.T) {
+	var now = time.Now()
+
+	t.Run("Should pass valid certificate", func(t *testing.T) {
+		a := assert.New(t)
+		var cfg = writeCertificate(t, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+		var result = checkCertificate(cfg, now)
+		a.Equal(checkOk, result.status)
+		a.Contains(result.detail, "localhost: valid until")
+	})
+
+	t.Run("Should warn about certificate expiring soon", func(t *testing.T) {
+		a := assert.New(t)
+		var cfg = writeCertificate(t, now.Add(-time.Hour), now.Add(7*24*time.Hour))
+		a.Equal(checkWarn, checkCertificate(cfg, now).status)
+	})
+
+	t.Run("Should fail expired certificate", func(t *testing.T) {
+		a := assert.New(t)
+		var cfg = writeCertificate(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+		var result = checkCertificate(cfg, now)
+		a.Equal(checkFail, result.status)
+		a.Contains(result.detail, "expired")
+	})
+
+	t.Run("Should fail missing files and skip unset paths", func(t *testing.T) {
+		a := assert.New(t)
+		var missing = checkCertificate(common.Config{SslSert: "missing.pem", SslKey: "missing.key"}, now)
+		a.Equal(checkFail, missing.status)
+		a.Equal(checkSkip, checkCertificate(common.Config{}, now).status)
+	})
+}
+
+func TestCheckJwks(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jwks, _ = json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "key-1",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(jwks) })
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(`{"keys":[]}`)) })
+	var server = httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Should count loaded keys", func(t *testing.T) {
+		a := assert.New(t)
+		var result = checkJwks(context.Background(), server.Client(), server.URL+"/certs")
+		a.Equal(checkOk, result.status)
+		a.Contains(result.detail, "1 keys")
+	})
+
+	t.Run("Should fail on error status and empty key set", func(t *testing.T) {
+		a := assert.New(t)
+		var notFound = checkJwks(context.Background(), server.Client(), server.URL+"/missing")
+		a.Equal(checkFail, notFound.status)
+		a.Contains(notFound.detail, "status 404")
+		a.Equal(checkFail, checkJwks(context.Background(), server.Client(), server.URL+"/empty").status)
+	})
+
+	t.Run("Should skip unset url", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(checkSkip, checkJwks(context.Background(), server.Client(), "").status)
+	})
+}
+
+func TestCheckValidation(t *testing.T) {
+	t.Run("Should list invalid fields", func(t *testing.T) {
+		a := assert.New(t)
+		t.Setenv("DB_DRIVER_NAME", "postgres")
+		t.Setenv("DB_DSN", "dsn")
+		t.Setenv("APP_NAME", "idm")
+		t.Setenv("APP_VERSION", "0.0.0")
+		t.Setenv("SSL_SERT", "")
+		t.Setenv("SSL_KEY", "")
+		t.Setenv("KEYCLOAK_JWK_URL", "url")
+		var _, err = common.LoadConfig("")
+		var result = checkValidation(err)
+		a.Equal(checkFail, result.status)
+		a.Equal("SslSert: required; SslKey: required", result.detail)
+		a.Equal(checkOk, checkValidation(nil).status)
+		a.Equal(checkFail, checkValidation(errors.New("broken")).status)
+	})
+}
+
+func TestPrintReport(t *testing.T) {
+	t.Run("Should fail only on FAIL status", func(t *testing.T) {
+		a := assert.New(t)
+		var out bytes.Buffer
+		a.True(printReport(&out, []checkResult{
+			{name: "config", status: checkOk, detail: "valid"},
+			{name: "jwks", status: checkSkip},
+			{name: "certificate", status: checkWarn},
+		}))
+		a.Contains(out.String(), "All checks passed")
+		out.Reset()
+		a.False(printReport(&out, []checkResult{
+			{name: "config", status: checkOk},
+			{name: "database", status: checkFail, detail: "connection refused"},
+		}))
+		a.Contains(out.String(), "FAIL  database  connection refused")
+		a.Contains(out.String(), "Some checks failed")
+	})
+}
+
+func TestCheckDirectory(t *testing.T) {
+	t.Run("Should fail invalid DNs", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(checkOk, checkDirectory(common.Config{LdifBaseDn: "dc=idm,dc=local"}).status)
+		a.Equal(checkFail, checkDirectory(common.Config{LdifBaseDn: "not a dn"}).status)
+		a.Equal(checkFail, checkDirectory(common.Config{LdifBaseDn: "dc=idm", LdapAddr: ":389", LdapBindDn: "bad"}).status)
+	})
+}
+
+func TestCheckSchema(t *testing.T) {
+	var behind = database.SchemaVersion{Current: 1, Latest: 3, Pending: []int64{2, 3}}
+
+	t.Run("Should pass up to date schema", func(t *testing.T) {
+		a := assert.New(t)
+		var result = checkSchema(database.SchemaVersion{Current: 3, Latest: 3}, database.SchemaRefuse)
+		a.Equal(checkOk, result.status)
+		a.Equal("version 3, latest migration 3", result.detail)
+	})
+
+	t.Run("Should fail pending migrations only in refuse mode", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(checkFail, checkSchema(behind, database.SchemaRefuse).status)
+		a.Equal(checkWarn, checkSchema(behind, database.SchemaWarn).status)
+		var migrate = checkSchema(behind, database.SchemaMigrate)
+		a.Equal(checkWarn, migrate.status)
+		a.Contains(migrate.detail, "2 pending, will be applied on start")
+	})
+}
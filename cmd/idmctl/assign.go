@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/assignment"
+	"time"
+)
+
+// assign - назначает роль сотруднику
+func assign(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("assign")
+	c.outputFlags(flags, "")
+	var request assignment.AssignRequest
+	flags.Int64Var(&request.EmployeeId, "employee", 0, "employee id, required")
+	flags.Int64Var(&request.RoleId, "role", 0, "role id, required")
+	var validFrom = flags.String("valid-from", "", "start of the assignment, RFC 3339, default now")
+	var validUntil = flags.String("valid-until", "", "end of the assignment, RFC 3339, default unlimited")
+	flags.StringVar(&request.OverrideReason, "reason", "", "reason to assign despite a SoD warning")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if request.EmployeeId < 1 || request.RoleId < 1 {
+		return usageError{"assign: --employee and --role are required"}
+	}
+	var err error
+	if request.ValidFrom, err = parseTime("valid-from", *validFrom); err != nil {
+		return err
+	}
+	if request.ValidUntil, err = parseTime("valid-until", *validUntil); err != nil {
+		return err
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	assigned, err := api.Assign(ctx, request)
+	if err != nil {
+		return err
+	}
+	return printOne(c.stdout, c.output, assigned, []column[assignment.Response]{
+		{"employee_id", func(r assignment.Response) string { return formatInt(r.EmployeeId) }},
+		{"role_id", func(r assignment.Response) string { return formatInt(r.RoleId) }},
+		{"valid_from", func(r assignment.Response) string { return r.ValidFrom.Format(time.RFC3339) }},
+		{"valid_until", func(r assignment.Response) string {
+			if r.ValidUntil == nil {
+				return ""
+			}
+			return r.ValidUntil.Format(time.RFC3339)
+		}},
+		{"source", func(r assignment.Response) string { return r.Source }},
+	})
+}
+
+// revoke - отзывает роль у сотрудника
+func revoke(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("revoke")
+	var request assignment.RevokeRequest
+	flags.Int64Var(&request.EmployeeId, "employee", 0, "employee id, required")
+	flags.Int64Var(&request.RoleId, "role", 0, "role id, required")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if request.EmployeeId < 1 || request.RoleId < 1 {
+		return usageError{"revoke: --employee and --role are required"}
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	if err = api.Revoke(ctx, request); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.stdout, "role %d revoked from employee %d\n", request.RoleId, request.EmployeeId)
+	return nil
+}
+
+// parseTime - необязательный момент RFC 3339 из флага name
+func parseTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var parsed, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("invalid --%s %q, expected RFC 3339", name, value)}
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"idm/client"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultProfile - профиль, если он не задан ни флагом, ни в конфигурации
+const defaultProfile = "default"
+
+// Config - конфигурационный файл idmctl
+type Config struct {
+	CurrentProfile string             `json:"current_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles"`
+}
+
+// Profile - адрес приложения и способ получения токена. Токен берётся из token,
+// иначе запрашивается в Keycloak по client credentials сервисного клиента
+type Profile struct {
+	BaseUrl string `json:"base_url"`
+	Token   string `json:"token,omitempty"`
+	// KeycloakUrl - адрес Keycloak без пути, например "https://keycloak.local:8443"
+	KeycloakUrl  string `json:"keycloak_url,omitempty"`
+	Realm        string `json:"realm,omitempty"`
+	ClientId     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// CaFile - PEM-сертификаты, которым доверять помимо системных, например самоподписанный сертификат приложения
+	CaFile string `json:"ca_file,omitempty"`
+	// Insecure - не проверять сертификат сервера, только для разработки
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// configFile - путь к конфигурационному файлу: флаг --config, IDMCTL_CONFIG или каталог конфигурации пользователя
+func (c *cli) configFile() (string, error) {
+	if c.configPath != "" {
+		return c.configPath, nil
+	}
+	var dir, err = os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("config directory: %w; use --config", err)
+	}
+	return filepath.Join(dir, "idmctl", "config.json"), nil
+}
+
+// loadConfig - конфигурация из файла; отсутствующий файл - пустая конфигурация
+func (c *cli) loadConfig() (Config, string, error) {
+	var config = Config{Profiles: map[string]Profile{}}
+	var file, err = c.configFile()
+	if err != nil {
+		return config, "", err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, file, nil
+	}
+	if err != nil {
+		return config, file, err
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return config, file, fmt.Errorf("config %s: %w", file, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	return config, file, nil
+}
+
+// saveConfig - файл доступен только владельцу, так как содержит токены и секреты
+func saveConfig(file string, config Config) error {
+	var data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o600)
+}
+
+// profileName - профиль из флага --profile или IDMCTL_PROFILE, иначе текущий профиль конфигурации
+func (c *cli) profileName(config Config) string {
+	switch {
+	case c.profile != "":
+		return c.profile
+	case config.CurrentProfile != "":
+		return config.CurrentProfile
+	default:
+		return defaultProfile
+	}
+}
+
+// newClient - клиент API по профилю с учётом флагов --base-url и --token
+func (c *cli) newClient() (*client.Client, error) {
+	var config, _, err = c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var name = c.profileName(config)
+	var profile, ok = config.Profiles[name]
+	if !ok && c.profile != "" {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	if c.baseUrl != "" {
+		profile.BaseUrl = c.baseUrl
+	}
+	if profile.BaseUrl == "" {
+		return nil, usageError{"base URL is not set, use --base-url or idmctl config set --base-url"}
+	}
+	httpClient, err := profile.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	var tokens client.TokenSource
+	switch {
+	case c.token != "":
+		tokens = client.StaticToken(c.token)
+	case profile.Token != "":
+		tokens = client.StaticToken(profile.Token)
+	case profile.ClientId != "":
+		if profile.KeycloakUrl == "" || profile.Realm == "" {
+			return nil, fmt.Errorf("profile %q: keycloak_url and realm are required for client credentials", name)
+		}
+		var secret = profile.ClientSecret
+		if env := os.Getenv("IDMCTL_CLIENT_SECRET"); env != "" {
+			secret = env
+		}
+		tokens = &client.ClientCredentials{
+			TokenUrl:     client.KeycloakTokenUrl(profile.KeycloakUrl, profile.Realm),
+			ClientId:     profile.ClientId,
+			ClientSecret: secret,
+			HttpClient:   httpClient,
+		}
+	}
+	var api = client.NewClient(profile.BaseUrl, tokens)
+	api.HttpClient = httpClient
+	return api, nil
+}
+
+// httpClient - транспорт с доверенными сертификатами профиля
+func (p Profile) httpClient() (*http.Client, error) {
+	if p.CaFile == "" && !p.Insecure {
+		return http.DefaultClient, nil
+	}
+	var tlsConfig = &tls.Config{InsecureSkipVerify: p.Insecure}
+	if p.CaFile != "" {
+		var pem, err = os.ReadFile(p.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s: no PEM certificates", p.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// configView - профили конфигурации; токены и секреты скрываются
+func configView(c *cli, _ context.Context, args []string) error {
+	var flags = c.newFlags("config view")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	var config, _, err = c.loadConfig()
+	if err != nil {
+		return err
+	}
+	var current = c.profileName(config)
+	var names = make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	type row struct {
+		Name    string `json:"name"`
+		Current bool   `json:"current"`
+		Profile
+	}
+	var rows = make([]row, len(names))
+	for i, name := range names {
+		var profile = config.Profiles[name]
+		profile.Token = mask(profile.Token)
+		profile.ClientSecret = mask(profile.ClientSecret)
+		rows[i] = row{Name: name, Current: name == current, Profile: profile}
+	}
+	return printList(c.stdout, c.output, rows, []column[row]{
+		{"name", func(r row) string { return r.Name }},
+		{"current", func(r row) string { return formatBool(r.Current) }},
+		{"base_url", func(r row) string { return r.BaseUrl }},
+		{"token", func(r row) string { return r.Token }},
+		{"keycloak_url", func(r row) string { return r.KeycloakUrl }},
+		{"realm", func(r row) string { return r.Realm }},
+		{"client_id", func(r row) string { return r.ClientId }},
+		{"client_secret", func(r row) string { return r.ClientSecret }},
+		{"ca_file", func(r row) string { return r.CaFile }},
+	})
+}
+
+// configSet - создаёт профиль или меняет заданные флагами поля; первый профиль становится текущим
+func configSet(c *cli, _ context.Context, args []string) error {
+	var flags = c.newFlags("config set")
+	for _, name := range []string{"base-url", "token", "keycloak-url", "realm", "client-id", "client-secret", "ca-file"} {
+		flags.String(name, "", strings.ReplaceAll(name, "-", " ")+" of the profile")
+	}
+	var insecure = flags.Bool("insecure", false, "skip server certificate verification, development only")
+	// меняются только явно заданные флаги, остальные поля профиля сохраняются
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	var config, file, err = c.loadConfig()
+	if err != nil {
+		return err
+	}
+	var name = flags.Arg(0)
+	var profile = config.Profiles[name]
+	flags.Visit(func(f *flag.Flag) {
+		var value = f.Value.String()
+		switch f.Name {
+		case "base-url":
+			profile.BaseUrl = value
+		case "token":
+			profile.Token = value
+		case "keycloak-url":
+			profile.KeycloakUrl = value
+		case "realm":
+			profile.Realm = value
+		case "client-id":
+			profile.ClientId = value
+		case "client-secret":
+			profile.ClientSecret = value
+		case "ca-file":
+			profile.CaFile = value
+		case "insecure":
+			profile.Insecure = *insecure
+		}
+	})
+	config.Profiles[name] = profile
+	if config.CurrentProfile == "" {
+		config.CurrentProfile = name
+	}
+	if err = saveConfig(file, config); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.stdout, "profile %q saved to %s\n", name, file)
+	return nil
+}
+
+// configUse - делает профиль текущим
+func configUse(c *cli, _ context.Context, args []string) error {
+	var flags = c.newFlags("config use")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	var config, file, err = c.loadConfig()
+	if err != nil {
+		return err
+	}
+	var name = flags.Arg(0)
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	config.CurrentProfile = name
+	if err = saveConfig(file, config); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.stdout, "switched to profile %q\n", name)
+	return nil
+}
+
+// mask - секрет для вывода: только признак наличия
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/employee"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// employeeColumns - колонки вывода сотрудников; CSV с ними же принимает employees import
+var employeeColumns = []column[employee.Response]{
+	{"id", func(e employee.Response) string { return formatInt(e.Id) }},
+	{"name", func(e employee.Response) string { return e.Name }},
+	{"surname", func(e employee.Response) string { return e.Surname }},
+	{"age", func(e employee.Response) string { return strconv.Itoa(int(e.Age)) }},
+	{"login", func(e employee.Response) string { return e.Login }},
+	{"email", func(e employee.Response) string { return e.Email }},
+	{"phone", func(e employee.Response) string { return e.Phone }},
+	{"department_id", func(e employee.Response) string { return formatInt(e.DepartmentId) }},
+	{"manager_id", func(e employee.Response) string { return formatInt(e.ManagerId) }},
+	{"position", func(e employee.Response) string { return e.Position }},
+	{"active", func(e employee.Response) string { return formatBool(e.Active) }},
+}
+
+// employeesList - все сотрудники постранично, с текстовым фильтром и на момент --as-of
+func employeesList(c *cli, ctx context.Context, args []string) error {
+	return listEmployees(c, ctx, "employees list", args, "")
+}
+
+// employeesExport - как employees list, но по умолчанию CSV и можно писать в файл
+func employeesExport(c *cli, ctx context.Context, args []string) error {
+	return listEmployees(c, ctx, "employees export", args, FormatCsv)
+}
+
+func listEmployees(c *cli, ctx context.Context, name string, args []string, defaultFormat string) error {
+	var flags = c.newFlags(name)
+	c.outputFlags(flags, defaultFormat)
+	var filter = flags.String("filter", "", "text filter by name, surname, login or email")
+	var asOf = flags.String("as-of", "", "state at the moment, RFC 3339")
+	var pageSize = flags.Int("page-size", 100, "employees per request, 1-100")
+	var file = flags.String("file", "", "write to the file instead of stdout")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if *asOf != "" {
+		if _, err := time.Parse(time.RFC3339, *asOf); err != nil {
+			return usageError{fmt.Sprintf("invalid --as-of %q, expected RFC 3339", *asOf)}
+		}
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	var request = employee.PageRequest{PageSize: *pageSize, TextFilter: *filter, AsOf: *asOf}
+	var employees []employee.Response
+	for e, err := range api.Employees(ctx, request) {
+		if err != nil {
+			return err
+		}
+		employees = append(employees, e)
+	}
+	if *file == "" {
+		return printList(c.stdout, c.output, employees, employeeColumns)
+	}
+	// файл пишется целиком после получения всех страниц, чтобы ошибка не оставила его неполным
+	var buffer bytes.Buffer
+	if err = printList(&buffer, c.output, employees, employeeColumns); err != nil {
+		return err
+	}
+	if err = os.WriteFile(*file, buffer.Bytes(), 0o644); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.stderr, "%d employees written to %s\n", len(employees), *file)
+	return nil
+}
+
+// employeesGet - сотрудник по id, с --as-of - его состояние на момент
+func employeesGet(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("employees get")
+	c.outputFlags(flags, "")
+	var asOf = flags.String("as-of", "", "state at the moment, RFC 3339")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var moment time.Time
+	if *asOf != "" {
+		if moment, err = time.Parse(time.RFC3339, *asOf); err != nil {
+			return usageError{fmt.Sprintf("invalid --as-of %q, expected RFC 3339", *asOf)}
+		}
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	var found employee.Response
+	if moment.IsZero() {
+		found, err = api.FindEmployeeById(ctx, id)
+	} else {
+		found, err = api.FindEmployeeByIdAsOf(ctx, id, moment)
+	}
+	if err != nil {
+		return err
+	}
+	return printOne(c.stdout, c.output, found, employeeColumns)
+}
+
+// employeesCreate - создаёт сотрудника и выводит его id
+func employeesCreate(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("employees create")
+	c.outputFlags(flags, "")
+	var request employee.CreateRequest
+	var age int
+	flags.StringVar(&request.Name, "name", "", "name, required")
+	flags.StringVar(&request.Surname, "surname", "", "surname, required")
+	flags.IntVar(&age, "age", 0, "age, required")
+	flags.StringVar(&request.Login, "login", "", "login")
+	flags.StringVar(&request.Email, "email", "", "email")
+	flags.StringVar(&request.Phone, "phone", "", "phone")
+	flags.Int64Var(&request.DepartmentId, "department-id", 0, "department id")
+	flags.Int64Var(&request.ManagerId, "manager-id", 0, "manager id")
+	flags.StringVar(&request.Position, "position", "", "position")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if request.Name == "" || request.Surname == "" || age == 0 {
+		return usageError{"employees create: --name, --surname and --age are required"}
+	}
+	if age < 0 || age > 127 {
+		return usageError{fmt.Sprintf("invalid --age %d", age)}
+	}
+	request.Age = int8(age)
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	id, err := createEmployee(ctx, api.CreateEmployee, request)
+	if err != nil {
+		return err
+	}
+	return printCreated(c, id)
+}
+
+// createEmployee - создание с отметками времени, которые API требует в запросе
+func createEmployee(
+	ctx context.Context,
+	create func(context.Context, employee.CreateRequest) (int64, error),
+	request employee.CreateRequest,
+) (int64, error) {
+	var now = time.Now().UTC()
+	request.CreatedAt = now
+	request.UpdatedAt = now
+	return create(ctx, request)
+}
+
+type created struct {
+	Id int64 `json:"id"`
+}
+
+func printCreated(c *cli, id int64) error {
+	return printOne(c.stdout, c.output, created{Id: id}, []column[created]{
+		{"id", func(r created) string { return formatInt(r.Id) }},
+	})
+}
+
+// employeesDelete - удаляет сотрудников и выводит удалённых
+func employeesDelete(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("employees delete")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 1, -1); err != nil {
+		return err
+	}
+	var ids = make([]int64, flags.NArg())
+	for i, arg := range flags.Args() {
+		var err error
+		if ids[i], err = parseId(arg); err != nil {
+			return err
+		}
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 1 {
+		deleted, err := api.DeleteEmployeeById(ctx, ids[0])
+		if err != nil {
+			return err
+		}
+		return printOne(c.stdout, c.output, deleted, employeeColumns)
+	}
+	deleted, err := api.DeleteEmployeesByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return printList(c.stdout, c.output, deleted, employeeColumns)
+}
+
+// importResult - итог импорта одной записи файла
+type importResult struct {
+	Row   int    `json:"row"`
+	Login string `json:"login,omitempty"`
+	Id    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// employeesImport - создаёт сотрудников из CSV с заголовком (колонки employees export, id и active
+// игнорируются) или JSON-массива запросов создания. Ошибка записи не прерывает импорт остальных,
+// но даёт ненулевой код выхода
+func employeesImport(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("employees import")
+	c.outputFlags(flags, "")
+	var dryRun = flags.Bool("dry-run", false, "only parse the file")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	var data, err = readInput(c, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	requests, err := parseEmployees(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+	var create = func(context.Context, employee.CreateRequest) (int64, error) { return 0, nil }
+	if !*dryRun {
+		api, err := c.newClient()
+		if err != nil {
+			return err
+		}
+		create = api.CreateEmployee
+	}
+	var results = make([]importResult, len(requests))
+	var failed int
+	for i, request := range requests {
+		results[i] = importResult{Row: i + 1, Login: request.Login}
+		id, err := createEmployee(ctx, create, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			results[i].Error = err.Error()
+			failed++
+			continue
+		}
+		results[i].Id = id
+	}
+	err = printList(c.stdout, c.output, results, []column[importResult]{
+		{"row", func(r importResult) string { return strconv.Itoa(r.Row) }},
+		{"login", func(r importResult) string { return r.Login }},
+		{"id", func(r importResult) string { return formatInt(r.Id) }},
+		{"error", func(r importResult) string { return r.Error }},
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d employees not imported", failed, len(requests))
+	}
+	return nil
+}
+
+// readInput - содержимое файла, "-" - стандартный ввод
+func readInput(c *cli, name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// parseEmployees - JSON-массив, если файл начинается с '[', иначе CSV с заголовком
+func parseEmployees(data []byte) ([]employee.CreateRequest, error) {
+	var trimmed = bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var requests []employee.CreateRequest
+		if err := json.Unmarshal(trimmed, &requests); err != nil {
+			return nil, err
+		}
+		return requests, nil
+	}
+	var reader = csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index = map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "surname", "age"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %q column", required)
+		}
+	}
+	var requests []employee.CreateRequest
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var field = func(name string) string {
+			if i, ok := index[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		var request = employee.CreateRequest{
+			Name:     field("name"),
+			Surname:  field("surname"),
+			Login:    field("login"),
+			Email:    field("email"),
+			Phone:    field("phone"),
+			Position: field("position"),
+		}
+		age, err := strconv.ParseInt(field("age"), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid age %q", line, field("age"))
+		}
+		request.Age = int8(age)
+		if request.DepartmentId, err = parseOptionalId(field("department_id")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid department_id: %w", line, err)
+		}
+		if request.ManagerId, err = parseOptionalId(field("manager_id")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid manager_id: %w", line, err)
+		}
+		requests = append(requests, request)
+	}
+}
+
+func parseOptionalId(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// formatInt - пустая строка для нуля, которым API обозначает отсутствие значения
+func formatInt(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func formatBool(value bool) string {
+	return strconv.FormatBool(value)
+}
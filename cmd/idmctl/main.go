@@ -0,0 +1,203 @@
+// idmctl - утилита администрирования IDM из командной строки поверх REST API.
+//
+//	idmctl [--config FILE] [--profile NAME] [-o table|json|csv] КОМАНДА [ФЛАГИ] [АРГУМЕНТЫ]
+//
+// Адрес приложения и токен берутся из профиля конфигурационного файла (см. idmctl config),
+// флаги --base-url и --token и переменные IDMCTL_BASE_URL и IDMCTL_TOKEN их переопределяют
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+const usage = `Usage: idmctl [global flags] COMMAND [flags] [args]
+
+Commands:
+  employees list [--filter TEXT] [--as-of TIME] [--page-size N]
+  employees get [--as-of TIME] ID
+  employees create --name NAME --surname SURNAME --age AGE [--login --email --phone --department-id --manager-id --position]
+  employees delete ID...
+  employees export [--file FILE]
+  employees import [--dry-run] FILE
+  roles list
+  roles get ID
+  roles create --name NAME [--description --owner-id --risk-level --requestable --max-assignment-days]
+  roles delete ID
+  roles effective EMPLOYEE_ID
+  assign --employee ID --role ID [--valid-from TIME] [--valid-until TIME] [--reason TEXT]
+  revoke --employee ID --role ID
+  config view
+  config set [--base-url --token --keycloak-url --realm --client-id --client-secret --ca-file --insecure] PROFILE
+  config use PROFILE
+
+Global flags:
+`
+
+// usageError - неверный вызов команды, код выхода 2
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+// cli - состояние запуска: потоки ввода-вывода и глобальные флаги
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	profile    string
+	output     string
+	outputSet  bool
+	baseUrl    string
+	token      string
+}
+
+// command - обработчик команды; args - аргументы после имени команды
+type command func(c *cli, ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"employees": group("employees", map[string]command{
+		"list":   employeesList,
+		"get":    employeesGet,
+		"create": employeesCreate,
+		"delete": employeesDelete,
+		"export": employeesExport,
+		"import": employeesImport,
+	}),
+	"roles": group("roles", map[string]command{
+		"list":      rolesList,
+		"get":       rolesGet,
+		"create":    rolesCreate,
+		"delete":    rolesDelete,
+		"effective": rolesEffective,
+	}),
+	"assign": assign,
+	"revoke": revoke,
+	"config": group("config", map[string]command{
+		"view": configView,
+		"set":  configSet,
+		"use":  configUse,
+	}),
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	var code = run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run - выполняет команду и возвращает код выхода: 0 - успех, 1 - ошибка, 2 - неверный вызов
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var c = &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	var flags = flag.NewFlagSet("idmctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&c.configPath, "config", os.Getenv("IDMCTL_CONFIG"), "config file, default $XDG_CONFIG_HOME/idmctl/config.json")
+	flags.StringVar(&c.profile, "profile", os.Getenv("IDMCTL_PROFILE"), "profile name, default current profile of the config")
+	flags.StringVar(&c.baseUrl, "base-url", os.Getenv("IDMCTL_BASE_URL"), "IDM address, overrides the profile")
+	flags.StringVar(&c.token, "token", os.Getenv("IDMCTL_TOKEN"), "access token, overrides the profile")
+	c.outputFlags(flags, "")
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	flags.Visit(func(f *flag.Flag) {
+		c.outputSet = c.outputSet || f.Name == "o" || f.Name == "output"
+	})
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	var handler, ok = commands[flags.Arg(0)]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "idmctl: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+	var err = handler(c, ctx, flags.Args()[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usageError{}):
+		_, _ = fmt.Fprintf(stderr, "idmctl: %v\n", err)
+		return 2
+	default:
+		_, _ = fmt.Fprintf(stderr, "idmctl: %v\n", err)
+		return 1
+	}
+}
+
+// group - команда с подкомандами, например employees list
+func group(name string, subcommands map[string]command) command {
+	return func(c *cli, ctx context.Context, args []string) error {
+		if len(args) == 0 {
+			return usageError{fmt.Sprintf("%s: subcommand required, see idmctl --help", name)}
+		}
+		var handler, ok = subcommands[args[0]]
+		if !ok {
+			return usageError{fmt.Sprintf("%s: unknown subcommand %q", name, args[0])}
+		}
+		return handler(c, ctx, args[1:])
+	}
+}
+
+// outputFlags - флаги -o и --output; defaultFormat - формат команды, если он не задан глобальным флагом
+func (c *cli) outputFlags(flags *flag.FlagSet, defaultFormat string) {
+	if defaultFormat != "" && !c.outputSet {
+		c.output = defaultFormat
+	} else if c.output == "" {
+		c.output = FormatTable
+	}
+	flags.StringVar(&c.output, "o", c.output, "output format: table, json or csv")
+	flags.StringVar(&c.output, "output", c.output, "output format: table, json or csv")
+}
+
+// newFlags - флаги подкоманды; ошибки разбора печатаются в stderr
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	var flags = flag.NewFlagSet("idmctl "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// parse - разбирает флаги подкоманды и проверяет число аргументов: от minArgs до maxArgs, maxArgs < 0 - без ограничения
+func parse(flags *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err.Error()}
+	}
+	if flags.NArg() < minArgs || maxArgs >= 0 && flags.NArg() > maxArgs {
+		return usageError{fmt.Sprintf("%s: wrong number of arguments, see idmctl --help", flags.Name())}
+	}
+	return nil
+}
+
+// parseId - положительный идентификатор из аргумента
+func parseId(arg string) (int64, error) {
+	var id, err = strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, usageError{fmt.Sprintf("invalid id %q", arg)}
+	}
+	return id, nil
+}
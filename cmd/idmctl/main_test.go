@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeApi - REST API и token endpoint Keycloak с минимальной логикой для проверки команд
+type fakeApi struct {
+	mu          sync.Mutex
+	employees   []employee.Response
+	created     []employee.CreateRequest
+	assigned    []assignment.AssignRequest
+	authHeaders []string
+	tokenForms  []string
+}
+
+func (f *fakeApi) handler() http.Handler {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("POST /realms/idm/protocol/openid-connect/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		f.mu.Lock()
+		f.tokenForms = append(f.tokenForms, r.PostForm.Get("client_id")+":"+r.PostForm.Get("client_secret"))
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "service-token", "expires_in": 300})
+	})
+	mux.HandleFunc("GET /api/v1/employees/page", func(w http.ResponseWriter, r *http.Request) {
+		f.auth(r)
+		var number, _ = strconv.Atoi(r.URL.Query().Get("page_number"))
+		var size, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+		var page = employee.PageResponse{PageSize: size, PageNum: number, Total: int64(len(f.employees))}
+		for i := number * size; i < len(f.employees) && i < (number+1)*size; i++ {
+			page.Result = append(page.Result, f.employees[i])
+		}
+		respond(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("POST /api/v1/employees/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.auth(r)
+		for _, e := range f.employees {
+			if strconv.FormatInt(e.Id, 10) == r.PathValue("id") {
+				respond(w, http.StatusOK, e)
+				return
+			}
+		}
+		fail(w, http.StatusNotFound, "employee not found")
+	})
+	mux.HandleFunc("POST /api/v1/employees", func(w http.ResponseWriter, r *http.Request) {
+		f.auth(r)
+		var request employee.CreateRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.Age < 16 || request.CreatedAt.IsZero() {
+			fail(w, http.StatusBadRequest, "age must be at least 16")
+			return
+		}
+		f.mu.Lock()
+		f.created = append(f.created, request)
+		var id = int64(100 + len(f.created))
+		f.mu.Unlock()
+		respond(w, http.StatusOK, id)
+	})
+	mux.HandleFunc("POST /api/v1/assignments", func(w http.ResponseWriter, r *http.Request) {
+		f.auth(r)
+		var request assignment.AssignRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		f.mu.Lock()
+		f.assigned = append(f.assigned, request)
+		f.mu.Unlock()
+		respond(w, http.StatusOK, assignment.Response{EmployeeId: request.EmployeeId, RoleId: request.RoleId, Source: "MANUAL"})
+	})
+	return mux
+}
+
+func (f *fakeApi) auth(r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+}
+
+func respond[T any](w http.ResponseWriter, status int, data T) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(common.Response[T]{Success: true, Data: data})
+}
+
+func fail(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(common.Response[any]{Message: message})
+}
+
+// execute - запуск idmctl с конфигурацией configFile; возвращает код выхода, stdout и stderr
+func execute(configFile string, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	var code = run(context.Background(), append([]string{"--config", configFile}, args...),
+		strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newFakeApi(t *testing.T) (*fakeApi, *httptest.Server, string) {
+	var api = &fakeApi{}
+	var server = httptest.NewServer(api.handler())
+	t.Cleanup(server.Close)
+	var configFile = filepath.Join(t.TempDir(), "config.json")
+	return api, server, configFile
+}
+
+func TestOutput(t *testing.T) {
+	var employees = []employee.Response{
+		{Id: 1, Name: "John", Surname: "Smith", Age: 30, Email: "john@example.com", Active: true},
+		{Id: 2, Name: "Jane", Surname: "Doe, Jr.", Age: 25, DepartmentId: 3},
+	}
+
+	t.Run("Should print table with upper-case header", func(t *testing.T) {
+		a := assert.New(t)
+		var out bytes.Buffer
+		a.Nil(printList(&out, FormatTable, employees, employeeColumns))
+		var lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+		a.Len(lines, 3)
+		a.True(strings.HasPrefix(lines[0], "ID  NAME"))
+		a.Contains(lines[1], "john@example.com")
+	})
+
+	t.Run("Should print CSV that import accepts", func(t *testing.T) {
+		a := assert.New(t)
+		var out bytes.Buffer
+		a.Nil(printList(&out, FormatCsv, employees, employeeColumns))
+		a.Contains(out.String(), `"Doe, Jr."`)
+		requests, err := parseEmployees(out.Bytes())
+		a.Nil(err)
+		a.Len(requests, 2)
+		a.Equal("Doe, Jr.", requests[1].Surname)
+		a.Equal(int64(3), requests[1].DepartmentId)
+		a.Equal(int8(30), requests[0].Age)
+	})
+
+	t.Run("Should print JSON array and empty array for no items", func(t *testing.T) {
+		a := assert.New(t)
+		var out bytes.Buffer
+		a.Nil(printList(&out, FormatJson, employees, employeeColumns))
+		var decoded []employee.Response
+		a.Nil(json.Unmarshal(out.Bytes(), &decoded))
+		a.Equal(employees, decoded)
+		out.Reset()
+		a.Nil(printList[employee.Response](&out, FormatJson, nil, employeeColumns))
+		a.Equal("[]\n", out.String())
+	})
+
+	t.Run("Should reject unknown format", func(t *testing.T) {
+		a := assert.New(t)
+		var err = printList(&bytes.Buffer{}, "yaml", employees, employeeColumns)
+		a.ErrorAs(err, &usageError{})
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("Should save profiles, switch current one and mask secrets", func(t *testing.T) {
+		a := assert.New(t)
+		var configFile = filepath.Join(t.TempDir(), "idmctl", "config.json")
+		code, _, stderr := execute(configFile, "", "config", "set", "--base-url", "https://dev:8080", "--token", "secret", "dev")
+		a.Equal(0, code, stderr)
+		code, _, _ = execute(configFile, "", "config", "set", "--base-url", "https://prod:8080", "--client-id", "idmctl",
+			"--client-secret", "very-secret", "--keycloak-url", "https://kc", "--realm", "idm", "prod")
+		a.Equal(0, code)
+
+		code, stdout, _ := execute(configFile, "", "-o", "json", "config", "view")
+		a.Equal(0, code)
+		a.NotContains(stdout, "very-secret")
+		var profiles []map[string]any
+		a.Nil(json.Unmarshal([]byte(stdout), &profiles))
+		a.Len(profiles, 2)
+		a.Equal("dev", profiles[0]["name"])
+		a.Equal(true, profiles[0]["current"])
+		a.Equal("***", profiles[0]["token"])
+
+		code, _, _ = execute(configFile, "", "config", "use", "prod")
+		a.Equal(0, code)
+		var config Config
+		data, err := os.ReadFile(configFile)
+		a.Nil(err)
+		a.Nil(json.Unmarshal(data, &config))
+		a.Equal("prod", config.CurrentProfile)
+		a.Equal("secret", config.Profiles["dev"].Token)
+
+		info, err := os.Stat(configFile)
+		a.Nil(err)
+		a.Equal(os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Should keep fields not given to config set", func(t *testing.T) {
+		a := assert.New(t)
+		var configFile = filepath.Join(t.TempDir(), "config.json")
+		execute(configFile, "", "config", "set", "--base-url", "https://dev:8080", "--token", "secret", "dev")
+		execute(configFile, "", "config", "set", "--token", "rotated", "dev")
+		var config Config
+		data, _ := os.ReadFile(configFile)
+		a.Nil(json.Unmarshal(data, &config))
+		a.Equal(Profile{BaseUrl: "https://dev:8080", Token: "rotated"}, config.Profiles["dev"])
+	})
+
+	t.Run("Should fail on unknown profile", func(t *testing.T) {
+		a := assert.New(t)
+		var configFile = filepath.Join(t.TempDir(), "config.json")
+		code, _, stderr := execute(configFile, "", "config", "use", "missing")
+		a.Equal(1, code)
+		a.Contains(stderr, `profile "missing" not found`)
+	})
+}
+
+func TestCommands(t *testing.T) {
+	t.Run("Should list all pages of employees with profile token", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		for i := 1; i <= 5; i++ {
+			api.employees = append(api.employees, employee.Response{Id: int64(i), Name: "Name" + strconv.Itoa(i), Surname: "Surname", Age: 30})
+		}
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "--token", "profile-token", "dev")
+
+		code, stdout, stderr := execute(configFile, "", "employees", "list", "-o", "csv", "--page-size", "2")
+		a.Equal(0, code, stderr)
+		var lines = strings.Split(strings.TrimSpace(stdout), "\n")
+		a.Len(lines, 6)
+		a.Equal("id,name,surname,age,login,email,phone,department_id,manager_id,position,active", lines[0])
+		a.Equal("5,Name5,Surname,30,,,,,,,false", lines[5])
+		a.Len(api.authHeaders, 3)
+		a.Equal("Bearer profile-token", api.authHeaders[0])
+	})
+
+	t.Run("Should prefer --token flag and report API errors with exit code 1", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "--token", "profile-token", "dev")
+
+		code, _, stderr := execute(configFile, "", "--token", "flag-token", "employees", "get", "42")
+		a.Equal(1, code)
+		a.Contains(stderr, "employee not found")
+		a.Equal([]string{"Bearer flag-token"}, api.authHeaders)
+	})
+
+	t.Run("Should return usage error for bad arguments", func(t *testing.T) {
+		a := assert.New(t)
+		_, server, configFile := newFakeApi(t)
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "dev")
+
+		code, _, _ := execute(configFile, "", "employees", "get", "abc")
+		a.Equal(2, code)
+		code, _, _ = execute(configFile, "", "employees")
+		a.Equal(2, code)
+		code, _, _ = execute(configFile, "", "unknown")
+		a.Equal(2, code)
+		code, _, _ = execute(configFile, "", "employees", "create", "--name", "John")
+		a.Equal(2, code)
+	})
+
+	t.Run("Should get token by client credentials", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "--keycloak-url", server.URL,
+			"--realm", "idm", "--client-id", "idmctl", "--client-secret", "file-secret", "svc")
+		t.Setenv("IDMCTL_CLIENT_SECRET", "env-secret")
+
+		code, stdout, stderr := execute(configFile, "", "-o", "json", "assign", "--employee", "7", "--role", "3",
+			"--valid-until", "2026-12-31T00:00:00Z", "--reason", "approved by CISO")
+		a.Equal(0, code, stderr)
+		a.Contains(stdout, `"role_id": 3`)
+		a.Equal([]string{"idmctl:env-secret"}, api.tokenForms)
+		a.Equal([]string{"Bearer service-token"}, api.authHeaders)
+		a.Len(api.assigned, 1)
+		a.Equal("approved by CISO", api.assigned[0].OverrideReason)
+		a.Equal("2026-12-31T00:00:00Z", api.assigned[0].ValidUntil.Format("2006-01-02T15:04:05Z07:00"))
+	})
+
+	t.Run("Should import employees and report failed rows", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "--token", "t", "dev")
+		var csv = "name,surname,age,login,department_id\n" +
+			"John,Smith,30,jsmith,2\n" +
+			"Kid,Young,10,kid,\n"
+
+		code, stdout, stderr := execute(configFile, csv, "-o", "csv", "employees", "import", "-")
+		a.Equal(1, code)
+		a.Contains(stderr, "1 of 2 employees not imported")
+		a.Contains(stdout, "1,jsmith,101,\n")
+		a.Contains(stdout, "2,kid,,idm: 400 age must be at least 16")
+		a.Len(api.created, 1)
+		a.Equal(int64(2), api.created[0].DepartmentId)
+	})
+
+	t.Run("Should only parse file on dry run", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "dev")
+		var file = filepath.Join(t.TempDir(), "employees.json")
+		a.Nil(os.WriteFile(file, []byte(`[{"name":"John","surname":"Smith","age":30}]`), 0o600))
+
+		code, stdout, stderr := execute(configFile, "", "employees", "import", "--dry-run", file)
+		a.Equal(0, code, stderr)
+		a.Contains(stdout, "ROW")
+		a.Empty(api.created)
+	})
+
+	t.Run("Should export employees to file as CSV", func(t *testing.T) {
+		a := assert.New(t)
+		api, server, configFile := newFakeApi(t)
+		api.employees = []employee.Response{{Id: 1, Name: "John", Surname: "Smith", Age: 30}}
+		execute(configFile, "", "config", "set", "--base-url", server.URL, "dev")
+		var file = filepath.Join(t.TempDir(), "employees.csv")
+
+		code, _, stderr := execute(configFile, "", "employees", "export", "--file", file)
+		a.Equal(0, code, stderr)
+		data, err := os.ReadFile(file)
+		a.Nil(err)
+		a.True(strings.HasPrefix(string(data), "id,name,surname"))
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Форматы вывода
+const (
+	FormatTable = "table"
+	FormatJson  = "json"
+	FormatCsv   = "csv"
+)
+
+// column - колонка таблицы и CSV; name - заголовок CSV, в таблице выводится заглавными буквами
+type column[T any] struct {
+	name  string
+	value func(T) string
+}
+
+// printList - items в формате format: JSON-массив, таблица или CSV с заголовком
+func printList[T any](w io.Writer, format string, items []T, columns []column[T]) error {
+	switch format {
+	case FormatJson:
+		if items == nil {
+			items = []T{}
+		}
+		return printJson(w, items)
+	case FormatCsv:
+		var writer = csv.NewWriter(w)
+		var record = make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = c.name
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		for _, item := range items {
+			for i, c := range columns {
+				record[i] = c.value(item)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatTable:
+		var writer = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		var cells = make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = strings.ToUpper(c.name)
+		}
+		_, _ = fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		for _, item := range items {
+			for i, c := range columns {
+				// табуляция и перевод строки в значении сломали бы выравнивание
+				cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(c.value(item))
+			}
+			_, _ = fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		}
+		return writer.Flush()
+	default:
+		return usageError{fmt.Sprintf("unknown output format %q, expected table, json or csv", format)}
+	}
+}
+
+// printOne - один объект: в JSON - объектом, а не массивом
+func printOne[T any](w io.Writer, format string, item T, columns []column[T]) error {
+	if format == FormatJson {
+		return printJson(w, item)
+	}
+	return printList(w, format, []T{item}, columns)
+}
+
+func printJson(w io.Writer, value any) error {
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
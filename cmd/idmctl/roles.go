@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"idm/inner/role"
+	"strconv"
+	"strings"
+)
+
+var roleColumns = []column[role.Response]{
+	{"id", func(r role.Response) string { return formatInt(r.Id) }},
+	{"name", func(r role.Response) string { return r.Name }},
+	{"description", func(r role.Response) string { return r.Description }},
+	{"owner_id", func(r role.Response) string {
+		if r.OwnerId == nil {
+			return ""
+		}
+		return formatInt(*r.OwnerId)
+	}},
+	{"risk_level", func(r role.Response) string { return r.RiskLevel }},
+	{"requestable", func(r role.Response) string { return formatBool(r.Requestable) }},
+	{"max_assignment_days", func(r role.Response) string {
+		if r.MaxAssignmentDays == nil {
+			return ""
+		}
+		return strconv.Itoa(int(*r.MaxAssignmentDays))
+	}},
+}
+
+func rolesList(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("roles list")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	entities, err := api.FindAllRoles(ctx)
+	if err != nil {
+		return err
+	}
+	var roles = make([]role.Response, len(entities))
+	for i := range entities {
+		roles[i] = entities[i].ToResponse()
+	}
+	return printList(c.stdout, c.output, roles, roleColumns)
+}
+
+func rolesGet(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("roles get")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	found, err := api.FindRoleById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printOne(c.stdout, c.output, found, roleColumns)
+}
+
+// rolesCreate - не заданные флаги не передаются, и роль получает значения по умолчанию API
+func rolesCreate(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("roles create")
+	c.outputFlags(flags, "")
+	var request role.Request
+	flags.StringVar(&request.Name, "name", "", "name, required")
+	flags.StringVar(&request.Description, "description", "", "description")
+	flags.StringVar(&request.RiskLevel, "risk-level", "", "LOW, MEDIUM or HIGH")
+	var ownerId = flags.Int64("owner-id", 0, "owner employee id")
+	var requestable = flags.Bool("requestable", true, "can be requested by employees")
+	var maxDays = flags.Int("max-assignment-days", 0, "maximum assignment duration in days")
+	if err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if request.Name == "" {
+		return usageError{"roles create: --name is required"}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "owner-id":
+			request.OwnerId = ownerId
+		case "requestable":
+			request.Requestable = requestable
+		case "max-assignment-days":
+			var days = int32(*maxDays)
+			request.MaxAssignmentDays = &days
+		}
+	})
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	added, err := api.AddRole(ctx, request)
+	if err != nil {
+		return err
+	}
+	return printOne(c.stdout, c.output, added, roleColumns)
+}
+
+func rolesDelete(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("roles delete")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	deleted, err := api.DeleteRoleById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printOne(c.stdout, c.output, deleted, roleColumns)
+}
+
+// rolesEffective - роли сотрудника с учётом составных ролей и пути получения каждой
+func rolesEffective(c *cli, ctx context.Context, args []string) error {
+	var flags = c.newFlags("roles effective")
+	c.outputFlags(flags, "")
+	if err := parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	api, err := c.newClient()
+	if err != nil {
+		return err
+	}
+	roles, err := api.FindEffectiveRoles(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printList(c.stdout, c.output, roles, []column[role.EffectiveRoleResponse]{
+		{"id", func(r role.EffectiveRoleResponse) string { return formatInt(r.Id) }},
+		{"name", func(r role.EffectiveRoleResponse) string { return r.Name }},
+		{"direct", func(r role.EffectiveRoleResponse) string { return formatBool(r.Direct) }},
+		{"paths", func(r role.EffectiveRoleResponse) string {
+			var paths = make([]string, len(r.Paths))
+			for i, p := range r.Paths {
+				paths[i] = strings.Join(p, " > ")
+			}
+			return strings.Join(paths, "; ")
+		}},
+	})
+}
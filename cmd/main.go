@@ -3,13 +3,33 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"idm/docs"
+	"idm/inner/accessrequest"
+	"idm/inner/assignment"
+	"idm/inner/audit"
+	"idm/inner/birthright"
+	"idm/inner/certification"
 	"idm/inner/common"
 	database2 "idm/inner/database"
+	"idm/inner/department"
 	"idm/inner/employee"
+	"idm/inner/idempotency"
 	"idm/inner/info"
+	"idm/inner/ldap"
+	"idm/inner/ldif"
+	"idm/inner/me"
+	"idm/inner/outbox"
+	"idm/inner/permission"
+	"idm/inner/provisioning"
 	"idm/inner/role"
+	"idm/inner/rpc"
+	"idm/inner/scim"
+	"idm/inner/sod"
+	"idm/inner/stream"
 	"idm/inner/web"
+	"idm/inner/webhook"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
@@ -17,11 +37,14 @@ import (
 
 	_ "idm/docs"
 
+	"github.com/MicahParks/keyfunc/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/swagger"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // @title IDM API documentation
@@ -31,15 +54,73 @@ import (
 // @in header
 // @name Authorization
 func main() {
+	var args = os.Args[1:]
+	// без подкоманды бинарник, как и раньше, запускает сервер
+	var name = "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	var run, ok = commands[name]
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	os.Exit(run(args))
+}
+
+const usage = `Usage: idm [COMMAND]
+
+Commands:
+  serve               start the server (default)
+  migrate up          apply all pending migrations
+  migrate down        roll back the last applied migration
+  migrate status      show the state of every migration
+  seed                load sample roles and employees
+  check-config        validate config, certificates, database and JWKS
+
+Configuration is read from .env and environment variables.
+`
+
+// commands - подкоманды бинарника; функция получает аргументы после имени и возвращает код выхода
+var commands = map[string]func(args []string) int{
+	"serve":        serve,
+	"migrate":      migrate,
+	"seed":         seed,
+	"check-config": checkConfig,
+	"help": func([]string) int {
+		_, _ = fmt.Fprint(os.Stdout, usage)
+		return 0
+	},
+}
+
+// loadConfig - конфиг для служебных команд: ошибка печатается, а не вызывает панику
+func loadConfig() (common.Config, bool) {
+	var cfg, err = common.LoadConfig(".env")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Config validation error: %v\n", err)
+		return cfg, false
+	}
+	return cfg, true
+}
+
+// serve - запуск HTTP-сервера и фоновых процессов до сигнала остановки
+func serve([]string) int {
 	var cfg = common.GetConfig(".env")
 	var logger = common.NewLogger(cfg)
 	defer func() { _ = logger.Sync() }()
+	// DN каталога проверяются до подключения к БД так же, как в check-config
+	if result := checkDirectory(cfg); result.status == checkFail {
+		logger.Panic("Invalid directory configuration", zap.String("detail", result.detail))
+	}
 	db := database2.ConnectDbWithCfg(cfg)
 	defer func() {
 		if err := db.Close(); err != nil {
 			logger.Error("Error closing db", zap.Error(err))
 		}
 	}()
+	if _, err := database2.EnsureSchema(context.Background(), db, cfg.SchemaCheck, logger); err != nil {
+		logger.Panic("Database schema check failed", zap.Error(err))
+	}
 	docs.SwaggerInfo.Version = cfg.AppVersion
 	cer, err := tls.LoadX509KeyPair(cfg.SslSert, cfg.SslKey)
 	if err != nil {
@@ -51,7 +132,10 @@ func main() {
 		logger.Panic("Failed TLS listen", zap.Error(err))
 	}
 	ln = common.CustomListener{Listener: ln, Url: "localhost:8080/swagger/index.html"}
-	var server = build(db, logger, cfg)
+	var server, workers = build(db, logger, cfg, tlsConfig)
+	for _, w := range workers {
+		w.Start()
+	}
 	go func() {
 		var err = server.App.Listener(ln)
 		if err != nil {
@@ -61,12 +145,25 @@ func main() {
 
 	var wg = &sync.WaitGroup{}
 	wg.Add(1)
-	go gracefulShutdown(server, wg, logger)
+	go gracefulShutdown(server, workers, wg, logger)
 	wg.Wait()
 	logger.Info("Graceful shutdown complete.")
+	return 0
+}
+
+// worker - фоновый процесс, запускаемый вместе с сервером
+type worker interface {
+	Start()
+	Stop(ctx context.Context) error
 }
 
-func gracefulShutdown(server *web.Server, wg *sync.WaitGroup, logger *common.Logger) {
+// streamCloser - worker с долгими подключениями клиентов, которые нужно закрыть до остановки
+// сервера: иначе сервер ждёт их завершения до таймаута
+type streamCloser interface {
+	CloseStreams()
+}
+
+func gracefulShutdown(server *web.Server, workers []worker, wg *sync.WaitGroup, logger *common.Logger) {
 	const timeOut = 5 * time.Second
 	defer wg.Done()
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
@@ -75,27 +172,200 @@ func gracefulShutdown(server *web.Server, wg *sync.WaitGroup, logger *common.Log
 	logger.Info("Shutting down gracefully")
 	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
 	defer cancel()
+	for _, w := range workers {
+		if s, ok := w.(streamCloser); ok {
+			s.CloseStreams()
+		}
+	}
 	if err := server.App.ShutdownWithContext(ctx); err != nil {
 		logger.Error("Server forced to shutdown with error", zap.Error(err))
 	}
+	for _, w := range workers {
+		if err := w.Stop(ctx); err != nil {
+			logger.Error("Worker forced to stop with error", zap.Error(err))
+		}
+	}
 	logger.Info("Server exiting")
 }
 
-func build(database *sqlx.DB, logger *common.Logger, cfg common.Config) *web.Server {
+func build(database *sqlx.DB, logger *common.Logger, cfg common.Config, tlsConfig *tls.Config) (*web.Server, []worker) {
 	var server = web.NewServer()
 	server.App.Use("/swagger/*", swagger.HandlerDefault)
 	server.App.Use(requestid.New())
 	server.App.Use(recover.New())
 	server.GroupApi.Use(web.AuthMiddleware(logger))
+	server.GroupScim.Use(web.AuthMiddleware(logger))
+	// сверка меняет учётные записи во внешней системе, поэтому, в отличие от info и health, требует токен
+	server.GroupInternal.Use("/reconcile", web.AuthMiddleware(logger))
+	var idempotencyRepo = idempotency.NewRepository(database)
+	var idempotencyMiddleware = idempotency.NewMiddleware(idempotencyRepo, cfg.IdempotencyKeyTtl, logger)
+	server.GroupApi.Use(idempotencyMiddleware)
+	server.GroupScim.Use(idempotencyMiddleware)
+	var permissionRepo = permission.NewRepository(database)
+	server.Permissions = permissionRepo
+	var outboxRepo = outbox.NewRepository(database)
 	var employeeRepo = employee.NewEmployeeRepository(database)
 	var employeeService = employee.NewService(employeeRepo, logger)
+	employeeService.Events = outboxRepo
 	var employeeHandler = employee.NewHandler(server, employeeService, logger)
 	employeeHandler.RegisterRoutes()
 	var roleRepo = role.NewRepository(database)
 	var roleService = role.NewService(roleRepo)
+	roleService.Events = outboxRepo
 	var roleHandler = role.NewHandler(server, roleService, logger)
 	roleHandler.RegisterRouters()
+	var departmentRepo = department.NewRepository(database)
+	var departmentService = department.NewService(departmentRepo, employeeRepo, logger)
+	server.Delegations = departmentService
+	var departmentHandler = department.NewHandler(server, departmentService, logger)
+	departmentHandler.RegisterRoutes()
+	var meService = me.NewService(employeeRepo, roleRepo, departmentRepo, logger)
+	meService.Events = outboxRepo
+	var meHandler = me.NewHandler(server, meService, logger)
+	meHandler.RegisterRoutes()
+	var auditRepo = audit.NewRepository(database)
+	var sodRepo = sod.NewRepository(database)
+	var sodService = sod.NewService(sodRepo)
+	var sodHandler = sod.NewHandler(server, sodService, logger)
+	sodHandler.RegisterRoutes()
+	var assignmentRepo = assignment.NewRepository(database)
+	var assignmentService = assignment.NewService(assignmentRepo, auditRepo, sodRepo, logger)
+	assignmentService.Events = outboxRepo
+	roleService.Revoker = assignmentService
+	var assignmentHandler = assignment.NewHandler(server, assignmentService, logger)
+	assignmentHandler.RegisterRoutes()
+	var birthrightRepo = birthright.NewRepository(database)
+	var birthrightService = birthright.NewService(birthrightRepo, assignmentService, logger)
+	employeeService.Rules = birthrightService
+	var birthrightHandler = birthright.NewHandler(server, birthrightService, logger)
+	birthrightHandler.RegisterRoutes()
+	var scimRepo = scim.NewRepository(database)
+	var scimService = scim.NewService(scimRepo, employeeService, roleService, assignmentService, logger)
+	scimService.Events = outboxRepo
+	var scimHandler = scim.NewHandler(server, scimService, logger)
+	scimHandler.RegisterRoutes()
+	ldifTree, err := ldif.NewTree(cfg.LdifBaseDn)
+	if err != nil {
+		logger.Panic("Invalid LDIF_BASE_DN", zap.Error(err))
+	}
+	var ldifService = ldif.NewService(ldif.NewRepository(database), ldifTree, employeeService, roleService,
+		assignmentService, logger)
+	ldifService.Events = outboxRepo
+	var ldifHandler = ldif.NewHandler(server, ldifService, logger)
+	ldifHandler.RegisterRoutes()
+	var accessRequestRepo = accessrequest.NewRepository(database)
+	var accessRequestService = accessrequest.NewService(accessRequestRepo, employeeRepo, roleRepo, assignmentService, sodRepo,
+		logger)
+	var accessRequestHandler = accessrequest.NewHandler(server, accessRequestService, logger)
+	accessRequestHandler.RegisterRoutes()
+	var certificationRepo = certification.NewRepository(database)
+	var certificationService = certification.NewService(certificationRepo, employeeRepo, assignmentService, logger)
+	var certificationHandler = certification.NewHandler(server, certificationService, logger)
+	certificationHandler.RegisterRoutes()
+	var permissionService = permission.NewService(permissionRepo)
+	var permissionHandler = permission.NewHandler(server, permissionService, logger)
+	permissionHandler.RegisterRoutes()
+	var expiryWorker = assignment.NewExpiryWorker(assignmentService, cfg.AssignmentExpiryInterval, logger)
+	var deadlineWorker = certification.NewDeadlineWorker(certificationService, cfg.CertificationDeadlineInterval, logger)
 	var infoHandler = info.NewHandler(server, cfg, database, logger)
+	if schema, err := database2.NewSchema(database); err == nil {
+		infoHandler.Schema = schema
+	} else {
+		logger.Error("Schema version is not available in info", zap.Error(err))
+	}
 	infoHandler.RegisterRoutes()
-	return server
+	var publisher outbox.Publisher = outbox.NewLogPublisher(logger)
+	switch cfg.OutboxPublisher {
+	case "file":
+		publisher = outbox.NewFilePublisher(cfg.OutboxFilePath)
+	case "http":
+		publisher = outbox.NewHttpPublisher(cfg.OutboxHttpUrl, 10*time.Second)
+	}
+	var webhookService = webhook.NewService(
+		webhook.NewRepository(database),
+		webhook.NewHttpSender(10*time.Second),
+		webhook.Options{MaxAttempts: cfg.WebhookMaxAttempts, RetryInterval: cfg.WebhookRetryInterval},
+		50,
+		logger,
+	)
+	var webhookHandler = webhook.NewHandler(server, webhookService, logger)
+	webhookHandler.RegisterRoutes()
+	var outboxService = outbox.NewService(outboxRepo, outbox.Fanout{publisher, webhookService}, 100, logger)
+	var relayWorker = outbox.NewRelayWorker(outboxService, cfg.OutboxRelayInterval, cfg.OutboxRetention, logger)
+	var webhookWorker = webhook.NewWorker(webhookService, cfg.WebhookDeliveryInterval, cfg.WebhookRetention, logger)
+	var streamService = stream.NewService(stream.NewRepository(database), 100)
+	var streamHandler = stream.NewHandler(server, streamService, logger)
+	streamHandler.RegisterRoutes()
+	var streamWorker = stream.NewWorker(streamService, cfg.StreamPollInterval, logger)
+	var idempotencyWorker = idempotency.NewWorker(idempotencyRepo, time.Hour, logger)
+	var workers = []worker{expiryWorker, deadlineWorker, relayWorker, webhookWorker, streamWorker, idempotencyWorker}
+	if cfg.LdapAddr != "" {
+		if _, err := ldif.ParseDN(cfg.LdapBindDn); err != nil {
+			logger.Panic("Invalid LDAP_BIND_DN", zap.Error(err))
+		}
+		var ldapService = ldap.NewService(ldifService, ldifTree, ldap.Options{
+			BindDn:       cfg.LdapBindDn,
+			BindPassword: cfg.LdapBindPassword,
+		}, logger)
+		var ldapServer = ldap.NewServer(cfg.LdapAddr, ldapService, logger)
+		if cfg.LdapTls {
+			ldapServer.TLSConfig = tlsConfig
+		}
+		workers = append(workers, ldapServer)
+	}
+	if cfg.GrpcAddr != "" {
+		jwks, err := keyfunc.Get(cfg.KeycloakJwkUrl, keyfunc.Options{
+			RefreshInterval:   time.Hour,
+			RefreshRateLimit:  5 * time.Minute,
+			RefreshTimeout:    10 * time.Second,
+			RefreshUnknownKID: true,
+			RefreshErrorHandler: func(err error) {
+				logger.Error("Failed to refresh JWKS", zap.Error(err))
+			},
+		})
+		if err != nil {
+			logger.Panic("Failed to load JWKS for gRPC", zap.Error(err))
+		}
+		var options []grpc.ServerOption
+		if cfg.GrpcTls {
+			options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		var rpcServer = rpc.NewServer(cfg.GrpcAddr, jwks.Keyfunc, employeeService, roleService, logger, options...)
+		rpcServer.Permissions = permissionRepo
+		rpcServer.Delegations = departmentService
+		workers = append(workers, rpcServer)
+	}
+	if cfg.KeycloakAdminUrl != "" {
+		var provisioningOptions = provisioning.Options{
+			MaxAttempts:   cfg.ProvisioningMaxAttempts,
+			RetryInterval: cfg.ProvisioningRetryInterval,
+		}
+		var provisioningService = provisioning.NewService(
+			provisioning.NewRepository(database),
+			provisioning.NewKeycloakConnector(provisioning.KeycloakConfig{
+				BaseUrl:      cfg.KeycloakAdminUrl,
+				Realm:        cfg.KeycloakRealm,
+				ClientId:     cfg.KeycloakClientId,
+				ClientSecret: cfg.KeycloakClientSecret,
+				Timeout:      10 * time.Second,
+			}),
+			provisioningOptions,
+			logger,
+		)
+		employeeService.Provisioning = provisioningService
+		assignmentService.Provisioning = provisioningService
+		roleService.Provisioning = provisioningService
+		meService.Provisioning = provisioningService
+		scimService.Provisioning = provisioningService
+		ldifService.Provisioning = provisioningService
+		accessRequestService.Provisioning = provisioningService
+		certificationService.Provisioning = provisioningService
+		birthrightService.Provisioning = provisioningService
+		var provisioningHandler = provisioning.NewHandler(server, provisioningService, logger)
+		provisioningHandler.RegisterRoutes()
+		workers = append(workers, provisioning.NewWorker(provisioningService, provisioningOptions, logger),
+			provisioning.NewReconcileWorker(provisioningService, cfg.ProvisioningReconcileInterval,
+				cfg.ProvisioningReconcileApply, logger))
+	}
+	return server, workers
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idm/inner/database"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrate - встроенные миграции: up применяет все ожидающие, down откатывает последнюю применённую,
+// status выводит состояние каждой
+func migrate(args []string) int {
+	if len(args) != 1 || args[0] != "up" && args[0] != "down" && args[0] != "status" {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: idm migrate up|down|status")
+		return 2
+	}
+	var cfg, ok = loadConfig()
+	if !ok {
+		return 1
+	}
+	db, err := database.Connect(cfg)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Database connection error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Migrations error: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		var partial *goose.PartialError
+		if errors.As(err, &partial) {
+			results = append(partial.Applied, partial.Failed)
+		}
+		printMigrationResults(os.Stdout, results)
+		if len(results) == 0 && err == nil {
+			_, _ = fmt.Fprintln(os.Stdout, "No pending migrations")
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return 1
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			_, _ = fmt.Fprintln(os.Stdout, "No migrations to roll back")
+			return 0
+		}
+		var partial *goose.PartialError
+		if errors.As(err, &partial) {
+			result = partial.Failed
+		}
+		if result != nil {
+			printMigrationResults(os.Stdout, []*goose.MigrationResult{result})
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Rollback failed: %v\n", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Migrations status error: %v\n", err)
+			return 1
+		}
+		printMigrationStatuses(os.Stdout, statuses)
+	}
+	return 0
+}
+
+func printMigrationResults(w io.Writer, results []*goose.MigrationResult) {
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		var state = "OK"
+		if r.Error != nil {
+			state = "FAILED"
+		}
+		_, _ = fmt.Fprintf(w, "%-6s %-4s %s (%s)\n", state, r.Direction, path.Base(r.Source.Path),
+			r.Duration.Round(time.Millisecond))
+	}
+}
+
+func printMigrationStatuses(w io.Writer, statuses []*goose.MigrationStatus) {
+	var writer = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+	for _, s := range statuses {
+		var appliedAt = ""
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, path.Base(s.Source.Path))
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/tests"
+	"os"
+	"time"
+)
+
+// seedRoles, seedEmployees - демонстрационные данные для локального стенда
+var seedRoles = []string{"DEVELOPER", "ANALYST", "SUPPORT", "AUDITOR"}
+
+var seedEmployees = []struct {
+	name    string
+	surname string
+	age     int8
+}{
+	{"Иван", "Иванов", 34},
+	{"Пётр", "Петров", 28},
+	{"Анна", "Смирнова", 41},
+	{"Мария", "Кузнецова", 25},
+	{"Олег", "Соколов", 52},
+}
+
+// seed - загружает демонстрационные роли и сотрудников через фикстуры интеграционных тестов.
+// Уже существующие записи пропускаются, поэтому команду можно запускать повторно
+func seed([]string) int {
+	var cfg, ok = loadConfig()
+	if !ok {
+		return 1
+	}
+	db, err := database.Connect(cfg)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Database connection error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+	var roleRepo = role.NewRepository(db)
+	var employeeRepo = employee.NewEmployeeRepository(db)
+	if err = loadSeed(roleRepo, employeeRepo); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Seed failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func loadSeed(roleRepo *role.Repository, employeeRepo *employee.Repository) (err error) {
+	// фикстуры сообщают об ошибках паникой, как и положено в тестах
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	var now = time.Now()
+	var roleFixture = tests.NewFixtureRole(roleRepo)
+	existing, err := roleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	var names = map[string]bool{}
+	for _, r := range existing {
+		names[r.Name] = true
+	}
+	for _, name := range seedRoles {
+		if names[name] {
+			fmt.Printf("role %s: exists\n", name)
+			continue
+		}
+		fmt.Printf("role %s: created with id %d\n", name, roleFixture.Role(name, now, now))
+	}
+	var employeeFixture = tests.NewFixtureEmployee(employeeRepo)
+	for _, e := range seedEmployees {
+		exists, err := employeeExists(employeeRepo, e.name, e.surname)
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Printf("employee %s %s: exists\n", e.name, e.surname)
+			continue
+		}
+		var id = employeeFixture.Employee(e.name, e.surname, e.age, now, now)
+		fmt.Printf("employee %s %s: created with id %d\n", e.name, e.surname, id)
+	}
+	return nil
+}
+
+func employeeExists(repo *employee.Repository, name, surname string) (bool, error) {
+	tx, err := repo.BeginTr()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	return repo.FindByNameAndSurname(tx, name, surname)
+}
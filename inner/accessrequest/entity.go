@@ -0,0 +1,183 @@
+package accessrequest
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Статусы запроса доступа. Из PENDING запрос переходит ровно в один из конечных статусов
+const (
+	StatusPending   = "PENDING"
+	StatusApproved  = "APPROVED"
+	StatusRejected  = "REJECTED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Типы согласующих в цепочке роли
+const (
+	// ApproverManager - руководитель запрашивающего сотрудника (employee.manager_id)
+	ApproverManager = "MANAGER"
+	// ApproverOwner - владелец запрашиваемой роли (role.owner_id)
+	ApproverOwner = "OWNER"
+	// ApproverEmployee - конкретный сотрудник
+	ApproverEmployee = "EMPLOYEE"
+	// ApproverAdmin - любой пользователь с разрешением access_request:manage
+	ApproverAdmin = "ADMIN"
+)
+
+type Entity struct {
+	Id            int64        `db:"id"`
+	RequesterId   int64        `db:"requester_id"`
+	RoleId        int64        `db:"role_id"`
+	Justification string       `db:"justification"`
+	ValidUntil    sql.NullTime `db:"valid_until"`
+	Status        string       `db:"status"`
+	CurrentStep   int          `db:"current_step"`
+	CreatedAt     time.Time    `db:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at"`
+	ClosedAt      sql.NullTime `db:"closed_at"`
+}
+
+func (e *Entity) ToResponse(approvals []ApprovalEntity) Response {
+	var response = Response{
+		Id:            e.Id,
+		RequesterId:   e.RequesterId,
+		RoleId:        e.RoleId,
+		Justification: e.Justification,
+		Status:        e.Status,
+		CurrentStep:   e.CurrentStep,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+		Approvals:     make([]ApprovalResponse, 0, len(approvals)),
+	}
+	if e.ValidUntil.Valid {
+		var validUntil = e.ValidUntil.Time
+		response.ValidUntil = &validUntil
+	}
+	if e.ClosedAt.Valid {
+		var closedAt = e.ClosedAt.Time
+		response.ClosedAt = &closedAt
+	}
+	for _, a := range approvals {
+		response.Approvals = append(response.Approvals, a.ToResponse())
+	}
+	return response
+}
+
+type Response struct {
+	Id            int64              `json:"id"`
+	RequesterId   int64              `json:"requester_id"`
+	RoleId        int64              `json:"role_id"`
+	Justification string             `json:"justification"`
+	ValidUntil    *time.Time         `json:"valid_until,omitempty" example:"2025-10-29T12:00:00Z"`
+	Status        string             `json:"status" example:"PENDING"`
+	CurrentStep   int                `json:"current_step"`
+	CreatedAt     time.Time          `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt     time.Time          `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+	ClosedAt      *time.Time         `json:"closed_at,omitempty" example:"2025-07-30T12:00:00Z"`
+	Approvals     []ApprovalResponse `json:"approvals,omitempty"`
+}
+
+// ApprovalEntity - шаг согласования конкретного запроса. ApproverId определяется при подаче
+// запроса; пустой ApproverId означает, что шаг может согласовать только администратор
+type ApprovalEntity struct {
+	RequestId    int64         `db:"request_id"`
+	StepNo       int           `db:"step_no"`
+	ApproverType string        `db:"approver_type"`
+	ApproverId   sql.NullInt64 `db:"approver_id"`
+	Status       string        `db:"status"`
+	DecidedBy    string        `db:"decided_by"`
+	Comment      string        `db:"comment"`
+	DecidedAt    sql.NullTime  `db:"decided_at"`
+}
+
+func (e *ApprovalEntity) ToResponse() ApprovalResponse {
+	var response = ApprovalResponse{
+		StepNo:       e.StepNo,
+		ApproverType: e.ApproverType,
+		ApproverId:   e.ApproverId.Int64,
+		Status:       e.Status,
+		DecidedBy:    e.DecidedBy,
+		Comment:      e.Comment,
+	}
+	if e.DecidedAt.Valid {
+		var decidedAt = e.DecidedAt.Time
+		response.DecidedAt = &decidedAt
+	}
+	return response
+}
+
+type ApprovalResponse struct {
+	StepNo       int        `json:"step_no"`
+	ApproverType string     `json:"approver_type" example:"MANAGER"`
+	ApproverId   int64      `json:"approver_id,omitempty"`
+	Status       string     `json:"status" example:"PENDING"`
+	DecidedBy    string     `json:"decided_by,omitempty"`
+	Comment      string     `json:"comment,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty" example:"2025-07-30T12:00:00Z"`
+}
+
+// StepEntity - шаг цепочки согласования, настроенной для роли
+type StepEntity struct {
+	RoleId       int64         `db:"role_id"`
+	StepNo       int           `db:"step_no"`
+	ApproverType string        `db:"approver_type"`
+	ApproverId   sql.NullInt64 `db:"approver_id"`
+}
+
+type Step struct {
+	ApproverType string `json:"approver_type" validate:"required,oneof=MANAGER OWNER EMPLOYEE ADMIN" example:"MANAGER"`
+	ApproverId   int64  `json:"approver_id" validate:"required_if=ApproverType EMPLOYEE,excluded_unless=ApproverType EMPLOYEE"`
+}
+
+// ChainRequest - цепочка согласования роли; шаги выполняются в порядке перечисления.
+// Пустая цепочка возвращает роль к согласованию по умолчанию - руководителем
+type ChainRequest struct {
+	Steps []Step `json:"steps" validate:"max=10,dive"`
+}
+
+func (req *ChainRequest) ToEntities(roleId int64) []StepEntity {
+	var steps = make([]StepEntity, 0, len(req.Steps))
+	for i, s := range req.Steps {
+		steps = append(steps, StepEntity{
+			RoleId:       roleId,
+			StepNo:       i + 1,
+			ApproverType: s.ApproverType,
+			ApproverId:   sql.NullInt64{Int64: s.ApproverId, Valid: s.ApproverId > 0},
+		})
+	}
+	return steps
+}
+
+func toSteps(entities []StepEntity) []Step {
+	var steps = make([]Step, 0, len(entities))
+	for _, e := range entities {
+		steps = append(steps, Step{ApproverType: e.ApproverType, ApproverId: e.ApproverId.Int64})
+	}
+	return steps
+}
+
+type CreateRequest struct {
+	RoleId        int64      `json:"role_id" validate:"required,min=1"`
+	Justification string     `json:"justification" validate:"required,min=5,max=1000"`
+	ValidUntil    *time.Time `json:"valid_until" example:"2025-10-29T12:00:00Z"`
+}
+
+type DecisionRequest struct {
+	Comment string `json:"comment" validate:"max=1000"`
+	// OverrideReason - причина согласования вопреки правилам SoD в режиме WARN; при окончательном
+	// согласовании сохраняется в аудите назначения роли
+	OverrideReason string `json:"override_reason" validate:"max=500"`
+}
+
+type ListRequest struct {
+	Status string `query:"status" validate:"omitempty,oneof=PENDING APPROVED REJECTED CANCELLED"`
+}
+
+// Caller - вызывающий пользователь: логины из токена и признак администратора запросов
+type Caller struct {
+	Subject           string
+	PreferredUsername string
+	Actor             string
+	Admin             bool
+}
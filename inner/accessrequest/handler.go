@@ -0,0 +1,356 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Create(ctx context.Context, caller Caller, request CreateRequest) (Response, error)
+	FindById(ctx context.Context, caller Caller, id int64) (Response, error)
+	FindMine(ctx context.Context, caller Caller) ([]Response, error)
+	FindAwaiting(ctx context.Context, caller Caller) ([]Response, error)
+	FindAll(ctx context.Context, request ListRequest) ([]Response, error)
+	Approve(ctx context.Context, caller Caller, id int64, request DecisionRequest) (Response, error)
+	Reject(ctx context.Context, caller Caller, id int64, request DecisionRequest) (Response, error)
+	Cancel(ctx context.Context, caller Caller, id int64) (Response, error)
+	FindChain(ctx context.Context, roleId int64) ([]Step, error)
+	SetChain(ctx context.Context, roleId int64, request ChainRequest) ([]Step, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/access-requests" и "/api/v1/roles/:id/approval-chain"
+func (c *Handler) RegisterRoutes() {
+	var submit = c.server.RequirePermission(web.PermAccessRequestSubmit)
+	var manage = c.server.RequirePermission(web.PermAccessRequestManage)
+	c.server.GroupApiV1.Post("/access-requests", submit, c.Create)
+	c.server.GroupApiV1.Get("/access-requests", manage, c.FindAll)
+	c.server.GroupApiV1.Get("/access-requests/my", submit, c.FindMine)
+	c.server.GroupApiV1.Get("/access-requests/awaiting", submit, c.FindAwaiting)
+	c.server.GroupApiV1.Get("/access-requests/:id", submit, c.FindById)
+	c.server.GroupApiV1.Post("/access-requests/:id/approve", submit, c.Approve)
+	c.server.GroupApiV1.Post("/access-requests/:id/reject", submit, c.Reject)
+	c.server.GroupApiV1.Post("/access-requests/:id/cancel", submit, c.Cancel)
+	c.server.GroupApiV1.Get("/roles/:id/approval-chain", c.server.RequirePermission(web.PermRoleRead), c.FindChain)
+	c.server.GroupApiV1.Put("/roles/:id/approval-chain", c.server.RequirePermission(web.PermRoleWrite), c.SetChain)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests"
+// @Description Request a role for the caller. Approvers are resolved from the role approval chain.
+// @Summary create access request
+// @Tags access-request
+// @Accept json
+// @Produce json
+// @Param request body CreateRequest true "access request"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[accessrequest.Response] "invalid request or already pending"
+// @Failure 404 {object} common.Response[accessrequest.Response] "role or employee not found"
+// @Failure 500 {object} common.Response[accessrequest.Response] "error db"
+// @Router /access-requests [post]
+// @Security BearerAuth
+func (c *Handler) Create(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Create: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Create: received request", zap.Any("request", request))
+	rsl, err := c.service.Create(ctx.Context(), c.caller(ctx), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Create: error creating access request", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests"
+// @Description Get all access requests, optionally filtered by status.
+// @Summary get access requests
+// @Tags access-request
+// @Produce json
+// @Param status query string false "PENDING, APPROVED, REJECTED or CANCELLED"
+// @Success 200 {object} common.Response[[]accessrequest.Response]
+// @Failure 400 {object} common.Response[[]accessrequest.Response] "invalid request"
+// @Failure 403 {object} common.Response[[]accessrequest.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]accessrequest.Response] "error db"
+// @Router /access-requests [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	var request ListRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindAll(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding access requests", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests/my"
+// @Description Get access requests of the caller.
+// @Summary get my access requests
+// @Tags access-request
+// @Produce json
+// @Success 200 {object} common.Response[[]accessrequest.Response]
+// @Failure 404 {object} common.Response[[]accessrequest.Response] "employee not found"
+// @Failure 500 {object} common.Response[[]accessrequest.Response] "error db"
+// @Router /access-requests/my [get]
+// @Security BearerAuth
+func (c *Handler) FindMine(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindMine(ctx.Context(), c.caller(ctx))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindMine: error finding access requests", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests/awaiting"
+// @Description Get access requests awaiting the caller's decision.
+// @Summary get access requests to decide
+// @Tags access-request
+// @Produce json
+// @Success 200 {object} common.Response[[]accessrequest.Response]
+// @Failure 404 {object} common.Response[[]accessrequest.Response] "employee not found"
+// @Failure 500 {object} common.Response[[]accessrequest.Response] "error db"
+// @Router /access-requests/awaiting [get]
+// @Security BearerAuth
+func (c *Handler) FindAwaiting(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAwaiting(ctx.Context(), c.caller(ctx))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAwaiting: error finding access requests", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests/:id"
+// @Description Find access request with its approval steps.
+// @Summary find access request
+// @Tags access-request
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[accessrequest.Response] "invalid request"
+// @Failure 403 {object} common.Response[accessrequest.Response] "Permission denied"
+// @Failure 404 {object} common.Response[accessrequest.Response] "not found"
+// @Failure 500 {object} common.Response[accessrequest.Response] "error db"
+// @Router /access-requests/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), c.caller(ctx), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding access request", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/approve"
+// @Description Approve current step of access request. The final approval assigns the role.
+// @Summary approve access request
+// @Tags access-request
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body DecisionRequest false "decision comment and sod override reason"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[accessrequest.Response] "invalid request or already decided"
+// @Failure 403 {object} common.Response[accessrequest.Response] "caller is not the approver"
+// @Failure 404 {object} common.Response[accessrequest.Response] "not found"
+// @Failure 409 {object} common.Response[[]sod.Violation] "segregation of duties violated"
+// @Failure 500 {object} common.Response[accessrequest.Response] "error db"
+// @Router /access-requests/{id}/approve [post]
+// @Security BearerAuth
+func (c *Handler) Approve(ctx *fiber.Ctx) error {
+	return c.decide(ctx, "Approve", c.service.Approve)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/reject"
+// @Description Reject access request on its current step.
+// @Summary reject access request
+// @Tags access-request
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body DecisionRequest false "decision comment"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[accessrequest.Response] "invalid request or already decided"
+// @Failure 403 {object} common.Response[accessrequest.Response] "caller is not the approver"
+// @Failure 404 {object} common.Response[accessrequest.Response] "not found"
+// @Failure 500 {object} common.Response[accessrequest.Response] "error db"
+// @Router /access-requests/{id}/reject [post]
+// @Security BearerAuth
+func (c *Handler) Reject(ctx *fiber.Ctx) error {
+	return c.decide(ctx, "Reject", c.service.Reject)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/cancel"
+// @Description Cancel own pending access request.
+// @Summary cancel access request
+// @Tags access-request
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[accessrequest.Response] "invalid request or already decided"
+// @Failure 403 {object} common.Response[accessrequest.Response] "caller is not the requester"
+// @Failure 404 {object} common.Response[accessrequest.Response] "not found"
+// @Failure 500 {object} common.Response[accessrequest.Response] "error db"
+// @Router /access-requests/{id}/cancel [post]
+// @Security BearerAuth
+func (c *Handler) Cancel(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Cancel: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Cancel(ctx.Context(), c.caller(ctx), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Cancel: error cancelling access request", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/approval-chain"
+// @Description Get approval chain of role.
+// @Summary get approval chain
+// @Tags access-request
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} common.Response[[]accessrequest.Step]
+// @Failure 400 {object} common.Response[[]accessrequest.Step] "invalid request"
+// @Failure 500 {object} common.Response[[]accessrequest.Step] "error db"
+// @Router /roles/{id}/approval-chain [get]
+// @Security BearerAuth
+func (c *Handler) FindChain(ctx *fiber.Ctx) error {
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindChain: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindChain(ctx.Context(), roleId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindChain: error finding approval chain", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/roles/:id/approval-chain"
+// @Description Replace approval chain of role. Steps are executed in the given order.
+// @Summary set approval chain
+// @Tags access-request
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param request body ChainRequest true "approval chain"
+// @Success 200 {object} common.Response[[]accessrequest.Step]
+// @Failure 400 {object} common.Response[[]accessrequest.Step] "invalid request"
+// @Failure 403 {object} common.Response[[]accessrequest.Step] "Permission denied"
+// @Failure 500 {object} common.Response[[]accessrequest.Step] "error db"
+// @Router /roles/{id}/approval-chain [put]
+// @Security BearerAuth
+func (c *Handler) SetChain(ctx *fiber.Ctx) error {
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "SetChain: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request ChainRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "SetChain: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.SetChain(ctx.Context(), roleId, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "SetChain: error setting approval chain", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+type decideFunc func(ctx context.Context, caller Caller, id int64, request DecisionRequest) (Response, error)
+
+func (c *Handler) decide(ctx *fiber.Ctx, name string, decide decideFunc) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), name+": error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request DecisionRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			c.logger.ErrorCtx(ctx.Context(), name+": error body parse", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	rsl, err := decide(ctx.Context(), c.caller(ctx), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), name+": error deciding access request", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// caller - вызывающий пользователь из токена; администратором запросов считается
+// владелец разрешения access_request:manage
+func (c *Handler) caller(ctx *fiber.Ctx) Caller {
+	var caller Caller
+	if claims, ok := web.ClaimsFromCtx(ctx); ok {
+		caller.Subject = claims.Subject
+		caller.PreferredUsername = claims.PreferredUsername
+		caller.Actor = claims.Actor()
+	}
+	caller.Admin, _ = c.server.HasPermission(ctx, web.PermAccessRequestManage)
+	return caller
+}
+
+// errResponse - при нарушении правил SoD в data возвращаются нарушенные правила, чтобы
+// согласующий мог оценить их и при необходимости повторить решение с override_reason
+func errResponse(ctx *fiber.Ctx, err error) error {
+	var violation sod.ViolationError
+	switch {
+	case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.ForbiddenError{}):
+		return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	case errors.As(err, &violation):
+		return ctx.Status(fiber.StatusConflict).JSON(&common.Response[[]sod.Violation]{
+			Message: err.Error(),
+			Data:    violation.Violations,
+		})
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
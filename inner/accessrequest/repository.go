@@ -0,0 +1,163 @@
+package accessrequest
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// FindChain - настроенная цепочка согласования роли по порядку шагов
+func (r *Repository) FindChain(ctx context.Context, roleId int64) (steps []StepEntity, err error) {
+	err = r.db.SelectContext(ctx, &steps,
+		"SELECT * FROM approval_step WHERE role_id = $1 ORDER BY step_no", roleId)
+	return steps, err
+}
+
+// ReplaceChain - заменяет цепочку согласования роли целиком
+func (r *Repository) ReplaceChain(tx *sqlx.Tx, roleId int64, steps []StepEntity) error {
+	if _, err := tx.Exec("DELETE FROM approval_step WHERE role_id = $1", roleId); err != nil {
+		return err
+	}
+	for _, s := range steps {
+		_, err := tx.Exec(
+			"INSERT INTO approval_step(role_id, step_no, approver_type, approver_id) VALUES ($1, $2, $3, $4)",
+			roleId, s.StepNo, s.ApproverType, s.ApproverId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ExistsPending(tx *sqlx.Tx, requesterId, roleId int64) (isExists bool, err error) {
+	err = tx.Get(&isExists,
+		"SELECT EXISTS(SELECT FROM access_request WHERE requester_id = $1 AND role_id = $2 AND status = 'PENDING')",
+		requesterId, roleId)
+	return isExists, err
+}
+
+// Add - сохраняет запрос вместе с шагами согласования
+func (r *Repository) Add(tx *sqlx.Tx, request Entity, approvals []ApprovalEntity) (created Entity, err error) {
+	err = tx.Get(&created,
+		`INSERT INTO access_request(requester_id, role_id, justification, valid_until)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING *`,
+		request.RequesterId, request.RoleId, request.Justification, request.ValidUntil)
+	if err != nil {
+		return Entity{}, err
+	}
+	for _, a := range approvals {
+		_, err = tx.Exec(
+			`INSERT INTO access_request_approval(request_id, step_no, approver_type, approver_id)
+			 VALUES ($1, $2, $3, $4)`,
+			created.Id, a.StepNo, a.ApproverType, a.ApproverId)
+		if err != nil {
+			return Entity{}, err
+		}
+	}
+	return created, nil
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (request Entity, err error) {
+	err = r.db.GetContext(ctx, &request, "SELECT * FROM access_request WHERE id = $1", id)
+	return request, err
+}
+
+// FindByIdForUpdate - запрос, заблокированный до конца транзакции, чтобы два согласующих
+// не приняли решение по одному шагу одновременно
+func (r *Repository) FindByIdForUpdate(tx *sqlx.Tx, id int64) (request Entity, err error) {
+	err = tx.Get(&request, "SELECT * FROM access_request WHERE id = $1 FOR UPDATE", id)
+	return request, err
+}
+
+func (r *Repository) FindApprovals(ctx context.Context, requestId int64) (approvals []ApprovalEntity, err error) {
+	err = r.db.SelectContext(ctx, &approvals,
+		"SELECT * FROM access_request_approval WHERE request_id = $1 ORDER BY step_no", requestId)
+	return approvals, err
+}
+
+func (r *Repository) FindApproval(tx *sqlx.Tx, requestId int64, stepNo int) (approval ApprovalEntity, err error) {
+	err = tx.Get(&approval,
+		"SELECT * FROM access_request_approval WHERE request_id = $1 AND step_no = $2", requestId, stepNo)
+	return approval, err
+}
+
+func (r *Repository) CountApprovals(tx *sqlx.Tx, requestId int64) (count int, err error) {
+	err = tx.Get(&count, "SELECT COUNT(*) FROM access_request_approval WHERE request_id = $1", requestId)
+	return count, err
+}
+
+// Decide - фиксирует решение по шагу согласования
+func (r *Repository) Decide(tx *sqlx.Tx, approval ApprovalEntity) error {
+	_, err := tx.Exec(
+		`UPDATE access_request_approval
+		 SET status = $3, decided_by = $4, comment = $5, decided_at = NOW()
+		 WHERE request_id = $1 AND step_no = $2`,
+		approval.RequestId, approval.StepNo, approval.Status, approval.DecidedBy, approval.Comment)
+	return err
+}
+
+// UpdateState - переводит запрос на следующий шаг или в конечный статус
+func (r *Repository) UpdateState(tx *sqlx.Tx, request Entity) (updated Entity, err error) {
+	err = tx.Get(&updated,
+		`UPDATE access_request
+		 SET status = $2, current_step = $3, updated_at = NOW(),
+		     closed_at = CASE WHEN $2 = 'PENDING' THEN NULL ELSE NOW() END
+		 WHERE id = $1
+		 RETURNING *`,
+		request.Id, request.Status, request.CurrentStep)
+	return updated, err
+}
+
+func (r *Repository) FindByRequester(ctx context.Context, requesterId int64) (requests []Entity, err error) {
+	err = r.db.SelectContext(ctx, &requests,
+		"SELECT * FROM access_request WHERE requester_id = $1 ORDER BY created_at DESC", requesterId)
+	return requests, err
+}
+
+// FindAwaiting - запросы, текущий шаг которых ждёт решения сотрудника approverId.
+// Для администратора включаются и шаги без назначенного согласующего
+func (r *Repository) FindAwaiting(ctx context.Context, approverId int64, admin bool) (requests []Entity, err error) {
+	err = r.db.SelectContext(ctx, &requests,
+		`SELECT ar.* FROM access_request ar
+		 JOIN access_request_approval a ON a.request_id = ar.id AND a.step_no = ar.current_step
+		 WHERE ar.status = 'PENDING'
+		   AND ar.requester_id <> $1
+		   AND (a.approver_id = $1 OR ($2 AND a.approver_id IS NULL))
+		 ORDER BY ar.created_at`,
+		approverId, admin)
+	return requests, err
+}
+
+func (r *Repository) FindAll(ctx context.Context, status string) (requests []Entity, err error) {
+	err = r.db.SelectContext(ctx, &requests,
+		`SELECT * FROM access_request
+		 WHERE $1 = '' OR status = $1
+		 ORDER BY created_at DESC`,
+		status)
+	return requests, err
+}
+
+// IsApprover - участвует ли сотрудник в согласовании запроса
+func (r *Repository) IsApprover(ctx context.Context, requestId, employeeId int64) (isApprover bool, err error) {
+	err = r.db.GetContext(ctx, &isApprover,
+		"SELECT EXISTS(SELECT FROM access_request_approval WHERE request_id = $1 AND approver_id = $2)",
+		requestId, employeeId)
+	return isApprover, err
+}
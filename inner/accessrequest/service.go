@@ -0,0 +1,537 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/sod"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo         Repo
+	employeeRepo EmployeeRepo
+	roleRepo     RoleRepo
+	assigner     Assigner
+	conflicts    ConflictChecker
+	validator    *validator.Validate
+	logger       common.LoggerInterface
+	// Provisioning - передача роли согласованного запроса во внешние системы после
+	// фиксации транзакции; nil - не передаётся
+	Provisioning Provisioner
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	FindChain(ctx context.Context, roleId int64) ([]StepEntity, error)
+	ReplaceChain(tx *sqlx.Tx, roleId int64, steps []StepEntity) error
+	ExistsPending(tx *sqlx.Tx, requesterId, roleId int64) (bool, error)
+	Add(tx *sqlx.Tx, request Entity, approvals []ApprovalEntity) (Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdForUpdate(tx *sqlx.Tx, id int64) (Entity, error)
+	FindApprovals(ctx context.Context, requestId int64) ([]ApprovalEntity, error)
+	FindApproval(tx *sqlx.Tx, requestId int64, stepNo int) (ApprovalEntity, error)
+	CountApprovals(tx *sqlx.Tx, requestId int64) (int, error)
+	Decide(tx *sqlx.Tx, approval ApprovalEntity) error
+	UpdateState(tx *sqlx.Tx, request Entity) (Entity, error)
+	FindByRequester(ctx context.Context, requesterId int64) ([]Entity, error)
+	FindAwaiting(ctx context.Context, approverId int64, admin bool) ([]Entity, error)
+	FindAll(ctx context.Context, status string) ([]Entity, error)
+	IsApprover(ctx context.Context, requestId, employeeId int64) (bool, error)
+}
+
+type EmployeeRepo interface {
+	FindById(id int64) (employee.Entity, error)
+	FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error)
+}
+
+type RoleRepo interface {
+	FindById(id int64) (role.Entity, error)
+}
+
+// Assigner - назначение роли в транзакции согласования запроса
+type Assigner interface {
+	AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.AssignRequest) (assignment.Response, error)
+}
+
+// ConflictChecker - поиск правил SoD, которые нарушит назначение запрошенной роли
+type ConflictChecker interface {
+	FindConflicts(tx *sqlx.Tx, employeeId, roleId int64, validFrom time.Time, validUntil sql.NullTime) ([]sod.Violation, error)
+}
+
+func NewService(
+	repo Repo,
+	employeeRepo EmployeeRepo,
+	roleRepo RoleRepo,
+	assigner Assigner,
+	conflicts ConflictChecker,
+	logger common.LoggerInterface,
+) *Service {
+	return &Service{
+		repo:         repo,
+		employeeRepo: employeeRepo,
+		roleRepo:     roleRepo,
+		assigner:     assigner,
+		conflicts:    conflicts,
+		validator:    validator.New(),
+		logger:       logger,
+	}
+}
+
+// Create - подаёт запрос роли от имени вызывающего сотрудника. Согласующие каждого шага
+// определяются сразу, поэтому смена руководителя не меняет уже поданные запросы
+func (svc *Service) Create(ctx context.Context, caller Caller, request CreateRequest) (response Response, err error) {
+	if err = svc.validator.Struct(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if request.ValidUntil != nil && !request.ValidUntil.After(time.Now()) {
+		return Response{}, common.RequestValidationError{Message: "valid_until must be in the future"}
+	}
+	requester, err := svc.resolve(ctx, caller)
+	if err != nil {
+		return Response{}, err
+	}
+	requested, err := svc.roleRepo.FindById(request.RoleId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("Role with id %d not found", request.RoleId)}
+		}
+		return Response{}, fmt.Errorf("Error finding role with id %d: %w", request.RoleId, err)
+	}
+	if err = checkRequestable(requested, request); err != nil {
+		return Response{}, err
+	}
+	chain, err := svc.repo.FindChain(ctx, request.RoleId)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding approval chain of role %d: %w", request.RoleId, err)
+	}
+	var approvals = resolveApprovers(chain, requester, requested)
+	var entity = Entity{
+		RequesterId:   requester.Id,
+		RoleId:        request.RoleId,
+		Justification: request.Justification,
+	}
+	if request.ValidUntil != nil {
+		entity.ValidUntil = sql.NullTime{Time: *request.ValidUntil, Valid: true}
+	}
+	err = common.InTx(svc.repo, "creating access request", func(tx *sqlx.Tx) error {
+		isExists, err := svc.repo.ExistsPending(tx, requester.Id, request.RoleId)
+		if err != nil {
+			return fmt.Errorf("Error checking pending access requests: %w", err)
+		}
+		if isExists {
+			return common.AlreadyExistsError{
+				Message: fmt.Sprintf("Access request for role %d is already pending", request.RoleId),
+			}
+		}
+		created, err := svc.repo.Add(tx, entity, approvals)
+		if err != nil {
+			return fmt.Errorf("Error adding access request for role %d: %w", request.RoleId, err)
+		}
+		for i := range approvals {
+			approvals[i].RequestId = created.Id
+		}
+		response = created.ToResponse(approvals)
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return response, nil
+}
+
+// FindById - запрос со всеми шагами согласования; доступен заявителю, согласующим и администратору
+func (svc *Service) FindById(ctx context.Context, caller Caller, id int64) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id access request: %d", id)}
+	}
+	entity, err := svc.findById(ctx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	if !caller.Admin {
+		current, err := svc.resolve(ctx, caller)
+		if err != nil {
+			return Response{}, err
+		}
+		isApprover, err := svc.repo.IsApprover(ctx, id, current.Id)
+		if err != nil {
+			return Response{}, fmt.Errorf("Error checking approvers of access request %d: %w", id, err)
+		}
+		if entity.RequesterId != current.Id && !isApprover {
+			return Response{}, common.ForbiddenError{Message: fmt.Sprintf("Access request %d is not visible to caller", id)}
+		}
+	}
+	return svc.withApprovals(ctx, entity)
+}
+
+// FindMine - запросы вызывающего сотрудника
+func (svc *Service) FindMine(ctx context.Context, caller Caller) ([]Response, error) {
+	requester, err := svc.resolve(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := svc.repo.FindByRequester(ctx, requester.Id)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding access requests of employee %d: %w", requester.Id, err)
+	}
+	return toResponses(entities), nil
+}
+
+// FindAwaiting - запросы, ожидающие решения вызывающего
+func (svc *Service) FindAwaiting(ctx context.Context, caller Caller) ([]Response, error) {
+	var approverId int64
+	approver, err := svc.resolve(ctx, caller)
+	if err == nil {
+		approverId = approver.Id
+	} else if !caller.Admin {
+		return nil, err
+	}
+	entities, err := svc.repo.FindAwaiting(ctx, approverId, caller.Admin)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding access requests awaiting decision: %w", err)
+	}
+	return toResponses(entities), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context, request ListRequest) ([]Response, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	entities, err := svc.repo.FindAll(ctx, request.Status)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding access requests: %w", err)
+	}
+	return toResponses(entities), nil
+}
+
+// Approve - согласует текущий шаг. Если назначение роли нарушит правила SoD, согласующий получает
+// нарушения в ViolationError; правила WARN он может обойти, указав OverrideReason. Согласование
+// последнего шага назначает роль в той же транзакции; если назначение невозможно, откатывается и решение
+func (svc *Service) Approve(ctx context.Context, caller Caller, id int64, request DecisionRequest) (Response, error) {
+	return svc.decide(ctx, caller, id, request, StatusApproved)
+}
+
+// Reject - отклоняет запрос на текущем шаге; следующие шаги не выполняются
+func (svc *Service) Reject(ctx context.Context, caller Caller, id int64, request DecisionRequest) (Response, error) {
+	return svc.decide(ctx, caller, id, request, StatusRejected)
+}
+
+// Cancel - отзыв запроса заявителем до окончательного решения
+func (svc *Service) Cancel(ctx context.Context, caller Caller, id int64) (response Response, err error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id access request: %d", id)}
+	}
+	requester, err := svc.resolve(ctx, caller)
+	if err != nil {
+		return Response{}, err
+	}
+	var updated Entity
+	err = common.InTx(svc.repo, "cancelling access request", func(tx *sqlx.Tx) error {
+		entity, err := svc.findPendingForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if entity.RequesterId != requester.Id {
+			return common.ForbiddenError{Message: "Only the requester can cancel access request"}
+		}
+		entity.Status = StatusCancelled
+		updated, err = svc.repo.UpdateState(tx, entity)
+		if err != nil {
+			return fmt.Errorf("Error cancelling access request %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return svc.withApprovals(ctx, updated)
+}
+
+// FindChain - цепочка согласования роли; для роли без настроенной цепочки - шаг руководителя
+func (svc *Service) FindChain(ctx context.Context, roleId int64) ([]Step, error) {
+	if roleId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id role: %d", roleId)}
+	}
+	chain, err := svc.repo.FindChain(ctx, roleId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding approval chain of role %d: %w", roleId, err)
+	}
+	if len(chain) == 0 {
+		chain = defaultChain(roleId)
+	}
+	return toSteps(chain), nil
+}
+
+// SetChain - заменяет цепочку согласования роли. Уже поданные запросы идут по старой цепочке
+func (svc *Service) SetChain(ctx context.Context, roleId int64, request ChainRequest) ([]Step, error) {
+	if roleId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id role: %d", roleId)}
+	}
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	var steps = request.ToEntities(roleId)
+	err := common.InTx(svc.repo, "setting approval chain", func(tx *sqlx.Tx) error {
+		if err := svc.repo.ReplaceChain(tx, roleId, steps); err != nil {
+			return fmt.Errorf("Error setting approval chain of role %d: %w", roleId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return svc.FindChain(ctx, roleId)
+}
+
+func (svc *Service) decide(
+	ctx context.Context,
+	caller Caller,
+	id int64,
+	request DecisionRequest,
+	decision string,
+) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id access request: %d", id)}
+	}
+	if err := svc.validator.Struct(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	// администратор без учётной записи сотрудника согласует как администратор
+	var deciderId int64
+	decider, err := svc.resolve(ctx, caller)
+	if err == nil {
+		deciderId = decider.Id
+	} else if !caller.Admin {
+		return Response{}, err
+	}
+	var updated Entity
+	err = common.InTx(svc.repo, "deciding access request", func(tx *sqlx.Tx) error {
+		entity, err := svc.findPendingForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if entity.RequesterId == deciderId {
+			return common.ForbiddenError{Message: "Requester cannot decide on own access request"}
+		}
+		approval, err := svc.repo.FindApproval(tx, id, entity.CurrentStep)
+		if err != nil {
+			return fmt.Errorf("Error finding step %d of access request %d: %w", entity.CurrentStep, id, err)
+		}
+		var isApprover = approval.ApproverId.Valid && approval.ApproverId.Int64 == deciderId
+		if !isApprover && !caller.Admin {
+			return common.ForbiddenError{
+				Message: fmt.Sprintf("Caller is not the approver of step %d of access request %d", approval.StepNo, id),
+			}
+		}
+		if decision == StatusApproved {
+			if err = svc.checkSod(tx, entity, request.OverrideReason); err != nil {
+				return err
+			}
+		}
+		approval.Status = decision
+		approval.DecidedBy = caller.Actor
+		approval.Comment = request.Comment
+		if err = svc.repo.Decide(tx, approval); err != nil {
+			return fmt.Errorf("Error saving decision on access request %d: %w", id, err)
+		}
+		steps, err := svc.repo.CountApprovals(tx, id)
+		if err != nil {
+			return fmt.Errorf("Error counting steps of access request %d: %w", id, err)
+		}
+		switch {
+		case decision == StatusRejected:
+			entity.Status = StatusRejected
+		case entity.CurrentStep < steps:
+			entity.CurrentStep++
+		default:
+			entity.Status = StatusApproved
+		}
+		updated, err = svc.repo.UpdateState(tx, entity)
+		if err != nil {
+			return fmt.Errorf("Error updating access request %d: %w", id, err)
+		}
+		if updated.Status != StatusApproved {
+			return nil
+		}
+		return svc.grant(ctx, tx, caller.Actor, updated, request.OverrideReason)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.logger.DebugCtx(ctx, "decide: access request decided",
+		zap.Int64("id", id), zap.String("actor", caller.Actor), zap.String("status", updated.Status))
+	if updated.Status == StatusApproved {
+		svc.provision(ctx, updated.RequesterId)
+	}
+	return svc.withApprovals(ctx, updated)
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil && len(employeeIds) > 0 {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+// checkSod - нарушения правил SoD назначением запрошенной роли показываются согласующему
+// на каждом шаге, а не только при назначении после последнего шага
+func (svc *Service) checkSod(tx *sqlx.Tx, entity Entity, overrideReason string) error {
+	violations, err := svc.conflicts.FindConflicts(tx, entity.RequesterId, entity.RoleId, time.Now(), entity.ValidUntil)
+	if err != nil {
+		return fmt.Errorf("Error checking sod rules for access request %d: %w", entity.Id, err)
+	}
+	return sod.Check(violations, overrideReason)
+}
+
+// grant - назначает роль по согласованному запросу. Правила SoD в режиме WARN обходятся только
+// с причиной, явно указанной согласующим последнего шага; правила BLOCK запрещают назначение
+func (svc *Service) grant(ctx context.Context, tx *sqlx.Tx, actor string, entity Entity, overrideReason string) error {
+	var request = assignment.AssignRequest{
+		EmployeeId:     entity.RequesterId,
+		RoleId:         entity.RoleId,
+		OverrideReason: overrideReason,
+	}
+	if entity.ValidUntil.Valid {
+		var validUntil = entity.ValidUntil.Time
+		request.ValidUntil = &validUntil
+	}
+	if _, err := svc.assigner.AssignInTx(ctx, tx, actor, request); err != nil {
+		return fmt.Errorf("Error granting role %d by access request %d: %w", entity.RoleId, entity.Id, err)
+	}
+	return nil
+}
+
+func (svc *Service) findById(ctx context.Context, id int64) (Entity, error) {
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("Access request with id %d not found", id)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("Error finding access request with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+func (svc *Service) findPendingForUpdate(tx *sqlx.Tx, id int64) (Entity, error) {
+	entity, err := svc.repo.FindByIdForUpdate(tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("Access request with id %d not found", id)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("Error finding access request with id %d: %w", id, err)
+	}
+	if entity.Status != StatusPending {
+		return Entity{}, common.RequestValidationError{
+			Message: fmt.Sprintf("Access request %d is already %s", id, entity.Status),
+		}
+	}
+	return entity, nil
+}
+
+func (svc *Service) withApprovals(ctx context.Context, entity Entity) (Response, error) {
+	approvals, err := svc.repo.FindApprovals(ctx, entity.Id)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding approvals of access request %d: %w", entity.Id, err)
+	}
+	return entity.ToResponse(approvals), nil
+}
+
+// resolve - сотрудник вызывающего пользователя: сначала по sub, затем по preferred_username
+func (svc *Service) resolve(ctx context.Context, caller Caller) (employee.Entity, error) {
+	entity, err := svc.employeeRepo.FindByIdentity(ctx, caller.Subject, caller.PreferredUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return employee.Entity{}, common.NotFoundError{Message: "No employee is linked to the token subject"}
+	}
+	if err != nil {
+		return employee.Entity{}, fmt.Errorf("Error finding employee linked to the token: %w", err)
+	}
+	return entity, nil
+}
+
+// checkRequestable - роль доступна для запроса, а запрошенный срок не превышает
+// максимальный срок назначения роли
+func checkRequestable(requested role.Entity, request CreateRequest) error {
+	if !requested.Requestable {
+		return common.RequestValidationError{Message: fmt.Sprintf("Role %s cannot be requested", requested.Name)}
+	}
+	if !requested.MaxAssignmentDays.Valid || request.ValidUntil == nil {
+		return nil
+	}
+	var limit = time.Now().AddDate(0, 0, int(requested.MaxAssignmentDays.Int32))
+	if request.ValidUntil.After(limit) {
+		return common.RequestValidationError{
+			Message: fmt.Sprintf("Role %s can be assigned for at most %d days", requested.Name, requested.MaxAssignmentDays.Int32),
+		}
+	}
+	return nil
+}
+
+// resolveApprovers - шаги согласования запроса с конкретными согласующими. Шаг без
+// согласующего (нет руководителя или владельца роли, согласующий - сам заявитель)
+// переходит к администратору. Роль высокого риска всегда согласует администратор:
+// если в цепочке нет шага ADMIN, он добавляется последним
+func resolveApprovers(chain []StepEntity, requester employee.Entity, requested role.Entity) []ApprovalEntity {
+	if len(chain) == 0 {
+		chain = defaultChain(0)
+	}
+	if requested.IsHighRisk() && !hasAdminStep(chain) {
+		chain = append(chain[:len(chain):len(chain)], StepEntity{ApproverType: ApproverAdmin})
+	}
+	var approvals = make([]ApprovalEntity, 0, len(chain))
+	for i, step := range chain {
+		var approverId sql.NullInt64
+		switch step.ApproverType {
+		case ApproverManager:
+			approverId = requester.ManagerId
+		case ApproverOwner:
+			approverId = requested.OwnerId
+		case ApproverEmployee:
+			approverId = step.ApproverId
+		}
+		if approverId.Valid && approverId.Int64 == requester.Id {
+			approverId = sql.NullInt64{}
+		}
+		approvals = append(approvals, ApprovalEntity{
+			StepNo:       i + 1,
+			ApproverType: step.ApproverType,
+			ApproverId:   approverId,
+			Status:       StatusPending,
+		})
+	}
+	return approvals
+}
+
+func hasAdminStep(chain []StepEntity) bool {
+	for _, step := range chain {
+		if step.ApproverType == ApproverAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultChain(roleId int64) []StepEntity {
+	return []StepEntity{{RoleId: roleId, StepNo: 1, ApproverType: ApproverManager}}
+}
+
+func toResponses(entities []Entity) []Response {
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse(nil))
+	}
+	return responses
+}
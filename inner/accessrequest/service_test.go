@@ -0,0 +1,658 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/sod"
+	"idm/inner/testutil"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) FindChain(ctx context.Context, roleId int64) ([]StepEntity, error) {
+	args := m.Called(ctx, roleId)
+	return args.Get(0).([]StepEntity), args.Error(1)
+}
+
+func (m *MockRepo) ReplaceChain(tx *sqlx.Tx, roleId int64, steps []StepEntity) error {
+	args := m.Called(tx, roleId, steps)
+	return args.Error(0)
+}
+
+func (m *MockRepo) ExistsPending(tx *sqlx.Tx, requesterId, roleId int64) (bool, error) {
+	args := m.Called(tx, requesterId, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) Add(tx *sqlx.Tx, request Entity, approvals []ApprovalEntity) (Entity, error) {
+	args := m.Called(tx, request, approvals)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdate(tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindApprovals(ctx context.Context, requestId int64) ([]ApprovalEntity, error) {
+	args := m.Called(ctx, requestId)
+	return args.Get(0).([]ApprovalEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindApproval(tx *sqlx.Tx, requestId int64, stepNo int) (ApprovalEntity, error) {
+	args := m.Called(tx, requestId, stepNo)
+	return args.Get(0).(ApprovalEntity), args.Error(1)
+}
+
+func (m *MockRepo) CountApprovals(tx *sqlx.Tx, requestId int64) (int, error) {
+	args := m.Called(tx, requestId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepo) Decide(tx *sqlx.Tx, approval ApprovalEntity) error {
+	args := m.Called(tx, approval)
+	return args.Error(0)
+}
+
+func (m *MockRepo) UpdateState(tx *sqlx.Tx, request Entity) (Entity, error) {
+	args := m.Called(tx, request)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByRequester(ctx context.Context, requesterId int64) ([]Entity, error) {
+	args := m.Called(ctx, requesterId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAwaiting(ctx context.Context, approverId int64, admin bool) ([]Entity, error) {
+	args := m.Called(ctx, approverId, admin)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context, status string) ([]Entity, error) {
+	args := m.Called(ctx, status)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) IsApprover(ctx context.Context, requestId, employeeId int64) (bool, error) {
+	args := m.Called(ctx, requestId, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockEmployeeRepo struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeRepo) FindById(id int64) (employee.Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+func (m *MockEmployeeRepo) FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error) {
+	args := m.Called(ctx, subject, username)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+type MockRoleRepo struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepo) FindById(id int64) (role.Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(role.Entity), args.Error(1)
+}
+
+type MockAssigner struct {
+	mock.Mock
+}
+
+func (m *MockAssigner) AssignInTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	actor string,
+	request assignment.AssignRequest,
+) (assignment.Response, error) {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Get(0).(assignment.Response), args.Error(1)
+}
+
+type MockConflicts struct {
+	mock.Mock
+}
+
+func (m *MockConflicts) FindConflicts(
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom time.Time,
+	validUntil sql.NullTime,
+) ([]sod.Violation, error) {
+	args := m.Called(tx, employeeId, roleId, validFrom, validUntil)
+	return args.Get(0).([]sod.Violation), args.Error(1)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+type fixture struct {
+	repo      *MockRepo
+	employees *MockEmployeeRepo
+	roles     *MockRoleRepo
+	assigner  *MockAssigner
+	conflicts *MockConflicts
+	svc       *Service
+}
+
+func newFixture() fixture {
+	f := fixture{
+		repo:      new(MockRepo),
+		employees: new(MockEmployeeRepo),
+		roles:     new(MockRoleRepo),
+		assigner:  new(MockAssigner),
+		conflicts: new(MockConflicts),
+	}
+	f.svc = NewService(f.repo, f.employees, f.roles, f.assigner, f.conflicts, &MockLogger{})
+	return f
+}
+
+// login - сотрудник, связанный с логином токена
+func (f fixture) login(login string, entity employee.Entity) Caller {
+	f.employees.On("FindByIdentity", mock.Anything, login, "").Return(entity, nil)
+	return Caller{Subject: login, Actor: login}
+}
+
+var (
+	requester = employee.Entity{Id: 1, ManagerId: sql.NullInt64{Int64: 2, Valid: true}}
+	manager   = employee.Entity{Id: 2}
+	owner     = employee.Entity{Id: 3}
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should create request approved by manager when role has no chain", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		tx, mockTr := testutil.NewTx(t, true)
+		f.roles.On("FindById", int64(10)).Return(role.Entity{Id: 10, Requestable: true}, nil)
+		f.repo.On("FindChain", ctx, int64(10)).Return([]StepEntity(nil), nil)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("ExistsPending", tx, int64(1), int64(10)).Return(false, nil)
+		f.repo.On("Add", tx, mock.Anything, []ApprovalEntity{{
+			StepNo: 1, ApproverType: ApproverManager, ApproverId: requester.ManagerId, Status: StatusPending,
+		}}).Return(Entity{Id: 5, RequesterId: 1, RoleId: 10, Status: StatusPending, CurrentStep: 1}, nil)
+
+		got, err := f.svc.Create(ctx, caller, CreateRequest{RoleId: 10, Justification: "month-end close"})
+
+		a.NoError(err)
+		a.Equal(int64(5), got.Id)
+		a.Len(got.Approvals, 1)
+		a.Equal(int64(2), got.Approvals[0].ApproverId)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject duplicate pending request", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		tx, mockTr := testutil.NewTx(t, false)
+		f.roles.On("FindById", int64(10)).Return(role.Entity{Id: 10, Requestable: true}, nil)
+		f.repo.On("FindChain", ctx, int64(10)).Return([]StepEntity(nil), nil)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("ExistsPending", tx, int64(1), int64(10)).Return(true, nil)
+
+		_, err := f.svc.Create(ctx, caller, CreateRequest{RoleId: 10, Justification: "month-end close"})
+
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return NotFoundError for unknown role", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		f.roles.On("FindById", int64(10)).Return(role.Entity{}, sql.ErrNoRows)
+
+		_, err := f.svc.Create(ctx, caller, CreateRequest{RoleId: 10, Justification: "month-end close"})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should reject non-requestable role", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		f.roles.On("FindById", int64(10)).Return(role.Entity{Id: 10, Name: "ROOT"}, nil)
+
+		_, err := f.svc.Create(ctx, caller, CreateRequest{RoleId: 10, Justification: "month-end close"})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		f.repo.AssertNotCalled(t, "FindChain", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should reject valid_until beyond role max duration", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		until := time.Now().AddDate(0, 0, 31)
+		f.roles.On("FindById", int64(10)).Return(role.Entity{Id: 10, Name: "DEV", Requestable: true,
+			MaxAssignmentDays: sql.NullInt32{Int32: 30, Valid: true}}, nil)
+
+		_, err := f.svc.Create(ctx, caller,
+			CreateRequest{RoleId: 10, Justification: "month-end close", ValidUntil: &until})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+
+	t.Run("Should reject valid_until in the past", func(t *testing.T) {
+		t.Parallel()
+		f := newFixture()
+		past := time.Now().Add(-time.Hour)
+
+		_, err := f.svc.Create(ctx, Caller{Subject: "alice"},
+			CreateRequest{RoleId: 10, Justification: "month-end close", ValidUntil: &past})
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestResolveApprovers(t *testing.T) {
+	var noManager = employee.Entity{Id: 1}
+	tests := []struct {
+		name      string
+		chain     []StepEntity
+		requester employee.Entity
+		requested role.Entity
+		want      []sql.NullInt64
+	}{
+		{"default chain goes to manager", nil, requester, role.Entity{}, []sql.NullInt64{requester.ManagerId}},
+		{"no manager falls back to admin", nil, noManager, role.Entity{}, []sql.NullInt64{{}}},
+		{
+			"owner step goes to role owner",
+			[]StepEntity{{StepNo: 1, ApproverType: ApproverOwner}},
+			requester,
+			role.Entity{OwnerId: sql.NullInt64{Int64: owner.Id, Valid: true}},
+			[]sql.NullInt64{{Int64: owner.Id, Valid: true}},
+		},
+		{
+			"role without owner falls back to admin",
+			[]StepEntity{{StepNo: 1, ApproverType: ApproverOwner}},
+			requester,
+			role.Entity{},
+			[]sql.NullInt64{{}},
+		},
+		{
+			"high-risk role requires admin step",
+			nil,
+			requester,
+			role.Entity{RiskLevel: role.RiskHigh},
+			[]sql.NullInt64{requester.ManagerId, {}},
+		},
+		{
+			"high-risk role keeps existing admin step",
+			[]StepEntity{{StepNo: 1, ApproverType: ApproverAdmin}},
+			requester,
+			role.Entity{RiskLevel: role.RiskHigh},
+			[]sql.NullInt64{{}},
+		},
+		{
+			"multi-step chain keeps order",
+			[]StepEntity{
+				{StepNo: 1, ApproverType: ApproverManager},
+				{StepNo: 2, ApproverType: ApproverEmployee, ApproverId: sql.NullInt64{Int64: owner.Id, Valid: true}},
+				{StepNo: 3, ApproverType: ApproverAdmin},
+			},
+			requester,
+			role.Entity{},
+			[]sql.NullInt64{requester.ManagerId, {Int64: owner.Id, Valid: true}, {}},
+		},
+		{
+			"requester cannot be own approver",
+			[]StepEntity{{StepNo: 1, ApproverType: ApproverEmployee, ApproverId: sql.NullInt64{Int64: 1, Valid: true}}},
+			requester,
+			role.Entity{},
+			[]sql.NullInt64{{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvals := resolveApprovers(tt.chain, tt.requester, tt.requested)
+
+			var got []sql.NullInt64
+			for i, a := range approvals {
+				assert.Equal(t, i+1, a.StepNo)
+				got = append(got, a.ApproverId)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	ctx := context.Background()
+	pending := func(step int) Entity {
+		return Entity{Id: 5, RequesterId: 1, RoleId: 10, Justification: "close", Status: StatusPending, CurrentStep: step}
+	}
+	approval := func(step int, approverId int64) ApprovalEntity {
+		return ApprovalEntity{RequestId: 5, StepNo: step, ApproverId: sql.NullInt64{Int64: approverId, Valid: true},
+			Status: StatusPending}
+	}
+
+	t.Run("Should move to next step on intermediate approval", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, true)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).
+			Return([]sod.Violation{}, nil)
+		f.repo.On("Decide", tx, mock.MatchedBy(func(e ApprovalEntity) bool {
+			return e.Status == StatusApproved && e.DecidedBy == "bob" && e.Comment == "ok"
+		})).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(2, nil)
+		f.repo.On("UpdateState", tx, pending(2)).Return(pending(2), nil)
+		f.repo.On("FindApprovals", ctx, int64(5)).Return([]ApprovalEntity{}, nil)
+
+		got, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{Comment: "ok"})
+
+		a.NoError(err)
+		a.Equal(StatusPending, got.Status)
+		a.Equal(2, got.CurrentStep)
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.assigner.AssertNotCalled(t, "AssignInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should assign role in the same transaction on final approval", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("carol", owner)
+		tx, mockTr := testutil.NewTx(t, true)
+		approved := pending(2)
+		approved.Status = StatusApproved
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(2), nil)
+		f.repo.On("FindApproval", tx, int64(5), 2).Return(approval(2, owner.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).
+			Return([]sod.Violation{}, nil)
+		f.repo.On("Decide", tx, mock.Anything).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(2, nil)
+		f.repo.On("UpdateState", tx, approved).Return(approved, nil)
+		f.assigner.On("AssignInTx", ctx, tx, "carol", mock.MatchedBy(func(r assignment.AssignRequest) bool {
+			return r.EmployeeId == 1 && r.RoleId == 10 && r.OverrideReason == ""
+		})).Return(assignment.Response{EmployeeId: 1, RoleId: 10}, nil)
+		f.repo.On("FindApprovals", ctx, int64(5)).Return([]ApprovalEntity{}, nil)
+
+		got, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{})
+
+		a.NoError(err)
+		a.Equal(StatusApproved, got.Status)
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.assigner.AssertExpectations(t)
+	})
+
+	t.Run("Should show sod violations to approver and keep request pending", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, false)
+		violations := []sod.Violation{{RuleName: "payments", Mode: sod.ModeWarn}}
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).Return(violations, nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{Comment: "ok"})
+
+		var violation sod.ViolationError
+		a.True(errors.As(err, &violation))
+		a.Equal(violations, violation.Violations)
+		a.True(violation.Overridable)
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.repo.AssertNotCalled(t, "Decide", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should pass explicit override reason to assignment", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, true)
+		approved := pending(1)
+		approved.Status = StatusApproved
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).
+			Return([]sod.Violation{{RuleName: "payments", Mode: sod.ModeWarn}}, nil)
+		f.repo.On("Decide", tx, mock.Anything).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(1, nil)
+		f.repo.On("UpdateState", tx, approved).Return(approved, nil)
+		f.assigner.On("AssignInTx", ctx, tx, "bob", mock.MatchedBy(func(r assignment.AssignRequest) bool {
+			return r.OverrideReason == "quarter close"
+		})).Return(assignment.Response{EmployeeId: 1, RoleId: 10}, nil)
+		f.repo.On("FindApprovals", ctx, int64(5)).Return([]ApprovalEntity{}, nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{OverrideReason: "quarter close"})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.assigner.AssertExpectations(t)
+	})
+
+	t.Run("Should not let override reason bypass block rules", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, false)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).
+			Return([]sod.Violation{{RuleName: "payments", Mode: sod.ModeBlock}}, nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{OverrideReason: "quarter close"})
+
+		a.True(errors.As(err, &sod.ViolationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.assigner.AssertNotCalled(t, "AssignInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should rollback final approval when assignment violates sod", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, false)
+		approved := pending(1)
+		approved.Status = StatusApproved
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.conflicts.On("FindConflicts", tx, int64(1), int64(10), mock.Anything, sql.NullTime{}).
+			Return([]sod.Violation{}, nil)
+		f.repo.On("Decide", tx, mock.Anything).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(1, nil)
+		f.repo.On("UpdateState", tx, approved).Return(approved, nil)
+		f.assigner.On("AssignInTx", ctx, tx, "bob", mock.Anything).
+			Return(assignment.Response{}, sod.ViolationError{Violations: []sod.Violation{{Mode: sod.ModeBlock}}})
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{})
+
+		a.True(errors.As(err, &sod.ViolationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject request and close it", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, true)
+		rejected := pending(1)
+		rejected.Status = StatusRejected
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+		f.repo.On("Decide", tx, mock.MatchedBy(func(e ApprovalEntity) bool {
+			return e.Status == StatusRejected
+		})).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(2, nil)
+		f.repo.On("UpdateState", tx, rejected).Return(rejected, nil)
+		f.repo.On("FindApprovals", ctx, int64(5)).Return([]ApprovalEntity{}, nil)
+
+		got, err := f.svc.Reject(ctx, caller, 5, DecisionRequest{Comment: "not needed"})
+
+		a.NoError(err)
+		a.Equal(StatusRejected, got.Status)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should forbid decision by someone other than the step approver", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("carol", owner)
+		tx, mockTr := testutil.NewTx(t, false)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(approval(1, manager.Id), nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{})
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should let admin decide step without approver", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		f.employees.On("FindByIdentity", mock.Anything, "admin", "").Return(employee.Entity{}, sql.ErrNoRows)
+		tx, mockTr := testutil.NewTx(t, true)
+		rejected := pending(1)
+		rejected.Status = StatusRejected
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+		f.repo.On("FindApproval", tx, int64(5), 1).Return(ApprovalEntity{RequestId: 5, StepNo: 1}, nil)
+		f.repo.On("Decide", tx, mock.Anything).Return(nil)
+		f.repo.On("CountApprovals", tx, int64(5)).Return(1, nil)
+		f.repo.On("UpdateState", tx, rejected).Return(rejected, nil)
+		f.repo.On("FindApprovals", ctx, int64(5)).Return([]ApprovalEntity{}, nil)
+
+		_, err := f.svc.Reject(ctx, Caller{Subject: "admin", Actor: "admin", Admin: true}, 5, DecisionRequest{})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should forbid requester to approve own request", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("alice", requester)
+		caller.Admin = true
+		tx, mockTr := testutil.NewTx(t, false)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(pending(1), nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{})
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject decision on closed request", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, false)
+		closed := pending(1)
+		closed.Status = StatusCancelled
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).Return(closed, nil)
+
+		_, err := f.svc.Approve(ctx, caller, 5, DecisionRequest{})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestCancel(t *testing.T) {
+	t.Run("Should forbid cancelling someone else's request", func(t *testing.T) {
+		a := assert.New(t)
+		f := newFixture()
+		caller := f.login("bob", manager)
+		tx, mockTr := testutil.NewTx(t, false)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("FindByIdForUpdate", tx, int64(5)).
+			Return(Entity{Id: 5, RequesterId: 1, Status: StatusPending, CurrentStep: 1}, nil)
+
+		_, err := f.svc.Cancel(context.Background(), caller, 5)
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestSetChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ChainRequest
+	}{
+		{"employee step without approver", ChainRequest{Steps: []Step{{ApproverType: ApproverEmployee}}}},
+		{"manager step with approver", ChainRequest{Steps: []Step{{ApproverType: ApproverManager, ApproverId: 3}}}},
+		{"unknown approver type", ChainRequest{Steps: []Step{{ApproverType: "AUDITOR"}}}},
+		{"owner step with approver", ChainRequest{Steps: []Step{{ApproverType: ApproverOwner, ApproverId: 3}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+
+			_, err := f.svc.SetChain(context.Background(), 10, tt.request)
+
+			assert.True(t, errors.As(err, &common.RequestValidationError{}))
+			f.repo.AssertNotCalled(t, "BeginTr")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package assignment
+
+import (
+	"database/sql"
+	"idm/inner/sod"
+	"time"
+)
+
+// Источники назначения роли
+const (
+	// SourceManual - роль выдана администратором или по запросу доступа
+	SourceManual = "MANUAL"
+	// SourceRule - роль выдана правилом базового доступа и отзывается при его пересчёте
+	SourceRule = "RULE"
+)
+
+type Entity struct {
+	EmployeeId int64        `db:"employee_id"`
+	RoleId     int64        `db:"role_id"`
+	ValidFrom  time.Time    `db:"valid_from"`
+	ValidUntil sql.NullTime `db:"valid_until"`
+	Source     string       `db:"source"`
+	CreatedAt  time.Time    `db:"created_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	var response = Response{
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		ValidFrom:  e.ValidFrom,
+		Source:     e.Source,
+		CreatedAt:  e.CreatedAt,
+	}
+	if e.ValidUntil.Valid {
+		var validUntil = e.ValidUntil.Time
+		response.ValidUntil = &validUntil
+	}
+	return response
+}
+
+type Response struct {
+	EmployeeId int64      `json:"employee_id"`
+	RoleId     int64      `json:"role_id"`
+	ValidFrom  time.Time  `json:"valid_from" example:"2025-07-29T12:00:00Z"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" example:"2025-10-29T12:00:00Z"`
+	Source     string     `json:"source" example:"MANUAL"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// AssignRequest - назначение роли сотруднику. Без valid_from роль действует сразу,
+// без valid_until - бессрочно
+type AssignRequest struct {
+	EmployeeId int64      `json:"employee_id" validate:"required,min=1"`
+	RoleId     int64      `json:"role_id" validate:"required,min=1"`
+	ValidFrom  *time.Time `json:"valid_from" example:"2025-07-29T12:00:00Z"`
+	ValidUntil *time.Time `json:"valid_until" example:"2025-10-29T12:00:00Z"`
+	// OverrideReason - причина назначения вопреки правилам SoD в режиме WARN, сохраняется в аудите
+	OverrideReason string `json:"override_reason" validate:"max=500"`
+	// Source - источник назначения; задаётся только внутренними процессами, по умолчанию MANUAL
+	Source string `json:"-"`
+}
+
+func (req *AssignRequest) ToEntity(now time.Time) Entity {
+	var entity = Entity{
+		EmployeeId: req.EmployeeId,
+		RoleId:     req.RoleId,
+		ValidFrom:  now,
+		Source:     SourceManual,
+	}
+	if req.Source != "" {
+		entity.Source = req.Source
+	}
+	if req.ValidFrom != nil {
+		entity.ValidFrom = *req.ValidFrom
+	}
+	if req.ValidUntil != nil {
+		entity.ValidUntil = sql.NullTime{Time: *req.ValidUntil, Valid: true}
+	}
+	return entity
+}
+
+// sodOverride - детали аудита для назначения вопреки правилам SoD
+type sodOverride struct {
+	Assignment     Response        `json:"assignment"`
+	OverrideReason string          `json:"override_reason"`
+	Violations     []sod.Violation `json:"violations"`
+}
+
+type RevokeRequest struct {
+	EmployeeId int64 `json:"employee_id" validate:"required,min=1"`
+	RoleId     int64 `json:"role_id" validate:"required,min=1"`
+}
+
+type ExpiringRequest struct {
+	Days int `query:"days" validate:"min=1,max=365"`
+}
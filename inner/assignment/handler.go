@@ -0,0 +1,146 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Assign(ctx context.Context, actor string, request AssignRequest) (Response, error)
+	Revoke(ctx context.Context, actor string, request RevokeRequest) error
+	FindExpiring(ctx context.Context, request ExpiringRequest) ([]Response, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрута "/api/v1/assignments"
+func (c *Handler) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/assignments", c.Assign)
+	c.server.GroupApiV1.Delete("/assignments", c.Revoke)
+	c.server.GroupApiV1.Get("/assignments/expiring", c.FindExpiring)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/assignments"
+// @Description Assign role to employee, optionally for a limited period.
+// @Summary assign role
+// @Tags assignment
+// @Accept json
+// @Produce json
+// @Param request body AssignRequest true "assignment"
+// @Success 200 {object} common.Response[assignment.Response]
+// @Failure 400 {object} common.Response[assignment.Response] "invalid request"
+// @Failure 403 {object} common.Response[assignment.Response] "Permission denied"
+// @Failure 409 {object} common.Response[assignment.Response] "segregation of duties violated"
+// @Failure 500 {object} common.Response[assignment.Response] "error db"
+// @Router /assignments [post]
+// @Security BearerAuth
+func (c *Handler) Assign(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermAssignmentWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	claims, _ := web.ClaimsFromCtx(ctx)
+	var request AssignRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Assign: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Assign: received request", zap.Any("request", request))
+	rsl, err := c.service.Assign(ctx.Context(), claims.Actor(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Assign: error assigning", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/assignments"
+// @Description Revoke role from employee.
+// @Summary revoke role
+// @Tags assignment
+// @Accept json
+// @Produce json
+// @Param request body RevokeRequest true "assignment to revoke"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 404 {object} common.Response[any] "role is not assigned"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /assignments [delete]
+// @Security BearerAuth
+func (c *Handler) Revoke(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermAssignmentWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	claims, _ := web.ClaimsFromCtx(ctx)
+	var request RevokeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Revoke: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Revoke: received request", zap.Any("request", request))
+	if err := c.service.Revoke(ctx.Context(), claims.Actor(), request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Revoke: error revoking", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/assignments/expiring?days=7"
+// @Description Find role assignments expiring in the next N days.
+// @Summary find expiring assignments
+// @Tags assignment
+// @Produce json
+// @Param days query int false "Number of days, 7 by default"
+// @Success 200 {object} common.Response[[]assignment.Response]
+// @Failure 400 {object} common.Response[[]assignment.Response] "invalid request"
+// @Failure 403 {object} common.Response[[]assignment.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]assignment.Response] "error db"
+// @Router /assignments/expiring [get]
+// @Security BearerAuth
+func (c *Handler) FindExpiring(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermAssignmentRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	var request = ExpiringRequest{Days: 7}
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindExpiring: query parse error", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	rsl, err := c.service.FindExpiring(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindExpiring: error finding", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	case errors.As(err, &sod.ViolationError{}):
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,86 @@
+package assignment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// Assign - назначает роль, при повторном назначении обновляет срок действия.
+// Ручное назначение роли, выданной правилом, делает её ручной, но не наоборот
+func (r *Repository) Assign(tx *sqlx.Tx, assignment Entity) (created Entity, err error) {
+	query := `INSERT INTO employee_role(employee_id, role_id, valid_from, valid_until, source)
+			  VALUES (:employee_id, :role_id, :valid_from, :valid_until, :source)
+			  ON CONFLICT (employee_id, role_id)
+			  DO UPDATE SET valid_from = EXCLUDED.valid_from, valid_until = EXCLUDED.valid_until,
+			                source = CASE WHEN employee_role.source = 'MANUAL' THEN 'MANUAL' ELSE EXCLUDED.source END
+			  RETURNING *`
+	query, args, err := tx.BindNamed(query, &assignment)
+	if err != nil {
+		return Entity{}, err
+	}
+	err = tx.Get(&created, query, args...)
+	return created, err
+}
+
+// FindMaxAssignmentDays - максимальный срок назначения роли в днях, NULL - бессрочно
+func (r *Repository) FindMaxAssignmentDays(tx *sqlx.Tx, roleId int64) (maxDays sql.NullInt32, err error) {
+	err = tx.Get(&maxDays, "SELECT max_assignment_days FROM role WHERE id = $1", roleId)
+	return maxDays, err
+}
+
+func (r *Repository) Revoke(tx *sqlx.Tx, employeeId, roleId int64) (bool, error) {
+	result, err := tx.Exec("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2", employeeId, roleId)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindExpiring - назначения, срок действия которых истекает в интервале [from, to)
+func (r *Repository) FindExpiring(ctx context.Context, from, to time.Time) (assignments []Entity, err error) {
+	err = r.db.SelectContext(ctx, &assignments,
+		`SELECT * FROM employee_role
+		 WHERE valid_until >= $1 AND valid_until < $2
+		 ORDER BY valid_until, employee_id`,
+		from, to)
+	return assignments, err
+}
+
+// FindStarted - действующие назначения, вступившие в силу в интервале (from, to]
+func (r *Repository) FindStarted(ctx context.Context, from, to time.Time) (assignments []Entity, err error) {
+	err = r.db.SelectContext(ctx, &assignments,
+		`SELECT * FROM employee_role
+		 WHERE valid_from > $1 AND valid_from <= $2
+		   AND (valid_until IS NULL OR valid_until > $2)
+		 ORDER BY valid_from, employee_id`,
+		from, to)
+	return assignments, err
+}
+
+// DeleteExpired - удаляет назначения с истёкшим сроком действия и возвращает их
+func (r *Repository) DeleteExpired(tx *sqlx.Tx, now time.Time) (assignments []Entity, err error) {
+	err = tx.Select(&assignments,
+		"DELETE FROM employee_role WHERE valid_until <= $1 RETURNING *",
+		now)
+	return assignments, err
+}
@@ -0,0 +1,315 @@
+package assignment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"idm/inner/sod"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const expiryActor = "system:assignment-expiry"
+
+type Service struct {
+	repo      Repo
+	auditRepo AuditRepo
+	conflicts ConflictChecker
+	validator *validator.Validate
+	logger    common.LoggerInterface
+	// Provisioning - передача изменённых ролей сотрудников во внешние системы после фиксации
+	// транзакции; назначения в транзакции вызывающего (...InTx) передаёт вызывающий
+	Provisioning Provisioner
+	// Events - запись доменных событий в outbox в транзакции изменения, в том числе в транзакции
+	// вызывающего (...InTx); nil - события не пишутся
+	Events Outbox
+}
+
+// Outbox - хранилище доменных событий, публикуемых после фиксации транзакции
+type Outbox interface {
+	Add(tx *sqlx.Tx, event outbox.Entity) error
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	Assign(tx *sqlx.Tx, assignment Entity) (Entity, error)
+	Revoke(tx *sqlx.Tx, employeeId, roleId int64) (bool, error)
+	FindExpiring(ctx context.Context, from, to time.Time) ([]Entity, error)
+	FindStarted(ctx context.Context, from, to time.Time) ([]Entity, error)
+	DeleteExpired(tx *sqlx.Tx, now time.Time) ([]Entity, error)
+	FindMaxAssignmentDays(tx *sqlx.Tx, roleId int64) (sql.NullInt32, error)
+}
+
+type AuditRepo interface {
+	Add(tx *sqlx.Tx, entry audit.Entity) error
+}
+
+// ConflictChecker - поиск правил SoD, нарушаемых назначением роли на срок [validFrom, validUntil)
+type ConflictChecker interface {
+	FindConflicts(tx *sqlx.Tx, employeeId, roleId int64, validFrom time.Time, validUntil sql.NullTime) ([]sod.Violation, error)
+}
+
+func NewService(repo Repo, auditRepo AuditRepo, conflicts ConflictChecker, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		auditRepo: auditRepo,
+		conflicts: conflicts,
+		validator: validator.New(),
+		logger:    logger,
+	}
+}
+
+// Assign - назначает роль сотруднику на указанный срок и пишет запись аудита.
+// Назначение, нарушающее правило SoD, отклоняется с sod.ViolationError, если правило
+// в режиме BLOCK или в режиме WARN без указания причины override_reason
+func (svc *Service) Assign(ctx context.Context, actor string, request AssignRequest) (response Response, err error) {
+	entity, err := svc.toEntity(request)
+	if err != nil {
+		return Response{}, err
+	}
+	err = common.InTx(svc.repo, "assigning role", func(tx *sqlx.Tx) error {
+		response, err = svc.assign(ctx, tx, actor, entity, request.OverrideReason)
+		return err
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.provision(ctx, response.EmployeeId)
+	return response, nil
+}
+
+// AssignInTx - то же, что Assign, но в транзакции вызывающего: используется процессами,
+// которые должны назначить роль атомарно со своими изменениями
+func (svc *Service) AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request AssignRequest) (Response, error) {
+	entity, err := svc.toEntity(request)
+	if err != nil {
+		return Response{}, err
+	}
+	return svc.assign(ctx, tx, actor, entity, request.OverrideReason)
+}
+
+func (svc *Service) toEntity(request AssignRequest) (Entity, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return Entity{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var entity = request.ToEntity(time.Now())
+	if entity.ValidUntil.Valid && !entity.ValidUntil.Time.After(entity.ValidFrom) {
+		return Entity{}, common.RequestValidationError{Message: "valid_until must be after valid_from"}
+	}
+	return entity, nil
+}
+
+func (svc *Service) assign(ctx context.Context, tx *sqlx.Tx, actor string, entity Entity, overrideReason string) (Response, error) {
+	// срок проверяется по правилам SoD уже с учётом максимальной длительности роли
+	entity, err := svc.limitDuration(tx, entity)
+	if err != nil {
+		return Response{}, err
+	}
+	violations, err := svc.conflicts.FindConflicts(tx, entity.EmployeeId, entity.RoleId, entity.ValidFrom, entity.ValidUntil)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error checking sod rules for role %d and employee %d: %w", entity.RoleId, entity.EmployeeId, err)
+	}
+	if err = sod.Check(violations, overrideReason); err != nil {
+		return Response{}, err
+	}
+	created, err := svc.repo.Assign(tx, entity)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error assigning role %d to employee %d: %w", entity.RoleId, entity.EmployeeId, err)
+	}
+	var response = created.ToResponse()
+	if len(violations) > 0 {
+		svc.logger.DebugCtx(ctx, "Assign: sod rules overridden",
+			zap.String("actor", actor), zap.String("reason", overrideReason))
+		err = svc.audit(tx, actor, audit.ActionRoleAssignedSodOverride, sodOverride{
+			Assignment:     response,
+			OverrideReason: overrideReason,
+			Violations:     violations,
+		})
+	} else {
+		err = svc.audit(tx, actor, audit.ActionRoleAssigned, response)
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	if err = svc.event(tx, outbox.RoleAssigned, response.EmployeeId, response); err != nil {
+		return Response{}, err
+	}
+	return response, nil
+}
+
+// limitDuration - применяет максимальный срок назначения роли: бессрочное назначение
+// ограничивается сроком роли, назначение дольше срока отклоняется
+func (svc *Service) limitDuration(tx *sqlx.Tx, entity Entity) (Entity, error) {
+	maxDays, err := svc.repo.FindMaxAssignmentDays(tx, entity.RoleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("Role %d not found", entity.RoleId)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("Error finding max assignment duration of role %d: %w", entity.RoleId, err)
+	}
+	if !maxDays.Valid {
+		return entity, nil
+	}
+	var limit = entity.ValidFrom.AddDate(0, 0, int(maxDays.Int32))
+	if !entity.ValidUntil.Valid {
+		entity.ValidUntil = sql.NullTime{Time: limit, Valid: true}
+		return entity, nil
+	}
+	if entity.ValidUntil.Time.After(limit) {
+		return Entity{}, common.RequestValidationError{
+			Message: fmt.Sprintf("Role %d can be assigned for at most %d days", entity.RoleId, maxDays.Int32),
+		}
+	}
+	return entity, nil
+}
+
+// Revoke - отзывает роль у сотрудника
+func (svc *Service) Revoke(ctx context.Context, actor string, request RevokeRequest) error {
+	if err := svc.validator.Struct(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	var err = common.InTx(svc.repo, "revoking role", func(tx *sqlx.Tx) error {
+		return svc.revoke(tx, actor, request)
+	})
+	if err != nil {
+		return err
+	}
+	svc.provision(ctx, request.EmployeeId)
+	return nil
+}
+
+// RevokeInTx - то же, что Revoke, но в транзакции вызывающего
+func (svc *Service) RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request RevokeRequest) error {
+	if err := svc.validator.Struct(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return svc.revoke(tx, actor, request)
+}
+
+func (svc *Service) revoke(tx *sqlx.Tx, actor string, request RevokeRequest) error {
+	revoked, err := svc.repo.Revoke(tx, request.EmployeeId, request.RoleId)
+	if err != nil {
+		return fmt.Errorf("Error revoking role %d from employee %d: %w", request.RoleId, request.EmployeeId, err)
+	}
+	if !revoked {
+		return common.NotFoundError{
+			Message: fmt.Sprintf("Role %d is not assigned to employee %d", request.RoleId, request.EmployeeId),
+		}
+	}
+	if err = svc.audit(tx, actor, audit.ActionRoleRevoked, request); err != nil {
+		return err
+	}
+	return svc.event(tx, outbox.RoleRevoked, request.EmployeeId, request)
+}
+
+// FindExpiring - назначения, срок действия которых истекает в ближайшие days дней
+func (svc *Service) FindExpiring(ctx context.Context, request ExpiringRequest) ([]Response, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	var now = time.Now()
+	entities, err := svc.repo.FindExpiring(ctx, now, now.AddDate(0, 0, request.Days))
+	if err != nil {
+		return nil, fmt.Errorf("Error finding assignments expiring in %d days: %w", request.Days, err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses, nil
+}
+
+// RevokeExpired - удаляет все истёкшие назначения, по каждому пишет запись аудита
+func (svc *Service) RevokeExpired(ctx context.Context) (revoked []Response, err error) {
+	err = common.InTx(svc.repo, "revoking expired assignments", func(tx *sqlx.Tx) error {
+		entities, err := svc.repo.DeleteExpired(tx, time.Now())
+		if err != nil {
+			return fmt.Errorf("Error deleting expired assignments: %w", err)
+		}
+		for _, e := range entities {
+			var response = e.ToResponse()
+			if err := svc.audit(tx, expiryActor, audit.ActionRoleExpired, response); err != nil {
+				return err
+			}
+			if err := svc.event(tx, outbox.RoleAssignmentExpired, response.EmployeeId, response); err != nil {
+				return err
+			}
+			revoked = append(revoked, response)
+		}
+		return nil
+	})
+	if err != nil {
+		svc.logger.ErrorCtx(ctx, "RevokeExpired: error", zap.Error(err))
+		return nil, err
+	}
+	for _, r := range revoked {
+		svc.provision(ctx, r.EmployeeId)
+	}
+	return revoked, nil
+}
+
+// ProvisionStarted - передаёт в синхронизацию сотрудников, назначения которых с отложенным
+// valid_from вступили в силу в интервале (from, to]: до этого синхронизация их роль не выдавала
+func (svc *Service) ProvisionStarted(ctx context.Context, from, to time.Time) ([]Response, error) {
+	if svc.Provisioning == nil {
+		return nil, nil
+	}
+	entities, err := svc.repo.FindStarted(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding started assignments: %w", err)
+	}
+	var started = make([]Response, 0, len(entities))
+	var employeeIds = make([]int64, 0, len(entities))
+	for _, e := range entities {
+		started = append(started, e.ToResponse())
+		if !slices.Contains(employeeIds, e.EmployeeId) {
+			employeeIds = append(employeeIds, e.EmployeeId)
+		}
+	}
+	svc.provision(ctx, employeeIds...)
+	return started, nil
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+func (svc *Service) audit(tx *sqlx.Tx, actor, action string, details any) error {
+	entry, err := audit.NewEntry(actor, action, details)
+	if err != nil {
+		return fmt.Errorf("Error building audit entry %s: %w", action, err)
+	}
+	if err = svc.auditRepo.Add(tx, entry); err != nil {
+		return fmt.Errorf("Error writing audit entry %s: %w", action, err)
+	}
+	return nil
+}
+
+func (svc *Service) event(tx *sqlx.Tx, eventType string, employeeId int64, payload any) error {
+	if svc.Events == nil {
+		return nil
+	}
+	event, err := outbox.NewEvent(outbox.AggregateEmployee, employeeId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("Error building event %s: %w", eventType, err)
+	}
+	if err = svc.Events.Add(tx, event); err != nil {
+		return fmt.Errorf("Error writing event %s: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package assignment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/testutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) Assign(tx *sqlx.Tx, assignment Entity) (Entity, error) {
+	args := m.Called(tx, assignment)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) Revoke(tx *sqlx.Tx, employeeId, roleId int64) (bool, error) {
+	args := m.Called(tx, employeeId, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindExpiring(ctx context.Context, from, to time.Time) ([]Entity, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindStarted(ctx context.Context, from, to time.Time) ([]Entity, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindMaxAssignmentDays(tx *sqlx.Tx, roleId int64) (sql.NullInt32, error) {
+	args := m.Called(tx, roleId)
+	return args.Get(0).(sql.NullInt32), args.Error(1)
+}
+
+func (m *MockRepo) DeleteExpired(tx *sqlx.Tx, now time.Time) ([]Entity, error) {
+	args := m.Called(tx, now)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+type MockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepo) Add(tx *sqlx.Tx, entry audit.Entity) error {
+	args := m.Called(tx, entry)
+	return args.Error(0)
+}
+
+type MockConflictChecker struct {
+	mock.Mock
+}
+
+func (m *MockConflictChecker) FindConflicts(tx *sqlx.Tx, employeeId, roleId int64, validFrom time.Time,
+	validUntil sql.NullTime) ([]sod.Violation, error) {
+	args := m.Called(tx, employeeId, roleId, validFrom, validUntil)
+	return args.Get(0).([]sod.Violation), args.Error(1)
+}
+
+// noConflicts - проверка SoD, не находящая нарушений
+func noConflicts() *MockConflictChecker {
+	checker := new(MockConflictChecker)
+	checker.On("FindConflicts", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]sod.Violation(nil), nil)
+	return checker
+}
+
+type MockProvisioner struct {
+	mock.Mock
+}
+
+func (m *MockProvisioner) Provision(ctx context.Context, employeeIds ...int64) {
+	m.Called(ctx, employeeIds)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func TestAssign(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should assign role with validity window and audit it", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		auditRepo := new(MockAuditRepo)
+		svc := NewService(repo, auditRepo, noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		from := time.Now().Add(time.Hour)
+		until := from.Add(24 * time.Hour)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{}, nil)
+		repo.On("Assign", tx, mock.MatchedBy(func(e Entity) bool {
+			return e.EmployeeId == 1 && e.RoleId == 2 && e.ValidFrom.Equal(from) && e.ValidUntil.Time.Equal(until)
+		})).Return(Entity{EmployeeId: 1, RoleId: 2, ValidFrom: from,
+			ValidUntil: sql.NullTime{Time: until, Valid: true}}, nil)
+		auditRepo.On("Add", tx, mock.MatchedBy(func(e audit.Entity) bool {
+			return e.Actor == "admin" && e.Action == audit.ActionRoleAssigned
+		})).Return(nil)
+
+		got, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &from, ValidUntil: &until})
+
+		a.NoError(err)
+		a.Equal(until, *got.ValidUntil)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject window where valid_until is before valid_from", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRepo), new(MockAuditRepo), noConflicts(), &MockLogger{})
+		from := time.Now()
+		until := from.Add(-time.Hour)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &from, ValidUntil: &until})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+
+	t.Run("Should rollback when audit fails", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		auditRepo := new(MockAuditRepo)
+		svc := NewService(repo, auditRepo, noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{}, nil)
+		repo.On("Assign", tx, mock.Anything).Return(Entity{EmployeeId: 1, RoleId: 2}, nil)
+		auditRepo.On("Add", tx, mock.Anything).Return(errors.New("audit down"))
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2})
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestAssignMaxDuration(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Should limit open-ended assignment by role max duration", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		auditRepo := new(MockAuditRepo)
+		svc := NewService(repo, auditRepo, noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		until := from.AddDate(0, 0, 30)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{Int32: 30, Valid: true}, nil)
+		repo.On("Assign", tx, mock.MatchedBy(func(e Entity) bool {
+			return e.ValidUntil.Valid && e.ValidUntil.Time.Equal(until)
+		})).Return(Entity{EmployeeId: 1, RoleId: 2, ValidFrom: from,
+			ValidUntil: sql.NullTime{Time: until, Valid: true}}, nil)
+		auditRepo.On("Add", tx, mock.Anything).Return(nil)
+
+		got, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &from})
+
+		a.NoError(err)
+		a.Equal(until, *got.ValidUntil)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject assignment longer than role max duration", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAuditRepo), noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		until := from.AddDate(0, 0, 31)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{Int32: 30, Valid: true}, nil)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &from, ValidUntil: &until})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAssignSod(t *testing.T) {
+	ctx := context.Background()
+	warn := sod.Violation{EmployeeId: 1, RuleId: 1, RuleName: "payments", Mode: sod.ModeWarn,
+		RoleNames: []string{"PAYMENT_APPROVE", "PAYMENT_CREATE"}}
+	block := sod.Violation{EmployeeId: 1, RuleId: 2, RuleName: "treasury", Mode: sod.ModeBlock,
+		RoleNames: []string{"PAYMENT_APPROVE", "TREASURY"}}
+
+	t.Run("Should block assignment violating BLOCK rule even with override reason", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		checker := new(MockConflictChecker)
+		svc := NewService(repo, new(MockAuditRepo), checker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{}, nil)
+		checker.On("FindConflicts", tx, int64(1), int64(2), mock.Anything, mock.Anything).Return([]sod.Violation{warn, block}, nil)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, OverrideReason: "urgent"})
+
+		var violationErr sod.ViolationError
+		a.True(errors.As(err, &violationErr))
+		a.False(violationErr.Overridable)
+		a.Len(violationErr.Violations, 2)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should require override reason for WARN rule", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		checker := new(MockConflictChecker)
+		svc := NewService(repo, new(MockAuditRepo), checker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{}, nil)
+		checker.On("FindConflicts", tx, int64(1), int64(2), mock.Anything, mock.Anything).Return([]sod.Violation{warn}, nil)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2})
+
+		var violationErr sod.ViolationError
+		a.True(errors.As(err, &violationErr))
+		a.True(violationErr.Overridable)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should assign over WARN rule and audit override reason", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		auditRepo := new(MockAuditRepo)
+		checker := new(MockConflictChecker)
+		svc := NewService(repo, auditRepo, checker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		checker.On("FindConflicts", tx, int64(1), int64(2), mock.Anything, mock.Anything).Return([]sod.Violation{warn}, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{}, nil)
+		repo.On("Assign", tx, mock.Anything).Return(Entity{EmployeeId: 1, RoleId: 2}, nil)
+		auditRepo.On("Add", tx, mock.MatchedBy(func(e audit.Entity) bool {
+			return e.Action == audit.ActionRoleAssignedSodOverride &&
+				strings.Contains(string(e.Details), `"override_reason":"month-end close"`) &&
+				strings.Contains(string(e.Details), `"rule_name":"payments"`)
+		})).Return(nil)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, OverrideReason: "month-end close"})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Should check sod against limited assignment period", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		checker := new(MockConflictChecker)
+		svc := NewService(repo, new(MockAuditRepo), checker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		var from = time.Now().AddDate(0, 1, 0)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindMaxAssignmentDays", tx, int64(2)).Return(sql.NullInt32{Int32: 30, Valid: true}, nil)
+		checker.On("FindConflicts", tx, int64(1), int64(2), from,
+			sql.NullTime{Time: from.AddDate(0, 0, 30), Valid: true}).Return([]sod.Violation{block}, nil)
+
+		_, err := svc.Assign(ctx, "admin", AssignRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &from})
+
+		a.True(errors.As(err, &sod.ViolationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		checker.AssertExpectations(t)
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return NotFoundError when role is not assigned", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAuditRepo), noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Revoke", tx, int64(1), int64(2)).Return(false, nil)
+
+		err := svc.Revoke(ctx, "admin", RevokeRequest{EmployeeId: 1, RoleId: 2})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestFindExpiring(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should find assignments expiring in the window", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAuditRepo), noConflicts(), &MockLogger{})
+		until := time.Now().Add(48 * time.Hour)
+		repo.On("FindExpiring", ctx, mock.Anything, mock.MatchedBy(func(to time.Time) bool {
+			return to.Sub(time.Now()) > 6*24*time.Hour
+		})).Return([]Entity{{EmployeeId: 1, RoleId: 2, ValidUntil: sql.NullTime{Time: until, Valid: true}}}, nil)
+
+		got, err := svc.FindExpiring(ctx, ExpiringRequest{Days: 7})
+
+		a.NoError(err)
+		a.Len(got, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject days out of range", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRepo), new(MockAuditRepo), noConflicts(), &MockLogger{})
+
+		_, err := svc.FindExpiring(ctx, ExpiringRequest{Days: 0})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestRevokeExpired(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should revoke expired assignments and audit each", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		auditRepo := new(MockAuditRepo)
+		svc := NewService(repo, auditRepo, noConflicts(), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		expired := []Entity{{EmployeeId: 1, RoleId: 2}, {EmployeeId: 3, RoleId: 2}}
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteExpired", tx, mock.Anything).Return(expired, nil)
+		auditRepo.On("Add", tx, mock.MatchedBy(func(e audit.Entity) bool {
+			return e.Actor == expiryActor && e.Action == audit.ActionRoleExpired
+		})).Return(nil).Twice()
+
+		got, err := svc.RevokeExpired(ctx)
+
+		a.NoError(err)
+		a.Len(got, 2)
+		a.NoError(mockTr.ExpectationsWereMet())
+		auditRepo.AssertExpectations(t)
+	})
+}
+
+func TestProvisionStarted(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC)
+	to := from.Add(time.Minute)
+
+	t.Run("Should provision each employee with started assignments once", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		provisioner := new(MockProvisioner)
+		svc := NewService(repo, new(MockAuditRepo), noConflicts(), &MockLogger{})
+		svc.Provisioning = provisioner
+		repo.On("FindStarted", ctx, from, to).Return([]Entity{
+			{EmployeeId: 1, RoleId: 2}, {EmployeeId: 3, RoleId: 2}, {EmployeeId: 1, RoleId: 4},
+		}, nil)
+		provisioner.On("Provision", ctx, []int64{1, 3}).Return()
+
+		got, err := svc.ProvisionStarted(ctx, from, to)
+
+		a.NoError(err)
+		a.Len(got, 3)
+		provisioner.AssertExpectations(t)
+	})
+
+	t.Run("Should skip lookup without provisioning", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAuditRepo), noConflicts(), &MockLogger{})
+
+		got, err := svc.ProvisionStarted(ctx, from, to)
+
+		a.NoError(err)
+		a.Empty(got)
+		repo.AssertNotCalled(t, "FindStarted", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,90 @@
+package assignment
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Expirer interface {
+	RevokeExpired(ctx context.Context) ([]Response, error)
+	ProvisionStarted(ctx context.Context, from, to time.Time) ([]Response, error)
+}
+
+// ExpiryWorker - фоновый процесс, периодически отзывающий назначения с истёкшим сроком
+// и передающий в синхронизацию назначения, срок действия которых начался. Первая итерация
+// после старта проверяет начало действия и за предыдущий interval; назначения, начавшие
+// действовать за более долгий простой, находит сверка с внешней системой
+type ExpiryWorker struct {
+	expirer  Expirer
+	interval time.Duration
+	logger   *common.Logger
+	runner   common.Runner
+}
+
+func NewExpiryWorker(expirer Expirer, interval time.Duration, logger *common.Logger) *ExpiryWorker {
+	return &ExpiryWorker{
+		expirer:  expirer,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *ExpiryWorker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей итерации
+func (w *ExpiryWorker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *ExpiryWorker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var since = time.Now().Add(-w.interval)
+	for {
+		w.revoke(ctx)
+		since = w.start(ctx, since)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ExpiryWorker) revoke(ctx context.Context) {
+	revoked, err := w.expirer.RevokeExpired(ctx)
+	if err != nil {
+		w.logger.Error("ExpiryWorker: error revoking expired assignments", zap.Error(err))
+		return
+	}
+	for _, r := range revoked {
+		w.logger.Info("ExpiryWorker: role assignment expired and revoked",
+			zap.Int64("employee_id", r.EmployeeId),
+			zap.Int64("role_id", r.RoleId),
+			zap.Timep("valid_until", r.ValidUntil))
+	}
+}
+
+// start - передаёт в синхронизацию назначения, начавшие действовать после since, и возвращает
+// момент, с которого проверять в следующий раз; после ошибки интервал проверяется заново
+func (w *ExpiryWorker) start(ctx context.Context, since time.Time) time.Time {
+	var now = time.Now()
+	started, err := w.expirer.ProvisionStarted(ctx, since, now)
+	if err != nil {
+		w.logger.Error("ExpiryWorker: error provisioning started assignments", zap.Error(err))
+		return since
+	}
+	for _, r := range started {
+		w.logger.Info("ExpiryWorker: role assignment started and provisioned",
+			zap.Int64("employee_id", r.EmployeeId),
+			zap.Int64("role_id", r.RoleId),
+			zap.Time("valid_from", r.ValidFrom))
+	}
+	return now
+}
@@ -0,0 +1,97 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type StubExpirer struct {
+	calls atomic.Int32
+	mu    sync.Mutex
+	froms []time.Time
+	tos   []time.Time
+	fail  error
+}
+
+func (s *StubExpirer) RevokeExpired(ctx context.Context) ([]Response, error) {
+	s.calls.Add(1)
+	return []Response{{EmployeeId: 1, RoleId: 2}}, nil
+}
+
+func (s *StubExpirer) ProvisionStarted(ctx context.Context, from, to time.Time) ([]Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.froms = append(s.froms, from)
+	s.tos = append(s.tos, to)
+	return []Response{{EmployeeId: 1, RoleId: 3}}, s.fail
+}
+
+// intervals - интервалы проверки начала действия назначений
+func (s *StubExpirer) intervals() ([]time.Time, []time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.froms), slices.Clone(s.tos)
+}
+
+func TestExpiryWorker(t *testing.T) {
+	t.Run("Should revoke periodically and stop cleanly", func(t *testing.T) {
+		a := assert.New(t)
+		expirer := &StubExpirer{}
+		worker := NewExpiryWorker(expirer, 10*time.Millisecond, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { return expirer.calls.Load() >= 2 }, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		a.NoError(worker.Stop(ctx))
+		var calls = expirer.calls.Load()
+		time.Sleep(30 * time.Millisecond)
+		a.Equal(calls, expirer.calls.Load())
+	})
+	t.Run("Should check started assignments in adjacent intervals", func(t *testing.T) {
+		a := assert.New(t)
+		expirer := &StubExpirer{}
+		var interval = 10 * time.Millisecond
+		worker := NewExpiryWorker(expirer, interval, &common.Logger{Logger: zap.NewNop()})
+		var started = time.Now()
+
+		worker.Start()
+		a.Eventually(func() bool { froms, _ := expirer.intervals(); return len(froms) >= 3 },
+			time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.NoError(worker.Stop(ctx))
+
+		froms, tos := expirer.intervals()
+		a.True(froms[0].Before(started), "first pass must cover the interval before start")
+		for i := 1; i < len(froms); i++ {
+			a.Equal(tos[i-1], froms[i])
+		}
+	})
+
+	t.Run("Should check the same interval again after error", func(t *testing.T) {
+		a := assert.New(t)
+		expirer := &StubExpirer{fail: errors.New("connection refused")}
+		worker := NewExpiryWorker(expirer, 10*time.Millisecond, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { froms, _ := expirer.intervals(); return len(froms) >= 2 },
+			time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.NoError(worker.Stop(ctx))
+
+		froms, _ := expirer.intervals()
+		a.Equal(froms[0], froms[1])
+	})
+}
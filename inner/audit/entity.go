@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+const (
+	ActionRoleAssigned = "ROLE_ASSIGNED"
+	ActionRoleRevoked  = "ROLE_REVOKED"
+	ActionRoleExpired  = "ROLE_ASSIGNMENT_EXPIRED"
+	// ActionRoleAssignedSodOverride - роль назначена вопреки правилу SoD в режиме WARN
+	ActionRoleAssignedSodOverride = "ROLE_ASSIGNED_SOD_OVERRIDE"
+)
+
+type Entity struct {
+	Id        int64          `db:"id"`
+	Actor     string         `db:"actor"`
+	Action    string         `db:"action"`
+	Details   types.JSONText `db:"details"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// NewEntry - запись аудита с деталями, сериализованными в JSON
+func NewEntry(actor, action string, details any) (Entity, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return Entity{}, err
+	}
+	return Entity{
+		Actor:   actor,
+		Action:  action,
+		Details: data,
+	}, nil
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// Add - сохраняет запись аудита в рамках переданной транзакции
+func (r *Repository) Add(tx *sqlx.Tx, entry Entity) error {
+	_, err := tx.Exec(
+		"INSERT INTO audit_log(actor, action, details) VALUES ($1, $2, $3)",
+		entry.Actor, entry.Action, entry.Details)
+	return err
+}
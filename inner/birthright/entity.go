@@ -0,0 +1,77 @@
+package birthright
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Изменения назначений, которые следуют из правил
+const (
+	ChangeAdd    = "ADD"
+	ChangeRemove = "REMOVE"
+)
+
+// Entity - правило базового доступа: сотрудники отдела DepartmentId на должности Position
+// получают роль RoleId. Пустой отдел или должность означают любой отдел или должность
+type Entity struct {
+	Id           int64          `db:"id"`
+	Name         string         `db:"name"`
+	DepartmentId sql.NullInt64  `db:"department_id"`
+	Position     sql.NullString `db:"position"`
+	RoleId       int64          `db:"role_id"`
+	CreatedAt    time.Time      `db:"created_at"`
+	UpdatedAt    time.Time      `db:"updated_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	return Response{
+		Id:           e.Id,
+		Name:         e.Name,
+		DepartmentId: e.DepartmentId.Int64,
+		Position:     e.Position.String,
+		RoleId:       e.RoleId,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id           int64     `json:"id"`
+	Name         string    `json:"name"`
+	DepartmentId int64     `json:"department_id,omitempty"`
+	Position     string    `json:"position,omitempty"`
+	RoleId       int64     `json:"role_id"`
+	CreatedAt    time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// CreateRequest - правило должно ограничивать хотя бы отдел или должность
+type CreateRequest struct {
+	Name         string `json:"name" validate:"required,min=2,max=155"`
+	DepartmentId int64  `json:"department_id" validate:"required_without=Position,gte=0"`
+	Position     string `json:"position" validate:"required_without=DepartmentId,max=155"`
+	RoleId       int64  `json:"role_id" validate:"required,min=1"`
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Name:         req.Name,
+		DepartmentId: sql.NullInt64{Int64: req.DepartmentId, Valid: req.DepartmentId > 0},
+		Position:     sql.NullString{String: req.Position, Valid: req.Position != ""},
+		RoleId:       req.RoleId,
+	}
+}
+
+// Change - роль, которую правила выдают сотруднику (ADD), или выданная правилом роль,
+// которой больше не соответствует ни одно правило (REMOVE). Ручные назначения не удаляются
+type Change struct {
+	EmployeeId int64  `db:"employee_id" json:"employee_id"`
+	RoleId     int64  `db:"role_id" json:"role_id"`
+	RoleName   string `db:"role_name" json:"role_name"`
+	Change     string `db:"change" json:"change" example:"ADD"`
+}
+
+// DiffRequest - пересчёт для одного сотрудника; без employee_id - для всех сотрудников
+type DiffRequest struct {
+	EmployeeId int64 `query:"employee_id" validate:"omitempty,min=1"`
+}
@@ -0,0 +1,213 @@
+package birthright
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Add(ctx context.Context, request CreateRequest) (int64, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	Diff(ctx context.Context, request DiffRequest) ([]Change, error)
+	Apply(ctx context.Context, actor string, request DiffRequest) ([]Change, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/birthright"
+func (c *Handler) RegisterRoutes() {
+	var read = c.server.RequirePermission(web.PermBirthrightRead)
+	var write = c.server.RequirePermission(web.PermBirthrightWrite)
+	c.server.GroupApiV1.Get("/birthright/rules", read, c.FindAll)
+	c.server.GroupApiV1.Get("/birthright/rules/:id", read, c.FindById)
+	c.server.GroupApiV1.Post("/birthright/rules", write, c.Add)
+	c.server.GroupApiV1.Delete("/birthright/rules/:id", write, c.DeleteById)
+	c.server.GroupApiV1.Get("/birthright/diff", read, c.Diff)
+	c.server.GroupApiV1.Post("/birthright/apply", write, c.Apply)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/birthright/rules"
+// @Description Create birthright rule: employees of the department and/or position get the role automatically.
+// @Summary create birthright rule
+// @Tags birthright
+// @Accept json
+// @Produce json
+// @Param request body CreateRequest true "birthright rule"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[int64] "invalid request or already exists"
+// @Failure 403 {object} common.Response[int64] "Permission denied"
+// @Failure 500 {object} common.Response[int64] "error db"
+// @Router /birthright/rules [post]
+// @Security BearerAuth
+func (c *Handler) Add(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Add: received request", zap.Any("request", request))
+	id, err := c.service.Add(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error adding birthright rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/birthright/rules/:id"
+// @Description Find birthright rule by id.
+// @Summary find birthright rule
+// @Tags birthright
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} common.Response[birthright.Response]
+// @Failure 400 {object} common.Response[birthright.Response] "invalid request"
+// @Failure 404 {object} common.Response[birthright.Response] "not found"
+// @Failure 500 {object} common.Response[birthright.Response] "error db"
+// @Router /birthright/rules/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding birthright rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/birthright/rules"
+// @Description Get all birthright rules.
+// @Summary get birthright rules
+// @Tags birthright
+// @Produce json
+// @Success 200 {object} common.Response[[]birthright.Response]
+// @Failure 403 {object} common.Response[[]birthright.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]birthright.Response] "error db"
+// @Router /birthright/rules [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding birthright rules", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/birthright/rules/:id"
+// @Description Delete birthright rule by id. Roles granted by the rule are revoked by the next apply.
+// @Summary delete birthright rule
+// @Tags birthright
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /birthright/rules/{id} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteById(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error deleting birthright rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/birthright/diff"
+// @Description Re-evaluate birthright rules and show roles that would be added or removed. Manual assignments are never removed.
+// @Summary preview birthright re-evaluation
+// @Tags birthright
+// @Produce json
+// @Param employee_id query int false "Employee ID, all employees if omitted"
+// @Success 200 {object} common.Response[[]birthright.Change]
+// @Failure 400 {object} common.Response[[]birthright.Change] "invalid request"
+// @Failure 403 {object} common.Response[[]birthright.Change] "Permission denied"
+// @Failure 500 {object} common.Response[[]birthright.Change] "error db"
+// @Router /birthright/diff [get]
+// @Security BearerAuth
+func (c *Handler) Diff(ctx *fiber.Ctx) error {
+	var request DiffRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Diff: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Diff(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Diff: error evaluating birthright rules", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/birthright/apply"
+// @Description Re-evaluate birthright rules and apply the difference in one transaction.
+// @Summary apply birthright rules
+// @Tags birthright
+// @Produce json
+// @Param employee_id query int false "Employee ID, all employees if omitted"
+// @Success 200 {object} common.Response[[]birthright.Change]
+// @Failure 400 {object} common.Response[[]birthright.Change] "invalid request"
+// @Failure 403 {object} common.Response[[]birthright.Change] "Permission denied"
+// @Failure 409 {object} common.Response[[]birthright.Change] "sod rule violated"
+// @Failure 500 {object} common.Response[[]birthright.Change] "error db"
+// @Router /birthright/apply [post]
+// @Security BearerAuth
+func (c *Handler) Apply(ctx *fiber.Ctx) error {
+	claims, _ := web.ClaimsFromCtx(ctx)
+	var request DiffRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Apply: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Apply(ctx.Context(), claims.Actor(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Apply: error applying birthright rules", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	case errors.As(err, &sod.ViolationError{}):
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,96 @@
+package birthright
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// selectDiff - расхождение назначений с правилами. desired - роли, которые сотрудникам выдают
+// правила, held - текущие назначения; удаляются только назначения с источником RULE
+const selectDiff = `WITH desired AS (
+		SELECT DISTINCT e.id AS employee_id, br.role_id
+		FROM employee e
+		JOIN birthright_rule br
+		  ON (br.department_id IS NULL OR br.department_id = e.department_id)
+		 AND (br.position IS NULL OR br.position = e.position)
+		WHERE $1::BIGINT IS NULL OR e.id = $1
+	), held AS (
+		SELECT employee_id, role_id, source FROM employee_role
+		WHERE $1::BIGINT IS NULL OR employee_id = $1
+	)
+	SELECT c.employee_id, c.role_id, r.name AS role_name, c.change
+	FROM (
+		SELECT d.employee_id, d.role_id, 'ADD' AS change
+		FROM desired d
+		LEFT JOIN held h ON h.employee_id = d.employee_id AND h.role_id = d.role_id
+		WHERE h.role_id IS NULL
+		UNION ALL
+		SELECT h.employee_id, h.role_id, 'REMOVE'
+		FROM held h
+		LEFT JOIN desired d ON d.employee_id = h.employee_id AND d.role_id = h.role_id
+		WHERE h.source = 'RULE' AND d.role_id IS NULL
+	) c
+	JOIN role r ON r.id = c.role_id
+	ORDER BY c.employee_id, c.change, r.name`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Add(tx *sqlx.Tx, rule Entity) (id int64, err error) {
+	err = tx.Get(&id,
+		"INSERT INTO birthright_rule(name, department_id, position, role_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		rule.Name, rule.DepartmentId, rule.Position, rule.RoleId)
+	return id, err
+}
+
+func (r *Repository) ExistsByName(tx *sqlx.Tx, name string) (isExists bool, err error) {
+	err = tx.Get(&isExists, "SELECT EXISTS(SELECT FROM birthright_rule WHERE name = $1)", name)
+	return isExists, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (rule Entity, err error) {
+	err = r.db.GetContext(ctx, &rule, "SELECT * FROM birthright_rule WHERE id = $1", id)
+	return rule, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) (rules []Entity, err error) {
+	err = r.db.SelectContext(ctx, &rules, "SELECT * FROM birthright_rule ORDER BY name")
+	return rules, err
+}
+
+// DeleteById - удаляет правило; выданные им роли отзываются при следующем пересчёте
+func (r *Repository) DeleteById(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM birthright_rule WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindDiff - расхождение назначений с правилами для предпросмотра
+func (r *Repository) FindDiff(ctx context.Context, employeeId sql.NullInt64) (changes []Change, err error) {
+	err = r.db.SelectContext(ctx, &changes, selectDiff, employeeId)
+	return changes, err
+}
+
+// FindDiffTx - расхождение назначений с правилами в транзакции, в которой оно будет применено
+func (r *Repository) FindDiffTx(tx *sqlx.Tx, employeeId sql.NullInt64) (changes []Change, err error) {
+	err = tx.Select(&changes, selectDiff, employeeId)
+	return changes, err
+}
@@ -0,0 +1,221 @@
+package birthright
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ruleActor - автор назначений, выданных правилами при создании и переводе сотрудника
+const ruleActor = "system:birthright"
+
+type Service struct {
+	repo      Repo
+	assigner  Assigner
+	validator *validator.Validate
+	logger    common.LoggerInterface
+	// Provisioning - передача изменённых пересчётом ролей сотрудников во внешние системы после
+	// фиксации транзакции; пересчёт в транзакции вызывающего (ApplyInTx) передаёт вызывающий
+	Provisioning Provisioner
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	Add(tx *sqlx.Tx, rule Entity) (int64, error)
+	ExistsByName(tx *sqlx.Tx, name string) (bool, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	DeleteById(ctx context.Context, id int64) (bool, error)
+	FindDiff(ctx context.Context, employeeId sql.NullInt64) ([]Change, error)
+	FindDiffTx(tx *sqlx.Tx, employeeId sql.NullInt64) ([]Change, error)
+}
+
+// Assigner - назначение и отзыв ролей в транзакции пересчёта, с проверкой SoD и аудитом
+type Assigner interface {
+	AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.AssignRequest) (assignment.Response, error)
+	RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error
+}
+
+func NewService(repo Repo, assigner Assigner, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		assigner:  assigner,
+		validator: validator.New(),
+		logger:    logger,
+	}
+}
+
+// Add - создаёт правило. Существующим сотрудникам роль выдаётся при пересчёте
+func (svc *Service) Add(ctx context.Context, request CreateRequest) (id int64, err error) {
+	if err = svc.validator.Struct(request); err != nil {
+		return 0, common.RequestValidationError{Message: err.Error()}
+	}
+	err = common.InTx(svc.repo, "adding birthright rule", func(tx *sqlx.Tx) error {
+		isExists, err := svc.repo.ExistsByName(tx, request.Name)
+		if err != nil {
+			return fmt.Errorf("Error checking birthright rule %s existence: %w", request.Name, err)
+		}
+		if isExists {
+			return common.AlreadyExistsError{Message: fmt.Sprintf("Birthright rule %s already exists", request.Name)}
+		}
+		id, err = svc.repo.Add(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("Error adding birthright rule %s: %w", request.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id birthright rule: %d", id)}
+	}
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Birthright rule with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding birthright rule with id %d: %w", id, err)
+	}
+	return entity.ToResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding birthright rules: %w", err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses, nil
+}
+
+// DeleteById - удаляет правило. Выданные им роли остаются до пересчёта, ручные назначения
+// тех же ролей пересчёт не затрагивает
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id birthright rule: %d", id)}
+	}
+	deleted, err := svc.repo.DeleteById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting birthright rule with id %d: %w", id, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("Birthright rule with id %d not found", id)}
+	}
+	return nil
+}
+
+// Diff - роли, которые пересчёт правил выдаст и отзовёт, без изменения назначений
+func (svc *Service) Diff(ctx context.Context, request DiffRequest) ([]Change, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	changes, err := svc.repo.FindDiff(ctx, toNullId(request.EmployeeId))
+	if err != nil {
+		return nil, fmt.Errorf("Error evaluating birthright rules: %w", err)
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	return changes, nil
+}
+
+// Apply - пересчитывает правила и применяет расхождение в одной транзакции.
+// Возвращает применённые изменения
+func (svc *Service) Apply(ctx context.Context, actor string, request DiffRequest) (changes []Change, err error) {
+	if err = svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	err = common.InTx(svc.repo, "applying birthright rules", func(tx *sqlx.Tx) error {
+		changes, err = svc.apply(ctx, tx, actor, toNullId(request.EmployeeId))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	svc.provision(ctx, changes)
+	return changes, nil
+}
+
+// ApplyInTx - пересчёт правил для сотрудника в транзакции вызывающего: при создании
+// сотрудника и при переводе в другой отдел или на другую должность. Сотрудника передаёт
+// в синхронизацию вызывающий после фиксации своей транзакции
+func (svc *Service) ApplyInTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	_, err := svc.apply(ctx, tx, ruleActor, toNullId(employeeId))
+	return err
+}
+
+// apply - выдаёт и отзывает роли по расхождению. Правило считается согласованным решением,
+// поэтому нарушение правила SoD в режиме WARN переопределяется, а в режиме BLOCK отменяет пересчёт
+func (svc *Service) apply(ctx context.Context, tx *sqlx.Tx, actor string, employeeId sql.NullInt64) ([]Change, error) {
+	changes, err := svc.repo.FindDiffTx(tx, employeeId)
+	if err != nil {
+		return nil, fmt.Errorf("Error evaluating birthright rules: %w", err)
+	}
+	for _, change := range changes {
+		switch change.Change {
+		case ChangeAdd:
+			_, err = svc.assigner.AssignInTx(ctx, tx, actor, assignment.AssignRequest{
+				EmployeeId:     change.EmployeeId,
+				RoleId:         change.RoleId,
+				OverrideReason: fmt.Sprintf("Birthright rule grants role %s", change.RoleName),
+				Source:         assignment.SourceRule,
+			})
+		case ChangeRemove:
+			err = svc.assigner.RevokeInTx(ctx, tx, actor, assignment.RevokeRequest{
+				EmployeeId: change.EmployeeId,
+				RoleId:     change.RoleId,
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error applying birthright change %s of role %s to employee %d: %w",
+				change.Change, change.RoleName, change.EmployeeId, err)
+		}
+	}
+	if len(changes) > 0 {
+		svc.logger.DebugCtx(ctx, "Apply: birthright rules applied",
+			zap.String("actor", actor), zap.Int("changes", len(changes)))
+	}
+	return changes, nil
+}
+
+// provision - передаёт в синхронизацию сотрудников, роли которых изменил пересчёт
+func (svc *Service) provision(ctx context.Context, changes []Change) {
+	if svc.Provisioning == nil || len(changes) == 0 {
+		return
+	}
+	var employeeIds = make([]int64, 0, len(changes))
+	for _, change := range changes {
+		if !slices.Contains(employeeIds, change.EmployeeId) {
+			employeeIds = append(employeeIds, change.EmployeeId)
+		}
+	}
+	svc.Provisioning.Provision(ctx, employeeIds...)
+}
+
+func toNullId(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id > 0}
+}
@@ -0,0 +1,260 @@
+package birthright
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/testutil"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) Add(tx *sqlx.Tx, rule Entity) (int64, error) {
+	args := m.Called(tx, rule)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) ExistsByName(tx *sqlx.Tx, name string) (bool, error) {
+	args := m.Called(tx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindDiff(ctx context.Context, employeeId sql.NullInt64) ([]Change, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]Change), args.Error(1)
+}
+
+func (m *MockRepo) FindDiffTx(tx *sqlx.Tx, employeeId sql.NullInt64) ([]Change, error) {
+	args := m.Called(tx, employeeId)
+	return args.Get(0).([]Change), args.Error(1)
+}
+
+type MockAssigner struct {
+	mock.Mock
+}
+
+func (m *MockAssigner) AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.AssignRequest) (assignment.Response, error) {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Get(0).(assignment.Response), args.Error(1)
+}
+
+func (m *MockAssigner) RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Error(0)
+}
+
+type MockProvisioner struct {
+	mock.Mock
+}
+
+func (m *MockProvisioner) Provision(ctx context.Context, employeeIds ...int64) {
+	m.Called(ctx, employeeIds)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add rule", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAssigner), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		request := CreateRequest{Name: "accounting baseline", DepartmentId: 3, Position: "accountant", RoleId: 10}
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("ExistsByName", tx, request.Name).Return(false, nil)
+		repo.On("Add", tx, request.ToEntity()).Return(int64(1), nil)
+
+		id, err := svc.Add(ctx, request)
+
+		a.NoError(err)
+		a.Equal(int64(1), id)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject duplicated name", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAssigner), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("ExistsByName", tx, "baseline").Return(true, nil)
+
+		_, err := svc.Add(ctx, CreateRequest{Name: "baseline", Position: "accountant", RoleId: 10})
+
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should require department or position", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAssigner), &MockLogger{})
+
+		_, err := svc.Add(ctx, CreateRequest{Name: "everyone", RoleId: 10})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		repo.AssertNotCalled(t, "BeginTr")
+	})
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return empty diff for all employees", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAssigner), &MockLogger{})
+		repo.On("FindDiff", ctx, sql.NullInt64{}).Return([]Change(nil), nil)
+
+		got, err := svc.Diff(ctx, DiffRequest{})
+
+		a.NoError(err)
+		a.Equal([]Change{}, got)
+	})
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	employee := sql.NullInt64{Int64: 7, Valid: true}
+	changes := []Change{
+		{EmployeeId: 7, RoleId: 10, RoleName: "LEDGER", Change: ChangeAdd},
+		{EmployeeId: 7, RoleId: 11, RoleName: "PAYROLL", Change: ChangeRemove},
+	}
+
+	t.Run("Should grant and revoke rule roles on employee move", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		assigner := new(MockAssigner)
+		svc := NewService(repo, assigner, &MockLogger{})
+		tx, _ := testutil.NewTx(t, true)
+		repo.On("FindDiffTx", tx, employee).Return(changes, nil)
+		assigner.On("AssignInTx", ctx, tx, ruleActor, mock.MatchedBy(func(r assignment.AssignRequest) bool {
+			return r.EmployeeId == 7 && r.RoleId == 10 && r.Source == assignment.SourceRule && r.OverrideReason != ""
+		})).Return(assignment.Response{}, nil)
+		assigner.On("RevokeInTx", ctx, tx, ruleActor, assignment.RevokeRequest{EmployeeId: 7, RoleId: 11}).Return(nil)
+
+		err := svc.ApplyInTx(ctx, tx, 7)
+
+		a.NoError(err)
+		assigner.AssertExpectations(t)
+	})
+
+	t.Run("Should rollback apply on sod block", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		assigner := new(MockAssigner)
+		svc := NewService(repo, assigner, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindDiffTx", tx, employee).Return(changes, nil)
+		assigner.On("AssignInTx", ctx, tx, "admin", mock.Anything).
+			Return(assignment.Response{}, sod.ViolationError{Violations: []sod.Violation{{RuleName: "payments"}}})
+
+		_, err := svc.Apply(ctx, "admin", DiffRequest{EmployeeId: 7})
+
+		a.True(errors.As(err, &sod.ViolationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		assigner.AssertNotCalled(t, "RevokeInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should provision employees once after commit", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		assigner := new(MockAssigner)
+		provisioner := new(MockProvisioner)
+		svc := NewService(repo, assigner, &MockLogger{})
+		svc.Provisioning = provisioner
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindDiffTx", tx, sql.NullInt64{}).Return(append(changes,
+			Change{EmployeeId: 8, RoleId: 10, RoleName: "LEDGER", Change: ChangeAdd}), nil)
+		assigner.On("AssignInTx", ctx, tx, "admin", mock.Anything).Return(assignment.Response{}, nil)
+		assigner.On("RevokeInTx", ctx, tx, "admin", mock.Anything).Return(nil)
+		provisioner.On("Provision", ctx, []int64{7, 8}).Return()
+
+		_, err := svc.Apply(ctx, "admin", DiffRequest{})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		provisioner.AssertExpectations(t)
+	})
+
+	t.Run("Should not provision when apply is rolled back", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		assigner := new(MockAssigner)
+		provisioner := new(MockProvisioner)
+		svc := NewService(repo, assigner, &MockLogger{})
+		svc.Provisioning = provisioner
+		tx, _ := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindDiffTx", tx, employee).Return(changes, nil)
+		assigner.On("AssignInTx", ctx, tx, "admin", mock.Anything).Return(assignment.Response{}, errors.New("db down"))
+
+		_, err := svc.Apply(ctx, "admin", DiffRequest{EmployeeId: 7})
+
+		a.Error(err)
+		provisioner.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return applied changes", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockAssigner), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindDiffTx", tx, sql.NullInt64{}).Return([]Change(nil), nil)
+
+		got, err := svc.Apply(ctx, "admin", DiffRequest{})
+
+		a.NoError(err)
+		a.Equal([]Change{}, got)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,175 @@
+package certification
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Области кампании: сотрудники поддерева подразделения или все владельцы роли
+const (
+	ScopeDepartment = "DEPARTMENT"
+	ScopeRole       = "ROLE"
+)
+
+const (
+	StatusActive    = "ACTIVE"
+	StatusCompleted = "COMPLETED"
+)
+
+// Решения по элементу кампании
+const (
+	DecisionPending   = "PENDING"
+	DecisionCertified = "CERTIFIED"
+	DecisionRevoked   = "REVOKED"
+)
+
+type Entity struct {
+	Id          int64        `db:"id"`
+	Name        string       `db:"name"`
+	ScopeType   string       `db:"scope_type"`
+	ScopeId     int64        `db:"scope_id"`
+	Deadline    time.Time    `db:"deadline"`
+	Status      string       `db:"status"`
+	CreatedBy   string       `db:"created_by"`
+	CreatedAt   time.Time    `db:"created_at"`
+	CompletedAt sql.NullTime `db:"completed_at"`
+}
+
+func (e *Entity) ToResponse(summary Summary) Response {
+	var response = Response{
+		Id:        e.Id,
+		Name:      e.Name,
+		ScopeType: e.ScopeType,
+		ScopeId:   e.ScopeId,
+		Deadline:  e.Deadline,
+		Status:    e.Status,
+		CreatedBy: e.CreatedBy,
+		CreatedAt: e.CreatedAt,
+		Summary:   summary,
+	}
+	if e.CompletedAt.Valid {
+		var completedAt = e.CompletedAt.Time
+		response.CompletedAt = &completedAt
+	}
+	return response
+}
+
+type Response struct {
+	Id          int64      `json:"id"`
+	Name        string     `json:"name"`
+	ScopeType   string     `json:"scope_type" example:"DEPARTMENT"`
+	ScopeId     int64      `json:"scope_id"`
+	Deadline    time.Time  `json:"deadline" example:"2025-09-30T00:00:00Z"`
+	Status      string     `json:"status" example:"ACTIVE"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" example:"2025-09-30T00:00:00Z"`
+	Summary     Summary    `json:"summary"`
+}
+
+// Summary - ход кампании: сколько элементов подтверждено, отозвано и ещё ждёт решения
+type Summary struct {
+	Total       int `db:"total" json:"total"`
+	Pending     int `db:"pending" json:"pending"`
+	Certified   int `db:"certified" json:"certified"`
+	Revoked     int `db:"revoked" json:"revoked"`
+	AutoRevoked int `db:"auto_revoked" json:"auto_revoked"`
+}
+
+// ItemEntity - назначение роли, зафиксированное при запуске кампании, и решение по нему
+type ItemEntity struct {
+	Id           int64         `db:"id"`
+	CampaignId   int64         `db:"campaign_id"`
+	EmployeeId   int64         `db:"employee_id"`
+	EmployeeName string        `db:"employee_name"`
+	RoleId       int64         `db:"role_id"`
+	RoleName     string        `db:"role_name"`
+	ValidFrom    time.Time     `db:"valid_from"`
+	ValidUntil   sql.NullTime  `db:"valid_until"`
+	ReviewerId   sql.NullInt64 `db:"reviewer_id"`
+	Decision     string        `db:"decision"`
+	AutoRevoked  bool          `db:"auto_revoked"`
+	DecidedBy    string        `db:"decided_by"`
+	Comment      string        `db:"comment"`
+	DecidedAt    sql.NullTime  `db:"decided_at"`
+}
+
+func (e *ItemEntity) ToResponse() ItemResponse {
+	var response = ItemResponse{
+		Id:           e.Id,
+		CampaignId:   e.CampaignId,
+		EmployeeId:   e.EmployeeId,
+		EmployeeName: e.EmployeeName,
+		RoleId:       e.RoleId,
+		RoleName:     e.RoleName,
+		ValidFrom:    e.ValidFrom,
+		ReviewerId:   e.ReviewerId.Int64,
+		Decision:     e.Decision,
+		AutoRevoked:  e.AutoRevoked,
+		DecidedBy:    e.DecidedBy,
+		Comment:      e.Comment,
+	}
+	if e.ValidUntil.Valid {
+		var validUntil = e.ValidUntil.Time
+		response.ValidUntil = &validUntil
+	}
+	if e.DecidedAt.Valid {
+		var decidedAt = e.DecidedAt.Time
+		response.DecidedAt = &decidedAt
+	}
+	return response
+}
+
+type ItemResponse struct {
+	Id           int64      `json:"id"`
+	CampaignId   int64      `json:"campaign_id"`
+	EmployeeId   int64      `json:"employee_id"`
+	EmployeeName string     `json:"employee_name"`
+	RoleId       int64      `json:"role_id"`
+	RoleName     string     `json:"role_name"`
+	ValidFrom    time.Time  `json:"valid_from" example:"2025-07-29T12:00:00Z"`
+	ValidUntil   *time.Time `json:"valid_until,omitempty" example:"2025-10-29T12:00:00Z"`
+	ReviewerId   int64      `json:"reviewer_id,omitempty"`
+	Decision     string     `json:"decision" example:"PENDING"`
+	AutoRevoked  bool       `json:"auto_revoked"`
+	DecidedBy    string     `json:"decided_by,omitempty"`
+	Comment      string     `json:"comment,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty" example:"2025-08-01T12:00:00Z"`
+}
+
+// ReportResponse - отчёт о завершении кампании
+type ReportResponse struct {
+	Campaign Response       `json:"campaign"`
+	Items    []ItemResponse `json:"items"`
+}
+
+type CreateRequest struct {
+	Name      string    `json:"name" validate:"required,min=2,max=155"`
+	ScopeType string    `json:"scope_type" validate:"required,oneof=DEPARTMENT ROLE" example:"DEPARTMENT"`
+	ScopeId   int64     `json:"scope_id" validate:"required,min=1"`
+	Deadline  time.Time `json:"deadline" validate:"required" example:"2025-09-30T00:00:00Z"`
+}
+
+func (req *CreateRequest) ToEntity(actor string) Entity {
+	return Entity{
+		Name:      req.Name,
+		ScopeType: req.ScopeType,
+		ScopeId:   req.ScopeId,
+		Deadline:  req.Deadline,
+		Status:    StatusActive,
+		CreatedBy: actor,
+	}
+}
+
+type DecisionRequest struct {
+	Decision string `json:"decision" validate:"required,oneof=CERTIFIED REVOKED" example:"CERTIFIED"`
+	Comment  string `json:"comment" validate:"max=1000"`
+}
+
+// Caller - вызывающий пользователь: логины из токена и признак администратора кампаний
+type Caller struct {
+	Subject           string
+	PreferredUsername string
+	Actor             string
+	Admin             bool
+}
@@ -0,0 +1,277 @@
+package certification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Create(ctx context.Context, actor string, request CreateRequest) (Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindItems(ctx context.Context, campaignId int64) ([]ItemResponse, error)
+	FindReviews(ctx context.Context, caller Caller) ([]ItemResponse, error)
+	Decide(ctx context.Context, caller Caller, campaignId, itemId int64, request DecisionRequest) (ItemResponse, error)
+	Report(ctx context.Context, campaignId int64) (ReportResponse, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/certifications"
+func (c *Handler) RegisterRoutes() {
+	var review = c.server.RequirePermission(web.PermCertificationReview)
+	var manage = c.server.RequirePermission(web.PermCertificationManage)
+	c.server.GroupApiV1.Post("/certifications", manage, c.Create)
+	c.server.GroupApiV1.Get("/certifications", manage, c.FindAll)
+	c.server.GroupApiV1.Get("/certifications/reviews", review, c.FindReviews)
+	c.server.GroupApiV1.Get("/certifications/:id", manage, c.FindById)
+	c.server.GroupApiV1.Get("/certifications/:id/items", manage, c.FindItems)
+	c.server.GroupApiV1.Get("/certifications/:id/report", manage, c.Report)
+	c.server.GroupApiV1.Post("/certifications/:id/items/:itemId/decision", review, c.Decide)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/certifications"
+// @Description Start certification campaign: snapshot all role assignments in a department subtree or of a role.
+// @Summary create certification campaign
+// @Tags certification
+// @Accept json
+// @Produce json
+// @Param request body CreateRequest true "campaign"
+// @Success 200 {object} common.Response[certification.Response]
+// @Failure 400 {object} common.Response[certification.Response] "invalid request or empty scope"
+// @Failure 403 {object} common.Response[certification.Response] "Permission denied"
+// @Failure 500 {object} common.Response[certification.Response] "error db"
+// @Router /certifications [post]
+// @Security BearerAuth
+func (c *Handler) Create(ctx *fiber.Ctx) error {
+	claims, _ := web.ClaimsFromCtx(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Create: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Create: received request", zap.Any("request", request))
+	rsl, err := c.service.Create(ctx.Context(), claims.Actor(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Create: error creating campaign", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications"
+// @Description Get all certification campaigns with progress.
+// @Summary get certification campaigns
+// @Tags certification
+// @Produce json
+// @Success 200 {object} common.Response[[]certification.Response]
+// @Failure 403 {object} common.Response[[]certification.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]certification.Response] "error db"
+// @Router /certifications [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding campaigns", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/reviews"
+// @Description Get certification items awaiting the caller's decision.
+// @Summary get items to review
+// @Tags certification
+// @Produce json
+// @Success 200 {object} common.Response[[]certification.ItemResponse]
+// @Failure 404 {object} common.Response[[]certification.ItemResponse] "employee not found"
+// @Failure 500 {object} common.Response[[]certification.ItemResponse] "error db"
+// @Router /certifications/reviews [get]
+// @Security BearerAuth
+func (c *Handler) FindReviews(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindReviews(ctx.Context(), c.caller(ctx))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindReviews: error finding items", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/:id"
+// @Description Find certification campaign with progress.
+// @Summary find certification campaign
+// @Tags certification
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} common.Response[certification.Response]
+// @Failure 400 {object} common.Response[certification.Response] "invalid request"
+// @Failure 404 {object} common.Response[certification.Response] "not found"
+// @Failure 500 {object} common.Response[certification.Response] "error db"
+// @Router /certifications/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding campaign", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/:id/items"
+// @Description Get all items of certification campaign.
+// @Summary get campaign items
+// @Tags certification
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} common.Response[[]certification.ItemResponse]
+// @Failure 400 {object} common.Response[[]certification.ItemResponse] "invalid request"
+// @Failure 500 {object} common.Response[[]certification.ItemResponse] "error db"
+// @Router /certifications/{id}/items [get]
+// @Security BearerAuth
+func (c *Handler) FindItems(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindItems: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindItems(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindItems: error finding items", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/:id/report"
+// @Description Completion report of certification campaign as JSON or CSV.
+// @Summary campaign report
+// @Tags certification
+// @Produce json,text/csv
+// @Param id path int true "Campaign ID"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} common.Response[certification.ReportResponse]
+// @Failure 400 {object} common.Response[certification.ReportResponse] "invalid request"
+// @Failure 404 {object} common.Response[certification.ReportResponse] "not found"
+// @Failure 500 {object} common.Response[certification.ReportResponse] "error db"
+// @Router /certifications/{id}/report [get]
+// @Security BearerAuth
+func (c *Handler) Report(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Report: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var format = ctx.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "format must be json or csv")
+	}
+	rsl, err := c.service.Report(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Report: error building report", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	if format == "json" {
+		return common.OkResponse(ctx, rsl)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, rsl); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Report: error writing csv", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="certification-%d.csv"`, id))
+	return ctx.Send(buf.Bytes())
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту
+// "/api/v1/certifications/:id/items/:itemId/decision"
+// @Description Certify or revoke an assignment under review. Revoking removes the role immediately.
+// @Summary decide certification item
+// @Tags certification
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param itemId path int true "Item ID"
+// @Param request body DecisionRequest true "decision"
+// @Success 200 {object} common.Response[certification.ItemResponse]
+// @Failure 400 {object} common.Response[certification.ItemResponse] "invalid request, closed campaign or already decided"
+// @Failure 403 {object} common.Response[certification.ItemResponse] "caller is not the reviewer"
+// @Failure 404 {object} common.Response[certification.ItemResponse] "not found"
+// @Failure 500 {object} common.Response[certification.ItemResponse] "error db"
+// @Router /certifications/{id}/items/{itemId}/decision [post]
+// @Security BearerAuth
+func (c *Handler) Decide(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Decide: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	itemId, err := strconv.ParseInt(ctx.Params("itemId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Decide: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request DecisionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Decide: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Decide(ctx.Context(), c.caller(ctx), id, itemId, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Decide: error deciding item", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// caller - вызывающий пользователь из токена; администратором кампаний считается
+// владелец разрешения certification:manage
+func (c *Handler) caller(ctx *fiber.Ctx) Caller {
+	var caller Caller
+	if claims, ok := web.ClaimsFromCtx(ctx); ok {
+		caller.Subject = claims.Subject
+		caller.PreferredUsername = claims.PreferredUsername
+		caller.Actor = claims.Actor()
+	}
+	caller.Admin, _ = c.server.HasPermission(ctx, web.PermCertificationManage)
+	return caller
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.ForbiddenError{}):
+		return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package certification
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{
+	"campaign_id", "campaign_name", "item_id", "employee_id", "employee_name", "role_id", "role_name",
+	"valid_from", "valid_until", "reviewer_id", "decision", "auto_revoked", "decided_by", "decided_at", "comment",
+}
+
+// WriteCSV - отчёт кампании в CSV: строка заголовка и по строке на каждый элемент
+func WriteCSV(w io.Writer, report ReportResponse) error {
+	var writer = csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	var campaignId = strconv.FormatInt(report.Campaign.Id, 10)
+	for _, item := range report.Items {
+		var reviewerId string
+		if item.ReviewerId > 0 {
+			reviewerId = strconv.FormatInt(item.ReviewerId, 10)
+		}
+		err := writer.Write([]string{
+			campaignId,
+			report.Campaign.Name,
+			strconv.FormatInt(item.Id, 10),
+			strconv.FormatInt(item.EmployeeId, 10),
+			item.EmployeeName,
+			strconv.FormatInt(item.RoleId, 10),
+			item.RoleName,
+			item.ValidFrom.Format(time.RFC3339),
+			formatTime(item.ValidUntil),
+			reviewerId,
+			item.Decision,
+			strconv.FormatBool(item.AutoRevoked),
+			item.DecidedBy,
+			formatTime(item.DecidedAt),
+			item.Comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,41 @@
+package certification
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCSV(t *testing.T) {
+	t.Run("Should write header and one row per item", func(t *testing.T) {
+		a := assert.New(t)
+		validFrom := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+		decidedAt := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+		report := ReportResponse{
+			Campaign: Response{Id: 3, Name: "Q3, finance"},
+			Items: []ItemResponse{
+				{Id: 1, EmployeeId: 10, EmployeeName: "Иван Петров", RoleId: 20, RoleName: "PAYMENT_APPROVE",
+					ValidFrom: validFrom, ReviewerId: 2, Decision: DecisionCertified, DecidedBy: "bob",
+					DecidedAt: &decidedAt, Comment: `still "needed"`},
+				{Id: 2, EmployeeId: 11, EmployeeName: "Anna Smirnova", RoleId: 20, RoleName: "PAYMENT_APPROVE",
+					ValidFrom: validFrom, Decision: DecisionRevoked, AutoRevoked: true},
+			},
+		}
+		var buf bytes.Buffer
+
+		a.NoError(WriteCSV(&buf, report))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		a.NoError(err)
+		a.Len(records, 3)
+		a.Equal(csvHeader, records[0])
+		a.Equal([]string{"3", "Q3, finance", "1", "10", "Иван Петров", "20", "PAYMENT_APPROVE",
+			"2025-07-01T00:00:00Z", "", "2", "CERTIFIED", "false", "bob", "2025-09-01T10:00:00Z", `still "needed"`},
+			records[1])
+		a.Equal("", records[2][9])
+		a.Equal("true", records[2][11])
+	})
+}
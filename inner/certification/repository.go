@@ -0,0 +1,156 @@
+package certification
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const selectSummary = `SELECT COUNT(*) AS total,
+		COUNT(*) FILTER (WHERE decision = 'PENDING') AS pending,
+		COUNT(*) FILTER (WHERE decision = 'CERTIFIED') AS certified,
+		COUNT(*) FILTER (WHERE decision = 'REVOKED') AS revoked,
+		COUNT(*) FILTER (WHERE auto_revoked) AS auto_revoked
+	FROM certification_item WHERE campaign_id = $1`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Add(tx *sqlx.Tx, campaign Entity) (created Entity, err error) {
+	err = tx.Get(&created,
+		`INSERT INTO certification_campaign(name, scope_type, scope_id, deadline, created_by)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING *`,
+		campaign.Name, campaign.ScopeType, campaign.ScopeId, campaign.Deadline, campaign.CreatedBy)
+	return created, err
+}
+
+// Snapshot - фиксирует действующие на момент запуска назначения области кампании как элементы на проверку.
+// Проверяющий - руководитель сотрудника; свои назначения сотрудник не проверяет
+func (r *Repository) Snapshot(tx *sqlx.Tx, campaign Entity) (int64, error) {
+	var scope string
+	switch campaign.ScopeType {
+	case ScopeDepartment:
+		scope = `e.department_id IN (SELECT id FROM scope)`
+	default:
+		scope = `er.role_id = $2`
+	}
+	result, err := tx.Exec(
+		`WITH RECURSIVE scope AS (
+			SELECT id FROM department WHERE id = $2
+			UNION
+			SELECT d.id FROM department d JOIN scope s ON d.parent_id = s.id
+		)
+		INSERT INTO certification_item(campaign_id, employee_id, employee_name, role_id, role_name,
+		                               valid_from, valid_until, reviewer_id)
+		SELECT $1, e.id, e.name || ' ' || e.surname, r.id, r.name, er.valid_from, er.valid_until,
+		       NULLIF(e.manager_id, e.id)
+		FROM employee_role er
+		JOIN employee e ON e.id = er.employee_id
+		JOIN role r ON r.id = er.role_id
+		WHERE er.valid_from <= NOW() AND (er.valid_until IS NULL OR er.valid_until > NOW())
+		  AND `+scope,
+		campaign.Id, campaign.ScopeId)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (campaign Entity, err error) {
+	err = r.db.GetContext(ctx, &campaign, "SELECT * FROM certification_campaign WHERE id = $1", id)
+	return campaign, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) (campaigns []Entity, err error) {
+	err = r.db.SelectContext(ctx, &campaigns, "SELECT * FROM certification_campaign ORDER BY created_at DESC")
+	return campaigns, err
+}
+
+func (r *Repository) FindSummary(ctx context.Context, campaignId int64) (summary Summary, err error) {
+	err = r.db.GetContext(ctx, &summary, selectSummary, campaignId)
+	return summary, err
+}
+
+func (r *Repository) FindItems(ctx context.Context, campaignId int64) (items []ItemEntity, err error) {
+	err = r.db.SelectContext(ctx, &items,
+		"SELECT * FROM certification_item WHERE campaign_id = $1 ORDER BY employee_name, role_name", campaignId)
+	return items, err
+}
+
+// FindReviews - элементы активных кампаний, ожидающие решения проверяющего.
+// Для администратора включаются и элементы без проверяющего
+func (r *Repository) FindReviews(ctx context.Context, reviewerId int64, admin bool) (items []ItemEntity, err error) {
+	err = r.db.SelectContext(ctx, &items,
+		`SELECT i.* FROM certification_item i
+		 JOIN certification_campaign c ON c.id = i.campaign_id
+		 WHERE c.status = 'ACTIVE' AND i.decision = 'PENDING'
+		   AND i.employee_id <> $1
+		   AND (i.reviewer_id = $1 OR ($2 AND i.reviewer_id IS NULL))
+		 ORDER BY c.deadline, i.employee_name, i.role_name`,
+		reviewerId, admin)
+	return items, err
+}
+
+// FindForUpdate - кампания, заблокированная до конца транзакции
+func (r *Repository) FindForUpdate(tx *sqlx.Tx, id int64) (campaign Entity, err error) {
+	err = tx.Get(&campaign, "SELECT * FROM certification_campaign WHERE id = $1 FOR UPDATE", id)
+	return campaign, err
+}
+
+func (r *Repository) FindItem(tx *sqlx.Tx, campaignId, itemId int64) (item ItemEntity, err error) {
+	err = tx.Get(&item,
+		"SELECT * FROM certification_item WHERE campaign_id = $1 AND id = $2", campaignId, itemId)
+	return item, err
+}
+
+func (r *Repository) Decide(tx *sqlx.Tx, item ItemEntity) error {
+	_, err := tx.Exec(
+		`UPDATE certification_item
+		 SET decision = $2, auto_revoked = $3, decided_by = $4, comment = $5, decided_at = NOW()
+		 WHERE id = $1`,
+		item.Id, item.Decision, item.AutoRevoked, item.DecidedBy, item.Comment)
+	return err
+}
+
+func (r *Repository) CountPending(tx *sqlx.Tx, campaignId int64) (count int, err error) {
+	err = tx.Get(&count,
+		"SELECT COUNT(*) FROM certification_item WHERE campaign_id = $1 AND decision = 'PENDING'", campaignId)
+	return count, err
+}
+
+func (r *Repository) FindPendingItems(tx *sqlx.Tx, campaignId int64) (items []ItemEntity, err error) {
+	err = tx.Select(&items,
+		"SELECT * FROM certification_item WHERE campaign_id = $1 AND decision = 'PENDING' ORDER BY id", campaignId)
+	return items, err
+}
+
+// FindOverdue - активные кампании с истёкшим сроком. Уже обрабатываемые другим
+// экземпляром сервиса пропускаются
+func (r *Repository) FindOverdue(tx *sqlx.Tx) (campaigns []Entity, err error) {
+	err = tx.Select(&campaigns,
+		`SELECT * FROM certification_campaign
+		 WHERE status = 'ACTIVE' AND deadline <= NOW()
+		 ORDER BY deadline
+		 FOR UPDATE SKIP LOCKED`)
+	return campaigns, err
+}
+
+func (r *Repository) Complete(tx *sqlx.Tx, campaignId int64) error {
+	_, err := tx.Exec(
+		"UPDATE certification_campaign SET status = 'COMPLETED', completed_at = NOW() WHERE id = $1", campaignId)
+	return err
+}
@@ -0,0 +1,343 @@
+package certification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const deadlineActor = "system:certification-deadline"
+
+type Service struct {
+	repo         Repo
+	employeeRepo EmployeeRepo
+	revoker      Revoker
+	validator    *validator.Validate
+	logger       common.LoggerInterface
+	// Provisioning - передача отозванных ролей во внешние системы после фиксации
+	// транзакции; nil - не передаются
+	Provisioning Provisioner
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	Add(tx *sqlx.Tx, campaign Entity) (Entity, error)
+	Snapshot(tx *sqlx.Tx, campaign Entity) (int64, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	FindSummary(ctx context.Context, campaignId int64) (Summary, error)
+	FindItems(ctx context.Context, campaignId int64) ([]ItemEntity, error)
+	FindReviews(ctx context.Context, reviewerId int64, admin bool) ([]ItemEntity, error)
+	FindForUpdate(tx *sqlx.Tx, id int64) (Entity, error)
+	FindItem(tx *sqlx.Tx, campaignId, itemId int64) (ItemEntity, error)
+	Decide(tx *sqlx.Tx, item ItemEntity) error
+	CountPending(tx *sqlx.Tx, campaignId int64) (int, error)
+	FindPendingItems(tx *sqlx.Tx, campaignId int64) ([]ItemEntity, error)
+	FindOverdue(tx *sqlx.Tx) ([]Entity, error)
+	Complete(tx *sqlx.Tx, campaignId int64) error
+}
+
+type EmployeeRepo interface {
+	FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error)
+}
+
+// Revoker - отзыв роли в транзакции решения по элементу кампании
+type Revoker interface {
+	RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error
+}
+
+func NewService(repo Repo, employeeRepo EmployeeRepo, revoker Revoker, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:         repo,
+		employeeRepo: employeeRepo,
+		revoker:      revoker,
+		validator:    validator.New(),
+		logger:       logger,
+	}
+}
+
+// Create - запускает кампанию: фиксирует все назначения области и создаёт элементы на проверку
+func (svc *Service) Create(ctx context.Context, actor string, request CreateRequest) (response Response, err error) {
+	if err = svc.validator.Struct(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if !request.Deadline.After(time.Now()) {
+		return Response{}, common.RequestValidationError{Message: "deadline must be in the future"}
+	}
+	err = common.InTx(svc.repo, "creating certification campaign", func(tx *sqlx.Tx) error {
+		created, err := svc.repo.Add(tx, request.ToEntity(actor))
+		if err != nil {
+			return fmt.Errorf("Error adding certification campaign %s: %w", request.Name, err)
+		}
+		count, err := svc.repo.Snapshot(tx, created)
+		if err != nil {
+			return fmt.Errorf("Error taking assignments snapshot for campaign %s: %w", request.Name, err)
+		}
+		if count == 0 {
+			return common.RequestValidationError{
+				Message: fmt.Sprintf("No role assignments found in scope %s %d", request.ScopeType, request.ScopeId),
+			}
+		}
+		response = created.ToResponse(Summary{Total: int(count), Pending: int(count)})
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return response, nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id campaign: %d", id)}
+	}
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Campaign with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding campaign with id %d: %w", id, err)
+	}
+	return svc.withSummary(ctx, entity)
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding campaigns: %w", err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		response, err := svc.withSummary(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (svc *Service) FindItems(ctx context.Context, campaignId int64) ([]ItemResponse, error) {
+	if campaignId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id campaign: %d", campaignId)}
+	}
+	items, err := svc.repo.FindItems(ctx, campaignId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding items of campaign %d: %w", campaignId, err)
+	}
+	return toItemResponses(items), nil
+}
+
+// FindReviews - элементы, ожидающие решения вызывающего
+func (svc *Service) FindReviews(ctx context.Context, caller Caller) ([]ItemResponse, error) {
+	var reviewerId int64
+	reviewer, err := svc.resolve(ctx, caller)
+	if err == nil {
+		reviewerId = reviewer.Id
+	} else if !caller.Admin {
+		return nil, err
+	}
+	items, err := svc.repo.FindReviews(ctx, reviewerId, caller.Admin)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding items to review: %w", err)
+	}
+	return toItemResponses(items), nil
+}
+
+// Decide - решение проверяющего по элементу. REVOKED отзывает роль в той же транзакции;
+// после последнего решения кампания завершается
+func (svc *Service) Decide(
+	ctx context.Context,
+	caller Caller,
+	campaignId, itemId int64,
+	request DecisionRequest,
+) (response ItemResponse, err error) {
+	if campaignId <= 0 || itemId <= 0 {
+		return ItemResponse{}, common.RequestValidationError{
+			Message: fmt.Sprintf("Wrong campaign id %d or item id %d", campaignId, itemId),
+		}
+	}
+	if err = svc.validator.Struct(request); err != nil {
+		return ItemResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var reviewerId int64
+	reviewer, err := svc.resolve(ctx, caller)
+	if err == nil {
+		reviewerId = reviewer.Id
+	} else if !caller.Admin {
+		return ItemResponse{}, err
+	}
+	err = common.InTx(svc.repo, "deciding certification item", func(tx *sqlx.Tx) error {
+		campaign, err := svc.repo.FindForUpdate(tx, campaignId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("Campaign with id %d not found", campaignId)}
+		}
+		if err != nil {
+			return fmt.Errorf("Error finding campaign with id %d: %w", campaignId, err)
+		}
+		if campaign.Status != StatusActive || !campaign.Deadline.After(time.Now()) {
+			return common.RequestValidationError{Message: fmt.Sprintf("Campaign %d is closed", campaignId)}
+		}
+		item, err := svc.repo.FindItem(tx, campaignId, itemId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("Item %d not found in campaign %d", itemId, campaignId)}
+		}
+		if err != nil {
+			return fmt.Errorf("Error finding item %d of campaign %d: %w", itemId, campaignId, err)
+		}
+		if item.Decision != DecisionPending {
+			return common.RequestValidationError{Message: fmt.Sprintf("Item %d is already %s", itemId, item.Decision)}
+		}
+		if item.EmployeeId == reviewerId {
+			return common.ForbiddenError{Message: "Employee cannot certify own access"}
+		}
+		var isReviewer = item.ReviewerId.Valid && item.ReviewerId.Int64 == reviewerId
+		if !isReviewer && !caller.Admin {
+			return common.ForbiddenError{Message: fmt.Sprintf("Caller is not the reviewer of item %d", itemId)}
+		}
+		item.Decision = request.Decision
+		item.DecidedBy = caller.Actor
+		item.Comment = request.Comment
+		if err = svc.decide(ctx, tx, item); err != nil {
+			return err
+		}
+		pending, err := svc.repo.CountPending(tx, campaignId)
+		if err != nil {
+			return fmt.Errorf("Error counting pending items of campaign %d: %w", campaignId, err)
+		}
+		if pending == 0 {
+			if err = svc.repo.Complete(tx, campaignId); err != nil {
+				return fmt.Errorf("Error completing campaign %d: %w", campaignId, err)
+			}
+		}
+		response = item.ToResponse()
+		return nil
+	})
+	if err != nil {
+		return ItemResponse{}, err
+	}
+	if response.Decision == DecisionRevoked {
+		svc.provision(ctx, response.EmployeeId)
+	}
+	return response, nil
+}
+
+// CloseOverdue - отзывает роли по всем элементам без решения в кампаниях с истёкшим сроком
+// и завершает эти кампании
+func (svc *Service) CloseOverdue(ctx context.Context) (closed []Response, err error) {
+	var revoked []int64
+	err = common.InTx(svc.repo, "closing overdue campaigns", func(tx *sqlx.Tx) error {
+		campaigns, err := svc.repo.FindOverdue(tx)
+		if err != nil {
+			return fmt.Errorf("Error finding overdue campaigns: %w", err)
+		}
+		for _, campaign := range campaigns {
+			items, err := svc.repo.FindPendingItems(tx, campaign.Id)
+			if err != nil {
+				return fmt.Errorf("Error finding pending items of campaign %d: %w", campaign.Id, err)
+			}
+			for _, item := range items {
+				item.Decision = DecisionRevoked
+				item.AutoRevoked = true
+				item.DecidedBy = deadlineActor
+				item.Comment = "Not reviewed before deadline"
+				if err = svc.decide(ctx, tx, item); err != nil {
+					return err
+				}
+				revoked = append(revoked, item.EmployeeId)
+			}
+			if err = svc.repo.Complete(tx, campaign.Id); err != nil {
+				return fmt.Errorf("Error completing campaign %d: %w", campaign.Id, err)
+			}
+			campaign.Status = StatusCompleted
+			closed = append(closed, campaign.ToResponse(Summary{AutoRevoked: len(items)}))
+		}
+		return nil
+	})
+	if err != nil {
+		svc.logger.ErrorCtx(ctx, "CloseOverdue: error", zap.Error(err))
+		return nil, err
+	}
+	svc.provision(ctx, revoked...)
+	return closed, nil
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil && len(employeeIds) > 0 {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+// Report - отчёт по кампании: итоги и все элементы с решениями
+func (svc *Service) Report(ctx context.Context, campaignId int64) (ReportResponse, error) {
+	campaign, err := svc.FindById(ctx, campaignId)
+	if err != nil {
+		return ReportResponse{}, err
+	}
+	items, err := svc.FindItems(ctx, campaignId)
+	if err != nil {
+		return ReportResponse{}, err
+	}
+	return ReportResponse{Campaign: campaign, Items: items}, nil
+}
+
+// decide - сохраняет решение; для REVOKED отзывает роль. Роль, отозванная после запуска
+// кампании другим способом, не считается ошибкой
+func (svc *Service) decide(ctx context.Context, tx *sqlx.Tx, item ItemEntity) error {
+	if err := svc.repo.Decide(tx, item); err != nil {
+		return fmt.Errorf("Error saving decision on item %d: %w", item.Id, err)
+	}
+	if item.Decision != DecisionRevoked {
+		return nil
+	}
+	err := svc.revoker.RevokeInTx(ctx, tx, item.DecidedBy, assignment.RevokeRequest{
+		EmployeeId: item.EmployeeId,
+		RoleId:     item.RoleId,
+	})
+	if err != nil && !errors.As(err, &common.NotFoundError{}) {
+		return fmt.Errorf("Error revoking role %d from employee %d: %w", item.RoleId, item.EmployeeId, err)
+	}
+	return nil
+}
+
+func (svc *Service) withSummary(ctx context.Context, entity Entity) (Response, error) {
+	summary, err := svc.repo.FindSummary(ctx, entity.Id)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding summary of campaign %d: %w", entity.Id, err)
+	}
+	return entity.ToResponse(summary), nil
+}
+
+// resolve - сотрудник вызывающего пользователя: сначала по sub, затем по preferred_username
+func (svc *Service) resolve(ctx context.Context, caller Caller) (employee.Entity, error) {
+	entity, err := svc.employeeRepo.FindByIdentity(ctx, caller.Subject, caller.PreferredUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return employee.Entity{}, common.NotFoundError{Message: "No employee is linked to the token subject"}
+	}
+	if err != nil {
+		return employee.Entity{}, fmt.Errorf("Error finding employee linked to the token: %w", err)
+	}
+	return entity, nil
+}
+
+func toItemResponses(items []ItemEntity) []ItemResponse {
+	responses := make([]ItemResponse, 0, len(items))
+	for _, i := range items {
+		responses = append(responses, i.ToResponse())
+	}
+	return responses
+}
@@ -0,0 +1,308 @@
+package certification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/testutil"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) Add(tx *sqlx.Tx, campaign Entity) (Entity, error) {
+	args := m.Called(tx, campaign)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) Snapshot(tx *sqlx.Tx, campaign Entity) (int64, error) {
+	args := m.Called(tx, campaign)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindSummary(ctx context.Context, campaignId int64) (Summary, error) {
+	args := m.Called(ctx, campaignId)
+	return args.Get(0).(Summary), args.Error(1)
+}
+
+func (m *MockRepo) FindItems(ctx context.Context, campaignId int64) ([]ItemEntity, error) {
+	args := m.Called(ctx, campaignId)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindReviews(ctx context.Context, reviewerId int64, admin bool) ([]ItemEntity, error) {
+	args := m.Called(ctx, reviewerId, admin)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindForUpdate(tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindItem(tx *sqlx.Tx, campaignId, itemId int64) (ItemEntity, error) {
+	args := m.Called(tx, campaignId, itemId)
+	return args.Get(0).(ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) Decide(tx *sqlx.Tx, item ItemEntity) error {
+	args := m.Called(tx, item)
+	return args.Error(0)
+}
+
+func (m *MockRepo) CountPending(tx *sqlx.Tx, campaignId int64) (int, error) {
+	args := m.Called(tx, campaignId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepo) FindPendingItems(tx *sqlx.Tx, campaignId int64) ([]ItemEntity, error) {
+	args := m.Called(tx, campaignId)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindOverdue(tx *sqlx.Tx) ([]Entity, error) {
+	args := m.Called(tx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) Complete(tx *sqlx.Tx, campaignId int64) error {
+	args := m.Called(tx, campaignId)
+	return args.Error(0)
+}
+
+type MockEmployeeRepo struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeRepo) FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error) {
+	args := m.Called(ctx, subject, username)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+type MockRevoker struct {
+	mock.Mock
+}
+
+func (m *MockRevoker) RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Error(0)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	request := CreateRequest{Name: "Q3 finance", ScopeType: ScopeDepartment, ScopeId: 4,
+		Deadline: time.Now().Add(14 * 24 * time.Hour)}
+
+	t.Run("Should snapshot assignments of the scope", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), new(MockRevoker), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		created := request.ToEntity("admin")
+		created.Id = 1
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Add", tx, request.ToEntity("admin")).Return(created, nil)
+		repo.On("Snapshot", tx, created).Return(int64(12), nil)
+
+		got, err := svc.Create(ctx, "admin", request)
+
+		a.NoError(err)
+		a.Equal(12, got.Summary.Pending)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should rollback campaign without assignments in scope", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), new(MockRevoker), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Add", tx, mock.Anything).Return(Entity{Id: 1}, nil)
+		repo.On("Snapshot", tx, mock.Anything).Return(int64(0), nil)
+
+		_, err := svc.Create(ctx, "admin", request)
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject past deadline and unknown scope", func(t *testing.T) {
+		t.Parallel()
+		svc := NewService(new(MockRepo), new(MockEmployeeRepo), new(MockRevoker), &MockLogger{})
+		past := request
+		past.Deadline = time.Now().Add(-time.Hour)
+		unknown := request
+		unknown.ScopeType = "COMPANY"
+
+		for _, r := range []CreateRequest{past, unknown} {
+			_, err := svc.Create(ctx, "admin", r)
+			assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		}
+	})
+}
+
+func TestDecide(t *testing.T) {
+	ctx := context.Background()
+	active := Entity{Id: 1, Status: StatusActive, Deadline: time.Now().Add(time.Hour)}
+	pendingItem := ItemEntity{Id: 7, CampaignId: 1, EmployeeId: 10, RoleId: 20,
+		ReviewerId: sql.NullInt64{Int64: 2, Valid: true}, Decision: DecisionPending}
+	manager := employee.Entity{Id: 2}
+
+	t.Run("Should revoke role and complete campaign on last decision", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		revoker := new(MockRevoker)
+		svc := NewService(repo, employees, revoker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		employees.On("FindByIdentity", ctx, "bob", "").Return(manager, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindForUpdate", tx, int64(1)).Return(active, nil)
+		repo.On("FindItem", tx, int64(1), int64(7)).Return(pendingItem, nil)
+		repo.On("Decide", tx, mock.MatchedBy(func(i ItemEntity) bool {
+			return i.Decision == DecisionRevoked && i.DecidedBy == "bob" && !i.AutoRevoked
+		})).Return(nil)
+		revoker.On("RevokeInTx", ctx, tx, "bob", assignment.RevokeRequest{EmployeeId: 10, RoleId: 20}).Return(nil)
+		repo.On("CountPending", tx, int64(1)).Return(0, nil)
+		repo.On("Complete", tx, int64(1)).Return(nil)
+
+		got, err := svc.Decide(ctx, Caller{Subject: "bob", Actor: "bob"}, 1, 7,
+			DecisionRequest{Decision: DecisionRevoked, Comment: "left the team"})
+
+		a.NoError(err)
+		a.Equal(DecisionRevoked, got.Decision)
+		a.NoError(mockTr.ExpectationsWereMet())
+		revoker.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should certify without revoking", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		revoker := new(MockRevoker)
+		svc := NewService(repo, employees, revoker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		employees.On("FindByIdentity", ctx, "bob", "").Return(manager, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindForUpdate", tx, int64(1)).Return(active, nil)
+		repo.On("FindItem", tx, int64(1), int64(7)).Return(pendingItem, nil)
+		repo.On("Decide", tx, mock.Anything).Return(nil)
+		repo.On("CountPending", tx, int64(1)).Return(3, nil)
+
+		_, err := svc.Decide(ctx, Caller{Subject: "bob", Actor: "bob"}, 1, 7, DecisionRequest{Decision: DecisionCertified})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		revoker.AssertNotCalled(t, "RevokeInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should forbid decision by someone other than the reviewer", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(repo, employees, new(MockRevoker), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		employees.On("FindByIdentity", ctx, "eve", "").Return(employee.Entity{Id: 3}, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindForUpdate", tx, int64(1)).Return(active, nil)
+		repo.On("FindItem", tx, int64(1), int64(7)).Return(pendingItem, nil)
+
+		_, err := svc.Decide(ctx, Caller{Subject: "eve"}, 1, 7, DecisionRequest{Decision: DecisionCertified})
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject decision after deadline", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(repo, employees, new(MockRevoker), &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		overdue := active
+		overdue.Deadline = time.Now().Add(-time.Minute)
+		employees.On("FindByIdentity", ctx, "bob", "").Return(manager, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindForUpdate", tx, int64(1)).Return(overdue, nil)
+
+		_, err := svc.Decide(ctx, Caller{Subject: "bob"}, 1, 7, DecisionRequest{Decision: DecisionCertified})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestCloseOverdue(t *testing.T) {
+	t.Run("Should auto-revoke undecided items and complete campaign", func(t *testing.T) {
+		a := assert.New(t)
+		ctx := context.Background()
+		repo := new(MockRepo)
+		revoker := new(MockRevoker)
+		svc := NewService(repo, new(MockEmployeeRepo), revoker, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		items := []ItemEntity{
+			{Id: 1, CampaignId: 1, EmployeeId: 10, RoleId: 20, Decision: DecisionPending},
+			{Id: 2, CampaignId: 1, EmployeeId: 11, RoleId: 20, Decision: DecisionPending},
+		}
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindOverdue", tx).Return([]Entity{{Id: 1, Status: StatusActive}}, nil)
+		repo.On("FindPendingItems", tx, int64(1)).Return(items, nil)
+		repo.On("Decide", tx, mock.MatchedBy(func(i ItemEntity) bool {
+			return i.Decision == DecisionRevoked && i.AutoRevoked && i.DecidedBy == deadlineActor
+		})).Return(nil).Twice()
+		revoker.On("RevokeInTx", ctx, tx, deadlineActor, assignment.RevokeRequest{EmployeeId: 10, RoleId: 20}).
+			Return(nil)
+		// роль уже отозвана другим способом - не ошибка
+		revoker.On("RevokeInTx", ctx, tx, deadlineActor, assignment.RevokeRequest{EmployeeId: 11, RoleId: 20}).
+			Return(common.NotFoundError{Message: "not assigned"})
+		repo.On("Complete", tx, int64(1)).Return(nil)
+
+		closed, err := svc.CloseOverdue(ctx)
+
+		a.NoError(err)
+		a.Len(closed, 1)
+		a.Equal(2, closed[0].Summary.AutoRevoked)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertExpectations(t)
+	})
+}
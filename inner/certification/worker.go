@@ -0,0 +1,66 @@
+package certification
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Closer interface {
+	CloseOverdue(ctx context.Context) ([]Response, error)
+}
+
+// DeadlineWorker - фоновый процесс, завершающий кампании с истёкшим сроком
+type DeadlineWorker struct {
+	closer   Closer
+	interval time.Duration
+	logger   *common.Logger
+	runner   common.Runner
+}
+
+func NewDeadlineWorker(closer Closer, interval time.Duration, logger *common.Logger) *DeadlineWorker {
+	return &DeadlineWorker{
+		closer:   closer,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *DeadlineWorker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей итерации
+func (w *DeadlineWorker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *DeadlineWorker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.close(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *DeadlineWorker) close(ctx context.Context) {
+	closed, err := w.closer.CloseOverdue(ctx)
+	if err != nil {
+		w.logger.Error("DeadlineWorker: error closing overdue campaigns", zap.Error(err))
+		return
+	}
+	for _, c := range closed {
+		w.logger.Info("DeadlineWorker: campaign deadline passed, undecided items revoked",
+			zap.Int64("campaign_id", c.Id),
+			zap.String("name", c.Name),
+			zap.Int("auto_revoked", c.Summary.AutoRevoked))
+	}
+}
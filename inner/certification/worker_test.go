@@ -0,0 +1,39 @@
+package certification
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type StubCloser struct {
+	calls atomic.Int32
+}
+
+func (s *StubCloser) CloseOverdue(ctx context.Context) ([]Response, error) {
+	s.calls.Add(1)
+	return []Response{{Id: 1, Summary: Summary{AutoRevoked: 2}}}, nil
+}
+
+func TestDeadlineWorker(t *testing.T) {
+	t.Run("Should close overdue campaigns periodically and stop cleanly", func(t *testing.T) {
+		a := assert.New(t)
+		closer := &StubCloser{}
+		worker := NewDeadlineWorker(closer, 10*time.Millisecond, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { return closer.calls.Load() >= 2 }, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		a.NoError(worker.Stop(ctx))
+		var calls = closer.calls.Load()
+		time.Sleep(30 * time.Millisecond)
+		a.Equal(calls, closer.calls.Load())
+	})
+}
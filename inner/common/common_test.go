@@ -2,6 +2,7 @@ package common
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -144,3 +145,25 @@ func TestGetConfigWhenHaveCorrectFileAndVariablesEnvThenGetVariable(t *testing.T
 		}
 	})
 }
+
+// TestLoadConfigWhenInvalidThenReturnValidationErrors - невалидный конфиг возвращается вместе с ошибками
+// по полям, без паники
+func TestLoadConfigWhenInvalidThenReturnValidationErrors(t *testing.T) {
+	t.Setenv("DB_DRIVER_NAME", "postgres")
+	t.Setenv("DB_DSN", "host=127.0.0.1 port=5432")
+	t.Setenv("APP_NAME", "idm")
+	t.Setenv("APP_VERSION", "0.0.0")
+	t.Setenv("SSL_SERT", "")
+	t.Setenv("SSL_KEY", "Ket")
+	t.Setenv("KEYCLOAK_JWK_URL", "url")
+
+	rsl, err := LoadConfig("")
+	t.Run("Should return error naming invalid field", func(t *testing.T) {
+		if err == nil || !strings.Contains(err.Error(), "SslSert") {
+			t.Errorf("Error should name SslSert, got %v", err)
+		}
+		if rsl.Dsn != "host=127.0.0.1 port=5432" {
+			t.Errorf("Dsn should be read despite the error, got %s", rsl.Dsn)
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2/log"
@@ -22,32 +24,162 @@ type Config struct {
 	KeycloakJwkUrl string `validate:"required"`
 	LogLevel       string
 	LogDevelopMode bool
+	// AssignmentExpiryInterval - период запуска отзыва истёкших назначений ролей
+	AssignmentExpiryInterval time.Duration
+	// CertificationDeadlineInterval - период проверки кампаний пересертификации с истёкшим сроком
+	CertificationDeadlineInterval time.Duration
+	// KeycloakAdminUrl - адрес Keycloak для провижининга сотрудников через admin API;
+	// пусто - провижининг выключен
+	KeycloakAdminUrl     string
+	KeycloakRealm        string `validate:"required_with=KeycloakAdminUrl"`
+	KeycloakClientId     string `validate:"required_with=KeycloakAdminUrl"`
+	KeycloakClientSecret string `validate:"required_with=KeycloakAdminUrl"`
+	// ProvisioningMaxAttempts - число попыток синхронизации сотрудника до статуса FAILED
+	ProvisioningMaxAttempts int
+	// ProvisioningRetryInterval - пауза перед первым повтором, дальше удваивается
+	ProvisioningRetryInterval time.Duration
+	// ProvisioningReconcileInterval - период сверки сотрудников с Keycloak
+	ProvisioningReconcileInterval time.Duration
+	// ProvisioningReconcileApply - периодическая сверка исправляет расхождения, а не только сообщает о них
+	ProvisioningReconcileApply bool
+	// OutboxPublisher - способ публикации доменных событий: log (по умолчанию), file или http
+	OutboxPublisher string `validate:"omitempty,oneof=log file http"`
+	// OutboxFilePath - файл, в который дописываются события при OutboxPublisher=file
+	OutboxFilePath string `validate:"required_if=OutboxPublisher file"`
+	// OutboxHttpUrl - адрес, на который отправляются события при OutboxPublisher=http
+	OutboxHttpUrl string `validate:"required_if=OutboxPublisher http,omitempty,url"`
+	// OutboxRelayInterval - период проверки outbox на неопубликованные события
+	OutboxRelayInterval time.Duration
+	// OutboxRetention - срок хранения опубликованных событий
+	OutboxRetention time.Duration
+	// WebhookDeliveryInterval - период проверки очереди доставок подписчикам
+	WebhookDeliveryInterval time.Duration
+	// WebhookMaxAttempts - число попыток доставки до перехода в DEAD
+	WebhookMaxAttempts int
+	// WebhookRetryInterval - пауза перед первым повтором доставки, дальше удваивается
+	WebhookRetryInterval time.Duration
+	// WebhookRetention - срок хранения успешных доставок в журнале
+	WebhookRetention time.Duration
+	// StreamPollInterval - период проверки новых публикаций для потока событий SSE
+	StreamPollInterval time.Duration
+	// LdifBaseDn - базовый DN каталога в LDIF: сотрудники в ou=people, роли в ou=groups
+	LdifBaseDn string
+	// LdapAddr - адрес LDAP-фронтенда каталога только для чтения (например, ":389"); пусто - выключен
+	LdapAddr string
+	// LdapTls - LDAP-фронтенд принимает подключения по TLS (LDAPS) с сертификатом приложения
+	LdapTls bool
+	// LdapBindDn, LdapBindPassword - учётные данные сервисной записи, которой разрешён поиск
+	LdapBindDn       string `validate:"required_with=LdapAddr"`
+	LdapBindPassword string `validate:"required_with=LdapAddr"`
+	// GrpcAddr - адрес gRPC API сотрудников и ролей (например, ":9090"); пусто - выключен
+	GrpcAddr string
+	// GrpcTls - gRPC API принимает подключения по TLS с сертификатом приложения
+	GrpcTls bool
+	// SchemaCheck - действие при старте, если в БД применены не все миграции: refuse (по умолчанию) -
+	// не запускаться, migrate - применить их, warn - запуститься с предупреждением
+	SchemaCheck string `validate:"oneof=refuse migrate warn"`
+	// IdempotencyKeyTtl - срок хранения ключей Idempotency-Key и ответов на них
+	IdempotencyKeyTtl time.Duration
 }
 
 // GetConfig - получение конфигурации из .env файла или переменных окружения
 func GetConfig(envFile string) Config {
+	var cfg, err = LoadConfig(envFile)
+	if err != nil {
+		// если конфиг не прошел валидацию, то паникуем
+		panic(fmt.Sprintf("Config validation error: %v", err))
+	}
+	return cfg
+}
+
+// LoadConfig - конфиг из envFile и переменных окружения; ошибка валидации возвращается, а не вызывает панику
+func LoadConfig(envFile string) (Config, error) {
 	var err = godotenv.Load(envFile)
 	if err != nil {
 		log.Info("Error loading .env file: %v\n", zap.Error(err))
 	}
 	var cfg = Config{
-		DbDriverName:   os.Getenv("DB_DRIVER_NAME"),
-		Dsn:            os.Getenv("DB_DSN"),
-		AppName:        os.Getenv("APP_NAME"),
-		AppVersion:     os.Getenv("APP_VERSION"),
-		LogLevel:       os.Getenv("LOG_LEVEL"),
-		LogDevelopMode: os.Getenv("LOG_DEVELOP_MODE") == "true",
-		SslSert:        os.Getenv("SSL_SERT"),
-		SslKey:         os.Getenv("SSL_KEY"),
-		KeycloakJwkUrl: os.Getenv("KEYCLOAK_JWK_URL"),
+		DbDriverName:                  os.Getenv("DB_DRIVER_NAME"),
+		Dsn:                           os.Getenv("DB_DSN"),
+		AppName:                       os.Getenv("APP_NAME"),
+		AppVersion:                    os.Getenv("APP_VERSION"),
+		LogLevel:                      os.Getenv("LOG_LEVEL"),
+		LogDevelopMode:                os.Getenv("LOG_DEVELOP_MODE") == "true",
+		SslSert:                       os.Getenv("SSL_SERT"),
+		SslKey:                        os.Getenv("SSL_KEY"),
+		KeycloakJwkUrl:                os.Getenv("KEYCLOAK_JWK_URL"),
+		AssignmentExpiryInterval:      getDuration("ASSIGNMENT_EXPIRY_INTERVAL", time.Minute),
+		CertificationDeadlineInterval: getDuration("CERTIFICATION_DEADLINE_INTERVAL", 15*time.Minute),
+		KeycloakAdminUrl:              os.Getenv("KEYCLOAK_ADMIN_URL"),
+		KeycloakRealm:                 os.Getenv("KEYCLOAK_REALM"),
+		KeycloakClientId:              os.Getenv("KEYCLOAK_CLIENT_ID"),
+		KeycloakClientSecret:          os.Getenv("KEYCLOAK_CLIENT_SECRET"),
+		ProvisioningMaxAttempts:       getInt("PROVISIONING_MAX_ATTEMPTS", 5),
+		ProvisioningRetryInterval:     getDuration("PROVISIONING_RETRY_INTERVAL", 5*time.Second),
+		ProvisioningReconcileInterval: getDuration("PROVISIONING_RECONCILE_INTERVAL", time.Hour),
+		ProvisioningReconcileApply:    os.Getenv("PROVISIONING_RECONCILE_APPLY") == "true",
+		OutboxPublisher:               os.Getenv("OUTBOX_PUBLISHER"),
+		OutboxFilePath:                os.Getenv("OUTBOX_FILE_PATH"),
+		OutboxHttpUrl:                 os.Getenv("OUTBOX_HTTP_URL"),
+		OutboxRelayInterval:           getDuration("OUTBOX_RELAY_INTERVAL", time.Second),
+		OutboxRetention:               getDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+		WebhookDeliveryInterval:       getDuration("WEBHOOK_DELIVERY_INTERVAL", 5*time.Second),
+		WebhookMaxAttempts:            getInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryInterval:          getDuration("WEBHOOK_RETRY_INTERVAL", 30*time.Second),
+		WebhookRetention:              getDuration("WEBHOOK_RETENTION", 30*24*time.Hour),
+		StreamPollInterval:            getDuration("STREAM_POLL_INTERVAL", time.Second),
+		LdifBaseDn:                    getString("LDIF_BASE_DN", "dc=idm,dc=local"),
+		LdapAddr:                      os.Getenv("LDAP_ADDR"),
+		LdapTls:                       os.Getenv("LDAP_TLS") == "true",
+		LdapBindDn:                    os.Getenv("LDAP_BIND_DN"),
+		LdapBindPassword:              os.Getenv("LDAP_BIND_PASSWORD"),
+		GrpcAddr:                      os.Getenv("GRPC_ADDR"),
+		GrpcTls:                       os.Getenv("GRPC_TLS") == "true",
+		SchemaCheck:                   getString("SCHEMA_CHECK", "refuse"),
+		IdempotencyKeyTtl:             getDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
 	}
 	err = validator.New().Struct(cfg)
-	if err != nil {
-		var validateErrs validator.ValidationErrors
-		if errors.As(err, &validateErrs) {
-			// если конфиг не прошел валидацию, то паникуем
-			panic(fmt.Sprintf("Config validation error: %v", err))
-		}
+	var validateErrs validator.ValidationErrors
+	if errors.As(err, &validateErrs) {
+		return cfg, validateErrs
 	}
-	return cfg
+	return cfg, nil
+}
+
+// getDuration - читает длительность из переменной окружения (например, "30s", "5m"),
+// при отсутствии или ошибке разбора возвращает значение по умолчанию
+func getDuration(name string, defaultValue time.Duration) time.Duration {
+	var value = os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Info("Invalid duration in ", name, ", using default ", defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getString - значение переменной окружения или значение по умолчанию, если она не задана
+func getString(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getInt - читает положительное целое из переменной окружения, при отсутствии или ошибке
+// разбора возвращает значение по умолчанию
+func getInt(name string, defaultValue int) int {
+	var value = os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Info("Invalid number in ", name, ", using default ", defaultValue)
+		return defaultValue
+	}
+	return number
 }
@@ -15,3 +15,19 @@ type AlreadyExistsError struct {
 func (err AlreadyExistsError) Error() string {
 	return err.Message
 }
+
+type NotFoundError struct {
+	Message string
+}
+
+func (err NotFoundError) Error() string {
+	return err.Message
+}
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (err ForbiddenError) Error() string {
+	return err.Message
+}
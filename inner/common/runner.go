@@ -0,0 +1,35 @@
+package common
+
+import "context"
+
+// Runner - запускает фоновую функцию в отдельной горутине и останавливает её с ожиданием завершения.
+// Нулевое значение готово к использованию
+type Runner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start - запускает run в отдельной горутине; ctx отменяется при Stop
+func (r *Runner) Start(run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		run(ctx)
+	}()
+}
+
+// Stop - отменяет контекст run и ждёт её завершения, но не дольше ctx
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner(t *testing.T) {
+	t.Run("Should return nil when stopped before start", func(t *testing.T) {
+		a := assert.New(t)
+		var runner Runner
+
+		a.Nil(runner.Stop(context.Background()))
+	})
+	t.Run("Should cancel run and wait for it to finish", func(t *testing.T) {
+		a := assert.New(t)
+		var runner Runner
+		var finished = false
+		runner.Start(func(ctx context.Context) {
+			<-ctx.Done()
+			finished = true
+		})
+
+		a.Nil(runner.Stop(context.Background()))
+		a.True(finished)
+	})
+	t.Run("Should return context error when run does not finish in time", func(t *testing.T) {
+		a := assert.New(t)
+		var runner Runner
+		var release = make(chan struct{})
+		defer close(release)
+		runner.Start(func(ctx context.Context) { <-release })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		a.ErrorIs(runner.Stop(ctx), context.DeadlineExceeded)
+	})
+}
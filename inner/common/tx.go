@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TxBeginner - источник транзакций, как правило репозиторий сервиса
+type TxBeginner interface {
+	BeginTr() (*sqlx.Tx, error)
+}
+
+// InTx - выполняет fn в транзакции: коммит при успехе, откат при ошибке или панике
+func InTx(beginner TxBeginner, action string, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := beginner.BeginTr()
+	if err != nil || tx == nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panic: %v", action, r)
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", action, err, errTx)
+			}
+		} else if err != nil {
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", action, err, errTx)
+			}
+		} else {
+			if errTx := tx.Commit(); errTx != nil {
+				err = fmt.Errorf("%s: commiting transaction error: %w", action, errTx)
+			}
+		}
+	}()
+	return fn(tx)
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubBeginner struct {
+	db *sqlx.DB
+}
+
+func (b stubBeginner) BeginTr() (*sqlx.Tx, error) {
+	return b.db.Beginx()
+}
+
+func newBeginner(t *testing.T) (stubBeginner, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return stubBeginner{db: sqlx.NewDb(db, "sqlmock")}, mock
+}
+
+func TestInTx(t *testing.T) {
+	t.Run("Should commit when fn succeeds", func(t *testing.T) {
+		a := assert.New(t)
+		beginner, mock := newBeginner(t)
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		var err = InTx(beginner, "testing", func(tx *sqlx.Tx) error { return nil })
+
+		a.Nil(err)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+	t.Run("Should rollback and return error when fn fails", func(t *testing.T) {
+		a := assert.New(t)
+		beginner, mock := newBeginner(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		var fnErr = errors.New("fn error")
+
+		var err = InTx(beginner, "testing", func(tx *sqlx.Tx) error { return fnErr })
+
+		a.ErrorIs(err, fnErr)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+	t.Run("Should rollback and return error when fn panics", func(t *testing.T) {
+		a := assert.New(t)
+		beginner, mock := newBeginner(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		var err = InTx(beginner, "testing", func(tx *sqlx.Tx) error { panic("boom") })
+
+		a.ErrorContains(err, "testing panic: boom")
+		a.Nil(mock.ExpectationsWereMet())
+	})
+	t.Run("Should return error when transaction cannot begin", func(t *testing.T) {
+		a := assert.New(t)
+		beginner, mock := newBeginner(t)
+		var beginErr = errors.New("begin error")
+		mock.ExpectBegin().WillReturnError(beginErr)
+		var called = false
+
+		var err = InTx(beginner, "testing", func(tx *sqlx.Tx) error { called = true; return nil })
+
+		a.ErrorIs(err, beginErr)
+		a.False(called)
+	})
+}
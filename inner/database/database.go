@@ -15,10 +15,22 @@ func ConnectDb() *sqlx.DB {
 
 // ConnectDbWithCfg подключиться к базе данных с переданным конфигом
 func ConnectDbWithCfg(cfg common.Config) *sqlx.DB {
-	var db = sqlx.MustConnect(cfg.DbDriverName, cfg.Dsn)
+	var db, err = Connect(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Connect подключиться к базе данных с переданным конфигом, вернув ошибку вместо паники
+func Connect(cfg common.Config) (*sqlx.DB, error) {
+	var db, err = sqlx.Connect(cfg.DbDriverName, cfg.Dsn)
+	if err != nil {
+		return nil, err
+	}
 	db.SetMaxIdleConns(5)
 	db.SetMaxOpenConns(20)
 	db.SetConnMaxLifetime(1 * time.Minute)
 	db.SetConnMaxIdleTime(10 * time.Minute)
-	return db
+	return db, nil
 }
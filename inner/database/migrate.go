@@ -0,0 +1,20 @@
+package database
+
+import (
+	"fmt"
+	"idm/migrations"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// NewMigrator - goose-провайдер встроенных миграций. Миграции выполняются под advisory-блокировкой
+// Postgres, поэтому одновременный запуск с нескольких реплик применяет каждую миграцию один раз
+func NewMigrator(db *sqlx.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("migration lock: %w", err)
+	}
+	return goose.NewProvider(goose.DialectPostgres, db.DB, migrations.FS, goose.WithSessionLocker(locker))
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMigrator(t *testing.T) {
+	t.Run("Should collect embedded versioned migrations only", func(t *testing.T) {
+		a := assert.New(t)
+		db, _, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+
+		migrator, err := NewMigrator(sqlx.NewDb(db, "postgres"))
+		a.Nil(err)
+		var sources = migrator.ListSources()
+		a.NotEmpty(sources)
+		var versions = make([]int64, len(sources))
+		for i, s := range sources {
+			versions[i] = s.Version
+			a.NotContains(s.Path, "keycloak")
+		}
+		a.True(slices.IsSorted(versions))
+		a.Equal(int64(20250612174148), versions[0])
+	})
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/common"
+	"idm/migrations"
+	"io/fs"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+)
+
+// Режимы проверки схемы при старте, когда в БД применены не все встроенные миграции
+const (
+	// SchemaRefuse - не запускаться
+	SchemaRefuse = "refuse"
+	// SchemaMigrate - применить недостающие миграции под advisory-блокировкой
+	SchemaMigrate = "migrate"
+	// SchemaWarn - запуститься с предупреждением в логе
+	SchemaWarn = "warn"
+)
+
+// SchemaVersion - версия схемы БД относительно встроенных миграций
+type SchemaVersion struct {
+	// Current - последняя применённая миграция, 0 - миграции не применялись
+	Current int64
+	// Latest - последняя встроенная миграция
+	Latest int64
+	// Pending - встроенные миграции, не применённые в БД
+	Pending []int64
+}
+
+// Schema - сверка встроенных миграций с таблицей версий goose. В отличие от goose.Provider
+// не берёт блокировку и не создаёт таблицу, поэтому подходит для частых запросов вроде /internal/info
+type Schema struct {
+	db       *sqlx.DB
+	versions []int64
+}
+
+func NewSchema(db *sqlx.DB) (*Schema, error) {
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var versions = make([]int64, 0, len(names))
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return &Schema{db: db, versions: versions}, nil
+}
+
+// Version - текущая версия схемы и ожидающие миграции
+func (s *Schema) Version(ctx context.Context) (SchemaVersion, error) {
+	var version SchemaVersion
+	if len(s.versions) > 0 {
+		version.Latest = s.versions[len(s.versions)-1]
+	}
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, "SELECT to_regclass('goose_db_version') IS NOT NULL"); err != nil {
+		return version, err
+	}
+	var applied []int64
+	if exists {
+		// старые версии goose при откате добавляли строку с is_applied = false, поэтому берётся последняя запись
+		var err = s.db.SelectContext(ctx, &applied, `SELECT version_id FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied FROM goose_db_version ORDER BY version_id, id DESC
+		) v WHERE is_applied AND version_id > 0`)
+		if err != nil {
+			return version, err
+		}
+	}
+	for _, v := range applied {
+		version.Current = max(version.Current, v)
+	}
+	for _, v := range s.versions {
+		if !slices.Contains(applied, v) {
+			version.Pending = append(version.Pending, v)
+		}
+	}
+	return version, nil
+}
+
+// EnsureSchema - проверка схемы при старте в режиме mode: SchemaRefuse возвращает ошибку,
+// если есть ожидающие миграции, SchemaMigrate применяет их, SchemaWarn пишет предупреждение
+func EnsureSchema(ctx context.Context, db *sqlx.DB, mode string, logger *common.Logger) (SchemaVersion, error) {
+	schema, err := NewSchema(db)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+	version, err := schema.Version(ctx)
+	if err != nil {
+		return version, fmt.Errorf("reading schema version: %w", err)
+	}
+	if version.Current > version.Latest {
+		// откат приложения без отката миграций: схема новее, чем знает этот бинарник
+		logger.Warn("Database schema is newer than the application",
+			zap.Int64("schema_version", version.Current), zap.Int64("latest_migration", version.Latest))
+	}
+	if len(version.Pending) == 0 {
+		logger.Info("Database schema is up to date", zap.Int64("schema_version", version.Current))
+		return version, nil
+	}
+	switch mode {
+	case SchemaWarn:
+		logger.Warn("Database schema is behind the application, some requests may fail",
+			zap.Int64("schema_version", version.Current), zap.Int64("latest_migration", version.Latest),
+			zap.Int64s("pending", version.Pending))
+		return version, nil
+	case SchemaMigrate:
+		migrator, err := NewMigrator(db)
+		if err != nil {
+			return version, err
+		}
+		// реплики, стартующие одновременно, ждут блокировку, и миграции применяет только первая
+		results, err := migrator.Up(ctx)
+		for _, r := range results {
+			logger.Info("Migration applied", zap.Int64("version", r.Source.Version), zap.Duration("duration", r.Duration))
+		}
+		if err != nil {
+			return version, fmt.Errorf("applying migrations: %w", err)
+		}
+		return schema.Version(ctx)
+	default:
+		return version, fmt.Errorf("database schema version %d is behind %d, %d migrations pending: "+
+			"run \"idm migrate up\" or set SCHEMA_CHECK=migrate", version.Current, version.Latest, len(version.Pending))
+	}
+}
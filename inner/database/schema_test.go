@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var (
+	versionTableQuery = regexp.QuoteMeta("SELECT to_regclass('goose_db_version') IS NOT NULL")
+	appliedQuery      = "SELECT version_id FROM"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("Should report all migrations pending without version table", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		mock.ExpectQuery(versionTableQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		schema, err := NewSchema(sqlx.NewDb(db, "postgres"))
+		a.Nil(err)
+		version, err := schema.Version(context.Background())
+		a.Nil(err)
+		a.Equal(int64(0), version.Current)
+		a.Equal(schema.versions, version.Pending)
+		a.Equal(schema.versions[len(schema.versions)-1], version.Latest)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Should report migrations not applied", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		var schema = &Schema{db: sqlx.NewDb(db, "postgres"), versions: []int64{1, 2, 3}}
+		mock.ExpectQuery(versionTableQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(appliedQuery).WillReturnRows(sqlmock.NewRows([]string{"version_id"}).AddRow(1).AddRow(2))
+
+		version, err := schema.Version(context.Background())
+		a.Nil(err)
+		a.Equal(SchemaVersion{Current: 2, Latest: 3, Pending: []int64{3}}, version)
+	})
+
+	t.Run("Should return query error", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		var schema = &Schema{db: sqlx.NewDb(db, "postgres"), versions: []int64{1}}
+		mock.ExpectQuery(versionTableQuery).WillReturnError(errors.New("connection refused"))
+
+		_, err = schema.Version(context.Background())
+		a.ErrorContains(err, "connection refused")
+	})
+}
+
+func TestEnsureSchema(t *testing.T) {
+	var logger = &common.Logger{Logger: zap.NewNop()}
+
+	// expectBehind - в БД не применена ни одна встроенная миграция
+	var expectBehind = func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(versionTableQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(appliedQuery).WillReturnRows(sqlmock.NewRows([]string{"version_id"}))
+	}
+
+	t.Run("Should refuse to start with pending migrations", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		expectBehind(mock)
+
+		version, err := EnsureSchema(context.Background(), sqlx.NewDb(db, "postgres"), SchemaRefuse, logger)
+		a.ErrorContains(err, "idm migrate up")
+		a.NotEmpty(version.Pending)
+	})
+
+	t.Run("Should start with warning in warn mode", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		expectBehind(mock)
+
+		version, err := EnsureSchema(context.Background(), sqlx.NewDb(db, "postgres"), SchemaWarn, logger)
+		a.Nil(err)
+		a.NotEmpty(version.Pending)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Should start when schema is up to date", func(t *testing.T) {
+		a := assert.New(t)
+		db, mock, err := sqlmock.New()
+		a.Nil(err)
+		defer func() { _ = db.Close() }()
+		schema, err := NewSchema(sqlx.NewDb(db, "postgres"))
+		a.Nil(err)
+		var rows = sqlmock.NewRows([]string{"version_id"})
+		for _, v := range schema.versions {
+			rows.AddRow(v)
+		}
+		mock.ExpectQuery(versionTableQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(appliedQuery).WillReturnRows(rows)
+
+		version, err := EnsureSchema(context.Background(), sqlx.NewDb(db, "postgres"), SchemaRefuse, logger)
+		a.Nil(err)
+		a.Empty(version.Pending)
+		a.Equal(version.Latest, version.Current)
+	})
+}
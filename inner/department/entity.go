@@ -0,0 +1,57 @@
+package department
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Entity struct {
+	Id        int64         `db:"id"`
+	Name      string        `db:"name"`
+	ParentId  sql.NullInt64 `db:"parent_id"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	return Response{
+		Id:        e.Id,
+		Name:      e.Name,
+		ParentId:  e.ParentId.Int64,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ParentId  int64     `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// Admin - делегированный администратор отдела
+type Admin struct {
+	DepartmentId int64     `db:"department_id"`
+	EmployeeId   int64     `db:"employee_id"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (e *Admin) ToResponse() AdminResponse {
+	return AdminResponse{
+		DepartmentId: e.DepartmentId,
+		EmployeeId:   e.EmployeeId,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+type AdminResponse struct {
+	DepartmentId int64     `json:"department_id"`
+	EmployeeId   int64     `json:"employee_id"`
+	CreatedAt    time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+}
+
+type AdminRequest struct {
+	EmployeeId int64 `json:"employee_id" validate:"required,gt=0"`
+}
@@ -0,0 +1,144 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	AddAdmin(ctx context.Context, departmentId int64, request AdminRequest) (AdminResponse, error)
+	DeleteAdmin(ctx context.Context, departmentId, employeeId int64) error
+	FindAdmins(ctx context.Context, departmentId int64) ([]AdminResponse, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/departments"
+func (c *Handler) RegisterRoutes() {
+	var delegate = c.server.RequirePermission(web.PermDepartmentDelegate)
+	c.server.GroupApiV1.Get("/departments/:id/admins", delegate, c.FindAdmins)
+	c.server.GroupApiV1.Post("/departments/:id/admins", delegate, c.AddAdmin)
+	c.server.GroupApiV1.Delete("/departments/:id/admins/:employeeId", delegate, c.DeleteAdmin)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/departments/:id/admins"
+// @Description Delegate administration of the department and all its child departments to the employee.
+// @Summary add department admin
+// @Tags department
+// @Accept json
+// @Produce json
+// @Param id path int true "Department ID"
+// @Param request body AdminRequest true "employee"
+// @Success 200 {object} common.Response[department.AdminResponse]
+// @Failure 400 {object} common.Response[department.AdminResponse] "invalid request"
+// @Failure 403 {object} common.Response[department.AdminResponse] "Permission denied"
+// @Failure 404 {object} common.Response[department.AdminResponse] "department or employee not found"
+// @Failure 500 {object} common.Response[department.AdminResponse] "error db"
+// @Router /departments/{id}/admins [post]
+// @Security BearerAuth
+func (c *Handler) AddAdmin(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddAdmin: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request AdminRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddAdmin: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.AddAdmin(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddAdmin: error adding department admin", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/departments/:id/admins"
+// @Description Get admins delegated to the department. Admins of parent departments are not listed.
+// @Summary get department admins
+// @Tags department
+// @Produce json
+// @Param id path int true "Department ID"
+// @Success 200 {object} common.Response[[]department.AdminResponse]
+// @Failure 400 {object} common.Response[[]department.AdminResponse] "invalid request"
+// @Failure 403 {object} common.Response[[]department.AdminResponse] "Permission denied"
+// @Failure 404 {object} common.Response[[]department.AdminResponse] "department not found"
+// @Failure 500 {object} common.Response[[]department.AdminResponse] "error db"
+// @Router /departments/{id}/admins [get]
+// @Security BearerAuth
+func (c *Handler) FindAdmins(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAdmins: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindAdmins(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAdmins: error finding department admins", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/departments/:id/admins/:employeeId"
+// @Description Revoke delegated administration of the department from the employee.
+// @Summary delete department admin
+// @Tags department
+// @Produce json
+// @Param id path int true "Department ID"
+// @Param employeeId path int true "Employee ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /departments/{id}/admins/{employeeId} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteAdmin(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteAdmin: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	employeeId, err := strconv.ParseInt(ctx.Params("employeeId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteAdmin: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteAdmin(ctx.Context(), id, employeeId); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteAdmin: error deleting department admin", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
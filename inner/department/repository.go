@@ -0,0 +1,72 @@
+package department
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (department Entity, err error) {
+	err = r.db.GetContext(ctx, &department, "SELECT * FROM department WHERE id = $1", id)
+	return department, err
+}
+
+func (r *Repository) ExistsEmployee(ctx context.Context, id int64) (exists bool, err error) {
+	err = r.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM employee WHERE id = $1)", id)
+	return exists, err
+}
+
+// AddAdmin - назначает администратора отдела; повторное назначение возвращает существующую запись
+func (r *Repository) AddAdmin(ctx context.Context, departmentId, employeeId int64) (admin Admin, err error) {
+	err = r.db.GetContext(ctx, &admin,
+		`INSERT INTO department_admin(department_id, employee_id) VALUES ($1, $2)
+		 ON CONFLICT (department_id, employee_id) DO UPDATE SET created_at = department_admin.created_at
+		 RETURNING *`,
+		departmentId, employeeId)
+	return admin, err
+}
+
+func (r *Repository) DeleteAdmin(ctx context.Context, departmentId, employeeId int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM department_admin WHERE department_id = $1 AND employee_id = $2",
+		departmentId, employeeId)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowInter > 0, nil
+}
+
+func (r *Repository) FindAdmins(ctx context.Context, departmentId int64) (admins []Admin, err error) {
+	err = r.db.SelectContext(ctx, &admins,
+		"SELECT * FROM department_admin WHERE department_id = $1 ORDER BY employee_id", departmentId)
+	return admins, err
+}
+
+// FindAdministeredDepartments - отделы, назначенные сотруднику, вместе со всем поддеревом
+func (r *Repository) FindAdministeredDepartments(ctx context.Context, employeeId int64) (ids []int64, err error) {
+	err = r.db.SelectContext(ctx, &ids,
+		`WITH RECURSIVE tree AS (
+		     SELECT department_id AS id FROM department_admin WHERE employee_id = $1
+		     UNION
+		     SELECT d.id FROM department d JOIN tree t ON d.parent_id = t.id
+		 )
+		 SELECT id FROM tree ORDER BY id`,
+		employeeId)
+	return ids, err
+}
@@ -0,0 +1,124 @@
+package department
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/web"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type Service struct {
+	repo         Repo
+	employeeRepo EmployeeRepo
+	validator    *validator.Validate
+	logger       common.LoggerInterface
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (Entity, error)
+	ExistsEmployee(ctx context.Context, id int64) (bool, error)
+	AddAdmin(ctx context.Context, departmentId, employeeId int64) (Admin, error)
+	DeleteAdmin(ctx context.Context, departmentId, employeeId int64) (bool, error)
+	FindAdmins(ctx context.Context, departmentId int64) ([]Admin, error)
+	FindAdministeredDepartments(ctx context.Context, employeeId int64) ([]int64, error)
+}
+
+type EmployeeRepo interface {
+	FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error)
+}
+
+func NewService(repo Repo, employeeRepo EmployeeRepo, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:         repo,
+		employeeRepo: employeeRepo,
+		validator:    validator.New(),
+		logger:       logger,
+	}
+}
+
+// AddAdmin - делегирует сотруднику администрирование отдела и всех его дочерних отделов
+func (svc *Service) AddAdmin(ctx context.Context, departmentId int64, request AdminRequest) (AdminResponse, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return AdminResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if err := svc.checkDepartment(ctx, departmentId); err != nil {
+		return AdminResponse{}, err
+	}
+	exists, err := svc.repo.ExistsEmployee(ctx, request.EmployeeId)
+	if err != nil {
+		return AdminResponse{}, fmt.Errorf("Error checking employee %d existence: %w", request.EmployeeId, err)
+	}
+	if !exists {
+		return AdminResponse{}, common.NotFoundError{Message: fmt.Sprintf("Employee %d not found", request.EmployeeId)}
+	}
+	admin, err := svc.repo.AddAdmin(ctx, departmentId, request.EmployeeId)
+	if err != nil {
+		return AdminResponse{}, fmt.Errorf("Error adding admin %d of department %d: %w", request.EmployeeId, departmentId, err)
+	}
+	svc.logger.DebugCtx(ctx, "AddAdmin: department admin added")
+	return admin.ToResponse(), nil
+}
+
+func (svc *Service) DeleteAdmin(ctx context.Context, departmentId, employeeId int64) error {
+	if departmentId <= 0 || employeeId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong department %d or employee %d id", departmentId, employeeId)}
+	}
+	deleted, err := svc.repo.DeleteAdmin(ctx, departmentId, employeeId)
+	if err != nil {
+		return fmt.Errorf("Error deleting admin %d of department %d: %w", employeeId, departmentId, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("Employee %d is not admin of department %d", employeeId, departmentId)}
+	}
+	return nil
+}
+
+func (svc *Service) FindAdmins(ctx context.Context, departmentId int64) ([]AdminResponse, error) {
+	if err := svc.checkDepartment(ctx, departmentId); err != nil {
+		return nil, err
+	}
+	admins, err := svc.repo.FindAdmins(ctx, departmentId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding admins of department %d: %w", departmentId, err)
+	}
+	responses := make([]AdminResponse, 0, len(admins))
+	for _, admin := range admins {
+		responses = append(responses, admin.ToResponse())
+	}
+	return responses, nil
+}
+
+func (svc *Service) checkDepartment(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id department: %d", id)}
+	}
+	_, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return common.NotFoundError{Message: fmt.Sprintf("Department %d not found", id)}
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding department %d: %w", id, err)
+	}
+	return nil
+}
+
+// FindAdministeredDepartments - отделы, которыми вызывающий управляет как администратор отдела,
+// вместе со всеми дочерними отделами; реализует web.DelegationResolver
+func (svc *Service) FindAdministeredDepartments(ctx context.Context, claims *web.IdmClaims) ([]int64, error) {
+	if claims == nil {
+		return nil, nil
+	}
+	caller, err := svc.employeeRepo.FindByIdentity(ctx, claims.Subject, claims.PreferredUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error finding employee linked to the token: %w", err)
+	}
+	return svc.repo.FindAdministeredDepartments(ctx, caller.Id)
+}
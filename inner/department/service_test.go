@@ -0,0 +1,215 @@
+package department
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/web"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsEmployee(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) AddAdmin(ctx context.Context, departmentId, employeeId int64) (Admin, error) {
+	args := m.Called(ctx, departmentId, employeeId)
+	return args.Get(0).(Admin), args.Error(1)
+}
+
+func (m *MockRepo) DeleteAdmin(ctx context.Context, departmentId, employeeId int64) (bool, error) {
+	args := m.Called(ctx, departmentId, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindAdmins(ctx context.Context, departmentId int64) ([]Admin, error) {
+	args := m.Called(ctx, departmentId)
+	return args.Get(0).([]Admin), args.Error(1)
+}
+
+func (m *MockRepo) FindAdministeredDepartments(ctx context.Context, employeeId int64) ([]int64, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+type MockEmployeeRepo struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeRepo) FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error) {
+	args := m.Called(ctx, subject, username)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func TestAddAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add department admin", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		now := time.Now()
+		repo.On("FindById", ctx, int64(3)).Return(Entity{Id: 3, Name: "Accounting"}, nil)
+		repo.On("ExistsEmployee", ctx, int64(7)).Return(true, nil)
+		repo.On("AddAdmin", ctx, int64(3), int64(7)).Return(Admin{DepartmentId: 3, EmployeeId: 7, CreatedAt: now}, nil)
+
+		got, err := svc.AddAdmin(ctx, 3, AdminRequest{EmployeeId: 7})
+
+		a.NoError(err)
+		a.Equal(AdminResponse{DepartmentId: 3, EmployeeId: 7, CreatedAt: now}, got)
+	})
+
+	t.Run("Should return not found for unknown department", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		repo.On("FindById", ctx, int64(3)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.AddAdmin(ctx, 3, AdminRequest{EmployeeId: 7})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		repo.AssertNotCalled(t, "AddAdmin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return not found for unknown employee", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		repo.On("FindById", ctx, int64(3)).Return(Entity{Id: 3}, nil)
+		repo.On("ExistsEmployee", ctx, int64(7)).Return(false, nil)
+
+		_, err := svc.AddAdmin(ctx, 3, AdminRequest{EmployeeId: 7})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		repo.AssertNotCalled(t, "AddAdmin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should validate request", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+
+		_, err := svc.AddAdmin(ctx, 3, AdminRequest{})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		repo.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should delete department admin", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		repo.On("DeleteAdmin", ctx, int64(3), int64(7)).Return(true, nil)
+
+		assert.NoError(t, svc.DeleteAdmin(ctx, 3, 7))
+	})
+
+	t.Run("Should return not found when employee is not admin", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		repo.On("DeleteAdmin", ctx, int64(3), int64(7)).Return(false, nil)
+
+		err := svc.DeleteAdmin(ctx, 3, 7)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestFindAdmins(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return empty list", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+		repo.On("FindById", ctx, int64(3)).Return(Entity{Id: 3}, nil)
+		repo.On("FindAdmins", ctx, int64(3)).Return([]Admin(nil), nil)
+
+		got, err := svc.FindAdmins(ctx, 3)
+
+		a.NoError(err)
+		a.Equal([]AdminResponse{}, got)
+	})
+}
+
+func TestFindAdministeredDepartments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should resolve departments by token subject and username", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(repo, employees, &MockLogger{})
+		var claims = &web.IdmClaims{PreferredUsername: "jdoe"}
+		claims.Subject = "f81d4fae"
+		employees.On("FindByIdentity", ctx, "f81d4fae", "jdoe").Return(employee.Entity{Id: 7}, nil)
+		repo.On("FindAdministeredDepartments", ctx, int64(7)).Return([]int64{3, 4}, nil)
+
+		got, err := svc.FindAdministeredDepartments(ctx, claims)
+
+		a.NoError(err)
+		a.Equal([]int64{3, 4}, got)
+	})
+
+	t.Run("Should return no departments when no employee is linked", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(repo, employees, &MockLogger{})
+		var claims = &web.IdmClaims{PreferredUsername: "jdoe"}
+		employees.On("FindByIdentity", ctx, "", "jdoe").Return(employee.Entity{}, sql.ErrNoRows)
+
+		got, err := svc.FindAdministeredDepartments(ctx, claims)
+
+		a.NoError(err)
+		a.Empty(got)
+		repo.AssertNotCalled(t, "FindAdministeredDepartments", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return no departments without claims", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockEmployeeRepo), &MockLogger{})
+
+		got, err := svc.FindAdministeredDepartments(ctx, nil)
+
+		a.NoError(err)
+		a.Empty(got)
+		repo.AssertNotCalled(t, "FindAdministeredDepartments", mock.Anything, mock.Anything)
+	})
+}
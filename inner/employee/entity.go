@@ -1,12 +1,22 @@
 package employee
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type Entity struct {
-	Id      int64  `db:"id"`
-	Name    string `db:"name"`
-	Surname string `db:"surname"`
-	Age     int8   `db:"age"`
+	Id           int64          `db:"id"`
+	Name         string         `db:"name"`
+	Surname      string         `db:"surname"`
+	Age          int8           `db:"age"`
+	Login        sql.NullString `db:"login"`
+	Email        string         `db:"email"`
+	Phone        string         `db:"phone"`
+	DepartmentId sql.NullInt64  `db:"department_id"`
+	ManagerId    sql.NullInt64  `db:"manager_id"`
+	Position     string         `db:"position"`
+	Active       bool           `db:"active"`
 	// @example 2025-07-29T12:00:00Z
 	CreatedAt time.Time `db:"created_at" example:"2025-07-29T12:00:00Z"`
 	// @example 2025-07-29T12:00:00Z
@@ -14,50 +24,97 @@ type Entity struct {
 }
 
 type CreateRequest struct {
-	Name      string    `json:"name" validate:"required,min=2,max=155"`
-	Surname   string    `json:"surname" validate:"required,min=2,max=155"`
-	Age       int8      `json:"age" validate:"required,min=16,max=90"`
+	Name         string `json:"name" validate:"required,min=2,max=155"`
+	Surname      string `json:"surname" validate:"required,min=2,max=155"`
+	Age          int8   `json:"age" validate:"required,min=16,max=90"`
+	Login        string `json:"login" validate:"omitempty,min=2,max=155"`
+	Email        string `json:"email" validate:"omitempty,email"`
+	Phone        string `json:"phone" validate:"omitempty,max=32"`
+	DepartmentId int64  `json:"department_id" validate:"omitempty,min=1"`
+	ManagerId    int64  `json:"manager_id" validate:"omitempty,min=1"`
+	Position     string `json:"position" validate:"max=155"`
+	// Active - сотрудник создаётся активным, если не передано иное
+	Active    *bool     `json:"active"`
 	CreatedAt time.Time `json:"created_at" validate:"required" example:"2025-07-29T12:00:00Z"`
 	UpdatedAt time.Time `json:"updated_at" validate:"required" example:"2025-07-29T12:00:00Z"`
 }
 
 func (req *CreateRequest) ToEntity() Entity {
 	return Entity{Name: req.Name,
-		Surname:   req.Surname,
-		Age:       req.Age,
-		CreatedAt: req.CreatedAt,
-		UpdatedAt: req.UpdatedAt}
+		Surname:      req.Surname,
+		Age:          req.Age,
+		Login:        sql.NullString{String: req.Login, Valid: req.Login != ""},
+		Email:        req.Email,
+		Phone:        req.Phone,
+		DepartmentId: sql.NullInt64{Int64: req.DepartmentId, Valid: req.DepartmentId > 0},
+		ManagerId:    sql.NullInt64{Int64: req.ManagerId, Valid: req.ManagerId > 0},
+		Position:     req.Position,
+		Active:       req.Active == nil || *req.Active,
+		CreatedAt:    req.CreatedAt,
+		UpdatedAt:    req.UpdatedAt}
+}
+
+// MoveRequest - перевод сотрудника в другой отдел и/или на другую должность;
+// не указанные отдел и руководитель остаются прежними
+type MoveRequest struct {
+	DepartmentId int64  `json:"department_id" validate:"omitempty,min=1"`
+	Position     string `json:"position" validate:"max=155"`
+	ManagerId    int64  `json:"manager_id" validate:"omitempty,min=1"`
+}
+
+// ProfileUpdate - поля профиля, которые сотрудник может менять самостоятельно
+type ProfileUpdate struct {
+	Email *string
+	Phone *string
 }
 
 func (e *Entity) ToResponse() Response {
 	return Response{
-		Id:        e.Id,
-		Name:      e.Name,
-		Surname:   e.Surname,
-		Age:       e.Age,
-		CreatedAt: e.CreatedAt,
-		UpdatedAt: e.UpdatedAt,
+		Id:           e.Id,
+		Name:         e.Name,
+		Surname:      e.Surname,
+		Age:          e.Age,
+		Login:        e.Login.String,
+		Email:        e.Email,
+		Phone:        e.Phone,
+		DepartmentId: e.DepartmentId.Int64,
+		ManagerId:    e.ManagerId.Int64,
+		Position:     e.Position,
+		Active:       e.Active,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
 	}
 }
 
 type Response struct {
-	Id        int64     `json:"id" query:"id"`
-	Name      string    `json:"name" query:"name"`
-	Surname   string    `json:"surname" query:"surname"`
-	Age       int8      `json:"age" query:"age"`
-	CreatedAt time.Time `json:"created_at" query:"created_at" example:"2025-07-29T12:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" query:"updated_at" example:"2025-07-29T12:00:00Z"`
+	Id           int64     `json:"id" query:"id"`
+	Name         string    `json:"name" query:"name"`
+	Surname      string    `json:"surname" query:"surname"`
+	Age          int8      `json:"age" query:"age"`
+	Login        string    `json:"login,omitempty" query:"login"`
+	Email        string    `json:"email,omitempty" query:"email"`
+	Phone        string    `json:"phone,omitempty" query:"phone"`
+	DepartmentId int64     `json:"department_id,omitempty" query:"department_id"`
+	ManagerId    int64     `json:"manager_id,omitempty" query:"manager_id"`
+	Position     string    `json:"position,omitempty" query:"position"`
+	Active       bool      `json:"active" query:"active"`
+	CreatedAt    time.Time `json:"created_at" query:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt    time.Time `json:"updated_at" query:"updated_at" example:"2025-07-29T12:00:00Z"`
 }
 
 type PageRequest struct {
 	PageNumber int    `json:"page_number" query:"page_number" validate:"min=0"`
 	PageSize   int    `json:"page_size" query:"page_size" validate:"min=1,max=100"`
 	TextFilter string `json:"text_filter" query:"text_filter"`
+	// AsOf - момент в формате RFC 3339, на который нужно состояние сотрудников; пусто - текущее.
+	// Разбирается хендлером так же, как as_of у FindById, и передаётся в FindAllWithLimitOffsetAsOf
+	AsOf string `json:"as_of" query:"as_of"`
 }
 
 type PageResponse struct {
 	Result     []Response `json:"result" query:"result"`
 	TextFilter string     `json:"text_filter" query:"text_filter"`
+	AsOf       string     `json:"as_of,omitempty" query:"as_of"`
 	PageSize   int        `json:"page_size" query:"page_size"`
 	PageNum    int        `json:"page_num" query:"page_num"`
 	Total      int64      `json:"total" query:"total"`
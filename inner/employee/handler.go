@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"idm/inner/common"
+	"idm/inner/sod"
 	"idm/inner/web"
-	"slices"
 	"strconv"
 	"time"
 
@@ -30,6 +30,10 @@ type Svc interface {
 	DeleteById(ctx context.Context, id int64) (Response, error)
 	FindAll(ctx context.Context) (employees []Response, err error)
 	FindAllWithLimitOffset(ctx context.Context, req PageRequest) (result PageResponse, err error)
+	FindAllWithLimitOffsetAsOf(ctx context.Context, req PageRequest, asOf time.Time) (result PageResponse, err error)
+	Move(ctx context.Context, id int64, request MoveRequest) (Response, error)
+	FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (Response, error)
+	FindHistory(ctx context.Context, id int64) ([]HistoryResponse, error)
 }
 
 func NewHandler(server *web.Server, employeeService Svc, logger *common.Logger) *Handler {
@@ -46,10 +50,12 @@ func (c *Handler) RegisterRoutes() {
 	c.Server.GroupApiV1.Post("/employees/add", c.AddEmployee)
 	c.Server.GroupApiV1.Post("/employees/ids", c.FindByIds)
 	c.Server.GroupApiV1.Post("/employees/:id", c.FindById)
+	c.Server.GroupApiV1.Post("/employees/:id/move", c.Move)
 	c.Server.GroupApiV1.Delete("/employees/ids", c.DeleteByIds)
 	c.Server.GroupApiV1.Delete("/employees/:id", c.DeleteById)
 	c.Server.GroupApiV1.Get("/employees", c.FindAll)
 	c.Server.GroupApiV1.Get("/employees/page", c.FindByPagesWithFilter)
+	c.Server.GroupApiV1.Get("/employees/:id/history", c.FindHistory)
 }
 
 // Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees"
@@ -61,14 +67,14 @@ func (c *Handler) RegisterRoutes() {
 // @Param request body CreateRequest true "create employee request"
 // @Success 200 {object} common.Response[employee.Entity]
 // @Failure 400 {object} common.Response[employee.Entity] "invalid request"
+// @Failure 409 {object} common.Response[employee.Entity] "SoD violation"
 // @Failure 500 {object} common.Response[employee.Entity] "error db"
 // @Router /employees [post]
 // @Security BearerAuth
 func (c *Handler) CreateEmployee(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeWrite)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	var request CreateRequest
 	if err := ctx.BodyParser(&request); err != nil {
@@ -76,19 +82,54 @@ func (c *Handler) CreateEmployee(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	c.logger.DebugCtx(ctx.Context(), "Create employee: received request", zap.Any("request", request))
-	var newEmployeeId, err = c.employeeService.CreateEmployee(ctx.Context(), request)
+	newEmployeeId, err := c.employeeService.CreateEmployee(scoped, request)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "CreateEmployee: error creating", zap.Error(err))
-		switch {
-		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return errResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, newEmployeeId)
 }
 
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/:id/move"
+// @Description Move employee to another department and/or position and re-apply birthright rules.
+// @Summary move employee
+// @Tags employee
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param request body MoveRequest true "new department and position"
+// @Success 200 {object} common.Response[employee.Response]
+// @Failure 400 {object} common.Response[employee.Response] "invalid request"
+// @Failure 403 {object} common.Response[employee.Response] "Permission denied"
+// @Failure 404 {object} common.Response[employee.Response] "employee not found"
+// @Failure 409 {object} common.Response[employee.Response] "SoD violation"
+// @Failure 500 {object} common.Response[employee.Response] "error db"
+// @Router /employees/{id}/move [post]
+// @Security BearerAuth
+func (c *Handler) Move(ctx *fiber.Ctx) error {
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeWrite)
+	if !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Move: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request MoveRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Move: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Move: received request", zap.Int64("id", id), zap.Any("request", request))
+	moved, err := c.employeeService.Move(scoped, id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Move: error moving employee", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, moved)
+}
+
 // Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/add"
 // @Description Create a new employee with secure transaction.
 // @Summary create a new employee with transaction
@@ -102,10 +143,9 @@ func (c *Handler) CreateEmployee(ctx *fiber.Ctx) error {
 // @Router /employees/add [post]
 // @Security BearerAuth
 func (c *Handler) AddEmployee(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeWrite)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	var entity Entity
 	if err := ctx.BodyParser(&entity); err != nil {
@@ -115,10 +155,10 @@ func (c *Handler) AddEmployee(ctx *fiber.Ctx) error {
 		})
 	}
 	c.logger.DebugCtx(ctx.Context(), "AddEmployee: receive entity", zap.Any("entity", entity))
-	var newEmployeeId, err = c.employeeService.Add(ctx.Context(), entity)
+	newEmployeeId, err := c.employeeService.Add(scoped, entity)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "AddEmployee: error adding", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return scopeErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, newEmployeeId)
 }
@@ -130,16 +170,18 @@ func (c *Handler) AddEmployee(ctx *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Employee ID"
+// @Param as_of query string false "State as of the moment, RFC 3339"
 // @Success 200 {object} common.Response[employee.Entity]
 // @Failure 400 {object} common.Response[employee.Entity] "invalid request"
+// @Failure 403 {object} common.Response[employee.Entity] "Permission denied"
+// @Failure 404 {object} common.Response[employee.Entity] "employee did not exist at as_of"
 // @Failure 500 {object} common.Response[employee.Entity] "error db"
 // @Router /employees/{id} [post]
 // @Security BearerAuth
 func (c *Handler) FindById(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeRead)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	idParam := ctx.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -148,14 +190,55 @@ func (c *Handler) FindById(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	c.logger.DebugCtx(ctx.Context(), "FindById: receive idParam", zap.Any("idParam", idParam))
-	employee, err := c.employeeService.FindById(ctx.Context(), id)
+	var employee Response
+	if asOfParam := ctx.Query("as_of"); asOfParam != "" {
+		asOf, parseErr := parseAsOf(asOfParam)
+		if parseErr != nil {
+			c.logger.ErrorCtx(ctx.Context(), "FindById: invalid as_of", zap.Error(parseErr))
+			return errResponse(ctx, parseErr)
+		}
+		employee, err = c.employeeService.FindByIdAsOf(scoped, id, asOf)
+	} else {
+		employee, err = c.employeeService.FindById(scoped, id)
+	}
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return errResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, employee)
 }
 
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/history"
+// @Description Employee versions with field-level changes between consecutive versions. History of deleted employees is kept.
+// @Summary employee history
+// @Tags employee
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} common.Response[[]employee.HistoryResponse]
+// @Failure 400 {object} common.Response[[]employee.HistoryResponse] "invalid request"
+// @Failure 403 {object} common.Response[[]employee.HistoryResponse] "Permission denied"
+// @Failure 404 {object} common.Response[[]employee.HistoryResponse] "employee not found"
+// @Failure 500 {object} common.Response[[]employee.HistoryResponse] "error db"
+// @Router /employees/{id}/history [get]
+// @Security BearerAuth
+func (c *Handler) FindHistory(ctx *fiber.Ctx) error {
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeRead)
+	if !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindHistory: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	history, err := c.employeeService.FindHistory(scoped, id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindHistory: error finding history", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, history)
+}
+
 // Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/ids"
 // @Description Find employees by ids.
 // @Summary find employees
@@ -169,10 +252,9 @@ func (c *Handler) FindById(ctx *fiber.Ctx) error {
 // @Router /employees/ids [post]
 // @Security BearerAuth
 func (c *Handler) FindByIds(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeRead)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	var ids []int64
 	if err := ctx.BodyParser(&ids); err != nil {
@@ -180,7 +262,7 @@ func (c *Handler) FindByIds(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 	c.logger.DebugCtx(ctx.Context(), "FindByIds: receive ids", zap.Any("ids", ids))
-	employees, err := c.employeeService.FindByIds(ctx.Context(), ids)
+	employees, err := c.employeeService.FindByIds(scoped, ids)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "FindByIds: error finding", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error finding employees")
@@ -201,10 +283,9 @@ func (c *Handler) FindByIds(ctx *fiber.Ctx) error {
 // @Router /employees/{id} [delete]
 // @Security BearerAuth
 func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeDelete)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	idParam := ctx.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -213,10 +294,10 @@ func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	c.logger.DebugCtx(ctx.Context(), "DeleteById: receive idParam", zap.Any("idParam", idParam))
-	rsl, err := c.employeeService.DeleteById(ctx.Context(), id)
+	rsl, err := c.employeeService.DeleteById(scoped, id)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error deleting", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return scopeErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, rsl)
 }
@@ -234,10 +315,9 @@ func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
 // @Router /employees/ids [delete]
 // @Security BearerAuth
 func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, ctx.Context(), web.PermEmployeeDelete)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	bodyBytes := ctx.Body()
 	var ids []int64
@@ -246,10 +326,10 @@ func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 	c.logger.DebugCtx(ctx.Context(), "DeleteByIds: receive ids", zap.Any("ids", ids))
-	rsl, err := c.employeeService.DeleteByIds(ctx.Context(), ids)
+	rsl, err := c.employeeService.DeleteByIds(scoped, ids)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "DeleteByIds: error deleting", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return scopeErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, rsl)
 }
@@ -266,12 +346,11 @@ func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
 // @Router /employees [get]
 // @Security BearerAuth
 func (c *Handler) FindAll(ctx *fiber.Ctx) error {
-	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
-	var claims = token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, context.Background(), web.PermEmployeeRead)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
-	con, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	con, cancel := context.WithTimeout(scoped, 5*time.Second)
 	defer cancel()
 	employees, err := c.employeeService.FindAll(con)
 	if err != nil {
@@ -294,6 +373,7 @@ func (c *Handler) FindAll(ctx *fiber.Ctx) error {
 // @Param page_number query int false "Page number"
 // @Param page_size query int false "Page size"
 // @Param text_filter query string false "Text filter"
+// @Param as_of query string false "State as of the moment, RFC 3339"
 // @Success 200 {object} PageResponse[]
 // @Failure 400 {object} PageResponse[]
 // @Failure 408 {object} PageResponse[] "time out request"
@@ -314,8 +394,9 @@ func (c *Handler) FindByPagesWithFilter(ctx *fiber.Ctx) error {
 	if claims.ExpiresAt != nil && time.Now().After(claims.ExpiresAt.Time) {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, "Token expired")
 	}
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	scoped, ok, err := c.scoped(ctx, context.Background(), web.PermEmployeeRead)
+	if !ok {
+		return web.DenyResponse(ctx, err)
 	}
 	var request PageRequest
 	if err := ctx.QueryParser(&request); err != nil {
@@ -324,9 +405,19 @@ func (c *Handler) FindByPagesWithFilter(ctx *fiber.Ctx) error {
 	}
 	c.logger.DebugCtx(ctx.Context(), "FindByPagesWithFilter: received page request", zap.Any("request", request))
 
-	con, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	con, cancel := context.WithTimeout(scoped, 8*time.Second)
 	defer cancel()
-	employees, err := c.employeeService.FindAllWithLimitOffset(con, request)
+	var employees PageResponse
+	if request.AsOf != "" {
+		asOf, parseErr := parseAsOf(request.AsOf)
+		if parseErr != nil {
+			c.logger.ErrorCtx(ctx.Context(), "FindByPagesWithFilter: invalid as_of", zap.Error(parseErr))
+			return errResponse(ctx, parseErr)
+		}
+		employees, err = c.employeeService.FindAllWithLimitOffsetAsOf(con, request, asOf)
+	} else {
+		employees, err = c.employeeService.FindAllWithLimitOffset(con, request)
+	}
 	if err != nil {
 		var validationErr common.RequestValidationError
 		if ok := errors.As(err, &validationErr); ok {
@@ -342,3 +433,53 @@ func (c *Handler) FindByPagesWithFilter(ctx *fiber.Ctx) error {
 	}
 	return common.OkResponse(ctx, employees)
 }
+
+// scoped - контекст вызова сервиса. С разрешением permission вызывающий действует без ограничений,
+// иначе - как делегированный администратор в пределах своих отделов. ok=false - доступа нет
+func (c *Handler) scoped(ctx *fiber.Ctx, parent context.Context, permission string) (context.Context, bool, error) {
+	if ok, err := c.Server.HasPermission(ctx, permission); ok || err != nil {
+		return parent, ok, err
+	}
+	departments, err := c.Server.AdministeredDepartments(ctx)
+	if err != nil || len(departments) == 0 {
+		return nil, false, err
+	}
+	return WithScope(parent, Scope{DepartmentIds: departments}), true, nil
+}
+
+// parseAsOf - момент as_of из query: RFC 3339, доли секунды допускаются. Один разбор для всех
+// ручек, чтобы FindById и страница сотрудников принимали одни и те же значения
+func parseAsOf(value string) (time.Time, error) {
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, common.RequestValidationError{Message: "Invalid as_of, expected RFC 3339 timestamp"}
+	}
+	return asOf, nil
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.ForbiddenError{}):
+		return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	case errors.As(err, &sod.ViolationError{}):
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
+
+// scopeErrResponse - ответ для хендлеров, где любая ошибка сервиса, кроме выхода за пределы
+// делегированных отделов и нарушения SoD правилами назначения по должности, считается внутренней
+func scopeErrResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.ForbiddenError{}):
+		return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.As(err, &sod.ViolationError{}):
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+	}
+	return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+}
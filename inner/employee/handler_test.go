@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"idm/inner/common"
+	"idm/inner/sod"
 	"idm/inner/web"
 	"io"
 	"net/http"
@@ -66,6 +67,26 @@ func (m *MockService) FindAllWithLimitOffset(ctx context.Context, req PageReques
 	return args.Get(0).(PageResponse), args.Error(1)
 }
 
+func (m *MockService) FindAllWithLimitOffsetAsOf(ctx context.Context, req PageRequest, asOf time.Time) (PageResponse, error) {
+	args := m.Called(ctx, req, asOf)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+func (m *MockService) Move(ctx context.Context, id int64, request MoveRequest) (Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (Response, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]HistoryResponse), args.Error(1)
+}
+
 func TestCreateEmployee(t *testing.T) {
 	a := assert.New(t)
 	logger := &common.Logger{
@@ -151,6 +172,28 @@ func TestCreateEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("Should return 409 when birthright rules violate SoD", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+		body := strings.NewReader(`{"name": "John", "surname": "Doe", "age": 25}`)
+		var violation = sod.ViolationError{Violations: []sod.Violation{
+			{RuleName: "payments", Mode: sod.ModeBlock, RoleNames: []string{"payer", "approver"}},
+		}}
+		svc.On("CreateEmployee", mock.Anything, mock.Anything).Return(int64(0), violation)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Contains(string(bytesData), "payments")
+	})
+
 	t.Run("Should return 500 on unknown internal error", func(t *testing.T) {
 		t.Parallel()
 		server := web.NewServer()
@@ -706,6 +749,119 @@ func TestFindByIdEmployee(t *testing.T) {
 		a.Nil(err)
 		a.Equal(http.StatusForbidden, resp.StatusCode)
 	})
+
+	t.Run("Should find employee as of moment", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		asOf := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+		svc.On("FindByIdAsOf", mock.Anything, int64(2), asOf).Return(Response{Id: 2}, nil)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/2?as_of=2025-08-01T12:00:00Z", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return 400 when as_of is not a timestamp", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/2?as_of=yesterday", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Should accept as_of with fractional seconds", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		asOf := time.Date(2025, 8, 1, 12, 0, 0, 500_000_000, time.UTC)
+		svc.On("FindByIdAsOf", mock.Anything, int64(2), asOf).Return(Response{Id: 2}, nil)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/2?as_of=2025-08-01T12:00:00.5Z", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Should return 404 when employee did not exist at as_of", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		svc.On("FindByIdAsOf", mock.Anything, int64(2), mock.Anything).
+			Return(Response{}, common.NotFoundError{Message: "not found"})
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/2?as_of=2020-01-01T00:00:00Z", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestFindHistoryEmployee(t *testing.T) {
+	a := assert.New(t)
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+
+	var claims = &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}},
+	}
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+	t.Run("Should return employee history", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		history := []HistoryResponse{{Version: 1, Operation: OperationInsert,
+			Changes: []FieldChange{{Field: "name", New: "John"}}}}
+		svc.On("FindHistory", mock.Anything, int64(2)).Return(history, nil)
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/2/history", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var body common.Response[[]HistoryResponse]
+		a.Nil(json.NewDecoder(resp.Body).Decode(&body))
+		a.Equal(history, body.Data)
+	})
+
+	t.Run("Should return 404 when employee has no history", func(t *testing.T) {
+		t.Parallel()
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := Handler{Server: server, employeeService: svc, logger: logger}
+		handler.RegisterRoutes()
+
+		svc.On("FindHistory", mock.Anything, int64(2)).
+			Return([]HistoryResponse(nil), common.NotFoundError{Message: "not found"})
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/2/history", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
 }
 
 func TestFindAllEmployees(t *testing.T) {
@@ -1017,4 +1173,41 @@ func TestFindAllEmployeesWithLimitOffset(t *testing.T) {
 		defer resp.Body.Close()
 		a.Equal(fiber.StatusUnauthorized, resp.StatusCode)
 	})
+
+	t.Run("Should find page as of moment with fractional seconds", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := NewHandler(server, svc, &common.Logger{Logger: zap.NewNop()})
+		handler.RegisterRoutes()
+
+		asOf := time.Date(2025, 8, 1, 12, 0, 0, 500_000_000, time.UTC)
+		svc.On("FindAllWithLimitOffsetAsOf", mock.Anything, PageRequest{PageSize: 10, AsOf: "2025-08-01T12:00:00.5Z"}, asOf).
+			Return(PageResponse{Total: 1}, nil)
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/page?page_size=10&as_of=2025-08-01T12:00:00.5Z", nil)
+
+		resp, err := server.App.Test(req, -1)
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+		svc.AssertNotCalled(t, "FindAllWithLimitOffset", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return 400 BadRequest when as_of is not a timestamp", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := web.NewServer()
+		server.GroupApi.Use(auth)
+		svc := new(MockService)
+		handler := NewHandler(server, svc, &common.Logger{Logger: zap.NewNop()})
+		handler.RegisterRoutes()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/page?page_size=10&as_of=yesterday", nil)
+
+		resp, err := server.App.Test(req, -1)
+		a.Nil(err)
+		a.Equal(fiber.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "FindAllWithLimitOffsetAsOf", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
@@ -0,0 +1,117 @@
+package employee
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Операции, после которых записана версия сотрудника
+const (
+	OperationInsert = "INSERT"
+	OperationUpdate = "UPDATE"
+	OperationDelete = "DELETE"
+)
+
+// untrackedFields - поля, которые меняются при каждом изменении и в различия не попадают
+var untrackedFields = []string{"id", "created_at", "updated_at"}
+
+// Version - версия записи сотрудника, которую пишет триггер таблицы employee. Data - запись
+// целиком в JSON с именами колонок; для DELETE - последнее состояние перед удалением
+type Version struct {
+	Id         int64          `db:"id"`
+	EmployeeId int64          `db:"employee_id"`
+	Version    int            `db:"version"`
+	Operation  string         `db:"operation"`
+	Data       types.JSONText `db:"data"`
+	ValidFrom  time.Time      `db:"valid_from"`
+}
+
+// departmentId - отдел сотрудника в этой версии
+func (v Version) departmentId() (sql.NullInt64, error) {
+	var data struct {
+		DepartmentId *int64 `json:"department_id"`
+	}
+	if err := json.Unmarshal(v.Data, &data); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("Error decoding version %d of employee %d: %w", v.Version, v.EmployeeId, err)
+	}
+	if data.DepartmentId == nil {
+		return sql.NullInt64{}, nil
+	}
+	return sql.NullInt64{Int64: *data.DepartmentId, Valid: true}, nil
+}
+
+// FieldChange - изменение поля между соседними версиями; Old или New равен null, если
+// значения не было
+type FieldChange struct {
+	Field string `json:"field" example:"department_id"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type HistoryResponse struct {
+	Version   int           `json:"version"`
+	Operation string        `json:"operation" example:"UPDATE"`
+	ValidFrom time.Time     `json:"valid_from" example:"2025-07-29T12:00:00Z"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// toHistory - версии с различиями относительно предыдущей версии. Версия INSERT содержит
+// все заполненные поля, DELETE - без изменений полей
+func toHistory(versions []Version) ([]HistoryResponse, error) {
+	var responses = make([]HistoryResponse, 0, len(versions))
+	var previous map[string]any
+	for _, v := range versions {
+		var current map[string]any
+		if err := json.Unmarshal(v.Data, &current); err != nil {
+			return nil, fmt.Errorf("Error decoding version %d of employee %d: %w", v.Version, v.EmployeeId, err)
+		}
+		var changes = []FieldChange{}
+		if v.Operation != OperationDelete {
+			changes = diff(previous, current)
+		}
+		responses = append(responses, HistoryResponse{
+			Version:   v.Version,
+			Operation: v.Operation,
+			ValidFrom: v.ValidFrom,
+			Changes:   changes,
+		})
+		previous = current
+		if v.Operation == OperationDelete {
+			// сотрудник с тем же id мог появиться снова только вставкой, сравнивать не с чем
+			previous = nil
+		}
+	}
+	return responses, nil
+}
+
+// diff - различающиеся поля двух версий в алфавитном порядке
+func diff(previous, current map[string]any) []FieldChange {
+	var fields = make([]string, 0, len(current))
+	for field := range current {
+		fields = append(fields, field)
+	}
+	for field := range previous {
+		if _, ok := current[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	var changes = []FieldChange{}
+	for _, field := range fields {
+		if slices.Contains(untrackedFields, field) {
+			continue
+		}
+		var old, value = previous[field], current[field]
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: old, New: value})
+	}
+	return changes
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Repository struct {
@@ -24,8 +25,8 @@ func (r *Repository) BeginTr() (*sqlx.Tx, error) {
 }
 
 func (r *Repository) Add(tx *sqlx.Tx, employee Entity) (id int64, err error) {
-	query := `INSERT INTO employee(name, surname, age, created_at, updated_at) 
-			  VALUES (:name, :surname, :age, :created_at, :updated_at) 
+	query := `INSERT INTO employee(name, surname, age, login, email, phone, department_id, manager_id, position, active, created_at, updated_at) 
+			  VALUES (:name, :surname, :age, :login, :email, :phone, :department_id, :manager_id, :position, :active, :created_at, :updated_at) 
 			  RETURNING id`
 	rows, err := tx.NamedQuery(query, &employee)
 
@@ -51,6 +52,43 @@ func (r *Repository) FindById(id int64) (employee Entity, err error) {
 	return employee, err
 }
 
+// FindByIdentity - сотрудник вызывающего пользователя: по login = subject (sub токена), а если
+// такого нет - по login = username (preferred_username). Пустые значения ни с кем не совпадают
+func (r *Repository) FindByIdentity(ctx context.Context, subject, username string) (employee Entity, err error) {
+	err = r.db.GetContext(ctx, &employee,
+		"SELECT * FROM employee WHERE login <> '' AND login IN ($1, $2) ORDER BY login = $1 DESC LIMIT 1",
+		subject, username)
+	return employee, err
+}
+
+// UpdateProfile - обновляет только переданные (не nil) поля профиля сотрудника
+func (r *Repository) UpdateProfile(tx *sqlx.Tx, id int64, update ProfileUpdate) (employee Entity, err error) {
+	err = tx.Get(&employee,
+		`UPDATE employee
+		 SET email = COALESCE($2, email),
+		     phone = COALESCE($3, phone),
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		id, update.Email, update.Phone)
+	return employee, err
+}
+
+// Move - переводит сотрудника в отдел и на должность из запроса; отдел и руководитель меняются,
+// только если переданы
+func (r *Repository) Move(tx *sqlx.Tx, id int64, request MoveRequest) (employee Entity, err error) {
+	err = tx.Get(&employee,
+		`UPDATE employee
+		 SET department_id = COALESCE(NULLIF($2, 0), department_id),
+		     position = $3,
+		     manager_id = COALESCE(NULLIF($4, 0), manager_id),
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		id, request.DepartmentId, request.Position, request.ManagerId)
+	return employee, err
+}
+
 func (r *Repository) FindAll(ctx context.Context) (employees []Entity, err error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
@@ -61,24 +99,83 @@ func (r *Repository) FindAll(ctx context.Context) (employees []Entity, err error
 	return employees, nil
 }
 
-func (r *Repository) FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string) ([]Entity, int64, error) {
+func (r *Repository) FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64) ([]Entity, int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
+	const where = `WHERE ($1 = '' OR name ILIKE '%' || $1 || '%')
+		AND ($2::BIGINT[] IS NULL OR department_id = ANY($2))`
 	var employees []Entity
 	err := r.db.SelectContext(ctx, &employees,
-		"SELECT * FROM employee WHERE ($1 = '' OR name ILIKE '%' || $1 || '%') ORDER BY id ASC LIMIT $2 OFFSET $3",
-		filter, limit, offset)
+		"SELECT * FROM employee "+where+" ORDER BY id ASC LIMIT $3 OFFSET $4",
+		filter, pq.Array(departmentIds), limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	var total int64
-	err = r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM employee WHERE ($1 = '' OR name ILIKE '%' || $1 || '%')", filter)
+	err = r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM employee "+where, filter, pq.Array(departmentIds))
 	if err != nil {
 		return nil, 0, err
 	}
 	return employees, total, nil
 }
 
+// snapshotAsOf - сотрудники в состоянии на момент $3: последняя версия каждого, начавшая
+// действовать не позже $3, кроме удалённых к этому моменту
+const snapshotAsOf = `WITH snapshot AS (
+	SELECT e.*
+	FROM (SELECT DISTINCT ON (employee_id) data, operation
+	      FROM employee_history
+	      WHERE valid_from <= $3
+	      ORDER BY employee_id, version DESC) h,
+	     LATERAL jsonb_populate_record(NULL::employee, h.data) e
+	WHERE h.operation <> 'DELETE')
+`
+
+// FindWithLimitOffsetAndFilterAsOf - то же, что FindWithLimitOffsetAndFilter, по состоянию на момент asOf
+func (r *Repository) FindWithLimitOffsetAndFilterAsOf(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64, asOf time.Time) ([]Entity, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+	const where = `WHERE ($1 = '' OR name ILIKE '%' || $1 || '%')
+		AND ($2::BIGINT[] IS NULL OR department_id = ANY($2))`
+	var employees []Entity
+	err := r.db.SelectContext(ctx, &employees,
+		snapshotAsOf+"SELECT * FROM snapshot "+where+" ORDER BY id ASC LIMIT $4 OFFSET $5",
+		filter, pq.Array(departmentIds), asOf, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int64
+	err = r.db.GetContext(ctx, &total, snapshotAsOf+"SELECT COUNT(*) FROM snapshot "+where,
+		filter, pq.Array(departmentIds), asOf)
+	if err != nil {
+		return nil, 0, err
+	}
+	return employees, total, nil
+}
+
+// FindByIdAsOf - сотрудник в состоянии на момент asOf; sql.ErrNoRows, если его тогда ещё
+// не было или он уже был удалён
+func (r *Repository) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (employee Entity, err error) {
+	err = r.db.GetContext(ctx, &employee,
+		`SELECT e.*
+		 FROM (SELECT data, operation
+		       FROM employee_history
+		       WHERE employee_id = $1 AND valid_from <= $2
+		       ORDER BY version DESC
+		       LIMIT 1) h,
+		      LATERAL jsonb_populate_record(NULL::employee, h.data) e
+		 WHERE h.operation <> 'DELETE'`,
+		id, asOf)
+	return employee, err
+}
+
+// FindHistory - все версии сотрудника в порядке их появления
+func (r *Repository) FindHistory(ctx context.Context, id int64) (versions []Version, err error) {
+	err = r.db.SelectContext(ctx, &versions,
+		"SELECT * FROM employee_history WHERE employee_id = $1 ORDER BY version", id)
+	return versions, err
+}
+
 func (r *Repository) FindBySliceIds(ids []int64) (employees []Entity, err error) {
 	query, args, err := sqlx.In("SELECT * FROM employee WHERE id IN (?)", ids)
 	if err != nil {
@@ -92,8 +189,8 @@ func (r *Repository) FindBySliceIds(ids []int64) (employees []Entity, err error)
 	return employees, nil
 }
 
-func (r *Repository) DeleteById(id int64) (bool, error) {
-	result, err := r.db.Exec("DELETE FROM employee WHERE id = $1", id)
+func (r *Repository) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.Exec("DELETE FROM employee WHERE id = $1", id)
 	if err != nil {
 		return false, err
 	}
@@ -104,17 +201,18 @@ func (r *Repository) DeleteById(id int64) (bool, error) {
 	return rowInter > 0, nil
 }
 
-func (r *Repository) DeleteBySliceIds(ids []int64) ([]int64, error) {
+func (r *Repository) DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error) {
 	query, args, err := sqlx.In("DELETE FROM employee WHERE id IN (?) RETURNING id", ids)
 	if err != nil {
 		return nil, nil
 	}
 
-	query = r.db.Rebind(query)
-	rows, err := r.db.Queryx(query, args...)
+	query = tx.Rebind(query)
+	rows, err := tx.Queryx(query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer func() { _ = rows.Close() }()
 
 	var deletedIDs []int64
 	for rows.Next() {
@@ -0,0 +1,31 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+)
+
+// Scope - ограничение делегированного администратора: доступны только сотрудники отделов
+// DepartmentIds (поддерево отделов, которыми он управляет). Сотрудники без отдела недоступны
+type Scope struct {
+	DepartmentIds []int64
+}
+
+// Contains - входит ли отдел в область администратора
+func (s Scope) Contains(departmentId sql.NullInt64) bool {
+	return departmentId.Valid && slices.Contains(s.DepartmentIds, departmentId.Int64)
+}
+
+type scopeKey struct{}
+
+// WithScope - контекст вызова сервиса от имени делегированного администратора
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromCtx - ограничение вызывающего; ok=false - вызов без ограничений
+func ScopeFromCtx(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
@@ -2,8 +2,12 @@ package employee
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"idm/inner/common"
+	"idm/inner/outbox"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jmoiron/sqlx"
@@ -14,6 +18,29 @@ type Service struct {
 	repo      Repo
 	validator *validator.Validate
 	logger    common.LoggerInterface
+	// Rules - правила базового доступа, применяемые при создании и переводе сотрудника;
+	// nil - роли по правилам не выдаются
+	Rules BirthrightRules
+	// Provisioning - передача изменений во внешние системы после фиксации транзакции;
+	// nil - изменения не передаются
+	Provisioning Provisioner
+	// Events - запись доменных событий в outbox в транзакции изменения; nil - события не пишутся
+	Events Outbox
+}
+
+// BirthrightRules - выдача и отзыв ролей по правилам базового доступа в транзакции вызывающего
+type BirthrightRules interface {
+	ApplyInTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+// Outbox - хранилище доменных событий, публикуемых после фиксации транзакции
+type Outbox interface {
+	Add(tx *sqlx.Tx, event outbox.Entity) error
 }
 
 type Repo interface {
@@ -21,11 +48,16 @@ type Repo interface {
 	FindById(id int64) (Entity, error)
 	FindAll(ctx context.Context) ([]Entity, error)
 	FindBySliceIds(ids []int64) ([]Entity, error)
-	DeleteById(id int64) (bool, error)
-	DeleteBySliceIds(ids []int64) ([]int64, error)
+	DeleteById(tx *sqlx.Tx, id int64) (bool, error)
+	DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error)
 	BeginTr() (*sqlx.Tx, error)
 	FindByNameAndSurname(tx *sqlx.Tx, name, surname string) (isExists bool, err error)
-	FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string) (employees []Entity, total int64, err error)
+	// FindWithLimitOffsetAndFilter - departmentIds ограничивает выборку отделами, nil - без ограничения
+	FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64) (employees []Entity, total int64, err error)
+	Move(tx *sqlx.Tx, id int64, request MoveRequest) (Entity, error)
+	FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (Entity, error)
+	FindWithLimitOffsetAndFilterAsOf(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64, asOf time.Time) (employees []Entity, total int64, err error)
+	FindHistory(ctx context.Context, id int64) ([]Version, error)
 }
 
 type Validator interface {
@@ -49,9 +81,59 @@ func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
 	if err != nil {
 		return Response{}, fmt.Errorf("Error finding employee with id %d: %w", id, err)
 	}
+	if err = checkScope(ctx, entity.DepartmentId); err != nil {
+		return Response{}, err
+	}
+	return entity.ToResponse(), nil
+}
+
+// FindByIdAsOf - сотрудник в состоянии на момент asOf. Область делегированного администратора
+// проверяется по отделу, в котором сотрудник был в тот момент
+func (svc *Service) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id: %d", id)}
+	}
+	var entity, err = svc.repo.FindByIdAsOf(ctx, id, asOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{
+			Message: fmt.Sprintf("Employee with id %d not found as of %s", id, asOf.Format(time.RFC3339)),
+		}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding employee with id %d as of %s: %w", id, asOf.Format(time.RFC3339), err)
+	}
+	if err = checkScope(ctx, entity.DepartmentId); err != nil {
+		return Response{}, err
+	}
 	return entity.ToResponse(), nil
 }
 
+// FindHistory - версии сотрудника с различиями полей между соседними версиями. История удалённого
+// сотрудника тоже доступна; область делегированного администратора проверяется по отделу
+// из последней версии
+func (svc *Service) FindHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	if id <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id: %d", id)}
+	}
+	versions, err := svc.repo.FindHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding history of employee %d: %w", id, err)
+	}
+	if len(versions) == 0 {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("Employee with id %d not found", id)}
+	}
+	if _, ok := ScopeFromCtx(ctx); ok {
+		departmentId, err := versions[len(versions)-1].departmentId()
+		if err != nil {
+			return nil, err
+		}
+		if err = checkScope(ctx, departmentId); err != nil {
+			return nil, outOfScope(id)
+		}
+	}
+	return toHistory(versions)
+}
+
 func (svc *Service) Add(ctx context.Context, employee Entity) (response Response, err error) {
 	if employee == (Entity{}) {
 		return Response{}, fmt.Errorf("Entity is empty, please check the employee")
@@ -59,6 +141,11 @@ func (svc *Service) Add(ctx context.Context, employee Entity) (response Response
 	if employee.Name == "" || employee.Surname == "" || employee.Age <= 16 {
 		return Response{}, fmt.Errorf("Invalid field, please check the employee %+v", employee)
 	}
+	if err = checkScope(ctx, employee.DepartmentId); err != nil {
+		return Response{}, err
+	}
+	// активность задаётся только через CreateRequest, здесь сотрудник всегда создаётся активным
+	employee.Active = true
 
 	tx, err := svc.repo.BeginTr()
 	if err != nil || tx == nil {
@@ -81,6 +168,8 @@ func (svc *Service) Add(ctx context.Context, employee Entity) (response Response
 			errTx := tx.Commit()
 			if errTx != nil {
 				err = fmt.Errorf("Сreating employee: commiting transaction error: %w", errTx)
+			} else {
+				svc.provision(ctx, response.Id)
 			}
 		}
 	}()
@@ -97,23 +186,33 @@ func (svc *Service) Add(ctx context.Context, employee Entity) (response Response
 	if err != nil {
 		return Response{}, fmt.Errorf("Failed to add employee: %w", err)
 	}
-
-	return Response{
+	if err = svc.applyRules(ctx, tx, id); err != nil {
+		return Response{}, err
+	}
+	response = Response{
 		Id:        id,
 		Name:      employee.Name,
 		Surname:   employee.Surname,
 		Age:       employee.Age,
 		CreatedAt: employee.CreatedAt,
 		UpdatedAt: employee.UpdatedAt,
-	}, nil
+	}
+	if err = svc.event(tx, outbox.EmployeeCreated, id, response); err != nil {
+		return Response{}, err
+	}
+	return response, nil
 }
 
-func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (int64, error) {
+func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (id int64, err error) {
 
-	var err = svc.validator.Struct(request)
+	err = svc.validator.Struct(request)
 	if err != nil {
 		return 0, common.RequestValidationError{Message: err.Error()}
 	}
+	var entity = request.ToEntity()
+	if err = checkScope(ctx, entity.DepartmentId); err != nil {
+		return 0, err
+	}
 
 	tx, err := svc.repo.BeginTr()
 	if err != nil || tx == nil {
@@ -136,6 +235,8 @@ func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (
 			errTx := tx.Commit()
 			if errTx != nil {
 				err = fmt.Errorf("Сreating employee: commiting transaction error: %w", errTx)
+			} else {
+				svc.provision(ctx, id)
 			}
 		}
 	}()
@@ -153,13 +254,111 @@ func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (
 		}
 	}
 
-	newEmployeeId, err := svc.repo.Add(tx, request.ToEntity())
+	newEmployeeId, err := svc.repo.Add(tx, entity)
 	if err != nil {
 		err = fmt.Errorf("Error creating employee with name and sruanem: %s  %s %v", request.Name, request.Surname, err)
+		return newEmployeeId, err
+	}
+	if err = svc.applyRules(ctx, tx, newEmployeeId); err != nil {
+		return newEmployeeId, err
 	}
+	entity.Id = newEmployeeId
+	err = svc.event(tx, outbox.EmployeeCreated, newEmployeeId, entity.ToResponse())
 	return newEmployeeId, err
 }
 
+// Move - переводит сотрудника в другой отдел или на другую должность и пересчитывает
+// роли, выданные правилами базового доступа
+func (svc *Service) Move(ctx context.Context, id int64, request MoveRequest) (response Response, err error) {
+	if err = svc.checkMove(ctx, id, request); err != nil {
+		return Response{}, err
+	}
+	err = common.InTx(svc.repo, "Moving employee", func(tx *sqlx.Tx) (err error) {
+		response, err = svc.move(ctx, tx, id, request)
+		return err
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.provision(ctx, id)
+	return response, nil
+}
+
+// MoveInTx - то же, что Move, но в транзакции вызывающего: используется процессами, которые должны
+// перевести сотрудника атомарно со своими изменениями. Передачу во внешние системы после фиксации
+// транзакции выполняет вызывающий
+func (svc *Service) MoveInTx(ctx context.Context, tx *sqlx.Tx, id int64, request MoveRequest) (Response, error) {
+	if err := svc.checkMove(ctx, id, request); err != nil {
+		return Response{}, err
+	}
+	return svc.move(ctx, tx, id, request)
+}
+
+// checkMove - проверка запроса перевода и области делегированного администратора
+func (svc *Service) checkMove(ctx context.Context, id int64, request MoveRequest) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id: %d", id)}
+	}
+	if err := svc.validator.Struct(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	if err := svc.checkScopeById(ctx, id); err != nil {
+		return err
+	}
+	if request.DepartmentId > 0 {
+		return checkScope(ctx, sql.NullInt64{Int64: request.DepartmentId, Valid: true})
+	}
+	return nil
+}
+
+func (svc *Service) move(ctx context.Context, tx *sqlx.Tx, id int64, request MoveRequest) (Response, error) {
+	moved, err := svc.repo.Move(tx, id, request)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error moving employee with id %d: %w", id, err)
+	}
+	if err = svc.applyRules(ctx, tx, id); err != nil {
+		return Response{}, err
+	}
+	var response = moved.ToResponse()
+	if err = svc.event(tx, outbox.EmployeeMoved, id, response); err != nil {
+		return Response{}, err
+	}
+	return response, nil
+}
+
+func (svc *Service) applyRules(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	if svc.Rules == nil {
+		return nil
+	}
+	if err := svc.Rules.ApplyInTx(ctx, tx, employeeId); err != nil {
+		return fmt.Errorf("Error applying birthright rules to employee %d: %w", employeeId, err)
+	}
+	return nil
+}
+
+func (svc *Service) event(tx *sqlx.Tx, eventType string, employeeId int64, payload any) error {
+	if svc.Events == nil {
+		return nil
+	}
+	event, err := outbox.NewEvent(outbox.AggregateEmployee, employeeId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("Error building event %s: %w", eventType, err)
+	}
+	if err = svc.Events.Add(tx, event); err != nil {
+		return fmt.Errorf("Error writing event %s: %w", eventType, err)
+	}
+	return nil
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil && len(employeeIds) > 0 {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
 func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
 	if len(ids) == 0 {
 		return []Response{}, fmt.Errorf("No employees ids provided")
@@ -171,7 +370,9 @@ func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, err
 
 	responses := make([]Response, 0, len(rsl))
 	for _, e := range rsl {
-		responses = append(responses, e.ToResponse())
+		if checkScope(ctx, e.DepartmentId) == nil {
+			responses = append(responses, e.ToResponse())
+		}
 	}
 	return responses, nil
 }
@@ -180,10 +381,34 @@ func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) ([]Response, e
 	if len(ids) == 0 {
 		return []Response{}, fmt.Errorf("No employees ids provided")
 	}
-	rsl, err := svc.repo.DeleteBySliceIds(ids)
+	if scope, ok := ScopeFromCtx(ctx); ok {
+		found, err := svc.repo.FindBySliceIds(ids)
+		if err != nil {
+			return []Response{}, fmt.Errorf("Error finding employees by ids %+v: %w", ids, err)
+		}
+		for _, e := range found {
+			if !scope.Contains(e.DepartmentId) {
+				return []Response{}, outOfScope(e.Id)
+			}
+		}
+	}
+	var rsl []int64
+	var err = common.InTx(svc.repo, "deleting employees", func(tx *sqlx.Tx) (err error) {
+		rsl, err = svc.repo.DeleteBySliceIds(tx, ids)
+		if err != nil {
+			return fmt.Errorf("Error deleting employees by ids %+v: %w", ids, err)
+		}
+		for _, id := range rsl {
+			if err = svc.event(tx, outbox.EmployeeDeleted, id, outbox.Deleted{Id: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return []Response{}, fmt.Errorf("Error deleting employees by ids %+v: %w", ids, err)
+		return []Response{}, err
 	}
+	svc.provision(ctx, rsl...)
 	responses := make([]Response, 0, len(rsl))
 	for _, id := range rsl {
 		responses = append(responses, Response{Id: id})
@@ -195,10 +420,20 @@ func (svc *Service) DeleteById(ctx context.Context, id int64) (Response, error)
 	if id <= 0 {
 		return Response{}, fmt.Errorf("Wrong id: %d", id)
 	}
-	var rsl, err = svc.repo.DeleteById(id)
-	if err != nil || !rsl {
-		return Response{}, fmt.Errorf("Error deleting employee with id %d: %w", id, err)
+	if err := svc.checkScopeById(ctx, id); err != nil {
+		return Response{}, err
+	}
+	var err = common.InTx(svc.repo, "deleting employee", func(tx *sqlx.Tx) error {
+		var rsl, err = svc.repo.DeleteById(tx, id)
+		if err != nil || !rsl {
+			return fmt.Errorf("Error deleting employee with id %d: %w", id, err)
+		}
+		return svc.event(tx, outbox.EmployeeDeleted, id, outbox.Deleted{Id: id})
+	})
+	if err != nil {
+		return Response{}, err
 	}
+	svc.provision(ctx, id)
 	return Response{Id: id}, nil
 }
 
@@ -208,18 +443,50 @@ func (svc *Service) FindAll(ctx context.Context) (employees []Response, err erro
 		return []Response{}, fmt.Errorf("Error finding employees: %w", err)
 	}
 	for _, e := range rsl {
-		employees = append(employees, e.ToResponse())
+		if checkScope(ctx, e.DepartmentId) == nil {
+			employees = append(employees, e.ToResponse())
+		}
 	}
 	return employees, nil
 }
 
 func (svc *Service) FindAllWithLimitOffset(ctx context.Context, req PageRequest) (result PageResponse, err error) {
+	return svc.findPage(ctx, req, func(limit, offset int64, departmentIds []int64) ([]Entity, int64, error) {
+		return svc.repo.FindWithLimitOffsetAndFilter(ctx, limit, offset, req.TextFilter, departmentIds)
+	})
+}
+
+// FindAllWithLimitOffsetAsOf - страница сотрудников в состоянии на момент asOf
+func (svc *Service) FindAllWithLimitOffsetAsOf(ctx context.Context, req PageRequest, asOf time.Time) (result PageResponse, err error) {
+	result, err = svc.findPage(ctx, req, func(limit, offset int64, departmentIds []int64) ([]Entity, int64, error) {
+		return svc.repo.FindWithLimitOffsetAndFilterAsOf(ctx, limit, offset, req.TextFilter, departmentIds, asOf)
+	})
+	if err != nil {
+		return PageResponse{}, err
+	}
+	result.AsOf = asOf.Format(time.RFC3339Nano)
+	return result, nil
+}
+
+// findPage - страница сотрудников, доступных вызывающему; find выбирает текущее или прошлое состояние
+func (svc *Service) findPage(
+	ctx context.Context,
+	req PageRequest,
+	find func(limit, offset int64, departmentIds []int64) ([]Entity, int64, error),
+) (PageResponse, error) {
 	if err := svc.validator.Struct(req); err != nil {
 		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
 	}
 	limit := req.PageSize
 	offset := req.PageNumber * req.PageSize
-	entities, total, err := svc.repo.FindWithLimitOffsetAndFilter(ctx, int64(limit), int64(offset), req.TextFilter)
+	var departmentIds []int64
+	if scope, ok := ScopeFromCtx(ctx); ok {
+		departmentIds = scope.DepartmentIds
+		if departmentIds == nil {
+			departmentIds = []int64{}
+		}
+	}
+	entities, total, err := find(int64(limit), int64(offset), departmentIds)
 	if err != nil {
 		return PageResponse{}, fmt.Errorf("Error finding employees with limit/offset: %w", err)
 	}
@@ -236,3 +503,35 @@ func (svc *Service) FindAllWithLimitOffset(ctx context.Context, req PageRequest)
 		TextFilter: req.TextFilter,
 	}, nil
 }
+
+// checkScope - сотрудник отдела departmentId доступен вызывающему
+func checkScope(ctx context.Context, departmentId sql.NullInt64) error {
+	scope, ok := ScopeFromCtx(ctx)
+	if !ok || scope.Contains(departmentId) {
+		return nil
+	}
+	return common.ForbiddenError{Message: "Department is outside of administered departments"}
+}
+
+// checkScopeById - отдел существующего сотрудника входит в область делегированного администратора
+func (svc *Service) checkScopeById(ctx context.Context, id int64) error {
+	scope, ok := ScopeFromCtx(ctx)
+	if !ok {
+		return nil
+	}
+	entity, err := svc.repo.FindById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return common.NotFoundError{Message: fmt.Sprintf("Employee with id %d not found", id)}
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding employee with id %d: %w", id, err)
+	}
+	if !scope.Contains(entity.DepartmentId) {
+		return outOfScope(id)
+	}
+	return nil
+}
+
+func outOfScope(id int64) error {
+	return common.ForbiddenError{Message: fmt.Sprintf("Employee %d is outside of administered departments", id)}
+}
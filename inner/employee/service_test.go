@@ -2,8 +2,12 @@ package employee
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"idm/inner/testutil"
 	"testing"
 	"time"
 
@@ -49,13 +53,13 @@ func (m *MockEmployeeRepo) FindAll(context.Context) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockEmployeeRepo) DeleteById(id int64) (bool, error) {
-	args := m.Called(id)
+func (m *MockEmployeeRepo) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(tx, id)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockEmployeeRepo) DeleteBySliceIds(ids []int64) ([]int64, error) {
-	args := m.Called(ids)
+func (m *MockEmployeeRepo) DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	args := m.Called(tx, ids)
 	return args.Get(0).([]int64), args.Error(1)
 }
 
@@ -64,11 +68,57 @@ func (m *MockEmployeeRepo) FindBySliceIds(ids []int64) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockEmployeeRepo) FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string) (employees []Entity, total int64, err error) {
-	args := m.Called(ctx, limit, offset, filter)
+func (m *MockEmployeeRepo) FindWithLimitOffsetAndFilter(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64) (employees []Entity, total int64, err error) {
+	args := m.Called(ctx, limit, offset, filter, departmentIds)
+	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockEmployeeRepo) Move(tx *sqlx.Tx, id int64, request MoveRequest) (Entity, error) {
+	args := m.Called(tx, id, request)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockEmployeeRepo) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (Entity, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockEmployeeRepo) FindWithLimitOffsetAndFilterAsOf(ctx context.Context, limit int64, offset int64, filter string, departmentIds []int64, asOf time.Time) (employees []Entity, total int64, err error) {
+	args := m.Called(ctx, limit, offset, filter, departmentIds, asOf)
 	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockEmployeeRepo) FindHistory(ctx context.Context, id int64) ([]Version, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]Version), args.Error(1)
+}
+
+type MockRules struct {
+	mock.Mock
+}
+
+func (m *MockRules) ApplyInTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	args := m.Called(ctx, tx, employeeId)
+	return args.Error(0)
+}
+
+type MockProvisioner struct {
+	mock.Mock
+}
+
+func (m *MockProvisioner) Provision(ctx context.Context, employeeIds ...int64) {
+	m.Called(ctx, employeeIds)
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Add(tx *sqlx.Tx, event outbox.Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
 type MockLogger struct{}
 
 func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
@@ -242,33 +292,85 @@ func TestFindAll(t *testing.T) {
 }
 
 func TestDeleteById(t *testing.T) {
-	a := assert.New(t)
-	repo := new(MockEmployeeRepo)
-	mockLogger := &MockLogger{}
-	svc := NewService(repo, mockLogger)
 	ctx := context.Background()
 	t.Run("Should delete employee", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(1)).Return(true, nil)
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
 		got, err := svc.DeleteById(ctx, 1)
 		a.Nil(err)
 		a.Equal(Response{Id: 1}, got)
+		a.NoError(mockTr.ExpectationsWereMet())
 	})
 
 	t.Run("Should return error if id <= 0", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(0)).Return(false, errors.New("Wrong id: 1"))
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
 		got, err := svc.DeleteById(ctx, 0)
 		a.Equal(Response{}, got)
 		a.Error(err)
+		repo.AssertNotCalled(t, "BeginTr")
 	})
 
 	t.Run("Should return error if any employee field is empty", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(5)).Return(false, errors.New("Error deleting employee with id"))
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(5)).Return(false, errors.New("Error deleting employee with id"))
 		got, err := svc.DeleteById(ctx, 5)
 		a.Equal(Response{}, got)
 		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should write event in deletion transaction", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		events := new(MockOutbox)
+		svc := NewService(repo, &MockLogger{})
+		svc.Events = events
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
+		events.On("Add", tx, mock.Anything).Return(nil)
+
+		_, err := svc.DeleteById(ctx, 1)
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		var event = events.Calls[0].Arguments.Get(1).(outbox.Entity)
+		a.Equal(outbox.AggregateEmployee, event.AggregateType)
+		a.Equal(int64(1), event.AggregateId)
+		a.Equal(outbox.EmployeeDeleted, event.Type)
+		a.JSONEq(`{"id":1}`, string(event.Payload))
+	})
+
+	t.Run("Should rollback deletion when event is not written", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		events := new(MockOutbox)
+		svc := NewService(repo, &MockLogger{})
+		svc.Events = events
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
+		events.On("Add", tx, mock.Anything).Return(errors.New("outbox unavailable"))
+
+		_, err := svc.DeleteById(ctx, 1)
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
 	})
 }
 
@@ -321,27 +423,321 @@ func TestFindByIds(t *testing.T) {
 }
 
 func TestDeleteByIds(t *testing.T) {
-	a := assert.New(t)
-	mockRepo := new(MockEmployeeRepo)
-	mockLogger := &MockLogger{}
-	svc := NewService(mockRepo, mockLogger)
 	ctx := context.Background()
 	t.Run("Should delete employee", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		mockRepo := new(MockEmployeeRepo)
+		events := new(MockOutbox)
+		svc := NewService(mockRepo, &MockLogger{})
+		svc.Events = events
+		tx, mockTr := testutil.NewTx(t, true)
 		ids := []int64{1, 2}
-		mockRepo.On("DeleteBySliceIds", ids).Return(ids, nil)
+		mockRepo.On("BeginTr").Return(tx, nil)
+		mockRepo.On("DeleteBySliceIds", tx, ids).Return(ids, nil)
+		events.On("Add", tx, mock.Anything).Return(nil)
 		got, err := svc.DeleteByIds(ctx, ids)
 		expected := []Response{{Id: 1}, {Id: 2}}
 		a.Nil(err)
 		a.Equal(expected, got)
+		a.NoError(mockTr.ExpectationsWereMet())
+		events.AssertNumberOfCalls(t, "Add", 2)
 	})
 
 	t.Run("Should return error if ids is empty", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		mockRepo := new(MockEmployeeRepo)
+		svc := NewService(mockRepo, &MockLogger{})
 		var ids []int64
-		mockRepo.On("DeleteBySliceIds", ids).Return(nil, errors.New("Wrong ids"))
 		got, err := svc.DeleteByIds(ctx, ids)
 		a.Empty(got)
 		a.Error(err)
+		mockRepo.AssertNotCalled(t, "BeginTr")
+	})
+}
+
+func TestMove(t *testing.T) {
+	ctx := context.Background()
+	request := MoveRequest{DepartmentId: 3, Position: "accountant"}
+
+	t.Run("Should move employee and apply birthright rules", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		rules := new(MockRules)
+		provisioner := new(MockProvisioner)
+		svc := NewService(repo, &MockLogger{})
+		svc.Rules = rules
+		svc.Provisioning = provisioner
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Move", tx, int64(1), request).Return(Entity{Id: 1, Name: "John",
+			DepartmentId: sql.NullInt64{Int64: 3, Valid: true}, Position: "accountant"}, nil)
+		rules.On("ApplyInTx", ctx, tx, int64(1)).Return(nil)
+		provisioner.On("Provision", ctx, []int64{1}).Return()
+
+		got, err := svc.Move(ctx, 1, request)
+
+		a.NoError(err)
+		a.Equal(int64(3), got.DepartmentId)
+		a.Equal("accountant", got.Position)
+		a.NoError(mockTr.ExpectationsWereMet())
+		rules.AssertExpectations(t)
+		provisioner.AssertExpectations(t)
+	})
+
+	t.Run("Should rollback move when rules fail", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		rules := new(MockRules)
+		provisioner := new(MockProvisioner)
+		svc := NewService(repo, &MockLogger{})
+		svc.Rules = rules
+		svc.Provisioning = provisioner
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Move", tx, int64(1), request).Return(Entity{Id: 1}, nil)
+		rules.On("ApplyInTx", ctx, tx, int64(1)).Return(errors.New("sod violation"))
+
+		_, err := svc.Move(ctx, 1, request)
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		provisioner.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
 	})
+
+	t.Run("Should keep department in scope when it is omitted", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		scoped := WithScope(ctx, Scope{DepartmentIds: []int64{4}})
+		var positionOnly = MoveRequest{Position: "lead"}
+		var current = Entity{Id: 1, DepartmentId: sql.NullInt64{Int64: 4, Valid: true}}
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("FindById", int64(1)).Return(current, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Move", tx, int64(1), positionOnly).Return(Entity{Id: 1,
+			DepartmentId: sql.NullInt64{Int64: 4, Valid: true}, Position: "lead"}, nil)
+
+		got, err := svc.Move(scoped, 1, positionOnly)
+
+		a.NoError(err)
+		a.Equal(int64(4), got.DepartmentId)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should return NotFoundError for unknown employee", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Move", tx, int64(9), request).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.Move(ctx, 9, request)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
+func TestScope(t *testing.T) {
+	scoped := WithScope(context.Background(), Scope{DepartmentIds: []int64{3, 4}})
+	inScope := Entity{Id: 1, Name: "John", Surname: "Doe", Age: 30, DepartmentId: sql.NullInt64{Int64: 4, Valid: true}}
+	outScope := Entity{Id: 2, Name: "Jane", Surname: "Roe", Age: 30, DepartmentId: sql.NullInt64{Int64: 9, Valid: true}}
+
+	t.Run("Should forbid finding employee outside administered departments", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindById", int64(2)).Return(outScope, nil)
+
+		_, err := svc.FindById(scoped, 2)
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+	})
+
+	t.Run("Should filter employees outside administered departments", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindAll").Return([]Entity{inScope, outScope, {Id: 3, Name: "No", Surname: "Department"}}, nil)
+
+		got, err := svc.FindAll(scoped)
+
+		a.NoError(err)
+		a.Len(got, 1)
+		a.Equal(int64(1), got[0].Id)
+	})
+
+	t.Run("Should reject deleting when any employee is outside administered departments", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindBySliceIds", []int64{1, 2}).Return([]Entity{inScope, outScope}, nil)
+
+		_, err := svc.DeleteByIds(scoped, []int64{1, 2})
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		repo.AssertNotCalled(t, "DeleteBySliceIds", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should delete employee inside administered departments", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		tx, _ := testutil.NewTx(t, true)
+		repo.On("FindById", int64(1)).Return(inScope, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
+
+		_, err := svc.DeleteById(scoped, 1)
+
+		a.NoError(err)
+	})
+
+	t.Run("Should forbid creating employee outside administered departments", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+
+		_, err := svc.CreateEmployee(scoped, CreateRequest{Name: "Jane", Surname: "Roe", Age: 30, DepartmentId: 9,
+			CreatedAt: time.Now(), UpdatedAt: time.Now()})
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		repo.AssertNotCalled(t, "BeginTr")
+	})
+}
+
+func TestHistory(t *testing.T) {
+	var ctx = context.Background()
+	var asOf = time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	var versions = []Version{
+		{EmployeeId: 7, Version: 1, Operation: OperationInsert, ValidFrom: asOf.Add(-2 * time.Hour),
+			Data: []byte(`{"id": 7, "name": "John", "email": "", "department_id": 4, "updated_at": "2025-08-01T10:00:00Z"}`)},
+		{EmployeeId: 7, Version: 2, Operation: OperationUpdate, ValidFrom: asOf.Add(-time.Hour),
+			Data: []byte(`{"id": 7, "name": "John", "email": "john@example.com", "department_id": 9, "updated_at": "2025-08-01T11:00:00Z"}`)},
+		{EmployeeId: 7, Version: 3, Operation: OperationDelete, ValidFrom: asOf,
+			Data: []byte(`{"id": 7, "name": "John", "email": "john@example.com", "department_id": 9, "updated_at": "2025-08-01T11:00:00Z"}`)},
+	}
+
+	t.Run("Should return field changes between versions", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindHistory", ctx, int64(7)).Return(versions, nil)
+
+		got, err := svc.FindHistory(ctx, 7)
+
+		a.NoError(err)
+		a.Len(got, 3)
+		a.Equal([]FieldChange{
+			{Field: "department_id", New: float64(4)},
+			{Field: "email", New: ""},
+			{Field: "name", New: "John"},
+		}, got[0].Changes)
+		a.Equal([]FieldChange{
+			{Field: "department_id", Old: float64(4), New: float64(9)},
+			{Field: "email", Old: "", New: "john@example.com"},
+		}, got[1].Changes)
+		a.Equal(OperationDelete, got[2].Operation)
+		a.Empty(got[2].Changes)
+	})
+
+	t.Run("Should return not found error when employee has no versions", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindHistory", ctx, int64(8)).Return([]Version{}, nil)
+
+		_, err := svc.FindHistory(ctx, 8)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should check scope by department of the last version", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		scoped := WithScope(ctx, Scope{DepartmentIds: []int64{4}})
+		repo.On("FindHistory", scoped, int64(7)).Return(versions, nil)
+
+		_, err := svc.FindHistory(scoped, 7)
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+	})
+
+	t.Run("Should find employee as of moment", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		scoped := WithScope(ctx, Scope{DepartmentIds: []int64{4}})
+		repo.On("FindByIdAsOf", scoped, int64(7), asOf).
+			Return(Entity{Id: 7, Name: "John", DepartmentId: sql.NullInt64{Int64: 4, Valid: true}}, nil)
+
+		got, err := svc.FindByIdAsOf(scoped, 7, asOf)
+
+		a.NoError(err)
+		a.Equal(int64(4), got.DepartmentId)
+	})
+
+	t.Run("Should return not found error when employee did not exist at the moment", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindByIdAsOf", ctx, int64(7), asOf).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.FindByIdAsOf(ctx, 7, asOf)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should find page as of moment", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockEmployeeRepo)
+		svc := NewService(repo, &MockLogger{})
+		repo.On("FindWithLimitOffsetAndFilterAsOf", ctx, int64(10), int64(0), "", []int64(nil), asOf).
+			Return([]Entity{{Id: 7, Name: "John"}}, int64(1), nil)
+
+		got, err := svc.FindAllWithLimitOffsetAsOf(ctx, PageRequest{PageSize: 10}, asOf)
+
+		a.NoError(err)
+		a.Equal(int64(1), got.Total)
+		a.Equal("2025-08-01T12:00:00Z", got.AsOf)
+		repo.AssertNotCalled(t, "FindWithLimitOffsetAndFilter", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCreateRequestToEntity(t *testing.T) {
+	var inactive = false
+	tests := []struct {
+		name   string
+		active *bool
+		want   bool
+	}{
+		{name: "Should create active employee by default", active: nil, want: true},
+		{name: "Should create inactive employee when requested", active: &inactive, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := CreateRequest{Name: "Jane", Surname: "Roe", Age: 30, Active: tt.active}
+
+			assert.Equal(t, tt.want, request.ToEntity().Active)
+		})
+	}
 }
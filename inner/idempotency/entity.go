@@ -0,0 +1,35 @@
+package idempotency
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Header - заголовок с ключом идемпотентности, который клиент генерирует на каждую операцию
+// и повторяет при повторных попытках
+const Header = "Idempotency-Key"
+
+// ReplayedHeader - признак ответа, воспроизведённого из сохранённого, а не выполненного заново
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxKeyLength - максимальная длина ключа; UUID и подобные ключи укладываются с запасом
+const maxKeyLength = 255
+
+// lease - сколько выполняющийся запрос держит ключ: если экземпляр упал, не успев сохранить
+// ответ или освободить ключ, после этого срока повтор выполнит запрос заново. Не связан с ttl
+// сохранённого ответа
+const lease = 2 * time.Minute
+
+// Entity - ключ идемпотентности и сохранённый ответ; StatusCode NULL - запрос ещё выполняется,
+// пока не истёк LockedUntil
+type Entity struct {
+	Owner       string        `db:"owner"`
+	Key         string        `db:"key"`
+	RequestHash string        `db:"request_hash"`
+	StatusCode  sql.NullInt32 `db:"status_code"`
+	ContentType string        `db:"content_type"`
+	Response    []byte        `db:"response"`
+	CreatedAt   time.Time     `db:"created_at"`
+	ExpiresAt   time.Time     `db:"expires_at"`
+	LockedUntil time.Time     `db:"locked_until"`
+}
@@ -0,0 +1,120 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	Reserve(ctx context.Context, entity Entity) (bool, error)
+	FindByKey(ctx context.Context, owner, key string) (Entity, error)
+	Complete(ctx context.Context, entity Entity) error
+	Release(ctx context.Context, entity Entity) error
+}
+
+// NewMiddleware - идемпотентность POST-запросов с заголовком Idempotency-Key. Первый запрос
+// с ключом выполняется, и его ответ сохраняется на ttl; повтор с тем же ключом и телом получает
+// сохранённый ответ без повторного выполнения, с другим телом или адресом - 422, а пока первый
+// ещё выполняется - 409. Выполняющийся запрос держит ключ не дольше аренды lease, после
+// которой повтор выполняется заново. Ответы 5xx не сохраняются, чтобы запрос можно было повторить.
+// Ключи разных вызывающих, определяемых по subject токена, не пересекаются
+func NewMiddleware(store Store, ttl time.Duration, logger *common.Logger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		// fiber возвращает заголовки без копирования, а ключ нужен и после ответа
+		var key = strings.Clone(ctx.Get(Header))
+		if ctx.Method() != fiber.MethodPost || key == "" {
+			return ctx.Next()
+		}
+		if len(key) > maxKeyLength {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Idempotency-Key must not exceed 255 characters")
+		}
+		var entity = Entity{
+			Key:         key,
+			RequestHash: requestHash(ctx),
+			ExpiresAt:   time.Now().Add(ttl),
+			// Postgres хранит микросекунды, а по locked_until запрос узнаёт свою аренду
+			LockedUntil: time.Now().Add(lease).Truncate(time.Microsecond),
+		}
+		if claims, ok := web.ClaimsFromCtx(ctx); ok {
+			entity.Owner = claims.Subject
+		}
+		reserved, err := store.Reserve(ctx.Context(), entity)
+		if err != nil {
+			logger.ErrorCtx(ctx.Context(), "Idempotency: error reserving key", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error checking Idempotency-Key")
+		}
+		if !reserved {
+			return replay(ctx, store, entity, logger)
+		}
+		var completed bool
+		defer func() {
+			// ошибка или паника обработчика: ключ освобождается, и клиент может повторить запрос
+			if !completed {
+				if err := store.Release(context.Background(), entity); err != nil {
+					logger.ErrorCtx(ctx.Context(), "Idempotency: error releasing key", zap.Error(err))
+				}
+			}
+		}()
+		if err = ctx.Next(); err != nil {
+			return err
+		}
+		var status = ctx.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			return nil
+		}
+		entity.StatusCode = sql.NullInt32{Int32: int32(status), Valid: true}
+		entity.ContentType = string(ctx.Response().Header.ContentType())
+		entity.Response = bytes.Clone(ctx.Response().Body())
+		if err = store.Complete(ctx.Context(), entity); err != nil {
+			// ответ уже сформирован, поэтому отдаём его; ключ освобождается, и повтор выполнит запрос заново
+			logger.ErrorCtx(ctx.Context(), "Idempotency: error saving response", zap.Error(err))
+			return nil
+		}
+		completed = true
+		return nil
+	}
+}
+
+// replay - ответ на запрос с уже занятым ключом
+func replay(ctx *fiber.Ctx, store Store, request Entity, logger *common.Logger) error {
+	var saved, err = store.FindByKey(ctx.Context(), request.Owner, request.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// ключ освободили между попытками занять его и прочитать: клиенту достаточно повторить
+		return common.ErrResponse(ctx, fiber.StatusConflict, "Request with this Idempotency-Key is in progress")
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "Idempotency: error finding key", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error checking Idempotency-Key")
+	}
+	if saved.RequestHash != request.RequestHash {
+		return common.ErrResponse(ctx, fiber.StatusUnprocessableEntity,
+			"Idempotency-Key is already used with a different request")
+	}
+	if !saved.StatusCode.Valid {
+		return common.ErrResponse(ctx, fiber.StatusConflict, "Request with this Idempotency-Key is in progress")
+	}
+	ctx.Set(ReplayedHeader, "true")
+	if saved.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, saved.ContentType)
+	}
+	return ctx.Status(int(saved.StatusCode.Int32)).Send(saved.Response)
+}
+
+// requestHash - отпечаток запроса: адрес с параметрами и тело
+func requestHash(ctx *fiber.Ctx) string {
+	var hash = sha256.New()
+	hash.Write([]byte(ctx.Method() + " " + ctx.OriginalURL() + "\n"))
+	hash.Write(ctx.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,290 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MemoryStore - Store в памяти с той же семантикой занятия ключа, что и Repository
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entity
+	fail    error
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]Entity{}}
+}
+
+func (s *MemoryStore) Reserve(_ context.Context, entity Entity) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return false, s.fail
+	}
+	if existing, ok := s.entries[entity.Owner+"/"+entity.Key]; ok && existing.ExpiresAt.After(time.Now()) &&
+		(existing.StatusCode.Valid || existing.LockedUntil.After(time.Now())) {
+		return false, nil
+	}
+	s.entries[entity.Owner+"/"+entity.Key] = entity
+	return true, nil
+}
+
+func (s *MemoryStore) FindByKey(_ context.Context, owner, key string) (Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entity, ok := s.entries[owner+"/"+key]; ok {
+		return entity, nil
+	}
+	return Entity{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) Complete(_ context.Context, entity Entity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held(entity) {
+		s.entries[entity.Owner+"/"+entity.Key] = entity
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, entity Entity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held(entity) {
+		delete(s.entries, entity.Owner+"/"+entity.Key)
+	}
+	return nil
+}
+
+// held - ключ всё ещё занят запросом entity и ответа у него нет
+func (s *MemoryStore) held(entity Entity) bool {
+	var existing, ok = s.entries[entity.Owner+"/"+entity.Key]
+	return ok && !existing.StatusCode.Valid && existing.LockedUntil.Equal(entity.LockedUntil)
+}
+
+// newApp - приложение с обработчиком создания, который выдаёт новый id на каждое выполнение;
+// subject вызывающего берётся из заголовка X-Subject вместо проверки токена
+func newApp(store Store, ttl time.Duration, calls *atomic.Int64, status func() int) *fiber.App {
+	var app = fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		var claims = &web.IdmClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: ctx.Get("X-Subject")}}
+		ctx.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return ctx.Next()
+	})
+	app.Use(NewMiddleware(store, ttl, &common.Logger{Logger: zap.NewNop()}))
+	var handler = func(ctx *fiber.Ctx) error {
+		var id = calls.Add(1)
+		if code := status(); code != fiber.StatusOK {
+			return common.ErrResponse(ctx, code, "failed")
+		}
+		return common.OkResponse(ctx, id)
+	}
+	app.Post("/employees", handler)
+	app.Get("/employees", handler)
+	return app
+}
+
+func ok() int {
+	return fiber.StatusOK
+}
+
+func post(a *assert.Assertions, app *fiber.App, key, subject, body string) (*http.Response, common.Response[int64]) {
+	var req = httptest.NewRequest(http.MethodPost, "/employees", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	req.Header.Set("X-Subject", subject)
+	resp, err := app.Test(req)
+	a.Nil(err)
+	var data, _ = io.ReadAll(resp.Body)
+	var envelope common.Response[int64]
+	_ = json.Unmarshal(data, &envelope)
+	return resp, envelope
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("Should replay saved response for repeated key and body", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var app = newApp(NewMemoryStore(), time.Hour, &calls, ok)
+
+		first, created := post(a, app, "key-1", "alice", `{"name":"John"}`)
+		second, replayed := post(a, app, "key-1", "alice", `{"name":"John"}`)
+
+		a.Equal(fiber.StatusOK, first.StatusCode)
+		a.Equal(fiber.StatusOK, second.StatusCode)
+		a.Equal(int64(1), calls.Load())
+		a.Equal(created, replayed)
+		a.Equal("true", second.Header.Get(ReplayedHeader))
+		a.Equal(fiber.MIMEApplicationJSON, second.Header.Get("Content-Type"))
+		a.Empty(first.Header.Get(ReplayedHeader))
+	})
+
+	t.Run("Should return 422 when key is reused with different body", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var app = newApp(NewMemoryStore(), time.Hour, &calls, ok)
+
+		post(a, app, "key-1", "alice", `{"name":"John"}`)
+		resp, body := post(a, app, "key-1", "alice", `{"name":"Jane"}`)
+
+		a.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+		a.False(body.Success)
+		a.Equal(int64(1), calls.Load())
+	})
+
+	t.Run("Should keep keys of different callers apart", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var app = newApp(NewMemoryStore(), time.Hour, &calls, ok)
+
+		_, alice := post(a, app, "key-1", "alice", `{}`)
+		_, bob := post(a, app, "key-1", "bob", `{}`)
+
+		a.Equal(int64(2), calls.Load())
+		a.NotEqual(alice.Data, bob.Data)
+	})
+
+	t.Run("Should execute again after key expires", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var app = newApp(NewMemoryStore(), -time.Second, &calls, ok)
+
+		post(a, app, "key-1", "alice", `{}`)
+		post(a, app, "key-1", "alice", `{}`)
+
+		a.Equal(int64(2), calls.Load())
+	})
+
+	t.Run("Should save client errors but not server errors", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var code atomic.Int64
+		code.Store(fiber.StatusBadRequest)
+		var app = newApp(NewMemoryStore(), time.Hour, &calls, func() int { return int(code.Load()) })
+
+		first, _ := post(a, app, "bad", "alice", `{}`)
+		second, _ := post(a, app, "bad", "alice", `{}`)
+		a.Equal(fiber.StatusBadRequest, first.StatusCode)
+		a.Equal(fiber.StatusBadRequest, second.StatusCode)
+		a.Equal(int64(1), calls.Load())
+
+		code.Store(fiber.StatusInternalServerError)
+		post(a, app, "broken", "alice", `{}`)
+		code.Store(fiber.StatusOK)
+		resp, body := post(a, app, "broken", "alice", `{}`)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+		a.True(body.Success)
+		a.Equal(int64(3), calls.Load())
+	})
+
+	t.Run("Should return 409 while first request is in progress", func(t *testing.T) {
+		a := assert.New(t)
+		var store = NewMemoryStore()
+		var calls atomic.Int64
+		var app = newApp(store, time.Hour, &calls, ok)
+		var body = `{}`
+		// ключ занят тем же запросом, ответ которого ещё не сохранён
+		store.entries["alice/key-1"] = Entity{
+			Owner:       "alice",
+			Key:         "key-1",
+			RequestHash: hashOf(t, body),
+			ExpiresAt:   time.Now().Add(time.Hour),
+			LockedUntil: time.Now().Add(time.Minute),
+		}
+
+		resp, _ := post(a, app, "key-1", "alice", body)
+
+		a.Equal(fiber.StatusConflict, resp.StatusCode)
+		a.Equal(int64(0), calls.Load())
+	})
+
+	t.Run("Should take over key when lease of unfinished request expires", func(t *testing.T) {
+		a := assert.New(t)
+		var store = NewMemoryStore()
+		var calls atomic.Int64
+		var app = newApp(store, time.Hour, &calls, ok)
+		var body = `{}`
+		// экземпляр, занявший ключ, упал, не сохранив ответ и не освободив ключ
+		store.entries["alice/key-1"] = Entity{
+			Owner:       "alice",
+			Key:         "key-1",
+			RequestHash: hashOf(t, body),
+			ExpiresAt:   time.Now().Add(time.Hour),
+			LockedUntil: time.Now().Add(-time.Second),
+		}
+
+		first, _ := post(a, app, "key-1", "alice", body)
+		second, _ := post(a, app, "key-1", "alice", body)
+
+		a.Equal(fiber.StatusOK, first.StatusCode)
+		a.Equal(fiber.StatusOK, second.StatusCode)
+		a.Equal("true", second.Header.Get(ReplayedHeader))
+		a.Equal(int64(1), calls.Load())
+	})
+
+	t.Run("Should pass through requests without key and non-POST requests", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var store = NewMemoryStore()
+		var app = newApp(store, time.Hour, &calls, ok)
+
+		post(a, app, "", "alice", `{}`)
+		post(a, app, "", "alice", `{}`)
+		var req = httptest.NewRequest(http.MethodGet, "/employees", nil)
+		req.Header.Set(Header, "key-1")
+		_, err := app.Test(req)
+		a.Nil(err)
+
+		a.Equal(int64(3), calls.Load())
+		a.Empty(store.entries)
+	})
+
+	t.Run("Should reject too long key and report store errors", func(t *testing.T) {
+		a := assert.New(t)
+		var calls atomic.Int64
+		var store = NewMemoryStore()
+		var app = newApp(store, time.Hour, &calls, ok)
+
+		resp, _ := post(a, app, strings.Repeat("k", maxKeyLength+1), "alice", `{}`)
+		a.Equal(fiber.StatusBadRequest, resp.StatusCode)
+
+		store.fail = errors.New("connection refused")
+		resp, _ = post(a, app, "key-1", "alice", `{}`)
+		a.Equal(fiber.StatusInternalServerError, resp.StatusCode)
+		a.Equal(int64(0), calls.Load())
+	})
+}
+
+// hashOf - отпечаток POST /employees с телом body, вычисленный самим middleware
+func hashOf(t *testing.T, body string) string {
+	var hash string
+	var app = fiber.New()
+	app.Post("/employees", func(ctx *fiber.Ctx) error {
+		hash = requestHash(ctx)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest(http.MethodPost, "/employees", strings.NewReader(body))); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
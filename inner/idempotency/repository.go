@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// Reserve - занимает ключ под выполняющийся запрос до entity.LockedUntil. Заново занимается
+// истёкший ключ и ключ без ответа, аренда которого истекла; false - ключ уже занят действующей записью
+func (r *Repository) Reserve(ctx context.Context, entity Entity) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO idempotency_key(owner, key, request_hash, expires_at, locked_until) VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (owner, key) DO UPDATE
+		 SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = '', response = NULL,
+		     created_at = NOW(), expires_at = EXCLUDED.expires_at, locked_until = EXCLUDED.locked_until
+		 WHERE idempotency_key.expires_at <= NOW()
+		    OR (idempotency_key.status_code IS NULL AND idempotency_key.locked_until <= NOW())`,
+		entity.Owner, entity.Key, entity.RequestHash, entity.ExpiresAt, entity.LockedUntil)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *Repository) FindByKey(ctx context.Context, owner, key string) (entity Entity, err error) {
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM idempotency_key WHERE owner = $1 AND key = $2", owner, key)
+	return entity, err
+}
+
+// Complete - сохраняет ответ выполненного запроса, если ключ всё ещё занят им: аренду,
+// перехваченную повтором, определяет другой locked_until
+func (r *Repository) Complete(ctx context.Context, entity Entity) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE idempotency_key SET status_code = $3, content_type = $4, response = $5
+		 WHERE owner = $1 AND key = $2 AND locked_until = $6 AND status_code IS NULL`,
+		entity.Owner, entity.Key, entity.StatusCode, entity.ContentType, entity.Response, entity.LockedUntil)
+	return err
+}
+
+// Release - освобождает ключ запроса, завершившегося без сохраняемого ответа, чтобы его можно было
+// повторить; ключ, перехваченный повтором после истечения аренды, не трогает
+func (r *Repository) Release(ctx context.Context, entity Entity) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_key WHERE owner = $1 AND key = $2 AND locked_until = $3 AND status_code IS NULL",
+		entity.Owner, entity.Key, entity.LockedUntil)
+	return err
+}
+
+// DeleteExpired - удаляет ключи, истёкшие раньше before
+func (r *Repository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Purger interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Worker - фоновый процесс, удаляющий истёкшие ключи идемпотентности. Истёкший ключ
+// и без удаления не мешает новому запросу, удаление лишь не даёт таблице расти
+type Worker struct {
+	purger   Purger
+	interval time.Duration
+	logger   *common.Logger
+	runner   common.Runner
+}
+
+func NewWorker(purger Purger, interval time.Duration, logger *common.Logger) *Worker {
+	return &Worker{
+		purger:   purger,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *Worker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущего удаления
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) purge(ctx context.Context) {
+	deleted, err := w.purger.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("Idempotency worker: error deleting expired keys", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Idempotency worker: expired keys deleted", zap.Int64("count", deleted))
+	}
+}
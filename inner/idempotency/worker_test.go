@@ -0,0 +1,37 @@
+package idempotency
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type StubPurger struct {
+	calls atomic.Int32
+}
+
+func (s *StubPurger) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	s.calls.Add(1)
+	return 2, nil
+}
+
+func TestWorker(t *testing.T) {
+	t.Run("Should purge on start and stop cleanly", func(t *testing.T) {
+		a := assert.New(t)
+		purger := &StubPurger{}
+		worker := NewWorker(purger, time.Hour, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { return purger.calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		a.NoError(worker.Stop(ctx))
+		a.Equal(int32(1), purger.calls.Load())
+	})
+}
@@ -1,7 +1,9 @@
 package info
 
 import (
+	"context"
 	"idm/inner/common"
+	"idm/inner/database"
 	"idm/inner/web"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,6 +16,13 @@ type Handler struct {
 	cfg    common.Config
 	db     *sqlx.DB
 	logger *common.Logger
+	// Schema - версия схемы БД для /internal/info; nil - не выводится
+	Schema SchemaVersions
+}
+
+// SchemaVersions - версия схемы БД относительно встроенных миграций
+type SchemaVersions interface {
+	Version(ctx context.Context) (database.SchemaVersion, error)
 }
 
 func NewHandler(server *web.Server, cfg common.Config, db *sqlx.DB, logger *common.Logger) *Handler {
@@ -26,8 +35,16 @@ func NewHandler(server *web.Server, cfg common.Config, db *sqlx.DB, logger *comm
 }
 
 type InfoResponse struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Schema  *SchemaResponse `json:"schema,omitempty"`
+}
+
+// SchemaResponse - версия схемы БД: последняя применённая и последняя встроенная миграции
+type SchemaResponse struct {
+	Version int64 `json:"version"`
+	Latest  int64 `json:"latest"`
+	Pending int   `json:"pending"`
 }
 
 // RegisterRoutes() - регистрация  "/internal/info" / "/internal/health"
@@ -38,10 +55,23 @@ func (c *Handler) RegisterRoutes() {
 
 // GetInfo - получение информации о приложении
 func (c *Handler) GetInfo(ctx *fiber.Ctx) error {
-	var err = ctx.Status(fiber.StatusOK).JSON(&InfoResponse{
+	var response = InfoResponse{
 		Name:    c.cfg.AppName,
 		Version: c.cfg.AppVersion,
-	})
+	}
+	if c.Schema != nil {
+		// недоступная БД не мешает отдать версию приложения, схема просто не выводится
+		if version, err := c.Schema.Version(ctx.Context()); err != nil {
+			c.logger.ErrorCtx(ctx.Context(), "GetInfo schema version", zap.Error(err))
+		} else {
+			response.Schema = &SchemaResponse{
+				Version: version.Current,
+				Latest:  version.Latest,
+				Pending: len(version.Pending),
+			}
+		}
+	}
+	var err = ctx.Status(fiber.StatusOK).JSON(&response)
 	if err != nil {
 		c.logger.Error("GetInfo", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error returning info")
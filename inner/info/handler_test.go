@@ -1,7 +1,11 @@
 package info
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"idm/inner/common"
+	"idm/inner/database"
 	"idm/inner/web"
 	"net/http"
 	"net/http/httptest"
@@ -108,3 +112,58 @@ func TestGetHealth(t *testing.T) {
 		a.Equal(fiber.StatusServiceUnavailable, resp.StatusCode)
 	})
 }
+
+type MockSchema struct {
+	mock.Mock
+}
+
+func (m *MockSchema) Version(ctx context.Context) (database.SchemaVersion, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(database.SchemaVersion), args.Error(1)
+}
+
+func TestGetInfoSchema(t *testing.T) {
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+	var newApp = func(schema SchemaVersions) *fiber.App {
+		app := fiber.New()
+		server := &web.Server{
+			App:           app,
+			GroupInternal: app.Group("/internal"),
+		}
+		ctrl := NewHandler(server, common.Config{AppName: "test", AppVersion: "1.0.0"}, nil, logger)
+		ctrl.Schema = schema
+		ctrl.RegisterRoutes()
+		return app
+	}
+	var getInfo = func(a *assert.Assertions, app *fiber.App) InfoResponse {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/internal/info", nil))
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+		var body InfoResponse
+		a.Nil(json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	t.Run("Should return schema version", func(t *testing.T) {
+		a := assert.New(t)
+		schema := &MockSchema{}
+		schema.On("Version", mock.Anything).
+			Return(database.SchemaVersion{Current: 17, Latest: 18, Pending: []int64{18}}, nil)
+
+		var body = getInfo(a, newApp(schema))
+		a.Equal("1.0.0", body.Version)
+		a.Equal(&SchemaResponse{Version: 17, Latest: 18, Pending: 1}, body.Schema)
+	})
+
+	t.Run("Should omit schema when database is unavailable", func(t *testing.T) {
+		a := assert.New(t)
+		schema := &MockSchema{}
+		schema.On("Version", mock.Anything).Return(database.SchemaVersion{}, errors.New("connection refused"))
+
+		var body = getInfo(a, newApp(schema))
+		a.Equal("test", body.Name)
+		a.Nil(body.Schema)
+	})
+}
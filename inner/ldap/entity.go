@@ -0,0 +1,47 @@
+package ldap
+
+import (
+	"idm/inner/ldif"
+)
+
+// Области поиска (RFC 4511, 4.5.1.2)
+const (
+	ScopeBase     = 0
+	ScopeOneLevel = 1
+	ScopeSubtree  = 2
+)
+
+// Options - учётные данные сервисной записи, которой разрешён поиск по каталогу
+type Options struct {
+	BindDn       string
+	BindPassword string
+}
+
+// SearchRequest - запрос поиска. Attributes - возвращаемые атрибуты: пусто или "*" - все,
+// "1.1" - ни одного
+type SearchRequest struct {
+	BaseDn     string
+	Scope      int
+	SizeLimit  int
+	TypesOnly  bool
+	Filter     Filter
+	Attributes []string
+	// Paging - запрос страницы (RFC 2696), nil - выдача без страниц
+	Paging *Paging
+}
+
+// Paging - размер страницы и курсор, который вернула предыдущая страница; пустой курсор - первая страница
+type Paging struct {
+	Size   int
+	Cookie []byte
+}
+
+// SearchResult - найденные записи. Cookie - курсор следующей страницы, пусто - страница последняя
+type SearchResult struct {
+	Entries []ldif.Entry
+	Cookie  []byte
+	// Total - число найденных записей без учёта страниц и ограничения размера
+	Total int
+	// SizeLimitExceeded - выдача обрезана по SizeLimit запроса
+	SizeLimitExceeded bool
+}
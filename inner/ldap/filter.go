@@ -0,0 +1,236 @@
+package ldap
+
+import (
+	"cmp"
+	"fmt"
+	"idm/inner/ldif"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Filter - разобранный фильтр поиска (RFC 4511, 4.5.1.7)
+type Filter interface {
+	match(entry *ldif.Entry) bool
+}
+
+// And - все условия выполняются; пустой And - истина
+type And []Filter
+
+// Or - выполняется хотя бы одно условие; пустой Or - ложь
+type Or []Filter
+
+// Not - отрицание условия
+type Not struct {
+	Filter Filter
+}
+
+// Equality - атрибут имеет значение Value; приблизительное совпадение (~=) сводится к нему
+type Equality struct {
+	Attr  string
+	Value string
+}
+
+// Substrings - значение атрибута начинается с Initial, содержит Any по порядку и заканчивается Final
+type Substrings struct {
+	Attr    string
+	Initial string
+	Any     []string
+	Final   string
+}
+
+// Present - атрибут есть в записи
+type Present struct {
+	Attr string
+}
+
+// Ordering - значение атрибута не меньше (Greater) или не больше Value
+type Ordering struct {
+	Attr    string
+	Greater bool
+	Value   string
+}
+
+// dnAttributes - атрибуты со значениями-DN: они сравниваются после разбора, без учёта
+// экранирования и пробелов между RDN
+var dnAttributes = map[string]bool{"member": true, "memberof": true, "manager": true}
+
+// ParseFilter - фильтр из BER-представления запроса поиска. Расширенное совпадение (:=)
+// не поддерживается
+func ParseFilter(packet *ber.Packet) (Filter, error) {
+	if packet.ClassType != ber.ClassContext {
+		return nil, fmt.Errorf("invalid filter class %d", packet.ClassType)
+	}
+	switch packet.Tag {
+	case goldap.FilterAnd, goldap.FilterOr:
+		var children = make([]Filter, 0, len(packet.Children))
+		for _, child := range packet.Children {
+			filter, err := ParseFilter(child)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, filter)
+		}
+		if packet.Tag == goldap.FilterAnd {
+			return And(children), nil
+		}
+		return Or(children), nil
+	case goldap.FilterNot:
+		if len(packet.Children) != 1 {
+			return nil, fmt.Errorf("not filter expects one child")
+		}
+		filter, err := ParseFilter(packet.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		return Not{Filter: filter}, nil
+	case goldap.FilterPresent:
+		return Present{Attr: packet.Data.String()}, nil
+	case goldap.FilterEqualityMatch, goldap.FilterApproxMatch, goldap.FilterGreaterOrEqual, goldap.FilterLessOrEqual:
+		attr, value, err := assertion(packet)
+		if err != nil {
+			return nil, err
+		}
+		switch packet.Tag {
+		case goldap.FilterGreaterOrEqual:
+			return Ordering{Attr: attr, Greater: true, Value: value}, nil
+		case goldap.FilterLessOrEqual:
+			return Ordering{Attr: attr, Value: value}, nil
+		}
+		return Equality{Attr: attr, Value: value}, nil
+	case goldap.FilterSubstrings:
+		return parseSubstrings(packet)
+	}
+	return nil, fmt.Errorf("filter %s is not supported", goldap.FilterMap[uint64(packet.Tag)])
+}
+
+// assertion - атрибут и значение из AttributeValueAssertion
+func assertion(packet *ber.Packet) (string, string, error) {
+	if len(packet.Children) != 2 {
+		return "", "", fmt.Errorf("attribute value assertion expects two elements")
+	}
+	return packet.Children[0].Data.String(), packet.Children[1].Data.String(), nil
+}
+
+func parseSubstrings(packet *ber.Packet) (Filter, error) {
+	if len(packet.Children) != 2 {
+		return nil, fmt.Errorf("substrings filter expects two elements")
+	}
+	var filter = Substrings{Attr: packet.Children[0].Data.String()}
+	for _, part := range packet.Children[1].Children {
+		switch part.Tag {
+		case goldap.FilterSubstringsInitial:
+			filter.Initial = part.Data.String()
+		case goldap.FilterSubstringsAny:
+			filter.Any = append(filter.Any, part.Data.String())
+		case goldap.FilterSubstringsFinal:
+			filter.Final = part.Data.String()
+		default:
+			return nil, fmt.Errorf("invalid substring choice %d", part.Tag)
+		}
+	}
+	return filter, nil
+}
+
+func (f And) match(entry *ldif.Entry) bool {
+	for _, filter := range f {
+		if !filter.match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Or) match(entry *ldif.Entry) bool {
+	for _, filter := range f {
+		if filter.match(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Not) match(entry *ldif.Entry) bool {
+	return !f.Filter.match(entry)
+}
+
+func (f Present) match(entry *ldif.Entry) bool {
+	return entry.Has(attrName(f.Attr))
+}
+
+func (f Equality) match(entry *ldif.Entry) bool {
+	var attr = attrName(f.Attr)
+	var want = normalize(attr, f.Value)
+	for _, value := range entry.Values(attr) {
+		if normalize(attr, value) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Substrings) match(entry *ldif.Entry) bool {
+	for _, value := range entry.Values(attrName(f.Attr)) {
+		if matchSubstrings(strings.ToLower(value), f) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSubstrings(value string, f Substrings) bool {
+	var initial, final = strings.ToLower(f.Initial), strings.ToLower(f.Final)
+	if !strings.HasPrefix(value, initial) {
+		return false
+	}
+	value = value[len(initial):]
+	for _, part := range f.Any {
+		part = strings.ToLower(part)
+		var i = strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, final)
+}
+
+// match - числа сравниваются как числа (employeeNumber, departmentNumber), остальное - как строки
+// без учёта регистра
+func (f Ordering) match(entry *ldif.Entry) bool {
+	for _, value := range entry.Values(attrName(f.Attr)) {
+		var order = compare(value, f.Value)
+		if f.Greater && order >= 0 || !f.Greater && order <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(left, right string) int {
+	l, lErr := strconv.ParseInt(left, 10, 64)
+	r, rErr := strconv.ParseInt(right, 10, 64)
+	if lErr == nil && rErr == nil {
+		return cmp.Compare(l, r)
+	}
+	return strings.Compare(strings.ToLower(left), strings.ToLower(right))
+}
+
+// attrName - имя атрибута без опций (";binary", ";lang-ru")
+func attrName(attr string) string {
+	name, _, _ := strings.Cut(attr, ";")
+	return name
+}
+
+// normalize - значение для сравнения: все атрибуты каталога сравниваются без учёта регистра,
+// DN - после разбора
+func normalize(attr, value string) string {
+	if dnAttributes[strings.ToLower(attr)] {
+		if dn, err := ldif.ParseDN(value); err == nil {
+			return strings.ToLower(dn.String())
+		}
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,130 @@
+package ldap
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"idm/inner/ldif"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// cursorTtl - сколько курсор постраничного поиска ждёт запроса следующей страницы
+const cursorTtl = 5 * time.Minute
+
+// maxCursors - предел одновременно открытых курсоров; при превышении вытесняется самый старый
+const maxCursors = 100
+
+// cursor - результат постраничного поиска, из которого выдаются следующие страницы.
+// Снимок каталога делается один раз на первой странице, поэтому страницы согласованы между собой
+type cursor struct {
+	bound   string
+	matches []ldif.Entry
+	expires time.Time
+}
+
+// cursors - открытые курсоры постраничного поиска. Курсор в cookie - "<токен>:<смещение>":
+// токен адресует сохранённый результат, смещение - начало следующей страницы
+type cursors struct {
+	mu    sync.Mutex
+	items map[string]*cursor
+}
+
+func newCursors() *cursors {
+	return &cursors{items: make(map[string]*cursor)}
+}
+
+// page - первая страница выдачи; если записи остались, результат сохраняется под курсором.
+// Без постраничного запроса выдача ограничивается SizeLimit
+func (c *cursors) page(bound string, matches []ldif.Entry, request SearchRequest) (SearchResult, error) {
+	var result = SearchResult{Entries: matches, Total: len(matches)}
+	if request.Paging == nil {
+		if request.SizeLimit > 0 && len(matches) > request.SizeLimit {
+			result.Entries = matches[:request.SizeLimit]
+			result.SizeLimitExceeded = true
+		}
+		return result, nil
+	}
+	// размер 0 - клиент прекращает постраничный поиск
+	if request.Paging.Size == 0 {
+		result.Entries = nil
+		return result, nil
+	}
+	var end = min(request.Paging.Size, len(matches))
+	result.Entries = matches[:end]
+	if end < len(matches) {
+		token, err := c.open(bound, matches)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		result.Cookie = cookie(token, end)
+	}
+	return result, nil
+}
+
+// next - следующая страница по курсору из cookie. Курсор закрывается на последней странице
+// и когда клиент прекращает поиск
+func (c *cursors) next(bound string, request SearchRequest) (SearchResult, error) {
+	var invalid = goldap.NewError(goldap.LDAPResultUnwillingToPerform, errors.New("Invalid paged results cookie"))
+	token, offsetText, ok := strings.Cut(string(request.Paging.Cookie), ":")
+	if !ok {
+		return SearchResult{}, invalid
+	}
+	offset, err := strconv.Atoi(offsetText)
+	if err != nil {
+		return SearchResult{}, invalid
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var cur, found = c.items[token]
+	if !found || cur.bound != bound || time.Now().After(cur.expires) || offset <= 0 || offset > len(cur.matches) {
+		return SearchResult{}, invalid
+	}
+	var result = SearchResult{Total: len(cur.matches)}
+	if request.Paging.Size == 0 {
+		delete(c.items, token)
+		return result, nil
+	}
+	var end = min(offset+request.Paging.Size, len(cur.matches))
+	result.Entries = cur.matches[offset:end]
+	if end < len(cur.matches) {
+		cur.expires = time.Now().Add(cursorTtl)
+		result.Cookie = cookie(token, end)
+	} else {
+		delete(c.items, token)
+	}
+	return result, nil
+}
+
+// open - сохраняет результат под новым токеном, попутно удаляя истёкшие курсоры
+func (c *cursors) open(bound string, matches []ldif.Entry) (string, error) {
+	var random = make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	var token = hex.EncodeToString(random)
+	var now = time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var oldest string
+	for t, cur := range c.items {
+		if now.After(cur.expires) {
+			delete(c.items, t)
+		} else if oldest == "" || cur.expires.Before(c.items[oldest].expires) {
+			oldest = t
+		}
+	}
+	if len(c.items) >= maxCursors {
+		delete(c.items, oldest)
+	}
+	c.items[token] = &cursor{bound: bound, matches: matches, expires: now.Add(cursorTtl)}
+	return token, nil
+}
+
+func cookie(token string, offset int) []byte {
+	return []byte(token + ":" + strconv.Itoa(offset))
+}
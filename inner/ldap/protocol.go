@@ -0,0 +1,102 @@
+package ldap
+
+import (
+	"fmt"
+	"idm/inner/ldif"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// control - элемент управления запроса; из известных разбирается только постраничная выдача
+type control struct {
+	oid      string
+	critical bool
+	paging   *Paging
+}
+
+// parseControls - элементы управления сообщения, [0] после операции
+func parseControls(packet *ber.Packet) ([]control, error) {
+	if len(packet.Children) < 3 {
+		return nil, nil
+	}
+	var controls []control
+	for _, child := range packet.Children[2].Children {
+		if len(child.Children) == 0 {
+			return nil, fmt.Errorf("Malformed control")
+		}
+		var c = control{oid: child.Children[0].Data.String()}
+		if len(child.Children) > 1 {
+			c.critical, _ = child.Children[1].Value.(bool)
+		}
+		if c.oid == goldap.ControlTypePaging {
+			decoded, err := goldap.DecodeControl(child)
+			if err != nil {
+				return nil, fmt.Errorf("Malformed paged results control: %w", err)
+			}
+			paging, ok := decoded.(*goldap.ControlPaging)
+			if !ok {
+				return nil, fmt.Errorf("Malformed paged results control")
+			}
+			c.paging = &Paging{Size: int(paging.PagingSize), Cookie: paging.Cookie}
+		}
+		controls = append(controls, c)
+	}
+	return controls, nil
+}
+
+// parseSearch - SearchRequest (RFC 4511, 4.5.1); derefAliases и timeLimit не используются: псевдонимов
+// в каталоге нет, а поиск идёт по каталогу в памяти
+func parseSearch(op *ber.Packet) (SearchRequest, error) {
+	if len(op.Children) != 8 {
+		return SearchRequest{}, fmt.Errorf("Malformed search request")
+	}
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	typesOnly, _ := op.Children[5].Value.(bool)
+	if scope < ScopeBase || scope > ScopeSubtree || sizeLimit < 0 {
+		return SearchRequest{}, fmt.Errorf("Malformed search request")
+	}
+	filter, err := ParseFilter(op.Children[6])
+	if err != nil {
+		return SearchRequest{}, err
+	}
+	var request = SearchRequest{
+		BaseDn:    op.Children[0].Data.String(),
+		Scope:     int(scope),
+		SizeLimit: int(sizeLimit),
+		TypesOnly: typesOnly,
+		Filter:    filter,
+	}
+	for _, attribute := range op.Children[7].Children {
+		request.Attributes = append(request.Attributes, attribute.Data.String())
+	}
+	return request, nil
+}
+
+// result - ответ операции с LDAPResult
+func result(tag ber.Tag, code uint16, matchedDn, message string) *ber.Packet {
+	var packet = ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, goldap.ApplicationMap[uint8(tag)])
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, matchedDn, "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return packet
+}
+
+func searchEntry(entry ldif.Entry) *ber.Packet {
+	var packet = ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	var attributes = ber.NewSequence("Attributes")
+	for _, attribute := range entry.Attributes {
+		var item = ber.NewSequence("Attribute")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attribute.Name, "Type"))
+		var values = ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range attribute.Values {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		item.AppendChild(values)
+		attributes.AppendChild(item)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
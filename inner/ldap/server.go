@@ -0,0 +1,314 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"io"
+	"net"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// maxMessageSize - предел размера одного запроса: в каталог только для чтения большие запросы не приходят
+const maxMessageSize = 1 << 20
+
+// noticeOfDisconnection - OID уведомления о разрыве подключения сервером (RFC 4511, 4.4.1)
+const noticeOfDisconnection = "1.3.6.1.4.1.1466.20036"
+
+// readOnlyOps - операции изменения каталога и теги их ответов
+var readOnlyOps = map[ber.Tag]ber.Tag{
+	goldap.ApplicationModifyRequest:   goldap.ApplicationModifyResponse,
+	goldap.ApplicationAddRequest:      goldap.ApplicationAddResponse,
+	goldap.ApplicationDelRequest:      goldap.ApplicationDelResponse,
+	goldap.ApplicationModifyDNRequest: goldap.ApplicationModifyDNResponse,
+	goldap.ApplicationCompareRequest:  goldap.ApplicationCompareResponse,
+}
+
+type Svc interface {
+	Bind(dn, password string) (string, error)
+	Search(ctx context.Context, bound string, request SearchRequest) (SearchResult, error)
+}
+
+// Server - LDAPv3-фронтенд каталога только для чтения: простая аутентификация и поиск
+type Server struct {
+	addr    string
+	service Svc
+	logger  *common.Logger
+	// TLSConfig - сертификат для LDAPS; nil - подключения без шифрования
+	TLSConfig *tls.Config
+	mu        sync.Mutex
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewServer(addr string, service Svc, logger *common.Logger) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		addr:    addr,
+		service: service,
+		logger:  logger,
+		conns:   map[net.Conn]struct{}{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start - открывает порт и принимает подключения в отдельной горутине
+func (s *Server) Start() {
+	var ln, err = net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Panic("Failed LDAP listen", zap.Error(err))
+	}
+	if s.TLSConfig != nil {
+		ln = tls.NewListener(ln, s.TLSConfig)
+	}
+	s.logger.Info("LDAP frontend listening", zap.String("addr", s.addr))
+	go func() {
+		if err := s.Serve(ln); err != nil {
+			s.logger.Error("LDAP frontend stopped with error", zap.Error(err))
+		}
+	}()
+}
+
+// Serve - принимает подключения на ln до вызова Stop
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return ln.Close()
+	}
+	s.listener = ln
+	s.mu.Unlock()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !s.track(conn) {
+			_ = conn.Close()
+			return nil
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Stop - закрывает порт и подключения и ждёт завершения обрабатываемых запросов
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.cancel()
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	var done = make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	_ = conn.Close()
+	s.wg.Done()
+}
+
+// session - состояние подключения: DN, под которым оно аутентифицировано
+type session struct {
+	conn  net.Conn
+	bound string
+}
+
+// serveConn - запросы подключения обрабатываются по очереди до Unbind или разрыва
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	var reader = bufio.NewReader(conn)
+	var sess = &session{conn: conn}
+	for {
+		packet, err := ber.ReadPacket(io.LimitReader(reader, maxMessageSize))
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && s.ctx.Err() == nil {
+				s.logger.Debug("LDAP: error reading request", zap.Error(err))
+				s.disconnect(sess, "Malformed request")
+			}
+			return
+		}
+		if !s.handle(sess, packet) {
+			return
+		}
+	}
+}
+
+// handle - обработка одного сообщения; false - подключение нужно закрыть
+func (s *Server) handle(sess *session, packet *ber.Packet) bool {
+	if len(packet.Children) < 2 {
+		s.disconnect(sess, "Malformed LDAP message")
+		return false
+	}
+	messageId, ok := packet.Children[0].Value.(int64)
+	var op = packet.Children[1]
+	if !ok || op.ClassType != ber.ClassApplication {
+		s.disconnect(sess, "Malformed LDAP message")
+		return false
+	}
+	controls, err := parseControls(packet)
+	if err != nil {
+		s.disconnect(sess, err.Error())
+		return false
+	}
+	switch op.Tag {
+	case goldap.ApplicationBindRequest:
+		s.bind(sess, messageId, op)
+	case goldap.ApplicationSearchRequest:
+		s.search(sess, messageId, op, controls)
+	case goldap.ApplicationUnbindRequest:
+		return false
+	case goldap.ApplicationAbandonRequest:
+		// запросы выполняются по очереди, к приходу Abandon отменять уже нечего
+	case goldap.ApplicationExtendedRequest:
+		s.send(sess, messageId, result(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError, "",
+			"Unsupported extended operation"))
+	default:
+		response, ok := readOnlyOps[op.Tag]
+		if !ok {
+			s.disconnect(sess, fmt.Sprintf("Unknown operation %d", op.Tag))
+			return false
+		}
+		s.send(sess, messageId, result(response, goldap.LDAPResultUnwillingToPerform, "", "Directory is read-only"))
+	}
+	return true
+}
+
+func (s *Server) bind(sess *session, messageId int64, op *ber.Packet) {
+	if len(op.Children) != 3 {
+		s.send(sess, messageId, result(goldap.ApplicationBindResponse, goldap.LDAPResultProtocolError, "",
+			"Malformed bind request"))
+		return
+	}
+	sess.bound = ""
+	if version, _ := op.Children[0].Value.(int64); version != 3 {
+		s.send(sess, messageId, result(goldap.ApplicationBindResponse, goldap.LDAPResultProtocolError, "",
+			"Only LDAPv3 is supported"))
+		return
+	}
+	var auth = op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		s.send(sess, messageId, result(goldap.ApplicationBindResponse, goldap.LDAPResultAuthMethodNotSupported, "",
+			"Only simple bind is supported"))
+		return
+	}
+	bound, err := s.service.Bind(op.Children[1].Data.String(), auth.Data.String())
+	if err != nil {
+		s.sendError(sess, messageId, goldap.ApplicationBindResponse, err)
+		return
+	}
+	sess.bound = bound
+	s.send(sess, messageId, result(goldap.ApplicationBindResponse, goldap.LDAPResultSuccess, "", ""))
+}
+
+func (s *Server) search(sess *session, messageId int64, op *ber.Packet, controls []control) {
+	var request, err = parseSearch(op)
+	if err != nil {
+		s.send(sess, messageId, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError, "",
+			err.Error()))
+		return
+	}
+	for _, c := range controls {
+		switch {
+		case c.paging != nil:
+			request.Paging = c.paging
+		case c.critical:
+			s.send(sess, messageId, result(goldap.ApplicationSearchResultDone,
+				goldap.LDAPResultUnavailableCriticalExtension, "", "Unsupported critical control "+c.oid))
+			return
+		}
+	}
+	rsl, err := s.service.Search(s.ctx, sess.bound, request)
+	if err != nil {
+		s.sendError(sess, messageId, goldap.ApplicationSearchResultDone, err)
+		return
+	}
+	for _, entry := range rsl.Entries {
+		s.send(sess, messageId, searchEntry(entry))
+	}
+	var code uint16 = goldap.LDAPResultSuccess
+	if rsl.SizeLimitExceeded {
+		code = goldap.LDAPResultSizeLimitExceeded
+	}
+	var done = result(goldap.ApplicationSearchResultDone, code, "", "")
+	if request.Paging != nil {
+		var paging = &goldap.ControlPaging{PagingSize: uint32(rsl.Total), Cookie: rsl.Cookie}
+		s.send(sess, messageId, done, paging.Encode())
+		return
+	}
+	s.send(sess, messageId, done)
+}
+
+// sendError - ответ с кодом ошибки LDAP; прочие ошибки - внутренние, клиенту их текст не передаётся
+func (s *Server) sendError(sess *session, messageId int64, tag ber.Tag, err error) {
+	var ldapErr *goldap.Error
+	if errors.As(err, &ldapErr) {
+		s.send(sess, messageId, result(tag, ldapErr.ResultCode, ldapErr.MatchedDN, ldapErr.Err.Error()))
+		return
+	}
+	s.logger.Error("LDAP: error handling request", zap.Error(err))
+	s.send(sess, messageId, result(tag, goldap.LDAPResultOperationsError, "", "Internal error"))
+}
+
+// disconnect - уведомление о разрыве подключения из-за нарушения протокола
+func (s *Server) disconnect(sess *session, message string) {
+	var notice = result(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError, "", message)
+	notice.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 10, noticeOfDisconnection, "Response Name"))
+	s.send(sess, 0, notice)
+}
+
+func (s *Server) send(sess *session, messageId int64, op *ber.Packet, controls ...*ber.Packet) {
+	var packet = ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "Message ID"))
+	packet.AppendChild(op)
+	if len(controls) > 0 {
+		var wrapper = ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, c := range controls {
+			wrapper.AppendChild(c)
+		}
+		packet.AppendChild(wrapper)
+	}
+	if _, err := sess.conn.Write(packet.Bytes()); err != nil && s.ctx.Err() == nil {
+		s.logger.Debug("LDAP: error writing response", zap.Error(err))
+	}
+}
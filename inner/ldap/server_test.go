@@ -0,0 +1,100 @@
+package ldap
+
+import (
+	"context"
+	"idm/inner/common"
+	"net"
+	"testing"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func startServer(t *testing.T) (*Server, string) {
+	var server = NewServer("", newService(newDirectory()), &common.Logger{Logger: zap.NewNop()})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Stop(context.Background()) })
+	return server, "ldap://" + ln.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	t.Run("Should bind and search with paged results", func(t *testing.T) {
+		a := assert.New(t)
+		_, url := startServer(t)
+		conn, err := goldap.DialURL(url)
+		a.NoError(err)
+		defer func() { _ = conn.Close() }()
+		a.NoError(conn.Bind(bindDn, "secret"))
+
+		rsl, err := conn.SearchWithPaging(goldap.NewSearchRequest("ou=people,dc=idm,dc=local",
+			goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+			"(&(objectClass=inetOrgPerson)(|(uid=*ov)(sn=Смирнова)))", []string{"uid", "cn"}, nil), 2)
+
+		a.NoError(err)
+		a.Len(rsl.Entries, 3)
+		a.Equal("ivanov", rsl.Entries[0].GetAttributeValue("uid"))
+		a.Equal("Анна Смирнова", rsl.Entries[2].GetAttributeValue("cn"))
+		a.Empty(rsl.Entries[0].GetAttributeValue("sn"))
+	})
+
+	t.Run("Should find group members and memberOf", func(t *testing.T) {
+		a := assert.New(t)
+		_, url := startServer(t)
+		conn, err := goldap.DialURL(url)
+		a.NoError(err)
+		defer func() { _ = conn.Close() }()
+		a.NoError(conn.Bind(bindDn, "secret"))
+
+		rsl, err := conn.Search(goldap.NewSearchRequest("dc=idm,dc=local", goldap.ScopeWholeSubtree,
+			goldap.NeverDerefAliases, 0, 0, false, "(uid=smirnova)", []string{"memberOf"}, nil))
+
+		a.NoError(err)
+		a.Equal([]string{"cn=Accountants,ou=groups,dc=idm,dc=local"}, rsl.Entries[0].GetAttributeValues("memberOf"))
+	})
+
+	t.Run("Should reject invalid credentials, anonymous search and changes", func(t *testing.T) {
+		a := assert.New(t)
+		_, url := startServer(t)
+		conn, err := goldap.DialURL(url)
+		a.NoError(err)
+		defer func() { _ = conn.Close() }()
+
+		err = conn.Bind(bindDn, "wrong")
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials))
+
+		_, err = conn.Search(goldap.NewSearchRequest("dc=idm,dc=local", goldap.ScopeWholeSubtree,
+			goldap.NeverDerefAliases, 0, 0, false, "(uid=*)", nil, nil))
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultInsufficientAccessRights))
+
+		a.NoError(conn.Bind(bindDn, "secret"))
+		err = conn.Del(goldap.NewDelRequest("uid=ivanov,ou=people,dc=idm,dc=local", nil))
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultUnwillingToPerform))
+
+		_, err = conn.Search(goldap.NewSearchRequest("dc=idm,dc=local", goldap.ScopeWholeSubtree,
+			goldap.NeverDerefAliases, 0, 0, false, "(uid:caseExactMatch:=ivanov)", nil, nil))
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultProtocolError))
+	})
+
+	t.Run("Should close connections on stop", func(t *testing.T) {
+		a := assert.New(t)
+		server, url := startServer(t)
+		conn, err := goldap.DialURL(url)
+		a.NoError(err)
+		defer func() { _ = conn.Close() }()
+		a.NoError(conn.Bind(bindDn, "secret"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.NoError(server.Stop(ctx))
+
+		_, err = conn.Search(goldap.NewSearchRequest("dc=idm,dc=local", goldap.ScopeBaseObject,
+			goldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", nil, nil))
+		a.Error(err)
+		_, err = goldap.DialURL(url)
+		a.Error(err)
+	})
+}
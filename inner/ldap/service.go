@@ -0,0 +1,207 @@
+package ldap
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/ldif"
+	"slices"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	directory Directory
+	tree      ldif.Tree
+	options   Options
+	logger    common.LoggerInterface
+	cursors   *cursors
+}
+
+// Directory - текущий каталог сотрудников и ролей в виде записей LDAP, тот же, что выгружается в LDIF
+type Directory interface {
+	Export(ctx context.Context) ([]ldif.Entry, error)
+}
+
+func NewService(directory Directory, tree ldif.Tree, options Options, logger common.LoggerInterface) *Service {
+	return &Service{
+		directory: directory,
+		tree:      tree,
+		options:   options,
+		logger:    logger,
+		cursors:   newCursors(),
+	}
+}
+
+// Bind - простая аутентификация; возвращает DN, под которым работает подключение. Пустые DN и
+// пароль - анонимное подключение, которому доступна только корневая запись (Root DSE)
+func (svc *Service) Bind(dn, password string) (string, error) {
+	if dn == "" && password == "" {
+		return "", nil
+	}
+	var invalid = goldap.NewError(goldap.LDAPResultInvalidCredentials, errors.New("Invalid credentials"))
+	parsed, err := ldif.ParseDN(dn)
+	if err != nil {
+		return "", invalid
+	}
+	expected, err := ldif.ParseDN(svc.options.BindDn)
+	if err != nil || !parsed.Equal(expected) ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(svc.options.BindPassword)) != 1 {
+		svc.logger.DebugCtx(context.Background(), "Bind: invalid credentials", zap.String("dn", dn))
+		return "", invalid
+	}
+	return svc.options.BindDn, nil
+}
+
+// Search - записи каталога под request.BaseDn в пределах области, подходящие под фильтр.
+// bound - DN подключения, пусто - анонимное. Каталог выгружается на каждый поиск, но не на каждую
+// страницу: следующие страницы выдаются из результата, сохранённого под курсором первой
+func (svc *Service) Search(ctx context.Context, bound string, request SearchRequest) (SearchResult, error) {
+	if request.BaseDn == "" && request.Scope == ScopeBase {
+		return svc.rootDSE(request), nil
+	}
+	if bound == "" {
+		return SearchResult{}, goldap.NewError(goldap.LDAPResultInsufficientAccessRights,
+			errors.New("Search requires bind"))
+	}
+	base, err := ldif.ParseDN(request.BaseDn)
+	if err != nil {
+		return SearchResult{}, goldap.NewError(goldap.LDAPResultInvalidDNSyntax, err)
+	}
+	if _, ok := depth(base, svc.tree.Base); !ok {
+		return SearchResult{}, goldap.NewError(goldap.LDAPResultNoSuchObject,
+			fmt.Errorf("%s is outside of the directory", request.BaseDn))
+	}
+	if request.Paging != nil && len(request.Paging.Cookie) > 0 {
+		result, err := svc.cursors.next(bound, request)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		return project(result, request), nil
+	}
+	entries, err := svc.directory.Export(ctx)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("Error loading directory: %w", err)
+	}
+	withMemberOf(entries)
+	var found = base.Equal(svc.tree.Base)
+	var matches []ldif.Entry
+	for i := range entries {
+		dn, err := ldif.ParseDN(entries[i].DN)
+		if err != nil {
+			continue
+		}
+		level, ok := depth(dn, base)
+		if !ok {
+			continue
+		}
+		if level == 0 {
+			found = true
+		}
+		if inScope(level, request.Scope) && (request.Filter == nil || request.Filter.match(&entries[i])) {
+			matches = append(matches, entries[i])
+		}
+	}
+	if !found {
+		return SearchResult{}, &goldap.Error{
+			ResultCode: goldap.LDAPResultNoSuchObject,
+			MatchedDN:  svc.tree.Base.String(),
+			Err:        fmt.Errorf("%s not found", request.BaseDn),
+		}
+	}
+	result, err := svc.cursors.page(bound, matches, request)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return project(result, request), nil
+}
+
+// rootDSE - корневая запись сервера: базовый DN каталога и поддерживаемые версия и элементы управления
+func (svc *Service) rootDSE(request SearchRequest) SearchResult {
+	var entry = ldif.Entry{}
+	entry.Add("objectClass", "top")
+	entry.Add("namingContexts", svc.tree.Base.String())
+	entry.Add("supportedLDAPVersion", "3")
+	entry.Add("supportedControl", goldap.ControlTypePaging)
+	if request.Filter != nil && !request.Filter.match(&entry) {
+		return SearchResult{}
+	}
+	return SearchResult{Entries: []ldif.Entry{projectEntry(entry, request.Attributes, request.TypesOnly)}, Total: 1}
+}
+
+// withMemberOf - добавляет сотрудникам атрибут memberOf с DN ролей, участником которых они являются
+func withMemberOf(entries []ldif.Entry) {
+	var groups = map[string][]string{}
+	for _, entry := range entries {
+		if !entry.IsA(ldif.ClassGroup) {
+			continue
+		}
+		for _, member := range entry.Values("member") {
+			if member != "" {
+				var key = strings.ToLower(member)
+				groups[key] = append(groups[key], entry.DN)
+			}
+		}
+	}
+	for i := range entries {
+		if memberOf, ok := groups[strings.ToLower(entries[i].DN)]; ok && entries[i].IsA(ldif.ClassPerson) {
+			entries[i].Add("memberOf", memberOf...)
+		}
+	}
+}
+
+// depth - на сколько уровней dn ниже base; ok = false - dn вне base
+func depth(dn, base ldif.DN) (int, bool) {
+	var level = len(dn) - len(base)
+	if level < 0 || !dn[level:].Equal(base) {
+		return 0, false
+	}
+	return level, true
+}
+
+func inScope(level, scope int) bool {
+	switch scope {
+	case ScopeBase:
+		return level == 0
+	case ScopeOneLevel:
+		return level == 1
+	}
+	return true
+}
+
+// project - выдача с запрошенными атрибутами. Записи копируются: результат под курсором
+// хранится целиком для следующих страниц
+func project(result SearchResult, request SearchRequest) SearchResult {
+	if len(result.Entries) == 0 {
+		return result
+	}
+	var entries = make([]ldif.Entry, len(result.Entries))
+	for i := range result.Entries {
+		entries[i] = projectEntry(result.Entries[i], request.Attributes, request.TypesOnly)
+	}
+	result.Entries = entries
+	return result
+}
+
+// projectEntry - запись только с запрошенными атрибутами, при typesOnly - без значений
+func projectEntry(entry ldif.Entry, attributes []string, typesOnly bool) ldif.Entry {
+	var all = len(attributes) == 0 || slices.Contains(attributes, "*")
+	var projected = ldif.Entry{DN: entry.DN}
+	for _, attribute := range entry.Attributes {
+		if !all && !slices.ContainsFunc(attributes, func(name string) bool {
+			return strings.EqualFold(attrName(name), attribute.Name)
+		}) {
+			continue
+		}
+		var values = attribute.Values
+		if typesOnly {
+			values = nil
+		}
+		projected.Attributes = append(projected.Attributes, ldif.Attribute{Name: attribute.Name, Values: values})
+	}
+	return projected
+}
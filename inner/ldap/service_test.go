@@ -0,0 +1,344 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"idm/inner/ldif"
+	"testing"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockDirectory struct {
+	mock.Mock
+}
+
+func (m *MockDirectory) Export(ctx context.Context) ([]ldif.Entry, error) {
+	args := m.Called(ctx)
+	if entries, ok := args.Get(0).(func(context.Context) []ldif.Entry); ok {
+		return entries(ctx), args.Error(1)
+	}
+	return args.Get(0).([]ldif.Entry), args.Error(1)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+const bindDn = "cn=reader,dc=idm,dc=local"
+
+// newDirectory - каталог из контейнеров, трёх сотрудников и двух ролей; Export каждый раз
+// возвращает новую копию, как и выгрузка из базы
+func newDirectory() *MockDirectory {
+	var tree, _ = ldif.NewTree("dc=idm,dc=local")
+	var entries = func() []ldif.Entry {
+		var entries = tree.Containers()
+		for _, p := range []struct{ uid, name, surname, id string }{
+			{"ivanov", "Ivan", "Ivanov", "1"},
+			{"petrov", "Petr", "Petrov", "2"},
+			{"smirnova", "Анна", "Смирнова", "3"},
+		} {
+			var entry = ldif.Entry{DN: "uid=" + p.uid + ",ou=people,dc=idm,dc=local"}
+			entry.Add("objectClass", "top", "person", "organizationalPerson", ldif.ClassPerson)
+			entry.Add("cn", p.name+" "+p.surname)
+			entry.Add("sn", p.surname)
+			entry.Add("uid", p.uid)
+			entry.Add("employeeNumber", p.id)
+			entries = append(entries, entry)
+		}
+		var accountants = ldif.Entry{DN: "cn=Accountants,ou=groups,dc=idm,dc=local"}
+		accountants.Add("objectClass", "top", ldif.ClassGroup)
+		accountants.Add("cn", "Accountants")
+		accountants.Add("member", "uid=ivanov,ou=people,dc=idm,dc=local", "uid=smirnova,ou=people,dc=idm,dc=local")
+		var empty = ldif.Entry{DN: "cn=Empty,ou=groups,dc=idm,dc=local"}
+		empty.Add("objectClass", "top", ldif.ClassGroup)
+		empty.Add("cn", "Empty")
+		empty.Add("member", "")
+		return append(entries, accountants, empty)
+	}
+	var directory = new(MockDirectory)
+	directory.On("Export", mock.Anything).Return(func(context.Context) []ldif.Entry { return entries() }, nil)
+	return directory
+}
+
+func newService(directory Directory) *Service {
+	var tree, _ = ldif.NewTree("dc=idm,dc=local")
+	return NewService(directory, tree, Options{BindDn: bindDn, BindPassword: "secret"}, &MockLogger{})
+}
+
+func compile(t *testing.T, filter string) Filter {
+	packet, err := goldap.CompileFilter(filter)
+	assert.NoError(t, err)
+	parsed, err := ParseFilter(packet)
+	assert.NoError(t, err)
+	return parsed
+}
+
+func dns(entries []ldif.Entry) []string {
+	var dns []string
+	for _, entry := range entries {
+		dns = append(dns, entry.DN)
+	}
+	return dns
+}
+
+func TestBind(t *testing.T) {
+	var svc = newService(newDirectory())
+
+	t.Run("Should bind service account with DN in any case", func(t *testing.T) {
+		a := assert.New(t)
+
+		bound, err := svc.Bind("CN=Reader, DC=idm,DC=local", "secret")
+
+		a.NoError(err)
+		a.Equal(bindDn, bound)
+	})
+
+	t.Run("Should bind anonymously", func(t *testing.T) {
+		a := assert.New(t)
+
+		bound, err := svc.Bind("", "")
+
+		a.NoError(err)
+		a.Empty(bound)
+	})
+
+	t.Run("Should reject invalid credentials", func(t *testing.T) {
+		a := assert.New(t)
+		for _, credentials := range [][2]string{{bindDn, "wrong"}, {"cn=other,dc=idm,dc=local", "secret"}, {bindDn, ""}, {"invalid", "secret"}} {
+			_, err := svc.Bind(credentials[0], credentials[1])
+
+			a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials), credentials[0])
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	var ctx = context.Background()
+
+	t.Run("Should find people by uid, cn, sn and memberOf", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var cases = map[string][]string{
+			"(uid=IVANOV)": {"uid=ivanov,ou=people,dc=idm,dc=local"},
+			"(&(objectClass=inetOrgPerson)(cn=*ov*))": {
+				"uid=ivanov,ou=people,dc=idm,dc=local", "uid=petrov,ou=people,dc=idm,dc=local",
+			},
+			"(sn=смирнова)": {"uid=smirnova,ou=people,dc=idm,dc=local"},
+			"(memberOf=CN=Accountants, OU=groups,DC=idm,DC=local)": {
+				"uid=ivanov,ou=people,dc=idm,dc=local", "uid=smirnova,ou=people,dc=idm,dc=local",
+			},
+			"(&(uid=*)(!(memberOf=cn=Accountants,ou=groups,dc=idm,dc=local)))": {"uid=petrov,ou=people,dc=idm,dc=local"},
+			"(|(uid=petrov)(cn=empty))": {
+				"uid=petrov,ou=people,dc=idm,dc=local", "cn=Empty,ou=groups,dc=idm,dc=local",
+			},
+			"(employeeNumber>=2)": {
+				"uid=petrov,ou=people,dc=idm,dc=local", "uid=smirnova,ou=people,dc=idm,dc=local",
+			},
+			"(member=uid=smirnova,ou=people,dc=idm,dc=local)": {"cn=Accountants,ou=groups,dc=idm,dc=local"},
+		}
+		for filter, expected := range cases {
+			rsl, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "dc=idm,dc=local", Scope: ScopeSubtree, Filter: compile(t, filter)})
+
+			a.NoError(err, filter)
+			a.Equal(expected, dns(rsl.Entries), filter)
+		}
+	})
+
+	t.Run("Should limit entries by scope", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var all = compile(t, "(objectClass=*)")
+
+		base, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "ou=people,dc=idm,dc=local", Scope: ScopeBase, Filter: all})
+		a.NoError(err)
+		a.Equal([]string{"ou=people,dc=idm,dc=local"}, dns(base.Entries))
+
+		one, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "dc=idm,dc=local", Scope: ScopeOneLevel, Filter: all})
+		a.NoError(err)
+		a.Equal([]string{"ou=people,dc=idm,dc=local", "ou=groups,dc=idm,dc=local"}, dns(one.Entries))
+
+		sub, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "dc=idm,dc=local", Scope: ScopeSubtree, Filter: all})
+		a.NoError(err)
+		a.Len(sub.Entries, 8)
+	})
+
+	t.Run("Should return requested attributes only", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var request = SearchRequest{
+			BaseDn:     "ou=people,dc=idm,dc=local",
+			Scope:      ScopeOneLevel,
+			Filter:     compile(t, "(uid=ivanov)"),
+			Attributes: []string{"UID", "memberOf"},
+		}
+
+		rsl, err := svc.Search(ctx, bindDn, request)
+
+		a.NoError(err)
+		a.Equal([]ldif.Attribute{
+			{Name: "uid", Values: []string{"ivanov"}},
+			{Name: "memberOf", Values: []string{"cn=Accountants,ou=groups,dc=idm,dc=local"}},
+		}, rsl.Entries[0].Attributes)
+
+		request.Attributes = []string{"1.1"}
+		rsl, err = svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		a.Empty(rsl.Entries[0].Attributes)
+
+		request.Attributes, request.TypesOnly = []string{"sn"}, true
+		rsl, err = svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		a.Equal([]ldif.Attribute{{Name: "sn"}}, rsl.Entries[0].Attributes)
+	})
+
+	t.Run("Should page results with cookie", func(t *testing.T) {
+		a := assert.New(t)
+		var directory = newDirectory()
+		var svc = newService(directory)
+		var request = SearchRequest{
+			BaseDn: "ou=people,dc=idm,dc=local",
+			Scope:  ScopeOneLevel,
+			Filter: compile(t, "(uid=*)"),
+			Paging: &Paging{Size: 2},
+		}
+
+		first, err := svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		a.Equal([]string{"uid=ivanov,ou=people,dc=idm,dc=local", "uid=petrov,ou=people,dc=idm,dc=local"}, dns(first.Entries))
+		a.Equal(3, first.Total)
+		a.NotEmpty(first.Cookie)
+
+		request.Paging.Cookie = first.Cookie
+		second, err := svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		a.Equal([]string{"uid=smirnova,ou=people,dc=idm,dc=local"}, dns(second.Entries))
+		a.Equal(3, second.Total)
+		a.Empty(second.Cookie)
+		directory.AssertNumberOfCalls(t, "Export", 1)
+
+		request.Paging.Cookie = []byte("100")
+		_, err = svc.Search(ctx, bindDn, request)
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultUnwillingToPerform))
+	})
+
+	t.Run("Should reject cookie of closed, expired or foreign cursor", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var request = SearchRequest{
+			BaseDn: "ou=people,dc=idm,dc=local",
+			Scope:  ScopeOneLevel,
+			Filter: compile(t, "(uid=*)"),
+			Paging: &Paging{Size: 1},
+		}
+		var unwilling = func(err error) bool { return goldap.IsErrorWithCode(err, goldap.LDAPResultUnwillingToPerform) }
+
+		first, err := svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		request.Paging.Cookie = first.Cookie
+
+		_, err = svc.Search(ctx, "cn=other,dc=idm,dc=local", request)
+		a.True(unwilling(err))
+
+		request.Paging.Size = 0
+		abandoned, err := svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		a.Empty(abandoned.Entries)
+		request.Paging.Size = 1
+		_, err = svc.Search(ctx, bindDn, request)
+		a.True(unwilling(err), "abandoned cursor must be closed")
+
+		request.Paging.Cookie = nil
+		first, err = svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+		for _, cur := range svc.cursors.items {
+			cur.expires = time.Now().Add(-time.Second)
+		}
+		request.Paging.Cookie = first.Cookie
+		_, err = svc.Search(ctx, bindDn, request)
+		a.True(unwilling(err))
+	})
+
+	t.Run("Should keep cursors within the limit", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var request = SearchRequest{
+			BaseDn: "ou=people,dc=idm,dc=local",
+			Scope:  ScopeOneLevel,
+			Filter: compile(t, "(uid=*)"),
+			Paging: &Paging{Size: 1},
+		}
+		var cookies [][]byte
+		for range maxCursors + 1 {
+			rsl, err := svc.Search(ctx, bindDn, request)
+			a.NoError(err)
+			cookies = append(cookies, rsl.Cookie)
+		}
+
+		a.Len(svc.cursors.items, maxCursors)
+		request.Paging.Cookie = cookies[maxCursors]
+		_, err := svc.Search(ctx, bindDn, request)
+		a.NoError(err)
+	})
+
+	t.Run("Should truncate results by size limit", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+
+		rsl, err := svc.Search(ctx, bindDn, SearchRequest{
+			BaseDn: "ou=people,dc=idm,dc=local", Scope: ScopeOneLevel, SizeLimit: 1, Filter: compile(t, "(uid=*)"),
+		})
+
+		a.NoError(err)
+		a.Len(rsl.Entries, 1)
+		a.True(rsl.SizeLimitExceeded)
+	})
+
+	t.Run("Should reject anonymous search except root DSE", func(t *testing.T) {
+		a := assert.New(t)
+		var directory = newDirectory()
+		var svc = newService(directory)
+
+		_, err := svc.Search(ctx, "", SearchRequest{BaseDn: "dc=idm,dc=local", Scope: ScopeSubtree, Filter: compile(t, "(uid=*)")})
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultInsufficientAccessRights))
+
+		rsl, err := svc.Search(ctx, "", SearchRequest{Scope: ScopeBase, Filter: compile(t, "(objectClass=*)")})
+		a.NoError(err)
+		a.Equal([]string{"dc=idm,dc=local"}, rsl.Entries[0].Values("namingContexts"))
+		directory.AssertNotCalled(t, "Export", mock.Anything)
+	})
+
+	t.Run("Should return no such object for unknown base", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(newDirectory())
+		var all = compile(t, "(objectClass=*)")
+
+		_, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "dc=example,dc=com", Scope: ScopeSubtree, Filter: all})
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject))
+
+		_, err = svc.Search(ctx, bindDn, SearchRequest{BaseDn: "uid=sidorov,ou=people,dc=idm,dc=local", Scope: ScopeBase, Filter: all})
+		var ldapErr *goldap.Error
+		a.True(errors.As(err, &ldapErr))
+		a.Equal(uint16(goldap.LDAPResultNoSuchObject), ldapErr.ResultCode)
+		a.Equal("dc=idm,dc=local", ldapErr.MatchedDN)
+
+		_, err = svc.Search(ctx, bindDn, SearchRequest{BaseDn: "not a dn", Scope: ScopeBase, Filter: all})
+		a.True(goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidDNSyntax))
+	})
+
+	t.Run("Should return directory error", func(t *testing.T) {
+		a := assert.New(t)
+		var directory = new(MockDirectory)
+		var svc = newService(directory)
+		directory.On("Export", mock.Anything).Return([]ldif.Entry(nil), errors.New("database error"))
+
+		_, err := svc.Search(ctx, bindDn, SearchRequest{BaseDn: "dc=idm,dc=local", Scope: ScopeSubtree})
+
+		a.ErrorContains(err, "database error")
+	})
+}
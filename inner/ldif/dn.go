@@ -0,0 +1,164 @@
+package ldif
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RDN - относительное имя записи: тип атрибута и значение без экранирования
+type RDN struct {
+	Type  string
+	Value string
+}
+
+// DN - имя записи от самого младшего RDN к корню
+type DN []RDN
+
+// ParseDN - разбор строкового DN (RFC 4514). Многозначные RDN и значения в виде #hex не поддерживаются
+func ParseDN(dn string) (DN, error) {
+	var parsed DN
+	var rest = strings.TrimLeft(dn, " ")
+	for rest != "" {
+		var rdn RDN
+		var err error
+		rdn, rest, err = parseRDN(rest)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid DN %q: %w", dn, err)
+		}
+		parsed = append(parsed, rdn)
+	}
+	return parsed, nil
+}
+
+// parseRDN - первый RDN из s и остаток строки после разделяющей запятой
+func parseRDN(s string) (RDN, string, error) {
+	var eq = strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return RDN{}, "", fmt.Errorf("attribute type expected in %q", s)
+	}
+	var rdn = RDN{Type: strings.TrimSpace(s[:eq])}
+	var value []byte
+	// escaped - длина value, защищённая экранированием от обрезки хвостовых пробелов
+	var escaped int
+	var i = eq + 1
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i < len(s) && s[i] == '#' {
+		return RDN{}, "", fmt.Errorf("hex-encoded value of %s is not supported", rdn.Type)
+	}
+	for ; i < len(s); i++ {
+		var c = s[i]
+		switch {
+		case c == ',' || c == ';':
+			rdn.Value = trimUnescaped(value, escaped)
+			return rdn, strings.TrimLeft(s[i+1:], " "), checkRDN(rdn)
+		case c == '+':
+			return RDN{}, "", fmt.Errorf("multi-valued RDN %s is not supported", s[:i])
+		case c == '\\':
+			if i+1 >= len(s) {
+				return RDN{}, "", fmt.Errorf("dangling escape in %q", s)
+			}
+			if b, ok := unhex(s[i+1:]); ok {
+				value = append(value, b)
+				i += 2
+			} else {
+				value = append(value, s[i+1])
+				i++
+			}
+			escaped = len(value)
+		default:
+			value = append(value, c)
+		}
+	}
+	rdn.Value = trimUnescaped(value, escaped)
+	return rdn, "", checkRDN(rdn)
+}
+
+func checkRDN(rdn RDN) error {
+	if rdn.Type == "" || strings.ContainsAny(rdn.Type, " =") {
+		return fmt.Errorf("invalid attribute type %q", rdn.Type)
+	}
+	return nil
+}
+
+// trimUnescaped - значение без хвостовых пробелов, кроме экранированных
+func trimUnescaped(value []byte, escaped int) string {
+	var end = len(value)
+	for end > escaped && value[end-1] == ' ' {
+		end--
+	}
+	return string(value[:end])
+}
+
+func unhex(s string) (byte, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	var b byte
+	for _, c := range []byte(s[:2]) {
+		switch {
+		case c >= '0' && c <= '9':
+			b = b<<4 | (c - '0')
+		case c >= 'a' && c <= 'f':
+			b = b<<4 | (c - 'a' + 10)
+		case c >= 'A' && c <= 'F':
+			b = b<<4 | (c - 'A' + 10)
+		default:
+			return 0, false
+		}
+	}
+	return b, true
+}
+
+// EscapeValue - значение RDN с экранированием специальных символов по RFC 4514. Символы
+// кириллицы остаются как есть: DN в LDIF с не-ASCII символами записывается в base64
+func EscapeValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		var c = value[i]
+		switch {
+		case c == 0:
+			b.WriteString(`\00`)
+		case strings.IndexByte(`"+,;<>\`, c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(value)-1):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (dn DN) String() string {
+	var parts = make([]string, 0, len(dn))
+	for _, rdn := range dn {
+		parts = append(parts, rdn.Type+"="+EscapeValue(rdn.Value))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Child - DN дочерней записи с RDN typ=value
+func (dn DN) Child(typ, value string) DN {
+	return append(DN{{Type: typ, Value: value}}, dn...)
+}
+
+// Equal - совпадение DN без учёта регистра типов и значений, как у cn и uid
+func (dn DN) Equal(other DN) bool {
+	if len(dn) != len(other) {
+		return false
+	}
+	for i := range dn {
+		if !strings.EqualFold(dn[i].Type, other[i].Type) || !strings.EqualFold(dn[i].Value, other[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// key - нормализованная форма для поиска записей по DN
+func (dn DN) key() string {
+	return strings.ToLower(dn.String())
+}
@@ -0,0 +1,130 @@
+package ldif
+
+import (
+	"idm/inner/employee"
+	"slices"
+	"strings"
+)
+
+// ContentType - медиатип выгрузки LDIF
+const ContentType = "text/ldif; charset=utf-8"
+
+// Классы объектов записей каталога
+const (
+	ClassPerson = "inetOrgPerson"
+	ClassGroup  = "groupOfNames"
+)
+
+// Контейнеры сотрудников и ролей под базовым DN
+const (
+	OuPeople = "people"
+	OuGroups = "groups"
+)
+
+// Действия импорта
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionAssign = "assign"
+	ActionRevoke = "revoke"
+)
+
+// Объекты, которые меняет импорт
+const (
+	ObjectEmployee = "employee"
+	ObjectRole     = "role"
+)
+
+// Attribute - атрибут записи со всеми значениями
+type Attribute struct {
+	Name   string
+	Values []string
+}
+
+// Entry - запись каталога; DN хранится строкой в том виде, в каком записан в LDIF
+type Entry struct {
+	DN         string
+	Attributes []Attribute
+}
+
+// Add - добавляет значение атрибуту; имена атрибутов сравниваются без учёта регистра
+func (e *Entry) Add(name string, values ...string) {
+	for i := range e.Attributes {
+		if strings.EqualFold(e.Attributes[i].Name, name) {
+			e.Attributes[i].Values = append(e.Attributes[i].Values, values...)
+			return
+		}
+	}
+	e.Attributes = append(e.Attributes, Attribute{Name: name, Values: values})
+}
+
+// Values - все значения атрибута, nil - атрибута нет
+func (e *Entry) Values(name string) []string {
+	for _, attribute := range e.Attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute.Values
+		}
+	}
+	return nil
+}
+
+// First - первое значение атрибута или пустая строка
+func (e *Entry) First(name string) string {
+	var values = e.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Has - запись содержит атрибут
+func (e *Entry) Has(name string) bool {
+	return e.Values(name) != nil
+}
+
+// IsA - запись относится к классу объектов class
+func (e *Entry) IsA(class string) bool {
+	return slices.ContainsFunc(e.Values("objectClass"), func(value string) bool {
+		return strings.EqualFold(value, class)
+	})
+}
+
+// ImportRequest - параметры импорта. DefaultAge - возраст новых сотрудников: в inetOrgPerson
+// его нет, а без него сотрудник не создаётся
+type ImportRequest struct {
+	DryRun     bool `query:"dry_run"`
+	DefaultAge int8 `query:"default_age" validate:"omitempty,min=17,max=90"`
+}
+
+// Change - изменение, которое вносит импорт. Fields - различия атрибутов LDAP между текущим
+// состоянием и файлом; Member - DN сотрудника, которому назначается или у которого отзывается роль
+type Change struct {
+	Action string                 `json:"action" example:"update"`
+	Object string                 `json:"object" example:"employee"`
+	DN     string                 `json:"dn" example:"uid=ivanov,ou=people,dc=idm,dc=local"`
+	Id     int64                  `json:"id,omitempty"`
+	Fields []employee.FieldChange `json:"fields,omitempty"`
+	Member string                 `json:"member,omitempty"`
+	// Error - изменение не удалось внести; остальные изменения импорта при этом вносятся
+	Error string `json:"error,omitempty"`
+}
+
+// Skipped - запись файла, которую импорт пропустил, и причина
+type Skipped struct {
+	DN     string `json:"dn"`
+	Reason string `json:"reason"`
+}
+
+// ImportResponse - результат импорта или, при dry_run, план изменений
+type ImportResponse struct {
+	DryRun    bool      `json:"dry_run"`
+	Changes   []Change  `json:"changes"`
+	Skipped   []Skipped `json:"skipped"`
+	Unchanged int       `json:"unchanged"`
+}
+
+// Membership - действующее назначение роли сотруднику
+type Membership struct {
+	RoleId     int64 `db:"role_id"`
+	EmployeeId int64 `db:"employee_id"`
+}
@@ -0,0 +1,104 @@
+package ldif
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Export(ctx context.Context) ([]Entry, error)
+	Import(ctx context.Context, actor string, entries []Entry, request ImportRequest) (ImportResponse, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/ldif"
+func (c *Handler) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/ldif/export", c.server.RequirePermission(web.PermLdifExport), c.Export)
+	c.server.GroupApiV1.Post("/ldif/import", c.server.RequirePermission(web.PermLdifImport), c.Import)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/ldif/export"
+// @Description Export employees as inetOrgPerson and roles as groupOfNames entries under the configured base DN.
+// @Summary export directory as LDIF
+// @Tags ldif
+// @Produce text/ldif
+// @Success 200 {string} string "LDIF file"
+// @Failure 403 {object} common.Response[string] "Permission denied"
+// @Failure 500 {object} common.Response[string] "error db"
+// @Router /ldif/export [get]
+// @Security BearerAuth
+func (c *Handler) Export(ctx *fiber.Ctx) error {
+	entries, err := c.service.Export(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Export: error exporting directory", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Export: error writing ldif", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	ctx.Set(fiber.HeaderContentType, ContentType)
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="directory.ldif"`)
+	return ctx.Send(buf.Bytes())
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/ldif/import"
+// @Description Import inetOrgPerson and groupOfNames entries into employees and roles. With dry_run returns the diff without applying it.
+// @Summary import directory from LDIF
+// @Tags ldif
+// @Accept text/ldif
+// @Produce json
+// @Param request body string true "LDIF file"
+// @Param dry_run query bool false "Only return planned changes"
+// @Param default_age query int false "Age of created employees, required when the file has new employees"
+// @Success 200 {object} common.Response[ldif.ImportResponse]
+// @Failure 400 {object} common.Response[ldif.ImportResponse] "invalid LDIF"
+// @Failure 403 {object} common.Response[ldif.ImportResponse] "Permission denied"
+// @Failure 500 {object} common.Response[ldif.ImportResponse] "error db"
+// @Router /ldif/import [post]
+// @Security BearerAuth
+func (c *Handler) Import(ctx *fiber.Ctx) error {
+	var request ImportRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Import: query parse error", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	entries, err := Read(bytes.NewReader(ctx.Body()))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Import: error reading ldif", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var actor string
+	if claims, ok := web.ClaimsFromCtx(ctx); ok {
+		actor = claims.Actor()
+	}
+	rsl, err := c.service.Import(ctx.Context(), actor, entries, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Import: error importing ldif", zap.Error(err))
+		if errors.As(err, &common.RequestValidationError{}) {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	return common.OkResponse(ctx, rsl)
+}
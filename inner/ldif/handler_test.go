@@ -0,0 +1,114 @@
+package ldif
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Export(ctx context.Context) ([]Entry, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entry), args.Error(1)
+}
+
+func (m *MockService) Import(ctx context.Context, actor string, entries []Entry, request ImportRequest) (ImportResponse, error) {
+	args := m.Called(ctx, actor, entries, request)
+	return args.Get(0).(ImportResponse), args.Error(1)
+}
+
+func newTestServer(roles []string, svc Svc) *web.Server {
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := web.NewServer()
+	claims := &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}, PreferredUsername: "john"}
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	NewHandler(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestLdifHandler(t *testing.T) {
+	t.Run("Should export directory as LDIF attachment", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		var entry = Entry{DN: "ou=people,dc=idm,dc=local"}
+		entry.Add("ou", "people")
+		svc.On("Export", mock.Anything).Return([]Entry{entry}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/ldif/export", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal(ContentType, resp.Header.Get(fiber.HeaderContentType))
+		a.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "directory.ldif")
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Equal("version: 1\n\ndn: ou=people,dc=idm,dc=local\nou: people\n", string(body))
+	})
+
+	t.Run("Should import LDIF with query parameters", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		var entry = Entry{DN: "uid=ivanov,ou=people,dc=idm,dc=local"}
+		entry.Add("sn", "Ivanov")
+		var response = ImportResponse{DryRun: true, Unchanged: 1}
+		svc.On("Import", mock.Anything, "john", []Entry{entry}, ImportRequest{DryRun: true, DefaultAge: 30}).
+			Return(response, nil)
+		var req = httptest.NewRequest(fiber.MethodPost, "/api/v1/ldif/import?dry_run=true&default_age=30",
+			strings.NewReader("dn: uid=ivanov,ou=people,dc=idm,dc=local\nsn: Ivanov\n"))
+		req.Header.Set(fiber.HeaderContentType, ContentType)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var body common.Response[ImportResponse]
+		a.Nil(json.NewDecoder(resp.Body).Decode(&body))
+		a.Equal(response, body.Data)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("Should return 400 on invalid LDIF", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		var req = httptest.NewRequest(fiber.MethodPost, "/api/v1/ldif/import",
+			strings.NewReader("dn: uid=ivanov,dc=local\nchangetype: delete\n"))
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Import", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return 403 without permission", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/ldif/export", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
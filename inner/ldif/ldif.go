@@ -0,0 +1,178 @@
+package ldif
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineWidth - длина строки, после которой значение переносится на строку продолжения
+const lineWidth = 76
+
+// Write - записи в формате LDIF (RFC 2849): строка версии и записи, разделённые пустой строкой.
+// Значения с не-ASCII символами, переводами строк и пробелами по краям записываются в base64
+func Write(w io.Writer, entries []Entry) error {
+	var writer = bufio.NewWriter(w)
+	writeLine(writer, "version: 1")
+	for _, entry := range entries {
+		_ = writer.WriteByte('\n')
+		writeLine(writer, encodeLine("dn", entry.DN))
+		for _, attribute := range entry.Attributes {
+			for _, value := range attribute.Values {
+				writeLine(writer, encodeLine(attribute.Name, value))
+			}
+		}
+	}
+	return writer.Flush()
+}
+
+func encodeLine(name, value string) string {
+	if value == "" {
+		return name + ":"
+	}
+	if isSafe(value) {
+		return name + ": " + value
+	}
+	return name + ":: " + base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+// isSafe - значение можно записать без base64: SAFE-STRING по RFC 2849 без пробела в конце
+func isSafe(value string) bool {
+	for i := 0; i < len(value); i++ {
+		var c = value[i]
+		if c == 0 || c == '\n' || c == '\r' || c > 127 {
+			return false
+		}
+	}
+	var first = value[0]
+	return first != ' ' && first != ':' && first != '<' && value[len(value)-1] != ' '
+}
+
+// writeLine - логическая строка с переносом: строки продолжения начинаются с пробела
+func writeLine(writer *bufio.Writer, line string) {
+	var width = lineWidth
+	for len(line) > width {
+		_, _ = writer.WriteString(line[:width])
+		_, _ = writer.WriteString("\n ")
+		line = line[width:]
+		width = lineWidth - 1
+	}
+	_, _ = writer.WriteString(line)
+	_ = writer.WriteByte('\n')
+}
+
+// Read - записи содержимого из LDIF. Записи изменений (changetype) и значения по ссылке (:<)
+// не поддерживаются; одноимённые атрибуты записи объединяются
+func Read(r io.Reader) ([]Entry, error) {
+	var scanner = bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var entries []Entry
+	var lines []string
+	var start, number int
+	// comment - текущая логическая строка - комментарий, её продолжения пропускаются
+	var comment bool
+	var flush = func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		var record = lines
+		lines = nil
+		if len(entries) == 0 && strings.HasPrefix(strings.ToLower(record[0]), "version:") {
+			if strings.TrimSpace(record[0][len("version:"):]) != "1" {
+				return fmt.Errorf("Line %d: unsupported LDIF %s", start, record[0])
+			}
+			record = record[1:]
+			if len(record) == 0 {
+				return nil
+			}
+		}
+		entry, err := parseRecord(record)
+		if err != nil {
+			return fmt.Errorf("Record at line %d: %w", start, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+	for scanner.Scan() {
+		number++
+		var line = strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			comment = false
+		case line[0] == ' ':
+			if comment {
+				continue
+			}
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("Line %d: continuation without preceding line", number)
+			}
+			lines[len(lines)-1] += line[1:]
+		case line[0] == '#':
+			comment = true
+		default:
+			if len(lines) == 0 {
+				start = number
+			}
+			lines = append(lines, line)
+			comment = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseRecord(lines []string) (Entry, error) {
+	var entry Entry
+	for i, line := range lines {
+		name, value, err := decodeLine(line)
+		if err != nil {
+			return Entry{}, err
+		}
+		if i == 0 {
+			if !strings.EqualFold(name, "dn") {
+				return Entry{}, fmt.Errorf("dn expected, got %s", name)
+			}
+			entry.DN = value
+			continue
+		}
+		if strings.EqualFold(name, "changetype") {
+			return Entry{}, fmt.Errorf("change records are not supported, only content records")
+		}
+		entry.Add(name, value)
+	}
+	return entry, nil
+}
+
+func decodeLine(line string) (name, value string, err error) {
+	var colon = strings.IndexByte(line, ':')
+	if colon <= 0 {
+		return "", "", fmt.Errorf("attribute expected in %q", line)
+	}
+	name, value = line[:colon], line[colon+1:]
+	switch {
+	case strings.HasPrefix(value, ":"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+		if err != nil {
+			return "", "", fmt.Errorf("invalid base64 value of %s: %w", name, err)
+		}
+		if !utf8.Valid(decoded) {
+			return "", "", fmt.Errorf("value of %s is not valid UTF-8", name)
+		}
+		return name, string(decoded), nil
+	case strings.HasPrefix(value, "<"):
+		return "", "", fmt.Errorf("URL value of %s is not supported", name)
+	default:
+		return name, strings.TrimLeft(value, " "), nil
+	}
+}
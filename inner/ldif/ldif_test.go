@@ -0,0 +1,170 @@
+package ldif
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("Should write cyrillic values and DN in base64", func(t *testing.T) {
+		a := assert.New(t)
+		var entry = Entry{DN: "uid=ivanov,ou=people,dc=idm,dc=local"}
+		entry.Add("sn", "Иванов")
+		entry.Add("cn", "Ivan Ivanov")
+		var cyrillic = Entry{DN: "cn=Бухгалтерия,ou=groups,dc=idm,dc=local"}
+
+		var buf bytes.Buffer
+		a.NoError(Write(&buf, []Entry{entry, cyrillic}))
+
+		a.Equal("version: 1\n\n"+
+			"dn: uid=ivanov,ou=people,dc=idm,dc=local\n"+
+			"sn:: 0JjQstCw0L3QvtCy\n"+
+			"cn: Ivan Ivanov\n\n"+
+			"dn:: Y2490JHRg9GF0LPQsNC70YLQtdGA0LjRjyxvdT1ncm91cHMsZGM9aWRtLGRjPWxvY2Fs\n",
+			buf.String())
+	})
+
+	t.Run("Should write unsafe values in base64 and empty values without space", func(t *testing.T) {
+		a := assert.New(t)
+		var entry = Entry{DN: "cn=empty,ou=groups,dc=idm,dc=local"}
+		entry.Add("member", "")
+		entry.Add("description", ":colon", " leading", "trailing ", "line\nbreak")
+
+		var buf bytes.Buffer
+		a.NoError(Write(&buf, []Entry{entry}))
+
+		a.Contains(buf.String(), "\nmember:\n")
+		a.NotContains(buf.String(), "description: ")
+		a.Equal(4, strings.Count(buf.String(), "description:: "))
+	})
+
+	t.Run("Should fold long lines at 76 characters", func(t *testing.T) {
+		a := assert.New(t)
+		var entry = Entry{DN: "cn=long,dc=idm,dc=local"}
+		entry.Add("description", strings.Repeat("x", 200))
+
+		var buf bytes.Buffer
+		a.NoError(Write(&buf, []Entry{entry}))
+
+		var lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for _, line := range lines {
+			a.LessOrEqual(len(line), lineWidth)
+		}
+		a.Equal(" ", lines[4][:1])
+		read, err := Read(&buf)
+		a.NoError(err)
+		a.Equal(strings.Repeat("x", 200), read[0].First("description"))
+	})
+}
+
+func TestRead(t *testing.T) {
+	t.Run("Should read entries written by Write", func(t *testing.T) {
+		a := assert.New(t)
+		var entry = Entry{DN: "cn=Отдел кадров,ou=groups,dc=idm,dc=local"}
+		entry.Add("objectClass", "top", ClassGroup)
+		entry.Add("description", strings.Repeat("Управление персоналом ", 10))
+		entry.Add("member", "uid=ivanov,ou=people,dc=idm,dc=local", "uid=petrov,ou=people,dc=idm,dc=local")
+		var buf bytes.Buffer
+		a.NoError(Write(&buf, []Entry{entry}))
+
+		read, err := Read(&buf)
+
+		a.NoError(err)
+		a.Equal([]Entry{entry}, read)
+	})
+
+	t.Run("Should skip comments, join continuations and merge attributes", func(t *testing.T) {
+		a := assert.New(t)
+		var source = "version: 1\r\n" +
+			"# comment\r\n" +
+			"  continued comment\r\n" +
+			"dn: uid=ivanov,ou=people,\r\n" +
+			" dc=idm,dc=local\r\n" +
+			"objectClass: inetOrgPerson\r\n" +
+			"mail: ivanov@example.com\r\n" +
+			"sn:: 0JjQstCw0L3QvtCy\r\n" +
+			"objectclass: person\r\n" +
+			"\r\n\r\n" +
+			"dn: uid=petrov,ou=people,dc=idm,dc=local\r\n" +
+			"sn: Petrov\r\n"
+
+		read, err := Read(strings.NewReader(source))
+
+		a.NoError(err)
+		a.Len(read, 2)
+		a.Equal("uid=ivanov,ou=people,dc=idm,dc=local", read[0].DN)
+		a.Equal([]string{"inetOrgPerson", "person"}, read[0].Values("objectClass"))
+		a.Equal("Иванов", read[0].First("sn"))
+		a.Equal("Petrov", read[1].First("sn"))
+	})
+
+	t.Run("Should reject change records", func(t *testing.T) {
+		a := assert.New(t)
+		var source = "dn: uid=ivanov,ou=people,dc=idm,dc=local\nchangetype: delete\n"
+
+		_, err := Read(strings.NewReader(source))
+
+		a.ErrorContains(err, "line 1")
+	})
+
+	t.Run("Should reject record without dn", func(t *testing.T) {
+		a := assert.New(t)
+
+		_, err := Read(strings.NewReader("version: 1\n\nsn: Ivanov\n"))
+
+		a.ErrorContains(err, "dn expected")
+	})
+}
+
+func TestDN(t *testing.T) {
+	t.Run("Should escape special characters", func(t *testing.T) {
+		a := assert.New(t)
+
+		a.Equal(`Smith\, John \+ \<Co\>`, EscapeValue("Smith, John + <Co>"))
+		a.Equal(`\#1  spaced\ `, EscapeValue("#1  spaced "))
+		a.Equal("Иван=Иванов", EscapeValue("Иван=Иванов"))
+	})
+
+	t.Run("Should parse escaped DN", func(t *testing.T) {
+		a := assert.New(t)
+
+		dn, err := ParseDN(`cn=Smith\, John\2B,ou=people , dc=idm,dc=local`)
+
+		a.NoError(err)
+		a.Equal(DN{{"cn", "Smith, John+"}, {"ou", "people"}, {"dc", "idm"}, {"dc", "local"}}, dn)
+		a.Equal(`cn=Smith\, John\+,ou=people,dc=idm,dc=local`, dn.String())
+	})
+
+	t.Run("Should round trip values through String and ParseDN", func(t *testing.T) {
+		a := assert.New(t)
+		for _, value := range []string{" leading", "trailing ", "#hash", `a\b"c;d`, "Отдел кадров"} {
+			var dn = DN{{Type: "cn", Value: value}}
+
+			parsed, err := ParseDN(dn.String())
+
+			a.NoError(err)
+			a.Equal(dn, parsed, value)
+		}
+	})
+
+	t.Run("Should compare DN case-insensitively", func(t *testing.T) {
+		a := assert.New(t)
+		left, _ := ParseDN("UID=Ivanov,OU=People,DC=idm,DC=local")
+		right, _ := ParseDN("uid=ivanov,ou=people,dc=idm,dc=local")
+
+		a.True(left.Equal(right))
+		a.Equal(left.key(), right.key())
+	})
+
+	t.Run("Should reject unsupported DN", func(t *testing.T) {
+		a := assert.New(t)
+		for _, dn := range []string{"cn=a+sn=b,dc=local", "cn=#04024869,dc=local", "local", `cn=a\`} {
+			_, err := ParseDN(dn)
+
+			a.Error(err, dn)
+		}
+	})
+}
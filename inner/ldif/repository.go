@@ -0,0 +1,52 @@
+package ldif
+
+import (
+	"context"
+	"idm/inner/employee"
+	"idm/inner/role"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) FindEmployees(ctx context.Context) (employees []employee.Entity, err error) {
+	err = r.db.SelectContext(ctx, &employees, "SELECT * FROM employee ORDER BY id")
+	return employees, err
+}
+
+func (r *Repository) FindRoles(ctx context.Context) (roles []role.Entity, err error) {
+	err = r.db.SelectContext(ctx, &roles, "SELECT * FROM role ORDER BY id")
+	return roles, err
+}
+
+// FindMembers - действующие прямые назначения ролей
+func (r *Repository) FindMembers(ctx context.Context) (members []Membership, err error) {
+	err = r.db.SelectContext(ctx, &members,
+		`SELECT role_id, employee_id FROM employee_role
+		 WHERE valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())
+		 ORDER BY role_id, employee_id`)
+	return members, err
+}
+
+// UpdateEmployee - заменяет имя, логин и контакты сотрудника. Отдел, руководитель и должность
+// меняются переводом сотрудника, чтобы пересчитать правила базового доступа
+func (r *Repository) UpdateEmployee(tx *sqlx.Tx, e employee.Entity) (updated employee.Entity, err error) {
+	err = tx.Get(&updated,
+		`UPDATE employee
+		 SET name = $2, surname = $3, login = $4, email = $5, phone = $6, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		e.Id, e.Name, e.Surname, e.Login, e.Email, e.Phone)
+	return updated, err
+}
@@ -0,0 +1,679 @@
+package ldif
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	tree      Tree
+	employees Employees
+	roles     Roles
+	assigner  Assigner
+	validator *validator.Validate
+	logger    common.LoggerInterface
+	// Provisioning - передача учётных атрибутов, изменённых напрямую в репозитории, во внешние
+	// системы; создание и перевод передаёт сервис сотрудников
+	Provisioning Provisioner
+	// Events - запись доменных событий в outbox в транзакции изменения; nil - события не пишутся
+	Events outbox.Writer
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	FindEmployees(ctx context.Context) ([]employee.Entity, error)
+	FindRoles(ctx context.Context) ([]role.Entity, error)
+	FindMembers(ctx context.Context) ([]Membership, error)
+	UpdateEmployee(tx *sqlx.Tx, e employee.Entity) (employee.Entity, error)
+}
+
+// Employees - создание и перевод сотрудников с правилами базового доступа
+type Employees interface {
+	CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error)
+	Move(ctx context.Context, id int64, request employee.MoveRequest) (employee.Response, error)
+}
+
+type Roles interface {
+	Add(role role.Entity) (role.Response, error)
+	Update(ctx context.Context, id int64, request role.Request) (role.Response, error)
+}
+
+// Assigner - назначение и отзыв ролей с проверкой SoD и аудитом
+type Assigner interface {
+	Assign(ctx context.Context, actor string, request assignment.AssignRequest) (assignment.Response, error)
+	Revoke(ctx context.Context, actor string, request assignment.RevokeRequest) error
+}
+
+func NewService(repo Repo, tree Tree, employees Employees, roles Roles, assigner Assigner, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		tree:      tree,
+		employees: employees,
+		roles:     roles,
+		assigner:  assigner,
+		validator: validator.New(),
+		logger:    logger,
+	}
+}
+
+// Export - каталог целиком: контейнеры, сотрудники и роли с действующими участниками
+func (svc *Service) Export(ctx context.Context) ([]Entry, error) {
+	dir, err := svc.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dir.entries(), nil
+}
+
+// Import - приводит сотрудников и роли к записям файла. Сотрудник сопоставляется по
+// employeeNumber, затем по uid; роль - по cn. Записи, которых нет в файле, не удаляются,
+// а состав каждой роли из файла заменяется целиком. При dry_run возвращается только план
+func (svc *Service) Import(ctx context.Context, actor string, entries []Entry, request ImportRequest) (ImportResponse, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return ImportResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	dir, err := svc.load(ctx)
+	if err != nil {
+		return ImportResponse{}, err
+	}
+	var p = newPlan(svc, actor, dir, request)
+	p.build(entries)
+	if !request.DryRun {
+		for _, step := range p.steps() {
+			if step.change.Error != "" {
+				continue
+			}
+			if err := step.apply(ctx); err != nil {
+				step.change.Error = err.Error()
+				svc.logger.ErrorCtx(ctx, "Import: error applying LDIF change",
+					zap.String("action", step.change.Action), zap.String("dn", step.change.DN), zap.Error(err))
+			}
+		}
+	}
+	var response = ImportResponse{
+		DryRun:    request.DryRun,
+		Changes:   make([]Change, 0, len(p.changes)),
+		Skipped:   p.skipped,
+		Unchanged: p.unchanged,
+	}
+	for _, change := range p.changes {
+		response.Changes = append(response.Changes, *change)
+	}
+	return response, nil
+}
+
+func (svc *Service) load(ctx context.Context) (directory, error) {
+	employees, err := svc.repo.FindEmployees(ctx)
+	if err != nil {
+		return directory{}, fmt.Errorf("Error finding employees: %w", err)
+	}
+	roles, err := svc.repo.FindRoles(ctx)
+	if err != nil {
+		return directory{}, fmt.Errorf("Error finding roles: %w", err)
+	}
+	memberships, err := svc.repo.FindMembers(ctx)
+	if err != nil {
+		return directory{}, fmt.Errorf("Error finding role members: %w", err)
+	}
+	var dir = directory{
+		tree:      svc.tree,
+		employees: employees,
+		roles:     roles,
+		byId:      make(map[int64]employee.Entity, len(employees)),
+		members:   map[int64][]int64{},
+	}
+	for _, e := range employees {
+		dir.byId[e.Id] = e
+	}
+	for _, membership := range memberships {
+		dir.members[membership.RoleId] = append(dir.members[membership.RoleId], membership.EmployeeId)
+	}
+	return dir, nil
+}
+
+// updateEmployee - сохраняет учётные атрибуты сотрудника и пишет событие EmployeeUpdated в той же транзакции
+func (svc *Service) updateEmployee(e employee.Entity) error {
+	return common.InTx(svc.repo, "Updating employee", func(tx *sqlx.Tx) error {
+		updated, err := svc.repo.UpdateEmployee(tx, e)
+		if err != nil {
+			return fmt.Errorf("Error updating employee %d: %w", e.Id, err)
+		}
+		return outbox.Write(svc.Events, tx, outbox.AggregateEmployee, e.Id, outbox.EmployeeUpdated, updated.ToResponse())
+	})
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+// directory - текущие сотрудники, роли и их участники
+type directory struct {
+	tree      Tree
+	employees []employee.Entity
+	roles     []role.Entity
+	byId      map[int64]employee.Entity
+	members   map[int64][]int64
+}
+
+// personDN - DN сотрудника id, пусто - сотрудника нет
+func (d directory) personDN(id int64) string {
+	if e, ok := d.byId[id]; ok {
+		return d.tree.PersonDN(e).String()
+	}
+	return ""
+}
+
+func (d directory) entries() []Entry {
+	var entries = d.tree.Containers()
+	for _, e := range d.employees {
+		var manager string
+		if e.ManagerId.Valid {
+			manager = d.personDN(e.ManagerId.Int64)
+		}
+		entries = append(entries, d.tree.Person(e, manager))
+	}
+	for _, r := range d.roles {
+		var members []string
+		for _, id := range d.members[r.Id] {
+			members = append(members, d.personDN(id))
+		}
+		slices.Sort(members)
+		entries = append(entries, d.tree.Group(r, members))
+	}
+	return entries
+}
+
+// person - сотрудник каталога или файла. У нового сотрудника current = nil, а id появляется
+// после создания
+type person struct {
+	dn      string
+	current *employee.Entity
+	id      int64
+	entry   *Entry
+	values  map[string]string
+	manager *person
+	skipped bool
+}
+
+type group struct {
+	dn          string
+	current     *role.Entity
+	id          int64
+	name        string
+	description string
+	members     []*person
+}
+
+// step - действие, вносящее изменение change; ошибка записывается в change
+type step struct {
+	change *Change
+	apply  func(ctx context.Context) error
+}
+
+// plan - изменения, которые приводят каталог к содержимому файла
+type plan struct {
+	svc     *Service
+	actor   string
+	dir     directory
+	request ImportRequest
+	now     time.Time
+	// people - сотрудники по нормализованному DN: из каталога и из файла
+	people  map[string]*person
+	byId    map[int64]*person
+	byLogin map[string]*person
+	persons []*person
+	// imported - роли, уже сопоставленные записям файла
+	imported      map[int64]bool
+	changes       []*Change
+	employeeSteps []step
+	moveSteps     []step
+	roleSteps     []step
+	memberSteps   []step
+	skipped       []Skipped
+	unchanged     int
+}
+
+func newPlan(svc *Service, actor string, dir directory, request ImportRequest) *plan {
+	var p = &plan{
+		svc:      svc,
+		actor:    actor,
+		dir:      dir,
+		request:  request,
+		now:      time.Now(),
+		people:   map[string]*person{},
+		byId:     map[int64]*person{},
+		byLogin:  map[string]*person{},
+		imported: map[int64]bool{},
+		skipped:  []Skipped{},
+	}
+	for i := range dir.employees {
+		var e = &dir.employees[i]
+		var existing = &person{dn: dir.tree.PersonDN(*e).String(), current: e, id: e.Id}
+		p.people[dir.tree.PersonDN(*e).key()] = existing
+		p.byId[e.Id] = existing
+		if e.Login.Valid && e.Login.String != "" {
+			p.byLogin[strings.ToLower(e.Login.String)] = existing
+		}
+	}
+	return p
+}
+
+func (p *plan) steps() []step {
+	return slices.Concat(p.employeeSteps, p.moveSteps, p.roleSteps, p.memberSteps)
+}
+
+func (p *plan) skip(dn, reason string) {
+	p.skipped = append(p.skipped, Skipped{DN: dn, Reason: reason})
+}
+
+func (p *plan) add(change Change) *Change {
+	var added = &change
+	p.changes = append(p.changes, added)
+	return added
+}
+
+// build - сначала все сотрудники файла, чтобы руководители и участники ролей находились
+// независимо от порядка записей
+func (p *plan) build(entries []Entry) {
+	var groups []Entry
+	for _, entry := range entries {
+		dn, err := ParseDN(entry.DN)
+		if err != nil {
+			p.skip(entry.DN, err.Error())
+			continue
+		}
+		switch {
+		case entry.IsA(ClassPerson):
+			p.addPerson(entry, dn)
+		case entry.IsA(ClassGroup):
+			groups = append(groups, entry)
+		case !p.dir.tree.IsContainer(dn):
+			p.skip(entry.DN, "neither "+ClassPerson+" nor "+ClassGroup)
+		}
+	}
+	p.resolveManagers()
+	for _, target := range p.persons {
+		if !target.skipped {
+			p.diffPerson(target)
+		}
+	}
+	for _, entry := range groups {
+		p.addGroup(entry)
+	}
+}
+
+func (p *plan) addPerson(entry Entry, dn DN) {
+	var values = map[string]string{}
+	for _, name := range personAttributes {
+		values[name] = strings.TrimSpace(entry.First(name))
+	}
+	if values["sn"] == "" {
+		p.skip(entry.DN, "sn is required")
+		return
+	}
+	if values["givenName"] == "" {
+		values["givenName"] = strings.TrimSpace(strings.TrimSuffix(entry.First("cn"), values["sn"]))
+	}
+	if department := values["departmentNumber"]; department != "" {
+		if id, err := strconv.ParseInt(department, 10, 64); err != nil || id <= 0 {
+			p.skip(entry.DN, fmt.Sprintf("departmentNumber %q is not a department id", department))
+			return
+		}
+	}
+	var target = p.match(entry)
+	if target != nil && target.entry != nil {
+		p.skip(entry.DN, fmt.Sprintf("matches the same employee as %s", target.entry.DN))
+		return
+	}
+	if target == nil {
+		if p.request.DefaultAge == 0 {
+			p.skip(entry.DN, "default_age is required to create employees")
+			return
+		}
+		var request = p.createRequest(values)
+		if err := p.svc.validator.Struct(request); err != nil {
+			p.skip(entry.DN, err.Error())
+			return
+		}
+		target = &person{dn: entry.DN}
+	}
+	target.entry = &entry
+	target.values = values
+	p.people[dn.key()] = target
+	p.persons = append(p.persons, target)
+}
+
+// match - существующий сотрудник по employeeNumber, затем по uid; nil - сотрудник новый
+func (p *plan) match(entry Entry) *person {
+	if id, err := strconv.ParseInt(entry.First("employeeNumber"), 10, 64); err == nil {
+		if existing, ok := p.byId[id]; ok {
+			return existing
+		}
+	}
+	if login := entry.First("uid"); login != "" {
+		return p.byLogin[strings.ToLower(login)]
+	}
+	return nil
+}
+
+// resolveManagers - руководитель должен быть в каталоге или среди принятых записей файла;
+// пропуск записи может оставить без руководителя другие, поэтому до неподвижной точки
+func (p *plan) resolveManagers() {
+	for changed := true; changed; {
+		changed = false
+		for _, target := range p.persons {
+			var managerDn = target.values["manager"]
+			if target.skipped || managerDn == "" {
+				continue
+			}
+			var manager = p.find(managerDn)
+			if manager == nil {
+				target.skipped = true
+				p.skip(target.entry.DN, fmt.Sprintf("manager %s not found", managerDn))
+				changed = true
+				continue
+			}
+			target.manager = manager
+		}
+	}
+}
+
+// find - сотрудник каталога или принятая запись файла с DN dn
+func (p *plan) find(dn string) *person {
+	parsed, err := ParseDN(dn)
+	if err != nil {
+		return nil
+	}
+	var found = p.people[parsed.key()]
+	if found == nil || found.skipped {
+		return nil
+	}
+	return found
+}
+
+func (p *plan) createRequest(values map[string]string) employee.CreateRequest {
+	var department, _ = strconv.ParseInt(values["departmentNumber"], 10, 64)
+	return employee.CreateRequest{
+		Name:         values["givenName"],
+		Surname:      values["sn"],
+		Age:          p.request.DefaultAge,
+		Login:        values["uid"],
+		Email:        values["mail"],
+		Phone:        values["telephoneNumber"],
+		DepartmentId: department,
+		Position:     values["title"],
+		CreatedAt:    p.now,
+		UpdatedAt:    p.now,
+	}
+}
+
+func (p *plan) moveRequest(target *person) employee.MoveRequest {
+	var department, _ = strconv.ParseInt(target.values["departmentNumber"], 10, 64)
+	var request = employee.MoveRequest{DepartmentId: department, Position: target.values["title"]}
+	if target.manager != nil {
+		request.ManagerId = target.manager.id
+	}
+	return request
+}
+
+func (p *plan) diffPerson(target *person) {
+	if target.current == nil {
+		p.createPerson(target)
+		return
+	}
+	var current = *target.current
+	var managerDn string
+	if current.ManagerId.Valid {
+		managerDn = p.dir.personDN(current.ManagerId.Int64)
+	}
+	var old = personValues(current, managerDn)
+	var fields []employee.FieldChange
+	var account, move bool
+	for _, name := range personAttributes {
+		if name == "manager" {
+			// без атрибута manager руководитель остаётся прежним
+			if target.manager == nil || (target.manager.current != nil && current.ManagerId.Int64 == target.manager.id) {
+				continue
+			}
+			fields = append(fields, field(name, managerDn, target.manager.dn))
+			move = true
+			continue
+		}
+		if old[name] == target.values[name] {
+			continue
+		}
+		fields = append(fields, field(name, old[name], target.values[name]))
+		if name == "title" || name == "departmentNumber" {
+			move = true
+		} else {
+			account = true
+		}
+	}
+	if len(fields) == 0 {
+		p.unchanged++
+		return
+	}
+	var change = p.add(Change{Action: ActionUpdate, Object: ObjectEmployee, DN: target.entry.DN, Id: current.Id, Fields: fields})
+	p.employeeSteps = append(p.employeeSteps, step{change: change, apply: func(ctx context.Context) error {
+		if account {
+			var updated = current
+			updated.Name = target.values["givenName"]
+			updated.Surname = target.values["sn"]
+			updated.Login.String, updated.Login.Valid = target.values["uid"], target.values["uid"] != ""
+			updated.Email = target.values["mail"]
+			updated.Phone = target.values["telephoneNumber"]
+			if err := p.svc.updateEmployee(updated); err != nil {
+				return err
+			}
+			p.svc.provision(ctx, current.Id)
+		}
+		if move {
+			_, err := p.svc.employees.Move(ctx, current.Id, p.moveRequest(target))
+			return err
+		}
+		return nil
+	}})
+}
+
+// createPerson - новый сотрудник; руководитель, который создаётся позже по порядку файла,
+// назначается переводом после создания всех сотрудников
+func (p *plan) createPerson(target *person) {
+	var fields []employee.FieldChange
+	for _, name := range personAttributes {
+		if name == "manager" && target.manager != nil {
+			fields = append(fields, field(name, "", target.manager.dn))
+		} else if name != "manager" && target.values[name] != "" {
+			fields = append(fields, field(name, "", target.values[name]))
+		}
+	}
+	var change = p.add(Change{Action: ActionCreate, Object: ObjectEmployee, DN: target.entry.DN, Fields: fields})
+	var managerPending bool
+	p.employeeSteps = append(p.employeeSteps, step{change: change, apply: func(ctx context.Context) error {
+		var request = p.createRequest(target.values)
+		if target.manager != nil {
+			request.ManagerId = target.manager.id
+			managerPending = target.manager.id == 0
+		}
+		id, err := p.svc.employees.CreateEmployee(ctx, request)
+		if err != nil {
+			return err
+		}
+		target.id = id
+		change.Id = id
+		return nil
+	}})
+	if target.manager == nil {
+		return
+	}
+	p.moveSteps = append(p.moveSteps, step{change: change, apply: func(ctx context.Context) error {
+		if !managerPending {
+			return nil
+		}
+		if target.manager.id == 0 {
+			return fmt.Errorf("Manager %s was not created", target.manager.dn)
+		}
+		_, err := p.svc.employees.Move(ctx, target.id, p.moveRequest(target))
+		return err
+	}})
+}
+
+func (p *plan) addGroup(entry Entry) {
+	var name = strings.TrimSpace(entry.First("cn"))
+	if name == "" {
+		p.skip(entry.DN, "cn is required")
+		return
+	}
+	var target = &group{dn: entry.DN, name: name, description: entry.First("description")}
+	for i := range p.dir.roles {
+		if strings.EqualFold(p.dir.roles[i].Name, name) {
+			target.current = &p.dir.roles[i]
+			target.id = target.current.Id
+		}
+	}
+	if target.current != nil {
+		if p.imported[target.id] {
+			p.skip(entry.DN, fmt.Sprintf("role %s is already imported from another entry", name))
+			return
+		}
+		p.imported[target.id] = true
+	}
+	for _, value := range entry.Values("member") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		var member = p.find(value)
+		if member == nil {
+			p.skip(entry.DN, fmt.Sprintf("member %s not found", value))
+			continue
+		}
+		if !slices.Contains(target.members, member) {
+			target.members = append(target.members, member)
+		}
+	}
+	var changed = p.diffRole(target)
+	if p.diffMembers(target) || changed {
+		return
+	}
+	p.unchanged++
+}
+
+// diffRole - создание или изменение названия и описания роли; false - роль не меняется
+func (p *plan) diffRole(target *group) bool {
+	if target.current == nil {
+		var fields = []employee.FieldChange{field("cn", "", target.name)}
+		if target.description != "" {
+			fields = append(fields, field("description", "", target.description))
+		}
+		var change = p.add(Change{Action: ActionCreate, Object: ObjectRole, DN: target.dn, Fields: fields})
+		p.roleSteps = append(p.roleSteps, step{change: change, apply: func(ctx context.Context) error {
+			var request = role.Request{Name: target.name, Description: target.description}
+			created, err := p.svc.roles.Add(request.ToEntity())
+			if err != nil {
+				return err
+			}
+			target.id = created.Id
+			change.Id = created.Id
+			return nil
+		}})
+		return true
+	}
+	var current = *target.current
+	var fields []employee.FieldChange
+	if current.Name != target.name {
+		fields = append(fields, field("cn", current.Name, target.name))
+	}
+	if current.Description != target.description {
+		fields = append(fields, field("description", current.Description, target.description))
+	}
+	if len(fields) == 0 {
+		return false
+	}
+	var change = p.add(Change{Action: ActionUpdate, Object: ObjectRole, DN: target.dn, Id: current.Id, Fields: fields})
+	p.roleSteps = append(p.roleSteps, step{change: change, apply: func(ctx context.Context) error {
+		var request = current.ToRequest()
+		request.Name = target.name
+		request.Description = target.description
+		_, err := p.svc.roles.Update(ctx, current.Id, request)
+		return err
+	}})
+	return true
+}
+
+// diffMembers - назначения новым участникам и отзыв у отсутствующих в файле; false - состав не меняется
+func (p *plan) diffMembers(target *group) bool {
+	var held []int64
+	if target.current != nil {
+		held = p.dir.members[target.current.Id]
+	}
+	var changed bool
+	for _, member := range target.members {
+		if member.current != nil && slices.Contains(held, member.id) {
+			continue
+		}
+		changed = true
+		var change = p.add(Change{Action: ActionAssign, Object: ObjectRole, DN: target.dn, Id: target.id, Member: member.dn})
+		p.memberSteps = append(p.memberSteps, step{change: change, apply: func(ctx context.Context) error {
+			if err := created(target, member); err != nil {
+				return err
+			}
+			change.Id = target.id
+			_, err := p.svc.assigner.Assign(ctx, p.actor, assignment.AssignRequest{EmployeeId: member.id, RoleId: target.id})
+			return err
+		}})
+	}
+	for _, employeeId := range held {
+		if slices.ContainsFunc(target.members, func(member *person) bool { return member.id == employeeId }) {
+			continue
+		}
+		changed = true
+		var change = p.add(Change{Action: ActionRevoke, Object: ObjectRole, DN: target.dn, Id: target.id,
+			Member: p.dir.personDN(employeeId)})
+		p.memberSteps = append(p.memberSteps, step{change: change, apply: func(ctx context.Context) error {
+			return p.svc.assigner.Revoke(ctx, p.actor, assignment.RevokeRequest{EmployeeId: employeeId, RoleId: target.id})
+		}})
+	}
+	return changed
+}
+
+func created(target *group, member *person) error {
+	if target.id == 0 {
+		return fmt.Errorf("Role %s was not created", target.name)
+	}
+	if member.id == 0 {
+		return fmt.Errorf("Employee %s was not created", member.dn)
+	}
+	return nil
+}
+
+// field - различие атрибута; пустое значение - null
+func field(name, old, value string) employee.FieldChange {
+	var change = employee.FieldChange{Field: name}
+	if old != "" {
+		change.Old = old
+	}
+	if value != "" {
+		change.New = value
+	}
+	return change
+}
@@ -0,0 +1,368 @@
+package ldif
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+	"idm/inner/testutil"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindEmployees(ctx context.Context) ([]employee.Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]employee.Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindRoles(ctx context.Context) ([]role.Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]role.Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindMembers(ctx context.Context) ([]Membership, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Membership), args.Error(1)
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) UpdateEmployee(tx *sqlx.Tx, e employee.Entity) (employee.Entity, error) {
+	args := m.Called(tx, e)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Add(tx *sqlx.Tx, event outbox.Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
+type MockEmployees struct {
+	mock.Mock
+}
+
+func (m *MockEmployees) CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployees) Move(ctx context.Context, id int64, request employee.MoveRequest) (employee.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+type MockRoles struct {
+	mock.Mock
+}
+
+func (m *MockRoles) Add(r role.Entity) (role.Response, error) {
+	args := m.Called(r)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoles) Update(ctx context.Context, id int64, request role.Request) (role.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+type MockAssigner struct {
+	mock.Mock
+}
+
+func (m *MockAssigner) Assign(ctx context.Context, actor string, request assignment.AssignRequest) (assignment.Response, error) {
+	args := m.Called(ctx, actor, request)
+	return args.Get(0).(assignment.Response), args.Error(1)
+}
+
+func (m *MockAssigner) Revoke(ctx context.Context, actor string, request assignment.RevokeRequest) error {
+	args := m.Called(ctx, actor, request)
+	return args.Error(0)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+type fixture struct {
+	svc       *Service
+	repo      *MockRepo
+	employees *MockEmployees
+	roles     *MockRoles
+	assigner  *MockAssigner
+	events    *MockOutbox
+}
+
+// newFixture - каталог: руководитель smirnova, её подчинённый ivanov с ролью Accountant,
+// сотрудник без логина и роль без участников
+func newFixture(t *testing.T) fixture {
+	tree, err := NewTree("dc=idm,dc=local")
+	assert.NoError(t, err)
+	var f = fixture{
+		repo:      new(MockRepo),
+		employees: new(MockEmployees),
+		roles:     new(MockRoles),
+		assigner:  new(MockAssigner),
+		events:    new(MockOutbox),
+	}
+	f.svc = NewService(f.repo, tree, f.employees, f.roles, f.assigner, &MockLogger{})
+	f.svc.Events = f.events
+	f.repo.On("FindEmployees", mock.Anything).Return([]employee.Entity{
+		{Id: 1, Name: "Анна", Surname: "Смирнова", Login: sql.NullString{String: "smirnova", Valid: true},
+			Email: "smirnova@example.com", DepartmentId: sql.NullInt64{Int64: 3, Valid: true}, Position: "Head"},
+		{Id: 2, Name: "Ivan", Surname: "Ivanov", Login: sql.NullString{String: "ivanov", Valid: true},
+			DepartmentId: sql.NullInt64{Int64: 3, Valid: true}, ManagerId: sql.NullInt64{Int64: 1, Valid: true}},
+		{Id: 3, Name: "Petr", Surname: "Petrov"},
+	}, nil)
+	f.repo.On("FindRoles", mock.Anything).Return([]role.Entity{
+		{Id: 10, Name: "Accountant", Description: "Бухгалтер, а не аудитор"},
+		{Id: 11, Name: "Empty"},
+	}, nil)
+	f.repo.On("FindMembers", mock.Anything).Return([]Membership{{RoleId: 10, EmployeeId: 2}}, nil)
+	return f
+}
+
+func personEntry(dn, givenName, sn string, attributes ...string) Entry {
+	var entry = Entry{DN: dn}
+	entry.Add("objectClass", "top", "person", "organizationalPerson", ClassPerson)
+	entry.Add("givenName", givenName)
+	entry.Add("sn", sn)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		entry.Add(attributes[i], attributes[i+1])
+	}
+	return entry
+}
+
+func groupEntry(dn, cn string, members ...string) Entry {
+	var entry = Entry{DN: dn}
+	entry.Add("objectClass", "top", ClassGroup)
+	entry.Add("cn", cn)
+	entry.Add("member", members...)
+	return entry
+}
+
+func TestExport(t *testing.T) {
+	t.Run("Should render employees and roles under base DN", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+
+		entries, err := f.svc.Export(context.Background())
+
+		a.NoError(err)
+		a.Len(entries, 8)
+		a.Equal("dc=idm,dc=local", entries[0].DN)
+		a.Equal([]string{"top", "domain"}, entries[0].Values("objectClass"))
+		a.Equal("ou=people,dc=idm,dc=local", entries[1].DN)
+		a.Equal("uid=ivanov,ou=people,dc=idm,dc=local", entries[4].DN)
+		a.Equal("uid=smirnova,ou=people,dc=idm,dc=local", entries[4].First("manager"))
+		a.Equal("3", entries[4].First("departmentNumber"))
+		a.Equal("2", entries[4].First("employeeNumber"))
+		a.False(entries[4].Has("mail"))
+		a.Equal("employeeNumber=3,ou=people,dc=idm,dc=local", entries[5].DN)
+		a.Equal("cn=Accountant,ou=groups,dc=idm,dc=local", entries[6].DN)
+		a.Equal([]string{"uid=ivanov,ou=people,dc=idm,dc=local"}, entries[6].Values("member"))
+		a.Equal([]string{""}, entries[7].Values("member"))
+	})
+
+	t.Run("Should import own export without changes", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		entries, err := f.svc.Export(context.Background())
+		a.NoError(err)
+		var buf bytes.Buffer
+		a.NoError(Write(&buf, entries))
+		read, err := Read(&buf)
+		a.NoError(err)
+
+		rsl, err := f.svc.Import(context.Background(), "admin", read, ImportRequest{DryRun: true})
+
+		a.NoError(err)
+		a.Empty(rsl.Changes)
+		a.Empty(rsl.Skipped)
+		a.Equal(5, rsl.Unchanged)
+	})
+}
+
+func TestImport(t *testing.T) {
+	var ctx = context.Background()
+
+	t.Run("Should return diff without applying it on dry run", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		var entries = []Entry{
+			personEntry("uid=ivanov,ou=people,dc=idm,dc=local", "Ivan", "Ivanov", "uid", "ivanov",
+				"mail", "ivanov@example.com", "departmentNumber", "3", "title", "Accountant",
+				"manager", "uid=smirnova,ou=people,dc=idm,dc=local"),
+			personEntry("uid=sidorov,ou=people,o=legacy", "Сидор", "Сидоров", "uid", "sidorov",
+				"manager", "UID=Ivanov,OU=People,DC=idm,DC=local"),
+			groupEntry("cn=accountant,ou=groups,dc=idm,dc=local", "Accountant", "uid=sidorov,ou=people,o=legacy"),
+			groupEntry("cn=Auditors,ou=groups,dc=idm,dc=local", "Auditors", "uid=smirnova,ou=people,dc=idm,dc=local"),
+		}
+
+		rsl, err := f.svc.Import(ctx, "admin", entries, ImportRequest{DryRun: true, DefaultAge: 30})
+
+		a.NoError(err)
+		a.True(rsl.DryRun)
+		a.Empty(rsl.Skipped)
+		a.Len(rsl.Changes, 7)
+		a.Equal(Change{Action: ActionUpdate, Object: ObjectEmployee, DN: "uid=ivanov,ou=people,dc=idm,dc=local", Id: 2,
+			Fields: []employee.FieldChange{
+				{Field: "mail", New: "ivanov@example.com"},
+				{Field: "title", New: "Accountant"},
+			}}, rsl.Changes[0])
+		a.Equal(ActionCreate, rsl.Changes[1].Action)
+		a.Contains(rsl.Changes[1].Fields, employee.FieldChange{Field: "manager", New: "uid=ivanov,ou=people,dc=idm,dc=local"})
+		a.Equal(ActionUpdate, rsl.Changes[2].Action)
+		a.Equal([]employee.FieldChange{{Field: "description", Old: "Бухгалтер, а не аудитор"}}, rsl.Changes[2].Fields)
+		a.Equal(Change{Action: ActionAssign, Object: ObjectRole, DN: "cn=accountant,ou=groups,dc=idm,dc=local", Id: 10,
+			Member: "uid=sidorov,ou=people,o=legacy"}, rsl.Changes[3])
+		a.Equal(Change{Action: ActionRevoke, Object: ObjectRole, DN: "cn=accountant,ou=groups,dc=idm,dc=local", Id: 10,
+			Member: "uid=ivanov,ou=people,dc=idm,dc=local"}, rsl.Changes[4])
+		a.Equal(ActionCreate, rsl.Changes[5].Action)
+		a.Equal(ObjectRole, rsl.Changes[5].Object)
+		a.Equal("uid=smirnova,ou=people,dc=idm,dc=local", rsl.Changes[6].Member)
+		f.employees.AssertNotCalled(t, "CreateEmployee", mock.Anything, mock.Anything)
+		f.repo.AssertNotCalled(t, "UpdateEmployee", mock.Anything, mock.Anything)
+		f.roles.AssertNotCalled(t, "Add", mock.Anything)
+		f.assigner.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should create employees, set manager created later and assign new role", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		var entries = []Entry{
+			personEntry("uid=sidorov,ou=people,dc=idm,dc=local", "Sidor", "Sidorov", "uid", "sidorov",
+				"departmentNumber", "4", "manager", "uid=kozlov,ou=people,dc=idm,dc=local"),
+			personEntry("uid=kozlov,ou=people,dc=idm,dc=local", "Kozma", "Kozlov", "uid", "kozlov"),
+			groupEntry("cn=Auditors,ou=groups,dc=idm,dc=local", "Auditors", "uid=sidorov,ou=people,dc=idm,dc=local"),
+		}
+		f.employees.On("CreateEmployee", ctx, mock.MatchedBy(func(r employee.CreateRequest) bool {
+			return r.Login == "sidorov" && r.Age == 30 && r.DepartmentId == 4 && r.ManagerId == 0
+		})).Return(int64(20), nil)
+		f.employees.On("CreateEmployee", ctx, mock.MatchedBy(func(r employee.CreateRequest) bool {
+			return r.Login == "kozlov"
+		})).Return(int64(21), nil)
+		f.employees.On("Move", ctx, int64(20), employee.MoveRequest{DepartmentId: 4, ManagerId: 21}).
+			Return(employee.Response{Id: 20}, nil)
+		f.roles.On("Add", mock.MatchedBy(func(r role.Entity) bool { return r.Name == "Auditors" })).
+			Return(role.Response{Id: 30, Name: "Auditors"}, nil)
+		f.assigner.On("Assign", ctx, "admin", assignment.AssignRequest{EmployeeId: 20, RoleId: 30}).
+			Return(assignment.Response{}, nil)
+
+		rsl, err := f.svc.Import(ctx, "admin", entries, ImportRequest{DefaultAge: 30})
+
+		a.NoError(err)
+		a.Len(rsl.Changes, 4)
+		for _, change := range rsl.Changes {
+			a.Empty(change.Error)
+		}
+		a.Equal(int64(20), rsl.Changes[0].Id)
+		a.Equal(int64(30), rsl.Changes[3].Id)
+		f.employees.AssertExpectations(t)
+		f.roles.AssertExpectations(t)
+		f.assigner.AssertExpectations(t)
+	})
+
+	t.Run("Should update account attributes and move employee", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		var entries = []Entry{
+			personEntry("employeeNumber=3,ou=people,dc=idm,dc=local", "Petr", "Petrov-Vodkin", "employeeNumber", "3",
+				"departmentNumber", "5"),
+		}
+		tx, mockTr := testutil.NewTx(t, true)
+		f.repo.On("BeginTr").Return(tx, nil)
+		f.repo.On("UpdateEmployee", tx, mock.MatchedBy(func(e employee.Entity) bool {
+			return e.Id == 3 && e.Surname == "Petrov-Vodkin"
+		})).Return(employee.Entity{Id: 3, Name: "Petr", Surname: "Petrov-Vodkin"}, nil)
+		f.events.On("Add", tx, mock.MatchedBy(func(e outbox.Entity) bool {
+			return e.Type == outbox.EmployeeUpdated && e.AggregateId == 3
+		})).Return(nil)
+		f.employees.On("Move", ctx, int64(3), employee.MoveRequest{DepartmentId: 5}).Return(employee.Response{Id: 3}, nil)
+
+		rsl, err := f.svc.Import(ctx, "admin", entries, ImportRequest{})
+
+		a.NoError(err)
+		a.Len(rsl.Changes, 1)
+		a.Empty(rsl.Changes[0].Error)
+		a.NoError(mockTr.ExpectationsWereMet())
+		f.repo.AssertExpectations(t)
+		f.events.AssertExpectations(t)
+		f.employees.AssertExpectations(t)
+	})
+
+	t.Run("Should record errors of failed and dependent changes", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		var entries = []Entry{
+			groupEntry("cn=Auditors,ou=groups,dc=idm,dc=local", "Auditors", "uid=ivanov,ou=people,dc=idm,dc=local"),
+		}
+		f.roles.On("Add", mock.Anything).Return(role.Response{}, errors.New("db failure"))
+
+		rsl, err := f.svc.Import(ctx, "admin", entries, ImportRequest{})
+
+		a.NoError(err)
+		a.Len(rsl.Changes, 2)
+		a.Equal("db failure", rsl.Changes[0].Error)
+		a.Equal("Role Auditors was not created", rsl.Changes[1].Error)
+		f.assigner.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should skip invalid entries", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+		var unknown = Entry{DN: "cn=printer,dc=idm,dc=local"}
+		unknown.Add("objectClass", "device")
+		var noSurname = personEntry("uid=nosn,ou=people,dc=idm,dc=local", "No", "")
+		var entries = []Entry{
+			unknown,
+			noSurname,
+			personEntry("uid=new,ou=people,dc=idm,dc=local", "New", "Employee", "uid", "new"),
+			personEntry("uid=ivanov,ou=people,dc=idm,dc=local", "Ivan", "Ivanov", "uid", "ivanov",
+				"manager", "uid=ghost,ou=people,dc=idm,dc=local"),
+			groupEntry("cn=Empty,ou=groups,dc=idm,dc=local", "Empty", "uid=new,ou=people,dc=idm,dc=local"),
+		}
+
+		rsl, err := f.svc.Import(ctx, "admin", entries, ImportRequest{DryRun: true})
+
+		a.NoError(err)
+		a.Empty(rsl.Changes)
+		a.Equal([]Skipped{
+			{DN: "cn=printer,dc=idm,dc=local", Reason: "neither inetOrgPerson nor groupOfNames"},
+			{DN: "uid=nosn,ou=people,dc=idm,dc=local", Reason: "sn is required"},
+			{DN: "uid=new,ou=people,dc=idm,dc=local", Reason: "default_age is required to create employees"},
+			{DN: "uid=ivanov,ou=people,dc=idm,dc=local", Reason: "manager uid=ghost,ou=people,dc=idm,dc=local not found"},
+			{DN: "cn=Empty,ou=groups,dc=idm,dc=local", Reason: "member uid=new,ou=people,dc=idm,dc=local not found"},
+		}, rsl.Skipped)
+		a.Equal(1, rsl.Unchanged)
+	})
+
+	t.Run("Should return validation error for invalid default age", func(t *testing.T) {
+		a := assert.New(t)
+		var f = newFixture(t)
+
+		_, err := f.svc.Import(ctx, "admin", nil, ImportRequest{DefaultAge: 5})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
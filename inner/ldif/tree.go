@@ -0,0 +1,129 @@
+package ldif
+
+import (
+	"fmt"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"strconv"
+	"strings"
+)
+
+// personAttributes - атрибуты inetOrgPerson, которые хранит сотрудник, в порядке выгрузки
+var personAttributes = []string{"givenName", "sn", "uid", "mail", "telephoneNumber", "title", "departmentNumber", "manager"}
+
+// Tree - размещение каталога под базовым DN: сотрудники в ou=people, роли в ou=groups
+type Tree struct {
+	Base DN
+}
+
+func NewTree(baseDn string) (Tree, error) {
+	base, err := ParseDN(baseDn)
+	if err != nil {
+		return Tree{}, err
+	}
+	if len(base) == 0 {
+		return Tree{}, fmt.Errorf("Base DN is empty")
+	}
+	return Tree{Base: base}, nil
+}
+
+func (t Tree) People() DN {
+	return t.Base.Child("ou", OuPeople)
+}
+
+func (t Tree) Groups() DN {
+	return t.Base.Child("ou", OuGroups)
+}
+
+// PersonDN - uid=логин; сотрудник без логина именуется табельным номером employeeNumber=id
+func (t Tree) PersonDN(e employee.Entity) DN {
+	if e.Login.Valid && e.Login.String != "" {
+		return t.People().Child("uid", e.Login.String)
+	}
+	return t.People().Child("employeeNumber", strconv.FormatInt(e.Id, 10))
+}
+
+func (t Tree) GroupDN(r role.Entity) DN {
+	return t.Groups().Child("cn", r.Name)
+}
+
+// Containers - базовая запись, если её класс известен по типу RDN, и контейнеры people и groups
+func (t Tree) Containers() []Entry {
+	var entries []Entry
+	for _, dn := range []DN{t.Base, t.People(), t.Groups()} {
+		if entry, ok := container(dn); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// IsContainer - dn - одна из записей Containers
+func (t Tree) IsContainer(dn DN) bool {
+	return dn.Equal(t.Base) || dn.Equal(t.People()) || dn.Equal(t.Groups())
+}
+
+func container(dn DN) (Entry, bool) {
+	var rdn = dn[0]
+	var entry = Entry{DN: dn.String()}
+	switch strings.ToLower(rdn.Type) {
+	case "dc":
+		entry.Add("objectClass", "top", "domain")
+	case "o":
+		entry.Add("objectClass", "top", "organization")
+	case "ou":
+		entry.Add("objectClass", "top", "organizationalUnit")
+	default:
+		return Entry{}, false
+	}
+	entry.Add(rdn.Type, rdn.Value)
+	return entry, true
+}
+
+// Person - сотрудник как inetOrgPerson; manager - DN руководителя, пусто - руководителя нет
+func (t Tree) Person(e employee.Entity, manager string) Entry {
+	var entry = Entry{DN: t.PersonDN(e).String()}
+	entry.Add("objectClass", "top", "person", "organizationalPerson", ClassPerson)
+	entry.Add("cn", e.Name+" "+e.Surname)
+	var values = personValues(e, manager)
+	for _, name := range personAttributes {
+		if values[name] != "" {
+			entry.Add(name, values[name])
+		}
+	}
+	entry.Add("employeeNumber", strconv.FormatInt(e.Id, 10))
+	return entry
+}
+
+// personValues - значения personAttributes сотрудника, пустая строка - значения нет
+func personValues(e employee.Entity, manager string) map[string]string {
+	var values = map[string]string{
+		"givenName":       e.Name,
+		"sn":              e.Surname,
+		"uid":             e.Login.String,
+		"mail":            e.Email,
+		"telephoneNumber": e.Phone,
+		"title":           e.Position,
+		"manager":         manager,
+	}
+	if e.DepartmentId.Valid {
+		values["departmentNumber"] = strconv.FormatInt(e.DepartmentId.Int64, 10)
+	}
+	return values
+}
+
+// Group - роль как groupOfNames. Класс требует хотя бы одного участника, поэтому у роли
+// без назначений единственный участник - пустой DN
+func (t Tree) Group(r role.Entity, members []string) Entry {
+	var entry = Entry{DN: t.GroupDN(r).String()}
+	entry.Add("objectClass", "top", ClassGroup)
+	entry.Add("cn", r.Name)
+	if r.Description != "" {
+		entry.Add("description", r.Description)
+	}
+	if len(members) == 0 {
+		members = []string{""}
+	}
+	entry.Add("member", members...)
+	return entry
+}
@@ -0,0 +1,35 @@
+package me
+
+import (
+	"idm/inner/department"
+	"idm/inner/employee"
+	"idm/inner/role"
+)
+
+// Identity - данные о вызывающем пользователе, извлечённые из JWT
+type Identity struct {
+	Subject           string
+	PreferredUsername string
+	TokenRoles        []string
+}
+
+type Response struct {
+	Profile       employee.Response    `json:"profile"`
+	Department    *department.Response `json:"department"`
+	AssignedRoles []role.Response      `json:"assigned_roles"`
+	TokenRoles    []string             `json:"token_roles"`
+}
+
+// UpdateRequest - поля, доступные сотруднику для самостоятельного редактирования.
+// Любые другие поля в теле запроса отклоняются.
+type UpdateRequest struct {
+	Email *string `json:"email" validate:"omitempty,email"`
+	Phone *string `json:"phone" validate:"omitempty,max=32"`
+}
+
+func (req *UpdateRequest) ToProfileUpdate() employee.ProfileUpdate {
+	return employee.ProfileUpdate{
+		Email: req.Email,
+		Phone: req.Phone,
+	}
+}
@@ -0,0 +1,127 @@
+package me
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	FindMe(ctx context.Context, identity Identity) (Response, error)
+	UpdateMe(ctx context.Context, identity Identity, request UpdateRequest) (Response, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрута "/api/v1/me"
+func (c *Handler) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/me", c.FindMe)
+	c.server.GroupApiV1.Patch("/me", c.UpdateMe)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/me"
+// @Description Get profile, department, assigned roles and token roles of the caller.
+// @Summary who am I
+// @Tags me
+// @Produce json
+// @Success 200 {object} common.Response[me.Response]
+// @Failure 403 {object} common.Response[me.Response] "Permission denied"
+// @Failure 404 {object} common.Response[me.Response] "employee is not linked to the token"
+// @Failure 500 {object} common.Response[me.Response] "error db"
+// @Router /me [get]
+// @Security BearerAuth
+func (c *Handler) FindMe(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermProfileRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	identity, ok := identityFromCtx(ctx)
+	if !ok {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	rsl, err := c.service.FindMe(ctx.Context(), identity)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindMe: error finding", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при PATCH запросе по маршруту "/api/v1/me"
+// @Description Update whitelisted fields (email, phone) of the caller's profile.
+// @Summary update own profile
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body UpdateRequest true "fields to update"
+// @Success 200 {object} common.Response[me.Response]
+// @Failure 400 {object} common.Response[me.Response] "invalid request or field is not editable"
+// @Failure 403 {object} common.Response[me.Response] "Permission denied"
+// @Failure 404 {object} common.Response[me.Response] "employee is not linked to the token"
+// @Failure 500 {object} common.Response[me.Response] "error db"
+// @Router /me [patch]
+// @Security BearerAuth
+func (c *Handler) UpdateMe(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermProfileWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	identity, ok := identityFromCtx(ctx)
+	if !ok {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	var request UpdateRequest
+	var decoder = json.NewDecoder(bytes.NewReader(ctx.Body()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "UpdateMe: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "UpdateMe: received request", zap.Any("request", request))
+	rsl, err := c.service.UpdateMe(ctx.Context(), identity, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "UpdateMe: error updating", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// identityFromCtx - извлекает данные вызывающего из JWT
+func identityFromCtx(ctx *fiber.Ctx) (Identity, bool) {
+	claims, ok := web.ClaimsFromCtx(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+	return Identity{
+		Subject:           claims.Subject,
+		PreferredUsername: claims.PreferredUsername,
+		TokenRoles:        claims.RealmAccess.Roles,
+	}, true
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
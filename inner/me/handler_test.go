@@ -0,0 +1,131 @@
+package me
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindMe(ctx context.Context, identity Identity) (Response, error) {
+	args := svc.Called(ctx, identity)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) UpdateMe(ctx context.Context, identity Identity, request UpdateRequest) (Response, error) {
+	args := svc.Called(ctx, identity, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newTestServer(roles []string, svc Svc) *web.Server {
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := web.NewServer()
+	claims := &web.IdmClaims{
+		RealmAccess:       web.RealmAccessClaims{Roles: roles},
+		PreferredUsername: "john",
+	}
+	claims.Subject = "f3c1"
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	NewHandler(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestFindMeHandler(t *testing.T) {
+	t.Run("Should return profile of the caller", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+		identity := Identity{Subject: "f3c1", PreferredUsername: "john", TokenRoles: []string{web.IdmUser}}
+		svc.On("FindMe", mock.Anything, identity).Return(Response{TokenRoles: []string{web.IdmUser}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[Response]
+		a.Nil(json.Unmarshal(body, &responseBody))
+		a.True(responseBody.Success)
+		a.Equal([]string{web.IdmUser}, responseBody.Data.TokenRoles)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("Should return 404 when employee is not linked", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+		svc.On("FindMe", mock.Anything, mock.Anything).Return(Response{}, common.NotFoundError{Message: "not found"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Should return 403 without idm roles", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := newTestServer([]string{}, new(MockService))
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestUpdateMeHandler(t *testing.T) {
+	t.Run("Should update whitelisted field", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+		svc.On("UpdateMe", mock.Anything, mock.Anything, mock.MatchedBy(func(r UpdateRequest) bool {
+			return r.Phone != nil && *r.Phone == "+7900" && r.Email == nil
+		})).Return(Response{}, nil)
+		req := httptest.NewRequest(fiber.MethodPatch, "/api/v1/me", strings.NewReader(`{"phone": "+7900"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("Should return 400 on not editable field", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+		req := httptest.NewRequest(fiber.MethodPatch, "/api/v1/me", strings.NewReader(`{"name": "Hacker"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "UpdateMe", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,146 @@
+package me
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/department"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	employeeRepo   EmployeeRepo
+	roleRepo       RoleRepo
+	departmentRepo DepartmentRepo
+	validator      *validator.Validate
+	logger         common.LoggerInterface
+	// Provisioning - передача изменённого профиля во внешние системы; nil - не передаётся
+	Provisioning Provisioner
+	// Events - запись доменных событий в outbox в транзакции изменения; nil - события не пишутся
+	Events outbox.Writer
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type EmployeeRepo interface {
+	BeginTr() (*sqlx.Tx, error)
+	FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error)
+	UpdateProfile(tx *sqlx.Tx, id int64, update employee.ProfileUpdate) (employee.Entity, error)
+}
+
+type RoleRepo interface {
+	FindByEmployeeId(ctx context.Context, employeeId int64) ([]role.Entity, error)
+}
+
+type DepartmentRepo interface {
+	FindById(ctx context.Context, id int64) (department.Entity, error)
+}
+
+func NewService(
+	employeeRepo EmployeeRepo,
+	roleRepo RoleRepo,
+	departmentRepo DepartmentRepo,
+	logger common.LoggerInterface,
+) *Service {
+	return &Service{
+		employeeRepo:   employeeRepo,
+		roleRepo:       roleRepo,
+		departmentRepo: departmentRepo,
+		validator:      validator.New(),
+		logger:         logger,
+	}
+}
+
+// FindMe - профиль вызывающего сотрудника вместе с подразделением и ролями
+func (svc *Service) FindMe(ctx context.Context, identity Identity) (Response, error) {
+	entity, err := svc.resolve(ctx, identity)
+	if err != nil {
+		return Response{}, err
+	}
+	return svc.toResponse(ctx, entity, identity)
+}
+
+// UpdateMe - самостоятельное изменение разрешённых полей профиля
+func (svc *Service) UpdateMe(ctx context.Context, identity Identity, request UpdateRequest) (Response, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if request.Email == nil && request.Phone == nil {
+		return Response{}, common.RequestValidationError{Message: "Nothing to update"}
+	}
+	entity, err := svc.resolve(ctx, identity)
+	if err != nil {
+		return Response{}, err
+	}
+	var updated employee.Entity
+	err = common.InTx(svc.employeeRepo, "Updating profile", func(tx *sqlx.Tx) (err error) {
+		updated, err = svc.employeeRepo.UpdateProfile(tx, entity.Id, request.ToProfileUpdate())
+		if err != nil {
+			return fmt.Errorf("Error updating profile of employee with id %d: %w", entity.Id, err)
+		}
+		return outbox.Write(svc.Events, tx, outbox.AggregateEmployee, entity.Id, outbox.EmployeeUpdated, updated.ToResponse())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.provision(ctx, entity.Id)
+	return svc.toResponse(ctx, updated, identity)
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+// resolve - ищет сотрудника сначала по sub, затем по preferred_username
+func (svc *Service) resolve(ctx context.Context, identity Identity) (employee.Entity, error) {
+	entity, err := svc.employeeRepo.FindByIdentity(ctx, identity.Subject, identity.PreferredUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		svc.logger.DebugCtx(ctx, "Employee for token not found",
+			zap.String("sub", identity.Subject), zap.String("preferred_username", identity.PreferredUsername))
+		return employee.Entity{}, common.NotFoundError{Message: "Employee linked to the token is not found"}
+	}
+	if err != nil {
+		return employee.Entity{}, fmt.Errorf("Error finding employee linked to the token: %w", err)
+	}
+	return entity, nil
+}
+
+func (svc *Service) toResponse(ctx context.Context, entity employee.Entity, identity Identity) (Response, error) {
+	var response = Response{
+		Profile:       entity.ToResponse(),
+		AssignedRoles: []role.Response{},
+		TokenRoles:    identity.TokenRoles,
+	}
+	if response.TokenRoles == nil {
+		response.TokenRoles = []string{}
+	}
+	if entity.DepartmentId.Valid {
+		dep, err := svc.departmentRepo.FindById(ctx, entity.DepartmentId.Int64)
+		if err != nil {
+			return Response{}, fmt.Errorf("Error finding department with id %d: %w", entity.DepartmentId.Int64, err)
+		}
+		var depResponse = dep.ToResponse()
+		response.Department = &depResponse
+	}
+	roles, err := svc.roleRepo.FindByEmployeeId(ctx, entity.Id)
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding roles of employee with id %d: %w", entity.Id, err)
+	}
+	for _, r := range roles {
+		response.AssignedRoles = append(response.AssignedRoles, r.ToResponse())
+	}
+	return response, nil
+}
@@ -0,0 +1,200 @@
+package me
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/department"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+	"idm/inner/testutil"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockEmployeeRepo struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeRepo) FindByIdentity(ctx context.Context, subject, username string) (employee.Entity, error) {
+	args := m.Called(ctx, subject, username)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+func (m *MockEmployeeRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockEmployeeRepo) UpdateProfile(tx *sqlx.Tx, id int64, update employee.ProfileUpdate) (employee.Entity, error) {
+	args := m.Called(tx, id, update)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Add(tx *sqlx.Tx, event outbox.Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
+type MockRoleRepo struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepo) FindByEmployeeId(ctx context.Context, employeeId int64) ([]role.Entity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]role.Entity), args.Error(1)
+}
+
+type MockDepartmentRepo struct {
+	mock.Mock
+}
+
+func (m *MockDepartmentRepo) FindById(ctx context.Context, id int64) (department.Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(department.Entity), args.Error(1)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+func TestFindMe(t *testing.T) {
+	ctx := context.Background()
+	identity := Identity{Subject: "f3c1", PreferredUsername: "john", TokenRoles: []string{"IDM_USER"}}
+
+	t.Run("Should resolve employee by sub", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		employees := new(MockEmployeeRepo)
+		roles := new(MockRoleRepo)
+		departments := new(MockDepartmentRepo)
+		svc := NewService(employees, roles, departments, &MockLogger{})
+		entity := employee.Entity{Id: 7, Name: "John", Login: sql.NullString{String: "f3c1", Valid: true},
+			DepartmentId: sql.NullInt64{Int64: 2, Valid: true}}
+		employees.On("FindByIdentity", ctx, "f3c1", "john").Return(entity, nil)
+		departments.On("FindById", ctx, int64(2)).Return(department.Entity{Id: 2, Name: "IT"}, nil)
+		roles.On("FindByEmployeeId", ctx, int64(7)).Return([]role.Entity{{Id: 1, Name: "DEV"}}, nil)
+
+		got, err := svc.FindMe(ctx, identity)
+
+		a.NoError(err)
+		a.Equal(int64(7), got.Profile.Id)
+		a.Equal("IT", got.Department.Name)
+		a.Equal([]role.Response{{Id: 1, Name: "DEV"}}, got.AssignedRoles)
+		a.Equal([]string{"IDM_USER"}, got.TokenRoles)
+		employees.AssertExpectations(t)
+		departments.AssertExpectations(t)
+	})
+
+	t.Run("Should return NotFoundError when no employee is linked", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(employees, new(MockRoleRepo), new(MockDepartmentRepo), &MockLogger{})
+		employees.On("FindByIdentity", ctx, "f3c1", "john").Return(employee.Entity{}, sql.ErrNoRows)
+
+		_, err := svc.FindMe(ctx, identity)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should return db error", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		employees := new(MockEmployeeRepo)
+		svc := NewService(employees, new(MockRoleRepo), new(MockDepartmentRepo), &MockLogger{})
+		employees.On("FindByIdentity", ctx, "f3c1", "john").Return(employee.Entity{}, errors.New("db down"))
+
+		_, err := svc.FindMe(ctx, identity)
+
+		a.Error(err)
+		a.False(errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestUpdateMe(t *testing.T) {
+	ctx := context.Background()
+	identity := Identity{Subject: "f3c1"}
+
+	t.Run("Should update whitelisted fields", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		employees := new(MockEmployeeRepo)
+		roles := new(MockRoleRepo)
+		events := new(MockOutbox)
+		svc := NewService(employees, roles, new(MockDepartmentRepo), &MockLogger{})
+		svc.Events = events
+		email := "john@example.com"
+		request := UpdateRequest{Email: &email}
+		tx, mockTr := testutil.NewTx(t, true)
+		employees.On("FindByIdentity", ctx, "f3c1", "").Return(employee.Entity{Id: 7}, nil)
+		employees.On("BeginTr").Return(tx, nil)
+		employees.On("UpdateProfile", tx, int64(7), employee.ProfileUpdate{Email: &email}).
+			Return(employee.Entity{Id: 7, Email: email}, nil)
+		events.On("Add", tx, mock.MatchedBy(func(e outbox.Entity) bool {
+			return e.Type == outbox.EmployeeUpdated && e.AggregateId == 7
+		})).Return(nil)
+		roles.On("FindByEmployeeId", ctx, int64(7)).Return([]role.Entity(nil), nil)
+
+		got, err := svc.UpdateMe(ctx, identity, request)
+
+		a.NoError(err)
+		a.Equal(email, got.Profile.Email)
+		a.NoError(mockTr.ExpectationsWereMet())
+		employees.AssertExpectations(t)
+		events.AssertExpectations(t)
+	})
+
+	t.Run("Should rollback profile update when event is not written", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		employees := new(MockEmployeeRepo)
+		events := new(MockOutbox)
+		svc := NewService(employees, new(MockRoleRepo), new(MockDepartmentRepo), &MockLogger{})
+		svc.Events = events
+		email := "john@example.com"
+		tx, mockTr := testutil.NewTx(t, false)
+		employees.On("FindByIdentity", ctx, "f3c1", "").Return(employee.Entity{Id: 7}, nil)
+		employees.On("BeginTr").Return(tx, nil)
+		employees.On("UpdateProfile", tx, int64(7), employee.ProfileUpdate{Email: &email}).
+			Return(employee.Entity{Id: 7, Email: email}, nil)
+		events.On("Add", tx, mock.Anything).Return(errors.New("outbox unavailable"))
+
+		_, err := svc.UpdateMe(ctx, identity, UpdateRequest{Email: &email})
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should reject invalid email", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockEmployeeRepo), new(MockRoleRepo), new(MockDepartmentRepo), &MockLogger{})
+		email := "not-an-email"
+
+		_, err := svc.UpdateMe(ctx, identity, UpdateRequest{Email: &email})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+
+	t.Run("Should reject empty update", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockEmployeeRepo), new(MockRoleRepo), new(MockDepartmentRepo), &MockLogger{})
+
+		_, err := svc.UpdateMe(ctx, identity, UpdateRequest{})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
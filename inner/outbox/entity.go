@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Агрегаты, к которым относятся события. Порядок публикации гарантируется в пределах агрегата
+const (
+	AggregateEmployee = "employee"
+	AggregateRole     = "role"
+)
+
+// Типы доменных событий
+const (
+	EmployeeCreated = "EmployeeCreated"
+	EmployeeUpdated = "EmployeeUpdated"
+	EmployeeMoved   = "EmployeeMoved"
+	EmployeeDeleted = "EmployeeDeleted"
+
+	RoleCreated          = "RoleCreated"
+	RoleUpdated          = "RoleUpdated"
+	RoleDeleted          = "RoleDeleted"
+	RoleCompositeAdded   = "RoleCompositeAdded"
+	RoleCompositeRemoved = "RoleCompositeRemoved"
+
+	// RoleAssigned, RoleRevoked, RoleAssignmentExpired - события агрегата employee
+	RoleAssigned          = "RoleAssigned"
+	RoleRevoked           = "RoleRevoked"
+	RoleAssignmentExpired = "RoleAssignmentExpired"
+)
+
+// EventTypes - все типы доменных событий, на которые можно подписаться
+var EventTypes = []string{
+	EmployeeCreated, EmployeeUpdated, EmployeeMoved, EmployeeDeleted,
+	RoleCreated, RoleUpdated, RoleDeleted, RoleCompositeAdded, RoleCompositeRemoved,
+	RoleAssigned, RoleRevoked, RoleAssignmentExpired,
+}
+
+// Deleted - данные событий удаления
+type Deleted struct {
+	Id int64 `json:"id"`
+}
+
+type Entity struct {
+	Id            int64          `db:"id"`
+	AggregateType string         `db:"aggregate_type"`
+	AggregateId   int64          `db:"aggregate_id"`
+	Type          string         `db:"type"`
+	Payload       types.JSONText `db:"payload"`
+	CreatedAt     time.Time      `db:"created_at"`
+	PublishedAt   sql.NullTime   `db:"published_at"`
+	PublishedSeq  sql.NullInt64  `db:"published_seq"`
+	Attempts      int            `db:"attempts"`
+	LastError     sql.NullString `db:"last_error"`
+}
+
+// NewEvent - событие с данными payload, сериализованными в JSON
+func NewEvent(aggregateType string, aggregateId int64, eventType string, payload any) (Entity, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Entity{}, err
+	}
+	return Entity{
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		Type:          eventType,
+		Payload:       data,
+	}, nil
+}
+
+// Writer - запись событий в outbox в транзакции изменения
+type Writer interface {
+	Add(tx *sqlx.Tx, event Entity) error
+}
+
+// Write - записывает событие агрегата в транзакции изменения tx; без writer события не пишутся
+func Write(writer Writer, tx *sqlx.Tx, aggregateType string, aggregateId int64, eventType string, payload any) error {
+	if writer == nil {
+		return nil
+	}
+	event, err := NewEvent(aggregateType, aggregateId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("Error building event %s: %w", eventType, err)
+	}
+	if err = writer.Add(tx, event); err != nil {
+		return fmt.Errorf("Error writing event %s: %w", eventType, err)
+	}
+	return nil
+}
+
+func (e *Entity) ToMessage() Message {
+	return Message{
+		Id:            e.Id,
+		Type:          e.Type,
+		AggregateType: e.AggregateType,
+		AggregateId:   e.AggregateId,
+		Payload:       json.RawMessage(e.Payload),
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+// Message - событие в том виде, в котором его получают подписчики. Id растёт вместе
+// с порядком событий и служит ключом идемпотентности: доставка не реже одного раза
+type Message struct {
+	Id            int64           `json:"id"`
+	Type          string          `json:"type" example:"EmployeeCreated"`
+	AggregateType string          `json:"aggregate_type" example:"employee"`
+	AggregateId   int64           `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"created_at" example:"2025-07-29T12:00:00Z"`
+}
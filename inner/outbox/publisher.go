@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"idm/inner/common"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Publisher - доставка события подписчикам. Ошибка оставляет событие и следующие события
+// того же агрегата в outbox до следующей попытки
+type Publisher interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+// Fanout - публикует событие каждому из получателей по очереди. Ошибка любого из них
+// возвращает событие в outbox, поэтому получатели должны быть идемпотентны по Message.Id
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, message Message) error {
+	for _, p := range f {
+		if err := p.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogPublisher - пишет события в лог приложения
+type LogPublisher struct {
+	logger *common.Logger
+}
+
+func NewLogPublisher(logger *common.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, message Message) error {
+	p.logger.Info("Domain event published",
+		zap.Int64("id", message.Id),
+		zap.String("type", message.Type),
+		zap.String("aggregate_type", message.AggregateType),
+		zap.Int64("aggregate_id", message.AggregateId),
+		zap.ByteString("payload", message.Payload))
+	return nil
+}
+
+// FilePublisher - дописывает события в файл по одному JSON в строке
+type FilePublisher struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFilePublisher(path string) *FilePublisher {
+	return &FilePublisher{path: path}
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("Error encoding event %d: %w", message.Id, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	file, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	// событие считается опубликованным только после записи на диск
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// HttpPublisher - отправляет событие POST-запросом с JSON; успех - любой ответ 2xx.
+// Заголовок X-Event-Id позволяет получателю отбросить повторную доставку
+type HttpPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHttpPublisher(url string, timeout time.Duration) *HttpPublisher {
+	return &HttpPublisher{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *HttpPublisher) Publish(ctx context.Context, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("Error encoding event %d: %w", message.Id, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(message.Id, 10))
+	req.Header.Set("X-Event-Type", message.Type)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending event %d: %w", message.Id, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Error sending event %d: status %d: %s", message.Id, resp.StatusCode,
+			strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishers(t *testing.T) {
+	ctx := context.Background()
+	var message = Message{Id: 42, Type: EmployeeCreated, AggregateType: AggregateEmployee, AggregateId: 7,
+		Payload: json.RawMessage(`{"id":7}`), CreatedAt: time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC)}
+
+	t.Run("Should post event with id header", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		var headers http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = r.Header
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		err := NewHttpPublisher(server.URL, time.Second).Publish(ctx, message)
+
+		a.NoError(err)
+		a.Equal("42", headers.Get("X-Event-Id"))
+		a.Equal(EmployeeCreated, headers.Get("X-Event-Type"))
+		a.JSONEq(`{"id":42,"type":"EmployeeCreated","aggregate_type":"employee","aggregate_id":7,
+			"payload":{"id":7},"created_at":"2025-07-29T12:00:00Z"}`, string(body))
+	})
+
+	t.Run("Should fail on non 2xx response", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := NewHttpPublisher(server.URL, time.Second).Publish(ctx, message)
+
+		assert.ErrorContains(t, err, "status 503: overloaded")
+	})
+
+	t.Run("Should append one event per line", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		var path = filepath.Join(t.TempDir(), "events.jsonl")
+		publisher := NewFilePublisher(path)
+
+		a.NoError(publisher.Publish(ctx, message))
+		a.NoError(publisher.Publish(ctx, message))
+
+		data, err := os.ReadFile(path)
+		a.NoError(err)
+		var lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		a.Len(lines, 2)
+		var got Message
+		a.NoError(json.Unmarshal([]byte(lines[1]), &got))
+		a.Equal(message, got)
+	})
+
+	t.Run("Should stop fanout at first failed publisher", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		first := &StubPublisher{}
+		failing := &StubPublisher{failures: map[int64]bool{42: true}}
+		last := &StubPublisher{}
+
+		err := Fanout{first, failing, last}.Publish(ctx, message)
+
+		a.Error(err)
+		a.Equal([]int64{42}, first.published)
+		a.Empty(last.published)
+	})
+}
@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// relayLockClass - класс advisory lock, которым relay исключает параллельную публикацию
+// с нескольких экземпляров приложения
+const relayLockClass = 7001
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// Add - сохраняет событие в рамках транзакции изменения. Блокировка агрегата до конца
+// транзакции упорядочивает id событий одного агрегата так же, как фиксируются транзакции
+func (r *Repository) Add(tx *sqlx.Tx, event Entity) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended($1::TEXT || ':' || $2::TEXT, 0))",
+		event.AggregateType, event.AggregateId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO outbox(aggregate_type, aggregate_id, type, payload) VALUES ($1, $2, $3, $4)",
+		event.AggregateType, event.AggregateId, event.Type, event.Payload)
+	return err
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// TryLock - захватывает право публикации до конца транзакции; false - публикует другой экземпляр
+func (r *Repository) TryLock(tx *sqlx.Tx) (locked bool, err error) {
+	err = tx.Get(&locked, "SELECT pg_try_advisory_xact_lock($1, 0)", relayLockClass)
+	return locked, err
+}
+
+// FindPending - неопубликованные события в порядке записи
+func (r *Repository) FindPending(tx *sqlx.Tx, limit int) (events []Entity, err error) {
+	err = tx.Select(&events, "SELECT * FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1", limit)
+	return events, err
+}
+
+// MarkPublished - помечает события опубликованными и нумерует их в порядке id. Relay работает
+// в одном экземпляре под блокировкой, поэтому номера фиксируются строго по возрастанию
+func (r *Repository) MarkPublished(tx *sqlx.Tx, ids []int64) error {
+	query, args, err := sqlx.In(
+		`UPDATE outbox o SET published_at = NOW(), last_error = NULL, published_seq = s.seq
+		 FROM (SELECT id, nextval('outbox_published_seq') AS seq
+		       FROM (SELECT id FROM outbox WHERE id IN (?) ORDER BY id) ordered) s
+		 WHERE o.id = s.id`, ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(tx.Rebind(query), args...)
+	return err
+}
+
+func (r *Repository) MarkFailed(tx *sqlx.Tx, id int64, lastError string) error {
+	_, err := tx.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1", id, lastError)
+	return err
+}
+
+// DeletePublished - удаляет события, опубликованные раньше before
+func (r *Repository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE published_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
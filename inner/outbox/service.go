@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/common"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Service - публикация событий из outbox. Событие помечается опубликованным в той же
+// транзакции, в которой выбрано, только после успешной доставки: при сбое оно будет
+// доставлено повторно (at-least-once)
+type Service struct {
+	repo      Repo
+	publisher Publisher
+	batchSize int
+	logger    common.LoggerInterface
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	TryLock(tx *sqlx.Tx) (bool, error)
+	FindPending(tx *sqlx.Tx, limit int) ([]Entity, error)
+	MarkPublished(tx *sqlx.Tx, ids []int64) error
+	MarkFailed(tx *sqlx.Tx, id int64, lastError string) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewService(repo Repo, publisher Publisher, batchSize int, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		publisher: publisher,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Relay - публикует очередную порцию событий в порядке записи. После неудачи события
+// агрегата пропускаются до следующего вызова, чтобы не нарушить их порядок; события
+// остальных агрегатов публикуются. more - порция выбрана целиком и в outbox могут быть ещё события
+func (svc *Service) Relay(ctx context.Context) (published int, more bool, err error) {
+	err = common.InTx(svc.repo, "relaying events", func(tx *sqlx.Tx) error {
+		locked, err := svc.repo.TryLock(tx)
+		if err != nil {
+			return fmt.Errorf("Error locking outbox: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		events, err := svc.repo.FindPending(tx, svc.batchSize)
+		if err != nil {
+			return fmt.Errorf("Error finding pending events: %w", err)
+		}
+		var blocked = make(map[string]bool)
+		var ids []int64
+		for _, e := range events {
+			var aggregate = e.AggregateType + ":" + strconv.FormatInt(e.AggregateId, 10)
+			if blocked[aggregate] {
+				continue
+			}
+			if err := svc.publisher.Publish(ctx, e.ToMessage()); err != nil {
+				blocked[aggregate] = true
+				svc.logger.ErrorCtx(ctx, "Relay: error publishing event",
+					zap.Int64("id", e.Id), zap.String("type", e.Type), zap.String("aggregate", aggregate),
+					zap.Error(err))
+				if err := svc.repo.MarkFailed(tx, e.Id, err.Error()); err != nil {
+					return fmt.Errorf("Error marking event %d as failed: %w", e.Id, err)
+				}
+				continue
+			}
+			ids = append(ids, e.Id)
+		}
+		if len(ids) > 0 {
+			if err := svc.repo.MarkPublished(tx, ids); err != nil {
+				return fmt.Errorf("Error marking events as published: %w", err)
+			}
+		}
+		published = len(ids)
+		more = len(events) == svc.batchSize && len(blocked) == 0
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return published, more, nil
+}
+
+// Purge - удаляет события, опубликованные раньше before
+func (svc *Service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := svc.repo.DeletePublished(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("Error deleting events published before %s: %w", before, err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"idm/inner/testutil"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) TryLock(tx *sqlx.Tx) (bool, error) {
+	args := m.Called(tx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindPending(tx *sqlx.Tx, limit int) ([]Entity, error) {
+	args := m.Called(tx, limit)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) MarkPublished(tx *sqlx.Tx, ids []int64) error {
+	args := m.Called(tx, ids)
+	return args.Error(0)
+}
+
+func (m *MockRepo) MarkFailed(tx *sqlx.Tx, id int64, lastError string) error {
+	args := m.Called(tx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *MockRepo) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// StubPublisher - запоминает опубликованные события, события из failures отклоняет
+type StubPublisher struct {
+	published []int64
+	failures  map[int64]bool
+}
+
+func (p *StubPublisher) Publish(ctx context.Context, message Message) error {
+	if p.failures[message.Id] {
+		return errors.New("subscriber unavailable")
+	}
+	p.published = append(p.published, message.Id)
+	return nil
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+// pending - события 1 и 3 сотрудника 7, событие 2 роли 5
+func pending() []Entity {
+	return []Entity{
+		{Id: 1, AggregateType: AggregateEmployee, AggregateId: 7, Type: EmployeeCreated, Payload: []byte(`{"id":7}`)},
+		{Id: 2, AggregateType: AggregateRole, AggregateId: 5, Type: RoleCreated, Payload: []byte(`{"id":5}`)},
+		{Id: 3, AggregateType: AggregateEmployee, AggregateId: 7, Type: RoleAssigned, Payload: []byte(`{}`)},
+	}
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should publish pending events in order", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		publisher := &StubPublisher{}
+		svc := NewService(repo, publisher, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("TryLock", tx).Return(true, nil)
+		repo.On("FindPending", tx, 10).Return(pending(), nil)
+		repo.On("MarkPublished", tx, []int64{1, 2, 3}).Return(nil)
+
+		published, more, err := svc.Relay(ctx)
+
+		a.NoError(err)
+		a.Equal(3, published)
+		a.False(more)
+		a.Equal([]int64{1, 2, 3}, publisher.published)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should hold later events of failed aggregate", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		publisher := &StubPublisher{failures: map[int64]bool{1: true}}
+		svc := NewService(repo, publisher, 3, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("TryLock", tx).Return(true, nil)
+		repo.On("FindPending", tx, 3).Return(pending(), nil)
+		repo.On("MarkFailed", tx, int64(1), "subscriber unavailable").Return(nil)
+		repo.On("MarkPublished", tx, []int64{2}).Return(nil)
+
+		published, more, err := svc.Relay(ctx)
+
+		a.NoError(err)
+		a.Equal(1, published)
+		a.False(more, "failed aggregate must wait for the next pass")
+		a.Equal([]int64{2}, publisher.published)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should report more events when batch is full", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubPublisher{}, 3, &MockLogger{})
+		tx, _ := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("TryLock", tx).Return(true, nil)
+		repo.On("FindPending", tx, 3).Return(pending(), nil)
+		repo.On("MarkPublished", tx, []int64{1, 2, 3}).Return(nil)
+
+		_, more, err := svc.Relay(ctx)
+
+		a.NoError(err)
+		a.True(more)
+	})
+
+	t.Run("Should skip pass while another instance relays", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubPublisher{}, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("TryLock", tx).Return(false, nil)
+
+		published, more, err := svc.Relay(ctx)
+
+		a.NoError(err)
+		a.Zero(published)
+		a.False(more)
+		repo.AssertNotCalled(t, "FindPending", mock.Anything, mock.Anything)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should rollback so that published events are delivered again", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubPublisher{}, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("TryLock", tx).Return(true, nil)
+		repo.On("FindPending", tx, 10).Return(pending(), nil)
+		repo.On("MarkPublished", tx, []int64{1, 2, 3}).Return(errors.New("connection reset"))
+
+		published, _, err := svc.Relay(ctx)
+
+		a.Error(err)
+		a.Zero(published)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
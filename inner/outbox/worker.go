@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// purgeInterval - период удаления опубликованных событий старше срока хранения
+const purgeInterval = time.Hour
+
+type Relayer interface {
+	Relay(ctx context.Context) (published int, more bool, err error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RelayWorker - фоновый процесс, публикующий события из outbox. За один проход outbox
+// вычитывается до конца; раз в час удаляются события, опубликованные раньше retention
+type RelayWorker struct {
+	relayer   Relayer
+	interval  time.Duration
+	retention time.Duration
+	logger    *common.Logger
+	runner    common.Runner
+}
+
+func NewRelayWorker(relayer Relayer, interval, retention time.Duration, logger *common.Logger) *RelayWorker {
+	return &RelayWorker{
+		relayer:   relayer,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *RelayWorker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей публикации
+func (w *RelayWorker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *RelayWorker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var purge = time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	for {
+		w.relay(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			w.purge(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RelayWorker) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, more, err := w.relayer.Relay(ctx)
+		if err != nil {
+			w.logger.Error("RelayWorker: error relaying events", zap.Error(err))
+			return
+		}
+		if published > 0 {
+			w.logger.Debug("RelayWorker: events published", zap.Int("count", published))
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func (w *RelayWorker) purge(ctx context.Context) {
+	deleted, err := w.relayer.Purge(ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		w.logger.Error("RelayWorker: error purging published events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("RelayWorker: published events purged", zap.Int64("count", deleted))
+	}
+}
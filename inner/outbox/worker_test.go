@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// StubRelayer - первые backlog вызовов возвращают полную порцию
+type StubRelayer struct {
+	backlog int32
+	calls   atomic.Int32
+}
+
+func (s *StubRelayer) Relay(ctx context.Context) (int, bool, error) {
+	var call = s.calls.Add(1)
+	return 1, call <= s.backlog, nil
+}
+
+func (s *StubRelayer) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRelayWorker(t *testing.T) {
+	t.Run("Should drain backlog at once and stop cleanly", func(t *testing.T) {
+		a := assert.New(t)
+		relayer := &StubRelayer{backlog: 3}
+		worker := NewRelayWorker(relayer, time.Hour, time.Hour, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { return relayer.calls.Load() == 4 }, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		a.NoError(worker.Stop(ctx))
+		a.Equal(int32(4), relayer.calls.Load())
+	})
+}
@@ -0,0 +1,46 @@
+package permission
+
+import "time"
+
+type Entity struct {
+	Id          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	return Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name" example:"employee:read"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// Request - создание и изменение разрешения. Имя в формате "ресурс:действие"
+type Request struct {
+	Name        string `json:"name" validate:"required,max=100" example:"employee:read"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+func (req *Request) ToEntity() Entity {
+	return Entity{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+}
+
+type RoleRequest struct {
+	PermissionId int64 `json:"permission_id" validate:"required,min=1"`
+}
@@ -0,0 +1,280 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Add(ctx context.Context, request Request) (Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	Update(ctx context.Context, id int64, request Request) (Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	AddToRole(ctx context.Context, roleId int64, request RoleRequest) error
+	RemoveFromRole(ctx context.Context, roleId, permissionId int64) error
+	FindByRoleId(ctx context.Context, roleId int64) ([]Response, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/permissions" и "/api/v1/roles/:id/permissions"
+func (c *Handler) RegisterRoutes() {
+	var read = c.server.RequirePermission(web.PermPermissionRead)
+	var write = c.server.RequirePermission(web.PermPermissionWrite)
+	c.server.GroupApiV1.Get("/permissions", read, c.FindAll)
+	c.server.GroupApiV1.Get("/permissions/:id", read, c.FindById)
+	c.server.GroupApiV1.Post("/permissions", write, c.Add)
+	c.server.GroupApiV1.Put("/permissions/:id", write, c.Update)
+	c.server.GroupApiV1.Delete("/permissions/:id", write, c.DeleteById)
+	c.server.GroupApiV1.Get("/roles/:id/permissions", read, c.FindByRoleId)
+	c.server.GroupApiV1.Post("/roles/:id/permissions", write, c.AddToRole)
+	c.server.GroupApiV1.Delete("/roles/:id/permissions/:permissionId", write, c.RemoveFromRole)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/permissions"
+// @Description Create a new permission.
+// @Summary create permission
+// @Tags permission
+// @Accept json
+// @Produce json
+// @Param request body Request true "permission"
+// @Success 200 {object} common.Response[permission.Response]
+// @Failure 400 {object} common.Response[permission.Response] "invalid request or already exists"
+// @Failure 403 {object} common.Response[permission.Response] "Permission denied"
+// @Failure 500 {object} common.Response[permission.Response] "error db"
+// @Router /permissions [post]
+// @Security BearerAuth
+func (c *Handler) Add(ctx *fiber.Ctx) error {
+	var request Request
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Add: received request", zap.Any("request", request))
+	rsl, err := c.service.Add(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error adding permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/permissions/:id"
+// @Description Find permission by id.
+// @Summary find permission
+// @Tags permission
+// @Produce json
+// @Param id path int true "Permission ID"
+// @Success 200 {object} common.Response[permission.Response]
+// @Failure 400 {object} common.Response[permission.Response] "invalid request"
+// @Failure 404 {object} common.Response[permission.Response] "not found"
+// @Failure 500 {object} common.Response[permission.Response] "error db"
+// @Router /permissions/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/permissions"
+// @Description Get permissions catalog.
+// @Summary get permissions
+// @Tags permission
+// @Produce json
+// @Success 200 {object} common.Response[[]permission.Response]
+// @Failure 403 {object} common.Response[[]permission.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]permission.Response] "error db"
+// @Router /permissions [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding permissions", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/permissions/:id"
+// @Description Update permission name and description.
+// @Summary update permission
+// @Tags permission
+// @Accept json
+// @Produce json
+// @Param id path int true "Permission ID"
+// @Param request body Request true "permission"
+// @Success 200 {object} common.Response[permission.Response]
+// @Failure 400 {object} common.Response[permission.Response] "invalid request or already exists"
+// @Failure 404 {object} common.Response[permission.Response] "not found"
+// @Failure 500 {object} common.Response[permission.Response] "error db"
+// @Router /permissions/{id} [put]
+// @Security BearerAuth
+func (c *Handler) Update(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request Request
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Update(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error updating permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/permissions/:id"
+// @Description Delete permission by id.
+// @Summary delete permission
+// @Tags permission
+// @Produce json
+// @Param id path int true "Permission ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /permissions/{id} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteById(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error deleting permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/permissions"
+// @Description Find permissions granted to role directly.
+// @Summary find role permissions
+// @Tags permission
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} common.Response[[]permission.Response]
+// @Failure 400 {object} common.Response[[]permission.Response] "invalid request"
+// @Failure 500 {object} common.Response[[]permission.Response] "error db"
+// @Router /roles/{id}/permissions [get]
+// @Security BearerAuth
+func (c *Handler) FindByRoleId(ctx *fiber.Ctx) error {
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindByRoleId: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindByRoleId(ctx.Context(), roleId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindByRoleId: error finding permissions", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/roles/:id/permissions"
+// @Description Grant permission to role.
+// @Summary grant permission
+// @Tags permission
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param request body RoleRequest true "permission"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /roles/{id}/permissions [post]
+// @Security BearerAuth
+func (c *Handler) AddToRole(ctx *fiber.Ctx) error {
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddToRole: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request RoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddToRole: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.AddToRole(ctx.Context(), roleId, request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "AddToRole: error granting permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/roles/:id/permissions/:permissionId"
+// @Description Revoke permission from role.
+// @Summary revoke permission
+// @Tags permission
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param permissionId path int true "Permission ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 404 {object} common.Response[any] "permission is not granted"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /roles/{id}/permissions/{permissionId} [delete]
+// @Security BearerAuth
+func (c *Handler) RemoveFromRole(ctx *fiber.Ctx) error {
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "RemoveFromRole: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	permissionId, err := strconv.ParseInt(ctx.Params("permissionId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "RemoveFromRole: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.RemoveFromRole(ctx.Context(), roleId, permissionId); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "RemoveFromRole: error revoking permission", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,109 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) Add(ctx context.Context, permission Entity) (created Entity, err error) {
+	err = r.db.GetContext(ctx, &created,
+		"INSERT INTO permission(name, description) VALUES ($1, $2) RETURNING *",
+		permission.Name, permission.Description)
+	return created, err
+}
+
+func (r *Repository) ExistsByName(ctx context.Context, name string, excludeId int64) (isExists bool, err error) {
+	err = r.db.GetContext(ctx, &isExists,
+		"SELECT EXISTS(SELECT FROM permission WHERE name = $1 AND id <> $2)",
+		name, excludeId)
+	return isExists, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (permission Entity, err error) {
+	err = r.db.GetContext(ctx, &permission, "SELECT * FROM permission WHERE id = $1", id)
+	return permission, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) (permissions []Entity, err error) {
+	err = r.db.SelectContext(ctx, &permissions, "SELECT * FROM permission ORDER BY name")
+	return permissions, err
+}
+
+func (r *Repository) Update(ctx context.Context, permission Entity) (updated Entity, err error) {
+	err = r.db.GetContext(ctx, &updated,
+		`UPDATE permission SET name = $2, description = $3, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		permission.Id, permission.Name, permission.Description)
+	return updated, err
+}
+
+func (r *Repository) DeleteById(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM permission WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+func (r *Repository) AddToRole(ctx context.Context, roleId, permissionId int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO role_permission(role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		roleId, permissionId)
+	return err
+}
+
+func (r *Repository) RemoveFromRole(ctx context.Context, roleId, permissionId int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM role_permission WHERE role_id = $1 AND permission_id = $2",
+		roleId, permissionId)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindByRoleId - разрешения, выданные роли напрямую
+func (r *Repository) FindByRoleId(ctx context.Context, roleId int64) (permissions []Entity, err error) {
+	err = r.db.SelectContext(ctx, &permissions,
+		`SELECT p.* FROM permission p
+		 JOIN role_permission rp ON rp.permission_id = p.id
+		 WHERE rp.role_id = $1
+		 ORDER BY p.name`,
+		roleId)
+	return permissions, err
+}
+
+// FindPermissionsByRoleNames - имена разрешений ролей с учётом иерархии составных ролей.
+// Реализует web.PermissionResolver
+func (r *Repository) FindPermissionsByRoleNames(ctx context.Context, roleNames []string) (permissions []string, err error) {
+	err = r.db.SelectContext(ctx, &permissions,
+		`WITH RECURSIVE roles AS (
+			SELECT id FROM role WHERE name = ANY($1)
+			UNION
+			SELECT rc.child_role_id FROM role_composite rc
+			JOIN roles r ON rc.parent_role_id = r.id
+		)
+		SELECT DISTINCT p.name FROM permission p
+		JOIN role_permission rp ON rp.permission_id = p.id
+		WHERE rp.role_id IN (SELECT id FROM roles)
+		ORDER BY p.name`,
+		pq.Array(roleNames))
+	return permissions, err
+}
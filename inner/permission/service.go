@@ -0,0 +1,167 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// namePattern - формат имени разрешения "ресурс:действие", например "employee:read"
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[a-z][a-z0-9_-]*$`)
+
+type Service struct {
+	repo      Repo
+	validator *validator.Validate
+}
+
+type Repo interface {
+	Add(ctx context.Context, permission Entity) (Entity, error)
+	ExistsByName(ctx context.Context, name string, excludeId int64) (bool, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	Update(ctx context.Context, permission Entity) (Entity, error)
+	DeleteById(ctx context.Context, id int64) (bool, error)
+	AddToRole(ctx context.Context, roleId, permissionId int64) error
+	RemoveFromRole(ctx context.Context, roleId, permissionId int64) (bool, error)
+	FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error)
+}
+
+func NewService(repo Repo) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator.New(),
+	}
+}
+
+func (svc *Service) Add(ctx context.Context, request Request) (Response, error) {
+	if err := svc.validate(ctx, request, 0); err != nil {
+		return Response{}, err
+	}
+	created, err := svc.repo.Add(ctx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("Error adding permission %s: %w", request.Name, err)
+	}
+	return created.ToResponse(), nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id permission: %d", id)}
+	}
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Permission with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding permission with id %d: %w", id, err)
+	}
+	return entity.ToResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding permissions: %w", err)
+	}
+	return toResponses(entities), nil
+}
+
+func (svc *Service) Update(ctx context.Context, id int64, request Request) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id permission: %d", id)}
+	}
+	if err := svc.validate(ctx, request, id); err != nil {
+		return Response{}, err
+	}
+	var entity = request.ToEntity()
+	entity.Id = id
+	updated, err := svc.repo.Update(ctx, entity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Permission with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error updating permission with id %d: %w", id, err)
+	}
+	return updated.ToResponse(), nil
+}
+
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id permission: %d", id)}
+	}
+	deleted, err := svc.repo.DeleteById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting permission with id %d: %w", id, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("Permission with id %d not found", id)}
+	}
+	return nil
+}
+
+func (svc *Service) AddToRole(ctx context.Context, roleId int64, request RoleRequest) error {
+	if err := svc.validator.Struct(request); err != nil || roleId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong role id %d or permission id %d", roleId, request.PermissionId)}
+	}
+	if err := svc.repo.AddToRole(ctx, roleId, request.PermissionId); err != nil {
+		return fmt.Errorf("Error granting permission %d to role %d: %w", request.PermissionId, roleId, err)
+	}
+	return nil
+}
+
+func (svc *Service) RemoveFromRole(ctx context.Context, roleId, permissionId int64) error {
+	if roleId <= 0 || permissionId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong role id %d or permission id %d", roleId, permissionId)}
+	}
+	removed, err := svc.repo.RemoveFromRole(ctx, roleId, permissionId)
+	if err != nil {
+		return fmt.Errorf("Error revoking permission %d from role %d: %w", permissionId, roleId, err)
+	}
+	if !removed {
+		return common.NotFoundError{Message: fmt.Sprintf("Permission %d is not granted to role %d", permissionId, roleId)}
+	}
+	return nil
+}
+
+func (svc *Service) FindByRoleId(ctx context.Context, roleId int64) ([]Response, error) {
+	if roleId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id role: %d", roleId)}
+	}
+	entities, err := svc.repo.FindByRoleId(ctx, roleId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding permissions of role %d: %w", roleId, err)
+	}
+	return toResponses(entities), nil
+}
+
+func (svc *Service) validate(ctx context.Context, request Request, excludeId int64) error {
+	if err := svc.validator.Struct(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	if !namePattern.MatchString(request.Name) {
+		return common.RequestValidationError{
+			Message: fmt.Sprintf("Permission name %q must match resource:action, e.g. employee:read", request.Name),
+		}
+	}
+	isExists, err := svc.repo.ExistsByName(ctx, request.Name, excludeId)
+	if err != nil {
+		return fmt.Errorf("Error checking permission %s existence: %w", request.Name, err)
+	}
+	if isExists {
+		return common.AlreadyExistsError{Message: fmt.Sprintf("Permission %s already exists", request.Name)}
+	}
+	return nil
+}
+
+func toResponses(entities []Entity) []Response {
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses
+}
@@ -0,0 +1,167 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Add(ctx context.Context, permission Entity) (Entity, error) {
+	args := m.Called(ctx, permission)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsByName(ctx context.Context, name string, excludeId int64) (bool, error) {
+	args := m.Called(ctx, name, excludeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) Update(ctx context.Context, permission Entity) (Entity, error) {
+	args := m.Called(ctx, permission)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) AddToRole(ctx context.Context, roleId, permissionId int64) error {
+	args := m.Called(ctx, roleId, permissionId)
+	return args.Error(0)
+}
+
+func (m *MockRepo) RemoveFromRole(ctx context.Context, roleId, permissionId int64) (bool, error) {
+	args := m.Called(ctx, roleId, permissionId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error) {
+	args := m.Called(ctx, roleId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func TestAddPermission(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add permission", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("ExistsByName", ctx, "payment:approve", int64(0)).Return(false, nil)
+		repo.On("Add", ctx, Entity{Name: "payment:approve", Description: "Approve"}).
+			Return(Entity{Id: 12, Name: "payment:approve", Description: "Approve"}, nil)
+
+		got, err := svc.Add(ctx, Request{Name: "payment:approve", Description: "Approve"})
+
+		a.NoError(err)
+		a.Equal(int64(12), got.Id)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject name not in resource:action format", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRepo))
+
+		for _, name := range []string{"employee", "Employee:Read", "employee:", ":read", "employee:read:all"} {
+			_, err := svc.Add(ctx, Request{Name: name})
+			a.True(errors.As(err, &common.RequestValidationError{}), name)
+		}
+	})
+
+	t.Run("Should reject duplicate", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("ExistsByName", ctx, "employee:read", int64(0)).Return(true, nil)
+
+		_, err := svc.Add(ctx, Request{Name: "employee:read"})
+
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+	})
+}
+
+func TestFindPermissionById(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return NotFoundError", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("FindById", ctx, int64(4)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.FindById(ctx, 4)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestUpdatePermission(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should exclude itself from duplicate check", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("ExistsByName", ctx, "employee:read", int64(3)).Return(false, nil)
+		repo.On("Update", ctx, Entity{Id: 3, Name: "employee:read", Description: "Read"}).
+			Return(Entity{Id: 3, Name: "employee:read", Description: "Read"}, nil)
+
+		got, err := svc.Update(ctx, 3, Request{Name: "employee:read", Description: "Read"})
+
+		a.NoError(err)
+		a.Equal("Read", got.Description)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestRolePermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should grant permission to role", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("AddToRole", ctx, int64(1), int64(2)).Return(nil)
+
+		a.NoError(svc.AddToRole(ctx, 1, RoleRequest{PermissionId: 2}))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should return NotFoundError when permission is not granted", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("RemoveFromRole", ctx, int64(1), int64(2)).Return(false, nil)
+
+		err := svc.RemoveFromRole(ctx, 1, 2)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+}
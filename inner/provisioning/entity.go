@@ -0,0 +1,151 @@
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Состояния провижининга сотрудника
+const (
+	// StatePending - ожидается синхронизация: после неудачной попытки или расхождения, найденного сверкой
+	StatePending = "PENDING"
+	// StateSynced - учётная запись и роли во внешней системе совпадают с IDM
+	StateSynced = "SYNCED"
+	// StateFailed - все попытки исчерпаны, нужен повтор вручную
+	StateFailed = "FAILED"
+	// StateSkipped - у сотрудника нет логина, учётная запись не создаётся
+	StateSkipped = "SKIPPED"
+	// StateDisabled - сотрудник удалён, учётная запись отключена
+	StateDisabled = "DISABLED"
+)
+
+// Connector - внешняя система учётных записей. Пользователь адресуется идентификатором
+// externalId, который система вернула при создании
+type Connector interface {
+	// CreateUser - создаёт пользователя; уже существующий пользователь с тем же логином
+	// обновляется и возвращается его идентификатор
+	CreateUser(ctx context.Context, user User) (externalId string, err error)
+	// UpdateUser - common.NotFoundError, если пользователь удалён во внешней системе
+	UpdateUser(ctx context.Context, externalId string, user User) error
+	DisableUser(ctx context.Context, externalId string) error
+	GrantRole(ctx context.Context, externalId string, role string) error
+	RevokeRole(ctx context.Context, externalId string, role string) error
+	// ListUsers - все учётные записи сотрудников, без технических (service account)
+	ListUsers(ctx context.Context) ([]Account, error)
+	// FindUserRoles - роли, выданные пользователю напрямую
+	FindUserRoles(ctx context.Context, externalId string) ([]string, error)
+}
+
+// User - учётная запись сотрудника во внешней системе
+type User struct {
+	Login     string
+	FirstName string
+	LastName  string
+	Email     string
+	Enabled   bool
+}
+
+// Account - учётная запись во внешней системе
+type Account struct {
+	ExternalId string
+	User
+}
+
+// Status - результат последней синхронизации сотрудника. Хранится и после удаления
+// сотрудника, чтобы отключить его учётную запись. Roles - роли, выданные во внешней
+// системе через IDM: отзываются только они, роли, выданные там напрямую, не трогаются
+type Status struct {
+	EmployeeId int64          `db:"employee_id"`
+	ExternalId sql.NullString `db:"external_id"`
+	Login      sql.NullString `db:"login"`
+	Roles      pq.StringArray `db:"roles"`
+	State      string         `db:"state"`
+	Attempts   int            `db:"attempts"`
+	LastError  sql.NullString `db:"last_error"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+}
+
+func (e *Status) ToResponse() StatusResponse {
+	var roles = []string(e.Roles)
+	if roles == nil {
+		roles = []string{}
+	}
+	return StatusResponse{
+		EmployeeId: e.EmployeeId,
+		ExternalId: e.ExternalId.String,
+		Login:      e.Login.String,
+		Roles:      roles,
+		State:      e.State,
+		Attempts:   e.Attempts,
+		LastError:  e.LastError.String,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+type StatusResponse struct {
+	EmployeeId int64     `json:"employee_id"`
+	ExternalId string    `json:"external_id,omitempty"`
+	Login      string    `json:"login,omitempty"`
+	Roles      []string  `json:"roles"`
+	State      string    `json:"state" example:"SYNCED"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// StatusRequest - фильтр списка статусов; пустое состояние - все сотрудники
+type StatusRequest struct {
+	State string `query:"state" validate:"omitempty,oneof=PENDING SYNCED FAILED SKIPPED DISABLED"`
+}
+
+// Options - параметры повторов: попытка n ждёт RetryInterval * 2^(n-1)
+type Options struct {
+	MaxAttempts   int
+	RetryInterval time.Duration
+}
+
+// AssignedRole - роль действующего назначения сотрудника
+type AssignedRole struct {
+	EmployeeId int64  `db:"employee_id"`
+	Name       string `db:"name"`
+}
+
+// Виды расхождений, найденных сверкой
+const (
+	// FindingOrphan - включённая учётная запись из статуса синхронизации без действующего сотрудника
+	FindingOrphan = "ORPHAN"
+	// FindingMissing - у действующего сотрудника с логином нет включённой учётной записи
+	FindingMissing = "MISSING"
+	// FindingRoleMismatch - роли учётной записи отличаются от назначенных в IDM
+	FindingRoleMismatch = "ROLE_MISMATCH"
+)
+
+// ReconcileRequest - Apply: исправить внешнюю систему, иначе только отчёт
+type ReconcileRequest struct {
+	Apply bool `query:"apply"`
+}
+
+// Finding - расхождение между IDM и внешней системой. Applied - учётная запись отключена
+// (ORPHAN) или сотрудник поставлен в очередь синхронизации (MISSING, ROLE_MISMATCH)
+type Finding struct {
+	Kind         string   `json:"kind" example:"ROLE_MISMATCH"`
+	EmployeeId   int64    `json:"employee_id,omitempty"`
+	Login        string   `json:"login,omitempty"`
+	ExternalId   string   `json:"external_id,omitempty"`
+	MissingRoles []string `json:"missing_roles,omitempty"`
+	ExtraRoles   []string `json:"extra_roles,omitempty"`
+	Applied      bool     `json:"applied"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Report - результат сверки. Сравниваются только роли, заведённые в IDM: роли самой
+// внешней системы (например, offline_access) в расхождения не попадают
+type Report struct {
+	Apply     bool      `json:"apply"`
+	Employees int       `json:"employees"`
+	Accounts  int       `json:"accounts"`
+	Findings  []Finding `json:"findings"`
+}
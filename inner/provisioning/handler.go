@@ -0,0 +1,147 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	FindStatus(ctx context.Context, employeeId int64) (StatusResponse, error)
+	FindStatuses(ctx context.Context, request StatusRequest) ([]StatusResponse, error)
+	Retry(ctx context.Context, employeeId int64) error
+	Reconcile(ctx context.Context, request ReconcileRequest) (Report, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/provisioning" и "/internal/reconcile";
+// сверка, как и повтор синхронизации, доступна с разрешением provisioning:write
+func (c *Handler) RegisterRoutes() {
+	var read = c.server.RequirePermission(web.PermProvisioningRead)
+	var write = c.server.RequirePermission(web.PermProvisioningWrite)
+	c.server.GroupApiV1.Get("/provisioning/status", read, c.FindStatuses)
+	c.server.GroupApiV1.Get("/provisioning/status/:employeeId", read, c.FindStatus)
+	c.server.GroupApiV1.Post("/provisioning/status/:employeeId/retry", write, c.Retry)
+	c.server.GroupInternal.Post("/reconcile", write, c.Reconcile)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/provisioning/status"
+// @Description Provisioning statuses of employees, optionally filtered by state.
+// @Summary get provisioning statuses
+// @Tags provisioning
+// @Produce json
+// @Param state query string false "PENDING, SYNCED, FAILED, SKIPPED or DISABLED"
+// @Success 200 {object} common.Response[[]provisioning.StatusResponse]
+// @Failure 400 {object} common.Response[[]provisioning.StatusResponse] "invalid request"
+// @Failure 403 {object} common.Response[[]provisioning.StatusResponse] "Permission denied"
+// @Failure 500 {object} common.Response[[]provisioning.StatusResponse] "error db"
+// @Router /provisioning/status [get]
+// @Security BearerAuth
+func (c *Handler) FindStatuses(ctx *fiber.Ctx) error {
+	var request StatusRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindStatuses: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindStatuses(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindStatuses: error finding provisioning statuses", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/provisioning/status/:employeeId"
+// @Description Provisioning status of the employee: external account, roles granted through IDM and last error.
+// @Summary get provisioning status
+// @Tags provisioning
+// @Produce json
+// @Param employeeId path int true "Employee ID"
+// @Success 200 {object} common.Response[provisioning.StatusResponse]
+// @Failure 400 {object} common.Response[provisioning.StatusResponse] "invalid request"
+// @Failure 403 {object} common.Response[provisioning.StatusResponse] "Permission denied"
+// @Failure 404 {object} common.Response[provisioning.StatusResponse] "not provisioned yet"
+// @Failure 500 {object} common.Response[provisioning.StatusResponse] "error db"
+// @Router /provisioning/status/{employeeId} [get]
+// @Security BearerAuth
+func (c *Handler) FindStatus(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("employeeId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindStatus: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindStatus(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindStatus: error finding provisioning status", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/provisioning/status/:employeeId/retry"
+// @Description Queue the employee for provisioning regardless of the current state. Synchronization runs asynchronously.
+// @Summary retry provisioning
+// @Tags provisioning
+// @Produce json
+// @Param employeeId path int true "Employee ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Router /provisioning/status/{employeeId}/retry [post]
+// @Security BearerAuth
+func (c *Handler) Retry(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("employeeId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Retry: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err = c.service.Retry(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Retry: error queueing employee", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Reconcile - сверка сотрудников с внешней системой; "?apply=true" исправляет расхождения
+func (c *Handler) Reconcile(ctx *fiber.Ctx) error {
+	var request ReconcileRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Reconcile: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Reconcile(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Reconcile: error reconciling employees", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package provisioning
+
+import (
+	"context"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) FindStatus(ctx context.Context, employeeId int64) (StatusResponse, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).(StatusResponse), args.Error(1)
+}
+
+func (m *MockService) FindStatuses(ctx context.Context, request StatusRequest) ([]StatusResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).([]StatusResponse), args.Error(1)
+}
+
+func (m *MockService) Retry(ctx context.Context, employeeId int64) error {
+	args := m.Called(ctx, employeeId)
+	return args.Error(0)
+}
+
+func (m *MockService) Reconcile(ctx context.Context, request ReconcileRequest) (Report, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+// newTestServer - токен с ролями roles проверен, как в main, и для "/api", и для "/internal/reconcile"
+func newTestServer(roles []string, svc Svc) *web.Server {
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := web.NewServer()
+	claims := &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+	server.GroupApi.Use(auth)
+	server.GroupInternal.Use("/reconcile", auth)
+	NewHandler(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestReconcileHandler(t *testing.T) {
+	t.Run("Should reconcile in apply mode for admin", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		svc.On("Reconcile", mock.Anything, ReconcileRequest{Apply: true}).Return(Report{Apply: true}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/internal/reconcile?apply=true", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("Should return 403 without permission", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		server := newTestServer([]string{web.IdmUser}, svc)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/internal/reconcile?apply=true", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Reconcile", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,343 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenLeeway - токен обновляется заранее, чтобы не истёк во время запроса
+const tokenLeeway = 30 * time.Second
+
+// usersPageSize - размер страницы при чтении пользователей realm
+const usersPageSize = 100
+
+// KeycloakConfig - доступ к admin REST API Keycloak. Клиент ClientId должен иметь
+// service account с ролью manage-users клиента realm-management
+type KeycloakConfig struct {
+	// BaseUrl - адрес Keycloak без "/admin", например "https://keycloak:8443"
+	BaseUrl      string
+	Realm        string
+	ClientId     string
+	ClientSecret string
+	Timeout      time.Duration
+}
+
+// KeycloakConnector - Connector поверх admin REST API Keycloak: пользователи realm
+// и роли realm (realm role mappings)
+type KeycloakConnector struct {
+	cfg    KeycloakConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// ResponseError - неуспешный ответ Keycloak
+type ResponseError struct {
+	Method string
+	Url    string
+	Status int
+	Body   string
+}
+
+func (err ResponseError) Error() string {
+	return fmt.Sprintf("keycloak %s %s: status %d: %s", err.Method, err.Url, err.Status, err.Body)
+}
+
+type keycloakUser struct {
+	Id        string `json:"id,omitempty"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	// ServiceAccountClientId - заполнен у service account клиента
+	ServiceAccountClientId string `json:"serviceAccountClientId,omitempty"`
+}
+
+type keycloakRole struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+func NewKeycloakConnector(cfg KeycloakConfig) *KeycloakConnector {
+	return &KeycloakConnector{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (c *KeycloakConnector) CreateUser(ctx context.Context, user User) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, c.adminUrl("users"), toKeycloakUser(user))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		// идентификатор созданного пользователя - последний сегмент Location
+		return path.Base(resp.Header.Get("Location")), nil
+	case http.StatusConflict:
+		id, err := c.findUserId(ctx, user.Login)
+		if err != nil {
+			return "", err
+		}
+		return id, c.UpdateUser(ctx, id, user)
+	default:
+		return "", responseError(resp)
+	}
+}
+
+func (c *KeycloakConnector) UpdateUser(ctx context.Context, externalId string, user User) error {
+	return c.expect(ctx, http.MethodPut, c.adminUrl("users", externalId), toKeycloakUser(user), http.StatusNoContent)
+}
+
+// DisableUser - учётная запись отключается, а не удаляется: сохраняется история входов
+func (c *KeycloakConnector) DisableUser(ctx context.Context, externalId string) error {
+	var err = c.expect(ctx, http.MethodPut, c.adminUrl("users", externalId), map[string]bool{"enabled": false},
+		http.StatusNoContent)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// GrantRole - роль, отсутствующая в realm, создаётся
+func (c *KeycloakConnector) GrantRole(ctx context.Context, externalId string, role string) error {
+	rep, err := c.findRole(ctx, role)
+	if isNotFound(err) {
+		err = c.expect(ctx, http.MethodPost, c.adminUrl("roles"), keycloakRole{Name: role}, http.StatusCreated)
+		if err == nil {
+			rep, err = c.findRole(ctx, role)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return c.expect(ctx, http.MethodPost, c.adminUrl("users", externalId, "role-mappings", "realm"),
+		[]keycloakRole{rep}, http.StatusNoContent)
+}
+
+// RevokeRole - отзыв роли, удалённой из realm, ничего не делает
+func (c *KeycloakConnector) RevokeRole(ctx context.Context, externalId string, role string) error {
+	rep, err := c.findRole(ctx, role)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.expect(ctx, http.MethodDelete, c.adminUrl("users", externalId, "role-mappings", "realm"),
+		[]keycloakRole{rep}, http.StatusNoContent)
+}
+
+// ListUsers - пользователи realm постранично, service account клиентов пропускаются
+func (c *KeycloakConnector) ListUsers(ctx context.Context) ([]Account, error) {
+	var accounts []Account
+	for first := 0; ; first += usersPageSize {
+		var users []keycloakUser
+		var query = url.Values{"first": {strconv.Itoa(first)}, "max": {strconv.Itoa(usersPageSize)}}
+		if err := c.get(ctx, c.adminUrl("users")+"?"+query.Encode(), &users); err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u.ServiceAccountClientId != "" {
+				continue
+			}
+			accounts = append(accounts, Account{
+				ExternalId: u.Id,
+				User: User{
+					Login:     u.Username,
+					FirstName: u.FirstName,
+					LastName:  u.LastName,
+					Email:     u.Email,
+					Enabled:   u.Enabled,
+				},
+			})
+		}
+		if len(users) < usersPageSize {
+			return accounts, nil
+		}
+	}
+}
+
+// FindUserRoles - роли realm, выданные пользователю напрямую, составные роли не раскрываются
+func (c *KeycloakConnector) FindUserRoles(ctx context.Context, externalId string) ([]string, error) {
+	var roles []keycloakRole
+	if err := c.get(ctx, c.adminUrl("users", externalId, "role-mappings", "realm"), &roles); err != nil {
+		return nil, err
+	}
+	var names = make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names, nil
+}
+
+func (c *KeycloakConnector) findUserId(ctx context.Context, login string) (string, error) {
+	var users []keycloakUser
+	var query = url.Values{"username": {login}, "exact": {"true"}}
+	if err := c.get(ctx, c.adminUrl("users")+"?"+query.Encode(), &users); err != nil {
+		return "", err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Username, login) {
+			return u.Id, nil
+		}
+	}
+	return "", common.NotFoundError{Message: fmt.Sprintf("Keycloak user %s not found", login)}
+}
+
+func (c *KeycloakConnector) findRole(ctx context.Context, role string) (rep keycloakRole, err error) {
+	err = c.get(ctx, c.adminUrl("roles", role), &rep)
+	return rep, err
+}
+
+func (c *KeycloakConnector) get(ctx context.Context, target string, result any) error {
+	resp, err := c.do(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("keycloak GET %s: error decoding response: %w", target, err)
+	}
+	return nil
+}
+
+// expect - запрос, успешный только со статусом status; 404 - common.NotFoundError
+func (c *KeycloakConnector) expect(ctx context.Context, method, target string, body any, status int) error {
+	resp, err := c.do(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != status {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (c *KeycloakConnector) do(ctx context.Context, method, target string, body any) (*http.Response, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("keycloak %s %s: error encoding request: %w", method, target, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("keycloak %s %s: %w", method, target, err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// токен отозван раньше срока - следующая попытка получит новый
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// accessToken - токен service account по client credentials, кэшируется до истечения
+func (c *KeycloakConnector) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+	var target = c.cfg.BaseUrl + "/realms/" + url.PathEscape(c.cfg.Realm) + "/protocol/openid-connect/token"
+	var form = url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.cfg.ClientId},
+		"client_secret": {c.cfg.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("keycloak token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("keycloak token: error decoding response: %w", err)
+	}
+	c.token = token.AccessToken
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenLeeway)
+	return c.token, nil
+}
+
+func (c *KeycloakConnector) adminUrl(segments ...string) string {
+	var target = c.cfg.BaseUrl + "/admin/realms/" + url.PathEscape(c.cfg.Realm)
+	for _, segment := range segments {
+		target += "/" + url.PathEscape(segment)
+	}
+	return target
+}
+
+func toKeycloakUser(user User) keycloakUser {
+	return keycloakUser{
+		Username:  user.Login,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Enabled:   user.Enabled,
+	}
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var err = ResponseError{
+		Method: resp.Request.Method,
+		Url:    resp.Request.URL.String(),
+		Status: resp.StatusCode,
+		Body:   strings.TrimSpace(string(body)),
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return common.NotFoundError{Message: err.Error()}
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	return errors.As(err, &common.NotFoundError{})
+}
@@ -0,0 +1,311 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKeycloak - admin REST API Keycloak в памяти: realm "idm", клиент "idm-provisioner"
+type fakeKeycloak struct {
+	*httptest.Server
+	mu       sync.Mutex
+	users    map[string]keycloakUser
+	roles    map[string]keycloakRole
+	mappings map[string][]string
+	tokens   int
+	// fail - статус, которым отвечают все запросы admin API; 0 - обычная работа
+	fail int
+	// rejectRole - роль, выдача которой отклоняется
+	rejectRole string
+}
+
+func newFakeKeycloak(t *testing.T) *fakeKeycloak {
+	var kc = &fakeKeycloak{
+		users:    map[string]keycloakUser{},
+		roles:    map[string]keycloakRole{},
+		mappings: map[string][]string{},
+	}
+	var mux = http.NewServeMux()
+	mux.HandleFunc("POST /realms/idm/protocol/openid-connect/token", kc.token)
+	mux.HandleFunc("POST /admin/realms/idm/users", kc.authorized(kc.createUser))
+	mux.HandleFunc("GET /admin/realms/idm/users", kc.authorized(kc.findUsers))
+	mux.HandleFunc("PUT /admin/realms/idm/users/{id}", kc.authorized(kc.updateUser))
+	mux.HandleFunc("GET /admin/realms/idm/roles/{name}", kc.authorized(kc.findRole))
+	mux.HandleFunc("POST /admin/realms/idm/roles", kc.authorized(kc.createRole))
+	mux.HandleFunc("POST /admin/realms/idm/users/{id}/role-mappings/realm", kc.authorized(kc.mapRoles))
+	mux.HandleFunc("DELETE /admin/realms/idm/users/{id}/role-mappings/realm", kc.authorized(kc.mapRoles))
+	mux.HandleFunc("GET /admin/realms/idm/users/{id}/role-mappings/realm", kc.authorized(kc.findMappings))
+	kc.Server = httptest.NewServer(mux)
+	t.Cleanup(kc.Close)
+	return kc
+}
+
+func (kc *fakeKeycloak) connector() *KeycloakConnector {
+	return NewKeycloakConnector(KeycloakConfig{
+		BaseUrl:      kc.URL,
+		Realm:        "idm",
+		ClientId:     "idm-provisioner",
+		ClientSecret: "secret",
+	})
+}
+
+func (kc *fakeKeycloak) token(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_secret") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	kc.mu.Lock()
+	kc.tokens++
+	kc.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 300})
+}
+
+func (kc *fakeKeycloak) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		kc.mu.Lock()
+		defer kc.mu.Unlock()
+		if kc.fail != 0 {
+			w.WriteHeader(kc.fail)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (kc *fakeKeycloak) createUser(w http.ResponseWriter, r *http.Request) {
+	var user keycloakUser
+	_ = json.NewDecoder(r.Body).Decode(&user)
+	for _, u := range kc.users {
+		if strings.EqualFold(u.Username, user.Username) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+	user.Id = "kc-" + strconv.Itoa(len(kc.users)+1)
+	kc.users[user.Id] = user
+	w.Header().Set("Location", kc.URL+"/admin/realms/idm/users/"+user.Id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// findUsers - поиск по username или постраничный список, отсортированный по username
+func (kc *fakeKeycloak) findUsers(w http.ResponseWriter, r *http.Request) {
+	var found = []keycloakUser{}
+	var username = r.URL.Query().Get("username")
+	for _, u := range kc.users {
+		if username == "" || strings.EqualFold(u.Username, username) {
+			found = append(found, u)
+		}
+	}
+	slices.SortFunc(found, func(a, b keycloakUser) int { return strings.Compare(a.Username, b.Username) })
+	if first, err := strconv.Atoi(r.URL.Query().Get("first")); err == nil {
+		var max, _ = strconv.Atoi(r.URL.Query().Get("max"))
+		found = found[min(first, len(found)):min(first+max, len(found))]
+	}
+	_ = json.NewEncoder(w).Encode(found)
+}
+
+// updateUser - как в Keycloak, переданные поля заменяют текущие
+func (kc *fakeKeycloak) updateUser(w http.ResponseWriter, r *http.Request) {
+	var user, ok = kc.users[r.PathValue("id")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewDecoder(r.Body).Decode(&user)
+	kc.users[user.Id] = user
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (kc *fakeKeycloak) findRole(w http.ResponseWriter, r *http.Request) {
+	var role, ok = kc.roles[r.PathValue("name")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(role)
+}
+
+func (kc *fakeKeycloak) createRole(w http.ResponseWriter, r *http.Request) {
+	var role keycloakRole
+	_ = json.NewDecoder(r.Body).Decode(&role)
+	role.Id = "role-" + role.Name
+	kc.roles[role.Name] = role
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (kc *fakeKeycloak) mapRoles(w http.ResponseWriter, r *http.Request) {
+	var id = r.PathValue("id")
+	if _, ok := kc.users[id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var roles []keycloakRole
+	_ = json.NewDecoder(r.Body).Decode(&roles)
+	for _, role := range roles {
+		if role.Name == kc.rejectRole {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		kc.mappings[id] = slices.DeleteFunc(kc.mappings[id], func(name string) bool { return name == role.Name })
+		if r.Method == http.MethodPost {
+			kc.mappings[id] = append(kc.mappings[id], role.Name)
+		}
+	}
+	slices.Sort(kc.mappings[id])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (kc *fakeKeycloak) findMappings(w http.ResponseWriter, r *http.Request) {
+	var id = r.PathValue("id")
+	if _, ok := kc.users[id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var roles = []keycloakRole{}
+	for _, name := range kc.mappings[id] {
+		roles = append(roles, keycloakRole{Id: "role-" + name, Name: name})
+	}
+	_ = json.NewEncoder(w).Encode(roles)
+}
+
+func TestKeycloakConnector(t *testing.T) {
+	ctx := context.Background()
+	var john = User{Login: "jdoe", FirstName: "John", LastName: "Doe", Email: "john@corp.example", Enabled: true}
+
+	t.Run("Should create user and cache access token", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		connector := kc.connector()
+
+		id, err := connector.CreateUser(ctx, john)
+		a.NoError(err)
+		a.NoError(connector.UpdateUser(ctx, id, User{Login: "jdoe", FirstName: "Jon", LastName: "Doe", Enabled: true}))
+
+		a.Equal("kc-1", id)
+		a.Equal("Jon", kc.users[id].FirstName)
+		a.Equal(1, kc.tokens)
+	})
+
+	t.Run("Should adopt existing user with the same login", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		kc.users["kc-7"] = keycloakUser{Id: "kc-7", Username: "JDOE", Enabled: false}
+
+		id, err := kc.connector().CreateUser(ctx, john)
+
+		a.NoError(err)
+		a.Equal("kc-7", id)
+		a.Len(kc.users, 1)
+		a.True(kc.users["kc-7"].Enabled)
+		a.Equal("john@corp.example", kc.users["kc-7"].Email)
+	})
+
+	t.Run("Should return not found for user deleted in keycloak", func(t *testing.T) {
+		t.Parallel()
+		kc := newFakeKeycloak(t)
+
+		err := kc.connector().UpdateUser(ctx, "kc-404", john)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should create missing realm role on grant and ignore it on revoke", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		connector := kc.connector()
+		id, err := connector.CreateUser(ctx, john)
+		a.NoError(err)
+
+		a.NoError(connector.GrantRole(ctx, id, "LEDGER"))
+		a.NoError(connector.GrantRole(ctx, id, "AUDIT"))
+		a.NoError(connector.RevokeRole(ctx, id, "LEDGER"))
+		a.NoError(connector.RevokeRole(ctx, id, "UNKNOWN"))
+
+		a.Contains(kc.roles, "LEDGER")
+		a.Equal([]string{"AUDIT"}, kc.mappings[id])
+	})
+
+	t.Run("Should disable user and ignore missing one", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		connector := kc.connector()
+		id, err := connector.CreateUser(ctx, john)
+		a.NoError(err)
+
+		a.NoError(connector.DisableUser(ctx, id))
+		a.NoError(connector.DisableUser(ctx, "kc-404"))
+
+		a.False(kc.users[id].Enabled)
+		a.Equal("jdoe", kc.users[id].Username)
+	})
+
+	t.Run("Should list users page by page without service accounts", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		for i := range usersPageSize + 1 {
+			var id = fmt.Sprintf("kc-%03d", i)
+			kc.users[id] = keycloakUser{Id: id, Username: fmt.Sprintf("user%03d", i), Enabled: true}
+		}
+		kc.users["kc-sa"] = keycloakUser{Id: "kc-sa", Username: "service-account-idm-provisioner", Enabled: true,
+			ServiceAccountClientId: "idm-provisioner"}
+		kc.mappings["kc-000"] = []string{"AUDIT", "offline_access"}
+		connector := kc.connector()
+
+		accounts, err := connector.ListUsers(ctx)
+		a.NoError(err)
+		roles, err := connector.FindUserRoles(ctx, "kc-000")
+		a.NoError(err)
+
+		a.Len(accounts, usersPageSize+1)
+		a.Equal(Account{ExternalId: "kc-000", User: User{Login: "user000", Enabled: true}}, accounts[0])
+		a.Equal("user100", accounts[usersPageSize].Login)
+		a.Equal([]string{"AUDIT", "offline_access"}, roles)
+	})
+
+	t.Run("Should return response error", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		kc.fail = http.StatusServiceUnavailable
+
+		_, err := kc.connector().CreateUser(ctx, john)
+
+		var responseErr ResponseError
+		a.True(errors.As(err, &responseErr))
+		a.Equal(http.StatusServiceUnavailable, responseErr.Status)
+		a.Equal(http.MethodPost, responseErr.Method)
+	})
+
+	t.Run("Should fail on rejected client credentials", func(t *testing.T) {
+		t.Parallel()
+		kc := newFakeKeycloak(t)
+		connector := NewKeycloakConnector(KeycloakConfig{BaseUrl: kc.URL, Realm: "idm", ClientId: "idm", ClientSecret: "wrong"})
+
+		_, err := connector.CreateUser(ctx, john)
+
+		var responseErr ResponseError
+		assert.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusUnauthorized, responseErr.Status)
+	})
+}
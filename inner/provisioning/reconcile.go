@@ -0,0 +1,173 @@
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Reconcile - сверка сотрудников и их ролей с учётными записями внешней системы. Учётная
+// запись сотрудника ищется по external_id из статуса, затем по логину без учёта регистра.
+// ORPHAN - только учётные записи, созданные или связанные через IDM, то есть указанные
+// в статусе синхронизации: технические и заведённые во внешней системе вручную не трогаются.
+// В режиме Apply учётные записи ORPHAN отключаются, а сотрудники с расхождениями получают
+// в статусе фактическое состояние внешней системы и ставятся в очередь синхронизации
+func (svc *Service) Reconcile(ctx context.Context, request ReconcileRequest) (Report, error) {
+	entities, err := svc.repo.FindEmployees(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("Error finding employees: %w", err)
+	}
+	assigned, err := svc.repo.FindAssignedRoles(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("Error finding assigned roles: %w", err)
+	}
+	managed, err := svc.repo.FindAllRoleNames(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("Error finding roles: %w", err)
+	}
+	statuses, err := svc.repo.FindStatuses(ctx, "")
+	if err != nil {
+		return Report{}, fmt.Errorf("Error finding provisioning statuses: %w", err)
+	}
+	accounts, err := svc.connector.ListUsers(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("Error listing users: %w", err)
+	}
+	var roles = make(map[int64][]string)
+	for _, r := range assigned {
+		roles[r.EmployeeId] = append(roles[r.EmployeeId], r.Name)
+	}
+	var linked = make(map[int64]Status, len(statuses))
+	var owned = make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		linked[s.EmployeeId] = s
+		if s.ExternalId.Valid {
+			owned[s.ExternalId.String] = true
+		}
+	}
+	var byId = make(map[string]Account, len(accounts))
+	var byLogin = make(map[string]Account, len(accounts))
+	for _, account := range accounts {
+		byId[account.ExternalId] = account
+		byLogin[strings.ToLower(account.Login)] = account
+	}
+	var report = Report{Apply: request.Apply, Employees: len(entities), Accounts: len(accounts), Findings: []Finding{}}
+	var matched = make(map[string]bool)
+	for _, entity := range entities {
+		if entity.Login.String == "" {
+			continue
+		}
+		var status = linked[entity.Id]
+		account, ok := byId[status.ExternalId.String]
+		if !ok {
+			account, ok = byLogin[strings.ToLower(entity.Login.String)]
+		}
+		if ok {
+			matched[account.ExternalId] = true
+		}
+		var actual []string
+		if ok && entity.Active {
+			actual, err = svc.connector.FindUserRoles(ctx, account.ExternalId)
+			if err != nil {
+				return Report{}, fmt.Errorf("Error finding roles of user %s: %w", account.Login, err)
+			}
+			actual = slices.DeleteFunc(actual, func(role string) bool { return !slices.Contains(managed, role) })
+		}
+		var finding *Finding
+		switch {
+		case !entity.Active:
+			if ok && account.Enabled && owned[account.ExternalId] {
+				finding = &Finding{Kind: FindingOrphan, ExternalId: account.ExternalId}
+			}
+		case !ok || !account.Enabled:
+			finding = &Finding{Kind: FindingMissing, ExternalId: account.ExternalId}
+		default:
+			var missing = difference(roles[entity.Id], actual)
+			var extra = difference(actual, roles[entity.Id])
+			if len(missing) > 0 || len(extra) > 0 {
+				finding = &Finding{Kind: FindingRoleMismatch, ExternalId: account.ExternalId,
+					MissingRoles: missing, ExtraRoles: extra}
+			}
+		}
+		if finding == nil {
+			continue
+		}
+		finding.EmployeeId = entity.Id
+		finding.Login = entity.Login.String
+		if request.Apply {
+			if finding.Kind == FindingOrphan {
+				svc.disableOrphan(ctx, finding)
+			} else {
+				svc.requeue(ctx, finding, status, account, ok, actual)
+			}
+		}
+		report.Findings = append(report.Findings, *finding)
+	}
+	for _, account := range accounts {
+		if matched[account.ExternalId] || !account.Enabled || !owned[account.ExternalId] {
+			continue
+		}
+		var finding = Finding{Kind: FindingOrphan, Login: account.Login, ExternalId: account.ExternalId}
+		if request.Apply {
+			svc.disableOrphan(ctx, &finding)
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	svc.logger.DebugCtx(ctx, "Reconcile: finished", zap.Bool("apply", request.Apply),
+		zap.Int("employees", report.Employees), zap.Int("accounts", report.Accounts),
+		zap.Int("findings", len(report.Findings)))
+	return report, nil
+}
+
+func (svc *Service) disableOrphan(ctx context.Context, finding *Finding) {
+	if err := svc.connector.DisableUser(ctx, finding.ExternalId); err != nil {
+		svc.logger.ErrorCtx(ctx, "Reconcile: error disabling orphan account",
+			zap.String("external_id", finding.ExternalId), zap.Error(err))
+		finding.Error = err.Error()
+		return
+	}
+	finding.Applied = true
+}
+
+// requeue - статус сотрудника приводится к фактическому состоянию учётной записи, чтобы
+// синхронизация выдала недостающие и отозвала лишние роли, после чего сотрудник ставится
+// в очередь. Без учётной записи статус не меняется: синхронизация создаст её заново
+func (svc *Service) requeue(ctx context.Context, finding *Finding, status Status, account Account, found bool,
+	roles []string) {
+	if found {
+		status.EmployeeId = finding.EmployeeId
+		status.ExternalId = sql.NullString{String: account.ExternalId, Valid: true}
+		status.Login = sql.NullString{String: account.Login, Valid: true}
+		status.Roles = pq.StringArray(roles)
+		if status.Roles == nil {
+			status.Roles = pq.StringArray{}
+		}
+		if status.State == "" {
+			status.State = StatePending
+		}
+		if err := svc.repo.SaveStatus(ctx, status); err != nil {
+			svc.logger.ErrorCtx(ctx, "Reconcile: error saving provisioning status",
+				zap.Int64("employee_id", finding.EmployeeId), zap.Error(err))
+			finding.Error = err.Error()
+			return
+		}
+	}
+	svc.Provision(ctx, finding.EmployeeId)
+	finding.Applied = true
+}
+
+// difference - элементы a, которых нет в b
+func difference(a, b []string) []string {
+	var rsl []string
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			rsl = append(rsl, s)
+		}
+	}
+	return rsl
+}
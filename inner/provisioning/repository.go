@@ -0,0 +1,93 @@
+package provisioning
+
+import (
+	"context"
+	"idm/inner/employee"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindEmployee(ctx context.Context, id int64) (entity employee.Entity, err error) {
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM employee WHERE id = $1", id)
+	return entity, err
+}
+
+// FindRoleNames - имена ролей действующих назначений сотрудника
+func (r *Repository) FindRoleNames(ctx context.Context, employeeId int64) (names []string, err error) {
+	err = r.db.SelectContext(ctx, &names,
+		`SELECT r.name
+		 FROM employee_role er
+		 JOIN role r ON r.id = er.role_id
+		 WHERE er.employee_id = $1
+		   AND er.valid_from <= NOW() AND (er.valid_until IS NULL OR er.valid_until > NOW())
+		 ORDER BY r.name`,
+		employeeId)
+	return names, err
+}
+
+func (r *Repository) FindEmployees(ctx context.Context) (entities []employee.Entity, err error) {
+	err = r.db.SelectContext(ctx, &entities, "SELECT * FROM employee ORDER BY id")
+	return entities, err
+}
+
+// FindAssignedRoles - роли действующих назначений всех сотрудников
+func (r *Repository) FindAssignedRoles(ctx context.Context) (roles []AssignedRole, err error) {
+	err = r.db.SelectContext(ctx, &roles,
+		`SELECT er.employee_id, r.name
+		 FROM employee_role er
+		 JOIN role r ON r.id = er.role_id
+		 WHERE er.valid_from <= NOW() AND (er.valid_until IS NULL OR er.valid_until > NOW())
+		 ORDER BY er.employee_id, r.name`)
+	return roles, err
+}
+
+// FindAllRoleNames - имена всех ролей IDM
+func (r *Repository) FindAllRoleNames(ctx context.Context) (names []string, err error) {
+	err = r.db.SelectContext(ctx, &names, "SELECT name FROM role ORDER BY name")
+	return names, err
+}
+
+func (r *Repository) FindStatus(ctx context.Context, employeeId int64) (status Status, err error) {
+	err = r.db.GetContext(ctx, &status, "SELECT * FROM provisioning_status WHERE employee_id = $1", employeeId)
+	return status, err
+}
+
+// FindStatuses - статусы в состоянии state, пустое состояние - все
+func (r *Repository) FindStatuses(ctx context.Context, state string) (statuses []Status, err error) {
+	err = r.db.SelectContext(ctx, &statuses,
+		"SELECT * FROM provisioning_status WHERE $1 = '' OR state = $1 ORDER BY employee_id", state)
+	return statuses, err
+}
+
+func (r *Repository) SaveStatus(ctx context.Context, status Status) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO provisioning_status (employee_id, external_id, login, roles, state, attempts, last_error, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		 ON CONFLICT (employee_id) DO UPDATE
+		 SET external_id = EXCLUDED.external_id, login = EXCLUDED.login, roles = EXCLUDED.roles,
+		     state = EXCLUDED.state, attempts = EXCLUDED.attempts, last_error = EXCLUDED.last_error,
+		     updated_at = NOW()`,
+		status.EmployeeId, status.ExternalId, status.Login, status.Roles, status.State, status.Attempts, status.LastError)
+	return err
+}
+
+// MarkPending - отмечает сотрудников поставленными в очередь синхронизации. Статус создаётся
+// для сотрудников, которые ещё не синхронизировались; учётная запись и роли в нём не меняются
+func (r *Repository) MarkPending(ctx context.Context, employeeIds []int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO provisioning_status (employee_id, state, updated_at)
+		 SELECT id, 'PENDING', NOW() FROM unnest($1::BIGINT[]) AS id
+		 ON CONFLICT (employee_id) DO UPDATE
+		 SET state = 'PENDING', updated_at = NOW()`,
+		pq.Array(employeeIds))
+	return err
+}
@@ -0,0 +1,289 @@
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"slices"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Service - синхронизация сотрудников с внешней системой через Connector. Сервисы IDM
+// после фиксации своих транзакций передают в Provision идентификаторы изменённых сотрудников,
+// worker по одному приводит их учётные записи и роли к состоянию в базе. Очередь хранится
+// в памяти, а в базе поставленные в неё сотрудники отмечаются статусом PENDING, по которому
+// очередь восстанавливается после перезапуска
+type Service struct {
+	repo      Repo
+	connector Connector
+	options   Options
+	validator *validator.Validate
+	logger    common.LoggerInterface
+
+	mu      sync.Mutex
+	pending []int64
+	queued  map[int64]bool
+	wake    chan struct{}
+}
+
+type Repo interface {
+	FindEmployee(ctx context.Context, id int64) (employee.Entity, error)
+	FindRoleNames(ctx context.Context, employeeId int64) ([]string, error)
+	FindEmployees(ctx context.Context) ([]employee.Entity, error)
+	FindAssignedRoles(ctx context.Context) ([]AssignedRole, error)
+	FindAllRoleNames(ctx context.Context) ([]string, error)
+	FindStatus(ctx context.Context, employeeId int64) (Status, error)
+	FindStatuses(ctx context.Context, state string) ([]Status, error)
+	SaveStatus(ctx context.Context, status Status) error
+	MarkPending(ctx context.Context, employeeIds []int64) error
+}
+
+func NewService(repo Repo, connector Connector, options Options, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		connector: connector,
+		options:   options,
+		validator: validator.New(),
+		logger:    logger,
+		queued:    make(map[int64]bool),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Provision - отмечает сотрудников статусом PENDING, ставит их в очередь синхронизации
+// и возвращается, не дожидаясь её. Сотрудник, уже ожидающий в очереди, повторно не добавляется:
+// синхронизация читает актуальное состояние. Ошибка сохранения статуса только пишется в лог -
+// сотрудник всё равно синхронизируется, если экземпляр не перезапустится раньше
+func (svc *Service) Provision(ctx context.Context, employeeIds ...int64) {
+	employeeIds = slices.DeleteFunc(slices.Clone(employeeIds), func(id int64) bool { return id <= 0 })
+	if len(employeeIds) == 0 {
+		return
+	}
+	if err := svc.repo.MarkPending(ctx, employeeIds); err != nil {
+		svc.logger.ErrorCtx(ctx, "Provision: error saving pending status",
+			zap.Int64s("employee_ids", employeeIds), zap.Error(err))
+	}
+	svc.enqueue(employeeIds)
+	svc.logger.DebugCtx(ctx, "Provision: employees queued", zap.Int64s("employee_ids", employeeIds))
+}
+
+// Resume - ставит в очередь сотрудников, ожидавших синхронизации в статусе PENDING до
+// перезапуска, в том числе ждавших повтора после неудачной попытки
+func (svc *Service) Resume(ctx context.Context) (int, error) {
+	statuses, err := svc.repo.FindStatuses(ctx, StatePending)
+	if err != nil {
+		return 0, fmt.Errorf("Error finding pending provisioning statuses: %w", err)
+	}
+	var employeeIds = make([]int64, 0, len(statuses))
+	for _, s := range statuses {
+		employeeIds = append(employeeIds, s.EmployeeId)
+	}
+	svc.enqueue(employeeIds)
+	return len(employeeIds), nil
+}
+
+func (svc *Service) enqueue(employeeIds []int64) {
+	svc.mu.Lock()
+	for _, id := range employeeIds {
+		if !svc.queued[id] {
+			svc.queued[id] = true
+			svc.pending = append(svc.pending, id)
+		}
+	}
+	svc.mu.Unlock()
+	select {
+	case svc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Next - следующий сотрудник из очереди; ждёт, пока очередь пуста. false - ctx отменён
+func (svc *Service) Next(ctx context.Context) (int64, bool) {
+	for {
+		svc.mu.Lock()
+		if len(svc.pending) > 0 {
+			var id = svc.pending[0]
+			svc.pending = svc.pending[1:]
+			delete(svc.queued, id)
+			svc.mu.Unlock()
+			return id, true
+		}
+		svc.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case <-svc.wake:
+		}
+	}
+}
+
+// Sync - одна попытка синхронизации сотрудника, attempt - её номер. Результат и частично
+// выполненные изменения сохраняются в статусе; после MaxAttempts неудач статус FAILED
+func (svc *Service) Sync(ctx context.Context, employeeId int64, attempt int) error {
+	status, err := svc.repo.FindStatus(ctx, employeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		status = Status{EmployeeId: employeeId}
+	} else if err != nil {
+		return fmt.Errorf("Error finding provisioning status of employee %d: %w", employeeId, err)
+	}
+	if status.Roles == nil {
+		status.Roles = pq.StringArray{}
+	}
+	err = svc.sync(ctx, &status)
+	if err != nil {
+		status.State = StatePending
+		if attempt >= svc.options.MaxAttempts {
+			status.State = StateFailed
+		}
+		status.Attempts = attempt
+		status.LastError = sql.NullString{String: err.Error(), Valid: true}
+	} else {
+		status.Attempts = 0
+		status.LastError = sql.NullString{}
+	}
+	if errSave := svc.repo.SaveStatus(ctx, status); errSave != nil {
+		return errors.Join(err, fmt.Errorf("Error saving provisioning status of employee %d: %w", employeeId, errSave))
+	}
+	return err
+}
+
+func (svc *Service) sync(ctx context.Context, status *Status) error {
+	entity, err := svc.repo.FindEmployee(ctx, status.EmployeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return svc.disable(ctx, status)
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding employee %d: %w", status.EmployeeId, err)
+	}
+	if entity.Login.String == "" {
+		if status.ExternalId.Valid {
+			return svc.disable(ctx, status)
+		}
+		status.State = StateSkipped
+		return nil
+	}
+	roles, err := svc.repo.FindRoleNames(ctx, entity.Id)
+	if err != nil {
+		return fmt.Errorf("Error finding roles of employee %d: %w", entity.Id, err)
+	}
+	var user = User{
+		Login:     entity.Login.String,
+		FirstName: entity.Name,
+		LastName:  entity.Surname,
+		Email:     entity.Email,
+		Enabled:   entity.Active,
+	}
+	if err = svc.upsertUser(ctx, status, user); err != nil {
+		return err
+	}
+	if err = svc.syncRoles(ctx, status, roles); err != nil {
+		return err
+	}
+	status.State = StateSynced
+	return nil
+}
+
+// upsertUser - пользователь, удалённый во внешней системе вручную, создаётся заново
+func (svc *Service) upsertUser(ctx context.Context, status *Status, user User) error {
+	if status.ExternalId.Valid {
+		var err = svc.connector.UpdateUser(ctx, status.ExternalId.String, user)
+		if err == nil {
+			status.Login = sql.NullString{String: user.Login, Valid: true}
+			return nil
+		}
+		if !errors.As(err, &common.NotFoundError{}) {
+			return fmt.Errorf("Error updating user %s: %w", user.Login, err)
+		}
+		status.ExternalId = sql.NullString{}
+		status.Roles = pq.StringArray{}
+	}
+	externalId, err := svc.connector.CreateUser(ctx, user)
+	if err != nil {
+		return fmt.Errorf("Error creating user %s: %w", user.Login, err)
+	}
+	status.ExternalId = sql.NullString{String: externalId, Valid: true}
+	status.Login = sql.NullString{String: user.Login, Valid: true}
+	return nil
+}
+
+// syncRoles - выдаёт недостающие роли и отзывает выданные ранее через IDM, но уже не назначенные
+func (svc *Service) syncRoles(ctx context.Context, status *Status, roles []string) error {
+	for _, role := range slices.Clone(status.Roles) {
+		if slices.Contains(roles, role) {
+			continue
+		}
+		if err := svc.connector.RevokeRole(ctx, status.ExternalId.String, role); err != nil {
+			return fmt.Errorf("Error revoking role %s from user %s: %w", role, status.Login.String, err)
+		}
+		status.Roles = slices.DeleteFunc(status.Roles, func(r string) bool { return r == role })
+	}
+	for _, role := range roles {
+		if slices.Contains(status.Roles, role) {
+			continue
+		}
+		if err := svc.connector.GrantRole(ctx, status.ExternalId.String, role); err != nil {
+			return fmt.Errorf("Error granting role %s to user %s: %w", role, status.Login.String, err)
+		}
+		status.Roles = append(status.Roles, role)
+		slices.Sort(status.Roles)
+	}
+	return nil
+}
+
+// disable - отключает учётную запись удалённого сотрудника или сотрудника без логина
+func (svc *Service) disable(ctx context.Context, status *Status) error {
+	if status.ExternalId.Valid {
+		if err := svc.connector.DisableUser(ctx, status.ExternalId.String); err != nil {
+			return fmt.Errorf("Error disabling user %s: %w", status.Login.String, err)
+		}
+	}
+	status.State = StateDisabled
+	return nil
+}
+
+func (svc *Service) FindStatus(ctx context.Context, employeeId int64) (StatusResponse, error) {
+	if employeeId <= 0 {
+		return StatusResponse{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id: %d", employeeId)}
+	}
+	status, err := svc.repo.FindStatus(ctx, employeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StatusResponse{}, common.NotFoundError{
+			Message: fmt.Sprintf("Employee %d has not been provisioned", employeeId),
+		}
+	}
+	if err != nil {
+		return StatusResponse{}, fmt.Errorf("Error finding provisioning status of employee %d: %w", employeeId, err)
+	}
+	return status.ToResponse(), nil
+}
+
+func (svc *Service) FindStatuses(ctx context.Context, request StatusRequest) ([]StatusResponse, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	statuses, err := svc.repo.FindStatuses(ctx, request.State)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding provisioning statuses: %w", err)
+	}
+	var responses = make([]StatusResponse, 0, len(statuses))
+	for _, s := range statuses {
+		responses = append(responses, s.ToResponse())
+	}
+	return responses, nil
+}
+
+// Retry - ставит сотрудника в очередь вне зависимости от его статуса
+func (svc *Service) Retry(ctx context.Context, employeeId int64) error {
+	if employeeId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id: %d", employeeId)}
+	}
+	svc.Provision(ctx, employeeId)
+	return nil
+}
@@ -0,0 +1,480 @@
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindEmployee(ctx context.Context, id int64) (employee.Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindRoleNames(ctx context.Context, employeeId int64) ([]string, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepo) FindEmployees(ctx context.Context) ([]employee.Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]employee.Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAssignedRoles(ctx context.Context) ([]AssignedRole, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]AssignedRole), args.Error(1)
+}
+
+func (m *MockRepo) FindAllRoleNames(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepo) FindStatus(ctx context.Context, employeeId int64) (Status, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).(Status), args.Error(1)
+}
+
+func (m *MockRepo) FindStatuses(ctx context.Context, state string) ([]Status, error) {
+	args := m.Called(ctx, state)
+	return args.Get(0).([]Status), args.Error(1)
+}
+
+func (m *MockRepo) SaveStatus(ctx context.Context, status Status) error {
+	args := m.Called(ctx, status)
+	return args.Error(0)
+}
+
+func (m *MockRepo) MarkPending(ctx context.Context, employeeIds []int64) error {
+	args := m.Called(ctx, employeeIds)
+	return args.Error(0)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+var testOptions = Options{MaxAttempts: 3, RetryInterval: time.Millisecond}
+
+// expectSave - сохраняет переданный в SaveStatus статус в saved
+func expectSave(repo *MockRepo, saved *Status) {
+	repo.On("SaveStatus", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*saved = args.Get(1).(Status)
+	}).Return(nil)
+}
+
+func johnDoe() employee.Entity {
+	return employee.Entity{
+		Id:      7,
+		Name:    "John",
+		Surname: "Doe",
+		Login:   sql.NullString{String: "jdoe", Valid: true},
+		Email:   "john@corp.example",
+		Active:  true,
+	}
+}
+
+func externalId(id string) sql.NullString {
+	return sql.NullString{String: id, Valid: true}
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should create user and grant assigned roles", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{}, sql.ErrNoRows)
+		repo.On("FindEmployee", ctx, int64(7)).Return(johnDoe(), nil)
+		repo.On("FindRoleNames", ctx, int64(7)).Return([]string{"AUDIT", "LEDGER"}, nil)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.NoError(svc.Sync(ctx, 7, 1))
+
+		a.Equal(StateSynced, saved.State)
+		a.Equal(externalId("kc-1"), saved.ExternalId)
+		a.Equal("jdoe", saved.Login.String)
+		a.Equal(pq.StringArray{"AUDIT", "LEDGER"}, saved.Roles)
+		a.Equal(keycloakUser{Id: "kc-1", Username: "jdoe", FirstName: "John", LastName: "Doe",
+			Email: "john@corp.example", Enabled: true}, kc.users["kc-1"])
+		a.Equal([]string{"AUDIT", "LEDGER"}, kc.mappings["kc-1"])
+	})
+
+	t.Run("Should revoke only roles granted through idm", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		kc.users["kc-1"] = keycloakUser{Id: "kc-1", Username: "jdoe", Enabled: true}
+		kc.roles = map[string]keycloakRole{"AUDIT": {Id: "r1", Name: "AUDIT"}, "LEDGER": {Id: "r2", Name: "LEDGER"},
+			"offline_access": {Id: "r3", Name: "offline_access"}}
+		kc.mappings["kc-1"] = []string{"AUDIT", "LEDGER", "offline_access"}
+		repo := new(MockRepo)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{EmployeeId: 7, ExternalId: externalId("kc-1"),
+			Roles: pq.StringArray{"AUDIT", "LEDGER"}, State: StateSynced}, nil)
+		repo.On("FindEmployee", ctx, int64(7)).Return(johnDoe(), nil)
+		repo.On("FindRoleNames", ctx, int64(7)).Return([]string{"LEDGER", "PAYROLL"}, nil)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.NoError(svc.Sync(ctx, 7, 1))
+
+		a.Equal(pq.StringArray{"LEDGER", "PAYROLL"}, saved.Roles)
+		a.Equal([]string{"LEDGER", "PAYROLL", "offline_access"}, kc.mappings["kc-1"])
+	})
+
+	t.Run("Should recreate user deleted in keycloak", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{EmployeeId: 7, ExternalId: externalId("kc-gone"),
+			Roles: pq.StringArray{"LEDGER"}, State: StateSynced}, nil)
+		repo.On("FindEmployee", ctx, int64(7)).Return(johnDoe(), nil)
+		repo.On("FindRoleNames", ctx, int64(7)).Return([]string{"LEDGER"}, nil)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.NoError(svc.Sync(ctx, 7, 1))
+
+		a.Equal(externalId("kc-1"), saved.ExternalId)
+		a.Equal([]string{"LEDGER"}, kc.mappings["kc-1"])
+	})
+
+	t.Run("Should disable account of deleted employee", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		kc.users["kc-1"] = keycloakUser{Id: "kc-1", Username: "jdoe", Enabled: true}
+		repo := new(MockRepo)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{EmployeeId: 7, ExternalId: externalId("kc-1"),
+			Login: sql.NullString{String: "jdoe", Valid: true}, State: StateSynced}, nil)
+		repo.On("FindEmployee", ctx, int64(7)).Return(employee.Entity{}, sql.ErrNoRows)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.NoError(svc.Sync(ctx, 7, 1))
+
+		a.Equal(StateDisabled, saved.State)
+		a.False(kc.users["kc-1"].Enabled)
+	})
+
+	t.Run("Should skip employee without login", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		var entity = johnDoe()
+		entity.Login = sql.NullString{}
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{}, sql.ErrNoRows)
+		repo.On("FindEmployee", ctx, int64(7)).Return(entity, nil)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.NoError(svc.Sync(ctx, 7, 1))
+
+		a.Equal(StateSkipped, saved.State)
+		a.Equal(pq.StringArray{}, saved.Roles)
+	})
+
+	tests := []struct {
+		name    string
+		attempt int
+		state   string
+	}{
+		{name: "Should keep pending while attempts remain", attempt: 1, state: StatePending},
+		{name: "Should fail after the last attempt", attempt: 3, state: StateFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			kc := newFakeKeycloak(t)
+			kc.fail = http.StatusServiceUnavailable
+			repo := new(MockRepo)
+			svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+			repo.On("FindStatus", ctx, int64(7)).Return(Status{}, sql.ErrNoRows)
+			repo.On("FindEmployee", ctx, int64(7)).Return(johnDoe(), nil)
+			repo.On("FindRoleNames", ctx, int64(7)).Return([]string{}, nil)
+			var saved Status
+			expectSave(repo, &saved)
+
+			err := svc.Sync(ctx, 7, tt.attempt)
+
+			var responseErr ResponseError
+			a.True(errors.As(err, &responseErr))
+			a.Equal(tt.state, saved.State)
+			a.Equal(tt.attempt, saved.Attempts)
+			a.Contains(saved.LastError.String, "status 503")
+		})
+	}
+
+	t.Run("Should keep progress made before failure", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc := newFakeKeycloak(t)
+		kc.rejectRole = "PAYROLL"
+		repo := new(MockRepo)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{}, sql.ErrNoRows)
+		repo.On("FindEmployee", ctx, int64(7)).Return(johnDoe(), nil)
+		repo.On("FindRoleNames", ctx, int64(7)).Return([]string{"LEDGER", "PAYROLL"}, nil)
+		var saved Status
+		expectSave(repo, &saved)
+
+		a.Error(svc.Sync(ctx, 7, 1))
+
+		a.Equal(StatePending, saved.State)
+		a.Equal(externalId("kc-1"), saved.ExternalId)
+		a.Equal(pq.StringArray{"LEDGER"}, saved.Roles)
+	})
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Should deduplicate queued employees and keep order", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		repo.On("MarkPending", mock.Anything, mock.Anything).Return(nil)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		svc.Provision(ctx, 3, 1, 3, 0)
+		svc.Provision(ctx, 1, 2)
+
+		var ids []int64
+		for range 3 {
+			id, ok := svc.Next(ctx)
+			a.True(ok)
+			ids = append(ids, id)
+		}
+		a.Equal([]int64{3, 1, 2}, ids)
+		svc.Provision(ctx, 3)
+		id, ok := svc.Next(ctx)
+		a.True(ok)
+		a.Equal(int64(3), id)
+	})
+
+	t.Run("Should wait for employees until cancelled", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		repo.On("MarkPending", mock.Anything, mock.Anything).Return(nil)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, func() { svc.Provision(ctx, 5) })
+
+		id, ok := svc.Next(ctx)
+		a.True(ok)
+		a.Equal(int64(5), id)
+
+		cancel()
+		_, ok = svc.Next(ctx)
+		a.False(ok)
+	})
+
+	t.Run("Should persist pending status of queued employees", func(t *testing.T) {
+		a := assert.New(t)
+		ctx := context.Background()
+		repo := new(MockRepo)
+		repo.On("MarkPending", ctx, []int64{3, 1}).Return(nil)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+
+		svc.Provision(ctx, 3, 0, 1)
+		svc.Provision(ctx, 0)
+
+		repo.AssertExpectations(t)
+		repo.AssertNumberOfCalls(t, "MarkPending", 1)
+		a.Equal([]int64{3, 1}, svc.pending)
+	})
+
+	t.Run("Should queue employees even if pending status is not saved", func(t *testing.T) {
+		a := assert.New(t)
+		ctx := context.Background()
+		repo := new(MockRepo)
+		repo.On("MarkPending", ctx, []int64{4}).Return(errors.New("connection refused"))
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+
+		svc.Provision(ctx, 4)
+
+		a.Equal([]int64{4}, svc.pending)
+	})
+
+	t.Run("Should resume employees pending before restart", func(t *testing.T) {
+		a := assert.New(t)
+		ctx := context.Background()
+		repo := new(MockRepo)
+		repo.On("FindStatuses", ctx, StatePending).Return([]Status{{EmployeeId: 5}, {EmployeeId: 2}}, nil)
+		repo.On("MarkPending", ctx, []int64{2}).Return(nil)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		svc.Provision(ctx, 2)
+
+		resumed, err := svc.Resume(ctx)
+
+		a.NoError(err)
+		a.Equal(2, resumed)
+		a.Equal([]int64{2, 5}, svc.pending)
+	})
+}
+
+func TestFindStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return status", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{EmployeeId: 7, ExternalId: externalId("kc-1"),
+			State: StateFailed, Attempts: 3, LastError: sql.NullString{String: "timeout", Valid: true}}, nil)
+
+		got, err := svc.FindStatus(ctx, 7)
+
+		a.NoError(err)
+		a.Equal(StatusResponse{EmployeeId: 7, ExternalId: "kc-1", Roles: []string{}, State: StateFailed,
+			Attempts: 3, LastError: "timeout"}, got)
+	})
+
+	t.Run("Should return not found for employee never provisioned", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockRepo)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+		repo.On("FindStatus", ctx, int64(7)).Return(Status{}, sql.ErrNoRows)
+
+		_, err := svc.FindStatus(ctx, 7)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+
+	t.Run("Should reject unknown state filter", func(t *testing.T) {
+		t.Parallel()
+		repo := new(MockRepo)
+		svc := NewService(repo, nil, testOptions, &MockLogger{})
+
+		_, err := svc.FindStatuses(ctx, StatusRequest{State: "DONE"})
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		repo.AssertNotCalled(t, "FindStatuses", mock.Anything, mock.Anything)
+	})
+}
+
+// reconcileFixture - jdoe с лишней и недостающей ролью, asmith без учётной записи, уволенный
+// bgone с включённой учётной записью, cok, переименованный в Keycloak, учётная запись удалённого
+// сотрудника и учётные записи, заведённые в Keycloak вне IDM
+func reconcileFixture(t *testing.T) (*fakeKeycloak, *MockRepo) {
+	ctx := context.Background()
+	kc := newFakeKeycloak(t)
+	kc.users = map[string]keycloakUser{
+		"kc-1": {Id: "kc-1", Username: "jdoe", Enabled: true},
+		"kc-2": {Id: "kc-2", Username: "bgone", Enabled: true},
+		"kc-3": {Id: "kc-3", Username: "c.ok", Enabled: true},
+		"kc-4": {Id: "kc-4", Username: "admin", Enabled: true},
+		"kc-5": {Id: "kc-5", Username: "old", Enabled: false},
+		"kc-6": {Id: "kc-6", Username: "left", Enabled: true},
+	}
+	kc.mappings = map[string][]string{"kc-1": {"AUDIT", "LEDGER", "offline_access"}, "kc-3": {"AUDIT"}}
+	var login = func(l string) sql.NullString { return sql.NullString{String: l, Valid: true} }
+	repo := new(MockRepo)
+	repo.On("FindEmployees", ctx).Return([]employee.Entity{
+		johnDoe(),
+		{Id: 8, Login: login("asmith"), Active: true},
+		{Id: 9, Login: login("bgone"), Active: false},
+		{Id: 10, Active: true},
+		{Id: 11, Login: login("cok"), Active: true},
+	}, nil)
+	repo.On("FindAssignedRoles", ctx).Return([]AssignedRole{
+		{EmployeeId: 7, Name: "LEDGER"}, {EmployeeId: 7, Name: "PAYROLL"}, {EmployeeId: 11, Name: "AUDIT"},
+	}, nil)
+	repo.On("FindAllRoleNames", ctx).Return([]string{"AUDIT", "LEDGER", "PAYROLL"}, nil)
+	repo.On("FindStatuses", ctx, "").Return([]Status{
+		{EmployeeId: 9, ExternalId: externalId("kc-2"), State: StateSynced},
+		{EmployeeId: 11, ExternalId: externalId("kc-3"), Roles: pq.StringArray{"AUDIT"}, State: StateSynced},
+		{EmployeeId: 12, ExternalId: externalId("kc-6"), State: StateSynced},
+	}, nil)
+	repo.On("MarkPending", ctx, mock.Anything).Return(nil)
+	return kc, repo
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should report drift without changing keycloak", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc, repo := reconcileFixture(t)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+
+		report, err := svc.Reconcile(ctx, ReconcileRequest{})
+
+		a.NoError(err)
+		a.Equal(Report{Employees: 5, Accounts: 6, Findings: []Finding{
+			{Kind: FindingRoleMismatch, EmployeeId: 7, Login: "jdoe", ExternalId: "kc-1",
+				MissingRoles: []string{"PAYROLL"}, ExtraRoles: []string{"AUDIT"}},
+			{Kind: FindingMissing, EmployeeId: 8, Login: "asmith"},
+			{Kind: FindingOrphan, EmployeeId: 9, Login: "bgone", ExternalId: "kc-2"},
+			{Kind: FindingOrphan, Login: "left", ExternalId: "kc-6"},
+		}}, report)
+		a.True(kc.users["kc-2"].Enabled)
+		a.True(kc.users["kc-6"].Enabled)
+		repo.AssertNotCalled(t, "SaveStatus", mock.Anything, mock.Anything)
+		a.Empty(svc.pending)
+	})
+
+	t.Run("Should disable orphans and queue employees in apply mode", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		kc, repo := reconcileFixture(t)
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+		var saved Status
+		expectSave(repo, &saved)
+
+		report, err := svc.Reconcile(ctx, ReconcileRequest{Apply: true})
+
+		a.NoError(err)
+		a.Len(report.Findings, 4)
+		for _, f := range report.Findings {
+			a.True(f.Applied, f.Kind)
+		}
+		a.False(kc.users["kc-2"].Enabled)
+		a.False(kc.users["kc-6"].Enabled)
+		a.True(kc.users["kc-4"].Enabled, "account created outside idm must not be disabled")
+		a.True(kc.users["kc-1"].Enabled)
+		a.Equal(Status{EmployeeId: 7, ExternalId: externalId("kc-1"), Login: sql.NullString{String: "jdoe", Valid: true},
+			Roles: pq.StringArray{"AUDIT", "LEDGER"}, State: StatePending}, saved)
+		repo.AssertNumberOfCalls(t, "SaveStatus", 1)
+		a.Equal([]int64{7, 8}, svc.pending)
+	})
+
+	t.Run("Should fail when keycloak is unavailable", func(t *testing.T) {
+		t.Parallel()
+		kc, repo := reconcileFixture(t)
+		kc.fail = http.StatusServiceUnavailable
+		svc := NewService(repo, kc.connector(), testOptions, &MockLogger{})
+
+		_, err := svc.Reconcile(ctx, ReconcileRequest{Apply: true})
+
+		assert.Error(t, err)
+		repo.AssertNotCalled(t, "SaveStatus", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,158 @@
+package provisioning
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Syncer interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+	Resume(ctx context.Context) (int, error)
+	Next(ctx context.Context) (int64, bool)
+	Sync(ctx context.Context, employeeId int64, attempt int) error
+}
+
+// Worker - фоновый процесс, синхронизирующий сотрудников из очереди по одному. При старте
+// он возвращает в очередь сотрудников, не синхронизированных до перезапуска. Неудачная
+// попытка возвращает сотрудника в очередь через RetryInterval * 2^(n-1) и не задерживает
+// остальных; после MaxAttempts неудач сотрудник ждёт следующего изменения или повтора вручную
+type Worker struct {
+	syncer   Syncer
+	options  Options
+	logger   *common.Logger
+	attempts map[int64]int
+	runner   common.Runner
+}
+
+func NewWorker(syncer Syncer, options Options, logger *common.Logger) *Worker {
+	return &Worker{
+		syncer:   syncer,
+		options:  options,
+		logger:   logger,
+		attempts: make(map[int64]int),
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *Worker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей синхронизации
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	if resumed, err := w.syncer.Resume(ctx); err != nil {
+		w.logger.Error("Worker: error resuming pending provisioning", zap.Error(err))
+	} else if resumed > 0 {
+		w.logger.Info("Worker: pending provisioning resumed", zap.Int("employees", resumed))
+	}
+	for {
+		id, ok := w.syncer.Next(ctx)
+		if !ok {
+			return
+		}
+		w.sync(ctx, id)
+	}
+}
+
+func (w *Worker) sync(ctx context.Context, employeeId int64) {
+	var attempt = w.attempts[employeeId] + 1
+	var err = w.syncer.Sync(ctx, employeeId, attempt)
+	if err == nil {
+		delete(w.attempts, employeeId)
+		return
+	}
+	if attempt >= w.options.MaxAttempts {
+		delete(w.attempts, employeeId)
+		w.logger.Error("Worker: provisioning failed, attempts exhausted",
+			zap.Int64("employee_id", employeeId), zap.Int("attempt", attempt), zap.Error(err))
+		return
+	}
+	w.attempts[employeeId] = attempt
+	var delay = w.options.RetryInterval << (attempt - 1)
+	w.logger.Info("Worker: provisioning failed, retry scheduled",
+		zap.Int64("employee_id", employeeId), zap.Int("attempt", attempt), zap.Duration("delay", delay),
+		zap.Error(err))
+	time.AfterFunc(delay, func() {
+		if ctx.Err() == nil {
+			w.syncer.Provision(ctx, employeeId)
+		}
+	})
+}
+
+type Reconciler interface {
+	Reconcile(ctx context.Context, request ReconcileRequest) (Report, error)
+}
+
+// ReconcileWorker - фоновый процесс, периодически сверяющий сотрудников с внешней системой.
+// Расхождения пишутся в лог, с apply - ещё и исправляются
+type ReconcileWorker struct {
+	reconciler Reconciler
+	interval   time.Duration
+	apply      bool
+	logger     *common.Logger
+	runner     common.Runner
+}
+
+func NewReconcileWorker(reconciler Reconciler, interval time.Duration, apply bool, logger *common.Logger) *ReconcileWorker {
+	return &ReconcileWorker{
+		reconciler: reconciler,
+		interval:   interval,
+		apply:      apply,
+		logger:     logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *ReconcileWorker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей сверки
+func (w *ReconcileWorker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+// run - первая сверка через interval после старта, чтобы не нагружать Keycloak при перезапусках
+func (w *ReconcileWorker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w.reconcile(ctx)
+	}
+}
+
+func (w *ReconcileWorker) reconcile(ctx context.Context) {
+	report, err := w.reconciler.Reconcile(ctx, ReconcileRequest{Apply: w.apply})
+	if err != nil {
+		w.logger.Error("ReconcileWorker: error reconciling employees", zap.Error(err))
+		return
+	}
+	for _, f := range report.Findings {
+		w.logger.Warn("ReconcileWorker: drift found",
+			zap.String("kind", f.Kind),
+			zap.Int64("employee_id", f.EmployeeId),
+			zap.String("login", f.Login),
+			zap.String("external_id", f.ExternalId),
+			zap.Strings("missing_roles", f.MissingRoles),
+			zap.Strings("extra_roles", f.ExtraRoles),
+			zap.Bool("applied", f.Applied),
+			zap.String("error", f.Error))
+	}
+	w.logger.Info("ReconcileWorker: reconciliation finished",
+		zap.Bool("apply", report.Apply),
+		zap.Int("employees", report.Employees),
+		zap.Int("accounts", report.Accounts),
+		zap.Int("findings", len(report.Findings)))
+}
@@ -0,0 +1,106 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// StubSyncer - очередь в памяти; Sync неуспешен, пока не исчерпаны failures сотрудника
+type StubSyncer struct {
+	mu       sync.Mutex
+	queue    chan int64
+	failures map[int64]int
+	attempts map[int64][]int
+	resumed  []int64
+}
+
+func newStubSyncer(failures map[int64]int) *StubSyncer {
+	return &StubSyncer{queue: make(chan int64, 16), failures: failures, attempts: map[int64][]int{}}
+}
+
+func (s *StubSyncer) Provision(ctx context.Context, employeeIds ...int64) {
+	for _, id := range employeeIds {
+		s.queue <- id
+	}
+}
+
+func (s *StubSyncer) Resume(ctx context.Context) (int, error) {
+	s.Provision(ctx, s.resumed...)
+	return len(s.resumed), nil
+}
+
+func (s *StubSyncer) Next(ctx context.Context) (int64, bool) {
+	select {
+	case id := <-s.queue:
+		return id, true
+	case <-ctx.Done():
+		return 0, false
+	}
+}
+
+func (s *StubSyncer) Sync(ctx context.Context, employeeId int64, attempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[employeeId] = append(s.attempts[employeeId], attempt)
+	if s.failures[employeeId] > 0 {
+		s.failures[employeeId]--
+		return errors.New("keycloak is unavailable")
+	}
+	return nil
+}
+
+func (s *StubSyncer) attemptsOf(employeeId int64) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.attempts[employeeId]...)
+}
+
+func TestWorker(t *testing.T) {
+	t.Run("Should retry failed employee without blocking others", func(t *testing.T) {
+		a := assert.New(t)
+		syncer := newStubSyncer(map[int64]int{1: 2, 2: 5})
+		worker := NewWorker(syncer, Options{MaxAttempts: 3, RetryInterval: 50 * time.Millisecond},
+			&common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		syncer.Provision(context.Background(), 1, 2, 3)
+
+		a.Eventually(func() bool { return len(syncer.attemptsOf(3)) == 1 }, time.Second, time.Millisecond)
+		a.Len(syncer.attemptsOf(1), 1)
+		a.Eventually(func() bool {
+			return len(syncer.attemptsOf(1)) == 3 && len(syncer.attemptsOf(2)) == 3
+		}, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.NoError(worker.Stop(ctx))
+
+		a.Equal([]int{1, 2, 3}, syncer.attemptsOf(1))
+		a.Equal([]int{1, 2, 3}, syncer.attemptsOf(2))
+		time.Sleep(100 * time.Millisecond)
+		a.Len(syncer.attemptsOf(2), 3)
+	})
+
+	t.Run("Should sync employees pending before restart", func(t *testing.T) {
+		a := assert.New(t)
+		syncer := newStubSyncer(map[int64]int{})
+		syncer.resumed = []int64{4, 5}
+		worker := NewWorker(syncer, Options{MaxAttempts: 3, RetryInterval: time.Millisecond},
+			&common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+
+		a.Eventually(func() bool {
+			return len(syncer.attemptsOf(4)) == 1 && len(syncer.attemptsOf(5)) == 1
+		}, time.Second, time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.NoError(worker.Stop(ctx))
+	})
+}
@@ -1,26 +1,158 @@
 package role
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Уровни риска роли
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
 
 type Entity struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id          int64         `db:"id"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	OwnerId     sql.NullInt64 `db:"owner_id"`
+	RiskLevel   string        `db:"risk_level"`
+	Requestable bool          `db:"requestable"`
+	// MaxAssignmentDays - максимальный срок назначения роли в днях, NULL - бессрочно
+	MaxAssignmentDays sql.NullInt32 `db:"max_assignment_days"`
+	CreatedAt         time.Time     `db:"created_at"`
+	UpdatedAt         time.Time     `db:"updated_at"`
 }
 
 func (e *Entity) ToResponse() Response {
-	return Response{
-		Id:        e.Id,
-		Name:      e.Name,
-		CreatedAt: e.CreatedAt,
-		UpdatedAt: e.UpdatedAt,
+	var response = Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		RiskLevel:   e.RiskLevel,
+		Requestable: e.Requestable,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+	if e.OwnerId.Valid {
+		var ownerId = e.OwnerId.Int64
+		response.OwnerId = &ownerId
+	}
+	if e.MaxAssignmentDays.Valid {
+		var days = e.MaxAssignmentDays.Int32
+		response.MaxAssignmentDays = &days
+	}
+	return response
+}
+
+// ToRequest - запрос на изменение роли с её текущими метаданными
+func (e *Entity) ToRequest() Request {
+	var requestable = e.Requestable
+	var request = Request{
+		Name:        e.Name,
+		Description: e.Description,
+		RiskLevel:   e.RiskLevel,
+		Requestable: &requestable,
+	}
+	if e.OwnerId.Valid {
+		var ownerId = e.OwnerId.Int64
+		request.OwnerId = &ownerId
 	}
+	if e.MaxAssignmentDays.Valid {
+		var days = e.MaxAssignmentDays.Int32
+		request.MaxAssignmentDays = &days
+	}
+	return request
+}
+
+// IsHighRisk - роль высокого риска, её выдача требует согласования администратором
+func (e *Entity) IsHighRisk() bool {
+	return e.RiskLevel == RiskHigh
 }
 
 type Response struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id                int64     `db:"id"`
+	Name              string    `db:"name"`
+	Description       string    `db:"description"`
+	OwnerId           *int64    `db:"owner_id"`
+	RiskLevel         string    `db:"risk_level"`
+	Requestable       bool      `db:"requestable"`
+	MaxAssignmentDays *int32    `db:"max_assignment_days"`
+	CreatedAt         time.Time `db:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at"`
+}
+
+// Request - создание или изменение роли. Без risk_level роль получает низкий риск,
+// без requestable - доступна для запроса
+type Request struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	OwnerId           *int64 `json:"owner_id"`
+	RiskLevel         string `json:"risk_level"`
+	Requestable       *bool  `json:"requestable"`
+	MaxAssignmentDays *int32 `json:"max_assignment_days"`
+}
+
+func (req *Request) ToEntity() Entity {
+	var entity = Entity{
+		Name:        req.Name,
+		Description: req.Description,
+		RiskLevel:   req.RiskLevel,
+		Requestable: true,
+	}
+	if entity.RiskLevel == "" {
+		entity.RiskLevel = RiskLow
+	}
+	if req.Requestable != nil {
+		entity.Requestable = *req.Requestable
+	}
+	if req.OwnerId != nil {
+		entity.OwnerId = sql.NullInt64{Int64: *req.OwnerId, Valid: true}
+	}
+	if req.MaxAssignmentDays != nil {
+		entity.MaxAssignmentDays = sql.NullInt32{Int32: *req.MaxAssignmentDays, Valid: true}
+	}
+	return entity
+}
+
+// CompositeEntity - связь составной роли с входящей в неё ролью
+type CompositeEntity struct {
+	ParentRoleId int64     `db:"parent_role_id"`
+	ChildRoleId  int64     `db:"child_role_id"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type CompositeRequest struct {
+	ChildRoleId int64 `json:"child_role_id" validate:"required,min=1"`
+}
+
+// CompositeEvent - данные событий RoleCompositeAdded и RoleCompositeRemoved
+type CompositeEvent struct {
+	ParentRoleId int64 `json:"parent_role_id"`
+	ChildRoleId  int64 `json:"child_role_id"`
+}
+
+// HolderEntity - прямое назначение роли сотруднику
+type HolderEntity struct {
+	EmployeeId int64 `db:"employee_id"`
+	RoleId     int64 `db:"role_id"`
+}
+
+// EffectiveRoleEntity - роль сотрудника и путь по иерархии, через который она получена
+type EffectiveRoleEntity struct {
+	Id   int64          `db:"id"`
+	Name string         `db:"name"`
+	Path pq.StringArray `db:"path"`
+}
+
+// EffectiveRoleResponse - действующая роль сотрудника. Direct - роль назначена напрямую,
+// Paths - все цепочки ролей от назначенной до данной
+type EffectiveRoleResponse struct {
+	Id     int64      `json:"id"`
+	Name   string     `json:"name"`
+	Direct bool       `json:"direct"`
+	Paths  [][]string `json:"paths"`
 }
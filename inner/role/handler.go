@@ -1,7 +1,9 @@
 package role
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"idm/inner/common"
 	"idm/inner/web"
 	"strconv"
@@ -20,9 +22,14 @@ type Svc interface {
 	Add(role Entity) (Response, error)
 	FindById(id int64) (Response, error)
 	FindByIds(ids []int64) ([]Response, error)
-	DeleteByIds(ids []int64) ([]Response, error)
-	DeleteById(id int64) (Response, error)
+	DeleteByIds(ctx context.Context, actor string, ids []int64) ([]Response, error)
+	DeleteById(ctx context.Context, actor string, id int64) (Response, error)
 	FindAll() (roles []Entity, err error)
+	AddComposite(ctx context.Context, parentId int64, request CompositeRequest) error
+	DeleteComposite(ctx context.Context, parentId, childId int64) error
+	FindComposites(ctx context.Context, parentId int64) ([]Response, error)
+	FindEffectiveRoles(ctx context.Context, employeeId int64) ([]EffectiveRoleResponse, error)
+	Update(ctx context.Context, id int64, request Request) (Response, error)
 }
 
 func NewHandler(server *web.Server, roleService Svc, logger *common.Logger) *Handler {
@@ -38,28 +45,77 @@ func (c *Handler) RegisterRouters() {
 	c.server.GroupApiV1.Post("/roles/ids", c.FindByIds)
 	c.server.GroupApiV1.Post("/roles/:id", c.FindById)
 	c.server.GroupApiV1.Delete("/roles/ids", c.DeleteByIds)
+	c.server.GroupApiV1.Put("/roles/:id", c.Update)
 	c.server.GroupApiV1.Delete("/roles/:id", c.DeleteById)
 	c.server.GroupApiV1.Get("/roles", c.FindAll)
+	c.server.GroupApiV1.Get("/roles/:id/composites", c.FindComposites)
+	c.server.GroupApiV1.Post("/roles/:id/composites", c.AddComposite)
+	c.server.GroupApiV1.Delete("/roles/:id/composites/:childId", c.DeleteComposite)
+	c.server.GroupApiV1.Get("/employees/:id/effective-roles", c.FindEffectiveRoles)
 }
 
 func (c *Handler) AddRoles(ctx *fiber.Ctx) error {
-	var entity Entity
-	if err := ctx.BodyParser(&entity); err != nil {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	var request Request
+	if err := ctx.BodyParser(&request); err != nil {
 		c.logger.Error("AddRoles: invalid request body", zap.Error(err))
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
 	c.logger.Debug("AddRoles: receive entity", zap.Any("entity", ctx.Body()))
-	var newRoleId, err = c.service.Add(entity)
+	var newRoleId, err = c.service.Add(request.ToEntity())
 	if err != nil {
 		c.logger.Error("AddRoles: error adding role", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return errResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, newRoleId)
 }
 
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/roles/:id"
+// @Description Update role name and metadata: description, owner, risk level, requestable flag and max assignment duration.
+// @Summary update role
+// @Tags role
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param request body Request true "role"
+// @Success 200 {object} common.Response[role.Response]
+// @Failure 400 {object} common.Response[role.Response] "invalid request"
+// @Failure 403 {object} common.Response[role.Response] "Permission denied"
+// @Failure 404 {object} common.Response[role.Response] "role not found"
+// @Failure 500 {object} common.Response[role.Response] "error db"
+// @Router /roles/{id} [put]
+// @Security BearerAuth
+func (c *Handler) Update(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("Update: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request Request
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Update: invalid request body", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	c.logger.Debug("Update: receive request", zap.Int64("id", id), zap.Any("request", request))
+	role, err := c.service.Update(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.Error("Update: error updating role", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, role)
+}
+
 func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
 	idParam := ctx.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
@@ -76,6 +132,9 @@ func (c *Handler) FindById(ctx *fiber.Ctx) error {
 }
 
 func (c *Handler) FindByIds(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
 	var ids []int64
 	if err := ctx.BodyParser(&ids); err != nil {
 		c.logger.Error("FindByIds: invalid request body", zap.Error(err))
@@ -91,6 +150,9 @@ func (c *Handler) FindByIds(ctx *fiber.Ctx) error {
 }
 
 func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
 	idParam := ctx.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
@@ -98,7 +160,8 @@ func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	c.logger.Debug("DeleteById: receive id", zap.Any("id", idParam))
-	rsl, err := c.service.DeleteById(id)
+	claims, _ := web.ClaimsFromCtx(ctx)
+	rsl, err := c.service.DeleteById(ctx.Context(), claims.Actor(), id)
 	if err != nil {
 		c.logger.Error("DeleteById: error deleting role", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -107,6 +170,9 @@ func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
 }
 
 func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
 	bodyBytes := ctx.Body()
 	var ids []int64
 	if err := json.Unmarshal(bodyBytes, &ids); err != nil {
@@ -114,7 +180,8 @@ func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 	c.logger.Debug("DeleteByIds: receive ids", zap.Any("ids", ids))
-	rsl, err := c.service.DeleteByIds(ids)
+	claims, _ := web.ClaimsFromCtx(ctx)
+	rsl, err := c.service.DeleteByIds(ctx.Context(), claims.Actor(), ids)
 	if err != nil {
 		c.logger.Error("DeleteByIds: error deleting roles", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -123,6 +190,9 @@ func (c *Handler) DeleteByIds(ctx *fiber.Ctx) error {
 }
 
 func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
 	roles, err := c.service.FindAll()
 	if err != nil {
 		c.logger.Error("FindAll: error finding roles", zap.Error(err))
@@ -130,3 +200,143 @@ func (c *Handler) FindAll(ctx *fiber.Ctx) error {
 	}
 	return common.OkResponse(ctx, roles)
 }
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/roles/:id/composites"
+// @Description Add role into composite role.
+// @Summary add composite role member
+// @Tags role
+// @Accept json
+// @Produce json
+// @Param id path int true "Composite role ID"
+// @Param request body CompositeRequest true "child role"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request or cycle"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /roles/{id}/composites [post]
+// @Security BearerAuth
+func (c *Handler) AddComposite(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	parentId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("AddComposite: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request CompositeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("AddComposite: invalid request body", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	c.logger.Debug("AddComposite: receive request", zap.Int64("parentId", parentId), zap.Any("request", request))
+	if err := c.service.AddComposite(ctx.Context(), parentId, request); err != nil {
+		c.logger.Error("AddComposite: error adding composite role", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/roles/:id/composites/:childId"
+// @Description Remove role from composite role.
+// @Summary remove composite role member
+// @Tags role
+// @Produce json
+// @Param id path int true "Composite role ID"
+// @Param childId path int true "Child role ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 404 {object} common.Response[any] "role is not a part of composite role"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /roles/{id}/composites/{childId} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteComposite(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleWrite); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	parentId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("DeleteComposite: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	childId, err := strconv.ParseInt(ctx.Params("childId"), 10, 64)
+	if err != nil {
+		c.logger.Error("DeleteComposite: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteComposite(ctx.Context(), parentId, childId); err != nil {
+		c.logger.Error("DeleteComposite: error deleting composite role", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/composites"
+// @Description Find roles directly included into composite role.
+// @Summary find composite role members
+// @Tags role
+// @Produce json
+// @Param id path int true "Composite role ID"
+// @Success 200 {object} common.Response[[]role.Response]
+// @Failure 400 {object} common.Response[[]role.Response] "invalid request"
+// @Failure 403 {object} common.Response[[]role.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]role.Response] "error db"
+// @Router /roles/{id}/composites [get]
+// @Security BearerAuth
+func (c *Handler) FindComposites(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	parentId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("FindComposites: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	roles, err := c.service.FindComposites(ctx.Context(), parentId)
+	if err != nil {
+		c.logger.Error("FindComposites: error finding composite roles", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, roles)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/effective-roles"
+// @Description Find effective roles of employee with expanded composite roles and the path granting each role.
+// @Summary find effective roles
+// @Tags role
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} common.Response[[]role.EffectiveRoleResponse]
+// @Failure 400 {object} common.Response[[]role.EffectiveRoleResponse] "invalid request"
+// @Failure 403 {object} common.Response[[]role.EffectiveRoleResponse] "Permission denied"
+// @Failure 500 {object} common.Response[[]role.EffectiveRoleResponse] "error db"
+// @Router /employees/{id}/effective-roles [get]
+// @Security BearerAuth
+func (c *Handler) FindEffectiveRoles(ctx *fiber.Ctx) error {
+	if ok, err := c.server.HasPermission(ctx, web.PermRoleRead); !ok {
+		return web.DenyResponse(ctx, err)
+	}
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("FindEffectiveRoles: invalid request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	roles, err := c.service.FindEffectiveRoles(ctx.Context(), employeeId)
+	if err != nil {
+		c.logger.Error("FindEffectiveRoles: error finding effective roles", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, roles)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
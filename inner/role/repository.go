@@ -1,7 +1,10 @@
 package role
 
 import (
+	"context"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Repository struct {
@@ -16,16 +19,21 @@ func NewRepository(database *sqlx.DB) *Repository {
 	return &Repository{db: database}
 }
 
-func (r *Repository) Add(role Entity) (id int64, err error) {
-	query := `INSERT INTO role(name, created_at, updated_at)
-      	 	  VALUES (:name, :created_at, :updated_at)
+func (r *Repository) Add(tx *sqlx.Tx, role Entity) (id int64, err error) {
+	query := `INSERT INTO role(name, description, owner_id, risk_level, requestable, max_assignment_days,
+      	 	                 created_at, updated_at)
+      	 	  VALUES (:name, :description, :owner_id, COALESCE(NULLIF(:risk_level, ''), 'low'), :requestable,
+      	 	          :max_assignment_days, :created_at, :updated_at)
       	 	  RETURNING id`
-	rows, err := r.db.NamedQuery(query, &role)
-
-	if err == nil && rows.Next() && rows.Scan(&id) == nil {
-		return id, nil
+	query, args, err := tx.BindNamed(query, &role)
+	if err != nil {
+		return -1, err
+	}
+	err = tx.Get(&id, query, args...)
+	if err != nil {
+		return -1, err
 	}
-	return -1, err
+	return id, nil
 }
 
 func (r *Repository) FindById(id int64) (role Entity, err error) {
@@ -33,11 +41,122 @@ func (r *Repository) FindById(id int64) (role Entity, err error) {
 	return role, err
 }
 
+// Update - изменяет роль и возвращает её новое состояние
+func (r *Repository) Update(ctx context.Context, tx *sqlx.Tx, role Entity) (updated Entity, err error) {
+	query := `UPDATE role
+			  SET name = :name, description = :description, owner_id = :owner_id, risk_level = :risk_level,
+			      requestable = :requestable, max_assignment_days = :max_assignment_days, updated_at = NOW()
+			  WHERE id = :id
+			  RETURNING *`
+	query, args, err := tx.BindNamed(query, &role)
+	if err != nil {
+		return Entity{}, err
+	}
+	err = tx.GetContext(ctx, &updated, query, args...)
+	return updated, err
+}
+
+// ExistsEmployee - существует ли сотрудник, назначаемый владельцем роли
+func (r *Repository) ExistsEmployee(id int64) (exists bool, err error) {
+	err = r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM employee WHERE id = $1)", id)
+	return exists, err
+}
+
 func (r *Repository) FindAll() (roles []Entity, err error) {
 	err = r.db.Select(&roles, "SELECT * FROM role")
 	return roles, err
 }
 
+// FindByEmployeeId - роли, назначенные сотруднику и действующие на текущий момент
+func (r *Repository) FindByEmployeeId(ctx context.Context, employeeId int64) (roles []Entity, err error) {
+	err = r.db.SelectContext(ctx, &roles,
+		`SELECT r.* FROM role r
+		 JOIN employee_role er ON er.role_id = r.id
+		 WHERE er.employee_id = $1
+		   AND er.valid_from <= NOW()
+		   AND (er.valid_until IS NULL OR er.valid_until > NOW())
+		 ORDER BY r.name`,
+		employeeId)
+	return roles, err
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// AddComposite - добавляет дочернюю роль в составную. Таблица блокируется до конца транзакции,
+// чтобы параллельные вставки не могли обойти проверку на цикл
+func (r *Repository) AddComposite(tx *sqlx.Tx, parentId, childId int64) error {
+	if _, err := tx.Exec("LOCK TABLE role_composite IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		`INSERT INTO role_composite(parent_role_id, child_role_id) VALUES ($1, $2)
+		 ON CONFLICT DO NOTHING`,
+		parentId, childId)
+	return err
+}
+
+// IsReachable - есть ли в иерархии путь от роли from к роли to
+func (r *Repository) IsReachable(tx *sqlx.Tx, from, to int64) (isReachable bool, err error) {
+	err = tx.Get(&isReachable,
+		`WITH RECURSIVE descendants AS (
+			SELECT child_role_id AS id FROM role_composite WHERE parent_role_id = $1
+			UNION
+			SELECT rc.child_role_id FROM role_composite rc
+			JOIN descendants d ON rc.parent_role_id = d.id
+		)
+		SELECT EXISTS(SELECT 1 FROM descendants WHERE id = $2)`,
+		from, to)
+	return isReachable, err
+}
+
+func (r *Repository) DeleteComposite(ctx context.Context, tx *sqlx.Tx, parentId, childId int64) (bool, error) {
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM role_composite WHERE parent_role_id = $1 AND child_role_id = $2",
+		parentId, childId)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindComposites - роли, непосредственно входящие в составную роль
+func (r *Repository) FindComposites(ctx context.Context, parentId int64) (roles []Entity, err error) {
+	err = r.db.SelectContext(ctx, &roles,
+		`SELECT r.* FROM role r
+		 JOIN role_composite rc ON rc.child_role_id = r.id
+		 WHERE rc.parent_role_id = $1
+		 ORDER BY r.name`,
+		parentId)
+	return roles, err
+}
+
+// FindEffectiveByEmployeeId - все роли сотрудника с раскрытой иерархией составных ролей.
+// Для каждой роли возвращается по строке на каждый путь от действующего назначения
+func (r *Repository) FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) (roles []EffectiveRoleEntity, err error) {
+	err = r.db.SelectContext(ctx, &roles,
+		`WITH RECURSIVE effective AS (
+			SELECT r.id, r.name, ARRAY[r.id] AS path_ids, ARRAY[r.name] AS path
+			FROM employee_role er
+			JOIN role r ON r.id = er.role_id
+			WHERE er.employee_id = $1
+			  AND er.valid_from <= NOW()
+			  AND (er.valid_until IS NULL OR er.valid_until > NOW())
+			UNION ALL
+			SELECT c.id, c.name, e.path_ids || c.id, e.path || c.name
+			FROM effective e
+			JOIN role_composite rc ON rc.parent_role_id = e.id
+			JOIN role c ON c.id = rc.child_role_id
+			WHERE NOT c.id = ANY(e.path_ids)
+		)
+		SELECT id, name, path FROM effective
+		ORDER BY name, array_length(path, 1), path`,
+		employeeId)
+	return roles, err
+}
+
 func (r *Repository) FindBySliceIds(ids []int64) (roles []Entity, err error) {
 	query, args, err := sqlx.In("SELECT * FROM role WHERE id IN (?)", ids)
 	if err != nil {
@@ -48,8 +167,8 @@ func (r *Repository) FindBySliceIds(ids []int64) (roles []Entity, err error) {
 	return roles, err
 }
 
-func (r *Repository) DeleteById(id int64) (bool, error) {
-	result, err := r.db.Exec("DELETE FROM role WHERE id = $1", id)
+func (r *Repository) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.Exec("DELETE FROM role WHERE id = $1", id)
 	if err != nil {
 		return false, err
 	}
@@ -57,18 +176,26 @@ func (r *Repository) DeleteById(id int64) (bool, error) {
 	return rowInter > 0, err
 }
 
-func (r *Repository) DeleteBySliceIds(ids []int64) ([]int64, error) {
+// FindHolders - назначения ролей roleIds; строки блокируются до конца транзакции удаления ролей
+func (r *Repository) FindHolders(tx *sqlx.Tx, roleIds []int64) (holders []HolderEntity, err error) {
+	err = tx.Select(&holders, `SELECT employee_id, role_id FROM employee_role
+		WHERE role_id = ANY($1) ORDER BY employee_id, role_id FOR UPDATE`, pq.Array(roleIds))
+	return holders, err
+}
+
+func (r *Repository) DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error) {
 
 	query, args, err := sqlx.In("DELETE FROM role WHERE id IN (?) RETURNING id", ids)
 	if err != nil {
 		return nil, err
 	}
 
-	query = r.db.Rebind(query)
-	rows, err := r.db.Queryx(query, args...)
+	query = tx.Rebind(query)
+	rows, err := tx.Queryx(query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer func() { _ = rows.Close() }()
 
 	var deletedIDs []int64
 	for rows.Next() {
@@ -1,18 +1,61 @@
 package role
 
-import "fmt"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
 
 type Service struct {
 	repo Repo
+	// Events - запись доменных событий в outbox в транзакции изменения; nil - события не пишутся
+	Events Outbox
+	// Revoker - отзыв удаляемой роли у сотрудников в транзакции удаления, с аудитом и событием
+	// RoleRevoked по каждому сотруднику; nil - назначения удаляются каскадно без отзыва
+	Revoker Revoker
+	// Provisioning - передача сотрудников, лишившихся удалённой роли, во внешние системы после
+	// фиксации транзакции; nil - не передаются
+	Provisioning Provisioner
+}
+
+// Revoker - отзыв роли в транзакции удаления роли
+type Revoker interface {
+	RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+// Outbox - хранилище доменных событий, публикуемых после фиксации транзакции
+type Outbox interface {
+	Add(tx *sqlx.Tx, event outbox.Entity) error
 }
 
 type Repo interface {
-	Add(role Entity) (id int64, err error)
+	Add(tx *sqlx.Tx, role Entity) (id int64, err error)
 	FindById(id int64) (role Entity, err error)
 	FindAll() (roles []Entity, err error)
 	FindBySliceIds(ids []int64) (roles []Entity, err error)
-	DeleteById(id int64) (bool, error)
-	DeleteBySliceIds(ids []int64) ([]int64, error)
+	DeleteById(tx *sqlx.Tx, id int64) (bool, error)
+	DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error)
+	FindHolders(tx *sqlx.Tx, roleIds []int64) ([]HolderEntity, error)
+	BeginTr() (*sqlx.Tx, error)
+	AddComposite(tx *sqlx.Tx, parentId, childId int64) error
+	IsReachable(tx *sqlx.Tx, from, to int64) (bool, error)
+	DeleteComposite(ctx context.Context, tx *sqlx.Tx, parentId, childId int64) (bool, error)
+	FindComposites(ctx context.Context, parentId int64) ([]Entity, error)
+	FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) ([]EffectiveRoleEntity, error)
+	Update(ctx context.Context, tx *sqlx.Tx, role Entity) (Entity, error)
+	ExistsEmployee(id int64) (bool, error)
 }
 
 func NewService(
@@ -38,15 +81,78 @@ func (svc *Service) Add(role Entity) (Response, error) {
 	if role == (Entity{}) || role.Name == "" {
 		return Response{Name: role.Name}, fmt.Errorf("Invalid field, please check the role")
 	}
-	var rsl, err = svc.repo.Add(role)
+	if err := svc.validateMetadata(role); err != nil {
+		return Response{}, err
+	}
+	var err = common.InTx(svc.repo, "adding role", func(tx *sqlx.Tx) error {
+		var rsl, err = svc.repo.Add(tx, role)
+		if err != nil {
+			return fmt.Errorf("Error adding role %+v: %w", role, err)
+		}
+		role.Id = rsl
+		return svc.event(tx, outbox.RoleCreated, rsl, role.ToResponse())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return role.ToResponse(), nil
+}
+
+// Update - изменяет название и метаданные роли
+func (svc *Service) Update(ctx context.Context, id int64, request Request) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id role: %d", id)}
+	}
+	var entity = request.ToEntity()
+	entity.Id = id
+	if entity.Name == "" {
+		return Response{}, common.RequestValidationError{Message: "Role name is required"}
+	}
+	if err := svc.validateMetadata(entity); err != nil {
+		return Response{}, err
+	}
+	var response Response
+	var err = common.InTx(svc.repo, "updating role", func(tx *sqlx.Tx) error {
+		updated, err := svc.repo.Update(ctx, tx, entity)
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("Role %d not found", id)}
+		}
+		if err != nil {
+			return fmt.Errorf("Error updating role %d: %w", id, err)
+		}
+		response = updated.ToResponse()
+		return svc.event(tx, outbox.RoleUpdated, id, response)
+	})
 	if err != nil {
-		return Response{}, fmt.Errorf("Error adding role %+v: %w", role, err)
+		return Response{}, err
 	}
-	return Response{
-		Id:        rsl,
-		Name:      role.Name,
-		CreatedAt: role.CreatedAt,
-		UpdatedAt: role.UpdatedAt}, nil
+	return response, nil
+}
+
+// validateMetadata - проверяет уровень риска, срок назначения и существование владельца роли.
+// Пустой уровень риска допустим: при сохранении роль получает низкий риск
+func (svc *Service) validateMetadata(role Entity) error {
+	switch role.RiskLevel {
+	case "", RiskLow, RiskMedium, RiskHigh:
+	default:
+		return common.RequestValidationError{
+			Message: fmt.Sprintf("Wrong risk level %q, expected one of: low, medium, high", role.RiskLevel),
+		}
+	}
+	if role.MaxAssignmentDays.Valid && role.MaxAssignmentDays.Int32 <= 0 {
+		return common.RequestValidationError{Message: "max_assignment_days must be positive"}
+	}
+	if !role.OwnerId.Valid {
+		return nil
+	}
+	exists, err := svc.repo.ExistsEmployee(role.OwnerId.Int64)
+	if err != nil {
+		return fmt.Errorf("Error finding role owner %d: %w", role.OwnerId.Int64, err)
+	}
+	if !exists {
+		return common.RequestValidationError{Message: fmt.Sprintf("Owner employee %d not found", role.OwnerId.Int64)}
+	}
+	return nil
 }
 
 func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
@@ -65,14 +171,32 @@ func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
 	return responses, nil
 }
 
-func (svc *Service) DeleteByIds(ids []int64) ([]Response, error) {
+// DeleteByIds - удаляет роли; сотрудники, которым они были назначены, теряют их в той же транзакции
+func (svc *Service) DeleteByIds(ctx context.Context, actor string, ids []int64) ([]Response, error) {
 	if len(ids) == 0 {
 		return []Response{}, fmt.Errorf("No roles ids provided")
 	}
-	rsl, err := svc.repo.DeleteBySliceIds(ids)
+	var rsl, employeeIds []int64
+	var err = common.InTx(svc.repo, "deleting roles", func(tx *sqlx.Tx) (err error) {
+		employeeIds, err = svc.revokeHolders(ctx, tx, actor, ids)
+		if err != nil {
+			return err
+		}
+		rsl, err = svc.repo.DeleteBySliceIds(tx, ids)
+		if err != nil {
+			return fmt.Errorf("Error deleting roles by ids %+v: %w", ids, err)
+		}
+		for _, id := range rsl {
+			if err = svc.event(tx, outbox.RoleDeleted, id, outbox.Deleted{Id: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return []Response{}, fmt.Errorf("Error deleting roles by ids %+v: %w", ids, err)
+		return []Response{}, err
 	}
+	svc.provision(ctx, employeeIds...)
 	responses := make([]Response, 0, len(rsl))
 	for _, id := range rsl {
 		responses = append(responses, Response{Id: id})
@@ -80,17 +204,157 @@ func (svc *Service) DeleteByIds(ids []int64) ([]Response, error) {
 	return responses, nil
 }
 
-func (svc *Service) DeleteById(id int64) (Response, error) {
+func (svc *Service) DeleteById(ctx context.Context, actor string, id int64) (Response, error) {
 	if id <= 0 {
 		return Response{}, fmt.Errorf("Wrong id: %d", id)
 	}
-	var rsl, err = svc.repo.DeleteById(id)
-	if err != nil || !rsl {
-		return Response{}, fmt.Errorf("Error deleting role with id %d: %w", id, err)
+	var employeeIds []int64
+	var err = common.InTx(svc.repo, "deleting role", func(tx *sqlx.Tx) (err error) {
+		employeeIds, err = svc.revokeHolders(ctx, tx, actor, []int64{id})
+		if err != nil {
+			return err
+		}
+		rsl, err := svc.repo.DeleteById(tx, id)
+		if err != nil || !rsl {
+			return fmt.Errorf("Error deleting role with id %d: %w", id, err)
+		}
+		return svc.event(tx, outbox.RoleDeleted, id, outbox.Deleted{Id: id})
+	})
+	if err != nil {
+		return Response{}, err
 	}
+	svc.provision(ctx, employeeIds...)
 	return Response{Id: id}, nil
 }
 
+// revokeHolders - отзывает роли roleIds у сотрудников до удаления ролей: иначе назначения
+// удалятся каскадно, без аудита и событий. Возвращает сотрудников, лишившихся ролей
+func (svc *Service) revokeHolders(ctx context.Context, tx *sqlx.Tx, actor string, roleIds []int64) ([]int64, error) {
+	if svc.Revoker == nil {
+		return nil, nil
+	}
+	holders, err := svc.repo.FindHolders(tx, roleIds)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding employees with roles %+v: %w", roleIds, err)
+	}
+	var employeeIds = make([]int64, 0, len(holders))
+	for _, h := range holders {
+		var request = assignment.RevokeRequest{EmployeeId: h.EmployeeId, RoleId: h.RoleId}
+		if err = svc.Revoker.RevokeInTx(ctx, tx, actor, request); err != nil {
+			return nil, fmt.Errorf("Error revoking role %d from employee %d: %w", h.RoleId, h.EmployeeId, err)
+		}
+		if !slices.Contains(employeeIds, h.EmployeeId) {
+			employeeIds = append(employeeIds, h.EmployeeId)
+		}
+	}
+	return employeeIds, nil
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil && len(employeeIds) > 0 {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
 func (svc *Service) FindAll() (roles []Entity, err error) {
 	return svc.repo.FindAll()
 }
+
+// AddComposite - включает роль request.ChildRoleId в составную роль parentId.
+// Отклоняет связь, если она замкнёт цикл в иерархии ролей
+func (svc *Service) AddComposite(ctx context.Context, parentId int64, request CompositeRequest) error {
+	if parentId <= 0 || request.ChildRoleId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong role ids: %d, %d", parentId, request.ChildRoleId)}
+	}
+	if parentId == request.ChildRoleId {
+		return common.RequestValidationError{Message: "Role cannot contain itself"}
+	}
+	return common.InTx(svc.repo, "adding composite role", func(tx *sqlx.Tx) error {
+		if err := svc.repo.AddComposite(tx, parentId, request.ChildRoleId); err != nil {
+			return fmt.Errorf("Error adding role %d to composite role %d: %w", request.ChildRoleId, parentId, err)
+		}
+		isCycle, err := svc.repo.IsReachable(tx, request.ChildRoleId, parentId)
+		if err != nil {
+			return fmt.Errorf("Error checking role hierarchy for cycles: %w", err)
+		}
+		if isCycle {
+			return common.RequestValidationError{
+				Message: fmt.Sprintf("Adding role %d to role %d creates a cycle", request.ChildRoleId, parentId),
+			}
+		}
+		return svc.event(tx, outbox.RoleCompositeAdded, parentId,
+			CompositeEvent{ParentRoleId: parentId, ChildRoleId: request.ChildRoleId})
+	})
+}
+
+func (svc *Service) DeleteComposite(ctx context.Context, parentId, childId int64) error {
+	if parentId <= 0 || childId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong role ids: %d, %d", parentId, childId)}
+	}
+	return common.InTx(svc.repo, "deleting composite role", func(tx *sqlx.Tx) error {
+		deleted, err := svc.repo.DeleteComposite(ctx, tx, parentId, childId)
+		if err != nil {
+			return fmt.Errorf("Error deleting role %d from composite role %d: %w", childId, parentId, err)
+		}
+		if !deleted {
+			return common.NotFoundError{Message: fmt.Sprintf("Role %d is not a part of role %d", childId, parentId)}
+		}
+		return svc.event(tx, outbox.RoleCompositeRemoved, parentId,
+			CompositeEvent{ParentRoleId: parentId, ChildRoleId: childId})
+	})
+}
+
+func (svc *Service) FindComposites(ctx context.Context, parentId int64) ([]Response, error) {
+	if parentId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong id role: %d", parentId)}
+	}
+	entities, err := svc.repo.FindComposites(ctx, parentId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding composites of role %d: %w", parentId, err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses, nil
+}
+
+// FindEffectiveRoles - роли сотрудника с учётом составных ролей и путей, которыми они получены
+func (svc *Service) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]EffectiveRoleResponse, error) {
+	if employeeId <= 0 {
+		return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong employee id: %d", employeeId)}
+	}
+	rows, err := svc.repo.FindEffectiveByEmployeeId(ctx, employeeId)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding effective roles of employee %d: %w", employeeId, err)
+	}
+	var responses = make([]EffectiveRoleResponse, 0)
+	var indexes = make(map[int64]int)
+	for _, row := range rows {
+		idx, ok := indexes[row.Id]
+		if !ok {
+			idx = len(responses)
+			indexes[row.Id] = idx
+			responses = append(responses, EffectiveRoleResponse{Id: row.Id, Name: row.Name, Paths: [][]string{}})
+		}
+		if len(row.Path) == 1 {
+			responses[idx].Direct = true
+		}
+		responses[idx].Paths = append(responses[idx].Paths, row.Path)
+	}
+	return responses, nil
+}
+
+func (svc *Service) event(tx *sqlx.Tx, eventType string, roleId int64, payload any) error {
+	if svc.Events == nil {
+		return nil
+	}
+	event, err := outbox.NewEvent(outbox.AggregateRole, roleId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("Error building event %s: %w", eventType, err)
+	}
+	if err = svc.Events.Add(tx, event); err != nil {
+		return fmt.Errorf("Error writing event %s: %w", eventType, err)
+	}
+	return nil
+}
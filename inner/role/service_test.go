@@ -1,10 +1,17 @@
 package role
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"idm/inner/testutil"
 	"testing"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,8 +20,8 @@ type MockRoleRepo struct {
 	mock.Mock
 }
 
-func (m *MockRoleRepo) Add(entity Entity) (int64, error) {
-	args := m.Called(entity)
+func (m *MockRoleRepo) Add(tx *sqlx.Tx, entity Entity) (int64, error) {
+	args := m.Called(tx, entity)
 	return args.Get(0).(int64), args.Error(1)
 }
 
@@ -28,21 +35,93 @@ func (m *MockRoleRepo) FindAll() ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRoleRepo) DeleteById(id int64) (bool, error) {
-	args := m.Called(id)
+func (m *MockRoleRepo) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(tx, id)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockRoleRepo) DeleteBySliceIds(ids []int64) ([]int64, error) {
-	args := m.Called(ids)
+func (m *MockRoleRepo) DeleteBySliceIds(tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	args := m.Called(tx, ids)
 	return args.Get(0).([]int64), args.Error(1)
 }
 
+func (m *MockRoleRepo) FindHolders(tx *sqlx.Tx, roleIds []int64) ([]HolderEntity, error) {
+	args := m.Called(tx, roleIds)
+	return args.Get(0).([]HolderEntity), args.Error(1)
+}
+
 func (m *MockRoleRepo) FindBySliceIds(ids []int64) ([]Entity, error) {
 	args := m.Called(ids)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
+func (m *MockRoleRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRoleRepo) AddComposite(tx *sqlx.Tx, parentId, childId int64) error {
+	args := m.Called(tx, parentId, childId)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) IsReachable(tx *sqlx.Tx, from, to int64) (bool, error) {
+	args := m.Called(tx, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepo) DeleteComposite(ctx context.Context, tx *sqlx.Tx, parentId, childId int64) (bool, error) {
+	args := m.Called(ctx, tx, parentId, childId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepo) FindComposites(ctx context.Context, parentId int64) ([]Entity, error) {
+	args := m.Called(ctx, parentId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRoleRepo) FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) ([]EffectiveRoleEntity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]EffectiveRoleEntity), args.Error(1)
+}
+
+func (m *MockRoleRepo) Update(ctx context.Context, tx *sqlx.Tx, role Entity) (Entity, error) {
+	args := m.Called(ctx, tx, role)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRoleRepo) ExistsEmployee(id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Add(tx *sqlx.Tx, event outbox.Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
+type MockRevoker struct {
+	mock.Mock
+}
+
+func (m *MockRevoker) RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Error(0)
+}
+
+type MockProvisioner struct {
+	mock.Mock
+}
+
+func (m *MockProvisioner) Provision(ctx context.Context, employeeIds ...int64) {
+	m.Called(ctx, employeeIds)
+}
+
 func TestFindByIdRole(t *testing.T) {
 	t.Run("Should return found role", func(t *testing.T) {
 		t.Parallel()
@@ -78,12 +157,12 @@ func TestFindByIdRole(t *testing.T) {
 }
 
 func TestAddRole(t *testing.T) {
-	a := assert.New(t)
-	repo := new(MockRoleRepo)
-	svc := NewService(repo)
-
 	t.Run("Should add role", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, true)
 		entity := Entity{
 			Id:        1,
 			Name:      "Admin",
@@ -99,15 +178,19 @@ func TestAddRole(t *testing.T) {
 			UpdatedAt: entity.UpdatedAt,
 		}
 
-		repo.On("Add", entity).Return(expectedId, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Add", tx, entity).Return(expectedId, nil)
 		got, err := svc.Add(entity)
 
 		a.Nil(err)
 		a.Equal(entityExpected, got)
+		a.NoError(mockTr.ExpectationsWereMet())
 	})
 
 	t.Run("Should return error if any role field is empty", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRoleRepo))
 		entity := Entity{
 			Id:        0,
 			Name:      "",
@@ -120,6 +203,104 @@ func TestAddRole(t *testing.T) {
 	})
 }
 
+func TestAddRoleMetadata(t *testing.T) {
+	t.Run("Should add role with owner", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		entity := Entity{
+			Name:              "PAYMENT_APPROVE",
+			OwnerId:           sql.NullInt64{Int64: 7, Valid: true},
+			RiskLevel:         RiskHigh,
+			Requestable:       true,
+			MaxAssignmentDays: sql.NullInt32{Int32: 90, Valid: true},
+		}
+		tx, _ := testutil.NewTx(t, true)
+		repo.On("ExistsEmployee", int64(7)).Return(true, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Add", tx, entity).Return(int64(3), nil)
+
+		got, err := svc.Add(entity)
+
+		a.NoError(err)
+		a.Equal(int64(3), got.Id)
+		a.Equal(int64(7), *got.OwnerId)
+		a.Equal(int32(90), *got.MaxAssignmentDays)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject unknown owner", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		repo.On("ExistsEmployee", int64(7)).Return(false, nil)
+
+		_, err := svc.Add(Entity{Name: "DEV", OwnerId: sql.NullInt64{Int64: 7, Valid: true}})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should reject wrong risk level and duration", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRoleRepo))
+
+		_, err := svc.Add(Entity{Name: "DEV", RiskLevel: "critical"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+
+		_, err = svc.Add(Entity{Name: "DEV", MaxAssignmentDays: sql.NullInt32{Int32: 0, Valid: true}})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestUpdateRole(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should update role with defaults", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		events := new(MockOutbox)
+		svc := NewService(repo)
+		svc.Events = events
+		tx, mockTr := testutil.NewTx(t, true)
+		requestable := false
+		entity := Entity{Id: 2, Name: "DEV", RiskLevel: RiskLow}
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Update", ctx, tx, entity).Return(entity, nil)
+		events.On("Add", tx, mock.MatchedBy(func(e outbox.Entity) bool {
+			return e.Type == outbox.RoleUpdated && e.AggregateType == outbox.AggregateRole && e.AggregateId == 2
+		})).Return(nil)
+
+		got, err := svc.Update(ctx, 2, Request{Name: "DEV", Requestable: &requestable})
+
+		a.NoError(err)
+		a.Equal(RiskLow, got.RiskLevel)
+		a.False(got.Requestable)
+		a.Nil(got.OwnerId)
+		a.NoError(mockTr.ExpectationsWereMet())
+		events.AssertExpectations(t)
+	})
+
+	t.Run("Should return not found", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("Update", ctx, tx, mock.Anything).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.Update(ctx, 2, Request{Name: "DEV"})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+}
+
 func TestFindAllRoles(t *testing.T) {
 	a := assert.New(t)
 	repo := new(MockRoleRepo)
@@ -133,31 +314,95 @@ func TestFindAllRoles(t *testing.T) {
 }
 
 func TestDeleteByIdRole(t *testing.T) {
-	a := assert.New(t)
-	repo := new(MockRoleRepo)
-	svc := NewService(repo)
+	ctx := context.Background()
+
 	t.Run("Should delete role", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(1)).Return(true, nil)
-		got, err := svc.DeleteById(1)
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
+		got, err := svc.DeleteById(ctx, "admin", 1)
 		a.Nil(err)
 		a.Equal(Response{Id: 1}, got)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should revoke role from its holders and provision them", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		revoker := new(MockRevoker)
+		provisioning := new(MockProvisioner)
+		svc := NewService(repo)
+		svc.Revoker = revoker
+		svc.Provisioning = provisioning
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindHolders", tx, []int64{1}).Return([]HolderEntity{
+			{EmployeeId: 10, RoleId: 1}, {EmployeeId: 11, RoleId: 1},
+		}, nil)
+		revoker.On("RevokeInTx", ctx, tx, "admin", assignment.RevokeRequest{EmployeeId: 10, RoleId: 1}).Return(nil)
+		revoker.On("RevokeInTx", ctx, tx, "admin", assignment.RevokeRequest{EmployeeId: 11, RoleId: 1}).Return(nil)
+		repo.On("DeleteById", tx, int64(1)).Return(true, nil)
+		provisioning.On("Provision", ctx, []int64{10, 11}).Return()
+
+		got, err := svc.DeleteById(ctx, "admin", 1)
+
+		a.NoError(err)
+		a.Equal(Response{Id: 1}, got)
+		a.NoError(mockTr.ExpectationsWereMet())
+		revoker.AssertExpectations(t)
+		provisioning.AssertExpectations(t)
+	})
+
+	t.Run("Should rollback and not provision when revoke fails", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		revoker := new(MockRevoker)
+		provisioning := new(MockProvisioner)
+		svc := NewService(repo)
+		svc.Revoker = revoker
+		svc.Provisioning = provisioning
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindHolders", tx, []int64{1}).Return([]HolderEntity{{EmployeeId: 10, RoleId: 1}}, nil)
+		revoker.On("RevokeInTx", ctx, tx, "admin", mock.Anything).Return(errors.New("audit unavailable"))
+
+		_, err := svc.DeleteById(ctx, "admin", 1)
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertNotCalled(t, "DeleteById", mock.Anything, mock.Anything)
+		provisioning.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
 	})
 
 	t.Run("Should return error if id <= 0", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(0)).Return(false, errors.New("Wrong id: 1"))
-		got, err := svc.DeleteById(0)
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		got, err := svc.DeleteById(ctx, "admin", 0)
 		a.Equal(Response{}, got)
 		a.Error(err)
+		repo.AssertNotCalled(t, "BeginTr")
 	})
 
 	t.Run("Should return error if any role field is empty", func(t *testing.T) {
 		t.Parallel()
-		repo.On("DeleteById", int64(5)).Return(false, errors.New("Error deleting role with id"))
-		got, err := svc.DeleteById(5)
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(5)).Return(false, errors.New("Error deleting role with id"))
+		got, err := svc.DeleteById(ctx, "admin", 5)
 		a.Equal(Response{}, got)
 		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
 	})
 }
 
@@ -195,25 +440,139 @@ func TestFindByIdsRoles(t *testing.T) {
 }
 
 func TestDeleteByIdsRoles(t *testing.T) {
-	a := assert.New(t)
-	mockRepo := new(MockRoleRepo)
-	svc := NewService(mockRepo)
+	ctx := context.Background()
+
 	t.Run("Should delete role", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		mockRepo := new(MockRoleRepo)
+		svc := NewService(mockRepo)
+		tx, mockTr := testutil.NewTx(t, true)
 		ids := []int64{1, 2}
-		mockRepo.On("DeleteBySliceIds", ids).Return(ids, nil)
-		got, err := svc.DeleteByIds(ids)
+		mockRepo.On("BeginTr").Return(tx, nil)
+		mockRepo.On("DeleteBySliceIds", tx, ids).Return(ids, nil)
+		got, err := svc.DeleteByIds(ctx, "admin", ids)
 		expected := []Response{{Id: 1}, {Id: 2}}
 		a.Nil(err)
 		a.Equal(expected, got)
+		a.NoError(mockTr.ExpectationsWereMet())
 	})
 
 	t.Run("Should return error if ids is empty", func(t *testing.T) {
 		t.Parallel()
+		a := assert.New(t)
+		mockRepo := new(MockRoleRepo)
+		svc := NewService(mockRepo)
 		var ids []int64
-		mockRepo.On("DeleteBySliceIds", ids).Return(nil, errors.New("Wrong ids"))
-		got, err := svc.DeleteByIds(ids)
+		got, err := svc.DeleteByIds(ctx, "admin", ids)
 		a.Empty(got)
 		a.Error(err)
+		mockRepo.AssertNotCalled(t, "BeginTr")
+	})
+}
+
+func TestAddComposite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add child role", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("AddComposite", tx, int64(1), int64(2)).Return(nil)
+		repo.On("IsReachable", tx, int64(2), int64(1)).Return(false, nil)
+
+		err := svc.AddComposite(ctx, 1, CompositeRequest{ChildRoleId: 2})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should reject and rollback cycle", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("AddComposite", tx, int64(1), int64(2)).Return(nil)
+		repo.On("IsReachable", tx, int64(2), int64(1)).Return(true, nil)
+
+		err := svc.AddComposite(ctx, 1, CompositeRequest{ChildRoleId: 2})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should write event in the same transaction", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		events := new(MockOutbox)
+		svc := NewService(repo)
+		svc.Events = events
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("AddComposite", tx, int64(1), int64(2)).Return(nil)
+		repo.On("IsReachable", tx, int64(2), int64(1)).Return(false, nil)
+		events.On("Add", tx, mock.Anything).Return(nil)
+
+		err := svc.AddComposite(ctx, 1, CompositeRequest{ChildRoleId: 2})
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		var event = events.Calls[0].Arguments.Get(1).(outbox.Entity)
+		a.Equal(outbox.RoleCompositeAdded, event.Type)
+		a.Equal(int64(1), event.AggregateId)
+		a.JSONEq(`{"parent_role_id":1,"child_role_id":2}`, string(event.Payload))
+	})
+
+	t.Run("Should reject role containing itself", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRoleRepo))
+
+		err := svc.AddComposite(ctx, 3, CompositeRequest{ChildRoleId: 3})
+
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestFindEffectiveRoles(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should group paths by role", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRoleRepo)
+		svc := NewService(repo)
+		repo.On("FindEffectiveByEmployeeId", ctx, int64(5)).Return([]EffectiveRoleEntity{
+			{Id: 1, Name: "ADMIN", Path: []string{"ADMIN"}},
+			{Id: 2, Name: "READER", Path: []string{"READER"}},
+			{Id: 2, Name: "READER", Path: []string{"ADMIN", "READER"}},
+			{Id: 3, Name: "WRITER", Path: []string{"ADMIN", "WRITER"}},
+		}, nil)
+
+		got, err := svc.FindEffectiveRoles(ctx, 5)
+
+		a.NoError(err)
+		a.Equal([]EffectiveRoleResponse{
+			{Id: 1, Name: "ADMIN", Direct: true, Paths: [][]string{{"ADMIN"}}},
+			{Id: 2, Name: "READER", Direct: true, Paths: [][]string{{"READER"}, {"ADMIN", "READER"}}},
+			{Id: 3, Name: "WRITER", Direct: false, Paths: [][]string{{"ADMIN", "WRITER"}}},
+		}, got)
+	})
+
+	t.Run("Should return error if employee id <= 0", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc := NewService(new(MockRoleRepo))
+
+		_, err := svc.FindEffectiveRoles(ctx, 0)
+
+		a.Error(err)
 	})
 }
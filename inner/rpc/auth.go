@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"context"
+	"idm/inner/employee"
+	"idm/inner/web"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// caller - вызывающий пользователь: claims токена и разрешения, вычисляемые один раз за вызов
+type caller struct {
+	claims      *web.IdmClaims
+	permissions []string
+	resolved    bool
+}
+
+type callerKey struct{}
+
+// ClaimsFromCtx - claims токена, проверенного перехватчиком сервера
+func ClaimsFromCtx(ctx context.Context) (*web.IdmClaims, bool) {
+	c, ok := ctx.Value(callerKey{}).(*caller)
+	if !ok {
+		return nil, false
+	}
+	return c.claims, true
+}
+
+// authenticate - проверка токена из метаданных "authorization: Bearer <token>", как у AuthMiddleware
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var values = md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Missing authorization metadata")
+	}
+	var scheme, token, found = strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, status.Error(codes.Unauthenticated, "Expected bearer token")
+	}
+	var claims = &web.IdmClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, s.keyfunc); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return context.WithValue(ctx, callerKey{}, &caller{claims: claims}), nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	return resp, s.toStatus(ctx, info.FullMethod, err)
+}
+
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return s.toStatus(ctx, info.FullMethod, handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx}))
+}
+
+// authenticatedStream - поток с контекстом, в котором сохранён вызывающий
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// hasPermission - есть ли у вызывающего разрешение, выданное одной из ролей токена (как web.Server.HasPermission)
+func (s *Server) hasPermission(ctx context.Context, permission string) (bool, error) {
+	c, ok := ctx.Value(callerKey{}).(*caller)
+	if !ok {
+		return false, nil
+	}
+	if !c.resolved {
+		var resolver = s.Permissions
+		if resolver == nil {
+			resolver = web.DefaultPermissions
+		}
+		permissions, err := resolver.FindPermissionsByRoleNames(ctx, c.claims.RealmAccess.Roles)
+		if err != nil {
+			return false, err
+		}
+		c.permissions, c.resolved = permissions, true
+	}
+	return slices.Contains(c.permissions, permission), nil
+}
+
+// require - ошибка PermissionDenied без разрешения, Internal - если разрешения не удалось получить
+func (s *Server) require(ctx context.Context, permission string) error {
+	ok, err := s.hasPermission(ctx, permission)
+	if err != nil {
+		return status.Error(codes.Internal, "Error resolving permissions")
+	}
+	if !ok {
+		return status.Error(codes.PermissionDenied, "Permission denied")
+	}
+	return nil
+}
+
+// scoped - контекст вызова сервиса сотрудников: без глобального разрешения делегированный
+// администратор ограничен своими отделами, как в хендлерах /api/v1/employees
+func (s *Server) scoped(ctx context.Context, permission string) (context.Context, error) {
+	ok, err := s.hasPermission(ctx, permission)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Error resolving permissions")
+	}
+	if ok {
+		return ctx, nil
+	}
+	claims, _ := ClaimsFromCtx(ctx)
+	if s.Delegations == nil || claims == nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+	departments, err := s.Delegations.FindAdministeredDepartments(ctx, claims)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Error resolving permissions")
+	}
+	if len(departments) == 0 {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+	return employee.WithScope(ctx, employee.Scope{DepartmentIds: departments}), nil
+}
@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"context"
+	"idm/inner/employee"
+	"idm/inner/rpc/idmv1"
+	"idm/inner/web"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxBatchSize - наибольшая страница, которую отдаёт employee.Svc.FindAllWithLimitOffset
+const maxBatchSize = 100
+
+type employeeServer struct {
+	idmv1.UnimplementedEmployeeServiceServer
+	server  *Server
+	service employee.Svc
+}
+
+func (s *employeeServer) CreateEmployee(ctx context.Context, req *idmv1.CreateEmployeeRequest) (*idmv1.CreateEmployeeResponse, error) {
+	ctx, err := s.server.scoped(ctx, web.PermEmployeeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetAge() < 0 || req.GetAge() > math.MaxInt8 {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid age %d", req.GetAge())
+	}
+	var now = time.Now()
+	id, err := s.service.CreateEmployee(ctx, employee.CreateRequest{
+		Name:         req.GetName(),
+		Surname:      req.GetSurname(),
+		Age:          int8(req.GetAge()),
+		Login:        req.GetLogin(),
+		Email:        req.GetEmail(),
+		Phone:        req.GetPhone(),
+		DepartmentId: req.GetDepartmentId(),
+		ManagerId:    req.GetManagerId(),
+		Position:     req.GetPosition(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &idmv1.CreateEmployeeResponse{Id: id}, nil
+}
+
+func (s *employeeServer) GetEmployee(ctx context.Context, req *idmv1.GetEmployeeRequest) (*idmv1.Employee, error) {
+	ctx, err := s.server.scoped(ctx, web.PermEmployeeRead)
+	if err != nil {
+		return nil, err
+	}
+	var found employee.Response
+	if req.GetAsOf() != nil {
+		found, err = s.service.FindByIdAsOf(ctx, req.GetId(), req.GetAsOf().AsTime())
+	} else {
+		found, err = s.service.FindById(ctx, req.GetId())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toEmployee(found), nil
+}
+
+// ListEmployees - сотрудники читаются страницами по batch_size и отправляются по одному
+func (s *employeeServer) ListEmployees(req *idmv1.ListEmployeesRequest, stream grpc.ServerStreamingServer[idmv1.Employee]) error {
+	ctx, err := s.server.scoped(stream.Context(), web.PermEmployeeRead)
+	if err != nil {
+		return err
+	}
+	var page = employee.PageRequest{PageSize: maxBatchSize, TextFilter: req.GetTextFilter()}
+	if size := req.GetBatchSize(); size > 0 && size < maxBatchSize {
+		page.PageSize = int(size)
+	}
+	var find = s.service.FindAllWithLimitOffset
+	if req.GetAsOf() != nil {
+		var asOf = req.GetAsOf().AsTime()
+		find = func(ctx context.Context, page employee.PageRequest) (employee.PageResponse, error) {
+			return s.service.FindAllWithLimitOffsetAsOf(ctx, page, asOf)
+		}
+	}
+	for {
+		rsl, err := find(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, e := range rsl.Result {
+			if err := stream.Send(toEmployee(e)); err != nil {
+				return err
+			}
+		}
+		if len(rsl.Result) < page.PageSize || int64((page.PageNumber+1)*page.PageSize) >= rsl.Total {
+			return nil
+		}
+		page.PageNumber++
+	}
+}
+
+func (s *employeeServer) MoveEmployee(ctx context.Context, req *idmv1.MoveEmployeeRequest) (*idmv1.Employee, error) {
+	ctx, err := s.server.scoped(ctx, web.PermEmployeeWrite)
+	if err != nil {
+		return nil, err
+	}
+	moved, err := s.service.Move(ctx, req.GetId(), employee.MoveRequest{
+		DepartmentId: req.GetDepartmentId(),
+		Position:     req.GetPosition(),
+		ManagerId:    req.GetManagerId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toEmployee(moved), nil
+}
+
+func (s *employeeServer) DeleteEmployee(ctx context.Context, req *idmv1.DeleteEmployeeRequest) (*idmv1.Employee, error) {
+	ctx, err := s.server.scoped(ctx, web.PermEmployeeDelete)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.service.DeleteById(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toEmployee(deleted), nil
+}
+
+func toEmployee(e employee.Response) *idmv1.Employee {
+	return &idmv1.Employee{
+		Id:           e.Id,
+		Name:         e.Name,
+		Surname:      e.Surname,
+		Age:          int32(e.Age),
+		Login:        e.Login,
+		Email:        e.Email,
+		Phone:        e.Phone,
+		DepartmentId: e.DepartmentId,
+		ManagerId:    e.ManagerId,
+		Position:     e.Position,
+		Active:       e.Active,
+		CreatedAt:    timestamppb.New(e.CreatedAt),
+		UpdatedAt:    timestamppb.New(e.UpdatedAt),
+	}
+}
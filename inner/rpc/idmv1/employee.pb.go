@@ -0,0 +1,672 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: idm/v1/employee.proto
+
+package idmv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Employee struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Surname string                 `protobuf:"bytes,3,opt,name=surname,proto3" json:"surname,omitempty"`
+	Age     int32                  `protobuf:"varint,4,opt,name=age,proto3" json:"age,omitempty"`
+	Login   string                 `protobuf:"bytes,5,opt,name=login,proto3" json:"login,omitempty"`
+	Email   string                 `protobuf:"bytes,6,opt,name=email,proto3" json:"email,omitempty"`
+	Phone   string                 `protobuf:"bytes,7,opt,name=phone,proto3" json:"phone,omitempty"`
+	// department_id, manager_id - 0, если отдела или руководителя нет
+	DepartmentId  int64                  `protobuf:"varint,8,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	ManagerId     int64                  `protobuf:"varint,9,opt,name=manager_id,json=managerId,proto3" json:"manager_id,omitempty"`
+	Position      string                 `protobuf:"bytes,10,opt,name=position,proto3" json:"position,omitempty"`
+	Active        bool                   `protobuf:"varint,11,opt,name=active,proto3" json:"active,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Employee) Reset() {
+	*x = Employee{}
+	mi := &file_idm_v1_employee_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Employee) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Employee) ProtoMessage() {}
+
+func (x *Employee) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Employee.ProtoReflect.Descriptor instead.
+func (*Employee) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Employee) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Employee) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Employee) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *Employee) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *Employee) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+func (x *Employee) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Employee) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *Employee) GetDepartmentId() int64 {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return 0
+}
+
+func (x *Employee) GetManagerId() int64 {
+	if x != nil {
+		return x.ManagerId
+	}
+	return 0
+}
+
+func (x *Employee) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+func (x *Employee) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Employee) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Employee) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CreateEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Surname       string                 `protobuf:"bytes,2,opt,name=surname,proto3" json:"surname,omitempty"`
+	Age           int32                  `protobuf:"varint,3,opt,name=age,proto3" json:"age,omitempty"`
+	Login         string                 `protobuf:"bytes,4,opt,name=login,proto3" json:"login,omitempty"`
+	Email         string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	Phone         string                 `protobuf:"bytes,6,opt,name=phone,proto3" json:"phone,omitempty"`
+	DepartmentId  int64                  `protobuf:"varint,7,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	ManagerId     int64                  `protobuf:"varint,8,opt,name=manager_id,json=managerId,proto3" json:"manager_id,omitempty"`
+	Position      string                 `protobuf:"bytes,9,opt,name=position,proto3" json:"position,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateEmployeeRequest) Reset() {
+	*x = CreateEmployeeRequest{}
+	mi := &file_idm_v1_employee_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateEmployeeRequest) ProtoMessage() {}
+
+func (x *CreateEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*CreateEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateEmployeeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateEmployeeRequest) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *CreateEmployeeRequest) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *CreateEmployeeRequest) GetLogin() string {
+	if x != nil {
+		return x.Login
+	}
+	return ""
+}
+
+func (x *CreateEmployeeRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *CreateEmployeeRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *CreateEmployeeRequest) GetDepartmentId() int64 {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return 0
+}
+
+func (x *CreateEmployeeRequest) GetManagerId() int64 {
+	if x != nil {
+		return x.ManagerId
+	}
+	return 0
+}
+
+func (x *CreateEmployeeRequest) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+type CreateEmployeeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateEmployeeResponse) Reset() {
+	*x = CreateEmployeeResponse{}
+	mi := &file_idm_v1_employee_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateEmployeeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateEmployeeResponse) ProtoMessage() {}
+
+func (x *CreateEmployeeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateEmployeeResponse.ProtoReflect.Descriptor instead.
+func (*CreateEmployeeResponse) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateEmployeeResponse) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetEmployeeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// as_of - момент, на который нужно состояние сотрудника; не задан - текущее
+	AsOf          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEmployeeRequest) Reset() {
+	*x = GetEmployeeRequest{}
+	mi := &file_idm_v1_employee_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEmployeeRequest) ProtoMessage() {}
+
+func (x *GetEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*GetEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetEmployeeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetEmployeeRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+type ListEmployeesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// text_filter - подстрока имени, как в /api/v1/employees/page
+	TextFilter string                 `protobuf:"bytes,1,opt,name=text_filter,json=textFilter,proto3" json:"text_filter,omitempty"`
+	AsOf       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	// batch_size - сколько сотрудников читается из базы за раз, по умолчанию и не больше 100
+	BatchSize     int32 `protobuf:"varint,3,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEmployeesRequest) Reset() {
+	*x = ListEmployeesRequest{}
+	mi := &file_idm_v1_employee_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEmployeesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEmployeesRequest) ProtoMessage() {}
+
+func (x *ListEmployeesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEmployeesRequest.ProtoReflect.Descriptor instead.
+func (*ListEmployeesRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListEmployeesRequest) GetTextFilter() string {
+	if x != nil {
+		return x.TextFilter
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+func (x *ListEmployeesRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+type MoveEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	DepartmentId  int64                  `protobuf:"varint,2,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	Position      string                 `protobuf:"bytes,3,opt,name=position,proto3" json:"position,omitempty"`
+	ManagerId     int64                  `protobuf:"varint,4,opt,name=manager_id,json=managerId,proto3" json:"manager_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveEmployeeRequest) Reset() {
+	*x = MoveEmployeeRequest{}
+	mi := &file_idm_v1_employee_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveEmployeeRequest) ProtoMessage() {}
+
+func (x *MoveEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*MoveEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MoveEmployeeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *MoveEmployeeRequest) GetDepartmentId() int64 {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return 0
+}
+
+func (x *MoveEmployeeRequest) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+func (x *MoveEmployeeRequest) GetManagerId() int64 {
+	if x != nil {
+		return x.ManagerId
+	}
+	return 0
+}
+
+type DeleteEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEmployeeRequest) Reset() {
+	*x = DeleteEmployeeRequest{}
+	mi := &file_idm_v1_employee_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEmployeeRequest) ProtoMessage() {}
+
+func (x *DeleteEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_employee_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_employee_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteEmployeeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+var File_idm_v1_employee_proto protoreflect.FileDescriptor
+
+const file_idm_v1_employee_proto_rawDesc = "" +
+	"\n" +
+	"\x15idm/v1/employee.proto\x12\x06idm.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8a\x03\n" +
+	"\bEmployee\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\asurname\x18\x03 \x01(\tR\asurname\x12\x10\n" +
+	"\x03age\x18\x04 \x01(\x05R\x03age\x12\x14\n" +
+	"\x05login\x18\x05 \x01(\tR\x05login\x12\x14\n" +
+	"\x05email\x18\x06 \x01(\tR\x05email\x12\x14\n" +
+	"\x05phone\x18\a \x01(\tR\x05phone\x12#\n" +
+	"\rdepartment_id\x18\b \x01(\x03R\fdepartmentId\x12\x1d\n" +
+	"\n" +
+	"manager_id\x18\t \x01(\x03R\tmanagerId\x12\x1a\n" +
+	"\bposition\x18\n" +
+	" \x01(\tR\bposition\x12\x16\n" +
+	"\x06active\x18\v \x01(\bR\x06active\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xf9\x01\n" +
+	"\x15CreateEmployeeRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\asurname\x18\x02 \x01(\tR\asurname\x12\x10\n" +
+	"\x03age\x18\x03 \x01(\x05R\x03age\x12\x14\n" +
+	"\x05login\x18\x04 \x01(\tR\x05login\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12\x14\n" +
+	"\x05phone\x18\x06 \x01(\tR\x05phone\x12#\n" +
+	"\rdepartment_id\x18\a \x01(\x03R\fdepartmentId\x12\x1d\n" +
+	"\n" +
+	"manager_id\x18\b \x01(\x03R\tmanagerId\x12\x1a\n" +
+	"\bposition\x18\t \x01(\tR\bposition\"(\n" +
+	"\x16CreateEmployeeResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"U\n" +
+	"\x12GetEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12/\n" +
+	"\x05as_of\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\"\x87\x01\n" +
+	"\x14ListEmployeesRequest\x12\x1f\n" +
+	"\vtext_filter\x18\x01 \x01(\tR\n" +
+	"textFilter\x12/\n" +
+	"\x05as_of\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x03 \x01(\x05R\tbatchSize\"\x85\x01\n" +
+	"\x13MoveEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12#\n" +
+	"\rdepartment_id\x18\x02 \x01(\x03R\fdepartmentId\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\tR\bposition\x12\x1d\n" +
+	"\n" +
+	"manager_id\x18\x04 \x01(\x03R\tmanagerId\"'\n" +
+	"\x15DeleteEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id2\xe4\x02\n" +
+	"\x0fEmployeeService\x12O\n" +
+	"\x0eCreateEmployee\x12\x1d.idm.v1.CreateEmployeeRequest\x1a\x1e.idm.v1.CreateEmployeeResponse\x12;\n" +
+	"\vGetEmployee\x12\x1a.idm.v1.GetEmployeeRequest\x1a\x10.idm.v1.Employee\x12A\n" +
+	"\rListEmployees\x12\x1c.idm.v1.ListEmployeesRequest\x1a\x10.idm.v1.Employee0\x01\x12=\n" +
+	"\fMoveEmployee\x12\x1b.idm.v1.MoveEmployeeRequest\x1a\x10.idm.v1.Employee\x12A\n" +
+	"\x0eDeleteEmployee\x12\x1d.idm.v1.DeleteEmployeeRequest\x1a\x10.idm.v1.EmployeeB\x1bZ\x19idm/inner/rpc/idmv1;idmv1b\x06proto3"
+
+var (
+	file_idm_v1_employee_proto_rawDescOnce sync.Once
+	file_idm_v1_employee_proto_rawDescData []byte
+)
+
+func file_idm_v1_employee_proto_rawDescGZIP() []byte {
+	file_idm_v1_employee_proto_rawDescOnce.Do(func() {
+		file_idm_v1_employee_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_idm_v1_employee_proto_rawDesc), len(file_idm_v1_employee_proto_rawDesc)))
+	})
+	return file_idm_v1_employee_proto_rawDescData
+}
+
+var file_idm_v1_employee_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_idm_v1_employee_proto_goTypes = []any{
+	(*Employee)(nil),               // 0: idm.v1.Employee
+	(*CreateEmployeeRequest)(nil),  // 1: idm.v1.CreateEmployeeRequest
+	(*CreateEmployeeResponse)(nil), // 2: idm.v1.CreateEmployeeResponse
+	(*GetEmployeeRequest)(nil),     // 3: idm.v1.GetEmployeeRequest
+	(*ListEmployeesRequest)(nil),   // 4: idm.v1.ListEmployeesRequest
+	(*MoveEmployeeRequest)(nil),    // 5: idm.v1.MoveEmployeeRequest
+	(*DeleteEmployeeRequest)(nil),  // 6: idm.v1.DeleteEmployeeRequest
+	(*timestamppb.Timestamp)(nil),  // 7: google.protobuf.Timestamp
+}
+var file_idm_v1_employee_proto_depIdxs = []int32{
+	7, // 0: idm.v1.Employee.created_at:type_name -> google.protobuf.Timestamp
+	7, // 1: idm.v1.Employee.updated_at:type_name -> google.protobuf.Timestamp
+	7, // 2: idm.v1.GetEmployeeRequest.as_of:type_name -> google.protobuf.Timestamp
+	7, // 3: idm.v1.ListEmployeesRequest.as_of:type_name -> google.protobuf.Timestamp
+	1, // 4: idm.v1.EmployeeService.CreateEmployee:input_type -> idm.v1.CreateEmployeeRequest
+	3, // 5: idm.v1.EmployeeService.GetEmployee:input_type -> idm.v1.GetEmployeeRequest
+	4, // 6: idm.v1.EmployeeService.ListEmployees:input_type -> idm.v1.ListEmployeesRequest
+	5, // 7: idm.v1.EmployeeService.MoveEmployee:input_type -> idm.v1.MoveEmployeeRequest
+	6, // 8: idm.v1.EmployeeService.DeleteEmployee:input_type -> idm.v1.DeleteEmployeeRequest
+	2, // 9: idm.v1.EmployeeService.CreateEmployee:output_type -> idm.v1.CreateEmployeeResponse
+	0, // 10: idm.v1.EmployeeService.GetEmployee:output_type -> idm.v1.Employee
+	0, // 11: idm.v1.EmployeeService.ListEmployees:output_type -> idm.v1.Employee
+	0, // 12: idm.v1.EmployeeService.MoveEmployee:output_type -> idm.v1.Employee
+	0, // 13: idm.v1.EmployeeService.DeleteEmployee:output_type -> idm.v1.Employee
+	9, // [9:14] is the sub-list for method output_type
+	4, // [4:9] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_idm_v1_employee_proto_init() }
+func file_idm_v1_employee_proto_init() {
+	if File_idm_v1_employee_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_idm_v1_employee_proto_rawDesc), len(file_idm_v1_employee_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_idm_v1_employee_proto_goTypes,
+		DependencyIndexes: file_idm_v1_employee_proto_depIdxs,
+		MessageInfos:      file_idm_v1_employee_proto_msgTypes,
+	}.Build()
+	File_idm_v1_employee_proto = out.File
+	file_idm_v1_employee_proto_goTypes = nil
+	file_idm_v1_employee_proto_depIdxs = nil
+}
@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: idm/v1/employee.proto
+
+package idmv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EmployeeService_CreateEmployee_FullMethodName = "/idm.v1.EmployeeService/CreateEmployee"
+	EmployeeService_GetEmployee_FullMethodName    = "/idm.v1.EmployeeService/GetEmployee"
+	EmployeeService_ListEmployees_FullMethodName  = "/idm.v1.EmployeeService/ListEmployees"
+	EmployeeService_MoveEmployee_FullMethodName   = "/idm.v1.EmployeeService/MoveEmployee"
+	EmployeeService_DeleteEmployee_FullMethodName = "/idm.v1.EmployeeService/DeleteEmployee"
+)
+
+// EmployeeServiceClient is the client API for EmployeeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// EmployeeService - сотрудники. Разрешения и ограничение делегированного администратора
+// отделами те же, что у /api/v1/employees
+type EmployeeServiceClient interface {
+	// CreateEmployee - создание сотрудника, разрешение employee:write
+	CreateEmployee(ctx context.Context, in *CreateEmployeeRequest, opts ...grpc.CallOption) (*CreateEmployeeResponse, error)
+	// GetEmployee - сотрудник по id, при заданном as_of - его состояние на этот момент; employee:read
+	GetEmployee(ctx context.Context, in *GetEmployeeRequest, opts ...grpc.CallOption) (*Employee, error)
+	// ListEmployees - все сотрудники, подходящие под фильтр, по одному в потоке; employee:read
+	ListEmployees(ctx context.Context, in *ListEmployeesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Employee], error)
+	// MoveEmployee - перевод в другой отдел и/или на другую должность; employee:write
+	MoveEmployee(ctx context.Context, in *MoveEmployeeRequest, opts ...grpc.CallOption) (*Employee, error)
+	// DeleteEmployee - удаление сотрудника, employee:delete
+	DeleteEmployee(ctx context.Context, in *DeleteEmployeeRequest, opts ...grpc.CallOption) (*Employee, error)
+}
+
+type employeeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEmployeeServiceClient(cc grpc.ClientConnInterface) EmployeeServiceClient {
+	return &employeeServiceClient{cc}
+}
+
+func (c *employeeServiceClient) CreateEmployee(ctx context.Context, in *CreateEmployeeRequest, opts ...grpc.CallOption) (*CreateEmployeeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateEmployeeResponse)
+	err := c.cc.Invoke(ctx, EmployeeService_CreateEmployee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *employeeServiceClient) GetEmployee(ctx context.Context, in *GetEmployeeRequest, opts ...grpc.CallOption) (*Employee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Employee)
+	err := c.cc.Invoke(ctx, EmployeeService_GetEmployee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *employeeServiceClient) ListEmployees(ctx context.Context, in *ListEmployeesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Employee], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EmployeeService_ServiceDesc.Streams[0], EmployeeService_ListEmployees_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListEmployeesRequest, Employee]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EmployeeService_ListEmployeesClient = grpc.ServerStreamingClient[Employee]
+
+func (c *employeeServiceClient) MoveEmployee(ctx context.Context, in *MoveEmployeeRequest, opts ...grpc.CallOption) (*Employee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Employee)
+	err := c.cc.Invoke(ctx, EmployeeService_MoveEmployee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *employeeServiceClient) DeleteEmployee(ctx context.Context, in *DeleteEmployeeRequest, opts ...grpc.CallOption) (*Employee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Employee)
+	err := c.cc.Invoke(ctx, EmployeeService_DeleteEmployee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EmployeeServiceServer is the server API for EmployeeService service.
+// All implementations must embed UnimplementedEmployeeServiceServer
+// for forward compatibility.
+//
+// EmployeeService - сотрудники. Разрешения и ограничение делегированного администратора
+// отделами те же, что у /api/v1/employees
+type EmployeeServiceServer interface {
+	// CreateEmployee - создание сотрудника, разрешение employee:write
+	CreateEmployee(context.Context, *CreateEmployeeRequest) (*CreateEmployeeResponse, error)
+	// GetEmployee - сотрудник по id, при заданном as_of - его состояние на этот момент; employee:read
+	GetEmployee(context.Context, *GetEmployeeRequest) (*Employee, error)
+	// ListEmployees - все сотрудники, подходящие под фильтр, по одному в потоке; employee:read
+	ListEmployees(*ListEmployeesRequest, grpc.ServerStreamingServer[Employee]) error
+	// MoveEmployee - перевод в другой отдел и/или на другую должность; employee:write
+	MoveEmployee(context.Context, *MoveEmployeeRequest) (*Employee, error)
+	// DeleteEmployee - удаление сотрудника, employee:delete
+	DeleteEmployee(context.Context, *DeleteEmployeeRequest) (*Employee, error)
+	mustEmbedUnimplementedEmployeeServiceServer()
+}
+
+// UnimplementedEmployeeServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEmployeeServiceServer struct{}
+
+func (UnimplementedEmployeeServiceServer) CreateEmployee(context.Context, *CreateEmployeeRequest) (*CreateEmployeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEmployee not implemented")
+}
+func (UnimplementedEmployeeServiceServer) GetEmployee(context.Context, *GetEmployeeRequest) (*Employee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmployee not implemented")
+}
+func (UnimplementedEmployeeServiceServer) ListEmployees(*ListEmployeesRequest, grpc.ServerStreamingServer[Employee]) error {
+	return status.Errorf(codes.Unimplemented, "method ListEmployees not implemented")
+}
+func (UnimplementedEmployeeServiceServer) MoveEmployee(context.Context, *MoveEmployeeRequest) (*Employee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveEmployee not implemented")
+}
+func (UnimplementedEmployeeServiceServer) DeleteEmployee(context.Context, *DeleteEmployeeRequest) (*Employee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEmployee not implemented")
+}
+func (UnimplementedEmployeeServiceServer) mustEmbedUnimplementedEmployeeServiceServer() {}
+func (UnimplementedEmployeeServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeEmployeeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EmployeeServiceServer will
+// result in compilation errors.
+type UnsafeEmployeeServiceServer interface {
+	mustEmbedUnimplementedEmployeeServiceServer()
+}
+
+func RegisterEmployeeServiceServer(s grpc.ServiceRegistrar, srv EmployeeServiceServer) {
+	// If the following call pancis, it indicates UnimplementedEmployeeServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EmployeeService_ServiceDesc, srv)
+}
+
+func _EmployeeService_CreateEmployee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEmployeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).CreateEmployee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_CreateEmployee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).CreateEmployee(ctx, req.(*CreateEmployeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmployeeService_GetEmployee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEmployeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).GetEmployee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_GetEmployee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).GetEmployee(ctx, req.(*GetEmployeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmployeeService_ListEmployees_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListEmployeesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EmployeeServiceServer).ListEmployees(m, &grpc.GenericServerStream[ListEmployeesRequest, Employee]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EmployeeService_ListEmployeesServer = grpc.ServerStreamingServer[Employee]
+
+func _EmployeeService_MoveEmployee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveEmployeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).MoveEmployee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_MoveEmployee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).MoveEmployee(ctx, req.(*MoveEmployeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmployeeService_DeleteEmployee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteEmployeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).DeleteEmployee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_DeleteEmployee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).DeleteEmployee(ctx, req.(*DeleteEmployeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EmployeeService_ServiceDesc is the grpc.ServiceDesc for EmployeeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EmployeeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "idm.v1.EmployeeService",
+	HandlerType: (*EmployeeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEmployee",
+			Handler:    _EmployeeService_CreateEmployee_Handler,
+		},
+		{
+			MethodName: "GetEmployee",
+			Handler:    _EmployeeService_GetEmployee_Handler,
+		},
+		{
+			MethodName: "MoveEmployee",
+			Handler:    _EmployeeService_MoveEmployee_Handler,
+		},
+		{
+			MethodName: "DeleteEmployee",
+			Handler:    _EmployeeService_DeleteEmployee_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListEmployees",
+			Handler:       _EmployeeService_ListEmployees_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "idm/v1/employee.proto",
+}
@@ -0,0 +1,736 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: idm/v1/role.proto
+
+package idmv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Role struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	OwnerId     *int64                 `protobuf:"varint,4,opt,name=owner_id,json=ownerId,proto3,oneof" json:"owner_id,omitempty"`
+	RiskLevel   string                 `protobuf:"bytes,5,opt,name=risk_level,json=riskLevel,proto3" json:"risk_level,omitempty"`
+	Requestable bool                   `protobuf:"varint,6,opt,name=requestable,proto3" json:"requestable,omitempty"`
+	// max_assignment_days - максимальный срок назначения в днях, не задан - бессрочно
+	MaxAssignmentDays *int32                 `protobuf:"varint,7,opt,name=max_assignment_days,json=maxAssignmentDays,proto3,oneof" json:"max_assignment_days,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt         *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Role) Reset() {
+	*x = Role{}
+	mi := &file_idm_v1_role_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Role) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Role) ProtoMessage() {}
+
+func (x *Role) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Role.ProtoReflect.Descriptor instead.
+func (*Role) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Role) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Role) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Role) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Role) GetOwnerId() int64 {
+	if x != nil && x.OwnerId != nil {
+		return *x.OwnerId
+	}
+	return 0
+}
+
+func (x *Role) GetRiskLevel() string {
+	if x != nil {
+		return x.RiskLevel
+	}
+	return ""
+}
+
+func (x *Role) GetRequestable() bool {
+	if x != nil {
+		return x.Requestable
+	}
+	return false
+}
+
+func (x *Role) GetMaxAssignmentDays() int32 {
+	if x != nil && x.MaxAssignmentDays != nil {
+		return *x.MaxAssignmentDays
+	}
+	return 0
+}
+
+func (x *Role) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Role) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// RoleFields - метаданные роли. Без risk_level роль получает низкий риск, без requestable -
+// доступна для запроса
+type RoleFields struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Name              string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description       string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	OwnerId           *int64                 `protobuf:"varint,3,opt,name=owner_id,json=ownerId,proto3,oneof" json:"owner_id,omitempty"`
+	RiskLevel         string                 `protobuf:"bytes,4,opt,name=risk_level,json=riskLevel,proto3" json:"risk_level,omitempty"`
+	Requestable       *bool                  `protobuf:"varint,5,opt,name=requestable,proto3,oneof" json:"requestable,omitempty"`
+	MaxAssignmentDays *int32                 `protobuf:"varint,6,opt,name=max_assignment_days,json=maxAssignmentDays,proto3,oneof" json:"max_assignment_days,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RoleFields) Reset() {
+	*x = RoleFields{}
+	mi := &file_idm_v1_role_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoleFields) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoleFields) ProtoMessage() {}
+
+func (x *RoleFields) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoleFields.ProtoReflect.Descriptor instead.
+func (*RoleFields) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RoleFields) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RoleFields) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *RoleFields) GetOwnerId() int64 {
+	if x != nil && x.OwnerId != nil {
+		return *x.OwnerId
+	}
+	return 0
+}
+
+func (x *RoleFields) GetRiskLevel() string {
+	if x != nil {
+		return x.RiskLevel
+	}
+	return ""
+}
+
+func (x *RoleFields) GetRequestable() bool {
+	if x != nil && x.Requestable != nil {
+		return *x.Requestable
+	}
+	return false
+}
+
+func (x *RoleFields) GetMaxAssignmentDays() int32 {
+	if x != nil && x.MaxAssignmentDays != nil {
+		return *x.MaxAssignmentDays
+	}
+	return 0
+}
+
+type CreateRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          *RoleFields            `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRoleRequest) Reset() {
+	*x = CreateRoleRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRoleRequest) ProtoMessage() {}
+
+func (x *CreateRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRoleRequest.ProtoReflect.Descriptor instead.
+func (*CreateRoleRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateRoleRequest) GetRole() *RoleFields {
+	if x != nil {
+		return x.Role
+	}
+	return nil
+}
+
+type GetRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoleRequest) Reset() {
+	*x = GetRoleRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoleRequest) ProtoMessage() {}
+
+func (x *GetRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoleRequest.ProtoReflect.Descriptor instead.
+func (*GetRoleRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRoleRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListRolesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRolesRequest) Reset() {
+	*x = ListRolesRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRolesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRolesRequest) ProtoMessage() {}
+
+func (x *ListRolesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRolesRequest.ProtoReflect.Descriptor instead.
+func (*ListRolesRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{4}
+}
+
+type UpdateRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Role          *RoleFields            `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRoleRequest) Reset() {
+	*x = UpdateRoleRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRoleRequest) ProtoMessage() {}
+
+func (x *UpdateRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRoleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRoleRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateRoleRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateRoleRequest) GetRole() *RoleFields {
+	if x != nil {
+		return x.Role
+	}
+	return nil
+}
+
+type DeleteRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRoleRequest) Reset() {
+	*x = DeleteRoleRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRoleRequest) ProtoMessage() {}
+
+func (x *DeleteRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRoleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRoleRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteRoleRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListEffectiveRolesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmployeeId    int64                  `protobuf:"varint,1,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEffectiveRolesRequest) Reset() {
+	*x = ListEffectiveRolesRequest{}
+	mi := &file_idm_v1_role_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEffectiveRolesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEffectiveRolesRequest) ProtoMessage() {}
+
+func (x *ListEffectiveRolesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEffectiveRolesRequest.ProtoReflect.Descriptor instead.
+func (*ListEffectiveRolesRequest) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListEffectiveRolesRequest) GetEmployeeId() int64 {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return 0
+}
+
+type EffectiveRole struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// direct - роль назначена напрямую, а не получена через составную роль
+	Direct bool `protobuf:"varint,3,opt,name=direct,proto3" json:"direct,omitempty"`
+	// paths - цепочки ролей от назначенной до данной
+	Paths         []*RolePath `protobuf:"bytes,4,rep,name=paths,proto3" json:"paths,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EffectiveRole) Reset() {
+	*x = EffectiveRole{}
+	mi := &file_idm_v1_role_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EffectiveRole) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EffectiveRole) ProtoMessage() {}
+
+func (x *EffectiveRole) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EffectiveRole.ProtoReflect.Descriptor instead.
+func (*EffectiveRole) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EffectiveRole) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *EffectiveRole) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EffectiveRole) GetDirect() bool {
+	if x != nil {
+		return x.Direct
+	}
+	return false
+}
+
+func (x *EffectiveRole) GetPaths() []*RolePath {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+type RolePath struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Roles         []string               `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RolePath) Reset() {
+	*x = RolePath{}
+	mi := &file_idm_v1_role_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RolePath) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RolePath) ProtoMessage() {}
+
+func (x *RolePath) ProtoReflect() protoreflect.Message {
+	mi := &file_idm_v1_role_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RolePath.ProtoReflect.Descriptor instead.
+func (*RolePath) Descriptor() ([]byte, []int) {
+	return file_idm_v1_role_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RolePath) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+var File_idm_v1_role_proto protoreflect.FileDescriptor
+
+const file_idm_v1_role_proto_rawDesc = "" +
+	"\n" +
+	"\x11idm/v1/role.proto\x12\x06idm.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfd\x02\n" +
+	"\x04Role\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1e\n" +
+	"\bowner_id\x18\x04 \x01(\x03H\x00R\aownerId\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"risk_level\x18\x05 \x01(\tR\triskLevel\x12 \n" +
+	"\vrequestable\x18\x06 \x01(\bR\vrequestable\x123\n" +
+	"\x13max_assignment_days\x18\a \x01(\x05H\x01R\x11maxAssignmentDays\x88\x01\x01\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\v\n" +
+	"\t_owner_idB\x16\n" +
+	"\x14_max_assignment_days\"\x92\x02\n" +
+	"\n" +
+	"RoleFields\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1e\n" +
+	"\bowner_id\x18\x03 \x01(\x03H\x00R\aownerId\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"risk_level\x18\x04 \x01(\tR\triskLevel\x12%\n" +
+	"\vrequestable\x18\x05 \x01(\bH\x01R\vrequestable\x88\x01\x01\x123\n" +
+	"\x13max_assignment_days\x18\x06 \x01(\x05H\x02R\x11maxAssignmentDays\x88\x01\x01B\v\n" +
+	"\t_owner_idB\x0e\n" +
+	"\f_requestableB\x16\n" +
+	"\x14_max_assignment_days\";\n" +
+	"\x11CreateRoleRequest\x12&\n" +
+	"\x04role\x18\x01 \x01(\v2\x12.idm.v1.RoleFieldsR\x04role\" \n" +
+	"\x0eGetRoleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x12\n" +
+	"\x10ListRolesRequest\"K\n" +
+	"\x11UpdateRoleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12&\n" +
+	"\x04role\x18\x02 \x01(\v2\x12.idm.v1.RoleFieldsR\x04role\"#\n" +
+	"\x11DeleteRoleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"<\n" +
+	"\x19ListEffectiveRolesRequest\x12\x1f\n" +
+	"\vemployee_id\x18\x01 \x01(\x03R\n" +
+	"employeeId\"s\n" +
+	"\rEffectiveRole\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06direct\x18\x03 \x01(\bR\x06direct\x12&\n" +
+	"\x05paths\x18\x04 \x03(\v2\x10.idm.v1.RolePathR\x05paths\" \n" +
+	"\bRolePath\x12\x14\n" +
+	"\x05roles\x18\x01 \x03(\tR\x05roles2\xec\x02\n" +
+	"\vRoleService\x125\n" +
+	"\n" +
+	"CreateRole\x12\x19.idm.v1.CreateRoleRequest\x1a\f.idm.v1.Role\x12/\n" +
+	"\aGetRole\x12\x16.idm.v1.GetRoleRequest\x1a\f.idm.v1.Role\x125\n" +
+	"\tListRoles\x12\x18.idm.v1.ListRolesRequest\x1a\f.idm.v1.Role0\x01\x125\n" +
+	"\n" +
+	"UpdateRole\x12\x19.idm.v1.UpdateRoleRequest\x1a\f.idm.v1.Role\x125\n" +
+	"\n" +
+	"DeleteRole\x12\x19.idm.v1.DeleteRoleRequest\x1a\f.idm.v1.Role\x12P\n" +
+	"\x12ListEffectiveRoles\x12!.idm.v1.ListEffectiveRolesRequest\x1a\x15.idm.v1.EffectiveRole0\x01B\x1bZ\x19idm/inner/rpc/idmv1;idmv1b\x06proto3"
+
+var (
+	file_idm_v1_role_proto_rawDescOnce sync.Once
+	file_idm_v1_role_proto_rawDescData []byte
+)
+
+func file_idm_v1_role_proto_rawDescGZIP() []byte {
+	file_idm_v1_role_proto_rawDescOnce.Do(func() {
+		file_idm_v1_role_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_idm_v1_role_proto_rawDesc), len(file_idm_v1_role_proto_rawDesc)))
+	})
+	return file_idm_v1_role_proto_rawDescData
+}
+
+var file_idm_v1_role_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_idm_v1_role_proto_goTypes = []any{
+	(*Role)(nil),                      // 0: idm.v1.Role
+	(*RoleFields)(nil),                // 1: idm.v1.RoleFields
+	(*CreateRoleRequest)(nil),         // 2: idm.v1.CreateRoleRequest
+	(*GetRoleRequest)(nil),            // 3: idm.v1.GetRoleRequest
+	(*ListRolesRequest)(nil),          // 4: idm.v1.ListRolesRequest
+	(*UpdateRoleRequest)(nil),         // 5: idm.v1.UpdateRoleRequest
+	(*DeleteRoleRequest)(nil),         // 6: idm.v1.DeleteRoleRequest
+	(*ListEffectiveRolesRequest)(nil), // 7: idm.v1.ListEffectiveRolesRequest
+	(*EffectiveRole)(nil),             // 8: idm.v1.EffectiveRole
+	(*RolePath)(nil),                  // 9: idm.v1.RolePath
+	(*timestamppb.Timestamp)(nil),     // 10: google.protobuf.Timestamp
+}
+var file_idm_v1_role_proto_depIdxs = []int32{
+	10, // 0: idm.v1.Role.created_at:type_name -> google.protobuf.Timestamp
+	10, // 1: idm.v1.Role.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: idm.v1.CreateRoleRequest.role:type_name -> idm.v1.RoleFields
+	1,  // 3: idm.v1.UpdateRoleRequest.role:type_name -> idm.v1.RoleFields
+	9,  // 4: idm.v1.EffectiveRole.paths:type_name -> idm.v1.RolePath
+	2,  // 5: idm.v1.RoleService.CreateRole:input_type -> idm.v1.CreateRoleRequest
+	3,  // 6: idm.v1.RoleService.GetRole:input_type -> idm.v1.GetRoleRequest
+	4,  // 7: idm.v1.RoleService.ListRoles:input_type -> idm.v1.ListRolesRequest
+	5,  // 8: idm.v1.RoleService.UpdateRole:input_type -> idm.v1.UpdateRoleRequest
+	6,  // 9: idm.v1.RoleService.DeleteRole:input_type -> idm.v1.DeleteRoleRequest
+	7,  // 10: idm.v1.RoleService.ListEffectiveRoles:input_type -> idm.v1.ListEffectiveRolesRequest
+	0,  // 11: idm.v1.RoleService.CreateRole:output_type -> idm.v1.Role
+	0,  // 12: idm.v1.RoleService.GetRole:output_type -> idm.v1.Role
+	0,  // 13: idm.v1.RoleService.ListRoles:output_type -> idm.v1.Role
+	0,  // 14: idm.v1.RoleService.UpdateRole:output_type -> idm.v1.Role
+	0,  // 15: idm.v1.RoleService.DeleteRole:output_type -> idm.v1.Role
+	8,  // 16: idm.v1.RoleService.ListEffectiveRoles:output_type -> idm.v1.EffectiveRole
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_idm_v1_role_proto_init() }
+func file_idm_v1_role_proto_init() {
+	if File_idm_v1_role_proto != nil {
+		return
+	}
+	file_idm_v1_role_proto_msgTypes[0].OneofWrappers = []any{}
+	file_idm_v1_role_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_idm_v1_role_proto_rawDesc), len(file_idm_v1_role_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_idm_v1_role_proto_goTypes,
+		DependencyIndexes: file_idm_v1_role_proto_depIdxs,
+		MessageInfos:      file_idm_v1_role_proto_msgTypes,
+	}.Build()
+	File_idm_v1_role_proto = out.File
+	file_idm_v1_role_proto_goTypes = nil
+	file_idm_v1_role_proto_depIdxs = nil
+}
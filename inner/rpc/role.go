@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"idm/inner/role"
+	"idm/inner/rpc/idmv1"
+	"idm/inner/web"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type roleServer struct {
+	idmv1.UnimplementedRoleServiceServer
+	server  *Server
+	service role.Svc
+}
+
+func (s *roleServer) CreateRole(ctx context.Context, req *idmv1.CreateRoleRequest) (*idmv1.Role, error) {
+	if err := s.server.require(ctx, web.PermRoleWrite); err != nil {
+		return nil, err
+	}
+	var request = toRoleRequest(req.GetRole())
+	created, err := s.service.Add(request.ToEntity())
+	if err != nil {
+		return nil, err
+	}
+	return toRole(created), nil
+}
+
+func (s *roleServer) GetRole(ctx context.Context, req *idmv1.GetRoleRequest) (*idmv1.Role, error) {
+	if err := s.server.require(ctx, web.PermRoleRead); err != nil {
+		return nil, err
+	}
+	found, err := s.service.FindById(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toRole(found), nil
+}
+
+func (s *roleServer) ListRoles(_ *idmv1.ListRolesRequest, stream grpc.ServerStreamingServer[idmv1.Role]) error {
+	if err := s.server.require(stream.Context(), web.PermRoleRead); err != nil {
+		return err
+	}
+	roles, err := s.service.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if err := stream.Send(toRole(r.ToResponse())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *roleServer) UpdateRole(ctx context.Context, req *idmv1.UpdateRoleRequest) (*idmv1.Role, error) {
+	if err := s.server.require(ctx, web.PermRoleWrite); err != nil {
+		return nil, err
+	}
+	updated, err := s.service.Update(ctx, req.GetId(), toRoleRequest(req.GetRole()))
+	if err != nil {
+		return nil, err
+	}
+	return toRole(updated), nil
+}
+
+func (s *roleServer) DeleteRole(ctx context.Context, req *idmv1.DeleteRoleRequest) (*idmv1.Role, error) {
+	if err := s.server.require(ctx, web.PermRoleWrite); err != nil {
+		return nil, err
+	}
+	claims, _ := ClaimsFromCtx(ctx)
+	deleted, err := s.service.DeleteById(ctx, claims.Actor(), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toRole(deleted), nil
+}
+
+func (s *roleServer) ListEffectiveRoles(req *idmv1.ListEffectiveRolesRequest, stream grpc.ServerStreamingServer[idmv1.EffectiveRole]) error {
+	var ctx = stream.Context()
+	if err := s.server.require(ctx, web.PermRoleRead); err != nil {
+		return err
+	}
+	roles, err := s.service.FindEffectiveRoles(ctx, req.GetEmployeeId())
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		var effective = &idmv1.EffectiveRole{Id: r.Id, Name: r.Name, Direct: r.Direct}
+		for _, path := range r.Paths {
+			effective.Paths = append(effective.Paths, &idmv1.RolePath{Roles: path})
+		}
+		if err := stream.Send(effective); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toRoleRequest - необязательные поля сообщения становятся nil-указателями, как отсутствующие поля JSON
+func toRoleRequest(fields *idmv1.RoleFields) role.Request {
+	return role.Request{
+		Name:              fields.GetName(),
+		Description:       fields.GetDescription(),
+		OwnerId:           fields.OwnerId,
+		RiskLevel:         fields.GetRiskLevel(),
+		Requestable:       fields.Requestable,
+		MaxAssignmentDays: fields.MaxAssignmentDays,
+	}
+}
+
+func toRole(r role.Response) *idmv1.Role {
+	return &idmv1.Role{
+		Id:                r.Id,
+		Name:              r.Name,
+		Description:       r.Description,
+		OwnerId:           r.OwnerId,
+		RiskLevel:         r.RiskLevel,
+		Requestable:       r.Requestable,
+		MaxAssignmentDays: r.MaxAssignmentDays,
+		CreatedAt:         timestamppb.New(r.CreatedAt),
+		UpdatedAt:         timestamppb.New(r.UpdatedAt),
+	}
+}
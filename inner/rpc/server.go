@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/rpc/idmv1"
+	"idm/inner/web"
+	"net"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server - gRPC API сотрудников и ролей поверх тех же сервисов, что и REST API
+type Server struct {
+	addr    string
+	grpc    *grpc.Server
+	keyfunc jwt.Keyfunc
+	logger  *common.Logger
+	// Permissions - резолвер разрешений по ролям токена, по умолчанию web.DefaultPermissions
+	Permissions web.PermissionResolver
+	// Delegations - резолвер делегированных администраторов отделов, nil - делегирования нет
+	Delegations web.DelegationResolver
+}
+
+// NewServer - keyfunc проверяет подпись токенов (ключи JWKS Keycloak), options - например, TLS
+func NewServer(addr string, keyfunc jwt.Keyfunc, employees employee.Svc, roles role.Svc, logger *common.Logger,
+	options ...grpc.ServerOption) *Server {
+	var s = &Server{
+		addr:        addr,
+		keyfunc:     keyfunc,
+		logger:      logger,
+		Permissions: web.DefaultPermissions,
+	}
+	options = append(options,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor))
+	s.grpc = grpc.NewServer(options...)
+	idmv1.RegisterEmployeeServiceServer(s.grpc, &employeeServer{server: s, service: employees})
+	idmv1.RegisterRoleServiceServer(s.grpc, &roleServer{server: s, service: roles})
+	return s
+}
+
+// Start - открывает порт и обслуживает вызовы в отдельной горутине
+func (s *Server) Start() {
+	var ln, err = net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Panic("Failed gRPC listen", zap.Error(err))
+	}
+	s.logger.Info("gRPC server listening", zap.String("addr", s.addr))
+	go func() {
+		if err := s.Serve(ln); err != nil {
+			s.logger.Error("gRPC server stopped with error", zap.Error(err))
+		}
+	}()
+}
+
+// Serve - обслуживает вызовы на ln до остановки сервера
+func (s *Server) Serve(ln net.Listener) error {
+	return s.grpc.Serve(ln)
+}
+
+// Stop - перестаёт принимать вызовы и ждёт завершения текущих, в том числе потоков;
+// по истечении ctx обрывает оставшиеся
+func (s *Server) Stop(ctx context.Context) error {
+	var done = make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// toStatus - код gRPC для ошибки сервиса, как errResponse хендлеров для HTTP-статуса
+func (s *Server) toStatus(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var code codes.Code
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		code = codes.InvalidArgument
+	case errors.As(err, &common.AlreadyExistsError{}):
+		code = codes.AlreadyExists
+	case errors.As(err, &common.ForbiddenError{}):
+		code = codes.PermissionDenied
+	case errors.As(err, &common.NotFoundError{}):
+		code = codes.NotFound
+	default:
+		s.logger.ErrorCtx(ctx, method+": error calling service", zap.Error(err))
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
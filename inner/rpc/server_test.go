@@ -0,0 +1,387 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/rpc/idmv1"
+	"idm/inner/web"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var secret = []byte("secret")
+
+type MockEmployeeService struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeService) Add(ctx context.Context, e employee.Entity) (employee.Response, error) {
+	args := m.Called(ctx, e)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) DeleteByIds(ctx context.Context, ids []int64) ([]employee.Response, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) DeleteById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAll(ctx context.Context) ([]employee.Response, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAllWithLimitOffset(ctx context.Context, req employee.PageRequest) (employee.PageResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(employee.PageResponse), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindAllWithLimitOffsetAsOf(ctx context.Context, req employee.PageRequest, asOf time.Time) (employee.PageResponse, error) {
+	args := m.Called(ctx, req, asOf)
+	return args.Get(0).(employee.PageResponse), args.Error(1)
+}
+
+func (m *MockEmployeeService) Move(ctx context.Context, id int64, request employee.MoveRequest) (employee.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindByIdAsOf(ctx context.Context, id int64, asOf time.Time) (employee.Response, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeService) FindHistory(ctx context.Context, id int64) ([]employee.HistoryResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]employee.HistoryResponse), args.Error(1)
+}
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) Add(r role.Entity) (role.Response, error) {
+	args := m.Called(r)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindById(id int64) (role.Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindByIds(ids []int64) ([]role.Response, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) DeleteByIds(ctx context.Context, actor string, ids []int64) ([]role.Response, error) {
+	args := m.Called(ctx, actor, ids)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) DeleteById(ctx context.Context, actor string, id int64) (role.Response, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindAll() ([]role.Entity, error) {
+	args := m.Called()
+	return args.Get(0).([]role.Entity), args.Error(1)
+}
+
+func (m *MockRoleService) AddComposite(ctx context.Context, parentId int64, request role.CompositeRequest) error {
+	return m.Called(ctx, parentId, request).Error(0)
+}
+
+func (m *MockRoleService) DeleteComposite(ctx context.Context, parentId, childId int64) error {
+	return m.Called(ctx, parentId, childId).Error(0)
+}
+
+func (m *MockRoleService) FindComposites(ctx context.Context, parentId int64) ([]role.Response, error) {
+	args := m.Called(ctx, parentId)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleService) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]role.EffectiveRoleResponse), args.Error(1)
+}
+
+func (m *MockRoleService) Update(ctx context.Context, id int64, request role.Request) (role.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+type MockDelegations struct {
+	mock.Mock
+}
+
+func (m *MockDelegations) FindAdministeredDepartments(ctx context.Context, claims *web.IdmClaims) ([]int64, error) {
+	args := m.Called(ctx, claims)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func keyfunc(*jwt.Token) (any, error) {
+	return secret, nil
+}
+
+func startServer(t *testing.T, employees employee.Svc, roles role.Svc) (*Server, *grpc.ClientConn) {
+	var server = NewServer("", keyfunc, employees, roles, &common.Logger{Logger: zap.NewNop()})
+	var ln = bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(ln) }()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = server.Stop(context.Background())
+	})
+	return server, conn
+}
+
+func withToken(t *testing.T, username string, roles ...string) context.Context {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &web.IdmClaims{
+		RealmAccess:       web.RealmAccessClaims{Roles: roles},
+		PreferredUsername: username,
+		RegisteredClaims:  jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(secret)
+	assert.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestEmployeeService(t *testing.T) {
+	t.Run("Should reject calls without a valid token", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		_, conn := startServer(t, svc, new(MockRoleService))
+		client := idmv1.NewEmployeeServiceClient(conn)
+
+		_, err := client.GetEmployee(context.Background(), &idmv1.GetEmployeeRequest{Id: 1})
+		a.Equal(codes.Unauthenticated, status.Code(err))
+
+		var ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+		_, err = client.GetEmployee(ctx, &idmv1.GetEmployeeRequest{Id: 1})
+		a.Equal(codes.Unauthenticated, status.Code(err))
+		svc.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should let IDM_USER read but not create employees", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		_, conn := startServer(t, svc, new(MockRoleService))
+		client := idmv1.NewEmployeeServiceClient(conn)
+		var createdAt = time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC)
+		svc.On("FindById", mock.Anything, int64(1)).
+			Return(employee.Response{Id: 1, Name: "John", Age: 30, Active: true, CreatedAt: createdAt}, nil)
+
+		got, err := client.GetEmployee(withToken(t, "john", web.IdmUser), &idmv1.GetEmployeeRequest{Id: 1})
+		a.NoError(err)
+		a.Equal("John", got.GetName())
+		a.Equal(int32(30), got.GetAge())
+		a.True(got.GetActive())
+		a.Equal(createdAt, got.GetCreatedAt().AsTime())
+
+		_, err = client.CreateEmployee(withToken(t, "john", web.IdmUser), &idmv1.CreateEmployeeRequest{Name: "Jane"})
+		a.Equal(codes.PermissionDenied, status.Code(err))
+		svc.AssertNotCalled(t, "CreateEmployee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should create employee and reject age out of range", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		_, conn := startServer(t, svc, new(MockRoleService))
+		client := idmv1.NewEmployeeServiceClient(conn)
+		svc.On("CreateEmployee", mock.Anything, mock.MatchedBy(func(req employee.CreateRequest) bool {
+			return req.Name == "Jane" && req.Age == 25 && req.DepartmentId == 3 && !req.CreatedAt.IsZero()
+		})).Return(int64(7), nil)
+
+		got, err := client.CreateEmployee(withToken(t, "admin", web.IdmAdmin),
+			&idmv1.CreateEmployeeRequest{Name: "Jane", Age: 25, DepartmentId: 3})
+		a.NoError(err)
+		a.Equal(int64(7), got.GetId())
+
+		_, err = client.CreateEmployee(withToken(t, "admin", web.IdmAdmin),
+			&idmv1.CreateEmployeeRequest{Name: "Jane", Age: 300})
+		a.Equal(codes.InvalidArgument, status.Code(err))
+		svc.AssertNumberOfCalls(t, "CreateEmployee", 1)
+	})
+
+	t.Run("Should limit delegated administrator to own departments", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		server, conn := startServer(t, svc, new(MockRoleService))
+		delegations := new(MockDelegations)
+		server.Delegations = delegations
+		client := idmv1.NewEmployeeServiceClient(conn)
+		delegations.On("FindAdministeredDepartments", mock.Anything, mock.MatchedBy(func(c *web.IdmClaims) bool {
+			return c.PreferredUsername == "head"
+		})).Return([]int64{3}, nil)
+		svc.On("DeleteById", mock.MatchedBy(func(ctx context.Context) bool {
+			scope, ok := employee.ScopeFromCtx(ctx)
+			return ok && scope.DepartmentIds[0] == 3
+		}), int64(5)).Return(employee.Response{Id: 5}, nil)
+
+		got, err := client.DeleteEmployee(withToken(t, "head", web.IdmUser), &idmv1.DeleteEmployeeRequest{Id: 5})
+
+		a.NoError(err)
+		a.Equal(int64(5), got.GetId())
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("Should map service errors to status codes", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		_, conn := startServer(t, svc, new(MockRoleService))
+		client := idmv1.NewEmployeeServiceClient(conn)
+		svc.On("FindById", mock.Anything, int64(1)).Return(employee.Response{}, common.NotFoundError{Message: "employee not found"})
+		svc.On("FindById", mock.Anything, int64(2)).Return(employee.Response{}, errors.New("database error"))
+
+		_, err := client.GetEmployee(withToken(t, "admin", web.IdmAdmin, web.IdmUser), &idmv1.GetEmployeeRequest{Id: 1})
+		a.Equal(codes.NotFound, status.Code(err))
+		a.Equal("employee not found", status.Convert(err).Message())
+
+		_, err = client.GetEmployee(withToken(t, "admin", web.IdmAdmin, web.IdmUser), &idmv1.GetEmployeeRequest{Id: 2})
+		a.Equal(codes.Internal, status.Code(err))
+	})
+
+	t.Run("Should stream all pages of employees", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockEmployeeService)
+		_, conn := startServer(t, svc, new(MockRoleService))
+		client := idmv1.NewEmployeeServiceClient(conn)
+		var asOf = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+		var page = employee.PageRequest{PageSize: 2, TextFilter: "ov"}
+		svc.On("FindAllWithLimitOffsetAsOf", mock.Anything, page, asOf).
+			Return(employee.PageResponse{Result: []employee.Response{{Id: 1}, {Id: 2}}, Total: 3}, nil)
+		page.PageNumber = 1
+		svc.On("FindAllWithLimitOffsetAsOf", mock.Anything, page, asOf).
+			Return(employee.PageResponse{Result: []employee.Response{{Id: 3}}, Total: 3}, nil)
+
+		stream, err := client.ListEmployees(withToken(t, "admin", web.IdmAdmin, web.IdmUser),
+			&idmv1.ListEmployeesRequest{TextFilter: "ov", AsOf: timestamppb.New(asOf), BatchSize: 2})
+		a.NoError(err)
+		var ids []int64
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				a.Equal(io.EOF, err)
+				break
+			}
+			ids = append(ids, e.GetId())
+		}
+
+		a.Equal([]int64{1, 2, 3}, ids)
+		svc.AssertNumberOfCalls(t, "FindAllWithLimitOffsetAsOf", 2)
+	})
+}
+
+func TestRoleService(t *testing.T) {
+	t.Run("Should create role with optional fields", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockRoleService)
+		_, conn := startServer(t, new(MockEmployeeService), svc)
+		client := idmv1.NewRoleServiceClient(conn)
+		var days = int32(30)
+		svc.On("Add", mock.MatchedBy(func(r role.Entity) bool {
+			return r.Name == "auditor" && !r.Requestable && r.MaxAssignmentDays.Valid && r.MaxAssignmentDays.Int32 == days
+		})).Return(role.Response{Id: 4, Name: "auditor", MaxAssignmentDays: &days}, nil)
+
+		var requestable = false
+		got, err := client.CreateRole(withToken(t, "admin", web.IdmAdmin), &idmv1.CreateRoleRequest{
+			Role: &idmv1.RoleFields{Name: "auditor", Requestable: &requestable, MaxAssignmentDays: &days},
+		})
+
+		a.NoError(err)
+		a.Equal(int64(4), got.GetId())
+		a.Equal(days, got.GetMaxAssignmentDays())
+		a.Nil(got.OwnerId)
+
+		_, err = client.CreateRole(withToken(t, "john", web.IdmUser), &idmv1.CreateRoleRequest{})
+		a.Equal(codes.PermissionDenied, status.Code(err))
+		svc.AssertNumberOfCalls(t, "Add", 1)
+	})
+
+	t.Run("Should stream roles and effective roles", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockRoleService)
+		_, conn := startServer(t, new(MockEmployeeService), svc)
+		client := idmv1.NewRoleServiceClient(conn)
+		svc.On("FindAll").Return([]role.Entity{{Id: 1, Name: "admin"}, {Id: 2, Name: "viewer"}}, nil)
+		svc.On("FindEffectiveRoles", mock.Anything, int64(9)).Return([]role.EffectiveRoleResponse{
+			{Id: 2, Name: "viewer", Paths: [][]string{{"admin", "viewer"}}},
+		}, nil)
+		var ctx = withToken(t, "john", web.IdmUser)
+
+		roles, err := client.ListRoles(ctx, &idmv1.ListRolesRequest{})
+		a.NoError(err)
+		first, err := roles.Recv()
+		a.NoError(err)
+		a.Equal("admin", first.GetName())
+		second, err := roles.Recv()
+		a.NoError(err)
+		a.Equal("viewer", second.GetName())
+		_, err = roles.Recv()
+		a.Equal(io.EOF, err)
+
+		effective, err := client.ListEffectiveRoles(ctx, &idmv1.ListEffectiveRolesRequest{EmployeeId: 9})
+		a.NoError(err)
+		got, err := effective.Recv()
+		a.NoError(err)
+		a.False(got.GetDirect())
+		a.Equal([]string{"admin", "viewer"}, got.GetPaths()[0].GetRoles())
+	})
+}
+
+func TestServerStop(t *testing.T) {
+	t.Run("Should stop gracefully and refuse new calls", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockRoleService)
+		server, conn := startServer(t, new(MockEmployeeService), svc)
+		client := idmv1.NewRoleServiceClient(conn)
+
+		a.NoError(server.Stop(context.Background()))
+
+		_, err := client.GetRole(withToken(t, "admin", web.IdmAdmin), &idmv1.GetRoleRequest{Id: 1})
+		a.Equal(codes.Unavailable, status.Code(err))
+	})
+}
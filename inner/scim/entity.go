@@ -0,0 +1,235 @@
+package scim
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"strconv"
+	"time"
+)
+
+// Схемы SCIM 2.0 (RFC 7643, RFC 7644)
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	// SchemaEmployee - расширение пользователя атрибутами сотрудника, которых нет в core-схеме
+	SchemaEmployee = "urn:idm:params:scim:schemas:extension:employee:2.0:User"
+)
+
+// ContentType - медиатип запросов и ответов SCIM
+const ContentType = "application/scim+json"
+
+// Типы ресурсов
+const (
+	ResourceUser  = "User"
+	ResourceGroup = "Group"
+)
+
+// Значения scimType ошибок (RFC 7644, 3.12)
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrNoTarget      = "noTarget"
+	ErrMutability    = "mutability"
+	ErrUniqueness    = "uniqueness"
+)
+
+// Постраничная выдача: по умолчанию и не больше MaxCount ресурсов
+const (
+	DefaultCount = 100
+	MaxCount     = 200
+)
+
+// Error - ошибка протокола SCIM со статусом и scimType
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (err Error) Error() string {
+	return err.Detail
+}
+
+// ErrorResponse - тело ответа с ошибкой; status по RFC передаётся строкой
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue - элемент многозначного атрибута (emails, phoneNumbers). Сотрудник хранит
+// одно значение, поэтому оно всегда основное
+type MultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Reference - ссылка на ресурс: группа пользователя или участник группы
+type Reference struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// EmployeeExtension - атрибуты расширения SchemaEmployee
+type EmployeeExtension struct {
+	Age          int8  `json:"age,omitempty"`
+	DepartmentId int64 `json:"departmentId,omitempty"`
+	ManagerId    int64 `json:"managerId,omitempty"`
+}
+
+// User - пользователь SCIM, отображается на сотрудника: userName - login, name - имя и фамилия,
+// title - должность. Группы пользователя только для чтения и меняются через Groups
+type User struct {
+	Schemas      []string           `json:"schemas"`
+	Id           string             `json:"id,omitempty"`
+	UserName     string             `json:"userName"`
+	Name         *Name              `json:"name,omitempty"`
+	DisplayName  string             `json:"displayName,omitempty"`
+	Title        string             `json:"title,omitempty"`
+	Active       *bool              `json:"active,omitempty"`
+	Emails       []MultiValue       `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue       `json:"phoneNumbers,omitempty"`
+	Groups       []Reference        `json:"groups,omitempty"`
+	Employee     *EmployeeExtension `json:"urn:idm:params:scim:schemas:extension:employee:2.0:User,omitempty"`
+	Meta         *Meta              `json:"meta,omitempty"`
+}
+
+// Group - группа SCIM, отображается на роль; участники - сотрудники с действующим назначением роли
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	Id          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+type ListResponse[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// ListRequest - параметры выборки: фильтр и страница, startIndex считается с 1
+type ListRequest struct {
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Membership - действующее назначение роли сотруднику с данными для ссылок в обе стороны
+type Membership struct {
+	RoleId          int64          `db:"role_id"`
+	RoleName        string         `db:"role_name"`
+	EmployeeId      int64          `db:"employee_id"`
+	EmployeeName    string         `db:"employee_name"`
+	EmployeeSurname string         `db:"employee_surname"`
+	EmployeeLogin   sql.NullString `db:"employee_login"`
+}
+
+// Query - выборка ресурсов: условие фильтра с позиционными аргументами и страница
+type Query struct {
+	Where  string
+	Args   []any
+	Limit  int
+	Offset int
+}
+
+func toUser(entity employee.Entity, groups []Membership) User {
+	var active = entity.Active
+	var user = User{
+		Schemas:  []string{SchemaUser, SchemaEmployee},
+		Id:       strconv.FormatInt(entity.Id, 10),
+		UserName: entity.Login.String,
+		Name: &Name{
+			Formatted:  fmt.Sprintf("%s %s", entity.Name, entity.Surname),
+			GivenName:  entity.Name,
+			FamilyName: entity.Surname,
+		},
+		DisplayName: fmt.Sprintf("%s %s", entity.Name, entity.Surname),
+		Title:       entity.Position,
+		Active:      &active,
+		Employee: &EmployeeExtension{
+			Age:          entity.Age,
+			DepartmentId: entity.DepartmentId.Int64,
+			ManagerId:    entity.ManagerId.Int64,
+		},
+		Meta: &Meta{
+			ResourceType: ResourceUser,
+			Created:      entity.CreatedAt,
+			LastModified: entity.UpdatedAt,
+		},
+	}
+	if entity.Email != "" {
+		user.Emails = []MultiValue{{Value: entity.Email, Type: "work", Primary: true}}
+	}
+	if entity.Phone != "" {
+		user.PhoneNumbers = []MultiValue{{Value: entity.Phone, Type: "work", Primary: true}}
+	}
+	for _, group := range groups {
+		user.Groups = append(user.Groups, Reference{
+			Value:   strconv.FormatInt(group.RoleId, 10),
+			Display: group.RoleName,
+		})
+	}
+	return user
+}
+
+func toGroup(entity role.Entity, members []Membership) Group {
+	var group = Group{
+		Schemas:     []string{SchemaGroup},
+		Id:          strconv.FormatInt(entity.Id, 10),
+		DisplayName: entity.Name,
+		Meta: &Meta{
+			ResourceType: ResourceGroup,
+			Created:      entity.CreatedAt,
+			LastModified: entity.UpdatedAt,
+		},
+	}
+	for _, member := range members {
+		group.Members = append(group.Members, Reference{
+			Value:   strconv.FormatInt(member.EmployeeId, 10),
+			Display: fmt.Sprintf("%s %s", member.EmployeeName, member.EmployeeSurname),
+		})
+	}
+	return group
+}
@@ -0,0 +1,466 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter - разобранное выражение фильтра SCIM (RFC 7644, 3.4.2.2)
+type Filter interface {
+	filter()
+}
+
+// Comparison - сравнение атрибута со значением; для оператора pr значение не задаётся.
+// Value - string, float64, bool или nil
+type Comparison struct {
+	Attr  string
+	Op    string
+	Value any
+}
+
+// Logical - and/or двух выражений
+type Logical struct {
+	Op    string
+	Left  Filter
+	Right Filter
+}
+
+// Not - отрицание выражения в скобках
+type Not struct {
+	Filter Filter
+}
+
+func (Comparison) filter() {}
+func (Logical) filter()    {}
+func (Not) filter()        {}
+
+var compareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+// ParseFilter - разбирает фильтр. Операторы и имена атрибутов регистронезависимы,
+// имена приводятся к нижнему регистру; and связывает сильнее or
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	var p = parser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, filterError("unexpected %q", p.peek().text)
+	}
+	return filter, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		switch c := input[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(input) && input[end] != '"'; end++ {
+				if input[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(input) {
+				return nil, filterError("unterminated string at %d", i)
+			}
+			var value string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &value); err != nil {
+				return nil, filterError("invalid string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && !strings.ContainsRune(" \t()\"", rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[i:end]})
+			i = end
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, filterError("empty filter")
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() (token, error) {
+	if p.done() {
+		return token{}, filterError("unexpected end of filter")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// keyword - следующий токен - слово word без учёта регистра
+func (p *parser) keyword(word string) bool {
+	return !p.done() && p.peek().kind == tokenWord && strings.EqualFold(p.peek().text, word)
+}
+
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	if p.keyword("not") {
+		p.pos++
+		if p.done() || p.peek().kind != tokenOpen {
+			return nil, filterError("expected ( after not")
+		}
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Filter: inner}, nil
+	}
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.kind {
+	case tokenOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, err := p.next(); err != nil || closing.kind != tokenClose {
+			return nil, filterError("expected )")
+		}
+		return inner, nil
+	case tokenWord:
+		return p.parseComparison(tok.text)
+	default:
+		return nil, filterError("expected attribute, got %q", tok.text)
+	}
+}
+
+func (p *parser) parseComparison(attr string) (Filter, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var op = strings.ToLower(tok.text)
+	if tok.kind != tokenWord || !compareOps[op] {
+		return nil, filterError("unknown operator %q", tok.text)
+	}
+	var comparison = Comparison{Attr: strings.ToLower(attr), Op: op}
+	if op == "pr" {
+		return comparison, nil
+	}
+	tok, err = p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tok.kind == tokenString:
+		comparison.Value = tok.text
+	case tok.kind != tokenWord:
+		return nil, filterError("expected value, got %q", tok.text)
+	case tok.text == "true" || tok.text == "false":
+		comparison.Value = tok.text == "true"
+	case tok.text == "null":
+		comparison.Value = nil
+	default:
+		number, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, filterError("invalid value %q", tok.text)
+		}
+		comparison.Value = number
+	}
+	return comparison, nil
+}
+
+func filterError(format string, args ...any) Error {
+	return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidFilter, Detail: fmt.Sprintf(format, args...)}
+}
+
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInt
+	kindBool
+	kindTime
+)
+
+// column - SQL-выражение, на которое отображается атрибут SCIM
+type column struct {
+	expr string
+	kind columnKind
+}
+
+var userColumns = map[string]column{
+	"id":                 {"id", kindInt},
+	"username":           {"login", kindString},
+	"name.givenname":     {"name", kindString},
+	"name.familyname":    {"surname", kindString},
+	"name.formatted":     {"(name || ' ' || surname)", kindString},
+	"displayname":        {"(name || ' ' || surname)", kindString},
+	"title":              {"position", kindString},
+	"active":             {"active", kindBool},
+	"emails":             {"email", kindString},
+	"emails.value":       {"email", kindString},
+	"phonenumbers":       {"phone", kindString},
+	"phonenumbers.value": {"phone", kindString},
+	"meta.created":       {"created_at", kindTime},
+	"meta.lastmodified":  {"updated_at", kindTime},
+
+	// атрибуты расширения указываются с URN схемы
+	strings.ToLower(SchemaEmployee) + ":age":          {"age", kindInt},
+	strings.ToLower(SchemaEmployee) + ":departmentid": {"department_id", kindInt},
+	strings.ToLower(SchemaEmployee) + ":managerid":    {"manager_id", kindInt},
+}
+
+var groupColumns = map[string]column{
+	"id":                {"id", kindInt},
+	"displayname":       {"name", kindString},
+	"meta.created":      {"created_at", kindTime},
+	"meta.lastmodified": {"updated_at", kindTime},
+}
+
+// whereBuilder - переводит фильтр в условие WHERE с аргументами $1..$n
+type whereBuilder struct {
+	columns map[string]column
+	schema  string
+	args    []any
+}
+
+// buildWhere - условие для фильтра input по атрибутам columns; пустой фильтр - без условия
+func buildWhere(input string, schema string, columns map[string]column) (string, []any, error) {
+	if strings.TrimSpace(input) == "" {
+		return "TRUE", nil, nil
+	}
+	filter, err := ParseFilter(input)
+	if err != nil {
+		return "", nil, err
+	}
+	var b = whereBuilder{columns: columns, schema: strings.ToLower(schema) + ":"}
+	where, err := b.build(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, b.args, nil
+}
+
+func (b *whereBuilder) arg(value any) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *whereBuilder) build(filter Filter) (string, error) {
+	switch f := filter.(type) {
+	case Logical:
+		left, err := b.build(f.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.build(f.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(f.Op), right), nil
+	case Not:
+		inner, err := b.build(f.Filter)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", inner), nil
+	case Comparison:
+		return b.compare(f)
+	default:
+		return "", filterError("unsupported filter")
+	}
+}
+
+func (b *whereBuilder) compare(f Comparison) (string, error) {
+	col, ok := b.columns[strings.TrimPrefix(f.Attr, b.schema)]
+	if !ok {
+		return "", filterError("unsupported attribute %q", f.Attr)
+	}
+	if f.Op == "pr" {
+		if col.kind == kindString {
+			return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", col.expr, col.expr), nil
+		}
+		return col.expr + " IS NOT NULL", nil
+	}
+	value, err := col.convert(f)
+	if err != nil {
+		return "", err
+	}
+	if col.kind == kindString {
+		switch f.Op {
+		case "eq":
+			return fmt.Sprintf("lower(%s) = lower(%s)", col.expr, b.arg(value)), nil
+		case "ne":
+			return fmt.Sprintf("lower(%s) IS DISTINCT FROM lower(%s)", col.expr, b.arg(value)), nil
+		case "co":
+			return fmt.Sprintf("%s ILIKE %s", col.expr, b.arg("%"+escapeLike(value.(string))+"%")), nil
+		case "sw":
+			return fmt.Sprintf("%s ILIKE %s", col.expr, b.arg(escapeLike(value.(string))+"%")), nil
+		case "ew":
+			return fmt.Sprintf("%s ILIKE %s", col.expr, b.arg("%"+escapeLike(value.(string)))), nil
+		}
+	}
+	switch f.Op {
+	case "eq":
+		return fmt.Sprintf("%s = %s", col.expr, b.arg(value)), nil
+	case "ne":
+		return fmt.Sprintf("%s IS DISTINCT FROM %s", col.expr, b.arg(value)), nil
+	case "gt", "ge", "lt", "le":
+		if col.kind == kindBool {
+			break
+		}
+		var ops = map[string]string{"gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+		return fmt.Sprintf("%s %s %s", col.expr, ops[f.Op], b.arg(value)), nil
+	}
+	return "", filterError("operator %s is not supported for %q", f.Op, f.Attr)
+}
+
+// convert - значение фильтра в типе колонки
+func (col column) convert(f Comparison) (any, error) {
+	switch col.kind {
+	case kindString:
+		if value, ok := f.Value.(string); ok {
+			return value, nil
+		}
+	case kindInt:
+		switch value := f.Value.(type) {
+		case string:
+			if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return number, nil
+			}
+		case float64:
+			if value == float64(int64(value)) {
+				return int64(value), nil
+			}
+		}
+	case kindBool:
+		if value, ok := f.Value.(bool); ok {
+			return value, nil
+		}
+	case kindTime:
+		if value, ok := f.Value.(string); ok {
+			if moment, err := time.Parse(time.RFC3339, value); err == nil {
+				return moment, nil
+			}
+		}
+	}
+	return nil, filterError("invalid value %v for %q", f.Value, f.Attr)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// Path - путь операции PATCH: attr[filter].sub. Атрибуты расширения указываются с URN схемы
+type Path struct {
+	Attr   string
+	Filter Filter
+	Sub    string
+}
+
+// ParsePath - разбирает путь PATCH; имена приводятся к нижнему регистру, URN core-схемы schema
+// отбрасывается
+func ParsePath(input string, schema string) (Path, error) {
+	var path Path
+	var rest = strings.ToLower(strings.TrimSpace(input))
+	rest = strings.TrimPrefix(rest, strings.ToLower(schema)+":")
+	if open := strings.IndexByte(input, '['); open >= 0 {
+		closing := strings.LastIndexByte(input, ']')
+		if closing < open {
+			return Path{}, Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("invalid path %q", input)}
+		}
+		filter, err := ParseFilter(input[open+1 : closing])
+		if err != nil {
+			return Path{}, Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: err.Error()}
+		}
+		path.Filter = filter
+		var prefix = strings.TrimPrefix(strings.ToLower(input[:open]), strings.ToLower(schema)+":")
+		rest = prefix + strings.ToLower(input[closing+1:])
+	}
+	if !strings.HasPrefix(rest, "urn:") {
+		if dot := strings.IndexByte(rest, '.'); dot >= 0 {
+			rest, path.Sub = rest[:dot], rest[dot+1:]
+		}
+	}
+	if rest == "" {
+		return Path{}, Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("invalid path %q", input)}
+	}
+	path.Attr = rest
+	return path, nil
+}
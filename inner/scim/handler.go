@@ -0,0 +1,365 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	FindUsers(ctx context.Context, request ListRequest) (ListResponse[User], error)
+	FindUser(ctx context.Context, id string) (User, error)
+	CreateUser(ctx context.Context, user User) (User, error)
+	ReplaceUser(ctx context.Context, id string, user User) (User, error)
+	PatchUser(ctx context.Context, id string, request PatchRequest) (User, error)
+	DeleteUser(ctx context.Context, id string) error
+	FindGroups(ctx context.Context, request ListRequest) (ListResponse[Group], error)
+	FindGroup(ctx context.Context, id string) (Group, error)
+	CreateGroup(ctx context.Context, actor string, group Group) (Group, error)
+	ReplaceGroup(ctx context.Context, actor string, id string, group Group) (Group, error)
+	PatchGroup(ctx context.Context, actor string, id string, request PatchRequest) (Group, error)
+	DeleteGroup(ctx context.Context, actor string, id string) error
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/scim/v2". Документы обнаружения доступны любому
+// аутентифицированному клиенту
+func (c *Handler) RegisterRoutes() {
+	var read = c.requirePermission(web.PermScimRead)
+	var write = c.requirePermission(web.PermScimWrite)
+	var group = c.server.GroupScim
+	group.Get("/ServiceProviderConfig", c.ServiceProviderConfig)
+	group.Get("/ResourceTypes", c.ResourceTypes)
+	group.Get("/ResourceTypes/:id", c.ResourceTypes)
+	group.Get("/Schemas", c.Schemas)
+	group.Get("/Schemas/:id", c.Schemas)
+	group.Get("/Users", read, c.FindUsers)
+	group.Get("/Users/:id", read, c.FindUser)
+	group.Post("/Users", write, c.CreateUser)
+	group.Put("/Users/:id", write, c.ReplaceUser)
+	group.Patch("/Users/:id", write, c.PatchUser)
+	group.Delete("/Users/:id", write, c.DeleteUser)
+	group.Get("/Groups", read, c.FindGroups)
+	group.Get("/Groups/:id", read, c.FindGroup)
+	group.Post("/Groups", write, c.CreateGroup)
+	group.Put("/Groups/:id", write, c.ReplaceGroup)
+	group.Patch("/Groups/:id", write, c.PatchGroup)
+	group.Delete("/Groups/:id", write, c.DeleteGroup)
+}
+
+// ServiceProviderConfig - возможности сервера
+func (c *Handler) ServiceProviderConfig(ctx *fiber.Ctx) error {
+	var config = NewServiceProviderConfig()
+	config.Meta.Location = c.location(ctx, "ServiceProviderConfig", "")
+	return c.send(ctx, fiber.StatusOK, config)
+}
+
+// ResourceTypes - типы ресурсов: все или один по идентификатору
+func (c *Handler) ResourceTypes(ctx *fiber.Ctx) error {
+	var resourceTypes = NewResourceTypes()
+	for i := range resourceTypes {
+		resourceTypes[i].Meta.Location = c.location(ctx, "ResourceTypes", resourceTypes[i].Id)
+	}
+	return sendDiscovery(c, ctx, resourceTypes, func(resourceType ResourceType) string { return resourceType.Id })
+}
+
+// Schemas - схемы атрибутов: все или одна по URN
+func (c *Handler) Schemas(ctx *fiber.Ctx) error {
+	var schemas = NewSchemas()
+	for i := range schemas {
+		schemas[i].Meta.Location = c.location(ctx, "Schemas", schemas[i].Id)
+	}
+	return sendDiscovery(c, ctx, schemas, func(schema Schema) string { return schema.Id })
+}
+
+// FindUsers - пользователи по фильтру filter, страница startIndex/count
+func (c *Handler) FindUsers(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindUsers(ctx.Context(), listRequest(ctx))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindUsers: error finding scim users", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	for i := range rsl.Resources {
+		c.locateUser(ctx, &rsl.Resources[i])
+	}
+	return c.send(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) FindUser(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindUser(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindUser: error finding scim user", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendUser(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) CreateUser(ctx *fiber.Ctx) error {
+	var user User
+	if err := ctx.BodyParser(&user); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "CreateUser: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.CreateUser(ctx.Context(), user)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "CreateUser: error creating scim user", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendUser(ctx, fiber.StatusCreated, rsl)
+}
+
+func (c *Handler) ReplaceUser(ctx *fiber.Ctx) error {
+	var user User
+	if err := ctx.BodyParser(&user); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "ReplaceUser: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.ReplaceUser(ctx.Context(), ctx.Params("id"), user)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "ReplaceUser: error replacing scim user", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendUser(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) PatchUser(ctx *fiber.Ctx) error {
+	var request PatchRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "PatchUser: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.PatchUser(ctx.Context(), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "PatchUser: error patching scim user", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendUser(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) DeleteUser(ctx *fiber.Ctx) error {
+	if err := c.service.DeleteUser(ctx.Context(), ctx.Params("id")); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteUser: error deleting scim user", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// FindGroups - группы по фильтру filter, страница startIndex/count
+func (c *Handler) FindGroups(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindGroups(ctx.Context(), listRequest(ctx))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindGroups: error finding scim groups", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	for i := range rsl.Resources {
+		c.locateGroup(ctx, &rsl.Resources[i])
+	}
+	return c.send(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) FindGroup(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindGroup(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindGroup: error finding scim group", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendGroup(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) CreateGroup(ctx *fiber.Ctx) error {
+	var group Group
+	if err := ctx.BodyParser(&group); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "CreateGroup: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.CreateGroup(ctx.Context(), actor(ctx), group)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "CreateGroup: error creating scim group", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendGroup(ctx, fiber.StatusCreated, rsl)
+}
+
+func (c *Handler) ReplaceGroup(ctx *fiber.Ctx) error {
+	var group Group
+	if err := ctx.BodyParser(&group); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "ReplaceGroup: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.ReplaceGroup(ctx.Context(), actor(ctx), ctx.Params("id"), group)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "ReplaceGroup: error replacing scim group", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendGroup(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) PatchGroup(ctx *fiber.Ctx) error {
+	var request PatchRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "PatchGroup: error body parse", zap.Error(err))
+		return c.syntaxError(ctx, err)
+	}
+	rsl, err := c.service.PatchGroup(ctx.Context(), actor(ctx), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "PatchGroup: error patching scim group", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return c.sendGroup(ctx, fiber.StatusOK, rsl)
+}
+
+func (c *Handler) DeleteGroup(ctx *fiber.Ctx) error {
+	if err := c.service.DeleteGroup(ctx.Context(), actor(ctx), ctx.Params("id")); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteGroup: error deleting scim group", zap.Error(err))
+		return c.errResponse(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// requirePermission - как web.RequirePermission, но отказ в формате ошибки SCIM
+func (c *Handler) requirePermission(permission string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		allowed, err := c.server.HasPermission(ctx, permission)
+		if err != nil {
+			c.logger.ErrorCtx(ctx.Context(), "scim: error resolving permissions", zap.Error(err))
+			return c.sendError(ctx, Error{Status: fiber.StatusInternalServerError, Detail: "Error resolving permissions"})
+		}
+		if !allowed {
+			return c.sendError(ctx, Error{Status: fiber.StatusForbidden, Detail: "Permission denied"})
+		}
+		return ctx.Next()
+	}
+}
+
+// listRequest - параметры выборки из query; без count отдаётся DefaultCount ресурсов
+func listRequest(ctx *fiber.Ctx) ListRequest {
+	return ListRequest{
+		Filter:     ctx.Query("filter"),
+		StartIndex: ctx.QueryInt("startIndex", 1),
+		Count:      ctx.QueryInt("count", DefaultCount),
+	}
+}
+
+func actor(ctx *fiber.Ctx) string {
+	if claims, ok := web.ClaimsFromCtx(ctx); ok {
+		return claims.Actor()
+	}
+	return ""
+}
+
+func (c *Handler) location(ctx *fiber.Ctx, endpoint string, id string) string {
+	var location = ctx.BaseURL() + "/scim/v2/" + endpoint
+	if id != "" {
+		location += "/" + id
+	}
+	return location
+}
+
+func (c *Handler) locateUser(ctx *fiber.Ctx, user *User) {
+	if user.Meta != nil {
+		user.Meta.Location = c.location(ctx, "Users", user.Id)
+	}
+	for i := range user.Groups {
+		user.Groups[i].Ref = c.location(ctx, "Groups", user.Groups[i].Value)
+	}
+}
+
+func (c *Handler) locateGroup(ctx *fiber.Ctx, group *Group) {
+	if group.Meta != nil {
+		group.Meta.Location = c.location(ctx, "Groups", group.Id)
+	}
+	for i := range group.Members {
+		group.Members[i].Ref = c.location(ctx, "Users", group.Members[i].Value)
+	}
+}
+
+func (c *Handler) sendUser(ctx *fiber.Ctx, status int, user User) error {
+	c.locateUser(ctx, &user)
+	if user.Meta != nil {
+		ctx.Set(fiber.HeaderLocation, user.Meta.Location)
+	}
+	return c.send(ctx, status, user)
+}
+
+func (c *Handler) sendGroup(ctx *fiber.Ctx, status int, group Group) error {
+	c.locateGroup(ctx, &group)
+	if group.Meta != nil {
+		ctx.Set(fiber.HeaderLocation, group.Meta.Location)
+	}
+	return c.send(ctx, status, group)
+}
+
+// sendDiscovery - список документов или один документ по :id
+func sendDiscovery[T any](c *Handler, ctx *fiber.Ctx, documents []T, id func(T) string) error {
+	if ctx.Params("id") == "" {
+		return c.send(ctx, fiber.StatusOK, ListResponse[T]{
+			Schemas:      []string{SchemaListResponse},
+			TotalResults: int64(len(documents)),
+			StartIndex:   1,
+			ItemsPerPage: len(documents),
+			Resources:    documents,
+		})
+	}
+	for _, document := range documents {
+		if id(document) == ctx.Params("id") {
+			return c.send(ctx, fiber.StatusOK, document)
+		}
+	}
+	return c.sendError(ctx, Error{Status: fiber.StatusNotFound, Detail: "Resource " + ctx.Params("id") + " not found"})
+}
+
+func (c *Handler) send(ctx *fiber.Ctx, status int, body any) error {
+	return ctx.Status(status).JSON(body, ContentType)
+}
+
+func (c *Handler) sendError(ctx *fiber.Ctx, err Error) error {
+	return c.send(ctx, err.Status, ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(err.Status),
+		ScimType: err.ScimType,
+		Detail:   err.Detail,
+	})
+}
+
+func (c *Handler) syntaxError(ctx *fiber.Ctx, err error) error {
+	return c.sendError(ctx, Error{Status: fiber.StatusBadRequest, ScimType: ErrInvalidSyntax, Detail: err.Error()})
+}
+
+func (c *Handler) errResponse(ctx *fiber.Ctx, err error) error {
+	var scimErr Error
+	switch {
+	case errors.As(err, &scimErr):
+		return c.sendError(ctx, scimErr)
+	case errors.As(err, &common.RequestValidationError{}):
+		return c.sendError(ctx, Error{Status: fiber.StatusBadRequest, ScimType: ErrInvalidValue, Detail: err.Error()})
+	case errors.As(err, &common.AlreadyExistsError{}):
+		return c.sendError(ctx, Error{Status: fiber.StatusConflict, ScimType: ErrUniqueness, Detail: err.Error()})
+	case errors.As(err, &common.NotFoundError{}):
+		return c.sendError(ctx, Error{Status: fiber.StatusNotFound, Detail: err.Error()})
+	case errors.As(err, &common.ForbiddenError{}):
+		return c.sendError(ctx, Error{Status: fiber.StatusForbidden, Detail: err.Error()})
+	case errors.As(err, &sod.ViolationError{}):
+		return c.sendError(ctx, Error{Status: fiber.StatusConflict, Detail: err.Error()})
+	default:
+		return c.sendError(ctx, Error{Status: fiber.StatusInternalServerError, Detail: err.Error()})
+	}
+}
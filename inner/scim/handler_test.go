@@ -0,0 +1,283 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/sod"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindUsers(ctx context.Context, request ListRequest) (ListResponse[User], error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(ListResponse[User]), args.Error(1)
+}
+
+func (svc *MockService) FindUser(ctx context.Context, id string) (User, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) CreateUser(ctx context.Context, user User) (User, error) {
+	args := svc.Called(ctx, user)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) ReplaceUser(ctx context.Context, id string, user User) (User, error) {
+	args := svc.Called(ctx, id, user)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) PatchUser(ctx context.Context, id string, request PatchRequest) (User, error) {
+	args := svc.Called(ctx, id, request)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) DeleteUser(ctx context.Context, id string) error {
+	args := svc.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (svc *MockService) FindGroups(ctx context.Context, request ListRequest) (ListResponse[Group], error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(ListResponse[Group]), args.Error(1)
+}
+
+func (svc *MockService) FindGroup(ctx context.Context, id string) (Group, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) CreateGroup(ctx context.Context, actor string, group Group) (Group, error) {
+	args := svc.Called(ctx, actor, group)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) ReplaceGroup(ctx context.Context, actor string, id string, group Group) (Group, error) {
+	args := svc.Called(ctx, actor, id, group)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) PatchGroup(ctx context.Context, actor string, id string, request PatchRequest) (Group, error) {
+	args := svc.Called(ctx, actor, id, request)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) DeleteGroup(ctx context.Context, actor string, id string) error {
+	args := svc.Called(ctx, actor, id)
+	return args.Error(0)
+}
+
+func newTestServer(roles []string, svc Svc) *web.Server {
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := web.NewServer()
+	claims := &web.IdmClaims{
+		RealmAccess:       web.RealmAccessClaims{Roles: roles},
+		PreferredUsername: "okta",
+	}
+	server.GroupScim.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	NewHandler(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestHandlerConformance(t *testing.T) {
+	var user = User{Schemas: []string{SchemaUser}, Id: "7", UserName: "jdoe", Meta: &Meta{ResourceType: ResourceUser}}
+	var group = Group{Schemas: []string{SchemaGroup}, Id: "3", DisplayName: "LEDGER",
+		Members: []Reference{{Value: "7"}}, Meta: &Meta{ResourceType: ResourceGroup}}
+	var patch = `{"schemas":["` + SchemaPatchOp + `"],"Operations":[{"op":"remove","path":"title"}]}`
+	tests := []struct {
+		name     string
+		roles    []string
+		method   string
+		target   string
+		body     string
+		setup    func(svc *MockService)
+		status   int
+		scimType string
+		// contains - фрагменты тела ответа
+		contains []string
+		location string
+	}{
+		{
+			name: "service provider config", method: fiber.MethodGet, target: "/scim/v2/ServiceProviderConfig",
+			status:   http.StatusOK,
+			contains: []string{SchemaServiceProviderConfig, `"patch":{"supported":true}`, `"maxResults":200`},
+		},
+		{
+			name: "resource types", method: fiber.MethodGet, target: "/scim/v2/ResourceTypes",
+			status:   http.StatusOK,
+			contains: []string{SchemaListResponse, `"totalResults":2`, `"Resources":[`, `"endpoint":"/Users"`},
+		},
+		{
+			name: "schema by urn", method: fiber.MethodGet, target: "/scim/v2/Schemas/" + SchemaEmployee,
+			status:   http.StatusOK,
+			contains: []string{`"id":"` + SchemaEmployee + `"`, `"name":"age"`},
+		},
+		{
+			name: "unknown resource type", method: fiber.MethodGet, target: "/scim/v2/ResourceTypes/Device",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "list users with paging", method: fiber.MethodGet,
+			target: "/scim/v2/Users?filter=userName%20eq%20%22jdoe%22&startIndex=3&count=5",
+			setup: func(svc *MockService) {
+				svc.On("FindUsers", mock.Anything, ListRequest{Filter: `userName eq "jdoe"`, StartIndex: 3, Count: 5}).
+					Return(ListResponse[User]{Schemas: []string{SchemaListResponse}, TotalResults: 1, StartIndex: 3,
+						ItemsPerPage: 1, Resources: []User{user}}, nil)
+			},
+			status:   http.StatusOK,
+			contains: []string{`"startIndex":3`, `"location":"http://example.com/scim/v2/Users/7"`},
+		},
+		{
+			name: "list users with default paging", method: fiber.MethodGet, target: "/scim/v2/Users",
+			setup: func(svc *MockService) {
+				svc.On("FindUsers", mock.Anything, ListRequest{StartIndex: 1, Count: DefaultCount}).
+					Return(ListResponse[User]{Schemas: []string{SchemaListResponse}, Resources: []User{}}, nil)
+			},
+			status:   http.StatusOK,
+			contains: []string{`"Resources":[]`},
+		},
+		{
+			name: "invalid filter", method: fiber.MethodGet, target: "/scim/v2/Users?filter=userName%20zz%20%22x%22",
+			setup: func(svc *MockService) {
+				svc.On("FindUsers", mock.Anything, mock.Anything).Return(ListResponse[User]{},
+					Error{Status: http.StatusBadRequest, ScimType: ErrInvalidFilter, Detail: "unknown operator"})
+			},
+			status: http.StatusBadRequest, scimType: ErrInvalidFilter,
+		},
+		{
+			name: "user not found", method: fiber.MethodGet, target: "/scim/v2/Users/9",
+			setup: func(svc *MockService) {
+				svc.On("FindUser", mock.Anything, "9").Return(User{}, common.NotFoundError{Message: "not found"})
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name: "create user", method: fiber.MethodPost, target: "/scim/v2/Users",
+			body: `{"schemas":["` + SchemaUser + `"],"userName":"jdoe"}`,
+			setup: func(svc *MockService) {
+				svc.On("CreateUser", mock.Anything, User{Schemas: []string{SchemaUser}, UserName: "jdoe"}).Return(user, nil)
+			},
+			status: http.StatusCreated, location: "http://example.com/scim/v2/Users/7",
+			contains: []string{`"userName":"jdoe"`},
+		},
+		{
+			name: "malformed body", method: fiber.MethodPost, target: "/scim/v2/Users", body: `{"userName":`,
+			status: http.StatusBadRequest, scimType: ErrInvalidSyntax,
+		},
+		{
+			name: "invalid value", method: fiber.MethodPost, target: "/scim/v2/Users", body: `{}`,
+			setup: func(svc *MockService) {
+				svc.On("CreateUser", mock.Anything, mock.Anything).
+					Return(User{}, common.RequestValidationError{Message: "userName is required"})
+			},
+			status: http.StatusBadRequest, scimType: ErrInvalidValue,
+		},
+		{
+			name: "duplicated userName", method: fiber.MethodPost, target: "/scim/v2/Users", body: `{"userName":"jdoe"}`,
+			setup: func(svc *MockService) {
+				svc.On("CreateUser", mock.Anything, mock.Anything).
+					Return(User{}, common.AlreadyExistsError{Message: "userName is taken"})
+			},
+			status: http.StatusConflict, scimType: ErrUniqueness,
+		},
+		{
+			name: "patch user", method: fiber.MethodPatch, target: "/scim/v2/Users/7", body: patch,
+			setup: func(svc *MockService) {
+				svc.On("PatchUser", mock.Anything, "7", PatchRequest{Schemas: []string{SchemaPatchOp},
+					Operations: []PatchOperation{{Op: "remove", Path: "title"}}}).Return(user, nil)
+			},
+			status: http.StatusOK, location: "http://example.com/scim/v2/Users/7",
+		},
+		{
+			name: "delete user", method: fiber.MethodDelete, target: "/scim/v2/Users/7",
+			setup:  func(svc *MockService) { svc.On("DeleteUser", mock.Anything, "7").Return(nil) },
+			status: http.StatusNoContent,
+		},
+		{
+			name: "patch group as token actor", method: fiber.MethodPatch, target: "/scim/v2/Groups/3", body: patch,
+			setup: func(svc *MockService) {
+				svc.On("PatchGroup", mock.Anything, "okta", "3", mock.Anything).Return(group, nil)
+			},
+			status: http.StatusOK, location: "http://example.com/scim/v2/Groups/3",
+			contains: []string{`"$ref":"http://example.com/scim/v2/Users/7"`},
+		},
+		{
+			name: "separation of duties conflict", method: fiber.MethodPatch, target: "/scim/v2/Groups/3", body: patch,
+			setup: func(svc *MockService) {
+				svc.On("PatchGroup", mock.Anything, "okta", "3", mock.Anything).Return(Group{}, sod.ViolationError{})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			name: "read without permission", roles: []string{web.IdmUser}, method: fiber.MethodGet, target: "/scim/v2/Groups",
+			status: http.StatusForbidden,
+		},
+		{
+			name: "write without permission", roles: []string{web.IdmUser}, method: fiber.MethodDelete, target: "/scim/v2/Groups/3",
+			status: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			svc := new(MockService)
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			var roles = tt.roles
+			if roles == nil {
+				roles = []string{web.IdmAdmin}
+			}
+			server := newTestServer(roles, svc)
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, ContentType)
+
+			resp, err := server.App.Test(req)
+
+			a.Nil(err)
+			a.Equal(tt.status, resp.StatusCode)
+			a.Equal(tt.location, resp.Header.Get(fiber.HeaderLocation))
+			body, err := io.ReadAll(resp.Body)
+			a.Nil(err)
+			if tt.status == http.StatusNoContent {
+				a.Empty(body)
+				svc.AssertExpectations(t)
+				return
+			}
+			a.True(strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), ContentType))
+			for _, fragment := range tt.contains {
+				a.Contains(string(body), fragment)
+			}
+			if tt.status >= http.StatusBadRequest {
+				var errorBody ErrorResponse
+				a.Nil(json.Unmarshal(body, &errorBody))
+				a.Equal([]string{SchemaError}, errorBody.Schemas)
+				a.Equal(strconv.Itoa(tt.status), errorBody.Status)
+				a.Equal(tt.scimType, errorBody.ScimType)
+				a.NotEmpty(errorBody.Detail)
+			}
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/employee"
+	"idm/inner/role"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// selectMembership - действующие назначения ролей с именами сотрудников и ролей
+const selectMembership = `
+	SELECT er.role_id, r.name AS role_name, e.id AS employee_id, e.name AS employee_name,
+	       e.surname AS employee_surname, e.login AS employee_login
+	FROM employee_role er
+	JOIN role r ON r.id = er.role_id
+	JOIN employee e ON e.id = er.employee_id
+	WHERE er.valid_from <= NOW() AND (er.valid_until IS NULL OR er.valid_until > NOW())`
+
+// FindUsers - страница сотрудников по условию фильтра и общее число подходящих
+func (r *Repository) FindUsers(ctx context.Context, query Query) (users []employee.Entity, total int64, err error) {
+	total, err = r.find(ctx, &users, "employee", query)
+	return users, total, err
+}
+
+func (r *Repository) FindUserById(ctx context.Context, id int64) (user employee.Entity, err error) {
+	err = r.db.GetContext(ctx, &user, "SELECT * FROM employee WHERE id = $1", id)
+	return user, err
+}
+
+// ExistsUserName - логин занят другим сотрудником; сравнение без учёта регистра, как в фильтре
+func (r *Repository) ExistsUserName(ctx context.Context, userName string, exceptId int64) (exists bool, err error) {
+	err = r.db.GetContext(ctx, &exists,
+		"SELECT EXISTS(SELECT 1 FROM employee WHERE lower(login) = lower($1) AND id <> $2)",
+		userName, exceptId)
+	return exists, err
+}
+
+// UpdateUser - заменяет учётные атрибуты сотрудника. Отдел, руководитель и должность меняются
+// переводом сотрудника, чтобы пересчитать правила базового доступа
+func (r *Repository) UpdateUser(tx *sqlx.Tx, user employee.Entity) (updated employee.Entity, err error) {
+	err = tx.Get(&updated,
+		`UPDATE employee
+		 SET name = $2, surname = $3, age = $4, login = $5, email = $6, phone = $7, active = $8,
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		user.Id, user.Name, user.Surname, user.Age, user.Login, user.Email, user.Phone, user.Active)
+	return updated, err
+}
+
+// FindGroups - страница ролей по условию фильтра и общее число подходящих
+func (r *Repository) FindGroups(ctx context.Context, query Query) (groups []role.Entity, total int64, err error) {
+	total, err = r.find(ctx, &groups, "role", query)
+	return groups, total, err
+}
+
+func (r *Repository) FindGroupById(ctx context.Context, id int64) (group role.Entity, err error) {
+	err = r.db.GetContext(ctx, &group, "SELECT * FROM role WHERE id = $1", id)
+	return group, err
+}
+
+func (r *Repository) ExistsGroupName(ctx context.Context, name string, exceptId int64) (exists bool, err error) {
+	err = r.db.GetContext(ctx, &exists,
+		"SELECT EXISTS(SELECT 1 FROM role WHERE lower(name) = lower($1) AND id <> $2)",
+		name, exceptId)
+	return exists, err
+}
+
+// FindMembers - участники групп roleIds
+func (r *Repository) FindMembers(ctx context.Context, roleIds []int64) (members []Membership, err error) {
+	err = r.db.SelectContext(ctx, &members,
+		selectMembership+" AND er.role_id = ANY($1) ORDER BY er.role_id, e.id", pq.Array(roleIds))
+	return members, err
+}
+
+// FindGroupsOfUsers - группы сотрудников employeeIds
+func (r *Repository) FindGroupsOfUsers(ctx context.Context, employeeIds []int64) (groups []Membership, err error) {
+	err = r.db.SelectContext(ctx, &groups,
+		selectMembership+" AND er.employee_id = ANY($1) ORDER BY e.id, er.role_id", pq.Array(employeeIds))
+	return groups, err
+}
+
+// find - выборка страницы из table; count = 0 возвращает только общее число
+func (r *Repository) find(ctx context.Context, dest any, table string, query Query) (total int64, err error) {
+	var where = "WHERE " + query.Where
+	err = r.db.GetContext(ctx, &total, fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where), query.Args...)
+	if err != nil || query.Limit == 0 {
+		return total, err
+	}
+	var limit = len(query.Args) + 1
+	err = r.db.SelectContext(ctx, dest,
+		fmt.Sprintf("SELECT * FROM %s %s ORDER BY id LIMIT $%d OFFSET $%d", table, where, limit, limit+1),
+		append(append([]any{}, query.Args...), query.Limit, query.Offset)...)
+	return total, err
+}
@@ -0,0 +1,189 @@
+package scim
+
+// Документы обнаружения SCIM (RFC 7643, разделы 5-7): возможности сервера, типы ресурсов
+// и схемы атрибутов. Статичны и описывают отображение на сотрудников и роли
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// LocationMeta - meta документов обнаружения, у которых нет дат создания и изменения
+type LocationMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	Etag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  LocationMeta           `json:"meta"`
+}
+
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+type ResourceType struct {
+	Schemas          []string          `json:"schemas"`
+	Id               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             LocationMeta      `json:"meta"`
+}
+
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Description   string      `json:"description"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Attributes  []Attribute  `json:"attributes"`
+	Meta        LocationMeta `json:"meta"`
+}
+
+func NewServiceProviderConfig() ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          Supported{Supported: true},
+		Filter:         FilterSupport{Supported: true, MaxResults: MaxCount},
+		ChangePassword: Supported{},
+		Sort:           Supported{},
+		Etag:           Supported{},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Keycloak access token with scim:read or scim:write permission",
+			Primary:     true,
+		}},
+		Meta: LocationMeta{ResourceType: "ServiceProviderConfig"},
+	}
+}
+
+func NewResourceTypes() []ResourceType {
+	return []ResourceType{
+		{
+			Schemas:          []string{SchemaResourceType},
+			Id:               ResourceUser,
+			Name:             ResourceUser,
+			Endpoint:         "/Users",
+			Description:      "Employee",
+			Schema:           SchemaUser,
+			SchemaExtensions: []SchemaExtension{{Schema: SchemaEmployee, Required: false}},
+			Meta:             LocationMeta{ResourceType: "ResourceType"},
+		},
+		{
+			Schemas:     []string{SchemaResourceType},
+			Id:          ResourceGroup,
+			Name:        ResourceGroup,
+			Endpoint:    "/Groups",
+			Description: "Role; members are employees with an active assignment",
+			Schema:      SchemaGroup,
+			Meta:        LocationMeta{ResourceType: "ResourceType"},
+		},
+	}
+}
+
+func NewSchemas() []Schema {
+	var value = Attribute{Name: "value", Type: "string", Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+	var reference = []Attribute{
+		{Name: "value", Type: "string", Mutability: "immutable", Returned: "default", Uniqueness: "none"},
+		{Name: "$ref", Type: "reference", Mutability: "immutable", Returned: "default", Uniqueness: "none"},
+		{Name: "display", Type: "string", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+	}
+	return []Schema{
+		{
+			Schemas:     []string{SchemaSchema},
+			Id:          SchemaUser,
+			Name:        ResourceUser,
+			Description: "Employee",
+			Attributes: []Attribute{
+				{Name: "userName", Type: "string", Description: "Employee login", Required: true,
+					Mutability: "readWrite", Returned: "default", Uniqueness: "server"},
+				{Name: "name", Type: "complex", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []Attribute{
+						{Name: "givenName", Type: "string", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+						{Name: "familyName", Type: "string", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+						{Name: "formatted", Type: "string", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+					}},
+				{Name: "displayName", Type: "string", Description: "givenName and familyName",
+					Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+				{Name: "title", Type: "string", Description: "Employee position",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				{Name: "active", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				{Name: "emails", Type: "complex", MultiValued: true, Description: "Only one email is stored",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []Attribute{value}},
+				{Name: "phoneNumbers", Type: "complex", MultiValued: true, Description: "Only one phone number is stored",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []Attribute{value}},
+				{Name: "groups", Type: "complex", MultiValued: true, Description: "Roles assigned to the employee",
+					Mutability: "readOnly", Returned: "default", Uniqueness: "none", SubAttributes: reference},
+			},
+			Meta: LocationMeta{ResourceType: "Schema"},
+		},
+		{
+			Schemas:     []string{SchemaSchema},
+			Id:          SchemaGroup,
+			Name:        ResourceGroup,
+			Description: "Role",
+			Attributes: []Attribute{
+				{Name: "displayName", Type: "string", Description: "Role name", Required: true,
+					Mutability: "readWrite", Returned: "default", Uniqueness: "server"},
+				{Name: "members", Type: "complex", MultiValued: true, Description: "Employees with the role",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: reference},
+			},
+			Meta: LocationMeta{ResourceType: "Schema"},
+		},
+		{
+			Schemas:     []string{SchemaSchema},
+			Id:          SchemaEmployee,
+			Name:        "Employee",
+			Description: "Employee attributes missing in the core schema",
+			Attributes: []Attribute{
+				{Name: "age", Type: "integer", Description: "Required on create",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				{Name: "departmentId", Type: "integer", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				{Name: "managerId", Type: "integer", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+			},
+			Meta: LocationMeta{ResourceType: "Schema"},
+		},
+	}
+}
@@ -0,0 +1,895 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Service struct {
+	repo      Repo
+	employees Employees
+	roles     Roles
+	assigner  Assigner
+	logger    common.LoggerInterface
+	// Provisioning - передача учётных атрибутов и переводов, выполненных в транзакциях SCIM, во внешние
+	// системы; создание и роли передают сервисы сотрудников и назначений
+	Provisioning Provisioner
+	// Events - запись доменных событий в outbox в транзакции изменения; nil - события не пишутся
+	Events outbox.Writer
+}
+
+// Provisioner - асинхронная синхронизация сотрудников с внешними системами
+type Provisioner interface {
+	Provision(ctx context.Context, employeeIds ...int64)
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	FindUsers(ctx context.Context, query Query) ([]employee.Entity, int64, error)
+	FindUserById(ctx context.Context, id int64) (employee.Entity, error)
+	ExistsUserName(ctx context.Context, userName string, exceptId int64) (bool, error)
+	UpdateUser(tx *sqlx.Tx, user employee.Entity) (employee.Entity, error)
+	FindGroups(ctx context.Context, query Query) ([]role.Entity, int64, error)
+	FindGroupById(ctx context.Context, id int64) (role.Entity, error)
+	ExistsGroupName(ctx context.Context, name string, exceptId int64) (bool, error)
+	FindMembers(ctx context.Context, roleIds []int64) ([]Membership, error)
+	FindGroupsOfUsers(ctx context.Context, employeeIds []int64) ([]Membership, error)
+}
+
+// Employees - создание, перевод и удаление сотрудников с правилами базового доступа
+type Employees interface {
+	CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error)
+	MoveInTx(ctx context.Context, tx *sqlx.Tx, id int64, request employee.MoveRequest) (employee.Response, error)
+	DeleteById(ctx context.Context, id int64) (employee.Response, error)
+}
+
+type Roles interface {
+	Add(role role.Entity) (role.Response, error)
+	Update(ctx context.Context, id int64, request role.Request) (role.Response, error)
+	DeleteById(ctx context.Context, actor string, id int64) (role.Response, error)
+}
+
+// Assigner - изменение состава группы в транзакции SCIM: назначение и отзыв роли с проверкой SoD и аудитом
+type Assigner interface {
+	AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.AssignRequest) (assignment.Response, error)
+	RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error
+}
+
+func NewService(repo Repo, employees Employees, roles Roles, assigner Assigner, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		employees: employees,
+		roles:     roles,
+		assigner:  assigner,
+		logger:    logger,
+	}
+}
+
+// FindUsers - страница пользователей, подходящих под фильтр
+func (svc *Service) FindUsers(ctx context.Context, request ListRequest) (ListResponse[User], error) {
+	where, args, err := buildWhere(request.Filter, SchemaUser, userColumns)
+	if err != nil {
+		return ListResponse[User]{}, err
+	}
+	var query = page(request, where, args)
+	entities, total, err := svc.repo.FindUsers(ctx, query)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("Error finding scim users: %w", err)
+	}
+	var ids = make([]int64, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.Id)
+	}
+	groups, err := svc.findGroupsOfUsers(ctx, ids)
+	if err != nil {
+		return ListResponse[User]{}, err
+	}
+	var users = make([]User, 0, len(entities))
+	for _, entity := range entities {
+		users = append(users, toUser(entity, groups[entity.Id]))
+	}
+	return listResponse(users, total, query), nil
+}
+
+func (svc *Service) FindUser(ctx context.Context, id string) (User, error) {
+	entity, err := svc.findUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	groups, err := svc.findGroupsOfUsers(ctx, []int64{entity.Id})
+	if err != nil {
+		return User{}, err
+	}
+	return toUser(entity, groups[entity.Id]), nil
+}
+
+// CreateUser - создаёт сотрудника; возраст обязателен и передаётся в расширении SchemaEmployee
+func (svc *Service) CreateUser(ctx context.Context, user User) (User, error) {
+	if err := validateUser(user); err != nil {
+		return User{}, err
+	}
+	if user.Employee == nil || user.Employee.Age == 0 {
+		return User{}, common.RequestValidationError{Message: SchemaEmployee + ":age is required"}
+	}
+	if err := svc.checkUserName(ctx, user.UserName, 0); err != nil {
+		return User{}, err
+	}
+	var now = time.Now()
+	id, err := svc.employees.CreateEmployee(ctx, employee.CreateRequest{
+		Name:         user.Name.GivenName,
+		Surname:      user.Name.FamilyName,
+		Age:          user.Employee.Age,
+		Login:        user.UserName,
+		Email:        primary(user.Emails),
+		Phone:        primary(user.PhoneNumbers),
+		DepartmentId: user.Employee.DepartmentId,
+		ManagerId:    user.Employee.ManagerId,
+		Position:     user.Title,
+		Active:       user.Active,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return svc.FindUser(ctx, strconv.FormatInt(id, 10))
+}
+
+// ReplaceUser - заменяет пользователя целиком. Не переданные active и атрибуты расширения
+// сохраняют текущие значения
+func (svc *Service) ReplaceUser(ctx context.Context, id string, user User) (User, error) {
+	current, err := svc.findUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	return svc.saveUser(ctx, current, user)
+}
+
+// PatchUser - применяет операции PATCH к текущему состоянию пользователя и сохраняет результат
+func (svc *Service) PatchUser(ctx context.Context, id string, request PatchRequest) (User, error) {
+	if err := validatePatch(request); err != nil {
+		return User{}, err
+	}
+	current, err := svc.findUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	var user = toUser(current, nil)
+	for _, operation := range request.Operations {
+		if err = patchUser(&user, operation); err != nil {
+			return User{}, err
+		}
+	}
+	return svc.saveUser(ctx, current, user)
+}
+
+func (svc *Service) DeleteUser(ctx context.Context, id string) error {
+	entity, err := svc.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err = svc.employees.DeleteById(ctx, entity.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindGroups - страница групп, подходящих под фильтр
+func (svc *Service) FindGroups(ctx context.Context, request ListRequest) (ListResponse[Group], error) {
+	where, args, err := buildWhere(request.Filter, SchemaGroup, groupColumns)
+	if err != nil {
+		return ListResponse[Group]{}, err
+	}
+	var query = page(request, where, args)
+	entities, total, err := svc.repo.FindGroups(ctx, query)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("Error finding scim groups: %w", err)
+	}
+	var ids = make([]int64, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.Id)
+	}
+	members, err := svc.findMembers(ctx, ids)
+	if err != nil {
+		return ListResponse[Group]{}, err
+	}
+	var groups = make([]Group, 0, len(entities))
+	for _, entity := range entities {
+		groups = append(groups, toGroup(entity, members[entity.Id]))
+	}
+	return listResponse(groups, total, query), nil
+}
+
+func (svc *Service) FindGroup(ctx context.Context, id string) (Group, error) {
+	entity, err := svc.findGroup(ctx, id)
+	if err != nil {
+		return Group{}, err
+	}
+	members, err := svc.findMembers(ctx, []int64{entity.Id})
+	if err != nil {
+		return Group{}, err
+	}
+	return toGroup(entity, members[entity.Id]), nil
+}
+
+// CreateGroup - создаёт роль и назначает её участникам группы от имени actor
+func (svc *Service) CreateGroup(ctx context.Context, actor string, group Group) (Group, error) {
+	members, err := validateGroup(group)
+	if err != nil {
+		return Group{}, err
+	}
+	if err = svc.checkGroupName(ctx, group.DisplayName, 0); err != nil {
+		return Group{}, err
+	}
+	var request = role.Request{Name: group.DisplayName}
+	created, err := svc.roles.Add(request.ToEntity())
+	if err != nil {
+		return Group{}, err
+	}
+	if err = svc.setMembers(ctx, actor, created.Id, nil, members); err != nil {
+		return Group{}, err
+	}
+	return svc.FindGroup(ctx, strconv.FormatInt(created.Id, 10))
+}
+
+// ReplaceGroup - переименовывает роль и приводит состав участников к переданному
+func (svc *Service) ReplaceGroup(ctx context.Context, actor string, id string, group Group) (Group, error) {
+	current, err := svc.findGroup(ctx, id)
+	if err != nil {
+		return Group{}, err
+	}
+	members, err := validateGroup(group)
+	if err != nil {
+		return Group{}, err
+	}
+	return svc.saveGroup(ctx, actor, current, group.DisplayName, members)
+}
+
+// PatchGroup - применяет операции PATCH к названию и составу группы
+func (svc *Service) PatchGroup(ctx context.Context, actor string, id string, request PatchRequest) (Group, error) {
+	if err := validatePatch(request); err != nil {
+		return Group{}, err
+	}
+	current, err := svc.findGroup(ctx, id)
+	if err != nil {
+		return Group{}, err
+	}
+	currentMembers, err := svc.findMembers(ctx, []int64{current.Id})
+	if err != nil {
+		return Group{}, err
+	}
+	var group = toGroup(current, currentMembers[current.Id])
+	for _, operation := range request.Operations {
+		if err = patchGroup(&group, operation); err != nil {
+			return Group{}, err
+		}
+	}
+	members, err := validateGroup(group)
+	if err != nil {
+		return Group{}, err
+	}
+	return svc.saveGroup(ctx, actor, current, group.DisplayName, members)
+}
+
+func (svc *Service) DeleteGroup(ctx context.Context, actor string, id string) error {
+	entity, err := svc.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err = svc.roles.DeleteById(ctx, actor, entity.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// saveUser - сохраняет учётные атрибуты и, если изменились отдел, руководитель или должность,
+// переводит сотрудника в той же транзакции
+func (svc *Service) saveUser(ctx context.Context, current employee.Entity, user User) (User, error) {
+	if err := validateUser(user); err != nil {
+		return User{}, err
+	}
+	if err := svc.checkUserName(ctx, user.UserName, current.Id); err != nil {
+		return User{}, err
+	}
+	var extension = EmployeeExtension{
+		Age:          current.Age,
+		DepartmentId: current.DepartmentId.Int64,
+		ManagerId:    current.ManagerId.Int64,
+	}
+	if user.Employee != nil {
+		extension = *user.Employee
+		if extension.Age == 0 {
+			extension.Age = current.Age
+		}
+	}
+	var updated = current
+	updated.Name = user.Name.GivenName
+	updated.Surname = user.Name.FamilyName
+	updated.Age = extension.Age
+	updated.Login = sql.NullString{String: user.UserName, Valid: true}
+	updated.Email = primary(user.Emails)
+	updated.Phone = primary(user.PhoneNumbers)
+	if user.Active != nil {
+		updated.Active = *user.Active
+	}
+	var move = user.Title != current.Position ||
+		extension.DepartmentId != current.DepartmentId.Int64 ||
+		extension.ManagerId != current.ManagerId.Int64
+	err := common.InTx(svc.repo, "Updating user", func(tx *sqlx.Tx) error {
+		if err := svc.updateUser(tx, updated); err != nil || !move {
+			return err
+		}
+		_, err := svc.employees.MoveInTx(ctx, tx, current.Id, employee.MoveRequest{
+			DepartmentId: extension.DepartmentId,
+			Position:     user.Title,
+			ManagerId:    extension.ManagerId,
+		})
+		return err
+	})
+	if err != nil {
+		return User{}, err
+	}
+	svc.provision(ctx, current.Id)
+	return svc.FindUser(ctx, strconv.FormatInt(current.Id, 10))
+}
+
+// updateUser - сохраняет учётные атрибуты сотрудника и пишет событие EmployeeUpdated в той же транзакции
+func (svc *Service) updateUser(tx *sqlx.Tx, user employee.Entity) error {
+	updated, err := svc.repo.UpdateUser(tx, user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return common.NotFoundError{Message: fmt.Sprintf("User %d not found", user.Id)}
+	}
+	if err != nil {
+		return fmt.Errorf("Error updating employee %d: %w", user.Id, err)
+	}
+	return outbox.Write(svc.Events, tx, outbox.AggregateEmployee, user.Id, outbox.EmployeeUpdated, updated.ToResponse())
+}
+
+func (svc *Service) provision(ctx context.Context, employeeIds ...int64) {
+	if svc.Provisioning != nil {
+		svc.Provisioning.Provision(ctx, employeeIds...)
+	}
+}
+
+// saveGroup - переименовывает роль с сохранением её метаданных и меняет состав участников
+func (svc *Service) saveGroup(ctx context.Context, actor string, current role.Entity, name string, members []int64) (Group, error) {
+	if name != current.Name {
+		if err := svc.checkGroupName(ctx, name, current.Id); err != nil {
+			return Group{}, err
+		}
+		var request = current.ToRequest()
+		request.Name = name
+		if _, err := svc.roles.Update(ctx, current.Id, request); err != nil {
+			return Group{}, err
+		}
+	}
+	currentMembers, err := svc.findMembers(ctx, []int64{current.Id})
+	if err != nil {
+		return Group{}, err
+	}
+	var held []int64
+	for _, member := range currentMembers[current.Id] {
+		held = append(held, member.EmployeeId)
+	}
+	if err = svc.setMembers(ctx, actor, current.Id, held, members); err != nil {
+		return Group{}, err
+	}
+	return svc.FindGroup(ctx, strconv.FormatInt(current.Id, 10))
+}
+
+// setMembers - в одной транзакции назначает роль новым участникам и отзывает у исключённых,
+// после фиксации передаёт изменённых сотрудников во внешние системы
+func (svc *Service) setMembers(ctx context.Context, actor string, roleId int64, held, members []int64) error {
+	var added, removed []int64
+	for _, employeeId := range members {
+		if !slices.Contains(held, employeeId) {
+			added = append(added, employeeId)
+		}
+	}
+	for _, employeeId := range held {
+		if !slices.Contains(members, employeeId) {
+			removed = append(removed, employeeId)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	err := common.InTx(svc.repo, "Setting group members", func(tx *sqlx.Tx) error {
+		for _, employeeId := range added {
+			_, err := svc.assigner.AssignInTx(ctx, tx, actor, assignment.AssignRequest{EmployeeId: employeeId, RoleId: roleId})
+			if err != nil {
+				return err
+			}
+		}
+		for _, employeeId := range removed {
+			err := svc.assigner.RevokeInTx(ctx, tx, actor, assignment.RevokeRequest{EmployeeId: employeeId, RoleId: roleId})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	svc.provision(ctx, append(added, removed...)...)
+	return nil
+}
+
+func (svc *Service) findUser(ctx context.Context, id string) (employee.Entity, error) {
+	employeeId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || employeeId <= 0 {
+		return employee.Entity{}, common.NotFoundError{Message: fmt.Sprintf("User %s not found", id)}
+	}
+	entity, err := svc.repo.FindUserById(ctx, employeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return employee.Entity{}, common.NotFoundError{Message: fmt.Sprintf("User %s not found", id)}
+	}
+	if err != nil {
+		return employee.Entity{}, fmt.Errorf("Error finding employee %d: %w", employeeId, err)
+	}
+	return entity, nil
+}
+
+func (svc *Service) findGroup(ctx context.Context, id string) (role.Entity, error) {
+	roleId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || roleId <= 0 {
+		return role.Entity{}, common.NotFoundError{Message: fmt.Sprintf("Group %s not found", id)}
+	}
+	entity, err := svc.repo.FindGroupById(ctx, roleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return role.Entity{}, common.NotFoundError{Message: fmt.Sprintf("Group %s not found", id)}
+	}
+	if err != nil {
+		return role.Entity{}, fmt.Errorf("Error finding role %d: %w", roleId, err)
+	}
+	return entity, nil
+}
+
+func (svc *Service) findGroupsOfUsers(ctx context.Context, employeeIds []int64) (map[int64][]Membership, error) {
+	var groups = map[int64][]Membership{}
+	if len(employeeIds) == 0 {
+		return groups, nil
+	}
+	memberships, err := svc.repo.FindGroupsOfUsers(ctx, employeeIds)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding groups of employees %v: %w", employeeIds, err)
+	}
+	for _, membership := range memberships {
+		groups[membership.EmployeeId] = append(groups[membership.EmployeeId], membership)
+	}
+	return groups, nil
+}
+
+func (svc *Service) findMembers(ctx context.Context, roleIds []int64) (map[int64][]Membership, error) {
+	var members = map[int64][]Membership{}
+	if len(roleIds) == 0 {
+		return members, nil
+	}
+	memberships, err := svc.repo.FindMembers(ctx, roleIds)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding members of roles %v: %w", roleIds, err)
+	}
+	for _, membership := range memberships {
+		members[membership.RoleId] = append(members[membership.RoleId], membership)
+	}
+	return members, nil
+}
+
+func (svc *Service) checkUserName(ctx context.Context, userName string, exceptId int64) error {
+	exists, err := svc.repo.ExistsUserName(ctx, userName, exceptId)
+	if err != nil {
+		return fmt.Errorf("Error checking userName %s: %w", userName, err)
+	}
+	if exists {
+		return common.AlreadyExistsError{Message: fmt.Sprintf("userName %s is already taken", userName)}
+	}
+	return nil
+}
+
+func (svc *Service) checkGroupName(ctx context.Context, name string, exceptId int64) error {
+	exists, err := svc.repo.ExistsGroupName(ctx, name, exceptId)
+	if err != nil {
+		return fmt.Errorf("Error checking group %s: %w", name, err)
+	}
+	if exists {
+		return common.AlreadyExistsError{Message: fmt.Sprintf("Group %s already exists", name)}
+	}
+	return nil
+}
+
+func validateUser(user User) error {
+	if strings.TrimSpace(user.UserName) == "" {
+		return common.RequestValidationError{Message: "userName is required"}
+	}
+	if user.Name == nil || user.Name.GivenName == "" || user.Name.FamilyName == "" {
+		return common.RequestValidationError{Message: "name.givenName and name.familyName are required"}
+	}
+	return nil
+}
+
+// validateGroup - название обязательно, участники - идентификаторы пользователей
+func validateGroup(group Group) ([]int64, error) {
+	if strings.TrimSpace(group.DisplayName) == "" {
+		return nil, common.RequestValidationError{Message: "displayName is required"}
+	}
+	var members []int64
+	for _, member := range group.Members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, common.RequestValidationError{Message: fmt.Sprintf("Wrong member value %q", member.Value)}
+		}
+		if !slices.Contains(members, id) {
+			members = append(members, id)
+		}
+	}
+	return members, nil
+}
+
+func validatePatch(request PatchRequest) error {
+	if !slices.Contains(request.Schemas, SchemaPatchOp) {
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidSyntax, Detail: "schemas must contain " + SchemaPatchOp}
+	}
+	if len(request.Operations) == 0 {
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidSyntax, Detail: "Operations are required"}
+	}
+	return nil
+}
+
+// page - страница выборки: startIndex меньше 1 считается 1, count ограничивается MaxCount
+func page(request ListRequest, where string, args []any) Query {
+	var startIndex = max(request.StartIndex, 1)
+	var count = min(max(request.Count, 0), MaxCount)
+	return Query{Where: where, Args: args, Limit: count, Offset: startIndex - 1}
+}
+
+func listResponse[T any](resources []T, total int64, query Query) ListResponse[T] {
+	return ListResponse[T]{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   query.Offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// primary - основное значение многозначного атрибута, иначе первое
+func primary(values []MultiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// patchUser - применяет операцию к пользователю. Операция без пути задаёт атрибуты объектом,
+// ключи которого - пути
+func patchUser(user *User, operation PatchOperation) error {
+	var op = strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidSyntax, Detail: fmt.Sprintf("unknown op %q", operation.Op)}
+	}
+	if operation.Path == "" {
+		if op == "remove" {
+			return Error{Status: http.StatusBadRequest, ScimType: ErrNoTarget, Detail: "remove requires path"}
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return invalidValue("value must be an object")
+		}
+		for path, value := range values {
+			if strings.EqualFold(path, "schemas") || strings.EqualFold(path, "id") {
+				continue
+			}
+			if err := patchUser(user, PatchOperation{Op: op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	path, err := ParsePath(operation.Path, SchemaUser)
+	if err != nil {
+		return err
+	}
+	var remove = op == "remove"
+	var extension = strings.ToLower(SchemaEmployee)
+	if user.Employee == nil {
+		user.Employee = &EmployeeExtension{}
+	}
+	switch {
+	case path.Attr == "username" && path.Sub == "":
+		return patchString(&user.UserName, operation.Value, remove)
+	case path.Attr == "title" && path.Sub == "":
+		return patchString(&user.Title, operation.Value, remove)
+	case path.Attr == "active" && path.Sub == "":
+		var active = false
+		if !remove {
+			value, err := parseBool(operation.Value)
+			if err != nil {
+				return err
+			}
+			active = value
+		}
+		user.Active = &active
+		return nil
+	case path.Attr == "name":
+		return patchName(user, path.Sub, op, operation.Value)
+	case path.Attr == "emails":
+		return patchMultiValue(&user.Emails, path, operation.Value, remove)
+	case path.Attr == "phonenumbers":
+		return patchMultiValue(&user.PhoneNumbers, path, operation.Value, remove)
+	case path.Attr == extension:
+		if remove {
+			user.Employee.DepartmentId, user.Employee.ManagerId = 0, 0
+			return nil
+		}
+		var value EmployeeExtension
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return invalidValue(err.Error())
+		}
+		if value.Age != 0 {
+			user.Employee.Age = value.Age
+		}
+		if value.DepartmentId != 0 || op == "replace" {
+			user.Employee.DepartmentId = value.DepartmentId
+		}
+		if value.ManagerId != 0 || op == "replace" {
+			user.Employee.ManagerId = value.ManagerId
+		}
+		return nil
+	case path.Attr == extension+":age":
+		return patchNumber(&user.Employee.Age, operation.Value, remove)
+	case path.Attr == extension+":departmentid":
+		return patchNumber(&user.Employee.DepartmentId, operation.Value, remove)
+	case path.Attr == extension+":managerid":
+		return patchNumber(&user.Employee.ManagerId, operation.Value, remove)
+	case path.Attr == "id" || path.Attr == "displayname" || path.Attr == "groups" || path.Attr == "meta":
+		return Error{Status: http.StatusBadRequest, ScimType: ErrMutability, Detail: fmt.Sprintf("%s is read-only", operation.Path)}
+	default:
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("unknown attribute %q", operation.Path)}
+	}
+}
+
+// patchName - изменение имени целиком (add объединяет, replace заменяет) или податрибута
+func patchName(user *User, sub string, op string, raw json.RawMessage) error {
+	if user.Name == nil {
+		user.Name = &Name{}
+	}
+	var remove = op == "remove"
+	switch sub {
+	case "":
+		if remove {
+			user.Name = &Name{}
+			return nil
+		}
+		var value Name
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return invalidValue(err.Error())
+		}
+		if op == "replace" {
+			user.Name = &value
+			return nil
+		}
+		if value.GivenName != "" {
+			user.Name.GivenName = value.GivenName
+		}
+		if value.FamilyName != "" {
+			user.Name.FamilyName = value.FamilyName
+		}
+		return nil
+	case "givenname":
+		return patchString(&user.Name.GivenName, raw, remove)
+	case "familyname":
+		return patchString(&user.Name.FamilyName, raw, remove)
+	case "formatted":
+		return Error{Status: http.StatusBadRequest, ScimType: ErrMutability, Detail: "name.formatted is read-only"}
+	default:
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("unknown attribute name.%s", sub)}
+	}
+}
+
+// patchMultiValue - сотрудник хранит одно значение, поэтому фильтр пути (emails[type eq "work"])
+// указывает на него же. Без податрибута значение - массив, с податрибутом value - строка
+func patchMultiValue(values *[]MultiValue, path Path, raw json.RawMessage, remove bool) error {
+	if remove {
+		*values = nil
+		return nil
+	}
+	switch path.Sub {
+	case "":
+		var items []MultiValue
+		if err := json.Unmarshal(raw, &items); err != nil {
+			var item MultiValue
+			if json.Unmarshal(raw, &item) != nil {
+				return invalidValue("value must be an array of {value}")
+			}
+			items = []MultiValue{item}
+		}
+		var value = primary(items)
+		*values = nil
+		if value != "" {
+			*values = []MultiValue{{Value: value, Type: "work", Primary: true}}
+		}
+		return nil
+	case "value":
+		var value string
+		if err := patchString(&value, raw, false); err != nil {
+			return err
+		}
+		*values = []MultiValue{{Value: value, Type: "work", Primary: true}}
+		return nil
+	case "type", "primary":
+		return nil
+	default:
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("unknown sub-attribute %q", path.Sub)}
+	}
+}
+
+// patchGroup - операции над названием и участниками группы. Участник удаляется путём
+// members[value eq "id"] или списком в value
+func patchGroup(group *Group, operation PatchOperation) error {
+	var op = strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidSyntax, Detail: fmt.Sprintf("unknown op %q", operation.Op)}
+	}
+	if operation.Path == "" {
+		if op == "remove" {
+			return Error{Status: http.StatusBadRequest, ScimType: ErrNoTarget, Detail: "remove requires path"}
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return invalidValue("value must be an object")
+		}
+		for path, value := range values {
+			if strings.EqualFold(path, "schemas") || strings.EqualFold(path, "id") {
+				continue
+			}
+			if err := patchGroup(group, PatchOperation{Op: op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	path, err := ParsePath(operation.Path, SchemaGroup)
+	if err != nil {
+		return err
+	}
+	switch path.Attr {
+	case "displayname":
+		return patchString(&group.DisplayName, operation.Value, op == "remove")
+	case "members":
+		return patchMembers(group, op, path, operation.Value)
+	case "id", "meta":
+		return Error{Status: http.StatusBadRequest, ScimType: ErrMutability, Detail: fmt.Sprintf("%s is read-only", operation.Path)}
+	default:
+		return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: fmt.Sprintf("unknown attribute %q", operation.Path)}
+	}
+}
+
+func patchMembers(group *Group, op string, path Path, raw json.RawMessage) error {
+	if path.Filter != nil {
+		if op != "remove" {
+			return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidPath, Detail: "member filter is supported only for remove"}
+		}
+		var kept []Reference
+		for _, member := range group.Members {
+			matched, err := matchMember(path.Filter, member)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				kept = append(kept, member)
+			}
+		}
+		group.Members = kept
+		return nil
+	}
+	var members []Reference
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &members); err != nil {
+			return invalidValue("members must be an array of {value}")
+		}
+	}
+	switch op {
+	case "add":
+		group.Members = append(group.Members, members...)
+	case "replace":
+		group.Members = members
+	case "remove":
+		if len(members) == 0 {
+			group.Members = nil
+			return nil
+		}
+		group.Members = slices.DeleteFunc(group.Members, func(member Reference) bool {
+			return slices.ContainsFunc(members, func(removed Reference) bool { return removed.Value == member.Value })
+		})
+	}
+	return nil
+}
+
+// matchMember - фильтр участника: поддерживаются сравнения value eq, объединённые and/or
+func matchMember(filter Filter, member Reference) (bool, error) {
+	switch f := filter.(type) {
+	case Comparison:
+		value, ok := f.Value.(string)
+		if f.Attr != "value" || f.Op != "eq" || !ok {
+			return false, Error{Status: http.StatusBadRequest, ScimType: ErrInvalidFilter, Detail: "only value eq \"id\" is supported for members"}
+		}
+		return member.Value == value, nil
+	case Logical:
+		left, err := matchMember(f.Left, member)
+		if err != nil {
+			return false, err
+		}
+		right, err := matchMember(f.Right, member)
+		if err != nil {
+			return false, err
+		}
+		if f.Op == "and" {
+			return left && right, nil
+		}
+		return left || right, nil
+	default:
+		return false, Error{Status: http.StatusBadRequest, ScimType: ErrInvalidFilter, Detail: "unsupported member filter"}
+	}
+}
+
+func patchString(target *string, raw json.RawMessage, remove bool) error {
+	if remove {
+		*target = ""
+		return nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return invalidValue("value must be a string")
+	}
+	return nil
+}
+
+func patchNumber[T int8 | int64](target *T, raw json.RawMessage, remove bool) error {
+	if remove {
+		*target = 0
+		return nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return invalidValue("value must be a number")
+	}
+	return nil
+}
+
+// parseBool - булево значение; некоторые клиенты передают его строкой "True"/"False"
+func parseBool(raw json.RawMessage) (bool, error) {
+	var value bool
+	if json.Unmarshal(raw, &value) == nil {
+		return value, nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if parsed, err := strconv.ParseBool(strings.ToLower(text)); err == nil {
+			return parsed, nil
+		}
+	}
+	return false, invalidValue("value must be a boolean")
+}
+
+func invalidValue(detail string) Error {
+	return Error{Status: http.StatusBadRequest, ScimType: ErrInvalidValue, Detail: detail}
+}
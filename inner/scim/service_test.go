@@ -0,0 +1,742 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/outbox"
+	"idm/inner/role"
+	"idm/inner/testutil"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindUsers(ctx context.Context, query Query) ([]employee.Entity, int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]employee.Entity), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepo) FindUserById(ctx context.Context, id int64) (employee.Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsUserName(ctx context.Context, userName string, exceptId int64) (bool, error) {
+	args := m.Called(ctx, userName, exceptId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) UpdateUser(tx *sqlx.Tx, user employee.Entity) (employee.Entity, error) {
+	args := m.Called(tx, user)
+	return args.Get(0).(employee.Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindGroups(ctx context.Context, query Query) ([]role.Entity, int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]role.Entity), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepo) FindGroupById(ctx context.Context, id int64) (role.Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(role.Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsGroupName(ctx context.Context, name string, exceptId int64) (bool, error) {
+	args := m.Called(ctx, name, exceptId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindMembers(ctx context.Context, roleIds []int64) ([]Membership, error) {
+	args := m.Called(ctx, roleIds)
+	return args.Get(0).([]Membership), args.Error(1)
+}
+
+func (m *MockRepo) FindGroupsOfUsers(ctx context.Context, employeeIds []int64) ([]Membership, error) {
+	args := m.Called(ctx, employeeIds)
+	return args.Get(0).([]Membership), args.Error(1)
+}
+
+type MockEmployees struct {
+	mock.Mock
+}
+
+func (m *MockEmployees) CreateEmployee(ctx context.Context, request employee.CreateRequest) (int64, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployees) MoveInTx(ctx context.Context, tx *sqlx.Tx, id int64, request employee.MoveRequest) (employee.Response, error) {
+	args := m.Called(ctx, tx, id, request)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployees) DeleteById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+type MockRoles struct {
+	mock.Mock
+}
+
+func (m *MockRoles) Add(entity role.Entity) (role.Response, error) {
+	args := m.Called(entity)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoles) Update(ctx context.Context, id int64, request role.Request) (role.Response, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoles) DeleteById(ctx context.Context, actor string, id int64) (role.Response, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+type MockAssigner struct {
+	mock.Mock
+}
+
+func (m *MockAssigner) AssignInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.AssignRequest) (assignment.Response, error) {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Get(0).(assignment.Response), args.Error(1)
+}
+
+func (m *MockAssigner) RevokeInTx(ctx context.Context, tx *sqlx.Tx, actor string, request assignment.RevokeRequest) error {
+	args := m.Called(ctx, tx, actor, request)
+	return args.Error(0)
+}
+
+type MockProvisioner struct {
+	mock.Mock
+}
+
+func (m *MockProvisioner) Provision(ctx context.Context, employeeIds ...int64) {
+	m.Called(ctx, employeeIds)
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Add(tx *sqlx.Tx, event outbox.Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+type mocks struct {
+	repo      *MockRepo
+	employees *MockEmployees
+	roles     *MockRoles
+	assigner  *MockAssigner
+	events    *MockOutbox
+}
+
+func newTestService() (*Service, mocks) {
+	var m = mocks{new(MockRepo), new(MockEmployees), new(MockRoles), new(MockAssigner), new(MockOutbox)}
+	var svc = NewService(m.repo, m.employees, m.roles, m.assigner, &MockLogger{})
+	svc.Events = m.events
+	return svc, m
+}
+
+// updatedEvent - событие EmployeeUpdated сотрудника id
+func updatedEvent(id int64) any {
+	return mock.MatchedBy(func(e outbox.Entity) bool {
+		return e.Type == outbox.EmployeeUpdated && e.AggregateId == id
+	})
+}
+
+func TestBuildWhere(t *testing.T) {
+	var created = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter string
+		where  string
+		args   []any
+		err    string
+	}{
+		{name: "empty filter", filter: " ", where: "TRUE"},
+		{name: "eq", filter: `userName eq "john"`, where: "lower(login) = lower($1)", args: []any{"john"}},
+		{name: "case insensitive attribute and operator", filter: `USERNAME Eq "John"`,
+			where: "lower(login) = lower($1)", args: []any{"John"}},
+		{name: "core schema urn", filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "x"`,
+			where: "lower(login) = lower($1)", args: []any{"x"}},
+		{name: "and", filter: `userName eq "a" and active eq true`,
+			where: "(lower(login) = lower($1) AND active = $2)", args: []any{"a", true}},
+		{name: "or of like operators", filter: `title sw "Dev" or emails co "@corp"`,
+			where: "(position ILIKE $1 OR email ILIKE $2)", args: []any{"Dev%", "%@corp%"}},
+		{name: "and binds tighter than or", filter: `title ew "ops" or userName eq "a" and active eq false`,
+			where: "(position ILIKE $1 OR (lower(login) = lower($2) AND active = $3))", args: []any{"%ops", "a", false}},
+		{name: "parentheses", filter: `(userName eq "a" or userName eq "b") and active eq true`,
+			where: "((lower(login) = lower($1) OR lower(login) = lower($2)) AND active = $3)", args: []any{"a", "b", true}},
+		{name: "not", filter: `not (active eq false)`, where: "NOT (active = $1)", args: []any{false}},
+		{name: "present", filter: `emails pr`, where: "(email IS NOT NULL AND email <> '')"},
+		{name: "sub-attribute", filter: `name.familyName ne "Doe"`,
+			where: "lower(surname) IS DISTINCT FROM lower($1)", args: []any{"Doe"}},
+		{name: "time comparison", filter: `meta.lastModified gt "2025-01-01T00:00:00Z"`,
+			where: "updated_at > $1", args: []any{created}},
+		{name: "id as string", filter: `id eq "7"`, where: "id = $1", args: []any{int64(7)}},
+		{name: "extension attribute", filter: `urn:idm:params:scim:schemas:extension:employee:2.0:User:departmentId eq 3`,
+			where: "department_id = $1", args: []any{int64(3)}},
+		{name: "like escaping", filter: `userName co "50%_\\"`, where: "login ILIKE $1", args: []any{`%50\%\_\\%`}},
+		{name: "escaped quote", filter: `title eq "say \"hi\""`, where: "lower(position) = lower($1)", args: []any{`say "hi"`}},
+		{name: "missing value", filter: `userName eq`, err: ErrInvalidFilter},
+		{name: "unknown operator", filter: `userName zz "x"`, err: ErrInvalidFilter},
+		{name: "unknown attribute", filter: `password eq "x"`, err: ErrInvalidFilter},
+		{name: "wrong value type", filter: `active eq "yes"`, err: ErrInvalidFilter},
+		{name: "unsupported operator for type", filter: `active co true`, err: ErrInvalidFilter},
+		{name: "dangling and", filter: `userName eq "x" and`, err: ErrInvalidFilter},
+		{name: "unbalanced parenthesis", filter: `(userName eq "x"`, err: ErrInvalidFilter},
+		{name: "unterminated string", filter: `userName eq "x`, err: ErrInvalidFilter},
+		{name: "trailing token", filter: `userName eq "x" "y"`, err: ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			where, args, err := buildWhere(tt.filter, SchemaUser, userColumns)
+			if tt.err != "" {
+				var scimErr Error
+				a.True(errors.As(err, &scimErr))
+				a.Equal(tt.err, scimErr.ScimType)
+				a.Equal(400, scimErr.Status)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.where, where)
+			a.Equal(tt.args, args)
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		attr   string
+		sub    string
+		filter bool
+		err    bool
+	}{
+		{name: "attribute", path: "userName", attr: "username"},
+		{name: "sub-attribute", path: "name.givenName", attr: "name", sub: "givenname"},
+		{name: "core urn", path: "urn:ietf:params:scim:schemas:core:2.0:User:title", attr: "title"},
+		{name: "extension", path: "urn:idm:params:scim:schemas:extension:employee:2.0:User:managerId",
+			attr: "urn:idm:params:scim:schemas:extension:employee:2.0:user:managerid"},
+		{name: "value filter with sub-attribute", path: `emails[type eq "work"].value`, attr: "emails", sub: "value", filter: true},
+		{name: "member filter", path: `members[value eq "2"]`, attr: "members", filter: true},
+		{name: "invalid filter", path: `members[value zz "2"]`, err: true},
+		{name: "empty", path: " ", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			path, err := ParsePath(tt.path, SchemaUser)
+			if tt.err {
+				var scimErr Error
+				a.True(errors.As(err, &scimErr))
+				a.Equal(ErrInvalidPath, scimErr.ScimType)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.attr, path.Attr)
+			a.Equal(tt.sub, path.Sub)
+			a.Equal(tt.filter, path.Filter != nil)
+		})
+	}
+}
+
+func TestFindUsers(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		request ListRequest
+		limit   int
+		offset  int
+	}{
+		{name: "first page", request: ListRequest{StartIndex: 1, Count: 10}, limit: 10, offset: 0},
+		{name: "start index below one", request: ListRequest{StartIndex: 0, Count: 10}, limit: 10, offset: 0},
+		{name: "count above maximum", request: ListRequest{StartIndex: 21, Count: 1000}, limit: MaxCount, offset: 20},
+		{name: "negative count", request: ListRequest{StartIndex: 1, Count: -5}, limit: 0, offset: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			svc, m := newTestService()
+			m.repo.On("FindUsers", ctx, Query{Where: "TRUE", Limit: tt.limit, Offset: tt.offset}).
+				Return([]employee.Entity{}, int64(42), nil)
+
+			got, err := svc.FindUsers(ctx, tt.request)
+
+			a.NoError(err)
+			a.Equal([]string{SchemaListResponse}, got.Schemas)
+			a.Equal(int64(42), got.TotalResults)
+			a.Equal(tt.offset+1, got.StartIndex)
+			a.Equal(0, got.ItemsPerPage)
+			a.Equal([]User{}, got.Resources)
+		})
+	}
+
+	t.Run("Should map employees with their groups", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		m.repo.On("FindUsers", ctx, mock.Anything).Return([]employee.Entity{john()}, int64(1), nil)
+		m.repo.On("FindGroupsOfUsers", ctx, []int64{7}).
+			Return([]Membership{{RoleId: 3, RoleName: "LEDGER", EmployeeId: 7}}, nil)
+
+		got, err := svc.FindUsers(ctx, ListRequest{Filter: `userName eq "jdoe"`, StartIndex: 1, Count: 10})
+
+		a.NoError(err)
+		a.Len(got.Resources, 1)
+		var user = got.Resources[0]
+		a.Equal("7", user.Id)
+		a.Equal("jdoe", user.UserName)
+		a.Equal("John Doe", user.DisplayName)
+		a.Equal("john@corp.example", user.Emails[0].Value)
+		a.True(*user.Active)
+		a.Equal([]Reference{{Value: "3", Display: "LEDGER"}}, user.Groups)
+	})
+}
+
+func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
+	var user = User{
+		Schemas:  []string{SchemaUser, SchemaEmployee},
+		UserName: "jdoe",
+		Name:     &Name{GivenName: "John", FamilyName: "Doe"},
+		Title:    "accountant",
+		Emails:   []MultiValue{{Value: "home@example.com"}, {Value: "john@corp.example", Primary: true}},
+		Employee: &EmployeeExtension{Age: 30, DepartmentId: 3},
+	}
+
+	t.Run("Should create employee from user", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		m.repo.On("ExistsUserName", ctx, "jdoe", int64(0)).Return(false, nil)
+		m.employees.On("CreateEmployee", ctx, mock.MatchedBy(func(r employee.CreateRequest) bool {
+			return r.Name == "John" && r.Surname == "Doe" && r.Age == 30 && r.Login == "jdoe" &&
+				r.Email == "john@corp.example" && r.DepartmentId == 3 && r.Position == "accountant"
+		})).Return(int64(7), nil)
+		m.repo.On("FindUserById", ctx, int64(7)).Return(john(), nil)
+		m.repo.On("FindGroupsOfUsers", ctx, []int64{7}).Return([]Membership{}, nil)
+
+		got, err := svc.CreateUser(ctx, user)
+
+		a.NoError(err)
+		a.Equal("7", got.Id)
+		m.repo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should create inactive employee with a single insert", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		var inactive = user
+		inactive.Active = new(bool)
+		var created = john()
+		created.Active = false
+		m.repo.On("ExistsUserName", ctx, "jdoe", int64(0)).Return(false, nil)
+		m.employees.On("CreateEmployee", ctx, mock.MatchedBy(func(r employee.CreateRequest) bool {
+			return r.Active != nil && !*r.Active
+		})).Return(int64(7), nil)
+		m.repo.On("FindUserById", ctx, int64(7)).Return(created, nil)
+		m.repo.On("FindGroupsOfUsers", ctx, []int64{7}).Return([]Membership{}, nil)
+
+		got, err := svc.CreateUser(ctx, inactive)
+
+		a.NoError(err)
+		a.False(*got.Active)
+		m.repo.AssertNotCalled(t, "BeginTr")
+		m.repo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name   string
+		modify func(u *User)
+		taken  bool
+		want   any
+	}{
+		{name: "missing userName", modify: func(u *User) { u.UserName = "" }, want: common.RequestValidationError{}},
+		{name: "missing familyName", modify: func(u *User) { u.Name = &Name{GivenName: "John"} }, want: common.RequestValidationError{}},
+		{name: "missing age", modify: func(u *User) { u.Employee = nil }, want: common.RequestValidationError{}},
+		{name: "taken userName", modify: func(u *User) {}, taken: true, want: common.AlreadyExistsError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			svc, m := newTestService()
+			var request = user
+			request.Employee = &EmployeeExtension{Age: 30}
+			tt.modify(&request)
+			m.repo.On("ExistsUserName", ctx, mock.Anything, int64(0)).Return(tt.taken, nil)
+
+			_, err := svc.CreateUser(ctx, request)
+
+			switch tt.want.(type) {
+			case common.RequestValidationError:
+				a.True(errors.As(err, &common.RequestValidationError{}))
+			case common.AlreadyExistsError:
+				a.True(errors.As(err, &common.AlreadyExistsError{}))
+			}
+			m.employees.AssertNotCalled(t, "CreateEmployee", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		operations string
+		update     func(e *employee.Entity)
+		move       *employee.MoveRequest
+		scimType   string
+	}{
+		{
+			name:       "deactivate with path-less replace and string boolean",
+			operations: `[{"op":"Replace","value":{"active":"False"}}]`,
+			update:     func(e *employee.Entity) { e.Active = false },
+		},
+		{
+			name:       "replace given name",
+			operations: `[{"op":"replace","path":"name.givenName","value":"Jon"}]`,
+			update:     func(e *employee.Entity) { e.Name = "Jon" },
+		},
+		{
+			name:       "path-less object with dotted keys",
+			operations: `[{"op":"replace","value":{"name.familyName":"Roe","userName":"jroe"}}]`,
+			update: func(e *employee.Entity) {
+				e.Surname = "Roe"
+				e.Login = sql.NullString{String: "jroe", Valid: true}
+			},
+		},
+		{
+			name:       "email through value filter",
+			operations: `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"jd@corp.example"}]`,
+			update:     func(e *employee.Entity) { e.Email = "jd@corp.example" },
+		},
+		{
+			name:       "remove phone numbers",
+			operations: `[{"op":"remove","path":"phoneNumbers"}]`,
+			update:     func(e *employee.Entity) { e.Phone = "" },
+		},
+		{
+			name:       "title change moves employee",
+			operations: `[{"op":"replace","path":"title","value":"controller"}]`,
+			update:     func(e *employee.Entity) {},
+			move:       &employee.MoveRequest{DepartmentId: 3, Position: "controller", ManagerId: 5},
+		},
+		{
+			name:       "extension attribute moves employee",
+			operations: `[{"op":"replace","path":"urn:idm:params:scim:schemas:extension:employee:2.0:User:departmentId","value":4}]`,
+			update:     func(e *employee.Entity) {},
+			move:       &employee.MoveRequest{DepartmentId: 4, Position: "accountant", ManagerId: 5},
+		},
+		{name: "read-only attribute", operations: `[{"op":"replace","path":"displayName","value":"X"}]`, scimType: ErrMutability},
+		{name: "unknown attribute", operations: `[{"op":"replace","path":"nickName","value":"X"}]`, scimType: ErrInvalidPath},
+		{name: "remove without path", operations: `[{"op":"remove"}]`, scimType: ErrNoTarget},
+		{name: "unknown op", operations: `[{"op":"copy","path":"title"}]`, scimType: ErrInvalidSyntax},
+		{name: "wrong value type", operations: `[{"op":"replace","path":"title","value":5}]`, scimType: ErrInvalidValue},
+		{name: "no operations", operations: `[]`, scimType: ErrInvalidSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			svc, m := newTestService()
+			var request = PatchRequest{Schemas: []string{SchemaPatchOp}}
+			a.NoError(json.Unmarshal([]byte(tt.operations), &request.Operations))
+			var current = john()
+			m.repo.On("FindUserById", ctx, int64(7)).Return(current, nil)
+			m.repo.On("FindGroupsOfUsers", ctx, []int64{7}).Return([]Membership{}, nil)
+			m.repo.On("ExistsUserName", ctx, mock.Anything, int64(7)).Return(false, nil)
+			if tt.update != nil {
+				var updated = current
+				tt.update(&updated)
+				tx, _ := testutil.NewTx(t, true)
+				m.repo.On("BeginTr").Return(tx, nil)
+				m.repo.On("UpdateUser", tx, updated).Return(updated, nil)
+				m.events.On("Add", tx, updatedEvent(7)).Return(nil)
+				if tt.move != nil {
+					m.employees.On("MoveInTx", ctx, tx, int64(7), *tt.move).Return(employee.Response{}, nil)
+				}
+			}
+
+			_, err := svc.PatchUser(ctx, "7", request)
+
+			if tt.scimType != "" {
+				var scimErr Error
+				a.True(errors.As(err, &scimErr), "got %v", err)
+				a.Equal(tt.scimType, scimErr.ScimType)
+				m.repo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+				return
+			}
+			a.NoError(err)
+			m.repo.AssertExpectations(t)
+			m.events.AssertExpectations(t)
+			if tt.move == nil {
+				m.employees.AssertNotCalled(t, "MoveInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	t.Run("Should rollback attribute update when move fails", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		var request = PatchRequest{Schemas: []string{SchemaPatchOp}, Operations: []PatchOperation{
+			{Op: "replace", Path: "name.givenName", Value: json.RawMessage(`"Jon"`)},
+			{Op: "replace", Path: "title", Value: json.RawMessage(`"controller"`)},
+		}}
+		var current = john()
+		var updated = current
+		updated.Name = "Jon"
+		tx, mockTr := testutil.NewTx(t, false)
+		m.repo.On("FindUserById", ctx, int64(7)).Return(current, nil)
+		m.repo.On("ExistsUserName", ctx, mock.Anything, int64(7)).Return(false, nil)
+		m.repo.On("BeginTr").Return(tx, nil)
+		m.repo.On("UpdateUser", tx, updated).Return(updated, nil)
+		m.events.On("Add", tx, updatedEvent(7)).Return(nil)
+		m.employees.On("MoveInTx", ctx, tx, int64(7), mock.Anything).
+			Return(employee.Response{}, common.ForbiddenError{Message: "Department is outside of administered departments"})
+
+		_, err := svc.PatchUser(ctx, "7", request)
+
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should require PatchOp schema", func(t *testing.T) {
+		t.Parallel()
+		svc, _ := newTestService()
+
+		_, err := svc.PatchUser(ctx, "7", PatchRequest{Operations: []PatchOperation{{Op: "remove", Path: "title"}}})
+
+		var scimErr Error
+		assert.True(t, errors.As(err, &scimErr))
+		assert.Equal(t, ErrInvalidSyntax, scimErr.ScimType)
+	})
+
+	t.Run("Should return not found for unknown user", func(t *testing.T) {
+		t.Parallel()
+		svc, m := newTestService()
+		m.repo.On("FindUserById", ctx, int64(9)).Return(employee.Entity{}, sql.ErrNoRows)
+
+		_, err := svc.PatchUser(ctx, "9", PatchRequest{Schemas: []string{SchemaPatchOp},
+			Operations: []PatchOperation{{Op: "remove", Path: "title"}}})
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestPatchGroup(t *testing.T) {
+	ctx := context.Background()
+	var ledger = role.Entity{Id: 3, Name: "LEDGER", Description: "General ledger", RiskLevel: role.RiskHigh, Requestable: false}
+	var members = []Membership{{RoleId: 3, EmployeeId: 1}, {RoleId: 3, EmployeeId: 2}}
+	tests := []struct {
+		name       string
+		operations string
+		assigned   []int64
+		revoked    []int64
+		rename     string
+		scimType   string
+	}{
+		{name: "add members", operations: `[{"op":"add","path":"members","value":[{"value":"2"},{"value":"5"}]}]`,
+			assigned: []int64{5}},
+		{name: "remove member by filter", operations: `[{"op":"remove","path":"members[value eq \"2\"]"}]`,
+			revoked: []int64{2}},
+		{name: "remove members by value list", operations: `[{"op":"remove","path":"members","value":[{"value":"1"}]}]`,
+			revoked: []int64{1}},
+		{name: "replace members", operations: `[{"op":"replace","path":"members","value":[{"value":"2"},{"value":"6"}]}]`,
+			assigned: []int64{6}, revoked: []int64{1}},
+		{name: "remove all members", operations: `[{"op":"remove","path":"members"}]`, revoked: []int64{1, 2}},
+		{name: "rename keeps role metadata", operations: `[{"op":"replace","value":{"id":"3","displayName":"GL"}}]`,
+			rename: "GL"},
+		{name: "member filter on add", operations: `[{"op":"add","path":"members[value eq \"2\"]","value":[]}]`,
+			scimType: ErrInvalidPath},
+		{name: "unsupported member filter", operations: `[{"op":"remove","path":"members[display eq \"x\"]"}]`,
+			scimType: ErrInvalidFilter},
+		{name: "wrong member value", operations: `[{"op":"add","path":"members","value":[{"value":"abc"}]}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			svc, m := newTestService()
+			var request = PatchRequest{Schemas: []string{SchemaPatchOp}}
+			a.NoError(json.Unmarshal([]byte(tt.operations), &request.Operations))
+			m.repo.On("FindGroupById", ctx, int64(3)).Return(ledger, nil)
+			m.repo.On("FindMembers", ctx, []int64{3}).Return(members, nil)
+			var tx *sqlx.Tx
+			if tt.assigned != nil || tt.revoked != nil {
+				tx, _ = testutil.NewTx(t, true)
+				m.repo.On("BeginTr").Return(tx, nil)
+			}
+			for _, id := range tt.assigned {
+				m.assigner.On("AssignInTx", ctx, tx, "admin", assignment.AssignRequest{EmployeeId: id, RoleId: 3}).
+					Return(assignment.Response{}, nil)
+			}
+			for _, id := range tt.revoked {
+				m.assigner.On("RevokeInTx", ctx, tx, "admin", assignment.RevokeRequest{EmployeeId: id, RoleId: 3}).Return(nil)
+			}
+			if tt.rename != "" {
+				var renamed = ledger.ToRequest()
+				renamed.Name = tt.rename
+				m.repo.On("ExistsGroupName", ctx, tt.rename, int64(3)).Return(false, nil)
+				m.roles.On("Update", ctx, int64(3), renamed).Return(role.Response{}, nil)
+			}
+
+			_, err := svc.PatchGroup(ctx, "admin", "3", request)
+
+			switch {
+			case tt.scimType != "":
+				var scimErr Error
+				a.True(errors.As(err, &scimErr), "got %v", err)
+				a.Equal(tt.scimType, scimErr.ScimType)
+			case tt.assigned == nil && tt.revoked == nil && tt.rename == "":
+				a.True(errors.As(err, &common.RequestValidationError{}))
+			default:
+				a.NoError(err)
+				m.assigner.AssertExpectations(t)
+				m.roles.AssertExpectations(t)
+			}
+			if tt.assigned == nil {
+				m.assigner.AssertNotCalled(t, "AssignInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.revoked == nil {
+				m.assigner.AssertNotCalled(t, "RevokeInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	t.Run("Should rollback all membership changes when one of them fails", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		var provisioner = new(MockProvisioner)
+		svc.Provisioning = provisioner
+		var request = PatchRequest{Schemas: []string{SchemaPatchOp}}
+		a.NoError(json.Unmarshal([]byte(`[{"op":"replace","path":"members","value":[{"value":"2"},{"value":"6"}]}]`),
+			&request.Operations))
+		tx, mockTr := testutil.NewTx(t, false)
+		m.repo.On("FindGroupById", ctx, int64(3)).Return(ledger, nil)
+		m.repo.On("FindMembers", ctx, []int64{3}).Return(members, nil)
+		m.repo.On("BeginTr").Return(tx, nil)
+		m.assigner.On("AssignInTx", ctx, tx, "admin", assignment.AssignRequest{EmployeeId: 6, RoleId: 3}).
+			Return(assignment.Response{}, nil)
+		m.assigner.On("RevokeInTx", ctx, tx, "admin", assignment.RevokeRequest{EmployeeId: 1, RoleId: 3}).
+			Return(errors.New("db failure"))
+
+		_, err := svc.PatchGroup(ctx, "admin", "3", request)
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		provisioner.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should provision changed members after commit", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		var provisioner = new(MockProvisioner)
+		svc.Provisioning = provisioner
+		var request = PatchRequest{Schemas: []string{SchemaPatchOp}}
+		a.NoError(json.Unmarshal([]byte(`[{"op":"replace","path":"members","value":[{"value":"2"},{"value":"6"}]}]`),
+			&request.Operations))
+		tx, mockTr := testutil.NewTx(t, true)
+		m.repo.On("FindGroupById", ctx, int64(3)).Return(ledger, nil)
+		m.repo.On("FindMembers", ctx, []int64{3}).Return(members, nil)
+		m.repo.On("BeginTr").Return(tx, nil)
+		m.assigner.On("AssignInTx", ctx, tx, "admin", mock.Anything).Return(assignment.Response{}, nil)
+		m.assigner.On("RevokeInTx", ctx, tx, "admin", mock.Anything).Return(nil)
+		provisioner.On("Provision", ctx, []int64{6, 1}).Return()
+
+		_, err := svc.PatchGroup(ctx, "admin", "3", request)
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		provisioner.AssertExpectations(t)
+	})
+}
+
+func TestCreateGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should create role and assign members", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		svc, m := newTestService()
+		m.repo.On("ExistsGroupName", ctx, "LEDGER", int64(0)).Return(false, nil)
+		m.roles.On("Add", mock.MatchedBy(func(e role.Entity) bool {
+			return e.Name == "LEDGER" && e.RiskLevel == role.RiskLow && e.Requestable
+		})).Return(role.Response{Id: 3, Name: "LEDGER"}, nil)
+		tx, _ := testutil.NewTx(t, true)
+		m.repo.On("BeginTr").Return(tx, nil)
+		m.assigner.On("AssignInTx", ctx, tx, "admin", assignment.AssignRequest{EmployeeId: 7, RoleId: 3}).
+			Return(assignment.Response{}, nil)
+		m.repo.On("FindGroupById", ctx, int64(3)).Return(role.Entity{Id: 3, Name: "LEDGER"}, nil)
+		m.repo.On("FindMembers", ctx, []int64{3}).
+			Return([]Membership{{RoleId: 3, EmployeeId: 7, EmployeeName: "John", EmployeeSurname: "Doe"}}, nil)
+
+		got, err := svc.CreateGroup(ctx, "admin", Group{DisplayName: "LEDGER", Members: []Reference{{Value: "7"}}})
+
+		a.NoError(err)
+		a.Equal("3", got.Id)
+		a.Equal([]Reference{{Value: "7", Display: "John Doe"}}, got.Members)
+		m.assigner.AssertExpectations(t)
+	})
+
+	t.Run("Should reject duplicated displayName", func(t *testing.T) {
+		t.Parallel()
+		svc, m := newTestService()
+		m.repo.On("ExistsGroupName", ctx, "LEDGER", int64(0)).Return(true, nil)
+
+		_, err := svc.CreateGroup(ctx, "admin", Group{DisplayName: "LEDGER"})
+
+		assert.True(t, errors.As(err, &common.AlreadyExistsError{}))
+		m.roles.AssertNotCalled(t, "Add", mock.Anything)
+	})
+}
+
+func john() employee.Entity {
+	return employee.Entity{
+		Id:           7,
+		Name:         "John",
+		Surname:      "Doe",
+		Age:          30,
+		Login:        sql.NullString{String: "jdoe", Valid: true},
+		Email:        "john@corp.example",
+		Phone:        "+100",
+		DepartmentId: sql.NullInt64{Int64: 3, Valid: true},
+		ManagerId:    sql.NullInt64{Int64: 5, Valid: true},
+		Position:     "accountant",
+		Active:       true,
+	}
+}
@@ -0,0 +1,93 @@
+package sod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// ModeBlock - назначение, нарушающее правило, отклоняется
+	ModeBlock = "BLOCK"
+	// ModeWarn - назначение возможно только с указанием причины, которая сохраняется в аудите
+	ModeWarn = "WARN"
+)
+
+type Entity struct {
+	Id          int64         `db:"id"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	Mode        string        `db:"mode"`
+	RoleIds     pq.Int64Array `db:"role_ids"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	return Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		Mode:        e.Mode,
+		RoleIds:     e.RoleIds,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Mode        string    `json:"mode" example:"BLOCK"`
+	RoleIds     []int64   `json:"role_ids"`
+	CreatedAt   time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// CreateRequest - набор взаимоисключающих ролей: сотрудник не должен владеть двумя и более из них
+type CreateRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=155"`
+	Description string  `json:"description" validate:"max=500"`
+	Mode        string  `json:"mode" validate:"required,oneof=BLOCK WARN" example:"BLOCK"`
+	RoleIds     []int64 `json:"role_ids" validate:"required,min=2,unique,dive,min=1"`
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Name:        req.Name,
+		Description: req.Description,
+		Mode:        req.Mode,
+		RoleIds:     req.RoleIds,
+	}
+}
+
+// Violation - правило, нарушенное набором ролей сотрудника
+type Violation struct {
+	EmployeeId int64          `db:"employee_id" json:"employee_id"`
+	RuleId     int64          `db:"rule_id" json:"rule_id"`
+	RuleName   string         `db:"rule_name" json:"rule_name"`
+	Mode       string         `db:"mode" json:"mode"`
+	RoleNames  pq.StringArray `db:"role_names" json:"role_names"`
+}
+
+// ViolationError - назначение роли нарушает правила SoD
+type ViolationError struct {
+	Violations []Violation
+	// Overridable - все нарушенные правила в режиме WARN, назначение возможно с указанием причины
+	Overridable bool
+}
+
+func (err ViolationError) Error() string {
+	var rules = make([]string, 0, len(err.Violations))
+	for _, v := range err.Violations {
+		rules = append(rules, fmt.Sprintf("%s (%s: %s)", v.RuleName, v.Mode, strings.Join(v.RoleNames, ", ")))
+	}
+	var message = "Segregation of duties violated: " + strings.Join(rules, "; ")
+	if err.Overridable {
+		message += ". Provide override_reason to assign anyway"
+	}
+	return message
+}
@@ -0,0 +1,173 @@
+package sod
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Add(ctx context.Context, request CreateRequest) (int64, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	FindViolations(ctx context.Context) ([]Violation, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/sod"
+func (c *Handler) RegisterRoutes() {
+	var read = c.server.RequirePermission(web.PermSodRead)
+	var write = c.server.RequirePermission(web.PermSodWrite)
+	c.server.GroupApiV1.Get("/sod/rules", read, c.FindAll)
+	c.server.GroupApiV1.Get("/sod/rules/:id", read, c.FindById)
+	c.server.GroupApiV1.Post("/sod/rules", write, c.Add)
+	c.server.GroupApiV1.Delete("/sod/rules/:id", write, c.DeleteById)
+	c.server.GroupApiV1.Get("/sod/violations", read, c.FindViolations)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/sod/rules"
+// @Description Create segregation of duties rule: a set of mutually exclusive roles.
+// @Summary create sod rule
+// @Tags sod
+// @Accept json
+// @Produce json
+// @Param request body CreateRequest true "sod rule"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[int64] "invalid request or already exists"
+// @Failure 403 {object} common.Response[int64] "Permission denied"
+// @Failure 500 {object} common.Response[int64] "error db"
+// @Router /sod/rules [post]
+// @Security BearerAuth
+func (c *Handler) Add(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "Add: received request", zap.Any("request", request))
+	id, err := c.service.Add(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error adding sod rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/sod/rules/:id"
+// @Description Find sod rule by id.
+// @Summary find sod rule
+// @Tags sod
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} common.Response[sod.Response]
+// @Failure 400 {object} common.Response[sod.Response] "invalid request"
+// @Failure 404 {object} common.Response[sod.Response] "not found"
+// @Failure 500 {object} common.Response[sod.Response] "error db"
+// @Router /sod/rules/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding sod rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/sod/rules"
+// @Description Get all sod rules.
+// @Summary get sod rules
+// @Tags sod
+// @Produce json
+// @Success 200 {object} common.Response[[]sod.Response]
+// @Failure 403 {object} common.Response[[]sod.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]sod.Response] "error db"
+// @Router /sod/rules [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding sod rules", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/sod/rules/:id"
+// @Description Delete sod rule by id.
+// @Summary delete sod rule
+// @Tags sod
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /sod/rules/{id} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteById(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error deleting sod rule", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/sod/violations"
+// @Description Report of all current sod violations across employees.
+// @Summary sod violations report
+// @Tags sod
+// @Produce json
+// @Success 200 {object} common.Response[[]sod.Violation]
+// @Failure 403 {object} common.Response[[]sod.Violation] "Permission denied"
+// @Failure 500 {object} common.Response[[]sod.Violation] "error db"
+// @Router /sod/violations [get]
+// @Security BearerAuth
+func (c *Handler) FindViolations(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindViolations(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindViolations: error building report", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,139 @@
+package sod
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// selectRules - правила вместе с идентификаторами входящих в них ролей
+const selectRules = `SELECT r.*, COALESCE(array_agg(srr.role_id ORDER BY srr.role_id)
+		FILTER (WHERE srr.role_id IS NOT NULL), '{}') AS role_ids
+	FROM sod_rule r
+	LEFT JOIN sod_rule_role srr ON srr.rule_id = r.id`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Add(tx *sqlx.Tx, rule Entity) (id int64, err error) {
+	err = tx.Get(&id,
+		"INSERT INTO sod_rule(name, description, mode) VALUES ($1, $2, $3) RETURNING id",
+		rule.Name, rule.Description, rule.Mode)
+	if err != nil {
+		return 0, err
+	}
+	for _, roleId := range rule.RoleIds {
+		if _, err = tx.Exec("INSERT INTO sod_rule_role(rule_id, role_id) VALUES ($1, $2)", id, roleId); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+func (r *Repository) ExistsByName(tx *sqlx.Tx, name string) (isExists bool, err error) {
+	err = tx.Get(&isExists, "SELECT EXISTS(SELECT FROM sod_rule WHERE name = $1)", name)
+	return isExists, err
+}
+
+// FindMissingRoles - идентификаторы из roleIds, для которых нет роли
+func (r *Repository) FindMissingRoles(tx *sqlx.Tx, roleIds []int64) (missing []int64, err error) {
+	err = tx.Select(&missing, `SELECT id FROM unnest($1::BIGINT[]) AS ids(id)
+		WHERE NOT EXISTS(SELECT FROM role WHERE role.id = ids.id) ORDER BY id`, pq.Array(roleIds))
+	return missing, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (rule Entity, err error) {
+	err = r.db.GetContext(ctx, &rule, selectRules+" WHERE r.id = $1 GROUP BY r.id", id)
+	return rule, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) (rules []Entity, err error) {
+	err = r.db.SelectContext(ctx, &rules, selectRules+" GROUP BY r.id ORDER BY r.name")
+	return rules, err
+}
+
+func (r *Repository) DeleteById(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM sod_rule WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindConflicts - правила, которые будут нарушены, если сотруднику employeeId назначить роль roleId
+// на срок [validFrom, validUntil), validUntil NULL - бессрочно. Учитываются составные роли и назначения,
+// срок которых пересекается с новым, в том числе ещё не начавшиеся; нарушения, не связанные с новой
+// ролью, не возвращаются. Проверка берёт блокировку сотрудника до конца транзакции, поэтому
+// параллельные назначения одному сотруднику проверяются по очереди и видят друг друга
+func (r *Repository) FindConflicts(tx *sqlx.Tx, employeeId, roleId int64, validFrom time.Time,
+	validUntil sql.NullTime) (violations []Violation, err error) {
+	_, err = tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended('sod:' || $1::TEXT, 0))", employeeId)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Select(&violations,
+		`WITH RECURSIVE granted AS (
+			SELECT $2::BIGINT AS id
+			UNION
+			SELECT rc.child_role_id FROM role_composite rc JOIN granted g ON rc.parent_role_id = g.id
+		), held AS (
+			SELECT er.role_id AS id FROM employee_role er
+			WHERE er.employee_id = $1
+			  AND er.role_id <> $2
+			  AND ($4::TIMESTAMPTZ IS NULL OR er.valid_from < $4)
+			  AND (er.valid_until IS NULL OR er.valid_until > GREATEST($3::TIMESTAMPTZ, NOW()))
+			UNION
+			SELECT rc.child_role_id FROM role_composite rc JOIN held h ON rc.parent_role_id = h.id
+		), all_roles AS (
+			SELECT id FROM granted UNION SELECT id FROM held
+		)
+		SELECT $1::BIGINT AS employee_id, r.id AS rule_id, r.name AS rule_name, r.mode,
+		       array_agg(role.name ORDER BY role.name) AS role_names
+		FROM sod_rule r
+		JOIN sod_rule_role srr ON srr.rule_id = r.id
+		JOIN all_roles a ON a.id = srr.role_id
+		JOIN role ON role.id = srr.role_id
+		GROUP BY r.id
+		HAVING COUNT(*) >= 2 AND bool_or(srr.role_id IN (SELECT id FROM granted))
+		ORDER BY r.id`,
+		employeeId, roleId, validFrom, validUntil)
+	return violations, err
+}
+
+// FindViolations - все текущие нарушения правил SoD по всем сотрудникам
+func (r *Repository) FindViolations(ctx context.Context) (violations []Violation, err error) {
+	err = r.db.SelectContext(ctx, &violations,
+		`WITH RECURSIVE held AS (
+			SELECT er.employee_id, er.role_id AS id FROM employee_role er
+			WHERE er.valid_from <= NOW() AND (er.valid_until IS NULL OR er.valid_until > NOW())
+			UNION
+			SELECT h.employee_id, rc.child_role_id FROM role_composite rc JOIN held h ON rc.parent_role_id = h.id
+		)
+		SELECT h.employee_id, r.id AS rule_id, r.name AS rule_name, r.mode,
+		       array_agg(role.name ORDER BY role.name) AS role_names
+		FROM held h
+		JOIN sod_rule_role srr ON srr.role_id = h.id
+		JOIN sod_rule r ON r.id = srr.rule_id
+		JOIN role ON role.id = h.id
+		GROUP BY h.employee_id, r.id
+		HAVING COUNT(*) >= 2
+		ORDER BY h.employee_id, r.id`)
+	return violations, err
+}
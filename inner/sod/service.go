@@ -0,0 +1,138 @@
+package sod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+type Service struct {
+	repo      Repo
+	validator *validator.Validate
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	Add(tx *sqlx.Tx, rule Entity) (int64, error)
+	ExistsByName(tx *sqlx.Tx, name string) (bool, error)
+	FindMissingRoles(tx *sqlx.Tx, roleIds []int64) ([]int64, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	DeleteById(ctx context.Context, id int64) (bool, error)
+	FindViolations(ctx context.Context) ([]Violation, error)
+}
+
+func NewService(repo Repo) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator.New(),
+	}
+}
+
+// Add - создаёт правило вместе с набором взаимоисключающих ролей
+func (svc *Service) Add(ctx context.Context, request CreateRequest) (id int64, err error) {
+	if err = svc.validator.Struct(request); err != nil {
+		return 0, common.RequestValidationError{Message: err.Error()}
+	}
+	err = common.InTx(svc.repo, "adding sod rule", func(tx *sqlx.Tx) error {
+		isExists, err := svc.repo.ExistsByName(tx, request.Name)
+		if err != nil {
+			return fmt.Errorf("Error checking sod rule %s existence: %w", request.Name, err)
+		}
+		if isExists {
+			return common.AlreadyExistsError{Message: fmt.Sprintf("SoD rule %s already exists", request.Name)}
+		}
+		missing, err := svc.repo.FindMissingRoles(tx, request.RoleIds)
+		if err != nil {
+			return fmt.Errorf("Error checking roles of sod rule %s: %w", request.Name, err)
+		}
+		if len(missing) > 0 {
+			return common.NotFoundError{Message: fmt.Sprintf("Roles %v not found", missing)}
+		}
+		id, err = svc.repo.Add(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("Error adding sod rule %s: %w", request.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id sod rule: %d", id)}
+	}
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("SoD rule with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error finding sod rule with id %d: %w", id, err)
+	}
+	return entity.ToResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding sod rules: %w", err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses, nil
+}
+
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id sod rule: %d", id)}
+	}
+	deleted, err := svc.repo.DeleteById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting sod rule with id %d: %w", id, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("SoD rule with id %d not found", id)}
+	}
+	return nil
+}
+
+// FindViolations - отчёт по всем сотрудникам, чьи действующие роли нарушают правила SoD
+func (svc *Service) FindViolations(ctx context.Context) ([]Violation, error) {
+	violations, err := svc.repo.FindViolations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding sod violations: %w", err)
+	}
+	if violations == nil {
+		violations = []Violation{}
+	}
+	return violations, nil
+}
+
+// Check - разбирает нарушения, найденные при назначении роли.
+// BLOCK запрещает назначение всегда, WARN - только если не указана причина переопределения
+func Check(violations []Violation, overrideReason string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	var overridable = true
+	for _, v := range violations {
+		if v.Mode != ModeWarn {
+			overridable = false
+			break
+		}
+	}
+	if overridable && overrideReason != "" {
+		return nil
+	}
+	return ViolationError{Violations: violations, Overridable: overridable}
+}
@@ -0,0 +1,183 @@
+package sod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/testutil"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) Add(tx *sqlx.Tx, rule Entity) (int64, error) {
+	args := m.Called(tx, rule)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) ExistsByName(tx *sqlx.Tx, name string) (bool, error) {
+	args := m.Called(tx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindMissingRoles(tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	args := m.Called(tx, roleIds)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindViolations(ctx context.Context) ([]Violation, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Violation), args.Error(1)
+}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add rule with its roles", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, true)
+		request := CreateRequest{Name: "payments", Mode: ModeBlock, RoleIds: []int64{1, 2}}
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("ExistsByName", tx, "payments").Return(false, nil)
+		repo.On("FindMissingRoles", tx, []int64{1, 2}).Return([]int64{}, nil)
+		repo.On("Add", tx, request.ToEntity()).Return(int64(7), nil)
+
+		id, err := svc.Add(ctx, request)
+
+		a.NoError(err)
+		a.Equal(int64(7), id)
+		a.NoError(mockTr.ExpectationsWereMet())
+	})
+
+	t.Run("Should rollback when rule already exists", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("ExistsByName", tx, "payments").Return(true, nil)
+
+		_, err := svc.Add(ctx, CreateRequest{Name: "payments", Mode: ModeWarn, RoleIds: []int64{1, 2}})
+
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return NotFoundError for unknown roles", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("ExistsByName", tx, "payments").Return(false, nil)
+		repo.On("FindMissingRoles", tx, []int64{1, 99}).Return([]int64{99}, nil)
+
+		_, err := svc.Add(ctx, CreateRequest{Name: "payments", Mode: ModeBlock, RoleIds: []int64{1, 99}})
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.NoError(mockTr.ExpectationsWereMet())
+		repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should reject invalid rules", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			name    string
+			request CreateRequest
+		}{
+			{"single role", CreateRequest{Name: "payments", Mode: ModeBlock, RoleIds: []int64{1}}},
+			{"duplicate roles", CreateRequest{Name: "payments", Mode: ModeBlock, RoleIds: []int64{1, 1}}},
+			{"unknown mode", CreateRequest{Name: "payments", Mode: "DENY", RoleIds: []int64{1, 2}}},
+			{"empty name", CreateRequest{Mode: ModeBlock, RoleIds: []int64{1, 2}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := new(MockRepo)
+				svc := NewService(repo)
+
+				_, err := svc.Add(ctx, tt.request)
+
+				assert.True(t, errors.As(err, &common.RequestValidationError{}))
+				repo.AssertNotCalled(t, "BeginTr")
+			})
+		}
+	})
+}
+
+func TestFindById(t *testing.T) {
+	t.Run("Should return NotFoundError when rule is missing", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo)
+		repo.On("FindById", mock.Anything, int64(5)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.FindById(context.Background(), 5)
+
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestCheck(t *testing.T) {
+	warn := Violation{RuleName: "payments", Mode: ModeWarn}
+	block := Violation{RuleName: "treasury", Mode: ModeBlock}
+	tests := []struct {
+		name        string
+		violations  []Violation
+		reason      string
+		wantErr     bool
+		overridable bool
+	}{
+		{"no violations", nil, "", false, false},
+		{"warn without reason", []Violation{warn}, "", true, true},
+		{"warn with reason", []Violation{warn}, "approved by CFO", false, false},
+		{"block with reason", []Violation{block}, "approved by CFO", true, false},
+		{"warn and block with reason", []Violation{warn, block}, "approved by CFO", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.violations, tt.reason)
+
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var violationErr ViolationError
+			assert.True(t, errors.As(err, &violationErr))
+			assert.Equal(t, tt.overridable, violationErr.Overridable)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"idm/inner/outbox"
+)
+
+// Event - событие потока. Seq - порядковый номер публикации: передаётся клиенту как id
+// и возвращается им в Last-Event-ID при переподключении
+type Event struct {
+	Seq     int64
+	Message outbox.Message
+}
+
+func toEvent(entity outbox.Entity) Event {
+	return Event{Seq: entity.PublishedSeq.Int64, Message: entity.ToMessage()}
+}
+
+// Encode - событие в формате text/event-stream; data - outbox.Message в JSON
+func (e *Event) Encode() ([]byte, error) {
+	data, err := json.Marshal(e.Message)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Message.Type, data), nil
+}
@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/web"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// heartbeatInterval - период комментария-пинга: прокси не закрывают простаивающее
+	// подключение, а отключение клиента обнаруживается по ошибке записи
+	heartbeatInterval = 15 * time.Second
+	// retryMillis - пауза перед переподключением, которую браузер берёт из поля retry
+	retryMillis = 3000
+	// queryTimeout - ограничение чтения событий: контекст запроса в потоке уже недоступен
+	queryTimeout = 10 * time.Second
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Cursor(ctx context.Context, lastEventId string) (int64, error)
+	Next(ctx context.Context, cursor int64) ([]Event, bool, error)
+	Changed() <-chan struct{}
+	Closing() <-chan struct{}
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрута "/api/v1/events/stream"
+func (c *Handler) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/events/stream", c.server.RequirePermission(web.PermEventRead), c.Stream)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/events/stream"
+// @Description Server-Sent Events stream of employee and role changes. Event id is the publication sequence; reconnect with the Last-Event-ID header (or last_event_id query) to resume without gaps. Without it only new events are sent.
+// @Summary stream change events
+// @Tags event
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "Last received event id"
+// @Param last_event_id query string false "Last received event id, for clients that cannot set headers"
+// @Success 200 {object} outbox.Message "stream of events, data is the message"
+// @Failure 400 {object} common.Response[any] "invalid Last-Event-ID"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /events/stream [get]
+// @Security BearerAuth
+func (c *Handler) Stream(ctx *fiber.Ctx) error {
+	var lastEventId = ctx.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("last_event_id")
+	}
+	cursor, err := c.service.Cursor(ctx.Context(), lastEventId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Stream: error starting stream", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	// отключает буферизацию ответа в nginx
+	ctx.Set("X-Accel-Buffering", "no")
+	var actor = "unknown"
+	if claims, ok := web.ClaimsFromCtx(ctx); ok {
+		actor = claims.Actor()
+	}
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		c.logger.Debug("Stream: subscriber connected", zap.String("actor", actor), zap.Int64("cursor", cursor))
+		cursor = c.stream(w, cursor)
+		c.logger.Debug("Stream: subscriber disconnected", zap.String("actor", actor), zap.Int64("cursor", cursor))
+	})
+	return nil
+}
+
+// stream - пишет события после cursor, пока клиент подключён и приложение работает.
+// Возвращает курсор последнего отправленного события
+func (c *Handler) stream(w *bufio.Writer, cursor int64) int64 {
+	var heartbeat = time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retryMillis); err != nil || w.Flush() != nil {
+		return cursor
+	}
+	for {
+		select {
+		case <-c.service.Closing():
+			return cursor
+		default:
+		}
+		var changed = c.service.Changed()
+		events, more, err := c.next(cursor)
+		if err != nil {
+			// клиент переподключится и продолжит с последнего полученного события
+			c.logger.Error("Stream: error reading events", zap.Int64("cursor", cursor), zap.Error(err))
+			return cursor
+		}
+		for _, e := range events {
+			frame, err := e.Encode()
+			if err != nil {
+				c.logger.Error("Stream: error encoding event", zap.Int64("seq", e.Seq), zap.Error(err))
+				return cursor
+			}
+			if _, err = w.Write(frame); err != nil {
+				return cursor
+			}
+			cursor = e.Seq
+		}
+		if err = w.Flush(); err != nil {
+			return cursor
+		}
+		if more {
+			continue
+		}
+		if !c.wait(w, changed, heartbeat.C) {
+			return cursor
+		}
+	}
+}
+
+// wait - ждёт новых публикаций, отправляя пинги; false - клиент отключился или приложение останавливается
+func (c *Handler) wait(w *bufio.Writer, changed <-chan struct{}, heartbeat <-chan time.Time) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-c.service.Closing():
+			return false
+		case <-heartbeat:
+			if _, err := w.WriteString(": ping\n\n"); err != nil || w.Flush() != nil {
+				return false
+			}
+		}
+	}
+}
+
+func (c *Handler) next(cursor int64) ([]Event, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	return c.service.Next(ctx, cursor)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"context"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func newTestServer(roles []string, svc Svc) *web.Server {
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := web.NewServer()
+	claims := &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}, PreferredUsername: "john"}
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	NewHandler(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestStreamHandler(t *testing.T) {
+	t.Run("Should replay events after Last-Event-ID and end on shutdown", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		repo.On("FindAfter", mock.Anything, int64(40), 10).
+			Return([]outbox.Entity{published(41, outbox.EmployeeCreated), published(42, outbox.EmployeeMoved)}, nil)
+		var req = httptest.NewRequest(fiber.MethodGet, "/api/v1/events/stream", nil)
+		req.Header.Set("Last-Event-ID", "40")
+		time.AfterFunc(100*time.Millisecond, svc.Close)
+
+		resp, err := server.App.Test(req, -1)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal("text/event-stream", resp.Header.Get(fiber.HeaderContentType))
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Contains(string(body), "retry: 3000\n\n")
+		a.Contains(string(body), "id: 41\nevent: EmployeeCreated\n")
+		a.Contains(string(body), "id: 42\nevent: EmployeeMoved\n")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should push events published after connecting", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+		server := newTestServer([]string{web.IdmAdmin}, svc)
+		repo.On("LastSeq", mock.Anything).Return(int64(7), nil).Once()
+		repo.On("FindAfter", mock.Anything, int64(7), 10).Return([]outbox.Entity{}, nil).Once()
+		repo.On("LastSeq", mock.Anything).Return(int64(8), nil)
+		repo.On("FindAfter", mock.Anything, int64(7), 10).Return([]outbox.Entity{published(8, outbox.RoleCreated)}, nil)
+		repo.On("FindAfter", mock.Anything, int64(8), 10).Return([]outbox.Entity{}, nil)
+		time.AfterFunc(50*time.Millisecond, func() { _ = svc.Poll(context.Background()) })
+		time.AfterFunc(150*time.Millisecond, svc.Close)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/events/stream", nil), -1)
+
+		a.Nil(err)
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Contains(string(body), "id: 8\nevent: RoleCreated\n")
+	})
+
+	t.Run("Should return 400 on malformed Last-Event-ID", func(t *testing.T) {
+		t.Parallel()
+		server := newTestServer([]string{web.IdmAdmin}, NewService(new(MockRepo), 10))
+		var req = httptest.NewRequest(fiber.MethodGet, "/api/v1/events/stream?last_event_id=x", nil)
+
+		resp, err := server.App.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Should return 403 without permission", func(t *testing.T) {
+		t.Parallel()
+		server := newTestServer([]string{web.IdmUser}, NewService(new(MockRepo), 10))
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/events/stream", nil))
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -0,0 +1,29 @@
+package stream
+
+import (
+	"context"
+	"idm/inner/outbox"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// FindAfter - опубликованные события с номером больше seq в порядке публикации
+func (r *Repository) FindAfter(ctx context.Context, seq int64, limit int) (events []outbox.Entity, err error) {
+	err = r.db.SelectContext(ctx, &events,
+		"SELECT * FROM outbox WHERE published_seq > $1 ORDER BY published_seq LIMIT $2", seq, limit)
+	return events, err
+}
+
+// LastSeq - номер последнего опубликованного события, 0 - событий нет
+func (r *Repository) LastSeq(ctx context.Context) (seq int64, err error) {
+	err = r.db.GetContext(ctx, &seq, "SELECT COALESCE(MAX(published_seq), 0) FROM outbox")
+	return seq, err
+}
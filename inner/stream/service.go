@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"strconv"
+	"sync"
+)
+
+// Service - поток опубликованных событий для SSE-подписчиков. Каждый экземпляр приложения
+// опрашивает номер последней публикации и будит свои подключения; подключение само читает
+// события после своего курсора, поэтому отставшие и переподключившиеся клиенты ничего не теряют
+type Service struct {
+	repo      Repo
+	batchSize int
+
+	mu      sync.Mutex
+	last    int64
+	changed chan struct{}
+
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+type Repo interface {
+	FindAfter(ctx context.Context, seq int64, limit int) ([]outbox.Entity, error)
+	LastSeq(ctx context.Context) (int64, error)
+}
+
+func NewService(repo Repo, batchSize int) *Service {
+	return &Service{
+		repo:      repo,
+		batchSize: batchSize,
+		changed:   make(chan struct{}),
+		closing:   make(chan struct{}),
+	}
+}
+
+// Cursor - курсор нового подключения. lastEventId - id последнего полученного события;
+// пустой - поток начинается с событий, опубликованных после подключения
+func (svc *Service) Cursor(ctx context.Context, lastEventId string) (int64, error) {
+	if lastEventId != "" {
+		seq, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || seq < 0 {
+			return 0, common.RequestValidationError{Message: fmt.Sprintf("Wrong Last-Event-ID: %s", lastEventId)}
+		}
+		return seq, nil
+	}
+	seq, err := svc.repo.LastSeq(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Error finding last published event: %w", err)
+	}
+	return seq, nil
+}
+
+// Next - очередная порция событий после cursor. more - порция выбрана целиком
+func (svc *Service) Next(ctx context.Context, cursor int64) (events []Event, more bool, err error) {
+	entities, err := svc.repo.FindAfter(ctx, cursor, svc.batchSize)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error finding events after %d: %w", cursor, err)
+	}
+	events = make([]Event, 0, len(entities))
+	for _, e := range entities {
+		events = append(events, toEvent(e))
+	}
+	return events, len(entities) == svc.batchSize, nil
+}
+
+// Poll - проверяет, появились ли новые публикации, и будит подключения
+func (svc *Service) Poll(ctx context.Context) error {
+	seq, err := svc.repo.LastSeq(ctx)
+	if err != nil {
+		return fmt.Errorf("Error finding last published event: %w", err)
+	}
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if seq > svc.last {
+		svc.last = seq
+		close(svc.changed)
+		svc.changed = make(chan struct{})
+	}
+	return nil
+}
+
+// Changed - канал, который закроется при следующей публикации. Канал берётся до чтения
+// событий, чтобы публикация между чтением и ожиданием не потерялась
+func (svc *Service) Changed() <-chan struct{} {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.changed
+}
+
+// Closing - канал, закрываемый при остановке приложения
+func (svc *Service) Closing() <-chan struct{} {
+	return svc.closing
+}
+
+// Close - завершает все подключения; без этого открытые потоки задержали бы остановку сервера
+func (svc *Service) Close() {
+	svc.closeOnce.Do(func() { close(svc.closing) })
+}
@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindAfter(ctx context.Context, seq int64, limit int) ([]outbox.Entity, error) {
+	args := m.Called(ctx, seq, limit)
+	return args.Get(0).([]outbox.Entity), args.Error(1)
+}
+
+func (m *MockRepo) LastSeq(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// published - опубликованное событие с номером seq
+func published(seq int64, eventType string) outbox.Entity {
+	return outbox.Entity{
+		Id:            seq + 100,
+		AggregateType: outbox.AggregateEmployee,
+		AggregateId:   7,
+		Type:          eventType,
+		Payload:       []byte(`{"id":7}`),
+		PublishedSeq:  sql.NullInt64{Int64: seq, Valid: true},
+	}
+}
+
+func TestCursor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should resume after Last-Event-ID", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+
+		cursor, err := svc.Cursor(ctx, "41")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(41), cursor)
+		repo.AssertNotCalled(t, "LastSeq", mock.Anything)
+	})
+
+	t.Run("Should start from last publication without Last-Event-ID", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+		repo.On("LastSeq", ctx).Return(int64(57), nil)
+
+		cursor, err := svc.Cursor(ctx, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(57), cursor)
+	})
+
+	t.Run("Should reject malformed Last-Event-ID", func(t *testing.T) {
+		svc := NewService(new(MockRepo), 10)
+
+		_, err := svc.Cursor(ctx, "abc")
+
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+	})
+}
+
+func TestNext(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should return events in publication order and report full batch", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, 2)
+		repo.On("FindAfter", ctx, int64(40), 2).
+			Return([]outbox.Entity{published(41, outbox.EmployeeCreated), published(42, outbox.RoleAssigned)}, nil)
+
+		events, more, err := svc.Next(ctx, 40)
+
+		a.NoError(err)
+		a.True(more)
+		a.Len(events, 2)
+		a.Equal(int64(42), events[1].Seq)
+		a.Equal(int64(142), events[1].Message.Id)
+	})
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should wake subscribers only when new events are published", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+		repo.On("LastSeq", ctx).Return(int64(5), nil).Once()
+		repo.On("LastSeq", ctx).Return(int64(5), nil).Once()
+
+		var first = svc.Changed()
+		a.NoError(svc.Poll(ctx))
+		a.True(isClosed(first))
+		var second = svc.Changed()
+		a.NoError(svc.Poll(ctx))
+
+		a.False(isClosed(second))
+	})
+
+	t.Run("Should keep subscribers waiting when database is unavailable", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, 10)
+		repo.On("LastSeq", ctx).Return(int64(0), errors.New("connection refused"))
+		var changed = svc.Changed()
+
+		assert.Error(t, svc.Poll(ctx))
+		assert.False(t, isClosed(changed))
+	})
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("Should encode event as SSE frame", func(t *testing.T) {
+		var event = toEvent(published(3, outbox.EmployeeCreated))
+		event.Message.CreatedAt = event.Message.CreatedAt.UTC()
+
+		frame, err := event.Encode()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "id: 3\nevent: EmployeeCreated\n"+
+			`data: {"id":103,"type":"EmployeeCreated","aggregate_type":"employee","aggregate_id":7,`+
+			`"payload":{"id":7},"created_at":"0001-01-01T00:00:00Z"}`+"\n\n", string(frame))
+	})
+}
@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Poller interface {
+	Poll(ctx context.Context) error
+	Close()
+}
+
+// Worker - фоновый процесс, каждые interval проверяющий новые публикации событий
+type Worker struct {
+	poller   Poller
+	interval time.Duration
+	logger   *common.Logger
+	runner   common.Runner
+}
+
+func NewWorker(poller Poller, interval time.Duration, logger *common.Logger) *Worker {
+	return &Worker{
+		poller:   poller,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *Worker) Start() {
+	w.runner.Start(w.run)
+}
+
+// CloseStreams - завершает открытые подключения; вызывается до остановки сервера
+func (w *Worker) CloseStreams() {
+	w.poller.Close()
+}
+
+// Stop - завершает подключения, останавливает worker и ждёт завершения текущей проверки
+func (w *Worker) Stop(ctx context.Context) error {
+	w.poller.Close()
+	return w.runner.Stop(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.poller.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Worker: error polling published events", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package testutil - общие вспомогательные функции для модульных тестов
+package testutil
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewTx - транзакция sqlmock, которая должна быть зафиксирована (commit) или откачена
+func NewTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mockTr, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	mockTr.ExpectBegin()
+	if commit {
+		mockTr.ExpectCommit()
+	} else {
+		mockTr.ExpectRollback()
+	}
+	tx, err := sqlx.NewDb(db, "sqlmock").Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, mockTr
+}
@@ -16,7 +16,8 @@ const (
 )
 
 type IdmClaims struct {
-	RealmAccess RealmAccessClaims `json:"realm_access"`
+	RealmAccess       RealmAccessClaims `json:"realm_access"`
+	PreferredUsername string            `json:"preferred_username"`
 	jwt.RegisteredClaims
 }
 
@@ -24,6 +25,27 @@ type RealmAccessClaims struct {
 	Roles []string `json:"roles"`
 }
 
+// ClaimsFromCtx - claims токена, сохранённые AuthMiddleware в контексте запроса
+func ClaimsFromCtx(ctx *fiber.Ctx) (*IdmClaims, bool) {
+	token, ok := ctx.Locals(JwtKey).(*jwt.Token)
+	if !ok || token == nil {
+		return nil, false
+	}
+	claims, ok := token.Claims.(*IdmClaims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// Actor - имя вызывающего пользователя для журнала аудита
+func (c *IdmClaims) Actor() string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+	return c.Subject
+}
+
 var AuthMiddleware = func(logger *common.Logger) fiber.Handler {
 	config := jwtMiddleware.Config{
 		ContextKey:   JwtKey,
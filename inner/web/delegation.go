@@ -0,0 +1,36 @@
+package web
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const DepartmentsKey = "administered_departments"
+
+// DelegationResolver - источник делегированных полномочий: отделы, которыми вызывающий
+// управляет как администратор отдела, вместе со всеми дочерними отделами
+type DelegationResolver interface {
+	FindAdministeredDepartments(ctx context.Context, claims *IdmClaims) ([]int64, error)
+}
+
+// AdministeredDepartments - отделы, сотрудниками которых вызывающий может управлять без
+// глобального разрешения. Вычисляются один раз за запрос и кэшируются в ctx.Locals
+func (s *Server) AdministeredDepartments(ctx *fiber.Ctx) ([]int64, error) {
+	if departments, ok := ctx.Locals(DepartmentsKey).([]int64); ok {
+		return departments, nil
+	}
+	claims, ok := ClaimsFromCtx(ctx)
+	if !ok || s.Delegations == nil {
+		return nil, nil
+	}
+	departments, err := s.Delegations.FindAdministeredDepartments(ctx.Context(), claims)
+	if err != nil {
+		return nil, err
+	}
+	if departments == nil {
+		departments = []int64{}
+	}
+	ctx.Locals(DepartmentsKey, departments)
+	return departments, nil
+}
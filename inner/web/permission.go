@@ -0,0 +1,133 @@
+package web
+
+import (
+	"context"
+	"idm/inner/common"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const PermissionsKey = "permissions"
+
+// Разрешения, которыми защищены эндпоинты. Выдаются ролям через таблицу role_permission
+const (
+	PermEmployeeRead    = "employee:read"
+	PermEmployeeWrite   = "employee:write"
+	PermEmployeeDelete  = "employee:delete"
+	PermRoleRead        = "role:read"
+	PermRoleWrite       = "role:write"
+	PermAssignmentRead  = "assignment:read"
+	PermAssignmentWrite = "assignment:write"
+	PermPermissionRead  = "permission:read"
+	PermPermissionWrite = "permission:write"
+	PermProfileRead     = "profile:read"
+	PermProfileWrite    = "profile:write"
+	PermSodRead         = "sod:read"
+	PermSodWrite        = "sod:write"
+	// PermAccessRequestSubmit - подача своих запросов и решения по запросам, где вызывающий - согласующий
+	PermAccessRequestSubmit = "access_request:submit"
+	// PermAccessRequestManage - просмотр всех запросов и согласование любого шага
+	PermAccessRequestManage = "access_request:manage"
+	// PermCertificationReview - решения по назначениям, где вызывающий - проверяющий
+	PermCertificationReview = "certification:review"
+	PermCertificationManage = "certification:manage"
+	PermBirthrightRead      = "birthright:read"
+	PermBirthrightWrite     = "birthright:write"
+	// PermDepartmentDelegate - назначение администраторов отделов
+	PermDepartmentDelegate = "department:delegate"
+	// PermScimRead, PermScimWrite - доступ SCIM-клиентов: справочников и внешних сервисов
+	PermScimRead  = "scim:read"
+	PermScimWrite = "scim:write"
+	// PermProvisioningRead, PermProvisioningWrite - статусы синхронизации с Keycloak и повтор вручную
+	PermProvisioningRead  = "provisioning:read"
+	PermProvisioningWrite = "provisioning:write"
+	// PermWebhookRead, PermWebhookWrite - подписки внешних систем на события и журнал доставок
+	PermWebhookRead  = "webhook:read"
+	PermWebhookWrite = "webhook:write"
+	// PermEventRead - поток изменений сотрудников и ролей
+	PermEventRead = "event:read"
+	// PermLdifExport, PermLdifImport - выгрузка каталога в LDIF и загрузка из него
+	PermLdifExport = "ldif:export"
+	PermLdifImport = "ldif:import"
+)
+
+// PermissionResolver - источник разрешений, выданных ролям
+type PermissionResolver interface {
+	FindPermissionsByRoleNames(ctx context.Context, roleNames []string) ([]string, error)
+}
+
+// StaticPermissionResolver - разрешения ролей, заданные в коде
+type StaticPermissionResolver map[string][]string
+
+func (r StaticPermissionResolver) FindPermissionsByRoleNames(_ context.Context, roleNames []string) ([]string, error) {
+	var permissions []string
+	for _, name := range roleNames {
+		for _, permission := range r[name] {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// DefaultPermissions - разрешения встроенных ролей Keycloak; используются, пока серверу
+// не передан резолвер, читающий role_permission из базы. Совпадают с начальным наполнением миграции
+var DefaultPermissions = StaticPermissionResolver{
+	IdmAdmin: {
+		PermEmployeeWrite, PermEmployeeDelete, PermRoleWrite, PermAssignmentRead, PermAssignmentWrite,
+		PermPermissionWrite, PermProfileRead, PermProfileWrite, PermSodRead, PermSodWrite,
+		PermAccessRequestSubmit, PermAccessRequestManage, PermCertificationReview, PermCertificationManage,
+		PermBirthrightRead, PermBirthrightWrite, PermDepartmentDelegate, PermScimRead, PermScimWrite,
+		PermProvisioningRead, PermProvisioningWrite, PermWebhookRead, PermWebhookWrite, PermEventRead,
+		PermLdifExport, PermLdifImport,
+	},
+	IdmUser: {
+		PermEmployeeRead, PermRoleRead, PermPermissionRead, PermProfileRead, PermProfileWrite,
+		PermAccessRequestSubmit, PermCertificationReview,
+	},
+}
+
+// HasPermission - есть ли у вызывающего пользователя разрешение, выданное одной из ролей токена.
+// Разрешения вычисляются один раз за запрос и кэшируются в ctx.Locals
+func (s *Server) HasPermission(ctx *fiber.Ctx, permission string) (bool, error) {
+	if permissions, ok := ctx.Locals(PermissionsKey).([]string); ok {
+		return slices.Contains(permissions, permission), nil
+	}
+	claims, ok := ClaimsFromCtx(ctx)
+	if !ok {
+		return false, nil
+	}
+	var resolver = s.Permissions
+	if resolver == nil {
+		resolver = DefaultPermissions
+	}
+	permissions, err := resolver.FindPermissionsByRoleNames(ctx.Context(), claims.RealmAccess.Roles)
+	if err != nil {
+		return false, err
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	ctx.Locals(PermissionsKey, permissions)
+	return slices.Contains(permissions, permission), nil
+}
+
+// RequirePermission - middleware, пропускающий запрос дальше только при наличии разрешения
+func (s *Server) RequirePermission(permission string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if allowed, err := s.HasPermission(ctx, permission); !allowed {
+			return DenyResponse(ctx, err)
+		}
+		return ctx.Next()
+	}
+}
+
+// DenyResponse - ответ на неудачную проверку разрешения: 500, если разрешения не удалось получить, иначе 403
+func DenyResponse(ctx *fiber.Ctx, err error) error {
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error resolving permissions")
+	}
+	return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+}
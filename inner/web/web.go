@@ -11,6 +11,12 @@ type Server struct {
 	GroupApi      fiber.Router
 	GroupApiV1    fiber.Router
 	GroupInternal fiber.Router
+	// GroupScim - группа "/scim/v2" для SCIM-клиентов, вне "/api"
+	GroupScim fiber.Router
+	// Permissions - резолвер разрешений по ролям токена, по умолчанию DefaultPermissions
+	Permissions PermissionResolver
+	// Delegations - резолвер делегированных администраторов отделов, nil - делегирования нет
+	Delegations DelegationResolver
 }
 
 type AuthMiddlewareInterface interface {
@@ -29,10 +35,14 @@ func NewServer() *Server {
 	groupApi := app.Group("/api")
 	// подгруппа "api/v1"
 	groupApiV1 := groupApi.Group("/v1")
+	// группа "/scim/v2"
+	groupScim := app.Group("/scim/v2")
 	return &Server{
 		App:           app,
 		GroupApi:      groupApi,
 		GroupApiV1:    groupApiV1,
 		GroupInternal: groupInternal,
+		GroupScim:     groupScim,
+		Permissions:   DefaultPermissions,
 	}
 }
@@ -1,12 +1,16 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,3 +48,88 @@ func TestNewServerMiddleware(t *testing.T) {
 		a.NotEmpty(res.Header.Get("X-Request-ID"))
 	})
 }
+
+type StubResolver struct {
+	permissions []string
+	err         error
+	calls       int
+}
+
+func (s *StubResolver) FindPermissionsByRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	s.calls++
+	return s.permissions, s.err
+}
+
+func TestRequirePermission(t *testing.T) {
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(JwtKey, &jwt.Token{Claims: &IdmClaims{RealmAccess: RealmAccessClaims{Roles: []string{"AUDITOR"}}}})
+		return c.Next()
+	}
+
+	t.Run("Should pass when role grants permission", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		resolver := &StubResolver{permissions: []string{"report:read"}}
+		server := NewServer()
+		server.Permissions = resolver
+		server.App.Use(auth)
+		server.App.Get("/report", server.RequirePermission("report:read"), func(c *fiber.Ctx) error {
+			if ok, _ := server.HasPermission(c, "report:read"); !ok {
+				return c.SendStatus(http.StatusForbidden)
+			}
+			return c.SendStatus(http.StatusOK)
+		})
+
+		res, err := server.App.Test(httptest.NewRequest(http.MethodGet, "/report", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, res.StatusCode)
+		a.Equal(1, resolver.calls)
+	})
+
+	t.Run("Should return 403 without permission", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := NewServer()
+		server.Permissions = &StubResolver{permissions: []string{"report:read"}}
+		server.App.Use(auth)
+		server.App.Delete("/report", server.RequirePermission("report:delete"), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+
+		res, err := server.App.Test(httptest.NewRequest(http.MethodDelete, "/report", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("Should return 500 when permissions cannot be resolved", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := NewServer()
+		server.Permissions = &StubResolver{err: errors.New("db down")}
+		server.App.Use(auth)
+		server.App.Get("/report", server.RequirePermission("report:read"), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+
+		res, err := server.App.Test(httptest.NewRequest(http.MethodGet, "/report", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("Should return 403 without token", func(t *testing.T) {
+		t.Parallel()
+		a := assert.New(t)
+		server := NewServer()
+		server.App.Get("/report", server.RequirePermission(PermEmployeeRead), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+
+		res, err := server.App.Test(httptest.NewRequest(http.MethodGet, "/report", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+// Состояния доставки
+const (
+	// StatePending - ожидает отправки или повтора в NextAttemptAt
+	StatePending = "PENDING"
+	// StateDelivered - подписчик ответил 2xx
+	StateDelivered = "DELIVERED"
+	// StateDead - попытки исчерпаны, доставку можно повторить вручную
+	StateDead = "DEAD"
+)
+
+// EventTest - тип тестового события, которое отправляется по запросу администратора
+const EventTest = "WebhookTest"
+
+// Entity - подписка внешней системы на события. Пустой EventTypes - подписка на все события
+type Entity struct {
+	Id         int64          `db:"id"`
+	Url        string         `db:"url"`
+	EventTypes pq.StringArray `db:"event_types"`
+	Secret     string         `db:"secret"`
+	Active     bool           `db:"active"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+}
+
+// ToResponse - секрет подписки в ответы не попадает
+func (e *Entity) ToResponse() Response {
+	var eventTypes = []string(e.EventTypes)
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return Response{
+		Id:         e.Id,
+		Url:        e.Url,
+		EventTypes: eventTypes,
+		Active:     e.Active,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id         int64     `json:"id"`
+	Url        string    `json:"url" example:"https://hr.example.com/idm/events"`
+	EventTypes []string  `json:"event_types" example:"EmployeeCreated,RoleAssigned"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2025-07-29T12:00:00Z"`
+}
+
+// CreateRequest - Secret - ключ подписи HMAC-SHA256, подписчик проверяет им заголовок X-Webhook-Signature
+type CreateRequest struct {
+	Url        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"dive,required"`
+	Secret     string   `json:"secret" validate:"required,min=16,max=255"`
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Url:        req.Url,
+		EventTypes: normalizeTypes(req.EventTypes),
+		Secret:     req.Secret,
+		Active:     true,
+	}
+}
+
+// UpdateRequest - пустой Secret оставляет прежний ключ. Выключенная подписка не получает
+// новых событий, ожидающие доставки отправляются после включения
+type UpdateRequest struct {
+	Url        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"dive,required"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=255"`
+	Active     bool     `json:"active"`
+}
+
+func (req *UpdateRequest) ToEntity() Entity {
+	return Entity{
+		Url:        req.Url,
+		EventTypes: normalizeTypes(req.EventTypes),
+		Secret:     req.Secret,
+		Active:     req.Active,
+	}
+}
+
+func normalizeTypes(eventTypes []string) pq.StringArray {
+	if eventTypes == nil {
+		return pq.StringArray{}
+	}
+	return eventTypes
+}
+
+// Delivery - доставка события одной подписке. Body - тело запроса, сохранённое при постановке
+// в очередь: повторы отправляют те же байты
+type Delivery struct {
+	Id            int64          `db:"id"`
+	WebhookId     int64          `db:"webhook_id"`
+	EventId       sql.NullInt64  `db:"event_id"`
+	EventType     string         `db:"event_type"`
+	Body          types.JSONText `db:"body"`
+	State         string         `db:"state"`
+	Attempts      int            `db:"attempts"`
+	NextAttemptAt time.Time      `db:"next_attempt_at"`
+	LastStatus    sql.NullInt32  `db:"last_status"`
+	LastError     sql.NullString `db:"last_error"`
+	CreatedAt     time.Time      `db:"created_at"`
+	DeliveredAt   sql.NullTime   `db:"delivered_at"`
+}
+
+func (e *Delivery) ToResponse() DeliveryResponse {
+	var response = DeliveryResponse{
+		Id:            e.Id,
+		WebhookId:     e.WebhookId,
+		EventId:       e.EventId.Int64,
+		EventType:     e.EventType,
+		Body:          json.RawMessage(e.Body),
+		State:         e.State,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		LastStatus:    int(e.LastStatus.Int32),
+		LastError:     e.LastError.String,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.DeliveredAt.Valid {
+		response.DeliveredAt = &e.DeliveredAt.Time
+	}
+	return response
+}
+
+type DeliveryResponse struct {
+	Id            int64           `json:"id"`
+	WebhookId     int64           `json:"webhook_id"`
+	EventId       int64           `json:"event_id,omitempty"`
+	EventType     string          `json:"event_type" example:"EmployeeCreated"`
+	Body          json.RawMessage `json:"body" swaggertype:"object"`
+	State         string          `json:"state" example:"DELIVERED"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" example:"2025-07-29T12:00:00Z"`
+	LastStatus    int             `json:"last_status,omitempty" example:"200"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at" example:"2025-07-29T12:00:00Z"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty" example:"2025-07-29T12:00:01Z"`
+}
+
+// DeliveriesRequest - фильтр журнала доставок, новые доставки первыми; пустое состояние - все
+type DeliveriesRequest struct {
+	State string `query:"state" validate:"omitempty,oneof=PENDING DELIVERED DEAD"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// Due - доставка, время которой пришло, вместе с адресом и ключом подписки
+type Due struct {
+	Delivery
+	Url    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// Options - параметры повторов: попытка n ждёт RetryInterval * 2^(n-1), после MaxAttempts
+// неудач доставка переходит в DEAD
+type Options struct {
+	MaxAttempts   int
+	RetryInterval time.Duration
+}
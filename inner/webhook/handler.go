@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	server  *web.Server
+	service Svc
+	logger  *common.Logger
+}
+
+type Svc interface {
+	Add(ctx context.Context, request CreateRequest) (Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest) (Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	FindDeliveries(ctx context.Context, id int64, request DeliveriesRequest) ([]DeliveryResponse, error)
+	Redeliver(ctx context.Context, id, deliveryId int64) error
+	Test(ctx context.Context, id int64) (DeliveryResponse, error)
+}
+
+func NewHandler(server *web.Server, service Svc, logger *common.Logger) *Handler {
+	return &Handler{
+		server:  server,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes - регистрация маршрутов "/api/v1/webhooks"
+func (c *Handler) RegisterRoutes() {
+	var read = c.server.RequirePermission(web.PermWebhookRead)
+	var write = c.server.RequirePermission(web.PermWebhookWrite)
+	c.server.GroupApiV1.Get("/webhooks", read, c.FindAll)
+	c.server.GroupApiV1.Get("/webhooks/:id", read, c.FindById)
+	c.server.GroupApiV1.Post("/webhooks", write, c.Add)
+	c.server.GroupApiV1.Put("/webhooks/:id", write, c.Update)
+	c.server.GroupApiV1.Delete("/webhooks/:id", write, c.DeleteById)
+	c.server.GroupApiV1.Get("/webhooks/:id/deliveries", read, c.FindDeliveries)
+	c.server.GroupApiV1.Post("/webhooks/:id/deliveries/:deliveryId/redeliver", write, c.Redeliver)
+	c.server.GroupApiV1.Post("/webhooks/:id/test", write, c.Test)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/webhooks"
+// @Description Subscribe an external system to domain events. Empty event_types subscribes to all events. Deliveries are signed with HMAC-SHA256 of "timestamp.body" using the secret.
+// @Summary create webhook
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param request body CreateRequest true "webhook"
+// @Success 200 {object} common.Response[webhook.Response]
+// @Failure 400 {object} common.Response[webhook.Response] "invalid request or unknown event type"
+// @Failure 403 {object} common.Response[webhook.Response] "Permission denied"
+// @Failure 500 {object} common.Response[webhook.Response] "error db"
+// @Router /webhooks [post]
+// @Security BearerAuth
+func (c *Handler) Add(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	// запрос целиком не логируется: в нём секрет подписки
+	c.logger.DebugCtx(ctx.Context(), "Add: received request", zap.String("url", request.Url),
+		zap.Strings("event_types", request.EventTypes))
+	rsl, err := c.service.Add(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Add: error adding webhook", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/webhooks"
+// @Description Get all webhooks. Secrets are never returned.
+// @Summary get webhooks
+// @Tags webhook
+// @Produce json
+// @Success 200 {object} common.Response[[]webhook.Response]
+// @Failure 403 {object} common.Response[[]webhook.Response] "Permission denied"
+// @Failure 500 {object} common.Response[[]webhook.Response] "error db"
+// @Router /webhooks [get]
+// @Security BearerAuth
+func (c *Handler) FindAll(ctx *fiber.Ctx) error {
+	rsl, err := c.service.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindAll: error finding webhooks", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/webhooks/:id"
+// @Description Find webhook by id.
+// @Summary find webhook
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} common.Response[webhook.Response]
+// @Failure 400 {object} common.Response[webhook.Response] "invalid request"
+// @Failure 403 {object} common.Response[webhook.Response] "Permission denied"
+// @Failure 404 {object} common.Response[webhook.Response] "not found"
+// @Failure 500 {object} common.Response[webhook.Response] "error db"
+// @Router /webhooks/{id} [get]
+// @Security BearerAuth
+func (c *Handler) FindById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindById: error finding webhook", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/webhooks/:id"
+// @Description Update webhook. Empty secret keeps the current one. A disabled webhook receives no new events; pending deliveries are sent once it is enabled again.
+// @Summary update webhook
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param request body UpdateRequest true "webhook"
+// @Success 200 {object} common.Response[webhook.Response]
+// @Failure 400 {object} common.Response[webhook.Response] "invalid request or unknown event type"
+// @Failure 403 {object} common.Response[webhook.Response] "Permission denied"
+// @Failure 404 {object} common.Response[webhook.Response] "not found"
+// @Failure 500 {object} common.Response[webhook.Response] "error db"
+// @Router /webhooks/{id} [put]
+// @Security BearerAuth
+func (c *Handler) Update(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error body parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Update(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Update: error updating webhook", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/webhooks/:id"
+// @Description Delete webhook together with its delivery log.
+// @Summary delete webhook
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /webhooks/{id} [delete]
+// @Security BearerAuth
+func (c *Handler) DeleteById(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.DeleteById(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "DeleteById: error deleting webhook", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/webhooks/:id/deliveries"
+// @Description Delivery log of the webhook, newest first: attempts, last response status and error. DEAD deliveries exhausted all retries.
+// @Summary get webhook deliveries
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param state query string false "PENDING, DELIVERED or DEAD"
+// @Param limit query int false "Max deliveries, 100 by default"
+// @Success 200 {object} common.Response[[]webhook.DeliveryResponse]
+// @Failure 400 {object} common.Response[[]webhook.DeliveryResponse] "invalid request"
+// @Failure 403 {object} common.Response[[]webhook.DeliveryResponse] "Permission denied"
+// @Failure 404 {object} common.Response[[]webhook.DeliveryResponse] "webhook not found"
+// @Failure 500 {object} common.Response[[]webhook.DeliveryResponse] "error db"
+// @Router /webhooks/{id}/deliveries [get]
+// @Security BearerAuth
+func (c *Handler) FindDeliveries(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindDeliveries: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request DeliveriesRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindDeliveries: error query parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.FindDeliveries(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "FindDeliveries: error finding deliveries", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/webhooks/:id/deliveries/:deliveryId/redeliver"
+// @Description Requeue a DEAD delivery; retries start over.
+// @Summary redeliver webhook delivery
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 200 {object} common.Response[any]
+// @Failure 400 {object} common.Response[any] "invalid request or delivery is not DEAD"
+// @Failure 403 {object} common.Response[any] "Permission denied"
+// @Failure 404 {object} common.Response[any] "not found"
+// @Failure 500 {object} common.Response[any] "error db"
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+// @Security BearerAuth
+func (c *Handler) Redeliver(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Redeliver: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	deliveryId, err := strconv.ParseInt(ctx.Params("deliveryId"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Redeliver: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err := c.service.Redeliver(ctx.Context(), id, deliveryId); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Redeliver: error requeueing delivery", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse[any](ctx, nil)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/webhooks/:id/test"
+// @Description Queue a WebhookTest event for the webhook, even a disabled one. The event is sent asynchronously; its result appears in the delivery log.
+// @Summary send test event
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} common.Response[webhook.DeliveryResponse]
+// @Failure 400 {object} common.Response[webhook.DeliveryResponse] "invalid request"
+// @Failure 403 {object} common.Response[webhook.DeliveryResponse] "Permission denied"
+// @Failure 404 {object} common.Response[webhook.DeliveryResponse] "not found"
+// @Failure 500 {object} common.Response[webhook.DeliveryResponse] "error db"
+// @Router /webhooks/{id}/test [post]
+// @Security BearerAuth
+func (c *Handler) Test(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Test: error param parse", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rsl, err := c.service.Test(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "Test: error queueing test event", zap.Error(err))
+		return errResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, rsl)
+}
+
+func errResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &common.NotFoundError{}):
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+	default:
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func (r *Repository) DB() *sqlx.DB {
+	return r.db
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) BeginTr() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Add(ctx context.Context, webhook Entity) (added Entity, err error) {
+	err = r.db.GetContext(ctx, &added,
+		"INSERT INTO webhook(url, event_types, secret, active) VALUES ($1, $2, $3, $4) RETURNING *",
+		webhook.Url, webhook.EventTypes, webhook.Secret, webhook.Active)
+	return added, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (webhook Entity, err error) {
+	err = r.db.GetContext(ctx, &webhook, "SELECT * FROM webhook WHERE id = $1", id)
+	return webhook, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) (webhooks []Entity, err error) {
+	err = r.db.SelectContext(ctx, &webhooks, "SELECT * FROM webhook ORDER BY id")
+	return webhooks, err
+}
+
+// Update - пустой секрет оставляет прежний; sql.ErrNoRows, если подписки нет
+func (r *Repository) Update(ctx context.Context, webhook Entity) (updated Entity, err error) {
+	err = r.db.GetContext(ctx, &updated,
+		`UPDATE webhook
+		 SET url = $2, event_types = $3, secret = COALESCE(NULLIF($4, ''), secret), active = $5, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING *`,
+		webhook.Id, webhook.Url, webhook.EventTypes, webhook.Secret, webhook.Active)
+	return updated, err
+}
+
+// DeleteById - удаляет подписку вместе с журналом её доставок
+func (r *Repository) DeleteById(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// FindSubscribed - включённые подписки на события типа eventType
+func (r *Repository) FindSubscribed(tx *sqlx.Tx, eventType string) (webhooks []Entity, err error) {
+	err = tx.Select(&webhooks,
+		"SELECT * FROM webhook WHERE active AND (event_types = '{}' OR $1 = ANY(event_types)) ORDER BY id",
+		eventType)
+	return webhooks, err
+}
+
+// AddDelivery - ставит доставку в очередь. Повторная публикация того же события подписке
+// пропускается: id = 0
+func (r *Repository) AddDelivery(tx *sqlx.Tx, delivery Delivery) (id int64, err error) {
+	err = tx.Get(&id,
+		`INSERT INTO webhook_delivery(webhook_id, event_id, event_type, body) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (webhook_id, event_id) DO NOTHING
+		 RETURNING id`,
+		delivery.WebhookId, delivery.EventId, delivery.EventType, delivery.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+func (r *Repository) FindDelivery(ctx context.Context, webhookId, id int64) (delivery Delivery, err error) {
+	err = r.db.GetContext(ctx, &delivery,
+		"SELECT * FROM webhook_delivery WHERE webhook_id = $1 AND id = $2", webhookId, id)
+	return delivery, err
+}
+
+// FindDeliveries - журнал доставок подписки, новые первыми; пустое состояние - все
+func (r *Repository) FindDeliveries(ctx context.Context, webhookId int64, state string, limit int) (deliveries []Delivery, err error) {
+	err = r.db.SelectContext(ctx, &deliveries,
+		`SELECT * FROM webhook_delivery
+		 WHERE webhook_id = $1 AND ($2 = '' OR state = $2)
+		 ORDER BY id DESC
+		 LIMIT $3`,
+		webhookId, state, limit)
+	return deliveries, err
+}
+
+// Redeliver - возвращает доставку из DEAD в очередь с обнулёнными попытками
+func (r *Repository) Redeliver(ctx context.Context, webhookId, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_delivery SET state = 'PENDING', attempts = 0, next_attempt_at = NOW()
+		 WHERE webhook_id = $1 AND id = $2 AND state = 'DEAD'`,
+		webhookId, id)
+	if err != nil {
+		return false, err
+	}
+	rowInter, err := result.RowsAffected()
+	return rowInter > 0, err
+}
+
+// ClaimDue - захватывает доставки, время которых пришло: событий - только включённым подпискам,
+// тестовые - любым. Захваченным доставкам next_attempt_at переносится на until, чтобы другие
+// экземпляры приложения не взяли их до конца отправки; занятые другим экземпляром строки пропускаются.
+// Захват фиксируется сразу, отправка идёт без открытой транзакции
+func (r *Repository) ClaimDue(ctx context.Context, limit int, until time.Time) (due []Due, err error) {
+	err = r.db.SelectContext(ctx, &due,
+		`WITH due AS (
+		     SELECT d.id
+		     FROM webhook_delivery d
+		     JOIN webhook w ON w.id = d.webhook_id
+		     WHERE d.state = 'PENDING' AND d.next_attempt_at <= NOW() AND (w.active OR d.event_id IS NULL)
+		     ORDER BY d.next_attempt_at, d.id
+		     LIMIT $1
+		     FOR UPDATE OF d SKIP LOCKED
+		 )
+		 UPDATE webhook_delivery d
+		 SET next_attempt_at = $2
+		 FROM due, webhook w
+		 WHERE d.id = due.id AND w.id = d.webhook_id
+		 RETURNING d.*, w.url, w.secret`,
+		limit, until)
+	return due, err
+}
+
+// SaveAttempt - сохраняет результат попытки: состояние, счётчик, время следующей попытки и ответ
+func (r *Repository) SaveAttempt(ctx context.Context, delivery Delivery) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_delivery
+		 SET state = $2, attempts = $3, next_attempt_at = $4, last_status = $5, last_error = $6, delivered_at = $7
+		 WHERE id = $1`,
+		delivery.Id, delivery.State, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatus,
+		delivery.LastError, delivery.DeliveredAt)
+	return err
+}
+
+// Release - возвращает захваченные, но не отправленные доставки в очередь без учёта попытки
+func (r *Repository) Release(ctx context.Context, ids []int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE webhook_delivery SET next_attempt_at = NOW() WHERE id = ANY($1) AND state = 'PENDING'",
+		pq.Array(ids))
+	return err
+}
+
+// DeleteDelivered - удаляет доставленные раньше before; DEAD остаются до разбора вручную
+func (r *Repository) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM webhook_delivery WHERE state = 'DELIVERED' AND delivered_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки доставки. Подписчик проверяет подпись и отбрасывает запросы со старой
+// меткой времени, а повторы одного события - по X-Webhook-Delivery
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Sign - подпись "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Метка времени входит в подпись, чтобы перехваченный запрос нельзя было повторить позже
+func Sign(secret string, timestamp int64, body []byte) string {
+	var mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender - отправка доставки подписчику; status - код ответа, 0 - ответа не было
+type Sender interface {
+	Send(ctx context.Context, due Due) (status int, err error)
+}
+
+// HttpSender - отправляет тело доставки POST-запросом с подписью; успех - любой ответ 2xx
+type HttpSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewHttpSender(timeout time.Duration) *HttpSender {
+	return &HttpSender{client: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+func (s *HttpSender) Send(ctx context.Context, due Due) (int, error) {
+	var timestamp = s.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, due.Url, bytes.NewReader(due.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, due.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(due.Id, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(due.Secret, timestamp, due.Body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
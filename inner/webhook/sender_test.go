@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	t.Run("Should match reference HMAC-SHA256", func(t *testing.T) {
+		// echo -n '1700000000.{"id":1}' | openssl dgst -sha256 -hmac 0123456789abcdef
+		assert.Equal(t, "sha256=4bcaced68dfea90a68df035b89cb7fb26692d899d32a1ccb1b0616cf48e4d1ed",
+			Sign("0123456789abcdef", 1700000000, []byte(`{"id":1}`)))
+	})
+}
+
+func TestHttpSender(t *testing.T) {
+	ctx := context.Background()
+	var due = Due{
+		Delivery: Delivery{Id: 10, WebhookId: 1, EventType: "EmployeeCreated", Body: []byte(`{"id":1}`)},
+		Secret:   "0123456789abcdef",
+	}
+
+	t.Run("Should send signed request", func(t *testing.T) {
+		a := assert.New(t)
+		var headers http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = r.Header
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		sender := NewHttpSender(time.Second)
+		sender.now = func() time.Time { return time.Unix(1700000000, 0) }
+		due.Url = server.URL
+
+		status, err := sender.Send(ctx, due)
+
+		a.NoError(err)
+		a.Equal(http.StatusNoContent, status)
+		a.Equal(`{"id":1}`, string(body))
+		a.Equal("EmployeeCreated", headers.Get(HeaderEvent))
+		a.Equal("10", headers.Get(HeaderDelivery))
+		a.Equal("1700000000", headers.Get(HeaderTimestamp))
+		a.Equal(Sign(due.Secret, 1700000000, body), headers.Get(HeaderSignature))
+	})
+
+	t.Run("Should return status and error on non 2xx response", func(t *testing.T) {
+		a := assert.New(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "gone", http.StatusGone)
+		}))
+		defer server.Close()
+		due.Url = server.URL
+
+		status, err := NewHttpSender(time.Second).Send(ctx, due)
+
+		a.Equal(http.StatusGone, status)
+		a.ErrorContains(err, "status 410: gone")
+	})
+}
@@ -0,0 +1,403 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// defaultDeliveriesLimit - размер журнала доставок, если limit не задан
+const defaultDeliveriesLimit = 100
+
+// sendConcurrency - сколько доставок пакета отправляется одновременно
+const sendConcurrency = 8
+
+// claimLease - на сколько захваченные доставки скрываются от других экземпляров приложения.
+// Должна с запасом превышать отправку пакета: batchSize / sendConcurrency попыток по таймауту
+// отправителя. Доставки экземпляра, упавшего во время отправки, повторяются по её истечении
+const claimLease = 5 * time.Minute
+
+// Service - подписки на доменные события и их доставка. Relay outbox передаёт событие
+// в Publish, который только ставит доставки в очередь; отправляет их worker, поэтому
+// медленный или недоступный подписчик не задерживает ни запросы, ни публикацию событий
+type Service struct {
+	repo      Repo
+	sender    Sender
+	options   Options
+	batchSize int
+	validator *validator.Validate
+	logger    common.LoggerInterface
+	wake      chan struct{}
+}
+
+type Repo interface {
+	BeginTr() (*sqlx.Tx, error)
+	Add(ctx context.Context, webhook Entity) (Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	Update(ctx context.Context, webhook Entity) (Entity, error)
+	DeleteById(ctx context.Context, id int64) (bool, error)
+	FindSubscribed(tx *sqlx.Tx, eventType string) ([]Entity, error)
+	AddDelivery(tx *sqlx.Tx, delivery Delivery) (int64, error)
+	FindDelivery(ctx context.Context, webhookId, id int64) (Delivery, error)
+	FindDeliveries(ctx context.Context, webhookId int64, state string, limit int) ([]Delivery, error)
+	Redeliver(ctx context.Context, webhookId, id int64) (bool, error)
+	ClaimDue(ctx context.Context, limit int, until time.Time) ([]Due, error)
+	SaveAttempt(ctx context.Context, delivery Delivery) error
+	Release(ctx context.Context, ids []int64) error
+	DeleteDelivered(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewService(repo Repo, sender Sender, options Options, batchSize int, logger common.LoggerInterface) *Service {
+	return &Service{
+		repo:      repo,
+		sender:    sender,
+		options:   options,
+		batchSize: batchSize,
+		validator: validator.New(),
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+func (svc *Service) Add(ctx context.Context, request CreateRequest) (Response, error) {
+	if err := svc.validate(request, request.EventTypes); err != nil {
+		return Response{}, err
+	}
+	added, err := svc.repo.Add(ctx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("Error adding webhook %s: %w", request.Url, err)
+	}
+	return added.ToResponse(), nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	entity, err := svc.findById(ctx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	return entity.ToResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding webhooks: %w", err)
+	}
+	responses := make([]Response, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, e.ToResponse())
+	}
+	return responses, nil
+}
+
+func (svc *Service) Update(ctx context.Context, id int64, request UpdateRequest) (Response, error) {
+	if id <= 0 {
+		return Response{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id webhook: %d", id)}
+	}
+	if err := svc.validate(request, request.EventTypes); err != nil {
+		return Response{}, err
+	}
+	var entity = request.ToEntity()
+	entity.Id = id
+	updated, err := svc.repo.Update(ctx, entity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("Webhook with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("Error updating webhook with id %d: %w", id, err)
+	}
+	if updated.Active {
+		svc.notify()
+	}
+	return updated.ToResponse(), nil
+}
+
+// DeleteById - удаляет подписку; недоставленные события ей больше не отправляются
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id webhook: %d", id)}
+	}
+	deleted, err := svc.repo.DeleteById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting webhook with id %d: %w", id, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("Webhook with id %d not found", id)}
+	}
+	return nil
+}
+
+// FindDeliveries - журнал доставок подписки
+func (svc *Service) FindDeliveries(ctx context.Context, id int64, request DeliveriesRequest) ([]DeliveryResponse, error) {
+	if err := svc.validator.Struct(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	if _, err := svc.findById(ctx, id); err != nil {
+		return nil, err
+	}
+	var limit = request.Limit
+	if limit == 0 {
+		limit = defaultDeliveriesLimit
+	}
+	deliveries, err := svc.repo.FindDeliveries(ctx, id, request.State, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding deliveries of webhook %d: %w", id, err)
+	}
+	responses := make([]DeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		responses = append(responses, d.ToResponse())
+	}
+	return responses, nil
+}
+
+// Redeliver - возвращает доставку из DEAD в очередь; попытки считаются заново
+func (svc *Service) Redeliver(ctx context.Context, id, deliveryId int64) error {
+	if id <= 0 || deliveryId <= 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("Wrong id delivery: %d/%d", id, deliveryId)}
+	}
+	requeued, err := svc.repo.Redeliver(ctx, id, deliveryId)
+	if err != nil {
+		return fmt.Errorf("Error requeueing delivery %d: %w", deliveryId, err)
+	}
+	if !requeued {
+		delivery, err := svc.repo.FindDelivery(ctx, id, deliveryId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("Delivery %d of webhook %d not found", deliveryId, id)}
+		}
+		if err != nil {
+			return fmt.Errorf("Error finding delivery %d: %w", deliveryId, err)
+		}
+		return common.RequestValidationError{
+			Message: fmt.Sprintf("Delivery %d is %s, only %s deliveries can be redelivered", deliveryId, delivery.State, StateDead),
+		}
+	}
+	svc.notify()
+	return nil
+}
+
+// Test - ставит в очередь тестовое событие подписки, в том числе выключенной. Результат
+// отправки виден в журнале доставок
+func (svc *Service) Test(ctx context.Context, id int64) (DeliveryResponse, error) {
+	entity, err := svc.findById(ctx, id)
+	if err != nil {
+		return DeliveryResponse{}, err
+	}
+	payload, err := json.Marshal(map[string]int64{"webhook_id": entity.Id})
+	if err != nil {
+		return DeliveryResponse{}, err
+	}
+	body, err := json.Marshal(outbox.Message{
+		Type:      EventTest,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return DeliveryResponse{}, err
+	}
+	var deliveryId int64
+	err = common.InTx(svc.repo, "adding test delivery", func(tx *sqlx.Tx) error {
+		deliveryId, err = svc.repo.AddDelivery(tx, Delivery{WebhookId: entity.Id, EventType: EventTest, Body: body})
+		return err
+	})
+	if err != nil {
+		return DeliveryResponse{}, fmt.Errorf("Error adding test delivery of webhook %d: %w", id, err)
+	}
+	svc.notify()
+	delivery, err := svc.repo.FindDelivery(ctx, entity.Id, deliveryId)
+	if err != nil {
+		return DeliveryResponse{}, fmt.Errorf("Error finding delivery %d: %w", deliveryId, err)
+	}
+	return delivery.ToResponse(), nil
+}
+
+// Publish - ставит событие в очередь доставки каждой подписке на его тип. Реализует
+// outbox.Publisher; повторная публикация того же события дубликатов не создаёт
+func (svc *Service) Publish(ctx context.Context, message outbox.Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("Error encoding event %d: %w", message.Id, err)
+	}
+	var queued int
+	err = common.InTx(svc.repo, "queueing webhook deliveries", func(tx *sqlx.Tx) error {
+		webhooks, err := svc.repo.FindSubscribed(tx, message.Type)
+		if err != nil {
+			return fmt.Errorf("Error finding webhooks subscribed to %s: %w", message.Type, err)
+		}
+		for _, w := range webhooks {
+			id, err := svc.repo.AddDelivery(tx, Delivery{
+				WebhookId: w.Id,
+				EventId:   sql.NullInt64{Int64: message.Id, Valid: true},
+				EventType: message.Type,
+				Body:      body,
+			})
+			if err != nil {
+				return fmt.Errorf("Error queueing event %d for webhook %d: %w", message.Id, w.Id, err)
+			}
+			if id > 0 {
+				queued++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		svc.logger.DebugCtx(ctx, "Publish: webhook deliveries queued",
+			zap.Int64("event_id", message.Id), zap.Int("count", queued))
+		svc.notify()
+	}
+	return nil
+}
+
+// Deliver - захватывает очередную порцию доставок, время которых пришло, и отправляет их вне
+// транзакции, не более sendConcurrency одновременно; результат каждой попытки сохраняется отдельно.
+// Неудачная попытка n откладывает доставку на RetryInterval * 2^(n-1), после MaxAttempts доставка
+// переходит в DEAD. more - порция выбрана целиком и могут быть ещё доставки
+func (svc *Service) Deliver(ctx context.Context) (sent int, more bool, err error) {
+	due, err := svc.repo.ClaimDue(ctx, svc.batchSize, time.Now().Add(claimLease))
+	if err != nil {
+		return 0, false, fmt.Errorf("Error claiming due deliveries: %w", err)
+	}
+	var results = make([]error, len(due))
+	var slots = make(chan struct{}, sendConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range due {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = svc.attempt(ctx, d)
+		}()
+	}
+	wg.Wait()
+	var interrupted []int64
+	var errs []error
+	for i, err := range results {
+		switch {
+		case err == nil:
+			sent++
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			interrupted = append(interrupted, due[i].Id)
+		default:
+			errs = append(errs, err)
+		}
+	}
+	if len(interrupted) > 0 {
+		// остановка приложения: попытка не засчитывается, доставка повторится после запуска
+		if err := svc.repo.Release(context.WithoutCancel(ctx), interrupted); err != nil {
+			errs = append(errs, fmt.Errorf("Error releasing deliveries: %w", err))
+		}
+		errs = append(errs, ctx.Err())
+	}
+	if len(errs) > 0 {
+		return sent, false, errors.Join(errs...)
+	}
+	return sent, len(due) == svc.batchSize, nil
+}
+
+// attempt - отправляет доставку и сохраняет результат попытки. Отправка, прерванная остановкой
+// приложения, попыткой не считается; завершённая сохраняется и во время остановки
+func (svc *Service) attempt(ctx context.Context, d Due) error {
+	status, err := svc.sender.Send(ctx, d)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var delivery = svc.attempted(d.Delivery, status, err)
+	if err != nil {
+		svc.logger.ErrorCtx(ctx, "Deliver: error sending webhook",
+			zap.Int64("delivery_id", d.Id), zap.Int64("webhook_id", d.WebhookId),
+			zap.String("event_type", d.EventType), zap.Int("attempt", delivery.Attempts),
+			zap.String("state", delivery.State), zap.Error(err))
+	}
+	if err := svc.repo.SaveAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+		return fmt.Errorf("Error saving delivery %d: %w", d.Id, err)
+	}
+	return nil
+}
+
+// attempted - доставка после попытки с ответом status и ошибкой err
+func (svc *Service) attempted(delivery Delivery, status int, err error) Delivery {
+	var now = time.Now()
+	delivery.Attempts++
+	delivery.LastStatus = sql.NullInt32{Int32: int32(status), Valid: status > 0}
+	if err == nil {
+		delivery.State = StateDelivered
+		delivery.LastError = sql.NullString{}
+		delivery.DeliveredAt = sql.NullTime{Time: now, Valid: true}
+		return delivery
+	}
+	delivery.LastError = sql.NullString{String: err.Error(), Valid: true}
+	if delivery.Attempts >= svc.options.MaxAttempts {
+		delivery.State = StateDead
+		return delivery
+	}
+	delivery.NextAttemptAt = now.Add(svc.options.RetryInterval << (delivery.Attempts - 1))
+	return delivery
+}
+
+// Purge - удаляет доставленные раньше before
+func (svc *Service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := svc.repo.DeleteDelivered(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("Error deleting deliveries delivered before %s: %w", before, err)
+	}
+	return deleted, nil
+}
+
+// Wake - сигнал worker'у, что в очереди появились доставки
+func (svc *Service) Wake() <-chan struct{} {
+	return svc.wake
+}
+
+func (svc *Service) notify() {
+	select {
+	case svc.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (svc *Service) findById(ctx context.Context, id int64) (Entity, error) {
+	if id <= 0 {
+		return Entity{}, common.RequestValidationError{Message: fmt.Sprintf("Wrong id webhook: %d", id)}
+	}
+	entity, err := svc.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("Webhook with id %d not found", id)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("Error finding webhook with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// validate - проверка запроса и типов событий: подписаться можно только на известные события
+func (svc *Service) validate(request any, eventTypes []string) error {
+	if err := svc.validator.Struct(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(outbox.EventTypes, t) {
+			return common.RequestValidationError{Message: fmt.Sprintf("Unknown event type %s", t)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/outbox"
+	"idm/inner/testutil"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) BeginTr() (*sqlx.Tx, error) {
+	args := m.Called()
+	tx, _ := args.Get(0).(*sqlx.Tx)
+	return tx, args.Error(1)
+}
+
+func (m *MockRepo) Add(ctx context.Context, webhook Entity) (Entity, error) {
+	args := m.Called(ctx, webhook)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) Update(ctx context.Context, webhook Entity) (Entity, error) {
+	args := m.Called(ctx, webhook)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindSubscribed(tx *sqlx.Tx, eventType string) ([]Entity, error) {
+	args := m.Called(tx, eventType)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) AddDelivery(tx *sqlx.Tx, delivery Delivery) (int64, error) {
+	args := m.Called(tx, delivery)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindDelivery(ctx context.Context, webhookId, id int64) (Delivery, error) {
+	args := m.Called(ctx, webhookId, id)
+	return args.Get(0).(Delivery), args.Error(1)
+}
+
+func (m *MockRepo) FindDeliveries(ctx context.Context, webhookId int64, state string, limit int) ([]Delivery, error) {
+	args := m.Called(ctx, webhookId, state, limit)
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *MockRepo) Redeliver(ctx context.Context, webhookId, id int64) (bool, error) {
+	args := m.Called(ctx, webhookId, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) ClaimDue(ctx context.Context, limit int, until time.Time) ([]Due, error) {
+	args := m.Called(ctx, limit, until)
+	return args.Get(0).([]Due), args.Error(1)
+}
+
+func (m *MockRepo) SaveAttempt(ctx context.Context, delivery Delivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockRepo) Release(ctx context.Context, ids []int64) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
+func (m *MockRepo) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockSender struct {
+	mock.Mock
+}
+
+func (m *MockSender) Send(ctx context.Context, due Due) (int, error) {
+	args := m.Called(ctx, due)
+	return args.Int(0), args.Error(1)
+}
+
+type MockLogger struct{}
+
+func (m *MockLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+func (m *MockLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {}
+
+var options = Options{MaxAttempts: 3, RetryInterval: time.Minute}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add webhook without exposing secret", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		var request = CreateRequest{
+			Url:        "https://hr.example.com/events",
+			EventTypes: []string{outbox.EmployeeCreated},
+			Secret:     "0123456789abcdef",
+		}
+		var entity = request.ToEntity()
+		var added = entity
+		added.Id = 1
+		repo.On("Add", ctx, entity).Return(added, nil)
+
+		got, err := svc.Add(ctx, request)
+
+		a.NoError(err)
+		a.Equal(int64(1), got.Id)
+		a.True(got.Active)
+		a.Equal([]string{outbox.EmployeeCreated}, got.EventTypes)
+	})
+
+	t.Run("Should reject unknown event type", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+
+		_, err := svc.Add(ctx, CreateRequest{
+			Url:        "https://hr.example.com/events",
+			EventTypes: []string{"EmployeeHired"},
+			Secret:     "0123456789abcdef",
+		})
+
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.ErrorContains(err, "EmployeeHired")
+		repo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should reject short secret", func(t *testing.T) {
+		svc := NewService(new(MockRepo), new(MockSender), options, 10, &MockLogger{})
+
+		_, err := svc.Add(ctx, CreateRequest{Url: "https://hr.example.com/events", Secret: "short"})
+
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+	})
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	var message = outbox.Message{Id: 42, Type: outbox.RoleAssigned, AggregateType: outbox.AggregateEmployee,
+		AggregateId: 7, Payload: []byte(`{"employee_id":7,"role_id":5}`)}
+
+	t.Run("Should queue delivery for each subscribed webhook", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindSubscribed", tx, outbox.RoleAssigned).Return([]Entity{{Id: 1}, {Id: 2}}, nil)
+		repo.On("AddDelivery", tx, mock.MatchedBy(func(d Delivery) bool {
+			return d.EventId == sql.NullInt64{Int64: 42, Valid: true} && d.EventType == outbox.RoleAssigned
+		})).Return(int64(10), nil).Once()
+		repo.On("AddDelivery", tx, mock.Anything).Return(int64(0), nil).Once()
+
+		err := svc.Publish(ctx, message)
+
+		a.NoError(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		var delivery = repo.Calls[2].Arguments.Get(1).(Delivery)
+		a.Equal(int64(1), delivery.WebhookId)
+		a.JSONEq(`{"id":42,"type":"RoleAssigned","aggregate_type":"employee","aggregate_id":7,
+			"payload":{"employee_id":7,"role_id":5},"created_at":"0001-01-01T00:00:00Z"}`, string(delivery.Body))
+		a.Len(svc.Wake(), 1, "worker must be woken up")
+	})
+
+	t.Run("Should return error so that outbox retries the event", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, false)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("FindSubscribed", tx, outbox.RoleAssigned).Return([]Entity{{Id: 1}}, nil)
+		repo.On("AddDelivery", tx, mock.Anything).Return(int64(0), errors.New("connection reset"))
+
+		err := svc.Publish(ctx, message)
+
+		a.Error(err)
+		a.NoError(mockTr.ExpectationsWereMet())
+		a.Empty(svc.Wake())
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	var due = func(id int64, attempts int) Due {
+		return Due{
+			Delivery: Delivery{Id: id, WebhookId: 1, EventType: outbox.EmployeeCreated, State: StatePending,
+				Attempts: attempts, Body: []byte(`{}`)},
+			Url:    "https://hr.example.com/events",
+			Secret: "0123456789abcdef",
+		}
+	}
+	// saved - результат попытки, сохранённый для доставки id
+	var saved = func(repo *MockRepo, id int64) Delivery {
+		for _, call := range repo.Calls {
+			if call.Method == "SaveAttempt" && call.Arguments.Get(1).(Delivery).Id == id {
+				return call.Arguments.Get(1).(Delivery)
+			}
+		}
+		return Delivery{}
+	}
+	var leased = mock.MatchedBy(func(until time.Time) bool {
+		return until.After(time.Now().Add(claimLease - time.Minute))
+	})
+
+	t.Run("Should claim deliveries with a lease and mark delivered on success", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		sender := new(MockSender)
+		svc := NewService(repo, sender, options, 10, &MockLogger{})
+		repo.On("ClaimDue", ctx, 10, leased).Return([]Due{due(10, 0)}, nil)
+		sender.On("Send", ctx, due(10, 0)).Return(204, nil)
+		repo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+		sent, more, err := svc.Deliver(ctx)
+
+		a.NoError(err)
+		a.Equal(1, sent)
+		a.False(more)
+		repo.AssertNotCalled(t, "BeginTr")
+		var delivery = saved(repo, 10)
+		a.Equal(StateDelivered, delivery.State)
+		a.Equal(1, delivery.Attempts)
+		a.Equal(sql.NullInt32{Int32: 204, Valid: true}, delivery.LastStatus)
+		a.True(delivery.DeliveredAt.Valid)
+	})
+
+	t.Run("Should back off exponentially after failure", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		sender := new(MockSender)
+		svc := NewService(repo, sender, options, 10, &MockLogger{})
+		repo.On("ClaimDue", ctx, 10, leased).Return([]Due{due(10, 1)}, nil)
+		sender.On("Send", ctx, due(10, 1)).Return(503, errors.New("status 503: overloaded"))
+		repo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err := svc.Deliver(ctx)
+
+		a.NoError(err)
+		var delivery = saved(repo, 10)
+		a.Equal(StatePending, delivery.State)
+		a.Equal(2, delivery.Attempts)
+		a.Equal("status 503: overloaded", delivery.LastError.String)
+		a.WithinDuration(time.Now().Add(2*time.Minute), delivery.NextAttemptAt, 5*time.Second)
+	})
+
+	t.Run("Should move to dead letter after last attempt", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		sender := new(MockSender)
+		svc := NewService(repo, sender, options, 10, &MockLogger{})
+		repo.On("ClaimDue", ctx, 10, leased).Return([]Due{due(10, 2)}, nil)
+		sender.On("Send", ctx, due(10, 2)).Return(0, errors.New("connection refused"))
+		repo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err := svc.Deliver(ctx)
+
+		a.NoError(err)
+		var delivery = saved(repo, 10)
+		a.Equal(StateDead, delivery.State)
+		a.Equal(3, delivery.Attempts)
+		a.False(delivery.LastStatus.Valid)
+	})
+
+	t.Run("Should send deliveries concurrently so a slow subscriber does not hold the batch", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		sender := new(MockSender)
+		svc := NewService(repo, sender, options, 2, &MockLogger{})
+		var release = make(chan struct{})
+		repo.On("ClaimDue", ctx, 2, leased).Return([]Due{due(10, 0), due(11, 0)}, nil)
+		sender.On("Send", ctx, due(10, 0)).Run(func(mock.Arguments) { <-release }).Return(204, nil)
+		sender.On("Send", ctx, due(11, 0)).Run(func(mock.Arguments) { close(release) }).Return(204, nil)
+		repo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+		sent, more, err := svc.Deliver(ctx)
+
+		a.NoError(err)
+		a.Equal(2, sent)
+		a.True(more)
+		a.Equal(StateDelivered, saved(repo, 10).State)
+		a.Equal(StateDelivered, saved(repo, 11).State)
+	})
+
+	t.Run("Should release delivery interrupted by shutdown without counting attempt", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		sender := new(MockSender)
+		svc := NewService(repo, sender, options, 10, &MockLogger{})
+		ctx, cancel := context.WithCancel(context.Background())
+		repo.On("ClaimDue", ctx, 10, leased).Return([]Due{due(10, 0)}, nil)
+		sender.On("Send", ctx, due(10, 0)).Run(func(mock.Arguments) { cancel() }).Return(0, context.Canceled)
+		repo.On("Release", mock.Anything, []int64{10}).Return(nil)
+
+		_, _, err := svc.Deliver(ctx)
+
+		a.ErrorIs(err, context.Canceled)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "SaveAttempt", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRedeliver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should requeue dead delivery", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		repo.On("Redeliver", ctx, int64(1), int64(10)).Return(true, nil)
+
+		a.NoError(svc.Redeliver(ctx, 1, 10))
+		a.Len(svc.Wake(), 1)
+	})
+
+	t.Run("Should reject delivered delivery", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		repo.On("Redeliver", ctx, int64(1), int64(10)).Return(false, nil)
+		repo.On("FindDelivery", ctx, int64(1), int64(10)).Return(Delivery{Id: 10, State: StateDelivered}, nil)
+
+		err := svc.Redeliver(ctx, 1, 10)
+
+		a.ErrorAs(err, &common.RequestValidationError{})
+	})
+
+	t.Run("Should return not found for delivery of another webhook", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		repo.On("Redeliver", ctx, int64(2), int64(10)).Return(false, nil)
+		repo.On("FindDelivery", ctx, int64(2), int64(10)).Return(Delivery{}, sql.ErrNoRows)
+
+		err := svc.Redeliver(ctx, 2, 10)
+
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+	})
+}
+
+func TestTest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should queue test event", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		tx, mockTr := testutil.NewTx(t, true)
+		repo.On("FindById", ctx, int64(1)).Return(Entity{Id: 1, Active: false}, nil)
+		repo.On("BeginTr").Return(tx, nil)
+		repo.On("AddDelivery", tx, mock.MatchedBy(func(d Delivery) bool {
+			return d.WebhookId == 1 && d.EventType == EventTest && !d.EventId.Valid
+		})).Return(int64(10), nil)
+		repo.On("FindDelivery", ctx, int64(1), int64(10)).
+			Return(Delivery{Id: 10, WebhookId: 1, EventType: EventTest, State: StatePending}, nil)
+
+		got, err := svc.Test(ctx, 1)
+
+		a.NoError(err)
+		a.Equal(int64(10), got.Id)
+		a.Equal(StatePending, got.State)
+		a.NoError(mockTr.ExpectationsWereMet())
+		var delivery = repo.Calls[2].Arguments.Get(1).(Delivery)
+		a.Contains(string(delivery.Body), `"payload":{"webhook_id":1}`)
+	})
+
+	t.Run("Should return not found", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, new(MockSender), options, 10, &MockLogger{})
+		repo.On("FindById", ctx, int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.Test(ctx, 9)
+
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+	})
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"idm/inner/common"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// purgeInterval - период удаления доставок старше срока хранения
+const purgeInterval = time.Hour
+
+type Deliverer interface {
+	Deliver(ctx context.Context) (sent int, more bool, err error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	Wake() <-chan struct{}
+}
+
+// Worker - фоновый процесс, отправляющий доставки подписчикам. Просыпается каждые interval
+// и сразу после постановки доставок в очередь; раз в час удаляет доставки старше retention
+type Worker struct {
+	deliverer Deliverer
+	interval  time.Duration
+	retention time.Duration
+	logger    *common.Logger
+	runner    common.Runner
+}
+
+func NewWorker(deliverer Deliverer, interval, retention time.Duration, logger *common.Logger) *Worker {
+	return &Worker{
+		deliverer: deliverer,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Start - запускает worker в отдельной горутине
+func (w *Worker) Start() {
+	w.runner.Start(w.run)
+}
+
+// Stop - останавливает worker и ждёт завершения текущей отправки
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var purge = time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	for {
+		w.deliver(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			w.purge(ctx)
+		case <-w.deliverer.Wake():
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context) {
+	for ctx.Err() == nil {
+		sent, more, err := w.deliverer.Deliver(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error("Worker: error delivering webhooks", zap.Error(err))
+			}
+			return
+		}
+		if sent > 0 {
+			w.logger.Debug("Worker: webhook deliveries attempted", zap.Int("count", sent))
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func (w *Worker) purge(ctx context.Context) {
+	deleted, err := w.deliverer.Purge(ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		w.logger.Error("Worker: error purging webhook deliveries", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Worker: webhook deliveries purged", zap.Int64("count", deleted))
+	}
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type StubDeliverer struct {
+	calls atomic.Int32
+	wake  chan struct{}
+}
+
+func (s *StubDeliverer) Deliver(ctx context.Context) (int, bool, error) {
+	s.calls.Add(1)
+	return 0, false, nil
+}
+
+func (s *StubDeliverer) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *StubDeliverer) Wake() <-chan struct{} {
+	return s.wake
+}
+
+func TestWorker(t *testing.T) {
+	t.Run("Should deliver as soon as woken up", func(t *testing.T) {
+		a := assert.New(t)
+		deliverer := &StubDeliverer{wake: make(chan struct{}, 1)}
+		worker := NewWorker(deliverer, time.Hour, time.Hour, &common.Logger{Logger: zap.NewNop()})
+
+		worker.Start()
+		a.Eventually(func() bool { return deliverer.calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+		deliverer.wake <- struct{}{}
+		a.Eventually(func() bool { return deliverer.calls.Load() == 2 }, time.Second, 5*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		a.NoError(worker.Stop(ctx))
+	})
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS department
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    parent_id   BIGINT REFERENCES department (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE department IS 'Подразделения';
+ALTER TABLE employee
+    ADD COLUMN IF NOT EXISTS login         TEXT UNIQUE,
+    ADD COLUMN IF NOT EXISTS email         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS phone         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS department_id BIGINT REFERENCES department (id) ON DELETE SET NULL;
+COMMENT ON COLUMN employee.login IS 'Логин сотрудника в Keycloak (sub или preferred_username)';
+CREATE TABLE IF NOT EXISTS employee_role
+(
+    employee_id BIGINT NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id     BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (employee_id, role_id)
+    );
+COMMENT ON TABLE employee_role IS 'Роли, назначенные сотрудникам';
+-- +goose Down
+DROP TABLE IF EXISTS employee_role;
+ALTER TABLE employee
+    DROP COLUMN IF EXISTS department_id,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS login;
+DROP TABLE IF EXISTS department;
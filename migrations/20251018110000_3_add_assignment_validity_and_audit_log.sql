@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE employee_role
+    ADD COLUMN IF NOT EXISTS valid_from  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS valid_until TIMESTAMPTZ,
+    ADD CONSTRAINT employee_role_validity_check CHECK (valid_until IS NULL OR valid_until > valid_from);
+CREATE INDEX IF NOT EXISTS employee_role_valid_until_idx ON employee_role (valid_until) WHERE valid_until IS NOT NULL;
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    actor       TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    details     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE audit_log IS 'Журнал аудита изменений доступа';
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
+DROP INDEX IF EXISTS employee_role_valid_until_idx;
+ALTER TABLE employee_role
+    DROP CONSTRAINT IF EXISTS employee_role_validity_check,
+    DROP COLUMN IF EXISTS valid_until,
+    DROP COLUMN IF EXISTS valid_from;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS role_composite
+(
+    parent_role_id BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    child_role_id  BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (parent_role_id, child_role_id),
+    CONSTRAINT role_composite_self_check CHECK (parent_role_id <> child_role_id)
+    );
+CREATE INDEX IF NOT EXISTS role_composite_child_idx ON role_composite (child_role_id);
+COMMENT ON TABLE role_composite IS 'Составные роли: родительская роль включает дочерние';
+-- +goose Down
+DROP TABLE IF EXISTS role_composite;
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS permission
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE permission IS 'Каталог разрешений вида ресурс:действие';
+CREATE TABLE IF NOT EXISTS role_permission
+(
+    role_id       BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permission (id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role_id, permission_id)
+    );
+CREATE INDEX IF NOT EXISTS role_permission_permission_idx ON role_permission (permission_id);
+COMMENT ON TABLE role_permission IS 'Разрешения, выданные ролям';
+INSERT INTO role(name) VALUES ('IDM_ADMIN'), ('IDM_USER') ON CONFLICT (name) DO NOTHING;
+INSERT INTO permission(name, description) VALUES
+    ('employee:read', 'Просмотр сотрудников'),
+    ('employee:write', 'Создание и изменение сотрудников'),
+    ('employee:delete', 'Удаление сотрудников'),
+    ('role:read', 'Просмотр ролей'),
+    ('role:write', 'Создание, изменение и удаление ролей'),
+    ('assignment:read', 'Просмотр назначений ролей'),
+    ('assignment:write', 'Назначение и отзыв ролей'),
+    ('permission:read', 'Просмотр каталога разрешений'),
+    ('permission:write', 'Управление каталогом разрешений'),
+    ('profile:read', 'Просмотр собственного профиля'),
+    ('profile:write', 'Изменение собственного профиля')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE (r.name = 'IDM_ADMIN' AND p.name IN ('employee:write', 'employee:delete', 'role:write', 'assignment:read',
+                                           'assignment:write', 'permission:write', 'profile:read', 'profile:write'))
+   OR (r.name = 'IDM_USER' AND p.name IN ('employee:read', 'role:read', 'permission:read', 'profile:read',
+                                          'profile:write'))
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS permission;
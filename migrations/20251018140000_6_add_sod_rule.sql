@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sod_rule
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    mode        TEXT NOT NULL DEFAULT 'BLOCK' CHECK (mode IN ('BLOCK', 'WARN')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE sod_rule IS 'Правила разделения полномочий (SoD): роли правила не должны принадлежать одному сотруднику';
+CREATE TABLE IF NOT EXISTS sod_rule_role
+(
+    rule_id BIGINT NOT NULL REFERENCES sod_rule (id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    PRIMARY KEY (rule_id, role_id)
+    );
+CREATE INDEX IF NOT EXISTS sod_rule_role_role_idx ON sod_rule_role (role_id);
+INSERT INTO permission(name, description) VALUES
+    ('sod:read', 'Просмотр правил SoD и нарушений'),
+    ('sod:write', 'Управление правилами SoD')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('sod:read', 'sod:write')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('sod:read', 'sod:write');
+DROP TABLE IF EXISTS sod_rule_role;
+DROP TABLE IF EXISTS sod_rule;
@@ -0,0 +1,60 @@
+-- +goose Up
+ALTER TABLE employee
+    ADD COLUMN IF NOT EXISTS manager_id BIGINT REFERENCES employee (id) ON DELETE SET NULL;
+COMMENT ON COLUMN employee.manager_id IS 'Непосредственный руководитель, согласующий запросы доступа';
+CREATE TABLE IF NOT EXISTS approval_step
+(
+    role_id       BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    step_no       INT NOT NULL CHECK (step_no > 0),
+    approver_type TEXT NOT NULL CHECK (approver_type IN ('MANAGER', 'EMPLOYEE', 'ADMIN')),
+    approver_id   BIGINT REFERENCES employee (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, step_no),
+    CONSTRAINT approval_step_approver_check CHECK ((approver_type = 'EMPLOYEE') = (approver_id IS NOT NULL))
+    );
+COMMENT ON TABLE approval_step IS 'Цепочка согласования запроса роли; без шагов запрос согласует руководитель';
+CREATE TABLE IF NOT EXISTS access_request
+(
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    requester_id  BIGINT NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id       BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    justification TEXT NOT NULL DEFAULT '',
+    valid_until   TIMESTAMPTZ,
+    status        TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED', 'CANCELLED')),
+    current_step  INT NOT NULL DEFAULT 1,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at     TIMESTAMPTZ
+    );
+COMMENT ON TABLE access_request IS 'Запросы сотрудников на получение роли';
+CREATE UNIQUE INDEX IF NOT EXISTS access_request_pending_uq ON access_request (requester_id, role_id)
+    WHERE status = 'PENDING';
+CREATE TABLE IF NOT EXISTS access_request_approval
+(
+    request_id    BIGINT NOT NULL REFERENCES access_request (id) ON DELETE CASCADE,
+    step_no       INT NOT NULL,
+    approver_type TEXT NOT NULL,
+    approver_id   BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    status        TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    decided_by    TEXT NOT NULL DEFAULT '',
+    comment       TEXT NOT NULL DEFAULT '',
+    decided_at    TIMESTAMPTZ,
+    PRIMARY KEY (request_id, step_no)
+    );
+CREATE INDEX IF NOT EXISTS access_request_approval_approver_idx ON access_request_approval (approver_id)
+    WHERE status = 'PENDING';
+COMMENT ON TABLE access_request_approval IS 'Шаги согласования запроса с согласующим, определённым при подаче';
+INSERT INTO permission(name, description) VALUES
+    ('access_request:submit', 'Подача собственных запросов доступа и их согласование'),
+    ('access_request:manage', 'Просмотр и согласование любых запросов доступа')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE (r.name = 'IDM_ADMIN' AND p.name IN ('access_request:submit', 'access_request:manage'))
+   OR (r.name = 'IDM_USER' AND p.name = 'access_request:submit')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('access_request:submit', 'access_request:manage');
+DROP TABLE IF EXISTS access_request_approval;
+DROP TABLE IF EXISTS access_request;
+DROP TABLE IF EXISTS approval_step;
+ALTER TABLE employee DROP COLUMN IF EXISTS manager_id;
@@ -0,0 +1,50 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS certification_campaign
+(
+    id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name         TEXT NOT NULL,
+    scope_type   TEXT NOT NULL CHECK (scope_type IN ('DEPARTMENT', 'ROLE')),
+    scope_id     BIGINT NOT NULL,
+    deadline     TIMESTAMPTZ NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'COMPLETED')),
+    created_by   TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+    );
+CREATE INDEX IF NOT EXISTS certification_campaign_deadline_idx ON certification_campaign (deadline)
+    WHERE status = 'ACTIVE';
+COMMENT ON TABLE certification_campaign IS 'Кампании пересертификации назначенных ролей';
+CREATE TABLE IF NOT EXISTS certification_item
+(
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    campaign_id   BIGINT NOT NULL REFERENCES certification_campaign (id) ON DELETE CASCADE,
+    employee_id   BIGINT NOT NULL,
+    employee_name TEXT NOT NULL,
+    role_id       BIGINT NOT NULL,
+    role_name     TEXT NOT NULL,
+    valid_from    TIMESTAMPTZ NOT NULL,
+    valid_until   TIMESTAMPTZ,
+    reviewer_id   BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    decision      TEXT NOT NULL DEFAULT 'PENDING' CHECK (decision IN ('PENDING', 'CERTIFIED', 'REVOKED')),
+    auto_revoked  BOOLEAN NOT NULL DEFAULT FALSE,
+    decided_by    TEXT NOT NULL DEFAULT '',
+    comment       TEXT NOT NULL DEFAULT '',
+    decided_at    TIMESTAMPTZ,
+    UNIQUE (campaign_id, employee_id, role_id)
+    );
+CREATE INDEX IF NOT EXISTS certification_item_reviewer_idx ON certification_item (reviewer_id)
+    WHERE decision = 'PENDING';
+COMMENT ON TABLE certification_item IS 'Снимок назначения на момент запуска кампании и решение по нему';
+INSERT INTO permission(name, description) VALUES
+    ('certification:review', 'Решения по назначениям, переданным на пересертификацию'),
+    ('certification:manage', 'Запуск кампаний пересертификации и отчёты')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE (r.name = 'IDM_ADMIN' AND p.name IN ('certification:review', 'certification:manage'))
+   OR (r.name = 'IDM_USER' AND p.name = 'certification:review')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('certification:review', 'certification:manage');
+DROP TABLE IF EXISTS certification_item;
+DROP TABLE IF EXISTS certification_campaign;
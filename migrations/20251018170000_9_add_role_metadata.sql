@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE role
+    ADD COLUMN IF NOT EXISTS description         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS owner_id            BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS risk_level          TEXT NOT NULL DEFAULT 'low' CHECK (risk_level IN ('low', 'medium', 'high')),
+    ADD COLUMN IF NOT EXISTS requestable         BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS max_assignment_days INT CHECK (max_assignment_days > 0);
+COMMENT ON COLUMN role.owner_id IS 'Владелец роли, согласует запросы на неё';
+COMMENT ON COLUMN role.requestable IS 'Можно ли запросить роль через запрос доступа';
+COMMENT ON COLUMN role.max_assignment_days IS 'Максимальный срок назначения роли в днях; NULL - бессрочно';
+ALTER TABLE approval_step
+    DROP CONSTRAINT IF EXISTS approval_step_approver_type_check,
+    ADD CONSTRAINT approval_step_approver_type_check
+        CHECK (approver_type IN ('MANAGER', 'OWNER', 'EMPLOYEE', 'ADMIN'));
+-- +goose Down
+DELETE FROM approval_step WHERE approver_type = 'OWNER';
+ALTER TABLE approval_step
+    DROP CONSTRAINT IF EXISTS approval_step_approver_type_check,
+    ADD CONSTRAINT approval_step_approver_type_check
+        CHECK (approver_type IN ('MANAGER', 'EMPLOYEE', 'ADMIN'));
+ALTER TABLE role
+    DROP COLUMN IF EXISTS max_assignment_days,
+    DROP COLUMN IF EXISTS requestable,
+    DROP COLUMN IF EXISTS risk_level,
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,35 @@
+-- +goose Up
+ALTER TABLE employee
+    ADD COLUMN IF NOT EXISTS position TEXT NOT NULL DEFAULT '';
+COMMENT ON COLUMN employee.position IS 'Должность сотрудника, учитывается правилами базового доступа';
+ALTER TABLE employee_role
+    ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'MANUAL' CHECK (source IN ('MANUAL', 'RULE'));
+COMMENT ON COLUMN employee_role.source IS 'MANUAL - роль выдана вручную, RULE - правилом базового доступа';
+CREATE TABLE IF NOT EXISTS birthright_rule
+(
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name          TEXT NOT NULL UNIQUE,
+    department_id BIGINT REFERENCES department (id) ON DELETE CASCADE,
+    position      TEXT,
+    role_id       BIGINT NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT birthright_rule_scope_check CHECK (department_id IS NOT NULL OR position IS NOT NULL)
+    );
+COMMENT ON TABLE birthright_rule IS 'Правила базового доступа: сотрудники отдела и/или должности получают роль автоматически';
+CREATE INDEX IF NOT EXISTS birthright_rule_department_idx ON birthright_rule (department_id);
+INSERT INTO permission(name, description) VALUES
+    ('birthright:read', 'Просмотр правил базового доступа и их расхождений с назначениями'),
+    ('birthright:write', 'Управление правилами базового доступа и их применение')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('birthright:read', 'birthright:write')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('birthright:read', 'birthright:write');
+DROP TABLE IF EXISTS birthright_rule;
+ALTER TABLE employee_role
+    DROP COLUMN IF EXISTS source;
+ALTER TABLE employee
+    DROP COLUMN IF EXISTS position;
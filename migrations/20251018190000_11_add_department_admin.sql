@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS department_admin
+(
+    department_id BIGINT NOT NULL REFERENCES department (id) ON DELETE CASCADE,
+    employee_id   BIGINT NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (department_id, employee_id)
+    );
+COMMENT ON TABLE department_admin IS 'Делегированные администраторы: управляют сотрудниками отдела и всех его дочерних отделов';
+CREATE INDEX IF NOT EXISTS department_admin_employee_idx ON department_admin (employee_id);
+INSERT INTO permission(name, description) VALUES
+    ('department:delegate', 'Назначение делегированных администраторов отделов')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name = 'department:delegate'
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name = 'department:delegate';
+DROP TABLE IF EXISTS department_admin;
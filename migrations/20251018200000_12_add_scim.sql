@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE employee
+    ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+COMMENT ON COLUMN employee.active IS 'Учётная запись активна; отключается SCIM-клиентами при увольнении';
+INSERT INTO permission(name, description) VALUES
+    ('scim:read', 'Чтение пользователей и групп через SCIM'),
+    ('scim:write', 'Провижининг пользователей и групп через SCIM')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('scim:read', 'scim:write')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('scim:read', 'scim:write');
+ALTER TABLE employee DROP COLUMN IF EXISTS active;
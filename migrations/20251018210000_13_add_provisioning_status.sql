@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS provisioning_status
+(
+    employee_id BIGINT PRIMARY KEY,
+    external_id TEXT,
+    login       TEXT,
+    roles       TEXT[] NOT NULL DEFAULT '{}',
+    state       TEXT NOT NULL CHECK (state IN ('PENDING', 'SYNCED', 'FAILED', 'SKIPPED', 'DISABLED')),
+    attempts    INT NOT NULL DEFAULT 0,
+    last_error  TEXT,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE provisioning_status IS 'Синхронизация сотрудников с Keycloak; без внешнего ключа, чтобы отключить учётную запись удалённого сотрудника';
+COMMENT ON COLUMN provisioning_status.roles IS 'Роли, выданные в Keycloak через IDM; роли, выданные там напрямую, не отзываются';
+CREATE INDEX IF NOT EXISTS provisioning_status_state_idx ON provisioning_status (state);
+INSERT INTO permission(name, description) VALUES
+    ('provisioning:read', 'Просмотр статусов синхронизации с Keycloak'),
+    ('provisioning:write', 'Повтор синхронизации сотрудника с Keycloak')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('provisioning:read', 'provisioning:write')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('provisioning:read', 'provisioning:write');
+DROP TABLE IF EXISTS provisioning_status;
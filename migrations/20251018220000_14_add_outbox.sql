@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id             BIGSERIAL PRIMARY KEY,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id   BIGINT NOT NULL,
+    type           TEXT NOT NULL,
+    payload        JSONB NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at   TIMESTAMPTZ,
+    attempts       INT NOT NULL DEFAULT 0,
+    last_error     TEXT
+    );
+COMMENT ON TABLE outbox IS 'Доменные события, записанные в транзакции изменения; публикуются relay в порядке id';
+COMMENT ON COLUMN outbox.published_at IS 'Время успешной публикации; NULL - событие ожидает отправки';
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_published_at_idx ON outbox (published_at);
+-- +goose Down
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhook
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    url         TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    secret      TEXT NOT NULL,
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+COMMENT ON TABLE webhook IS 'Подписки внешних систем на доменные события';
+COMMENT ON COLUMN webhook.event_types IS 'Типы событий подписки; пустой список - все события';
+COMMENT ON COLUMN webhook.secret IS 'Ключ подписи HMAC-SHA256 доставок';
+CREATE TABLE IF NOT EXISTS webhook_delivery
+(
+    id              BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    webhook_id      BIGINT NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+    event_id        BIGINT,
+    event_type      TEXT NOT NULL,
+    body            JSONB NOT NULL,
+    state           TEXT NOT NULL DEFAULT 'PENDING' CHECK (state IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status     INT,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    UNIQUE (webhook_id, event_id)
+    );
+COMMENT ON TABLE webhook_delivery IS 'Журнал доставок событий подписчикам; DEAD - попытки исчерпаны';
+COMMENT ON COLUMN webhook_delivery.event_id IS 'Id события outbox; NULL - тестовое событие';
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE state = 'PENDING';
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, id);
+INSERT INTO permission(name, description) VALUES
+    ('webhook:read', 'Просмотр подписок на события и журнала доставок'),
+    ('webhook:write', 'Управление подписками на события')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('webhook:read', 'webhook:write')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('webhook:read', 'webhook:write');
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook;
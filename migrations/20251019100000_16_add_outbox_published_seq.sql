@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE SEQUENCE IF NOT EXISTS outbox_published_seq;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS published_seq BIGINT;
+COMMENT ON COLUMN outbox.published_seq IS 'Порядковый номер публикации; растёт в порядке фиксации, по нему возобновляется поток событий';
+CREATE UNIQUE INDEX IF NOT EXISTS outbox_published_seq_idx ON outbox (published_seq);
+INSERT INTO permission(name, description) VALUES
+    ('event:read', 'Подписка на поток изменений сотрудников и ролей')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name = 'event:read'
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name = 'event:read';
+DROP INDEX IF EXISTS outbox_published_seq_idx;
+ALTER TABLE outbox DROP COLUMN IF EXISTS published_seq;
+DROP SEQUENCE IF EXISTS outbox_published_seq;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS employee_history
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    employee_id BIGINT NOT NULL,
+    version     INT NOT NULL,
+    operation   TEXT NOT NULL CHECK (operation IN ('INSERT', 'UPDATE', 'DELETE')),
+    data        JSONB NOT NULL,
+    valid_from  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (employee_id, version)
+    );
+COMMENT ON TABLE employee_history IS 'Версии записей employee; без внешнего ключа, чтобы история пережила удаление сотрудника';
+COMMENT ON COLUMN employee_history.data IS 'Запись employee после изменения; для DELETE - последнее состояние перед удалением';
+COMMENT ON COLUMN employee_history.valid_from IS 'Начало действия версии. Для сотрудников, созданных до ведения истории, первая версия - состояние на момент миграции';
+CREATE INDEX IF NOT EXISTS employee_history_valid_from_idx ON employee_history (valid_from);
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION employee_history_record() RETURNS TRIGGER AS
+$$
+DECLARE
+    row_data JSONB;
+    row_id   BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_data := to_jsonb(OLD);
+        row_id := OLD.id;
+    ELSE
+        row_data := to_jsonb(NEW);
+        row_id := NEW.id;
+    END IF;
+    -- обновление без изменения полей (например, того же email) версию не создаёт
+    IF TG_OP = 'UPDATE' AND to_jsonb(OLD) - 'updated_at' = row_data - 'updated_at' THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO employee_history (employee_id, version, operation, data)
+    SELECT row_id, COALESCE(MAX(version), 0) + 1, TG_OP, row_data
+    FROM employee_history
+    WHERE employee_id = row_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+CREATE OR REPLACE TRIGGER employee_history_trg
+    AFTER INSERT OR UPDATE OR DELETE ON employee
+    FOR EACH ROW EXECUTE FUNCTION employee_history_record();
+INSERT INTO employee_history (employee_id, version, operation, data, valid_from)
+SELECT e.id, 1, 'INSERT', to_jsonb(e), e.created_at
+FROM employee e
+ON CONFLICT (employee_id, version) DO NOTHING;
+-- +goose Down
+DROP TRIGGER IF EXISTS employee_history_trg ON employee;
+DROP FUNCTION IF EXISTS employee_history_record();
+DROP TABLE IF EXISTS employee_history;
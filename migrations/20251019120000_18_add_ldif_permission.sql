@@ -0,0 +1,11 @@
+-- +goose Up
+INSERT INTO permission(name, description) VALUES
+    ('ldif:export', 'Выгрузка сотрудников и ролей в LDIF'),
+    ('ldif:import', 'Загрузка сотрудников и ролей из LDIF')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permission(role_id, permission_id)
+SELECT r.id, p.id FROM role r, permission p
+WHERE r.name = 'IDM_ADMIN' AND p.name IN ('ldif:export', 'ldif:import')
+ON CONFLICT DO NOTHING;
+-- +goose Down
+DELETE FROM permission WHERE name IN ('ldif:export', 'ldif:import');
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS idempotency_key
+(
+    owner        TEXT NOT NULL,
+    key          TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code  INT,
+    content_type TEXT NOT NULL DEFAULT '',
+    response     BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (owner, key)
+    );
+COMMENT ON TABLE idempotency_key IS 'Ключи Idempotency-Key POST-запросов и сохранённые ответы для повторов';
+COMMENT ON COLUMN idempotency_key.owner IS 'Subject токена: ключи разных клиентов не пересекаются';
+COMMENT ON COLUMN idempotency_key.status_code IS 'Код сохранённого ответа; NULL - запрос ещё выполняется';
+COMMENT ON COLUMN idempotency_key.locked_until IS 'Срок, до которого выполняющийся запрос держит ключ; после него ключ может занять повтор';
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_key;
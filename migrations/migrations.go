@@ -0,0 +1,10 @@
+// Package migrations - SQL-миграции goose, встроенные в бинарник приложения.
+// keycloak.sql без версии выполняется контейнером Postgres при инициализации и сюда не входит
+package migrations
+
+import "embed"
+
+// FS - миграции схемы приложения
+//
+//go:embed [0-9]*_*.sql
+var FS embed.FS
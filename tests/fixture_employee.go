@@ -25,6 +25,13 @@ func InitSchemaEmployee(r *employee.Repository) error {
 		name        TEXT NOT NULL,
 		surname     TEXT NOT NULL,
 		age         SMALLINT CHECK (age > 16 AND age < 91),
+		login       TEXT UNIQUE,
+		email       TEXT NOT NULL DEFAULT '',
+		phone       TEXT NOT NULL DEFAULT '',
+		department_id BIGINT,
+		manager_id  BIGINT,
+		position    TEXT NOT NULL DEFAULT '',
+		active      BOOLEAN NOT NULL DEFAULT TRUE,
 		"created_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
 		"updated_at"  TIMESTAMPTZ NOT NULL DEFAULT now()
 	);`
@@ -41,6 +48,7 @@ func (f *FixtureEmployee) Employee(name string, surname string, age int8,
 		Name:      name,
 		Surname:   surname,
 		Age:       age,
+		Active:    true,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
@@ -21,6 +21,11 @@ func InitSchemaRole(r *role.Repository) error {
 	schema := `CREATE TABLE IF NOT EXISTS role (
     id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id    BIGINT,
+    risk_level  TEXT NOT NULL DEFAULT 'low',
+    requestable BOOLEAN NOT NULL DEFAULT TRUE,
+    max_assignment_days INT,
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW());`
 	_, err := r.DB().Exec(schema)
@@ -36,8 +41,16 @@ func (f *FixtureRole) Role(name string, createdAt time.Time, updatedAt time.Time
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
-	var newId, err = f.role.Add(entity)
+	tx, err := f.role.BeginTr()
 	if err != nil {
+		panic(fmt.Errorf("Failed to begin transaction: %w", err))
+	}
+	newId, err := f.role.Add(tx, entity)
+	if err != nil {
+		_ = tx.Rollback()
+		panic(err)
+	}
+	if err = tx.Commit(); err != nil {
 		panic(err)
 	}
 	return newId
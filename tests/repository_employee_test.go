@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"database/sql"
 	"idm/inner/database"
 	"idm/inner/employee"
 	"testing"
@@ -173,14 +174,18 @@ func TestEmployeeRepositoryWhenDeleteById(t *testing.T) {
 
 	t.Run("Deleting existing employee by ID", func(t *testing.T) {
 		t.Parallel()
-		got, err := repo.DeleteById(id)
+		tx := db.MustBegin()
+		got, err := repo.DeleteById(tx, id)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.True(got)
 	})
 
 	t.Run("Deleting when false", func(t *testing.T) {
 		t.Parallel()
-		deleted, err := repo.DeleteById(912384)
+		tx := db.MustBegin()
+		deleted, err := repo.DeleteById(tx, 912384)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.False(deleted)
 	})
@@ -203,7 +208,9 @@ func TestEmployeeRepositoryWhenDeleteByIds(t *testing.T) {
 	t.Run("Deleting when correct", func(t *testing.T) {
 		t.Parallel()
 		ids := []int64{id1, id2}
-		got, err := repo.DeleteBySliceIds(ids)
+		tx := db.MustBegin()
+		got, err := repo.DeleteBySliceIds(tx, ids)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.ElementsMatch(ids, got)
 	})
@@ -215,3 +222,39 @@ func TestEmployeeRepositoryWhenDeleteByIds(t *testing.T) {
 		a.Len(got, 1)
 	})
 }
+
+func TestEmployeeRepositoryWhenFindByIdentity(t *testing.T) {
+	a := assert.New(t)
+
+	db := database.ConnectDb()
+	t.Cleanup(func() {
+		db.MustExec("DELETE FROM employee")
+	})
+
+	ctx := context.Background()
+	repo := employee.NewEmployeeRepository(db)
+	_ = NewFixtureEmployee(repo)
+	var bySubject, byUsername int64
+	a.NoError(db.Get(&bySubject,
+		"INSERT INTO employee(name, surname, login) VALUES ('Sub', 'Ject', 'f81d4fae') RETURNING id"))
+	a.NoError(db.Get(&byUsername,
+		"INSERT INTO employee(name, surname, login) VALUES ('John', 'Doe', 'jdoe') RETURNING id"))
+	db.MustExec("INSERT INTO employee(name, surname, login) VALUES ('Empty', 'Login', '')")
+
+	t.Run("Prefer employee linked by subject", func(t *testing.T) {
+		got, err := repo.FindByIdentity(ctx, "f81d4fae", "jdoe")
+		a.NoError(err)
+		a.Equal(bySubject, got.Id)
+	})
+
+	t.Run("Fall back to username", func(t *testing.T) {
+		got, err := repo.FindByIdentity(ctx, "unknown", "jdoe")
+		a.NoError(err)
+		a.Equal(byUsername, got.Id)
+	})
+
+	t.Run("Empty values match no employee", func(t *testing.T) {
+		_, err := repo.FindByIdentity(ctx, "", "")
+		a.ErrorIs(err, sql.ErrNoRows)
+	})
+}
@@ -103,14 +103,18 @@ func TestRoleRepositoryWhenDeleteById(t *testing.T) {
 
 	t.Run("Deleting existing role by ID", func(t *testing.T) {
 		t.Parallel()
-		got, err := repo.DeleteById(id)
+		tx := db.MustBegin()
+		got, err := repo.DeleteById(tx, id)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.True(got)
 	})
 
 	t.Run("Deleting when false", func(t *testing.T) {
 		t.Parallel()
-		deleted, err := repo.DeleteById(912384)
+		tx := db.MustBegin()
+		deleted, err := repo.DeleteById(tx, 912384)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.False(deleted)
 	})
@@ -132,7 +136,9 @@ func TestRoleRepositoryWhenDeleteByIds(t *testing.T) {
 	fixture.Role("Guest3", time.Now(), time.Now())
 	t.Run("Deleting when correct", func(t *testing.T) {
 		ids := []int64{id1, id2}
-		got, err := repo.DeleteBySliceIds(ids)
+		tx := db.MustBegin()
+		got, err := repo.DeleteBySliceIds(tx, ids)
+		a.NoError(tx.Commit())
 		a.NoError(err)
 		a.ElementsMatch(ids, got)
 	})
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"database/sql"
+	"idm/inner/database"
+	"idm/inner/sod"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSodRepositoryWhenFindConflicts(t *testing.T) {
+	a := assert.New(t)
+
+	db := database.ConnectDb()
+	t.Cleanup(func() {
+		db.MustExec("DELETE FROM sod_rule")
+		db.MustExec("DELETE FROM employee_role")
+		db.MustExec("DELETE FROM role WHERE name IN ('PAYMENT_CREATE', 'PAYMENT_APPROVE')")
+		db.MustExec("DELETE FROM employee WHERE login = 'sod.test'")
+	})
+
+	repo := sod.NewRepository(db)
+	var employeeId, createId, approveId, ruleId int64
+	a.NoError(db.Get(&employeeId,
+		"INSERT INTO employee(name, surname, login) VALUES ('Sod', 'Test', 'sod.test') RETURNING id"))
+	a.NoError(db.Get(&createId, "INSERT INTO role(name) VALUES ('PAYMENT_CREATE') RETURNING id"))
+	a.NoError(db.Get(&approveId, "INSERT INTO role(name) VALUES ('PAYMENT_APPROVE') RETURNING id"))
+	a.NoError(db.Get(&ruleId, "INSERT INTO sod_rule(name, mode) VALUES ('payments', 'BLOCK') RETURNING id"))
+	db.MustExec("INSERT INTO sod_rule_role(rule_id, role_id) VALUES ($1, $2), ($1, $3)", ruleId, createId, approveId)
+	var now = time.Now()
+	var nextMonth = now.AddDate(0, 1, 0)
+
+	t.Run("Conflict with held role starting in the future", func(t *testing.T) {
+		db.MustExec("DELETE FROM employee_role")
+		db.MustExec("INSERT INTO employee_role(employee_id, role_id, valid_from) VALUES ($1, $2, $3)",
+			employeeId, createId, nextMonth)
+		tx := db.MustBegin()
+		defer func() { _ = tx.Rollback() }()
+
+		got, err := repo.FindConflicts(tx, employeeId, approveId, now, sql.NullTime{})
+
+		a.NoError(err)
+		a.Len(got, 1)
+	})
+
+	t.Run("Conflict of new role starting in the future", func(t *testing.T) {
+		db.MustExec("DELETE FROM employee_role")
+		db.MustExec("INSERT INTO employee_role(employee_id, role_id) VALUES ($1, $2)", employeeId, createId)
+		tx := db.MustBegin()
+		defer func() { _ = tx.Rollback() }()
+
+		got, err := repo.FindConflicts(tx, employeeId, approveId, nextMonth, sql.NullTime{})
+
+		a.NoError(err)
+		a.Len(got, 1)
+	})
+
+	t.Run("No conflict when periods do not overlap", func(t *testing.T) {
+		db.MustExec("DELETE FROM employee_role")
+		db.MustExec("INSERT INTO employee_role(employee_id, role_id, valid_until) VALUES ($1, $2, $3)",
+			employeeId, createId, nextMonth)
+		tx := db.MustBegin()
+		defer func() { _ = tx.Rollback() }()
+
+		got, err := repo.FindConflicts(tx, employeeId, approveId, nextMonth, sql.NullTime{})
+
+		a.NoError(err)
+		a.Empty(got)
+	})
+
+	t.Run("Concurrent checks of one employee run one after another", func(t *testing.T) {
+		db.MustExec("DELETE FROM employee_role")
+		first := db.MustBegin()
+		defer func() { _ = first.Rollback() }()
+		_, err := repo.FindConflicts(first, employeeId, createId, now, sql.NullTime{})
+		a.NoError(err)
+
+		var checked = make(chan []sod.Violation)
+		go func() {
+			second := db.MustBegin()
+			defer func() { _ = second.Rollback() }()
+			got, _ := repo.FindConflicts(second, employeeId, approveId, now, sql.NullTime{})
+			checked <- got
+		}()
+		select {
+		case <-checked:
+			a.Fail("second check must wait for the first transaction")
+		case <-time.After(200 * time.Millisecond):
+		}
+		first.MustExec("INSERT INTO employee_role(employee_id, role_id) VALUES ($1, $2)", employeeId, createId)
+		a.NoError(first.Commit())
+
+		a.Len(<-checked, 1, "second check must see the role granted by the first")
+	})
+}